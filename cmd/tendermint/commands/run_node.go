@@ -43,6 +43,9 @@ func AddNodeFlags(cmd *cobra.Command, conf *cfg.Config) {
 	cmd.Flags().Int64("consensus.double-sign-check-height", conf.Consensus.DoubleSignCheckHeight,
 		"how many blocks to look back to check existence of the node's "+
 			"consensus votes before joining consensus")
+	cmd.Flags().Int64("consensus.acknowledge-restart-at-height", conf.Consensus.AcknowledgeRestartAtHeight,
+		"height of the signature of the node's consensus key found by the double sign check "+
+			"to start despite, as when restoring a validator from a recent snapshot")
 
 	// abci flags
 	cmd.Flags().String(
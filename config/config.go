@@ -30,6 +30,17 @@ const (
 	ModeSeed      = "seed"
 )
 
+// Per-validator gauges of the missing validators power updated at each height.
+const (
+	// MissingValidatorsPowerAll updates the gauges of all the validators.
+	MissingValidatorsPowerAll = "all"
+	// MissingValidatorsPowerChanged updates the gauges of the validators
+	// whose signing of the last commit changed since the previous height.
+	MissingValidatorsPowerChanged = "changed"
+	// MissingValidatorsPowerNone updates no gauge, only the aggregates.
+	MissingValidatorsPowerNone = "none"
+)
+
 // NOTE: Most of the structs & relevant comments + the
 // default configuration options were used to manually
 // generate the config.toml. Please reflect any changes
@@ -1043,6 +1054,16 @@ type ConsensusConfig struct {
 	CreateEmptyBlocksInterval time.Duration `mapstructure:"create-empty-blocks-interval"`
 	// Send transaction hash only
 	GossipTransactionKeyOnly bool `mapstructure:"gossip-tx-key-only"`
+	// BlockReconstructionSoftLimit is how long rebuilding a proposal block
+	// from the mempool may take in key-only mode before a warning is logged.
+	// Reconstruction is abandoned once the propose timeout of the round has
+	// passed, but every attempt is given at least this long.
+	BlockReconstructionSoftLimit time.Duration `mapstructure:"block-reconstruction-soft-limit"`
+	// RebuildAuditPath is the directory the committed blocks rebuilt from the
+	// mempool in key-only mode are dumped to when they serialize otherwise
+	// than the block their stored parts encode. Empty, the default, disables
+	// the audit.
+	RebuildAuditPath string `mapstructure:"rebuild-audit-dir"`
 
 	// Reactor sleep duration parameters
 	PeerGossipSleepDuration     time.Duration `mapstructure:"peer-gossip-sleep-duration"`
@@ -1050,6 +1071,232 @@ type ConsensusConfig struct {
 
 	DoubleSignCheckHeight int64 `mapstructure:"double-sign-check-height"`
 
+	// AcknowledgeRestartAtHeight lets the node start although a signature of
+	// its key is found by the double sign check, as when a validator is
+	// restored from a recent snapshot, provided that the most recent
+	// signature is at this height. The acknowledgment is recorded in the WAL.
+	// 0, the default, acknowledges none.
+	AcknowledgeRestartAtHeight int64 `mapstructure:"acknowledge-restart-at-height"`
+
+	// HaltOnConflictingSelfVote stops this node from signing once a vote of
+	// its key conflicting with one it already signed is received, as another
+	// instance of this validator may be running. A marker file is written
+	// next to the WAL, and the node keeps following consensus without
+	// proposing or voting, also after a restart, until the file is removed.
+	// It is on by default, and only applies to a node with a private
+	// validator.
+	HaltOnConflictingSelfVote bool `mapstructure:"halt-on-conflicting-self-vote"`
+
+	// WatchdogTimeout is how long the consensus receive routine may go without
+	// processing a message while messages are pending before it is reported
+	// as stalled. 0, the default, disables the watchdog.
+	WatchdogTimeout time.Duration `mapstructure:"watchdog-timeout"`
+
+	// ProposerBlacklistThreshold is the number of consecutive proposals of a
+	// validator rejected by the application after which its new proposals are
+	// prevoted nil as soon as they are received, without waiting for the
+	// block. 0, the default, disables the blacklist.
+	ProposerBlacklistThreshold int `mapstructure:"proposer-blacklist-threshold"`
+	// ProposerBlacklistHeights is the number of heights a proposer stays
+	// blacklisted for.
+	ProposerBlacklistHeights int64 `mapstructure:"proposer-blacklist-heights"`
+
+	// TraceSampleInterval makes only one in TraceSampleInterval heights fully
+	// traced. The other heights only get the height span. 0 and 1 trace every
+	// height.
+	TraceSampleInterval int64 `mapstructure:"trace-sample-interval"`
+
+	// AbsenteeGracePeriod is how long after the start of a round a validator
+	// that has not voted in the height is reported as absent.
+	AbsenteeGracePeriod time.Duration `mapstructure:"absentee-grace-period"`
+
+	// MaxStepsPerSecond is the number of state machine steps per second above
+	// which the processing of peer messages is delayed until the next second.
+	// Messages of this node and timeouts are never delayed. 0, the default,
+	// disables the limit.
+	MaxStepsPerSecond int64 `mapstructure:"max-steps-per-second"`
+
+	// DecisionLogPath is the file the decisions of the consensus state
+	// machine are logged to as JSON lines. Empty, the default, disables the
+	// decision log.
+	DecisionLogPath string `mapstructure:"decision-log-file"`
+	// DecisionLogMaxSize is the maximum total size in bytes of the decision
+	// log files. The oldest files are removed once it is exceeded.
+	DecisionLogMaxSize int64 `mapstructure:"decision-log-max-size"`
+
+	// EventRingPath is the file the consensus state writes a compact record of
+	// the events it publishes to, such as its steps, polkas, locks and
+	// commits, for their inspection after a crash. The file is a ring of
+	// EventRingSize records, the oldest being overwritten. Empty, the default,
+	// disables the ring.
+	EventRingPath string `mapstructure:"event-ring-file"`
+	// EventRingSize is the number of records the event ring holds.
+	EventRingSize int `mapstructure:"event-ring-size"`
+
+	// SignRequestHistory is the number of the last proposals and votes this
+	// node asked its private validator to sign whose sign-bytes and
+	// signatures are kept for signer audits. The sign-bytes reveal the timing
+	// of the votes. 0, the default, disables the history.
+	SignRequestHistory int `mapstructure:"sign-request-history"`
+	// SignRequestLogPath is the file the sign requests are also appended to
+	// as JSON lines. Empty, the default, disables the log.
+	SignRequestLogPath string `mapstructure:"sign-request-log-file"`
+
+	// VoteExtensionMemorySoftCap is the size in bytes of the vote extensions
+	// held in the vote sets of a height above which a warning is logged,
+	// once per height, with the validators whose extensions take the most.
+	// 0, the default, disables the warning.
+	VoteExtensionMemorySoftCap int64 `mapstructure:"vote-extension-memory-soft-cap"`
+
+	// BlockGossipProgressThresholds are the percentages of the parts of a
+	// proposal block received at which a block gossip progress event is
+	// published, in increasing order.
+	BlockGossipProgressThresholds []int `mapstructure:"block-gossip-progress-thresholds"`
+
+	// PrecommitExclusionWindow is the number of heights over which the rate of
+	// our precommits for committed blocks excluded from their commit is
+	// computed. 0 disables the precommit exclusion event.
+	PrecommitExclusionWindow int `mapstructure:"precommit-exclusion-window"`
+	// PrecommitExclusionThreshold is the rate of our precommits excluded from
+	// the commit over the window above which an event is published.
+	PrecommitExclusionThreshold float64 `mapstructure:"precommit-exclusion-threshold"`
+
+	// BlockPartCodec is the name of the codec the blocks proposed by this node
+	// are compressed by before being split into block parts. Block parts,
+	// whether received by consensus or rebuilt from a block by consensus or
+	// block sync, are accepted uncompressed or compressed by this codec only.
+	// Empty, the default, disables the compression. The BlockID of a block
+	// commits to its compressed parts, so all the nodes of a network must use
+	// the same codec.
+	BlockPartCodec string `mapstructure:"block-part-codec"`
+
+	// ProposerHistoryHeights is the number of most recent heights whose
+	// proposer and its proposer priority are recorded. 0, the default,
+	// disables the record.
+	ProposerHistoryHeights int `mapstructure:"proposer-history-heights"`
+
+	// MaxProposalEvidenceFraction is the fraction of the block max bytes the
+	// evidence of the blocks proposed by this node may take, on top of the
+	// evidence max bytes of the consensus params. The evidence above it is
+	// left out of the blocks, for the txs. 0, the default, sets no ceiling
+	// other than the consensus params.
+	MaxProposalEvidenceFraction float64 `mapstructure:"max-proposal-evidence-fraction"`
+
+	// LastCommitValidatorMetrics enables the tallies, per validator, of the
+	// precommits for the previous height accepted into the last commit or
+	// ignored after the timeoutCommit window. It adds a label value per
+	// validator.
+	LastCommitValidatorMetrics bool `mapstructure:"last-commit-validator-metrics"`
+
+	// MissingValidatorsPowerMetrics selects the per-validator gauges of the
+	// missing validators power updated at each height: those of all the
+	// validators, of the validators whose signing changed since the previous
+	// height, or none, leaving only the aggregates. The gauges are updated in
+	// the background.
+	MissingValidatorsPowerMetrics string `mapstructure:"missing-validators-power-metrics"`
+
+	// SignerSkewTolerance is the difference between the timestamp of a vote
+	// of this node before and after its signing, by a signer normalizing or
+	// re-stamping the times, above which it is logged and counted.
+	SignerSkewTolerance time.Duration `mapstructure:"signer-skew-tolerance"`
+
+	// SignerSkewLimit is the difference between the timestamp of a vote of
+	// this node before and after its signing above which the vote is signed
+	// again, once, and then dropped rather than broadcast. 0, the default,
+	// disables it.
+	SignerSkewLimit time.Duration `mapstructure:"signer-skew-limit"`
+
+	// PeerStatsMaxPeers is the number of peers whose messages are counted per
+	// outcome by the consensus state. The least recently heard from peer is
+	// evicted beyond it. 0 disables the counts.
+	PeerStatsMaxPeers int `mapstructure:"peer-stats-max-peers"`
+
+	// PeerStatsMetrics exports the counts of the messages of each peer as
+	// metrics. It adds a label value per peer.
+	PeerStatsMetrics bool `mapstructure:"peer-stats-metrics"`
+
+	// LockHoldWarnThreshold is the time the consensus state mutex can be held
+	// by an operation above which a warning is logged. 0, the default,
+	// disables it.
+	LockHoldWarnThreshold time.Duration `mapstructure:"lock-hold-warn-threshold"`
+
+	// BypassCommitTimeoutMinPeers is the number of distinct peers the
+	// precommits of a round must have been received from for the commit
+	// timeout to be bypassed once all of them are received, unless one is
+	// this node's. It guards against all the precommits being relayed by a
+	// single peer ahead of a competing view. 0, the default, disables it.
+	BypassCommitTimeoutMinPeers int `mapstructure:"bypass-commit-timeout-min-peers"`
+
+	// StrictReplayTimeouts aborts the start when a timeout replayed from the
+	// WAL lasted otherwise than the timeout configuration computes, which
+	// means the configuration changed since the WAL was written and the
+	// replay may not follow the original transitions. Otherwise a warning is
+	// logged.
+	StrictReplayTimeouts bool `mapstructure:"strict-replay-timeouts"`
+
+	// TimeoutScalingCoefficient scales the propose and vote timeouts with the
+	// size of the validator set: their base is multiplied by
+	// 1 + k*ln(n/n0), where k is the coefficient, n the number of validators
+	// and n0 TimeoutScalingBaseValidators. The timeouts of sets of at most n0
+	// validators are not scaled, nor are the unsafe timeout overrides. 0, the
+	// default, disables the scaling.
+	TimeoutScalingCoefficient float64 `mapstructure:"timeout-scaling-coefficient"`
+	// TimeoutScalingBaseValidators is the size of the validator set the
+	// timeouts are configured for, above which they are scaled.
+	TimeoutScalingBaseValidators int `mapstructure:"timeout-scaling-base-validators"`
+
+	// PrecommitPrevoteFraction is the fraction of the voting power whose
+	// prevotes this node waits for before precommitting a block once +2/3
+	// prevoted for it, for at most PrecommitPrevoteWait. It is a local policy:
+	// the votes of this node and the rules of the protocol are unchanged.
+	// 0, the default, disables it.
+	PrecommitPrevoteFraction float64 `mapstructure:"precommit-prevote-fraction"`
+	// PrecommitPrevoteWait is the longest this node delays its precommit for
+	// PrecommitPrevoteFraction. The delay never exceeds the vote timeout of
+	// the round. 0 bounds it by the vote timeout only.
+	PrecommitPrevoteWait time.Duration `mapstructure:"precommit-prevote-wait"`
+
+	// ProposeGrace extends the propose timeout of a round, once, when the
+	// prevote of its proposer for a block is received before its proposal:
+	// the block exists, and its proposal is solicited from the peers that
+	// have it. The grace never exceeds the vote timeout of the round. 0, the
+	// default, disables the extension; the proposal is solicited regardless.
+	ProposeGrace time.Duration `mapstructure:"propose-grace"`
+
+	// RevalidateCommittedBlocks validates the block committed by +2/3
+	// precommits again before applying it, rather than reusing the outcome of
+	// its validation earlier in the height, as a safety backstop.
+	RevalidateCommittedBlocks bool `mapstructure:"revalidate-committed-blocks"`
+
+	// MsgDebugLogRate is the number of debug lines per second logged by the
+	// consensus state for each type of peer message and outcome, such as the
+	// votes added, past a burst of MsgDebugLogBurst. The lines over the rate
+	// are counted and reported in a summary every MsgDebugLogSummaryInterval.
+	// 0, the default, disables the limit.
+	MsgDebugLogRate float64 `mapstructure:"msg-debug-log-rate"`
+	// MsgDebugLogBurst is the number of debug lines per type of message and
+	// outcome logged at once before MsgDebugLogRate applies.
+	MsgDebugLogBurst int `mapstructure:"msg-debug-log-burst"`
+	// MsgDebugLogSummaryInterval is the interval at which the number of debug
+	// lines suppressed by MsgDebugLogRate is logged.
+	MsgDebugLogSummaryInterval time.Duration `mapstructure:"msg-debug-log-summary-interval"`
+
+	// EventBusPublishTimeout is the time the consensus state waits for the
+	// event bus to accept an event, when its subscribers are overloaded,
+	// before giving up on it. EventBusBreakerThreshold consecutive failed or
+	// timed out publishes open a circuit breaker: the events are skipped for
+	// EventBusBreakerCooldown, then a publish probes the event bus again. The
+	// events given up on or skipped are lost for the RPC subscribers, the
+	// indexer and the mempool. 0, the default, disables the timeout and the
+	// breaker.
+	EventBusPublishTimeout time.Duration `mapstructure:"event-bus-publish-timeout"`
+	// EventBusBreakerThreshold is the number of consecutive failed or timed
+	// out publishes opening the circuit breaker.
+	EventBusBreakerThreshold int `mapstructure:"event-bus-breaker-threshold"`
+	// EventBusBreakerCooldown is the time the circuit breaker stays open
+	// before a publish probes the event bus again.
+	EventBusBreakerCooldown time.Duration `mapstructure:"event-bus-breaker-cooldown"`
+
 	// TODO: The following fields are all temporary overrides that should exist only
 	// for the duration of the v0.36 release. The below fields should be completely
 	// removed in the v0.37 release of Tendermint.
@@ -1101,14 +1348,41 @@ type ConsensusConfig struct {
 // DefaultConsensusConfig returns a default configuration for the consensus service
 func DefaultConsensusConfig() *ConsensusConfig {
 	return &ConsensusConfig{
-		WalPath:                     filepath.Join(defaultDataDir, "cs.wal", "wal"),
-		CreateEmptyBlocks:           true,
-		CreateEmptyBlocksInterval:   0 * time.Second,
-		PeerGossipSleepDuration:     100 * time.Millisecond,
-		PeerQueryMaj23SleepDuration: 2000 * time.Millisecond,
-		DoubleSignCheckHeight:       int64(0),
+		WalPath:                       filepath.Join(defaultDataDir, "cs.wal", "wal"),
+		CreateEmptyBlocks:             true,
+		CreateEmptyBlocksInterval:     0 * time.Second,
+		PeerGossipSleepDuration:       100 * time.Millisecond,
+		PeerQueryMaj23SleepDuration:   2000 * time.Millisecond,
+		DoubleSignCheckHeight:         int64(0),
+		HaltOnConflictingSelfVote:     true,
+		WatchdogTimeout:               0,
+		ProposerBlacklistThreshold:    0,
+		ProposerBlacklistHeights:      10,
+		TraceSampleInterval:           1,
+		AbsenteeGracePeriod:           100 * time.Millisecond,
+		MaxStepsPerSecond:             0,
+		DecisionLogPath:               "",
+		DecisionLogMaxSize:            100 * 1024 * 1024,
+		EventRingSize:                 4096,
+		BlockGossipProgressThresholds: []int{25, 50, 75},
+		PrecommitExclusionWindow:      100,
+		PrecommitExclusionThreshold:   0.1,
+		ProposerHistoryHeights:        0,
+		MaxProposalEvidenceFraction:   0,
+		MissingValidatorsPowerMetrics: MissingValidatorsPowerAll,
+		PeerStatsMaxPeers:             128,
+		LockHoldWarnThreshold:         0,
+		SignerSkewTolerance:           100 * time.Millisecond,
+		TimeoutScalingBaseValidators:  4,
+		MsgDebugLogRate:               0,
+		MsgDebugLogBurst:              50,
+		MsgDebugLogSummaryInterval:    10 * time.Second,
+		EventBusPublishTimeout:        0,
+		EventBusBreakerThreshold:      0,
+		EventBusBreakerCooldown:       10 * time.Second,
 		// Sei Configurations
-		GossipTransactionKeyOnly: true,
+		GossipTransactionKeyOnly:     true,
+		BlockReconstructionSoftLimit: 100 * time.Millisecond,
 	}
 }
 
@@ -1141,6 +1415,27 @@ func (cfg *ConsensusConfig) SetWalFile(walFile string) {
 	cfg.walFile = walFile
 }
 
+// DecisionLogFile returns the full path to the decision log file
+func (cfg *ConsensusConfig) DecisionLogFile() string {
+	return rootify(cfg.DecisionLogPath, cfg.RootDir)
+}
+
+// EventRingFile returns the full path to the event ring file
+func (cfg *ConsensusConfig) EventRingFile() string {
+	return rootify(cfg.EventRingPath, cfg.RootDir)
+}
+
+// SignRequestLogFile returns the full path to the sign request log file
+func (cfg *ConsensusConfig) SignRequestLogFile() string {
+	return rootify(cfg.SignRequestLogPath, cfg.RootDir)
+}
+
+// RebuildAuditDir returns the full path to the directory the diverging
+// rebuilt blocks are dumped to
+func (cfg *ConsensusConfig) RebuildAuditDir() string {
+	return rootify(cfg.RebuildAuditPath, cfg.RootDir)
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *ConsensusConfig) ValidateBasic() error {
@@ -1171,6 +1466,135 @@ func (cfg *ConsensusConfig) ValidateBasic() error {
 	if cfg.DoubleSignCheckHeight < 0 {
 		return errors.New("double-sign-check-height can't be negative")
 	}
+	if cfg.AcknowledgeRestartAtHeight < 0 {
+		return errors.New("acknowledge-restart-at-height can't be negative")
+	}
+	if cfg.WatchdogTimeout < 0 {
+		return errors.New("watchdog-timeout can't be negative")
+	}
+	if cfg.BlockReconstructionSoftLimit < 0 {
+		return errors.New("block-reconstruction-soft-limit can't be negative")
+	}
+	if cfg.ProposerBlacklistThreshold < 0 {
+		return errors.New("proposer-blacklist-threshold can't be negative")
+	}
+	if cfg.ProposerBlacklistHeights < 0 {
+		return errors.New("proposer-blacklist-heights can't be negative")
+	}
+	if cfg.ProposerBlacklistThreshold > 0 && cfg.ProposerBlacklistHeights == 0 {
+		return errors.New("proposer-blacklist-heights must be positive when the proposer blacklist is enabled")
+	}
+	if cfg.TraceSampleInterval < 0 {
+		return errors.New("trace-sample-interval can't be negative")
+	}
+	if cfg.AbsenteeGracePeriod < 0 {
+		return errors.New("absentee-grace-period can't be negative")
+	}
+	if cfg.MaxStepsPerSecond < 0 {
+		return errors.New("max-steps-per-second can't be negative")
+	}
+	if cfg.DecisionLogMaxSize < 0 {
+		return errors.New("decision-log-max-size can't be negative")
+	}
+	if cfg.DecisionLogPath != "" && cfg.DecisionLogMaxSize == 0 {
+		return errors.New("decision-log-max-size must be positive when the decision log is enabled")
+	}
+	if cfg.EventRingSize < 0 {
+		return errors.New("event-ring-size can't be negative")
+	}
+	if cfg.EventRingPath != "" && cfg.EventRingSize == 0 {
+		return errors.New("event-ring-size must be positive when the event ring is enabled")
+	}
+	if cfg.SignRequestHistory < 0 {
+		return errors.New("sign-request-history can't be negative")
+	}
+	if cfg.VoteExtensionMemorySoftCap < 0 {
+		return errors.New("vote-extension-memory-soft-cap can't be negative")
+	}
+	for i, threshold := range cfg.BlockGossipProgressThresholds {
+		if threshold <= 0 || threshold >= 100 {
+			return errors.New("block-gossip-progress-thresholds must be between 0 and 100 exclusive")
+		}
+		if i > 0 && threshold <= cfg.BlockGossipProgressThresholds[i-1] {
+			return errors.New("block-gossip-progress-thresholds must be increasing")
+		}
+	}
+	if cfg.PrecommitExclusionWindow < 0 {
+		return errors.New("precommit-exclusion-window can't be negative")
+	}
+	if cfg.PrecommitExclusionThreshold < 0 || cfg.PrecommitExclusionThreshold >= 1 {
+		return errors.New("precommit-exclusion-threshold must be between 0 inclusive and 1 exclusive")
+	}
+	if cfg.MaxProposalEvidenceFraction < 0 || cfg.MaxProposalEvidenceFraction > 1 {
+		return errors.New("max-proposal-evidence-fraction must be between 0 and 1")
+	}
+	if cfg.ProposerHistoryHeights < 0 {
+		return errors.New("proposer-history-heights can't be negative")
+	}
+	if cfg.TimeoutScalingCoefficient < 0 {
+		return errors.New("timeout-scaling-coefficient can't be negative")
+	}
+	if cfg.TimeoutScalingBaseValidators < 0 {
+		return errors.New("timeout-scaling-base-validators can't be negative")
+	}
+	if cfg.TimeoutScalingCoefficient > 0 && cfg.TimeoutScalingBaseValidators == 0 {
+		return errors.New("timeout-scaling-base-validators must be positive when the timeout scaling is enabled")
+	}
+	if cfg.MsgDebugLogRate < 0 {
+		return errors.New("msg-debug-log-rate can't be negative")
+	}
+	if cfg.MsgDebugLogBurst < 0 {
+		return errors.New("msg-debug-log-burst can't be negative")
+	}
+	if cfg.MsgDebugLogSummaryInterval < 0 {
+		return errors.New("msg-debug-log-summary-interval can't be negative")
+	}
+	if cfg.EventBusPublishTimeout < 0 {
+		return errors.New("event-bus-publish-timeout can't be negative")
+	}
+	if cfg.EventBusPublishTimeout > 0 && cfg.EventBusBreakerThreshold <= 0 {
+		return errors.New("event-bus-breaker-threshold must be positive when the event bus publish timeout is enabled")
+	}
+	if cfg.EventBusBreakerCooldown < 0 {
+		return errors.New("event-bus-breaker-cooldown can't be negative")
+	}
+	if cfg.PrecommitPrevoteFraction < 0 || cfg.PrecommitPrevoteFraction > 1 {
+		return errors.New("precommit-prevote-fraction must be between 0 and 1")
+	}
+	if cfg.PrecommitPrevoteWait < 0 {
+		return errors.New("precommit-prevote-wait can't be negative")
+	}
+	if cfg.ProposeGrace < 0 {
+		return errors.New("propose-grace can't be negative")
+	}
+	if cfg.SignerSkewTolerance < 0 {
+		return errors.New("signer-skew-tolerance can't be negative")
+	}
+	if cfg.SignerSkewLimit < 0 {
+		return errors.New("signer-skew-limit can't be negative")
+	}
+	if cfg.SignerSkewLimit > 0 && cfg.SignerSkewLimit < cfg.SignerSkewTolerance {
+		return errors.New("signer-skew-limit can't be less than signer-skew-tolerance")
+	}
+	if cfg.PeerStatsMaxPeers < 0 {
+		return errors.New("peer-stats-max-peers can't be negative")
+	}
+	if cfg.LockHoldWarnThreshold < 0 {
+		return errors.New("lock-hold-warn-threshold can't be negative")
+	}
+	if cfg.BypassCommitTimeoutMinPeers < 0 {
+		return errors.New("bypass-commit-timeout-min-peers can't be negative")
+	}
+	switch cfg.MissingValidatorsPowerMetrics {
+	case "", MissingValidatorsPowerAll, MissingValidatorsPowerChanged, MissingValidatorsPowerNone:
+	default:
+		return fmt.Errorf("unknown missing-validators-power-metrics %q", cfg.MissingValidatorsPowerMetrics)
+	}
+	if cfg.BlockPartCodec != "" {
+		if _, ok := types.BlockPartCodecByName(cfg.BlockPartCodec); !ok {
+			return fmt.Errorf("unknown block-part-codec %q", cfg.BlockPartCodec)
+		}
+	}
 	return nil
 }
 
@@ -122,6 +122,71 @@ func TestConsensusConfig_ValidateBasic(t *testing.T) {
 		"PeerQueryMaj23SleepDuration":                {func(c *ConsensusConfig) { c.PeerQueryMaj23SleepDuration = time.Second }, false},
 		"PeerQueryMaj23SleepDuration negative":       {func(c *ConsensusConfig) { c.PeerQueryMaj23SleepDuration = -1 }, true},
 		"DoubleSignCheckHeight negative":             {func(c *ConsensusConfig) { c.DoubleSignCheckHeight = -1 }, true},
+		"AcknowledgeRestartAtHeight":                 {func(c *ConsensusConfig) { c.AcknowledgeRestartAtHeight = 10 }, false},
+		"AcknowledgeRestartAtHeight negative":        {func(c *ConsensusConfig) { c.AcknowledgeRestartAtHeight = -1 }, true},
+		"WatchdogTimeout":                            {func(c *ConsensusConfig) { c.WatchdogTimeout = time.Second }, false},
+		"WatchdogTimeout negative":                   {func(c *ConsensusConfig) { c.WatchdogTimeout = -1 }, true},
+		"BlockReconstructionSoftLimit":               {func(c *ConsensusConfig) { c.BlockReconstructionSoftLimit = time.Second }, false},
+		"BlockReconstructionSoftLimit negative":      {func(c *ConsensusConfig) { c.BlockReconstructionSoftLimit = -1 }, true},
+		"ProposerBlacklistThreshold":                 {func(c *ConsensusConfig) { c.ProposerBlacklistThreshold = 3 }, false},
+		"ProposerBlacklistThreshold negative":        {func(c *ConsensusConfig) { c.ProposerBlacklistThreshold = -1 }, true},
+		"ProposerBlacklistHeights negative":          {func(c *ConsensusConfig) { c.ProposerBlacklistHeights = -1 }, true},
+		"ProposerBlacklistHeights zero when enabled": {func(c *ConsensusConfig) { c.ProposerBlacklistThreshold = 3; c.ProposerBlacklistHeights = 0 }, true},
+		"TraceSampleInterval":                        {func(c *ConsensusConfig) { c.TraceSampleInterval = 10 }, false},
+		"TraceSampleInterval negative":               {func(c *ConsensusConfig) { c.TraceSampleInterval = -1 }, true},
+		"AbsenteeGracePeriod":                        {func(c *ConsensusConfig) { c.AbsenteeGracePeriod = time.Second }, false},
+		"AbsenteeGracePeriod negative":               {func(c *ConsensusConfig) { c.AbsenteeGracePeriod = -1 }, true},
+		"MaxStepsPerSecond":                          {func(c *ConsensusConfig) { c.MaxStepsPerSecond = 100 }, false},
+		"MaxStepsPerSecond negative":                 {func(c *ConsensusConfig) { c.MaxStepsPerSecond = -1 }, true},
+		"DecisionLogPath":                            {func(c *ConsensusConfig) { c.DecisionLogPath = "data/decisions.jsonl" }, false},
+		"DecisionLogMaxSize negative":                {func(c *ConsensusConfig) { c.DecisionLogMaxSize = -1 }, true},
+		"DecisionLogMaxSize zero when enabled":       {func(c *ConsensusConfig) { c.DecisionLogPath = "decisions"; c.DecisionLogMaxSize = 0 }, true},
+		"EventRingSize negative":                     {func(c *ConsensusConfig) { c.EventRingSize = -1 }, true},
+		"EventRingSize zero when enabled":            {func(c *ConsensusConfig) { c.EventRingPath = "events"; c.EventRingSize = 0 }, true},
+		"BlockGossipProgressThresholds empty":        {func(c *ConsensusConfig) { c.BlockGossipProgressThresholds = nil }, false},
+		"BlockGossipProgressThresholds 100":          {func(c *ConsensusConfig) { c.BlockGossipProgressThresholds = []int{50, 100} }, true},
+		"BlockGossipProgressThresholds unordered":    {func(c *ConsensusConfig) { c.BlockGossipProgressThresholds = []int{50, 25} }, true},
+		"PrecommitExclusionWindow disabled":          {func(c *ConsensusConfig) { c.PrecommitExclusionWindow = 0 }, false},
+		"PrecommitExclusionWindow negative":          {func(c *ConsensusConfig) { c.PrecommitExclusionWindow = -1 }, true},
+		"PrecommitExclusionThreshold zero":           {func(c *ConsensusConfig) { c.PrecommitExclusionThreshold = 0 }, false},
+		"PrecommitExclusionThreshold negative":       {func(c *ConsensusConfig) { c.PrecommitExclusionThreshold = -0.1 }, true},
+		"PrecommitExclusionThreshold one":            {func(c *ConsensusConfig) { c.PrecommitExclusionThreshold = 1 }, true},
+		"BlockPartCodec flate":                       {func(c *ConsensusConfig) { c.BlockPartCodec = "flate" }, false},
+		"BlockPartCodec unknown":                     {func(c *ConsensusConfig) { c.BlockPartCodec = "lz5" }, true},
+		"ProposerHistoryHeights disabled":            {func(c *ConsensusConfig) { c.ProposerHistoryHeights = 0 }, false},
+		"ProposerHistoryHeights negative":            {func(c *ConsensusConfig) { c.ProposerHistoryHeights = -1 }, true},
+		"MaxProposalEvidenceFraction zero":           {func(c *ConsensusConfig) { c.MaxProposalEvidenceFraction = 0 }, false},
+		"MaxProposalEvidenceFraction negative":       {func(c *ConsensusConfig) { c.MaxProposalEvidenceFraction = -0.5 }, true},
+		"MaxProposalEvidenceFraction above one":      {func(c *ConsensusConfig) { c.MaxProposalEvidenceFraction = 1.5 }, true},
+		"MissingValidatorsPowerMetrics changed":      {func(c *ConsensusConfig) { c.MissingValidatorsPowerMetrics = MissingValidatorsPowerChanged }, false},
+		"MissingValidatorsPowerMetrics unknown":      {func(c *ConsensusConfig) { c.MissingValidatorsPowerMetrics = "some" }, true},
+		"SignerSkewTolerance negative":               {func(c *ConsensusConfig) { c.SignerSkewTolerance = -time.Second }, true},
+		"SignerSkewLimit":                            {func(c *ConsensusConfig) { c.SignerSkewLimit = time.Second }, false},
+		"SignerSkewLimit negative":                   {func(c *ConsensusConfig) { c.SignerSkewLimit = -time.Second }, true},
+		"SignerSkewLimit below tolerance":            {func(c *ConsensusConfig) { c.SignerSkewLimit = time.Millisecond }, true},
+		"PeerStatsMaxPeers disabled":                 {func(c *ConsensusConfig) { c.PeerStatsMaxPeers = 0 }, false},
+		"PeerStatsMaxPeers negative":                 {func(c *ConsensusConfig) { c.PeerStatsMaxPeers = -1 }, true},
+		"LockHoldWarnThreshold disabled":             {func(c *ConsensusConfig) { c.LockHoldWarnThreshold = 0 }, false},
+		"LockHoldWarnThreshold negative":             {func(c *ConsensusConfig) { c.LockHoldWarnThreshold = -time.Second }, true},
+		"BypassCommitTimeoutMinPeers":                {func(c *ConsensusConfig) { c.BypassCommitTimeoutMinPeers = 2 }, false},
+		"BypassCommitTimeoutMinPeers negative":       {func(c *ConsensusConfig) { c.BypassCommitTimeoutMinPeers = -1 }, true},
+		"TimeoutScalingCoefficient":                  {func(c *ConsensusConfig) { c.TimeoutScalingCoefficient = 0.5 }, false},
+		"TimeoutScalingCoefficient negative":         {func(c *ConsensusConfig) { c.TimeoutScalingCoefficient = -0.5 }, true},
+		"TimeoutScalingBaseValidators negative":      {func(c *ConsensusConfig) { c.TimeoutScalingBaseValidators = -1 }, true},
+		"TimeoutScalingBaseValidators zero":          {func(c *ConsensusConfig) { c.TimeoutScalingCoefficient, c.TimeoutScalingBaseValidators = 0.5, 0 }, true},
+		"PrecommitPrevoteFraction":                   {func(c *ConsensusConfig) { c.PrecommitPrevoteFraction = 0.85 }, false},
+		"PrecommitPrevoteFraction above one":         {func(c *ConsensusConfig) { c.PrecommitPrevoteFraction = 1.5 }, true},
+		"PrecommitPrevoteWait negative":              {func(c *ConsensusConfig) { c.PrecommitPrevoteWait = -time.Second }, true},
+		"ProposeGrace negative":                      {func(c *ConsensusConfig) { c.ProposeGrace = -time.Second }, true},
+		"SignRequestHistory negative":                {func(c *ConsensusConfig) { c.SignRequestHistory = -1 }, true},
+		"VoteExtensionMemorySoftCap negative":        {func(c *ConsensusConfig) { c.VoteExtensionMemorySoftCap = -1 }, true},
+		"MsgDebugLogRate negative":                   {func(c *ConsensusConfig) { c.MsgDebugLogRate = -1 }, true},
+		"MsgDebugLogBurst negative":                  {func(c *ConsensusConfig) { c.MsgDebugLogBurst = -1 }, true},
+		"MsgDebugLogSummaryInterval negative":        {func(c *ConsensusConfig) { c.MsgDebugLogSummaryInterval = -time.Second }, true},
+		"EventBusPublishTimeout":                     {func(c *ConsensusConfig) { c.EventBusPublishTimeout, c.EventBusBreakerThreshold = time.Second, 5 }, false},
+		"EventBusPublishTimeout negative":            {func(c *ConsensusConfig) { c.EventBusPublishTimeout = -time.Second }, true},
+		"EventBusBreakerThreshold zero":              {func(c *ConsensusConfig) { c.EventBusPublishTimeout, c.EventBusBreakerThreshold = time.Second, 0 }, true},
+		"EventBusBreakerCooldown negative":           {func(c *ConsensusConfig) { c.EventBusBreakerCooldown = -time.Second }, true},
 	}
 	for desc, tc := range testcases {
 		tc := tc // appease linter
@@ -489,6 +489,20 @@ wal-file = "{{ js .Consensus.WalPath }}"
 # So, validators should stop the state machine, wait for some blocks, and then restart the state machine to avoid panic.
 double-sign-check-height = {{ .Consensus.DoubleSignCheckHeight }}
 
+# Height of the most recent signature of the consensus key found by the double
+# sign check that the node may start despite, as when a validator is restored
+# from a recent snapshot with the same key. The node still refuses to start if
+# the signature found is at another height. The acknowledgment is recorded in
+# the WAL. Set to 0, the default, to acknowledge none.
+acknowledge-restart-at-height = {{ .Consensus.AcknowledgeRestartAtHeight }}
+
+# Stop signing once a vote of the consensus key conflicting with one it already
+# signed is received, as another instance of this validator may be running.
+# The node keeps following consensus without proposing or voting, also after a
+# restart, until the conflicting_self_vote.json marker file written next to the
+# WAL is removed. Enabled by default; it only applies to validators.
+halt-on-conflicting-self-vote = {{ .Consensus.HaltOnConflictingSelfVote }}
+
 # EmptyBlocks mode and possible interval between empty blocks
 create-empty-blocks = {{ .Consensus.CreateEmptyBlocks }}
 create-empty-blocks-interval = "{{ .Consensus.CreateEmptyBlocksInterval }}"
@@ -496,10 +510,213 @@ create-empty-blocks-interval = "{{ .Consensus.CreateEmptyBlocksInterval }}"
 # Only gossip hashes, not the actual data
 gossip-tx-key-only = "{{ .Consensus.GossipTransactionKeyOnly }}"
 
+# How long rebuilding a proposal block from the mempool may take when only
+# hashes are gossiped before a warning is logged. Rebuilding is abandoned once
+# the propose timeout of the round has passed, but is always given at least
+# this long.
+block-reconstruction-soft-limit = "{{ .Consensus.BlockReconstructionSoftLimit }}"
+
+# Directory the committed blocks rebuilt from the mempool when only hashes are
+# gossiped are dumped to, along with the block their stored parts encode, when
+# the two serialize differently, e.g. "data/rebuild-audit". The dumps are not
+# removed. Leave empty, the default, to disable the audit.
+rebuild-audit-dir = "{{ js .Consensus.RebuildAuditPath }}"
+
 # Reactor sleep duration parameters
 peer-gossip-sleep-duration = "{{ .Consensus.PeerGossipSleepDuration }}"
 peer-query-maj23-sleep-duration = "{{ .Consensus.PeerQueryMaj23SleepDuration }}"
 
+# How long the consensus state machine may go without processing a message
+# while messages are pending before it is reported as stalled, e.g. "60s".
+# Set to 0, the default, to disable the watchdog.
+watchdog-timeout = "{{ .Consensus.WatchdogTimeout }}"
+
+# Number of consecutive proposals of a validator rejected by the application
+# after which its new proposals are prevoted nil as soon as they are received,
+# without waiting for the proposal timeout. Proposals re-proposing a block that
+# had +2/3 prevotes are never affected. Set to 0, the default, to disable.
+proposer-blacklist-threshold = {{ .Consensus.ProposerBlacklistThreshold }}
+
+# Number of heights a proposer stays blacklisted for.
+proposer-blacklist-heights = {{ .Consensus.ProposerBlacklistHeights }}
+
+# Fully trace only one in trace-sample-interval heights. The other heights
+# only get the span covering the height. Set to 1 to trace every height.
+trace-sample-interval = {{ .Consensus.TraceSampleInterval }}
+
+# How long after the start of a round a validator that has not voted in the
+# height is reported as absent.
+absentee-grace-period = "{{ .Consensus.AbsenteeGracePeriod }}"
+
+# Number of state machine steps per second above which the processing of peer
+# messages is delayed until the next second. Messages of this node and
+# timeouts are never delayed. Set to 0, the default, to disable the limit.
+max-steps-per-second = {{ .Consensus.MaxStepsPerSecond }}
+
+# File the decisions of the consensus state machine are logged to, one JSON
+# record per line: proposals accepted or rejected, prevotes and precommits
+# with their reason, locks, new rounds and commits. Records are dropped rather
+# than slowing consensus down. Leave empty, the default, to disable the
+# decision log.
+decision-log-file = "{{ js .Consensus.DecisionLogPath }}"
+
+# Maximum total size in bytes of the decision log files. The oldest files are
+# removed once it is exceeded.
+decision-log-max-size = {{ .Consensus.DecisionLogMaxSize }}
+
+# File a compact record of the events published by the consensus state, such
+# as its steps, polkas, locks and commits, is written to, for their inspection
+# after a crash. The file holds the last event-ring-size records, the oldest
+# being overwritten. Records are dropped rather than slowing consensus down.
+# Leave empty, the default, to disable the event ring.
+event-ring-file = "{{ js .Consensus.EventRingPath }}"
+event-ring-size = {{ .Consensus.EventRingSize }}
+
+# Number of the last proposals and votes this node asked its private validator
+# to sign whose exact sign-bytes and signatures are kept in memory for signer
+# audits. The sign-bytes reveal the timing of the votes. Set to 0, the default,
+# to disable.
+sign-request-history = {{ .Consensus.SignRequestHistory }}
+
+# File the sign requests are also appended to, one JSON record per line. Leave
+# empty, the default, to disable the sign request log.
+sign-request-log-file = "{{ js .Consensus.SignRequestLogPath }}"
+
+# Size in bytes of the vote extensions held in the vote sets of a height above
+# which a warning is logged, once per height, with the validators whose
+# extensions take the most. Set to 0, the default, to disable the warning.
+vote-extension-memory-soft-cap = {{ .Consensus.VoteExtensionMemorySoftCap }}
+
+# Percentages of the parts of a proposal block received at which a block
+# gossip progress event is published, in increasing order. Events are also
+# published when the gossip starts and once the block is complete.
+block-gossip-progress-thresholds = [{{ range $i, $e := .Consensus.BlockGossipProgressThresholds }}{{if $i}}, {{end}}{{ $e }}{{end}}]
+
+# Number of heights over which the rate of our precommits for committed blocks
+# that were excluded from their commit is computed. An event is published once
+# the rate exceeds precommit-exclusion-threshold. Set to 0 to disable.
+precommit-exclusion-window = {{ .Consensus.PrecommitExclusionWindow }}
+
+# Rate of our precommits excluded from the commit over the window, between 0
+# and 1, above which an event is published.
+precommit-exclusion-threshold = {{ .Consensus.PrecommitExclusionThreshold }}
+
+# Codec the blocks proposed by this node are compressed by before being split
+# into block parts, such as "flate". Block parts compressed by another codec are
+# rejected, by consensus and block sync alike. The block IDs commit to the
+# compressed parts, so all the nodes of a network must use the same codec. Leave
+# empty, the default, to disable compression.
+block-part-codec = "{{ .Consensus.BlockPartCodec }}"
+
+# Number of most recent heights whose proposer and its proposer priority are
+# recorded, to debug the fairness of the proposer rotation, e.g. 100. Set to 0,
+# the default, to disable.
+proposer-history-heights = {{ .Consensus.ProposerHistoryHeights }}
+
+# Fraction of the block max bytes, between 0 and 1, the evidence of the blocks
+# proposed by this node may take, on top of the evidence max bytes of the
+# consensus params. The evidence above it is left out of the blocks. Set to 0,
+# the default, to set no ceiling other than the consensus params.
+max-proposal-evidence-fraction = {{ .Consensus.MaxProposalEvidenceFraction }}
+
+# Tally, per validator, the precommits for the previous height accepted into
+# the last commit during the timeoutCommit window or ignored after it.
+last-commit-validator-metrics = {{ .Consensus.LastCommitValidatorMetrics }}
+
+# Per-validator gauges of the missing validators power updated at each height,
+# in the background. Options:
+#   1) "all" (default) - the gauges of all the validators
+#   2) "changed" - the gauges of the validators whose signing of the last
+#      commit changed since the previous height
+#   3) "none" - no gauge, only the aggregates
+missing-validators-power-metrics = "{{ .Consensus.MissingValidatorsPowerMetrics }}"
+
+# Difference between the timestamp of a vote of this node before and after its
+# signing, by a remote signer normalizing or re-stamping the times, above which
+# it is logged and counted.
+signer-skew-tolerance = "{{ .Consensus.SignerSkewTolerance }}"
+
+# Difference between the timestamp of a vote of this node before and after its
+# signing above which the vote is signed again, once, and then dropped rather
+# than broadcast, since the other nodes may reject it. Set to 0, the default,
+# to disable.
+signer-skew-limit = "{{ .Consensus.SignerSkewLimit }}"
+
+# Number of peers whose consensus messages are counted per outcome (received,
+# written to the WAL, added, duplicate, invalid) for the current height. The
+# least recently heard from peer is evicted beyond it. Set to 0 to disable.
+peer-stats-max-peers = {{ .Consensus.PeerStatsMaxPeers }}
+
+# Export the counts of the consensus messages of each peer as metrics.
+peer-stats-metrics = {{ .Consensus.PeerStatsMetrics }}
+
+# Time the consensus state lock can be held by an operation (handling a message
+# or a timeout, committing a block) above which a warning is logged, e.g.
+# "100ms". Set to 0, the default, to disable.
+lock-hold-warn-threshold = "{{ .Consensus.LockHoldWarnThreshold }}"
+
+# Number of distinct peers the precommits of a round must have been received
+# from for the commit timeout to be bypassed, when the bypass-commit-timeout
+# consensus parameter is set, once all the precommits are received. The bypass
+# is also allowed if one of the precommits is this node's. Set to 0, the
+# default, to disable.
+bypass-commit-timeout-min-peers = {{ .Consensus.BypassCommitTimeoutMinPeers }}
+
+# Abort the start when a timeout replayed from the WAL lasted otherwise than
+# the current timeout configuration computes, which means the timeouts were
+# reconfigured since the WAL was written and the replay may not follow the
+# original transitions. Otherwise a warning is logged.
+strict-replay-timeouts = {{ .Consensus.StrictReplayTimeouts }}
+
+# Scale the propose and vote timeouts with the size of the validator set:
+# their base is multiplied by 1 + k*ln(n/n0), where k is the coefficient, n the
+# number of validators and n0 the base number of validators. The timeouts of
+# sets of at most n0 validators are not scaled, nor are the unsafe timeout
+# overrides below. A coefficient of 0, the default, disables the scaling.
+timeout-scaling-coefficient = {{ .Consensus.TimeoutScalingCoefficient }}
+timeout-scaling-base-validators = {{ .Consensus.TimeoutScalingBaseValidators }}
+
+# Local policy delaying the precommit of this node for a block until the
+# prevotes of this fraction of the voting power are received, rather than
+# precommitting as soon as +2/3 prevoted for it, for at most the wait below.
+# The delay never exceeds the vote timeout of the round, and the protocol is
+# unchanged. A fraction of 0, the default, disables it.
+precommit-prevote-fraction = {{ .Consensus.PrecommitPrevoteFraction }}
+# Longest delay of the precommit for the fraction above. Set to 0 to bound it by
+# the vote timeout only.
+precommit-prevote-wait = "{{ .Consensus.PrecommitPrevoteWait }}"
+
+# Extension of the propose timeout of a round, once, when the prevote of its
+# proposer for a block is received before its proposal, which is then
+# solicited from the peers that have the block. The extension never exceeds the
+# vote timeout of the round. Set to 0, the default, to disable it.
+propose-grace = "{{ .Consensus.ProposeGrace }}"
+
+# Validate the block committed by +2/3 precommits again before applying it,
+# rather than reusing the outcome of its validation earlier in the height.
+revalidate-committed-blocks = {{ .Consensus.RevalidateCommittedBlocks }}
+
+# The number of debug lines per second logged for each type of peer message
+# and outcome, such as the votes added, past a burst of msg-debug-log-burst
+# lines. The lines over the rate are counted and their number is logged every
+# msg-debug-log-summary-interval, e.g. 10. Set to 0, the default, to log every
+# message.
+msg-debug-log-rate = {{ .Consensus.MsgDebugLogRate }}
+msg-debug-log-burst = {{ .Consensus.MsgDebugLogBurst }}
+msg-debug-log-summary-interval = "{{ .Consensus.MsgDebugLogSummaryInterval }}"
+
+# Time the consensus state waits for the event bus to accept an event, when its
+# subscribers (e.g. websocket clients) are overloaded, before giving up on it.
+# After event-bus-breaker-threshold consecutive failed or timed out publishes,
+# the events are skipped for event-bus-breaker-cooldown, then a publish probes
+# the event bus again. The events given up on or skipped while the breaker is
+# open are lost for the RPC subscribers, the indexer and the mempool. The
+# consensus reactor is not affected. Set to 0, the default, to disable the
+# timeout and the breaker.
+event-bus-publish-timeout = "{{ .Consensus.EventBusPublishTimeout }}"
+event-bus-breaker-threshold = {{ .Consensus.EventBusBreakerThreshold }}
+event-bus-breaker-cooldown = "{{ .Consensus.EventBusBreakerCooldown }}"
+
 ### Unsafe Timeout Overrides ###
 
 # These fields provide temporary overrides for the Timeout consensus parameters.
@@ -91,6 +91,8 @@ type Reactor struct {
 
 	metrics  *consensus.Metrics
 	eventBus *eventbus.EventBus
+	// the codec of the block parts accepted besides uncompressed ones
+	blockPartCodec types.BlockPartCodec
 
 	syncStartTime time.Time
 
@@ -113,6 +115,7 @@ func NewReactor(
 	blockSync bool,
 	metrics *consensus.Metrics,
 	eventBus *eventbus.EventBus,
+	blockPartCodec types.BlockPartCodec,
 	restartCh chan struct{},
 	selfRemediationConfig *config.SelfRemediationConfig,
 ) *Reactor {
@@ -127,6 +130,7 @@ func NewReactor(
 		peerManager:               peerManager,
 		metrics:                   metrics,
 		eventBus:                  eventBus,
+		blockPartCodec:            blockPartCodec,
 		restartCh:                 restartCh,
 		lastRestartTime:           time.Now(),
 		blocksBehindThreshold:     selfRemediationConfig.BlocksBehindThreshold,
@@ -624,7 +628,12 @@ func (r *Reactor) poolRoutine(ctx context.Context, stateSynced bool, blockSyncCh
 			// try again quickly next loop
 			didProcessCh <- struct{}{}
 
-			firstParts, err := first.MakePartSet(types.BlockPartSizeBytes)
+			// the parts the commit commits to may be compressed by the block
+			// part codec; if none matches, the commit is not verified below
+			firstParts, err := first.MakePartSetMatching(types.BlockPartSizeBytes, second.LastCommit.BlockID.PartSetHeader, r.blockPartCodec)
+			if err != nil {
+				firstParts, err = first.MakePartSet(types.BlockPartSizeBytes)
+			}
 			if err != nil {
 				r.logger.Error("failed to make ",
 					"height", first.Height,
@@ -651,7 +660,7 @@ func (r *Reactor) poolRoutine(ctx context.Context, stateSynced bool, blockSyncCh
 			}
 			if err == nil && state.ConsensusParams.ABCI.VoteExtensionsEnabled(first.Height) {
 				// if vote extensions were required at this height, ensure they exist.
-				err = extCommit.EnsureExtensions()
+				err = extCommit.EnsureQuorumExtensions(state.Validators)
 			}
 			// If either of the checks failed we log the error and request for a new block
 			// at that height
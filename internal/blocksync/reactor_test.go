@@ -163,6 +163,7 @@ func makeReactor(
 		true,
 		consensus.NopMetrics(),
 		nil, // eventbus, can be nil
+		nil,
 		restartChan,
 		selfRemediationConfig,
 	)
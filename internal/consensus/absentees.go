@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Absentees are the validators that have signed neither a prevote nor a
+// precommit in a height.
+type Absentees struct {
+	Height int64
+	Round  int32
+
+	// Validators are the addresses of the absent validators, in the order of
+	// the validator set.
+	Validators []types.Address
+	// Power is the total voting power of the absent validators.
+	Power int64
+}
+
+// absenteeTracker computes the absentees of the current height after the
+// prevote step of each round. A validator is only reported once the grace
+// period from the start of the round has elapsed, so that a vote arriving
+// slightly late does not flag it.
+type absenteeTracker struct {
+	height     int64
+	roundStart time.Time
+
+	// pending is set when the absentees were computed before the end of the
+	// grace period; they are computed again as votes arrive.
+	pending bool
+	absent  map[int32]bool
+	report  Absentees
+}
+
+// startAbsenteeRound records the start of a round, and resets the absentees
+// on a new height.
+func (cs *State) startAbsenteeRound(height int64) {
+	at := &cs.absentees
+	if at.height != height {
+		*at = absenteeTracker{height: height, report: Absentees{Height: height}}
+		cs.metrics.AbsentValidators.Set(0)
+		cs.metrics.AbsentValidatorsPower.Set(0)
+	}
+	at.roundStart = tmtime.Now()
+	at.pending = false
+}
+
+// updateAbsentees computes the validators that have not voted in any round
+// of the current height, unless the grace period has not elapsed yet.
+func (cs *State) updateAbsentees() {
+	at := &cs.absentees
+	if at.height != cs.roundState.Height() {
+		return
+	}
+	if tmtime.Now().Sub(at.roundStart) < cs.config.AbsenteeGracePeriod {
+		at.pending = true
+		return
+	}
+	at.pending = false
+
+	votes := cs.roundState.Votes()
+	report := Absentees{Height: at.height, Round: cs.roundState.Round()}
+	absent := make(map[int32]bool)
+	for i, val := range cs.roundState.Validators().Validators {
+		idx := int32(i)
+		if hasVotedInHeight(votes, idx) {
+			continue
+		}
+		absent[idx] = true
+		report.Validators = append(report.Validators, val.Address)
+		report.Power += val.VotingPower
+	}
+	at.absent = absent
+	at.report = report
+
+	cs.metrics.AbsentValidators.Set(float64(len(report.Validators)))
+	cs.metrics.AbsentValidatorsPower.Set(float64(report.Power))
+}
+
+// markAbsenteeVote updates the absentees when a vote of the current height
+// may change them.
+func (cs *State) markAbsenteeVote(vote *types.Vote) {
+	at := &cs.absentees
+	if vote.Height == at.height && (at.pending || at.absent[vote.ValidatorIndex]) {
+		cs.updateAbsentees()
+	}
+}
+
+// hasVotedInHeight returns whether the validator at idx has a prevote or a
+// precommit in any round tracked by votes.
+func hasVotedInHeight(votes *cstypes.HeightVoteSet, idx int32) bool {
+	for round := int32(0); round <= votes.Round()+1; round++ {
+		if votes.Prevotes(round).GetByIndex(idx) != nil || votes.Precommits(round).GetByIndex(idx) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAbsentees returns the validators that have not voted in the current
+// height, as of the latest prevote step past the grace period.
+func (cs *State) GetAbsentees() Absentees {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	report := cs.absentees.report
+	report.Validators = append([]types.Address(nil), report.Validators...)
+	return report
+}
@@ -0,0 +1,45 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// lastApplied is the last height whose block was applied, and the app hash
+// after applying it. It is read without the State mutex, which is held while
+// blocks are applied.
+type lastApplied struct {
+	mtx     sync.RWMutex
+	height  int64
+	appHash []byte
+}
+
+func (la *lastApplied) set(height int64, appHash []byte) {
+	la.mtx.Lock()
+	defer la.mtx.Unlock()
+	la.height = height
+	la.appHash = appHash
+}
+
+// LastApplied returns the last height whose block was applied and the app hash
+// after applying it. Once it returns a height, the results of the height are
+// available.
+func (cs *State) LastApplied() (height int64, appHash []byte) {
+	cs.lastApplied.mtx.RLock()
+	defer cs.lastApplied.mtx.RUnlock()
+	return cs.lastApplied.height, append([]byte(nil), cs.lastApplied.appHash...)
+}
+
+// publishBlockApplied signals that block was applied, resulting in appHash.
+func (cs *State) publishBlockApplied(block *types.Block, appHash []byte, applyTime time.Duration) {
+	if err := cs.publishEvent(types.EventBlockAppliedValue, types.EventDataBlockApplied{
+		Height:        block.Height,
+		BlockHash:     block.Hash(),
+		AppHash:       appHash,
+		ApplyDuration: applyTime,
+	}); err != nil {
+		cs.logger.Error("failed publishing block applied", "height", block.Height, "err", err)
+	}
+}
@@ -0,0 +1,24 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockFinality returns the time from the timestamp of block, set by its
+// proposer, to its application completing locally at appliedAt. The time is
+// observed by the BlockFinalityLatency metric, unless the clock of the
+// proposer is ahead of ours: the time would be negative, and is counted by
+// BlockFinalityClockSkew and clamped to zero instead.
+func (cs *State) blockFinality(block *types.Block, appliedAt time.Time) time.Duration {
+	finality := appliedAt.Sub(block.Time)
+	if finality < 0 {
+		cs.metrics.BlockFinalityClockSkew.Add(1)
+		cs.logger.Debug("block timestamp ahead of the local clock", "height", block.Height,
+			"block_time", block.Time, "applied_at", appliedAt)
+		return 0
+	}
+	cs.metrics.BlockFinalityLatency.Observe(finality.Seconds())
+	return finality
+}
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmtimemocks "github.com/tendermint/tendermint/libs/time/mocks"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBlockFinality(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := new(tmtimemocks.Source)
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 4, options: []StateOption{WithClock(clock)}})
+	latencies := generic.NewHistogram("block_finality_latency", 2)
+	skews := newLabeledCounter()
+	cs.metrics.BlockFinalityLatency = latencies
+	cs.metrics.BlockFinalityClockSkew = skews
+	cs.decisionLog = &decisionLog{records: make(chan DecisionRecord, 100)}
+	sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryHeightSummary,
+	})
+	require.NoError(t, err)
+
+	// vss[1] proposes in round 1 a block applied 1.5s after its timestamp
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, types.Txs{types.Tx("a=1")}, created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+	const finality = 1500 * time.Millisecond
+	clock.On("Now").Return(block.Time.Add(finality))
+
+	send := func(msg Message) {
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+
+	// round 0 fails, the other validators precommitting nil
+	cs.enterNewRound(ctx, height, 0, "test")
+	for _, vs := range vss[1:] {
+		send(&VoteMessage{signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), types.BlockID{})})
+	}
+	if cs.roundState.Round() == 0 {
+		cs.enterNewRound(ctx, height, round, "test")
+	}
+	require.Equal(t, round, cs.roundState.Round())
+
+	// and the block is committed in round 1
+	send(&ProposalMessage{proposal})
+	for i := 0; i < int(parts.Total()); i++ {
+		send(&BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)})
+	}
+	incrementRound(vss[1:]...)
+	for _, vs := range vss[1:] {
+		send(&VoteMessage{signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), blockID)})
+	}
+	require.Equal(t, height, cs.blockStore.Height())
+
+	nextCtx, nextCancel := context.WithTimeout(ctx, ensureTimeout)
+	defer nextCancel()
+	msg, err := sub.Next(nextCtx)
+	require.NoError(t, err)
+	summary := msg.Data().(types.EventDataHeightSummary)
+	require.Equal(t, height, summary.Height)
+	require.EqualValues(t, 2, summary.Rounds)
+	require.Equal(t, finality, summary.Finality)
+	require.Equal(t, finality.Seconds(), latencies.Quantile(0.5))
+	require.Empty(t, skews.values)
+
+	var commit *DecisionRecord
+	for len(cs.decisionLog.records) > 0 {
+		record := <-cs.decisionLog.records
+		if record.Kind == DecisionCommit {
+			commit = &record
+		}
+	}
+	require.NotNil(t, commit)
+	require.Equal(t, finality, commit.Timing.Finality)
+
+	// a block applied before its timestamp is clamped and counted apart
+	require.Zero(t, cs.blockFinality(block, block.Time.Add(-time.Second)))
+	require.Equal(t, map[string]float64{"": 1}, skews.values)
+	require.Equal(t, finality.Seconds(), latencies.Quantile(0.5))
+}
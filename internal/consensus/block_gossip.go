@@ -0,0 +1,111 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockGossipProgress tracks the block gossip events published in a round, so
+// that each is published once per round. It is accessed under the State
+// mutex.
+type blockGossipProgress struct {
+	height int64
+	round  int32
+
+	started bool
+	start   time.Time
+	// number of progress thresholds reached
+	thresholds int
+	complete   bool
+}
+
+// roundBlockGossip returns the block gossip progress of the current round.
+func (cs *State) roundBlockGossip() *blockGossipProgress {
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	if cs.blockGossip.height != height || cs.blockGossip.round != round {
+		cs.blockGossip = blockGossipProgress{height: height, round: round}
+	}
+	return &cs.blockGossip
+}
+
+// markBlockGossipStarted is called when we start expecting the parts of the
+// block of header.
+func (cs *State) markBlockGossipStarted(header types.PartSetHeader) {
+	cs.metrics.MarkBlockGossipStarted()
+
+	progress := cs.roundBlockGossip()
+	if progress.started {
+		return
+	}
+	progress.started = true
+	progress.start = time.Now()
+	cs.publishBlockGossip(types.EventDataBlockGossip{Stage: types.BlockGossipStarted, PartSetHeader: header})
+}
+
+// markBlockGossipProgress publishes the progress thresholds parts reached
+// and its completion, unless already published in the round.
+func (cs *State) markBlockGossipProgress(parts *types.PartSet) {
+	progress := cs.roundBlockGossip()
+	if progress.complete || parts.Total() == 0 {
+		return
+	}
+	header, count := parts.Header(), parts.Count()
+
+	percent := int(uint64(count) * 100 / uint64(parts.Total()))
+	thresholds := cs.config.BlockGossipProgressThresholds
+	for progress.thresholds < len(thresholds) && percent >= thresholds[progress.thresholds] {
+		cs.publishBlockGossip(types.EventDataBlockGossip{
+			Stage:         types.BlockGossipProgress,
+			PartSetHeader: header,
+			Parts:         count,
+			Percent:       thresholds[progress.thresholds],
+		})
+		progress.thresholds++
+	}
+
+	if !parts.IsComplete() {
+		return
+	}
+	progress.complete = true
+	var elapsed time.Duration
+	if progress.started {
+		elapsed = time.Since(progress.start)
+	}
+	cs.publishBlockGossip(types.EventDataBlockGossip{
+		Stage:         types.BlockGossipComplete,
+		PartSetHeader: header,
+		Parts:         count,
+		Percent:       100,
+		Elapsed:       elapsed,
+	})
+}
+
+func (cs *State) publishBlockGossip(event types.EventDataBlockGossip) {
+	event.Height, event.Round = cs.roundState.Height(), cs.roundState.Round()
+	if vals := cs.roundState.Validators(); vals != nil {
+		event.ProposerAddress = vals.GetProposer().Address
+	}
+	if err := cs.publishEvent(types.EventBlockGossipValue, event); err != nil {
+		cs.logger.Error("failed publishing block gossip", "stage", event.Stage, "err", err)
+	}
+}
+
+// resetBlockPartsMetrics resets the progress gauges of the download of the
+// proposal block parts when its part set is cleared.
+func (cs *State) resetBlockPartsMetrics() {
+	cs.metrics.PartsHave.Set(0)
+	cs.metrics.PartsTotal.Set(0)
+}
+
+// BlockPartsStatus returns the progress of the download of the proposal block
+// of the current round: the number of its parts received, its number of
+// parts, the bitmap of the parts received and their size in bytes. It returns
+// zeros if no part set is expected.
+func (cs *State) BlockPartsStatus() (have, total int, bitmap []byte, byteSize int64) {
+	parts := cs.GetRoundState().ProposalBlockParts
+	if parts == nil {
+		return 0, 0, nil, 0
+	}
+	return int(parts.Count()), int(parts.Total()), parts.BitArray().Bytes(), parts.ByteSize()
+}
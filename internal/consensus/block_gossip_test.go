@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBlockPartsStatus(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	// this node does not propose
+	cs.decideProposal = func(context.Context, int64, int32) {}
+	partsHave, partsTotal := generic.NewGauge("parts_have"), generic.NewGauge("parts_total")
+	cs.metrics.PartsHave, cs.metrics.PartsTotal = partsHave, partsTotal
+
+	// no part set is expected before a proposal
+	have, total, bitmap, byteSize := cs.BlockPartsStatus()
+	require.Zero(t, have)
+	require.Zero(t, total)
+	require.Nil(t, bitmap)
+	require.Zero(t, byteSize)
+
+	// vss[1] proposes a block of 4 parts in round 1
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, types.Txs{tmrand.Bytes(3 * int(types.BlockPartSizeBytes))},
+		created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, parts.Total())
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+
+	cs.enterNewRound(ctx, height, round, "test")
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer"}, false)
+	have, total, bitmap, byteSize = cs.BlockPartsStatus()
+	require.Zero(t, have)
+	require.Equal(t, 4, total)
+	require.Equal(t, []byte{0}, bitmap)
+	require.Zero(t, byteSize)
+
+	// the first and third parts are received
+	for _, index := range []int{0, 2} {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(index)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer"}, false)
+	}
+	have, total, bitmap, byteSize = cs.BlockPartsStatus()
+	require.Equal(t, 2, have)
+	require.Equal(t, 4, total)
+	require.Equal(t, []byte{0b101}, bitmap)
+	require.Equal(t, int64(len(parts.GetPart(0).Bytes)+len(parts.GetPart(2).Bytes)), byteSize)
+	require.Equal(t, 2.0, partsHave.Value())
+	require.Equal(t, 4.0, partsTotal.Value())
+	status := cs.Status()
+	require.Equal(t, have, status.BlockPartsHave)
+	require.Equal(t, total, status.BlockPartsTotal)
+	require.Equal(t, bitmap, status.BlockPartsBitmap)
+	require.Equal(t, byteSize, status.BlockPartsByteSize)
+
+	// the progress is reset in the next round
+	cs.enterNewRound(ctx, height, round+1, "test")
+	have, total, bitmap, byteSize = cs.BlockPartsStatus()
+	require.Zero(t, have)
+	require.Zero(t, total)
+	require.Nil(t, bitmap)
+	require.Zero(t, byteSize)
+	require.Zero(t, partsHave.Value())
+	require.Zero(t, partsTotal.Value())
+}
@@ -0,0 +1,94 @@
+package consensus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// BlockNeeded is fired on the internal event switch with
+// types.EventBlockNeededValue when the state machine commits a block it does
+// not have, so that the block can be requested from the peers that have it.
+type BlockNeeded struct {
+	Height  int64
+	Round   int32
+	BlockID types.BlockID
+
+	// Peers are the peers precommits for BlockID were received from, which
+	// are likely to have the block.
+	Peers []types.NodeID
+}
+
+// votePeers tracks the peers votes of a type for each block of a height were
+// received from.
+type votePeers struct {
+	height int64
+	peers  map[string]map[types.NodeID]struct{}
+}
+
+func (pp *votePeers) add(height int64, blockHash []byte, peerID types.NodeID) {
+	if pp.height != height || pp.peers == nil {
+		pp.height = height
+		pp.peers = make(map[string]map[types.NodeID]struct{})
+	}
+	peers, ok := pp.peers[string(blockHash)]
+	if !ok {
+		peers = make(map[types.NodeID]struct{})
+		pp.peers[string(blockHash)] = peers
+	}
+	peers[peerID] = struct{}{}
+}
+
+// get returns the peers votes for blockHash at height were received from,
+// sorted.
+func (pp *votePeers) get(height int64, blockHash []byte) []types.NodeID {
+	if pp.height != height {
+		return nil
+	}
+	peers := make([]types.NodeID, 0, len(pp.peers[string(blockHash)]))
+	for peerID := range pp.peers[string(blockHash)] {
+		peers = append(peers, peerID)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
+}
+
+// blockRecovery is a committed block this node is waiting for.
+type blockRecovery struct {
+	height  int64
+	blockID types.BlockID
+	start   time.Time
+}
+
+// recordPrecommitPeer records the peer a precommit of the current height for
+// a block was received from.
+func (cs *State) recordPrecommitPeer(vote *types.Vote, peerID types.NodeID) {
+	if peerID == "" || vote.BlockID.IsNil() {
+		return
+	}
+	cs.precommitPeers.add(vote.Height, vote.BlockID.Hash, peerID)
+}
+
+// fireBlockNeeded requests the committed block of blockID, which this node
+// does not have, and starts measuring the time until it is received.
+func (cs *State) fireBlockNeeded(height int64, round int32, blockID types.BlockID) {
+	cs.blockRecovery = &blockRecovery{height: height, blockID: blockID, start: time.Now()}
+	cs.evsw.FireEvent(types.EventBlockNeededValue, &BlockNeeded{
+		Height:  height,
+		Round:   round,
+		BlockID: blockID,
+		Peers:   cs.precommitPeers.get(height, blockID.Hash),
+	})
+}
+
+// markBlockRecovered records the time to receive the committed block of
+// height, if it was needed.
+func (cs *State) markBlockRecovered(height int64, block *types.Block) {
+	br := cs.blockRecovery
+	if br == nil || br.height != height || !block.HashesTo(br.blockID.Hash) {
+		return
+	}
+	cs.metrics.BlockRecoverySeconds.Observe(time.Since(br.start).Seconds())
+	cs.blockRecovery = nil
+}
@@ -0,0 +1,57 @@
+package consensus
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockPartCodec returns the codec of config.BlockPartCodec, nil if the
+// compression of block parts is disabled.
+func (cs *State) blockPartCodec() types.BlockPartCodec {
+	if cs.config.BlockPartCodec == "" {
+		return nil
+	}
+	codec, _ := types.BlockPartCodecByName(cs.config.BlockPartCodec)
+	return codec
+}
+
+// makeProposalBlockParts splits block proposed by this node into block parts,
+// compressed by the codec of config.BlockPartCodec.
+func (cs *State) makeProposalBlockParts(block *types.Block) (*types.PartSet, error) {
+	return block.MakePartSetWithCodec(types.BlockPartSizeBytes, cs.blockPartCodec())
+}
+
+// makeBlockPartsMatching returns the parts of block that header commits to,
+// made without compressing the block or compressed by the codec of
+// config.BlockPartCodec, the only block parts this node accepts.
+func (cs *State) makeBlockPartsMatching(block *types.Block, header types.PartSetHeader) (*types.PartSet, error) {
+	return block.MakePartSetMatching(types.BlockPartSizeBytes, header, cs.blockPartCodec())
+}
+
+// getBlockFromBlockParts decodes the block of the complete proposal block
+// parts.
+func (cs *State) getBlockFromBlockParts() (*types.Block, error) {
+	return cs.blockFromParts(cs.roundState.ProposalBlockParts())
+}
+
+// blockFromParts decodes the block of the complete parts. As by
+// makeBlockPartsMatching, parts compressed by another codec than the one of
+// config.BlockPartCodec are rejected.
+func (cs *State) blockFromParts(parts *types.PartSet) (*types.Block, error) {
+	bz, err := io.ReadAll(parts.GetReader())
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := types.BlockPartsCodec(bz)
+	if err != nil {
+		return nil, err
+	}
+	if own := cs.blockPartCodec(); codec != nil && (own == nil || own.ID() != codec.ID()) {
+		return nil, fmt.Errorf("proposal block parts are compressed by the %s codec, which is not the block-part-codec %q",
+			codec.Name(), cs.config.BlockPartCodec)
+	}
+	return types.BlockFromPartsBytes(bz, cs.state.ConsensusParams.Block.MaxBytes)
+}
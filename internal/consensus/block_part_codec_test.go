@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateCommitsCompressedBlockParts(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	cs.config.BlockPartCodec = "flate"
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height+1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the committed BlockIDs commit to the compressed parts, which the block
+	// store decompresses
+	for h := height; h <= height+1; h++ {
+		meta := cs.blockStore.LoadBlockMeta(h)
+		require.NotNil(t, meta)
+		block := cs.blockStore.LoadBlock(h)
+		require.NotNil(t, block)
+		require.Equal(t, meta.BlockID.Hash, block.Hash())
+
+		compressed, err := block.MakePartSetWithCodec(types.BlockPartSizeBytes, types.FlateBlockPartCodec{})
+		require.NoError(t, err)
+		require.True(t, compressed.HasHeader(meta.BlockID.PartSetHeader))
+	}
+}
+
+func TestStateRejectsBlockPartsOfAnotherCodec(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, block)
+	compressed, err := block.MakePartSetWithCodec(types.BlockPartSizeBytes, types.FlateBlockPartCodec{})
+	require.NoError(t, err)
+	cs.roundState.SetProposalBlockParts(compressed)
+
+	// a node without the codec rejects the compressed parts, whether
+	// received or rebuilt from the block
+	_, err = cs.getBlockFromBlockParts()
+	require.ErrorContains(t, err, "compressed by the flate codec")
+	_, err = cs.makeBlockPartsMatching(block, compressed.Header())
+	require.Error(t, err)
+
+	cs.config.BlockPartCodec = "flate"
+	decoded, err := cs.getBlockFromBlockParts()
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), decoded.Hash())
+	rebuilt, err := cs.makeBlockPartsMatching(block, compressed.Header())
+	require.NoError(t, err)
+	require.True(t, rebuilt.HasHeader(compressed.Header()))
+
+	// uncompressed parts are still accepted
+	uncompressed, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	cs.roundState.SetProposalBlockParts(uncompressed)
+	decoded, err = cs.getBlockFromBlockParts()
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), decoded.Hash())
+	rebuilt, err = cs.makeBlockPartsMatching(block, uncompressed.Header())
+	require.NoError(t, err)
+	require.True(t, rebuilt.HasHeader(uncompressed.Header()))
+}
@@ -0,0 +1,164 @@
+package consensus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockPartPriorityHead is the number of the most blocking missing parts
+// whose change fires types.EventBlockPartPriorityValue.
+const blockPartPriorityHead = 4
+
+// BlockPartPriority is the order in which the missing parts of the proposal
+// block of a round are best requested from peers. It is fired on the internal
+// event switch with types.EventBlockPartPriorityValue when its head changes.
+type BlockPartPriority struct {
+	Height int64
+	Round  int32
+	Have   int
+	Total  int
+
+	// Missing are the indices of the missing parts, the most blocking first:
+	// the parts passed over by the gossip, a part of a greater index having
+	// been received, the longest outstanding first, then the parts not
+	// received yet by index.
+	Missing []int
+	// Waits are the times the parts of Missing have been passed over for,
+	// 0 for the parts not passed over.
+	Waits []time.Duration
+}
+
+// blockPartWaits tracks since when the missing parts of the proposal block of
+// a round were passed over by the gossip. It is accessed under the State
+// mutex.
+type blockPartWaits struct {
+	height int64
+	round  int32
+	header types.PartSetHeader
+
+	// greatest index received; -1 if none
+	maxIndex int
+	// the time each missing part below maxIndex was first seen missing
+	missingSince map[int]time.Time
+	// the head of the last priority fired
+	head []int
+}
+
+// roundBlockPartWaits returns the part waits of the proposal block of header
+// in the current round.
+func (cs *State) roundBlockPartWaits(header types.PartSetHeader) *blockPartWaits {
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	bw := &cs.blockPartWaits
+	if bw.height != height || bw.round != round || !bw.header.Equals(header) {
+		*bw = blockPartWaits{
+			height:       height,
+			round:        round,
+			header:       header,
+			maxIndex:     -1,
+			missingSince: make(map[int]time.Time),
+		}
+	}
+	return bw
+}
+
+// markBlockPartWait records the receipt of part index of parts at
+// receiveTime: the missing parts it passes over are first seen missing. It
+// fires the priority of the missing parts if its head changed.
+func (cs *State) markBlockPartWait(parts *types.PartSet, index int, receiveTime time.Time) {
+	if receiveTime.IsZero() {
+		receiveTime = time.Now()
+	}
+	bw := cs.roundBlockPartWaits(parts.Header())
+	delete(bw.missingSince, index)
+	if index > bw.maxIndex {
+		received := parts.BitArray()
+		for i := bw.maxIndex + 1; i < index; i++ {
+			if !received.GetIndex(i) {
+				bw.missingSince[i] = receiveTime
+			}
+		}
+		bw.maxIndex = index
+	}
+	if parts.IsComplete() {
+		return
+	}
+
+	priority := bw.priority(parts, receiveTime)
+	head := priority.Missing
+	if len(head) > blockPartPriorityHead {
+		head = head[:blockPartPriorityHead]
+	}
+	if equalInts(head, bw.head) {
+		return
+	}
+	bw.head = append(bw.head[:0], head...)
+	cs.evsw.FireEvent(types.EventBlockPartPriorityValue, &priority)
+}
+
+// priority returns the priority of the missing parts of parts at now.
+func (bw *blockPartWaits) priority(parts *types.PartSet, now time.Time) BlockPartPriority {
+	priority := BlockPartPriority{
+		Height: bw.height,
+		Round:  bw.round,
+		Have:   int(parts.Count()),
+		Total:  int(parts.Total()),
+	}
+	received := parts.BitArray()
+	for i := 0; i < priority.Total; i++ {
+		if !received.GetIndex(i) {
+			priority.Missing = append(priority.Missing, i)
+		}
+	}
+	sort.SliceStable(priority.Missing, func(i, j int) bool {
+		si, passedI := bw.missingSince[priority.Missing[i]]
+		sj, passedJ := bw.missingSince[priority.Missing[j]]
+		if passedI != passedJ {
+			return passedI
+		}
+		return passedI && si.Before(sj)
+	})
+	priority.Waits = make([]time.Duration, len(priority.Missing))
+	for i, index := range priority.Missing {
+		if since, ok := bw.missingSince[index]; ok {
+			priority.Waits[i] = now.Sub(since)
+		}
+	}
+	return priority
+}
+
+// BlockPartPriority returns the priority of the missing parts of the proposal
+// block of the current round, or false if no part set is expected or it is
+// complete.
+func (cs *State) BlockPartPriority() (BlockPartPriority, bool) {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+	parts := cs.roundState.ProposalBlockParts()
+	if parts == nil || parts.IsComplete() {
+		return BlockPartPriority{}, false
+	}
+	return cs.roundBlockPartWaitsOf(parts.Header()).priority(parts, time.Now()), true
+}
+
+// roundBlockPartWaitsOf is roundBlockPartWaits without the reset, for the
+// readers holding the State mutex for reading only.
+func (cs *State) roundBlockPartWaitsOf(header types.PartSetHeader) *blockPartWaits {
+	bw := cs.blockPartWaits
+	if bw.height != cs.roundState.Height() || bw.round != cs.roundState.Round() || !bw.header.Equals(header) {
+		return &blockPartWaits{height: cs.roundState.Height(), round: cs.roundState.Round(), maxIndex: -1}
+	}
+	return &bw
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
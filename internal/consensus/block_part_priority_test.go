@@ -0,0 +1,109 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmevents "github.com/tendermint/tendermint/libs/events"
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBlockPartPriority(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+
+	var fired []BlockPartPriority
+	require.NoError(t, cs.evsw.AddListenerForEvent("test", types.EventBlockPartPriorityValue, func(data tmevents.EventData) error {
+		fired = append(fired, *data.(*BlockPartPriority))
+		return nil
+	}))
+
+	// vss[1] proposes a block of 6 parts in round 1
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, types.Txs{tmrand.Bytes(5 * int(types.BlockPartSizeBytes))},
+		created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, parts.Total())
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+
+	cs.enterNewRound(ctx, height, round, "test")
+	_, ok := cs.BlockPartPriority()
+	require.False(t, ok)
+	start := time.Now()
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: start}, false)
+
+	// no part received: the parts by index
+	priority, ok := cs.BlockPartPriority()
+	require.True(t, ok)
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5}, priority.Missing)
+	require.Equal(t, 0, priority.Have)
+	require.Equal(t, 6, priority.Total)
+
+	receive := func(index int, delay time.Duration) {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(index)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: start.Add(delay)}, false)
+	}
+
+	// part 2 passes over parts 0 and 1
+	receive(2, 10*time.Millisecond)
+	require.Len(t, fired, 1)
+	require.Equal(t, BlockPartPriority{
+		Height:  height,
+		Round:   round,
+		Have:    1,
+		Total:   6,
+		Missing: []int{0, 1, 3, 4, 5},
+		Waits:   []time.Duration{0, 0, 0, 0, 0},
+	}, fired[0])
+
+	// part 5 passes over parts 3 and 4, behind parts 0 and 1: the head is
+	// unchanged
+	receive(5, 20*time.Millisecond)
+	require.Len(t, fired, 1)
+	priority, ok = cs.BlockPartPriority()
+	require.True(t, ok)
+	require.Equal(t, []int{0, 1, 3, 4}, priority.Missing)
+
+	// part 0 leaves part 1 the longest passed over
+	receive(0, 30*time.Millisecond)
+	require.Len(t, fired, 2)
+	require.Equal(t, []int{1, 3, 4}, fired[1].Missing)
+	require.Equal(t, []time.Duration{
+		20 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond,
+	}, fired[1].Waits)
+
+	// a part received twice changes nothing
+	receive(0, 35*time.Millisecond)
+	require.Len(t, fired, 2)
+
+	receive(4, 40*time.Millisecond)
+	require.Len(t, fired, 3)
+	require.Equal(t, []int{1, 3}, fired[2].Missing)
+	require.Equal(t, 4, fired[2].Have)
+
+	// a complete block has no priority
+	receive(3, 50*time.Millisecond)
+	receive(1, 60*time.Millisecond)
+	require.Len(t, fired, 4)
+	require.Equal(t, []int{1}, fired[3].Missing)
+	require.True(t, cs.roundState.ProposalBlockParts().IsComplete())
+	_, ok = cs.BlockPartPriority()
+	require.False(t, ok)
+}
@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otrace "go.opentelemetry.io/otel/trace"
+)
+
+// BlockPartTiming is the timing of the receipt of the block parts of the
+// proposal of a round.
+type BlockPartTiming struct {
+	// Parts is the number of parts received.
+	Parts int
+	// FirstDelay is the time from the receipt of the proposal to the receipt
+	// of the first part. It is 0 if the proposal was received after it.
+	FirstDelay time.Duration
+	// Spread is the time from the receipt of the first part to the receipt of
+	// the last one.
+	Spread time.Duration
+	// MaxGap and MeanGap are the longest and mean times between the receipts
+	// of two consecutive parts.
+	MaxGap  time.Duration
+	MeanGap time.Duration
+}
+
+// blockPartTiming accumulates the receive times of the proposal block parts
+// added in a round. It is accessed under the State mutex.
+type blockPartTiming struct {
+	height int64
+	round  int32
+
+	parts       int
+	first, last time.Time
+	maxGap      time.Duration
+	recorded    bool
+}
+
+// roundBlockPartTiming returns the block part timing of the current round.
+func (cs *State) roundBlockPartTiming() *blockPartTiming {
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	if cs.blockPartTiming.height != height || cs.blockPartTiming.round != round {
+		cs.blockPartTiming = blockPartTiming{height: height, round: round}
+	}
+	return &cs.blockPartTiming
+}
+
+// markBlockPartReceived records the receipt of a block part of the proposal
+// of the current round at receiveTime.
+func (cs *State) markBlockPartReceived(receiveTime time.Time) {
+	if receiveTime.IsZero() {
+		receiveTime = time.Now()
+	}
+	bt := cs.roundBlockPartTiming()
+	switch {
+	case bt.parts == 0:
+		bt.first = receiveTime
+	case receiveTime.Sub(bt.last) > bt.maxGap:
+		bt.maxGap = receiveTime.Sub(bt.last)
+	}
+	bt.last = receiveTime
+	bt.parts++
+}
+
+// blockPartTimingOf returns the block part timing of the current round, or
+// false if no part was received.
+func (cs *State) blockPartTimingOf() (BlockPartTiming, bool) {
+	bt := cs.roundBlockPartTiming()
+	if bt.parts == 0 {
+		return BlockPartTiming{}, false
+	}
+	timing := BlockPartTiming{
+		Parts:  bt.parts,
+		Spread: bt.last.Sub(bt.first),
+		MaxGap: bt.maxGap,
+	}
+	if proposalTime := cs.roundState.ProposalReceiveTime(); !proposalTime.IsZero() && bt.first.After(proposalTime) {
+		timing.FirstDelay = bt.first.Sub(proposalTime)
+	}
+	if bt.parts > 1 {
+		timing.MeanGap = timing.Spread / time.Duration(bt.parts-1)
+	}
+	return timing, true
+}
+
+// recordBlockPartTiming exports the block part timing of the current round
+// once its proposal block is complete, and attaches it to span.
+func (cs *State) recordBlockPartTiming(span otrace.Span) {
+	timing, ok := cs.blockPartTimingOf()
+	if !ok {
+		return
+	}
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.Int("block_parts.count", timing.Parts),
+			attribute.Float64("block_parts.first_delay_seconds", timing.FirstDelay.Seconds()),
+			attribute.Float64("block_parts.spread_seconds", timing.Spread.Seconds()),
+			attribute.Float64("block_parts.max_gap_seconds", timing.MaxGap.Seconds()),
+			attribute.Float64("block_parts.mean_gap_seconds", timing.MeanGap.Seconds()),
+		)
+	}
+
+	bt := cs.roundBlockPartTiming()
+	if bt.recorded {
+		return
+	}
+	bt.recorded = true
+	cs.metrics.BlockPartFirstDelay.Observe(timing.FirstDelay.Seconds())
+	cs.metrics.BlockPartSpread.Observe(timing.Spread.Seconds())
+	cs.metrics.BlockPartMaxGap.Observe(timing.MaxGap.Seconds())
+	cs.metrics.BlockPartMeanGap.Observe(timing.MeanGap.Seconds())
+}
@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBlockPartTiming(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cs, vss := makeState(ctx, t, makeStateArgs{
+		config:     config,
+		validators: 2,
+		options:    []StateOption{WithTracerProvider(tp)},
+	})
+	maxGap := generic.NewHistogram("block_part_max_gap", 2)
+	cs.metrics.BlockPartMaxGap = maxGap
+
+	// vss[1] proposes a block of 4 parts in round 1
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, types.Txs{tmrand.Bytes(3 * int(types.BlockPartSizeBytes))},
+		created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, parts.Total())
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+
+	cs.enterNewRound(ctx, height, round, "test")
+	start := time.Now()
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: start}, false)
+	_, ok := cs.blockPartTimingOf()
+	require.False(t, ok)
+
+	// the parts are received 10ms, 20ms, 50ms and 60ms after the proposal,
+	// the second one twice
+	for i, delay := range []time.Duration{10, 20, 20, 50, 60} {
+		index := i
+		if i > 1 {
+			index--
+		}
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(index)}
+		receiveTime := start.Add(delay * time.Millisecond)
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: receiveTime}, false)
+	}
+	require.True(t, cs.roundState.ProposalBlockParts().IsComplete())
+
+	timing, ok := cs.blockPartTimingOf()
+	require.True(t, ok)
+	require.Equal(t, BlockPartTiming{
+		Parts:      4,
+		FirstDelay: 10 * time.Millisecond,
+		Spread:     50 * time.Millisecond,
+		MaxGap:     30 * time.Millisecond,
+		MeanGap:    50 * time.Millisecond / 3,
+	}, timing)
+	require.Equal(t, 0.03, maxGap.Quantile(0.5))
+
+	// the timing is attached to the span of the last part
+	var attrs []attribute.KeyValue
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "cs.state.handleBlockPartMsg" && len(span.Attributes) > 1 {
+			attrs = span.Attributes
+		}
+	}
+	require.Contains(t, attrs, attribute.Int("block_parts.count", 4))
+	require.Contains(t, attrs, attribute.Float64("block_parts.max_gap_seconds", 0.03))
+
+	// the timing is reset in the next round
+	cs.enterNewRound(ctx, height, round+1, "test")
+	_, ok = cs.blockPartTimingOf()
+	require.False(t, ok)
+}
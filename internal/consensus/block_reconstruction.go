@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockReconstruction is a fetch of the txs of a key-only proposal from the
+// mempool. It runs in the background so that an attempt which takes too long
+// can be abandoned without blocking the receiveRoutine, and picked up by a
+// later attempt for the same proposal.
+type blockReconstruction struct {
+	height int64
+	round  int32
+	done   chan struct{}
+
+	// set before done is closed
+	txs     types.Txs
+	missing []types.TxKey
+}
+
+// reconstructionDeadline returns the time by which a proposal block must be
+// rebuilt: the propose timeout of the current round, but at least the soft
+// limit from now.
+func (cs *State) reconstructionDeadline() time.Time {
+	deadline := cs.proposeDeadline
+	if minDeadline := time.Now().Add(cs.config.BlockReconstructionSoftLimit); deadline.Before(minDeadline) {
+		deadline = minDeadline
+	}
+	return deadline
+}
+
+// fetchProposalTxs returns the txs for the txKeys of the proposal at height and
+// round, waiting at most until deadline. If the deadline passes first, ok is
+// false and the fetch continues in the background, to be reused by the next
+// attempt for the same proposal.
+func (cs *State) fetchProposalTxs(
+	height int64,
+	round int32,
+	txKeys []types.TxKey,
+	deadline time.Time,
+) (txs types.Txs, missing []types.TxKey, ok bool) {
+	br := cs.blockReconstruction
+	if br == nil || br.height != height || br.round != round || br.isDone() && len(br.missing) > 0 {
+		// Txs may have arrived since a completed fetch found them missing, so
+		// only a fetch that is in flight or succeeded is reused.
+		br = &blockReconstruction{height: height, round: round, done: make(chan struct{})}
+		cs.blockReconstruction = br
+		cs.spawn(func() { cs.runBlockReconstruction(br, txKeys) })
+	}
+
+	if br.isDone() {
+		return br.txs, br.missing, true
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-br.done:
+		return br.txs, br.missing, true
+	case <-timer.C:
+		cs.logger.Info("abandoning proposal block reconstruction; deadline exceeded",
+			"height", height, "round", round, "num_txs", len(txKeys))
+		return nil, nil, false
+	}
+}
+
+func (cs *State) runBlockReconstruction(br *blockReconstruction, txKeys []types.TxKey) {
+	start := time.Now()
+	br.txs, br.missing = cs.blockExec.SafeGetTxsByKeys(txKeys)
+	close(br.done)
+
+	took := time.Since(start)
+	cs.metrics.BlockReconstructionTime.Observe(took.Seconds())
+	if took > cs.config.BlockReconstructionSoftLimit {
+		cs.logger.Error("proposal block reconstruction exceeded soft limit",
+			"height", br.height, "round", br.round, "num_txs", len(txKeys),
+			"took", took, "soft_limit", cs.config.BlockReconstructionSoftLimit)
+	}
+}
+
+func (br *blockReconstruction) isDone() bool {
+	select {
+	case <-br.done:
+		return true
+	default:
+		return false
+	}
+}
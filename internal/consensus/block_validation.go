@@ -0,0 +1,77 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockValidations caches the outcome of the validation of the blocks of a
+// height by block hash, as the same block is validated as it is prevoted,
+// locked on and committed. Unlike the cache of the block executor, it keeps
+// the errors of invalid blocks too.
+type blockValidations struct {
+	height int64
+	// lastBlockHash and appHash identify the state the blocks were validated
+	// against. cs.state does not change within a height, but the outcomes
+	// are discarded if it does.
+	lastBlockHash []byte
+	appHash       []byte
+	// outcomes by block hash; nil for valid blocks
+	outcomes map[string]error
+}
+
+// reset discards the outcomes, which were validated against another state.
+func (v *blockValidations) reset(state sm.State) {
+	*v = blockValidations{
+		height:        state.LastBlockHeight + 1,
+		lastBlockHash: state.LastBlockID.Hash,
+		appHash:       state.AppHash,
+		outcomes:      make(map[string]error),
+	}
+}
+
+// validatedAgainst reports whether the outcomes were validated against state.
+func (v *blockValidations) validatedAgainst(state sm.State) bool {
+	return v.outcomes != nil &&
+		v.height == state.LastBlockHeight+1 &&
+		bytes.Equal(v.lastBlockHash, state.LastBlockID.Hash) &&
+		bytes.Equal(v.appHash, state.AppHash)
+}
+
+// validateBlock validates block against cs.state, reusing the outcome of its
+// earlier validation in the height if any.
+func (cs *State) validateBlock(ctx context.Context, block *types.Block) error {
+	v := &cs.blockValidations
+	if !v.validatedAgainst(cs.state) {
+		v.reset(cs.state)
+	}
+	hash := string(block.Hash())
+	if err, ok := v.outcomes[hash]; ok {
+		cs.metrics.BlockValidations.With("outcome", "cached").Add(1)
+		return err
+	}
+
+	err := cs.blockExec.ValidateBlock(ctx, cs.state, block)
+	cs.metrics.BlockValidations.With("outcome", "validated").Add(1)
+	// the block was not validated if the validation was interrupted
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		v.outcomes[hash] = err
+	}
+	return err
+}
+
+// validateCommittedBlock validates block, committed by +2/3 precommits,
+// against cs.state before it is applied. Unless
+// config.RevalidateCommittedBlocks is set, the outcome of its earlier
+// validation in the height is reused.
+func (cs *State) validateCommittedBlock(ctx context.Context, block *types.Block) error {
+	if !cs.config.RevalidateCommittedBlocks {
+		return cs.validateBlock(ctx, block)
+	}
+	cs.metrics.BlockValidations.With("outcome", "validated").Add(1)
+	return cs.blockExec.RevalidateBlock(ctx, cs.state, block)
+}
@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBlockValidationsPerHeight(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		revalidate bool
+		validated  float64
+		cached     float64
+	}{
+		{name: "cached", validated: 1, cached: 2},
+		{name: "revalidate committed blocks", revalidate: true, validated: 2, cached: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := configSetup(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			cs.config.RevalidateCommittedBlocks = tc.revalidate
+			validations := newLabeledCounter()
+			cs.metrics.BlockValidations = validations
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+
+			proposalCh := subscribe(ctx, t, cs.eventBus, types.EventQueryCompleteProposal)
+			newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
+			pubKey, err := cs.privValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			voteCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+
+			// the block is prevoted, locked on and committed
+			startTestRound(ctx, cs, height, round)
+			ensureNewRound(t, newRoundCh, height, round)
+			ensureNewProposal(t, proposalCh, height, round)
+			rs := cs.GetRoundState()
+			blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+			ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+			signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[1:]...)
+			ensurePrecommitMatch(t, voteCh, height, round, blockID.Hash)
+			signAddVotes(ctx, t, cs, tmproto.PrecommitType, config.ChainID(), blockID, vss[1:]...)
+			ensureNewRound(t, newRoundCh, height+1, 0)
+
+			require.Equal(t, tc.validated, validations.values["outcome,validated"])
+			require.Equal(t, tc.cached, validations.values["outcome,cached"])
+		})
+	}
+}
+
+func TestStateBlockValidationsInvalidBlock(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	validations := newLabeledCounter()
+	cs.metrics.BlockValidations = validations
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	block.ChainID = "other-chain"
+
+	// the error is kept
+	err = cs.validateBlock(ctx, block)
+	require.Error(t, err)
+	require.Equal(t, err, cs.validateBlock(ctx, block))
+	require.Equal(t, float64(1), validations.values["outcome,validated"])
+	require.Equal(t, float64(1), validations.values["outcome,cached"])
+
+	// the committed block is validated again if configured
+	cs.config.RevalidateCommittedBlocks = true
+	require.Error(t, cs.validateCommittedBlock(ctx, block))
+	require.Equal(t, float64(2), validations.values["outcome,validated"])
+
+	// the outcomes are discarded if the state changes
+	cs.state.AppHash = []byte("other-app-hash")
+	require.Error(t, cs.validateBlock(ctx, block))
+	require.Equal(t, float64(3), validations.values["outcome,validated"])
+	require.Equal(t, float64(1), validations.values["outcome,cached"])
+}
+
+func BenchmarkStateBlockValidations(b *testing.B) {
+	for _, tc := range []struct {
+		name       string
+		revalidate bool
+	}{
+		{name: "cached"},
+		{name: "revalidate-committed-blocks", revalidate: true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			config := configSetup(b)
+			cs, _ := makeState(ctx, b, makeStateArgs{config: config, logger: log.NewNopLogger()})
+			cs.config.RevalidateCommittedBlocks = tc.revalidate
+			validations := newLabeledCounter()
+			cs.metrics.BlockValidations = validations
+			block, err := cs.createProposalBlock(ctx)
+			require.NoError(b, err)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// the block is prevoted, locked on and committed at each height
+				cs.blockValidations.reset(cs.state)
+				require.NoError(b, cs.validateBlock(ctx, block))
+				require.NoError(b, cs.validateBlock(ctx, block))
+				require.NoError(b, cs.validateCommittedBlock(ctx, block))
+			}
+			b.ReportMetric(validations.values["outcome,validated"]/float64(b.N), "validations/height")
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"bytes"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// precommitSource is where the precommits of a round were received from.
+type precommitSource struct {
+	peers map[types.NodeID]struct{}
+	// own is set if one of the precommits is signed with the key of this
+	// node
+	own bool
+}
+
+// precommitSources tracks where the precommits of each round of the current
+// and the previous heights, whose precommits can still be added to the last
+// commit, were received from.
+type precommitSources struct {
+	rounds map[int64]map[int32]*precommitSource
+}
+
+func (ps *precommitSources) add(height int64, round int32, peerID types.NodeID, own bool) {
+	if ps.rounds == nil {
+		ps.rounds = make(map[int64]map[int32]*precommitSource)
+	}
+	rounds, ok := ps.rounds[height]
+	if !ok {
+		rounds = make(map[int32]*precommitSource)
+		ps.rounds[height] = rounds
+	}
+	source, ok := rounds[round]
+	if !ok {
+		source = &precommitSource{peers: make(map[types.NodeID]struct{})}
+		rounds[round] = source
+	}
+	if own {
+		source.own = true
+	} else {
+		source.peers[peerID] = struct{}{}
+	}
+}
+
+// addPrecommitSource records that vote was received from peerID. The votes
+// fed without a sender, such as those of a vote relay, count as received
+// from a single peer unless they are signed with the key of this node.
+func (cs *State) addPrecommitSource(vote *types.Vote, peerID types.NodeID) {
+	pubKey := cs.getPrivValidatorPubKey()
+	own := pubKey != nil && bytes.Equal(vote.ValidatorAddress, pubKey.Address())
+	cs.precommitSources.add(vote.Height, vote.Round, peerID, own)
+}
+
+func (ps *precommitSources) get(height int64, round int32) precommitSource {
+	if source, ok := ps.rounds[height][round]; ok {
+		return *source
+	}
+	return precommitSource{}
+}
+
+// prune drops the rounds of the heights below height-1.
+func (ps *precommitSources) prune(height int64) {
+	for h := range ps.rounds {
+		if h < height-1 {
+			delete(ps.rounds, h)
+		}
+	}
+}
+
+// canBypassCommitTimeout returns whether the commit timeout can be bypassed
+// now that all the precommits of round at height are received: they must not
+// all have been relayed by fewer than config.BypassCommitTimeoutMinPeers
+// peers, unless one of them is this node's.
+func (cs *State) canBypassCommitTimeout(height int64, round int32) bool {
+	if !cs.bypassCommitTimeout() {
+		return false
+	}
+	minPeers := cs.config.BypassCommitTimeoutMinPeers
+	if minPeers == 0 {
+		return true
+	}
+	source := cs.precommitSources.get(height, round)
+	if source.own || len(source.peers) >= minPeers {
+		return true
+	}
+	cs.logger.Debug("not bypassing the commit timeout; precommits received from too few peers",
+		"height", height, "round", round, "peers", len(source.peers), "min_peers", minPeers)
+	return false
+}
@@ -0,0 +1,120 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateBypassCommitTimeoutMinPeers(t *testing.T) {
+	config := configSetup(t)
+
+	for _, tc := range []struct {
+		name     string
+		minPeers int
+		// peer returns the peer the precommit of the validator i is received
+		// from; the validator 0 is this node
+		peer func(i int) types.NodeID
+		// keyed is set if this node has the key of the validator 0
+		keyed  bool
+		bypass bool
+	}{
+		{
+			name:     "disabled",
+			minPeers: 0,
+			peer:     func(int) types.NodeID { return "relay" },
+			bypass:   true,
+		},
+		{
+			name:     "relayed by a single peer",
+			minPeers: 2,
+			peer:     func(int) types.NodeID { return "relay" },
+			bypass:   false,
+		},
+		{
+			name:     "distinct peers",
+			minPeers: 2,
+			peer:     func(i int) types.NodeID { return types.NodeID([]string{"relay", "peer1"}[i%2]) },
+			bypass:   true,
+		},
+		{
+			name:     "relayed without a sender",
+			minPeers: 2,
+			peer:     func(int) types.NodeID { return "" },
+			bypass:   false,
+		},
+		{
+			name:     "own precommit",
+			minPeers: 2,
+			peer: func(i int) types.NodeID {
+				if i == 0 {
+					return ""
+				}
+				return "relay"
+			},
+			keyed:  true,
+			bypass: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			cs.state.ConsensusParams.Timeout.BypassCommitTimeout = true
+			cs.config.BypassCommitTimeoutMinPeers = tc.minPeers
+			height := cs.roundState.Height()
+
+			var proposer *validatorStub
+			address := cs.roundState.Validators().GetProposer().Address
+			for _, vs := range vss {
+				pubKey, err := vs.PrivValidator.GetPubKey(ctx)
+				require.NoError(t, err)
+				if bytes.Equal(pubKey.Address(), address) {
+					proposer = vs
+				}
+			}
+			proposal, block := decideProposal(ctx, t, cs, proposer, height, 0)
+			parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+			require.NoError(t, err)
+			blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+
+			// the votes of the validators, including this node's, are handled
+			// as they are received
+			cs.privValidator = nil
+			if !tc.keyed {
+				cs.privValidatorPubKey = nil
+			}
+			cs.enterNewRound(ctx, height, 0, "test")
+			cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "relay"}, false)
+			for i := 0; i < int(parts.Total()); i++ {
+				cs.handleMsg(ctx, msgInfo{Msg: &BlockPartMessage{Height: height, Round: 0, Part: parts.GetPart(i)}, PeerID: "relay"}, false)
+			}
+			for _, vs := range vss {
+				vs.Height, vs.Round = height, 0
+				vote := signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), blockID)
+				cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "relay"}, false)
+			}
+
+			// the block is committed with the first precommits, the last
+			// completes the last commit
+			for i, vs := range vss {
+				vote := signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), blockID)
+				cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: tc.peer(i)}, false)
+			}
+			require.True(t, cs.roundState.LastCommit().HasAll())
+			require.Equal(t, height+1, cs.roundState.Height())
+			if tc.bypass {
+				require.Less(t, cstypes.RoundStepNewHeight, cs.roundState.Step())
+			} else {
+				require.Equal(t, cstypes.RoundStepNewHeight, cs.roundState.Step())
+			}
+		})
+	}
+}
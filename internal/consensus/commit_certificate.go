@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// CommitCertificate is the commit for a block, together with the validator
+// set that signed it and the hashes of the block header that are certified by
+// the commit.
+type CommitCertificate struct {
+	Height  int64         `json:"height,string"`
+	BlockID types.BlockID `json:"block_id"`
+	Commit  *types.Commit `json:"commit"`
+
+	ValidatorSet   *types.ValidatorSet `json:"validator_set"`
+	ValidatorsHash tmbytes.HexBytes    `json:"validators_hash"`
+	// AppHash is the app hash in the header of the block, i.e. the result of
+	// executing the previous block.
+	AppHash tmbytes.HexBytes `json:"app_hash"`
+}
+
+// CommitCertificate returns the commit certificate of the block at height,
+// after checking that the commit is signed by the validator set of height for
+// the stored block. It only reads the stores and does not need the consensus
+// state machine to be running.
+func (cs *State) CommitCertificate(height int64) (*CommitCertificate, error) {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	meta := cs.blockStore.LoadBlockMeta(height)
+	if meta == nil {
+		return nil, fmt.Errorf("no block meta for height %d", height)
+	}
+	commit := cs.loadCommit(height)
+	if commit == nil {
+		return nil, fmt.Errorf("no commit for height %d", height)
+	}
+	vals, err := cs.stateStore.LoadValidators(height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load validators for height %d: %w", height, err)
+	}
+
+	if !bytes.Equal(meta.Header.Hash(), meta.BlockID.Hash) {
+		return nil, fmt.Errorf("header hash %X does not match block ID %X at height %d",
+			meta.Header.Hash(), meta.BlockID.Hash, height)
+	}
+	if !bytes.Equal(vals.Hash(), meta.Header.ValidatorsHash) {
+		return nil, fmt.Errorf("validator set hash %X does not match the header validators hash %X at height %d",
+			vals.Hash(), meta.Header.ValidatorsHash, height)
+	}
+	if err := vals.VerifyCommit(cs.state.ChainID, meta.BlockID, height, commit); err != nil {
+		return nil, fmt.Errorf("invalid commit for height %d: %w", height, err)
+	}
+
+	return &CommitCertificate{
+		Height:         height,
+		BlockID:        meta.BlockID,
+		Commit:         commit,
+		ValidatorSet:   vals,
+		ValidatorsHash: meta.Header.ValidatorsHash,
+		AppHash:        meta.Header.AppHash,
+	}, nil
+}
@@ -48,7 +48,7 @@ const (
 // test.
 type cleanupFunc func()
 
-func configSetup(t *testing.T) *config.Config {
+func configSetup(t testing.TB) *config.Config {
 	t.Helper()
 
 	cfg, err := ResetConfig(t.TempDir(), "consensus_reactor_test")
@@ -77,7 +77,7 @@ func configSetup(t *testing.T) *config.Config {
 	return cfg
 }
 
-func ensureDir(t *testing.T, dir string, mode os.FileMode) {
+func ensureDir(t testing.TB, dir string, mode os.FileMode) {
 	t.Helper()
 	require.NoError(t, tmos.EnsureDir(dir, mode))
 }
@@ -154,7 +154,7 @@ func (vs *validatorStub) signVote(
 // Sign vote for type/hash/header
 func signVote(
 	ctx context.Context,
-	t *testing.T,
+	t testing.TB,
 	vs *validatorStub,
 	voteType tmproto.SignedMsgType,
 	chainID string,
@@ -436,42 +436,64 @@ func subscribeToVoterBuffered(ctx context.Context, t *testing.T, cs *State, addr
 
 func newState(
 	ctx context.Context,
-	t *testing.T,
+	t testing.TB,
 	logger log.Logger,
 	state sm.State,
 	pv types.PrivValidator,
 	app abci.Application,
+	options ...StateOption,
 ) *State {
 	t.Helper()
 
 	cfg, err := config.ResetTestRoot(t.TempDir(), "consensus_state_test")
 	require.NoError(t, err)
 
-	return newStateWithConfig(ctx, t, logger, cfg, state, pv, app)
+	return newStateWithConfig(ctx, t, logger, cfg, state, pv, app, options...)
 }
 
 func newStateWithConfig(
 	ctx context.Context,
-	t *testing.T,
+	t testing.TB,
 	logger log.Logger,
 	thisConfig *config.Config,
 	state sm.State,
 	pv types.PrivValidator,
 	app abci.Application,
+	options ...StateOption,
 ) *State {
 	t.Helper()
-	return newStateWithConfigAndBlockStore(ctx, t, logger, thisConfig, state, pv, app, store.NewBlockStore(dbm.NewMemDB()))
+	return newStateWithConfigAndBlockStore(ctx, t, logger, thisConfig, state, pv, app, store.NewBlockStore(dbm.NewMemDB()), options...)
 }
 
 func newStateWithConfigAndBlockStore(
 	ctx context.Context,
-	t *testing.T,
+	t testing.TB,
 	logger log.Logger,
 	thisConfig *config.Config,
 	state sm.State,
 	pv types.PrivValidator,
 	app abci.Application,
 	blockStore *store.BlockStore,
+	options ...StateOption,
+) *State {
+	t.Helper()
+	stateStore := sm.NewStore(dbm.NewMemDB())
+	require.NoError(t, stateStore.Save(state))
+	return newStateWithConfigAndStores(ctx, t, logger, thisConfig, stateStore, pv, app, blockStore, options...)
+}
+
+// newStateWithConfigAndStores returns a State starting from the state saved
+// in stateStore.
+func newStateWithConfigAndStores(
+	ctx context.Context,
+	t testing.TB,
+	logger log.Logger,
+	thisConfig *config.Config,
+	stateStore sm.Store,
+	pv types.PrivValidator,
+	app abci.Application,
+	blockStore *store.BlockStore,
+	options ...StateOption,
 ) *State {
 	t.Helper()
 
@@ -494,11 +516,6 @@ func newStateWithConfigAndBlockStore(
 
 	evpool := sm.EmptyEvidencePool{}
 
-	// Make State
-	stateDB := dbm.NewMemDB()
-	stateStore := sm.NewStore(stateDB)
-	require.NoError(t, stateStore.Save(state))
-
 	eventBus := eventbus.NewDefault(logger.With("module", "events"))
 	require.NoError(t, eventBus.Start(ctx))
 
@@ -512,6 +529,7 @@ func newStateWithConfigAndBlockStore(
 		evpool,
 		eventBus,
 		[]trace.TracerProviderOption{},
+		options...,
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -539,9 +557,10 @@ type makeStateArgs struct {
 	logger          log.Logger
 	validators      int
 	application     abci.Application
+	options         []StateOption
 }
 
-func makeState(ctx context.Context, t *testing.T, args makeStateArgs) (*State, []*validatorStub) {
+func makeState(ctx context.Context, t testing.TB, args makeStateArgs) (*State, []*validatorStub) {
 	t.Helper()
 	// Get State
 	validators := 4
@@ -571,7 +590,7 @@ func makeState(ctx context.Context, t *testing.T, args makeStateArgs) (*State, [
 
 	vss := make([]*validatorStub, validators)
 
-	cs := newState(ctx, t, args.logger, state, privVals[0], app)
+	cs := newState(ctx, t, args.logger, state, privVals[0], app, args.options...)
 
 	for i := 0; i < validators; i++ {
 		vss[i] = newValidatorStub(privVals[i], int32(i))
@@ -691,12 +710,25 @@ func ensureNewBlockHeader(t *testing.T, blockCh <-chan tmpubsub.Message, height
 
 func ensureLock(t *testing.T, lockCh <-chan tmpubsub.Message, height int64, round int32) {
 	t.Helper()
-	ensureNewEvent(t, lockCh, height, round, ensureTimeout)
+	ensureLockEvent(t, lockCh, height, round)
 }
 
 func ensureRelock(t *testing.T, relockCh <-chan tmpubsub.Message, height int64, round int32) {
 	t.Helper()
-	ensureNewEvent(t, relockCh, height, round, ensureTimeout)
+	ensureLockEvent(t, relockCh, height, round)
+}
+
+func ensureLockEvent(t *testing.T, ch <-chan tmpubsub.Message, height int64, round int32) {
+	t.Helper()
+	msg := ensureMessageBeforeTimeout(t, ch, ensureTimeout)
+	lockEvent, ok := msg.Data().(types.EventDataLock)
+	require.True(t, ok,
+		"expected a EventDataLock, got %T. Wrong subscription channel?",
+		msg.Data())
+
+	require.Equal(t, height, lockEvent.Height)
+	require.Equal(t, round, lockEvent.Round)
+	require.NotEmpty(t, lockEvent.BlockID.Hash)
 }
 
 func ensureProposal(t *testing.T, proposalCh <-chan tmpubsub.Message, height int64, round int32, propID types.BlockID) {
@@ -931,7 +963,7 @@ type genesisStateArgs struct {
 	Time       time.Time
 }
 
-func makeGenesisState(ctx context.Context, t *testing.T, cfg *config.Config, args genesisStateArgs) (sm.State, []types.PrivValidator) {
+func makeGenesisState(ctx context.Context, t testing.TB, cfg *config.Config, args genesisStateArgs) (sm.State, []types.PrivValidator) {
 	t.Helper()
 	if args.Power == 0 {
 		args.Power = 1
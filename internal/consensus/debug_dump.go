@@ -0,0 +1,160 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// handleMsgErrorHistory is the number of the last errors of handleMsg
+	// kept for the debug dump.
+	handleMsgErrorHistory = 20
+	// debugDumpInterval is the least time between two debug dumps.
+	debugDumpInterval = time.Second
+)
+
+// ErrDebugDumpRateLimited is returned by DebugDump when called again within
+// debugDumpInterval.
+var ErrDebugDumpRateLimited = errors.New("debug dump rate limited")
+
+// DebugSnapshot is the operational state of the consensus state machine for
+// incident debugging.
+type DebugSnapshot struct {
+	RoundState cstypes.RoundStateSimple `json:"round_state"`
+	// LastActivityAge is the time since the receive routine last processed a
+	// message.
+	LastActivityAge time.Duration `json:"last_activity_age,string"`
+
+	PeerMsgQueue        QueueDepth `json:"peer_msg_queue"`
+	InternalMsgQueue    QueueDepth `json:"internal_msg_queue"`
+	InternalMsgOverflow int        `json:"internal_msg_overflow"`
+
+	WAL WALHealth `json:"wal"`
+	// HandleMsgErrors are the last errors of processing messages, oldest
+	// first.
+	HandleMsgErrors []HandleMsgError `json:"handle_msg_errors"`
+}
+
+// QueueDepth is the number of messages waiting in a queue, out of its
+// capacity.
+type QueueDepth struct {
+	Len int `json:"len"`
+	Cap int `json:"cap"`
+}
+
+// WALHealth is the outcome of the last writes and syncs of the WAL. The WAL is
+// degraded while the last write or sync failed.
+type WALHealth struct {
+	LastWrite time.Time `json:"last_write"`
+	LastSync  time.Time `json:"last_sync"`
+	Degraded  bool      `json:"degraded"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// HandleMsgError is an error of processing a message.
+type HandleMsgError struct {
+	Time    time.Time    `json:"time"`
+	Height  int64        `json:"height,string"`
+	Round   int32        `json:"round"`
+	Peer    types.NodeID `json:"peer"`
+	MsgType string       `json:"msg_type"`
+	Error   string       `json:"error"`
+}
+
+// debugState holds the signals of the debug dump that are not kept
+// elsewhere. They are updated outside of cs.mtx too, so it has its own lock.
+type debugState struct {
+	mtx      sync.Mutex
+	wal      WALHealth
+	errors   []HandleMsgError
+	lastDump time.Time
+}
+
+// recordWALWrite records the outcome of a write of the WAL, synced if sync.
+func (cs *State) recordWALWrite(sync bool, err error) {
+	d := &cs.debugState
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if err != nil {
+		d.wal.Degraded = true
+		d.wal.LastError = err.Error()
+		return
+	}
+	now := time.Now()
+	d.wal.Degraded = false
+	d.wal.LastWrite = now
+	if sync {
+		d.wal.LastSync = now
+	}
+}
+
+// recordWALSync records the outcome of a sync of the WAL.
+func (cs *State) recordWALSync(err error) {
+	d := &cs.debugState
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if err != nil {
+		d.wal.Degraded = true
+		d.wal.LastError = err.Error()
+		return
+	}
+	d.wal.Degraded = false
+	d.wal.LastSync = time.Now()
+}
+
+// recordHandleMsgError keeps err, returned processing msg of peerID, for
+// the debug dump.
+func (cs *State) recordHandleMsgError(msg Message, peerID types.NodeID, err error) {
+	d := &cs.debugState
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if len(d.errors) == handleMsgErrorHistory {
+		d.errors = d.errors[1:]
+	}
+	d.errors = append(d.errors, HandleMsgError{
+		Time:    time.Now(),
+		Height:  cs.roundState.Height(),
+		Round:   cs.roundState.Round(),
+		Peer:    peerID,
+		MsgType: fmt.Sprintf("%T", msg),
+		Error:   err.Error(),
+	})
+}
+
+// DebugDump returns the round state, the depth of the message queues, the
+// health of the WAL, the time since the last activity of the receive routine
+// and the last errors of processing messages as a JSON document. It returns
+// ErrDebugDumpRateLimited if called again within a second.
+func (cs *State) DebugDump() ([]byte, error) {
+	d := &cs.debugState
+	d.mtx.Lock()
+	now := time.Now()
+	if now.Sub(d.lastDump) < debugDumpInterval {
+		d.mtx.Unlock()
+		return nil, ErrDebugDumpRateLimited
+	}
+	d.lastDump = now
+	dump := DebugSnapshot{
+		WAL:             d.wal,
+		HandleMsgErrors: append([]HandleMsgError{}, d.errors...),
+	}
+	d.mtx.Unlock()
+
+	dump.RoundState = cs.roundState.CopyInternal().RoundStateSimple()
+	dump.LastActivityAge = cs.GetLastActivityAge()
+	dump.PeerMsgQueue = QueueDepth{Len: len(cs.peerMsgQueue), Cap: cap(cs.peerMsgQueue)}
+	dump.InternalMsgQueue = QueueDepth{Len: len(cs.internalMsgQueue), Cap: cap(cs.internalMsgQueue)}
+	cs.internalMsgOverflow.mtx.Lock()
+	dump.InternalMsgOverflow = len(cs.internalMsgOverflow.msgs)
+	cs.internalMsgOverflow.mtx.Unlock()
+	return json.Marshal(dump)
+}
@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateDebugDump(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	cs.enterNewRound(ctx, height, round, "test")
+
+	// more invalid votes than the errors kept
+	vote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	vote.Signature = make([]byte, len(vote.Signature))
+	for i := 0; i < handleMsgErrorHistory+5; i++ {
+		cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer-a"}, false)
+	}
+	// a backlog of peer messages the receive routine is not processing
+	for i := 0; i < 3; i++ {
+		cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer-b"}
+	}
+	// a write of the WAL, then a failed sync
+	require.NoError(t, cs.walWrite(FaultPointNewStep, cs.roundState.RoundStateEvent()))
+	cs.recordWALSync(errors.New("disk full"))
+
+	data, err := cs.DebugDump()
+	require.NoError(t, err)
+	var dump DebugSnapshot
+	require.NoError(t, json.Unmarshal(data, &dump))
+
+	require.Equal(t, fmt.Sprintf("%d/%d/%d", height, round, cs.roundState.Step()), dump.RoundState.HeightRoundStep)
+	require.Equal(t, QueueDepth{Len: 3, Cap: msgQueueSize}, dump.PeerMsgQueue)
+	// our proposal and its block parts
+	require.Equal(t, QueueDepth{Len: len(cs.internalMsgQueue), Cap: msgQueueSize}, dump.InternalMsgQueue)
+	require.NotZero(t, dump.InternalMsgQueue.Len)
+	require.Len(t, dump.HandleMsgErrors, handleMsgErrorHistory)
+	for _, msgErr := range dump.HandleMsgErrors {
+		require.Equal(t, types.NodeID("peer-a"), msgErr.Peer)
+		require.Equal(t, "*consensus.VoteMessage", msgErr.MsgType)
+		require.Equal(t, height, msgErr.Height)
+		require.Equal(t, ErrAddingVote.Error(), msgErr.Error)
+	}
+	require.False(t, dump.WAL.LastWrite.IsZero())
+	require.True(t, dump.WAL.Degraded)
+	require.Equal(t, "disk full", dump.WAL.LastError)
+
+	// dumps are rate limited
+	_, err = cs.DebugDump()
+	require.ErrorIs(t, err, ErrDebugDumpRateLimited)
+	cs.debugState.lastDump = time.Now().Add(-debugDumpInterval)
+	_, err = cs.DebugDump()
+	require.NoError(t, err)
+}
@@ -0,0 +1,273 @@
+package consensus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	auto "github.com/tendermint/tendermint/internal/libs/autofile"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/libs/log"
+	tmos "github.com/tendermint/tendermint/libs/os"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// decisionLogQueueSize is the number of records buffered for the
+	// decision log writer. Records are dropped once it is full.
+	decisionLogQueueSize = 1000
+
+	// decisionLogFiles is the number of files the total size of the
+	// decision log is split into.
+	decisionLogFiles = 10
+)
+
+// DecisionKind is the kind of a decision of the consensus state machine.
+type DecisionKind string
+
+const (
+	// DecisionProposal is the acceptance or rejection of a proposal.
+	DecisionProposal DecisionKind = "proposal"
+	// DecisionPrevote is the prevote of this node.
+	DecisionPrevote DecisionKind = "prevote"
+	// DecisionPrecommit is the precommit of this node.
+	DecisionPrecommit DecisionKind = "precommit"
+	// DecisionLock is a lock or relock on a block.
+	DecisionLock DecisionKind = "lock"
+	// DecisionNewRound is the start of a round.
+	DecisionNewRound DecisionKind = "new-round"
+	// DecisionCommit is the commit of a block.
+	DecisionCommit DecisionKind = "commit"
+)
+
+// ProposalAccepted is the reason of a DecisionProposal record of an accepted
+// proposal. The reason of a rejected proposal is the error rejecting it.
+const ProposalAccepted = "accepted"
+
+// DecisionRecord is a record of the consensus decision log, written as one
+// JSON object per line.
+type DecisionRecord struct {
+	Time   time.Time    `json:"time"`
+	Height int64        `json:"height"`
+	Round  int32        `json:"round"`
+	Kind   DecisionKind `json:"kind"`
+	// Reason is why the decision was made: the cause of a new round, the
+	// trigger of a lock, why a vote was cast or a proposal was rejected.
+	Reason string `json:"reason,omitempty"`
+	// BlockHash is the block decided on. It is empty for nil votes.
+	BlockHash tmbytes.HexBytes `json:"block_hash,omitempty"`
+	// Peer is the peer a proposal was received from. It is empty for our
+	// own proposals.
+	Peer types.NodeID `json:"peer,omitempty"`
+	// Timing is the timing breakdown of a commit.
+	Timing *CommitTiming `json:"timing,omitempty"`
+}
+
+// CommitTiming is the timing breakdown of a commit.
+type CommitTiming struct {
+	// Consensus is the time from the start of the height to the commit.
+	Consensus time.Duration `json:"consensus"`
+	// Save is the time saving the block and syncing the WAL took.
+	Save time.Duration `json:"save"`
+	// Apply is the time applying the block took.
+	Apply time.Duration `json:"apply"`
+	// Finality is the time from the timestamp of the block to its
+	// application completing, zero if the timestamp is ahead of our clock.
+	Finality time.Duration `json:"finality"`
+	// ProposalBlockSource is how the block was obtained if it was proposed by
+	// another validator: ProposalBlockFromTxKeys or ProposalBlockFromParts.
+	ProposalBlockSource string `json:"proposal_block_source,omitempty"`
+	// ProposalBlockLatency is the time from the receipt of the proposal to
+	// the block being available.
+	ProposalBlockLatency time.Duration `json:"proposal_block_latency,omitempty"`
+}
+
+// ParseDecisionLog parses the records of a decision log.
+func ParseDecisionLog(r io.Reader) ([]DecisionRecord, error) {
+	var records []DecisionRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// decisionLog writes decision records to a size-capped group of files in the
+// background. Records are dropped when the writer falls behind, so the
+// decision log never slows consensus down.
+type decisionLog struct {
+	logger  log.Logger
+	group   *auto.Group
+	records chan DecisionRecord
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func openDecisionLog(ctx context.Context, logger log.Logger, path string, maxSize int64) (*decisionLog, error) {
+	if err := tmos.EnsureDir(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to ensure decision log directory is in place: %w", err)
+	}
+	group, err := auto.OpenGroup(ctx, logger, path,
+		auto.GroupHeadSizeLimit(maxSize/decisionLogFiles),
+		auto.GroupTotalSizeLimit(maxSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := group.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	dl := &decisionLog{
+		logger:  logger,
+		group:   group,
+		records: make(chan DecisionRecord, decisionLogQueueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go dl.run()
+	return dl, nil
+}
+
+func (dl *decisionLog) run() {
+	defer close(dl.done)
+	for {
+		select {
+		case record := <-dl.records:
+			dl.write(record)
+			if len(dl.records) == 0 {
+				dl.flush()
+			}
+		case <-dl.quit:
+			for {
+				select {
+				case record := <-dl.records:
+					dl.write(record)
+				default:
+					dl.flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (dl *decisionLog) write(record DecisionRecord) {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		dl.logger.Error("failed to encode decision record", "err", err)
+		return
+	}
+	if err := dl.group.WriteLine(string(bz)); err != nil {
+		dl.logger.Error("failed to write decision record", "err", err)
+	}
+}
+
+func (dl *decisionLog) flush() {
+	if err := dl.group.FlushAndSync(); err != nil {
+		dl.logger.Error("failed to flush decision log", "err", err)
+	}
+}
+
+// close writes the queued records and closes the files.
+func (dl *decisionLog) close() {
+	close(dl.quit)
+	<-dl.done
+	dl.group.Stop()
+	dl.group.Close()
+}
+
+// openDecisionLog opens the decision log if config.DecisionLogPath is set.
+func (cs *State) openDecisionLog(ctx context.Context) error {
+	if cs.config.DecisionLogPath == "" {
+		return nil
+	}
+	path := cs.config.DecisionLogFile()
+	dl, err := openDecisionLog(ctx, cs.logger.With("decision_log", path), path, cs.config.DecisionLogMaxSize)
+	if err != nil {
+		return err
+	}
+	cs.decisionLog = dl
+	return nil
+}
+
+// closeDecisionLog closes the decision log, if open.
+func (cs *State) closeDecisionLog() {
+	if cs.decisionLog == nil {
+		return
+	}
+	cs.decisionLog.close()
+	cs.decisionLog = nil
+}
+
+// logDecision queues record for the decision log, at the current height and
+// round unless set. Decisions replayed from the WAL were logged before the
+// restart and are not logged again.
+func (cs *State) logDecision(record DecisionRecord) {
+	if cs.decisionLog == nil || cs.replayMode {
+		return
+	}
+	record.Time = cs.clock.Now()
+	if record.Height == 0 {
+		record.Height = cs.roundState.Height()
+		record.Round = cs.roundState.Round()
+	}
+	select {
+	case cs.decisionLog.records <- record:
+	default:
+		cs.metrics.DecisionLogDropped.Add(1)
+	}
+}
+
+// signAddDecidedVote logs the decision to vote for hash for reason, then
+// signs and adds the vote.
+func (cs *State) signAddDecidedVote(
+	ctx context.Context,
+	msgType tmproto.SignedMsgType,
+	reason string,
+	hash []byte,
+	header types.PartSetHeader,
+) {
+	kind := DecisionPrevote
+	if msgType == tmproto.PrecommitType {
+		kind = DecisionPrecommit
+	}
+	cs.logDecision(DecisionRecord{Kind: kind, Reason: reason, BlockHash: hash})
+	cs.signAddVote(ctx, msgType, hash, header)
+}
+
+// logProposalDecision logs the acceptance of proposal received from peerID,
+// or its rejection with err. Proposals ignored because they do not apply to
+// the current round or one is already set are not logged.
+func (cs *State) logProposalDecision(proposal *types.Proposal, peerID types.NodeID, err error) {
+	reason := ProposalAccepted
+	if err != nil {
+		reason = err.Error()
+	} else if cs.roundState.Proposal() != proposal {
+		return
+	}
+	cs.logDecision(DecisionRecord{
+		Height:    proposal.Height,
+		Round:     proposal.Round,
+		Kind:      DecisionProposal,
+		Reason:    reason,
+		BlockHash: proposal.BlockID.Hash,
+		Peer:      peerID,
+	})
+}
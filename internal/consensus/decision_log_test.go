@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecisionLog(t *testing.T) {
+	records, err := ParseDecisionLog(strings.NewReader(`{"time":"2022-08-01T00:00:00Z","height":3,"round":1,"kind":"new-round","reason":"timeout"}
+
+{"time":"2022-08-01T00:00:01Z","height":3,"round":1,"kind":"prevote","reason":"valid-unlocked","block_hash":"0A0B"}
+{"time":"2022-08-01T00:00:02Z","height":3,"round":1,"kind":"commit","block_hash":"0A0B","timing":{"consensus":3000000000,"save":2000000,"apply":5000000}}
+`))
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	require.Equal(t, DecisionRecord{
+		Time:   time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC),
+		Height: 3,
+		Round:  1,
+		Kind:   DecisionNewRound,
+		Reason: "timeout",
+	}, records[0])
+	require.Equal(t, DecisionPrevote, records[1].Kind)
+	require.Equal(t, []byte{0x0a, 0x0b}, []byte(records[1].BlockHash))
+	require.Equal(t, &CommitTiming{Consensus: 3 * time.Second, Save: 2 * time.Millisecond, Apply: 5 * time.Millisecond},
+		records[2].Timing)
+
+	_, err = ParseDecisionLog(strings.NewReader("{\"height\":1}\n{\"height\":\n"))
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestStateDecisionLogFullHeight(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	cs.config.DecisionLogPath = path
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+
+	// the records are flushed once the writer has caught up
+	var records []DecisionRecord
+	require.Eventually(t, func() bool {
+		f, err := os.Open(path)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		all, err := ParseDecisionLog(f)
+		require.NoError(t, err)
+
+		records = records[:0]
+		for _, record := range all {
+			if record.Height == height {
+				records = append(records, record)
+			}
+		}
+		return len(records) > 0 && records[len(records)-1].Kind == DecisionCommit
+	}, 10*time.Second, 10*time.Millisecond)
+
+	kinds := make([]DecisionKind, len(records))
+	reasons := make([]string, len(records))
+	for i, record := range records {
+		kinds[i] = record.Kind
+		reasons[i] = record.Reason
+	}
+	require.Equal(t, []DecisionKind{
+		DecisionNewRound,
+		DecisionProposal,
+		DecisionPrevote,
+		DecisionLock,
+		DecisionPrecommit,
+		DecisionCommit,
+	}, kinds)
+	require.Equal(t, []string{
+		"timeout",
+		ProposalAccepted,
+		"valid-unlocked",
+		string(LockTriggerNewLock),
+		"lock",
+		"",
+	}, reasons)
+
+	blockHash := records[len(records)-1].BlockHash
+	require.NotEmpty(t, blockHash)
+	for _, record := range records[1:] {
+		require.Equal(t, blockHash, record.BlockHash, "%s record", record.Kind)
+		require.Zero(t, record.Round)
+		require.Empty(t, record.Peer)
+	}
+	timing := records[len(records)-1].Timing
+	require.NotNil(t, timing)
+	require.Positive(t, timing.Apply)
+}
@@ -0,0 +1,17 @@
+package consensus
+
+// writeEndHeight writes and syncs the #ENDHEIGHT of height to the WAL, unless
+// this process already wrote it. finalizeCommit is re-entered for the height
+// when applying its block failed after the marker was written, and writing the
+// marker again would only leave a duplicate in the WAL.
+func (cs *State) writeEndHeight(height int64) error {
+	if cs.lastEndHeight == height {
+		cs.logger.Info("#ENDHEIGHT already written to the WAL; skipping the duplicate", "height", height)
+		return nil
+	}
+	if err := cs.walWriteSync(FaultPointEndHeight, EndHeightMessage{height}); err != nil {
+		return err
+	}
+	cs.lastEndHeight = height
+	return nil
+}
@@ -0,0 +1,147 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+)
+
+// failOnceFinalizeApp fails the first FinalizeBlock.
+type failOnceFinalizeApp struct {
+	abci.Application
+	failed atomic.Bool
+}
+
+func (app *failOnceFinalizeApp) FinalizeBlock(ctx context.Context, req *abci.RequestFinalizeBlock) (*abci.ResponseFinalizeBlock, error) {
+	if app.failed.CompareAndSwap(false, true) {
+		return nil, errors.New("finalize block failed")
+	}
+	return app.Application.FinalizeBlock(ctx, req)
+}
+
+// countEndHeights returns the number of #ENDHEIGHT markers of height in wal.
+func countEndHeights(t *testing.T, wal *MemWAL, height int64) int {
+	t.Helper()
+	rd, found, err := wal.SearchForEndHeight(0, &WALSearchOptions{})
+	require.NoError(t, err)
+	require.True(t, found)
+	var n int
+	for _, msg := range readAllWALMessages(t, rd) {
+		if m, ok := msg.EndHeight(); ok && m.Height == height {
+			n++
+		}
+	}
+	return n
+}
+
+func TestStateEndHeightWrittenOnceOnRetry(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app := &failOnceFinalizeApp{Application: kvstore.NewApplication()}
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, application: app})
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+	cs.wal = wal
+	height := cs.roundState.Height()
+
+	// applying the block fails once the #ENDHEIGHT is written
+	startTestRound(ctx, cs, height, 0)
+	require.Eventually(t, app.failed.Load, 10*time.Second, 10*time.Millisecond)
+	cs.mtx.Lock()
+	require.Equal(t, height, cs.roundState.Height())
+	require.Equal(t, cstypes.RoundStepCommit, cs.roundState.Step())
+	require.Equal(t, 1, countEndHeights(t, wal, height))
+
+	// the retry applies the block without writing the marker again
+	cs.tryFinalizeCommit(ctx, height)
+	require.Equal(t, height+1, cs.roundState.Height())
+	cs.mtx.Unlock()
+	require.Equal(t, 1, countEndHeights(t, wal, height))
+}
+
+func TestWALSearchForEndHeightDuplicateMarkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the messages of the failed attempt precede the duplicate marker
+	msgs := []WALMessage{
+		EndHeightMessage{1},
+		timeoutInfo{Height: 1, Round: 1, Step: cstypes.RoundStepCommit},
+		EndHeightMessage{1},
+		timeoutInfo{Height: 2, Step: cstypes.RoundStepPropose},
+	}
+	expectAfterLast := func(t *testing.T, rd io.ReadCloser) {
+		t.Helper()
+		read := readAllWALMessages(t, rd)
+		require.Len(t, read, 1)
+		ti, ok := read[0].TimeoutInfo()
+		require.True(t, ok)
+		require.Equal(t, int64(2), ti.Height)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		wal, _ := startIndexedWAL(ctx, t, filepath.Join(t.TempDir(), "wal"))
+		for _, msg := range msgs {
+			require.NoError(t, wal.Write(msg))
+		}
+		require.NoError(t, wal.FlushAndSync())
+
+		options := &WALSearchOptions{IgnoreDataCorruptionErrors: true}
+		indexed, found, err := wal.SearchForEndHeight(1, options)
+		require.NoError(t, err)
+		require.True(t, found)
+		expectAfterLast(t, indexed)
+
+		scanned, found, err := wal.searchForEndHeight(1, options)
+		require.NoError(t, err)
+		require.True(t, found)
+		expectAfterLast(t, scanned)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		wal := NewMemWAL()
+		require.NoError(t, wal.Start(ctx))
+		for _, msg := range msgs {
+			require.NoError(t, wal.Write(msg))
+		}
+		rd, found, err := wal.SearchForEndHeight(1, &WALSearchOptions{})
+		require.NoError(t, err)
+		require.True(t, found)
+		expectAfterLast(t, rd)
+	})
+}
+
+func TestStateCatchupReplayDuplicateEndHeights(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	height := cs.roundState.Height()
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+	for _, msg := range []WALMessage{
+		timeoutInfo{Height: height, Step: cstypes.RoundStepNewHeight},
+		EndHeightMessage{height - 1},
+		timeoutInfo{Height: height, Step: cstypes.RoundStepNewHeight},
+	} {
+		require.NoError(t, wal.Write(msg))
+	}
+	cs.wal = wal
+
+	// only the messages following the last marker are replayed
+	require.NoError(t, cs.catchupReplay(ctx, height))
+	require.Equal(t, 1, cs.Status().ReplayedMsgs)
+}
@@ -0,0 +1,165 @@
+package consensus
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// errEventBusPublishTimeout is the failure of a publish the event bus did not
+// accept within config.EventBusPublishTimeout.
+var errEventBusPublishTimeout = errors.New("event bus did not accept the event in time")
+
+// EventBusBreakerState is the state of the circuit breaker of the publishes
+// of a State to its event bus.
+type EventBusBreakerState int32
+
+const (
+	// EventBusBreakerClosed is the state of a breaker publishing the events.
+	EventBusBreakerClosed EventBusBreakerState = iota
+	// EventBusBreakerOpen is the state of a breaker skipping the events until
+	// its cool-down elapsed.
+	EventBusBreakerOpen
+	// EventBusBreakerHalfOpen is the state of a breaker probing the event bus
+	// with a publish, closing again if it succeeds.
+	EventBusBreakerHalfOpen
+)
+
+func (s EventBusBreakerState) String() string {
+	switch s {
+	case EventBusBreakerClosed:
+		return "closed"
+	case EventBusBreakerOpen:
+		return "open"
+	case EventBusBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// EventBusBreakerStatus is the status of the circuit breaker of the event bus
+// publishes.
+type EventBusBreakerStatus struct {
+	State EventBusBreakerState
+	// Failures is the number of consecutive failed or timed out publishes.
+	Failures int
+	// OpenedAt is the time the breaker last opened.
+	OpenedAt time.Time
+	// Skipped is the number of events skipped since the breaker last opened.
+	Skipped uint64
+}
+
+// eventBusBreaker is the circuit breaker of the event bus publishes. The
+// events are published under cs.mtx but the status is read without it, so
+// it has its own lock, held by the publishes.
+type eventBusBreaker struct {
+	mtx    sync.Mutex
+	status EventBusBreakerStatus
+	// the outcome of a publish that timed out, until it is read; no event is
+	// published before, keeping their order
+	pending chan error
+}
+
+// EventBusBreaker returns the status of the circuit breaker of the event bus
+// publishes.
+func (cs *State) EventBusBreaker() EventBusBreakerStatus {
+	b := &cs.eventBusBreaker
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.status
+}
+
+// publishEvent publishes data as an event of eventValue to the event bus,
+// within config.EventBusPublishTimeout. While the circuit breaker is open,
+// the event is skipped. An event given up on is not an error of the caller:
+// the consensus state goes on without it, only the errors of the event bus
+// are returned. The internal event switch is not affected.
+func (cs *State) publishEvent(eventValue string, data types.EventData) error {
+	timeout := cs.config.EventBusPublishTimeout
+	if timeout <= 0 {
+		return cs.eventBus.Publish(eventValue, data)
+	}
+
+	b := &cs.eventBusBreaker
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	now := time.Now()
+	switch b.status.State {
+	case EventBusBreakerOpen:
+		if now.Sub(b.status.OpenedAt) < cs.config.EventBusBreakerCooldown {
+			b.status.Skipped++
+			cs.metrics.EventBusSkippedEvents.With("event", eventValue).Add(1)
+			return nil
+		}
+		b.setState(cs, EventBusBreakerHalfOpen)
+	}
+
+	if b.pending != nil {
+		select {
+		case <-b.pending:
+			b.pending = nil
+		default:
+			// the event bus still did not accept the event that timed out
+			b.recordFailure(cs, eventValue, now, errEventBusPublishTimeout)
+			return nil
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cs.eventBus.Publish(eventValue, data) }()
+	timer := time.NewTimer(timeout)
+	var err error
+	select {
+	case err = <-done:
+		timer.Stop()
+	case <-timer.C:
+		err = errEventBusPublishTimeout
+		b.pending = done
+	}
+	if err != nil {
+		b.recordFailure(cs, eventValue, time.Now(), err)
+		if errors.Is(err, errEventBusPublishTimeout) {
+			return nil
+		}
+		return err
+	}
+	if b.status.State == EventBusBreakerHalfOpen {
+		cs.logger.Info("event bus accepts events again, publishing",
+			"skipped", b.status.Skipped, "open_for", time.Since(b.status.OpenedAt))
+		b.status.Skipped = 0
+	}
+	b.status.Failures = 0
+	b.setState(cs, EventBusBreakerClosed)
+	return nil
+}
+
+// recordFailure records the publish of an event of eventValue failed with err
+// at now, opening the breaker on config.EventBusBreakerThreshold consecutive
+// failures or a failed probe. The caller must hold b.mtx.
+func (b *eventBusBreaker) recordFailure(cs *State, eventValue string, now time.Time, err error) {
+	b.status.Failures++
+	cs.logger.Debug("failed publishing event", "event", eventValue, "failures", b.status.Failures, "err", err)
+	if b.status.State != EventBusBreakerHalfOpen && b.status.Failures < cs.config.EventBusBreakerThreshold {
+		return
+	}
+	if b.status.State == EventBusBreakerClosed {
+		cs.logger.Error("event bus publishes failing, skipping events",
+			"event", eventValue, "failures", b.status.Failures, "cooldown", cs.config.EventBusBreakerCooldown)
+		b.status.Skipped = 0
+	}
+	b.status.OpenedAt = now
+	b.setState(cs, EventBusBreakerOpen)
+}
+
+// setState sets the state of the breaker and its metric. The caller must
+// hold b.mtx.
+func (b *eventBusBreaker) setState(cs *State, state EventBusBreakerState) {
+	if b.status.State == state {
+		return
+	}
+	b.status.State = state
+	cs.metrics.EventBusBreakerState.Set(float64(state))
+}
@@ -0,0 +1,84 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/internal/eventbus"
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	tmevents "github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateEventBusBreaker(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a single validator, committing heights on its own
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	cs.config.EventBusPublishTimeout = 10 * time.Millisecond
+	cs.config.EventBusBreakerThreshold = 3
+	cs.config.EventBusBreakerCooldown = 200 * time.Millisecond
+	var fired int
+	require.NoError(t, cs.evsw.AddListenerForEvent("test", types.EventNewRoundStepValue, func(tmevents.EventData) error {
+		fired++
+		return nil
+	}))
+
+	// the event bus of the State, whose subscriber blocks until released;
+	// the block executor keeps its own
+	bus := eventbus.NewDefault(log.NewNopLogger())
+	require.NoError(t, bus.Start(ctx))
+	release := make(chan struct{})
+	require.NoError(t, bus.Observe(ctx, func(tmpubsub.Message) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+	cs.eventBus = bus
+
+	// the publishes time out once the queue of the event bus is full, and the
+	// State keeps committing with the breaker open
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.EventBusBreaker().State != EventBusBreakerClosed
+	}, 5*time.Second, 10*time.Millisecond)
+	height := cs.blockStore.Height()
+	require.Eventually(t, func() bool { return cs.blockStore.Height() >= height+5 }, 5*time.Second, 10*time.Millisecond)
+	status := cs.Status().EventBus
+	require.NotEqual(t, EventBusBreakerClosed, status.State)
+	require.GreaterOrEqual(t, status.Failures, 3)
+	require.Positive(t, status.Skipped)
+	require.False(t, status.OpenedAt.IsZero())
+
+	// the internal event switch is not affected
+	cs.mtx.RLock()
+	require.Greater(t, fired, 5)
+	cs.mtx.RUnlock()
+
+	// once the subscriber recovers, a probe closes the breaker and the events
+	// of the next heights are published
+	close(release)
+	require.Eventually(t, func() bool {
+		return cs.EventBusBreaker().State == EventBusBreakerClosed
+	}, 5*time.Second, 10*time.Millisecond)
+	sub, err := bus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryHeightSummary,
+		Limit:    1000,
+	})
+	require.NoError(t, err)
+	resumed := cs.blockStore.Height()
+	nextCtx, nextCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer nextCancel()
+	msg, err := sub.Next(nextCtx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, msg.Data().(types.EventDataHeightSummary).Height, resumed)
+}
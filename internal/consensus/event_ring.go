@@ -0,0 +1,307 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/libs/log"
+	tmos "github.com/tendermint/tendermint/libs/os"
+)
+
+const (
+	// eventRingQueueSize is the number of records buffered for the event ring
+	// writer. Records are dropped once it is full.
+	eventRingQueueSize = 1000
+
+	// eventRingSlotSize is the size of a record in the event ring file:
+	//
+	//	checksum  4 bytes, CRC-32 of the rest of the slot
+	//	sequence  8 bytes, 0 for an empty slot
+	//	time      8 bytes, Unix nanoseconds
+	//	height    8 bytes
+	//	round     4 bytes
+	//	kind      1 byte
+	//	step      1 byte
+	//	txs       4 bytes
+	//	hash      1 byte length followed by up to 32 bytes
+	eventRingSlotSize = 128
+	eventRingMaxHash  = 32
+)
+
+// EventRingKind is the kind of an event recorded in the event ring.
+type EventRingKind uint8
+
+const (
+	// EventRingNewRoundStep is the entry of a step.
+	EventRingNewRoundStep EventRingKind = iota + 1
+	// EventRingPolka is +2/3 prevotes for a block or nil.
+	EventRingPolka
+	// EventRingLock is a lock on a block.
+	EventRingLock
+	// EventRingRelock is a relock on the locked block.
+	EventRingRelock
+	// EventRingValidBlock is the update of the valid block.
+	EventRingValidBlock
+	// EventRingCommit is the summary of a committed height.
+	EventRingCommit
+)
+
+var eventRingKindNames = map[EventRingKind]string{
+	EventRingNewRoundStep: "NewRoundStep",
+	EventRingPolka:        "Polka",
+	EventRingLock:         "Lock",
+	EventRingRelock:       "Relock",
+	EventRingValidBlock:   "ValidBlock",
+	EventRingCommit:       "Commit",
+}
+
+func (k EventRingKind) String() string {
+	if name, ok := eventRingKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventRingKind(%d)", uint8(k))
+}
+
+// EventRingRecord is a compact record of an event published by the consensus
+// state.
+type EventRingRecord struct {
+	// Seq orders the records of the ring. It keeps increasing across
+	// restarts.
+	Seq    uint64
+	Time   time.Time
+	Kind   EventRingKind
+	Height int64
+	Round  int32
+	// Step is the step of the state when the event was published.
+	Step cstypes.RoundStepType
+	// BlockHash is the block of a lock, relock, valid block or commit.
+	BlockHash tmbytes.HexBytes
+	// NumTxs is the number of txs of a committed block.
+	NumTxs int
+}
+
+func (r EventRingRecord) encode(slot []byte) {
+	for i := range slot {
+		slot[i] = 0
+	}
+	hash := r.BlockHash
+	if len(hash) > eventRingMaxHash {
+		hash = hash[:eventRingMaxHash]
+	}
+	binary.BigEndian.PutUint64(slot[4:], r.Seq)
+	binary.BigEndian.PutUint64(slot[12:], uint64(r.Time.UnixNano()))
+	binary.BigEndian.PutUint64(slot[20:], uint64(r.Height))
+	binary.BigEndian.PutUint32(slot[28:], uint32(r.Round))
+	slot[32] = byte(r.Kind)
+	slot[33] = byte(r.Step)
+	binary.BigEndian.PutUint32(slot[34:], uint32(r.NumTxs))
+	slot[38] = byte(len(hash))
+	copy(slot[39:], hash)
+	binary.BigEndian.PutUint32(slot[0:], crc32.ChecksumIEEE(slot[4:]))
+}
+
+// decodeEventRingSlot decodes the record of slot. It returns false for an
+// empty slot or one whose write was torn by a crash.
+func decodeEventRingSlot(slot []byte) (EventRingRecord, bool) {
+	seq := binary.BigEndian.Uint64(slot[4:])
+	if seq == 0 || binary.BigEndian.Uint32(slot[0:]) != crc32.ChecksumIEEE(slot[4:]) {
+		return EventRingRecord{}, false
+	}
+	hashLen := int(slot[38])
+	if hashLen > eventRingMaxHash {
+		return EventRingRecord{}, false
+	}
+	record := EventRingRecord{
+		Seq:    seq,
+		Time:   time.Unix(0, int64(binary.BigEndian.Uint64(slot[12:]))).UTC(),
+		Height: int64(binary.BigEndian.Uint64(slot[20:])),
+		Round:  int32(binary.BigEndian.Uint32(slot[28:])),
+		Kind:   EventRingKind(slot[32]),
+		Step:   cstypes.RoundStepType(slot[33]),
+		NumTxs: int(binary.BigEndian.Uint32(slot[34:])),
+	}
+	if hashLen > 0 {
+		record.BlockHash = append(tmbytes.HexBytes(nil), slot[39:39+hashLen]...)
+	}
+	return record, true
+}
+
+// ReadEventRing reads the records of the event ring file at path, oldest
+// first. The file needs not have been closed: the records of a crashed node
+// are read up to the last one written, the slots torn by the crash being
+// skipped.
+func ReadEventRing(path string) ([]EventRingRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readEventRing(f)
+}
+
+func readEventRing(r io.Reader) ([]EventRingRecord, error) {
+	var (
+		records []EventRingRecord
+		slot    = make([]byte, eventRingSlotSize)
+	)
+	for {
+		if _, err := io.ReadFull(r, slot); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if record, ok := decodeEventRingSlot(slot); ok {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+	return records, nil
+}
+
+// eventRing writes event records to a file of a fixed number of slots in the
+// background, overwriting the oldest. Records are dropped when the writer
+// falls behind, so the ring never blocks the publication of events.
+type eventRing struct {
+	logger  log.Logger
+	file    *os.File
+	slots   uint64
+	seq     uint64
+	records chan EventRingRecord
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func openEventRing(logger log.Logger, path string, size int) (*eventRing, error) {
+	if err := tmos.EnsureDir(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to ensure event ring directory is in place: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// the sequence continues from the records of the previous runs
+	records, err := readEventRing(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(int64(size) * eventRingSlotSize); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	er := &eventRing{
+		logger:  logger,
+		file:    file,
+		slots:   uint64(size),
+		records: make(chan EventRingRecord, eventRingQueueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if len(records) > 0 {
+		er.seq = records[len(records)-1].Seq
+	}
+	go er.run()
+	return er, nil
+}
+
+func (er *eventRing) run() {
+	defer close(er.done)
+	slot := make([]byte, eventRingSlotSize)
+	for {
+		select {
+		case record := <-er.records:
+			er.write(slot, record)
+			if len(er.records) == 0 {
+				er.sync()
+			}
+		case <-er.quit:
+			for {
+				select {
+				case record := <-er.records:
+					er.write(slot, record)
+				default:
+					er.sync()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (er *eventRing) write(slot []byte, record EventRingRecord) {
+	er.seq++
+	record.Seq = er.seq
+	record.encode(slot)
+	offset := int64((record.Seq-1)%er.slots) * eventRingSlotSize
+	if _, err := er.file.WriteAt(slot, offset); err != nil {
+		er.logger.Error("failed to write event ring record", "err", err)
+	}
+}
+
+func (er *eventRing) sync() {
+	if err := er.file.Sync(); err != nil {
+		er.logger.Error("failed to sync event ring", "err", err)
+	}
+}
+
+// close writes the queued records and closes the file.
+func (er *eventRing) close() {
+	close(er.quit)
+	<-er.done
+	if err := er.file.Close(); err != nil {
+		er.logger.Error("failed to close event ring", "err", err)
+	}
+}
+
+// openEventRing opens the event ring if config.EventRingPath is set.
+func (cs *State) openEventRing() error {
+	if cs.config.EventRingPath == "" {
+		return nil
+	}
+	path := cs.config.EventRingFile()
+	er, err := openEventRing(cs.logger.With("event_ring", path), path, cs.config.EventRingSize)
+	if err != nil {
+		return err
+	}
+	cs.eventRing = er
+	return nil
+}
+
+// closeEventRing closes the event ring, if open.
+func (cs *State) closeEventRing() {
+	if cs.eventRing == nil {
+		return
+	}
+	cs.eventRing.close()
+	cs.eventRing = nil
+}
+
+// recordRingEvent queues record for the event ring, at the current height and
+// round unless set, and the current step. Events replayed from the WAL were
+// recorded before the restart and are not recorded again.
+func (cs *State) recordRingEvent(record EventRingRecord) {
+	if cs.eventRing == nil || cs.replayMode {
+		return
+	}
+	record.Time = cs.clock.Now()
+	if record.Height == 0 {
+		record.Height = cs.roundState.Height()
+		record.Round = cs.roundState.Round()
+	}
+	record.Step = cs.roundState.Step()
+	select {
+	case cs.eventRing.records <- record:
+	default:
+		cs.metrics.EventRingDropped.Add(1)
+	}
+}
@@ -0,0 +1,122 @@
+package consensus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// waitForEventRingSeq waits until the record seq is readable from the event
+// ring file at path.
+func waitForEventRingSeq(t *testing.T, path string, seq uint64) []EventRingRecord {
+	t.Helper()
+	var records []EventRingRecord
+	require.Eventually(t, func() bool {
+		var err error
+		records, err = ReadEventRing(path)
+		require.NoError(t, err)
+		return len(records) > 0 && records[len(records)-1].Seq == seq
+	}, 5*time.Second, 10*time.Millisecond)
+	return records
+}
+
+func TestEventRingRecoversAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "ring")
+	er, err := openEventRing(log.NewNopLogger(), path, 8)
+	require.NoError(t, err)
+	t.Cleanup(er.close)
+
+	// the ring is filled past its capacity and never closed, as by a crash
+	start := time.Now()
+	for height := int64(1); height <= 20; height++ {
+		er.records <- EventRingRecord{
+			Time:      start.Add(time.Duration(height) * time.Second),
+			Kind:      EventRingCommit,
+			Height:    height,
+			Round:     1,
+			Step:      cstypes.RoundStepCommit,
+			BlockHash: []byte{byte(height)},
+			NumTxs:    int(height),
+		}
+	}
+	records := waitForEventRingSeq(t, path, 20)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(8*eventRingSlotSize), info.Size())
+
+	// the most recent records are recovered in order
+	require.Len(t, records, 8)
+	for i, record := range records {
+		height := int64(13 + i)
+		require.Equal(t, uint64(height), record.Seq)
+		require.Equal(t, height, record.Height)
+		require.Equal(t, int32(1), record.Round)
+		require.Equal(t, EventRingCommit, record.Kind)
+		require.Equal(t, cstypes.RoundStepCommit, record.Step)
+		require.Equal(t, []byte{byte(height)}, []byte(record.BlockHash))
+		require.Equal(t, int(height), record.NumTxs)
+		require.True(t, start.Add(time.Duration(height)*time.Second).Equal(record.Time))
+	}
+
+	// a slot torn by the crash is skipped
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff}, int64((20-1)%8)*eventRingSlotSize+20)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	records, err = ReadEventRing(path)
+	require.NoError(t, err)
+	require.Len(t, records, 7)
+	require.Equal(t, uint64(19), records[len(records)-1].Seq)
+
+	// the sequence continues after a restart, from the last record intact
+	restarted, err := openEventRing(log.NewNopLogger(), path, 8)
+	require.NoError(t, err)
+	restarted.records <- EventRingRecord{Kind: EventRingNewRoundStep, Height: 21}
+	restarted.close()
+	records, err = ReadEventRing(path)
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+	require.Equal(t, uint64(13), records[0].Seq)
+	require.Equal(t, uint64(20), records[len(records)-1].Seq)
+	require.Equal(t, int64(21), records[len(records)-1].Height)
+}
+
+func TestStateEventRing(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	cs.config.EventRingPath = filepath.Join(t.TempDir(), "events")
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+	defer cs.Stop()
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the events of the height are recorded in the order they were published
+	var kinds []EventRingKind
+	require.Eventually(t, func() bool {
+		records, err := ReadEventRing(cs.config.EventRingFile())
+		require.NoError(t, err)
+		kinds = kinds[:0]
+		for _, record := range records {
+			if record.Height == height && record.Kind != EventRingNewRoundStep {
+				kinds = append(kinds, record.Kind)
+			}
+		}
+		return len(kinds) > 0 && kinds[len(kinds)-1] == EventRingCommit
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []EventRingKind{EventRingValidBlock, EventRingPolka, EventRingLock, EventRingCommit}, kinds)
+}
@@ -0,0 +1,202 @@
+package consensus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	abcimocks "github.com/tendermint/tendermint/abci/types/mocks"
+	"github.com/tendermint/tendermint/config"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/internal/store"
+	"github.com/tendermint/tendermint/internal/test/factory"
+	tmevents "github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// extensionsBoundary runs a single validator State, with a file WAL and a
+// block executor over a mock application, across the height vote extensions
+// are enabled at, and records the branches the State takes at each height.
+type extensionsBoundary struct {
+	t            *testing.T
+	cfg          *config.Config
+	enableHeight int64
+	app          *abcimocks.Application
+	blockStore   *store.BlockStore
+	stateStore   sm.Store
+	privVal      types.PrivValidator
+
+	mtx sync.Mutex
+	// whether the vote set of the height verifies vote extensions
+	votes map[int64]bool
+	// whether the last commit of the height verifies vote extensions
+	lastCommits map[int64]bool
+	// the precommits of the validator by height
+	precommits map[int64]*types.Vote
+}
+
+func newExtensionsBoundary(ctx context.Context, t *testing.T, enableHeight int64) *extensionsBoundary {
+	t.Helper()
+	cfg := configSetup(t)
+	params := factory.ConsensusParams()
+	params.ABCI.VoteExtensionsEnableHeight = enableHeight
+	// the State waits at each height for the harness to stop it
+	params.Timeout.Commit = 200 * time.Millisecond
+	params.Timeout.BypassCommitTimeout = false
+	state, privVals := makeGenesisState(ctx, t, cfg, genesisStateArgs{Params: params, Validators: 1})
+	stateStore := sm.NewStore(dbm.NewMemDB())
+	require.NoError(t, stateStore.Save(state))
+
+	app := abcimocks.NewApplication(t)
+	app.On("PrepareProposal", mock.Anything, mock.Anything).Return(&abci.ResponsePrepareProposal{}, nil).Maybe()
+	app.On("ProcessProposal", mock.Anything, mock.Anything).Return(&abci.ResponseProcessProposal{
+		Status: abci.ResponseProcessProposal_ACCEPT,
+	}, nil).Maybe()
+	app.On("ExtendVote", mock.Anything, mock.Anything).Return(&abci.ResponseExtendVote{
+		VoteExtension: []byte("extension"),
+	}, nil).Maybe()
+	app.On("FinalizeBlock", mock.Anything, mock.Anything).Return(&abci.ResponseFinalizeBlock{
+		AppHash: []byte("apphash"),
+	}, nil).Maybe()
+	app.On("Commit", mock.Anything).Return(&abci.ResponseCommit{}, nil).Maybe()
+
+	return &extensionsBoundary{
+		t:            t,
+		cfg:          cfg,
+		enableHeight: enableHeight,
+		app:          app,
+		blockStore:   store.NewBlockStore(dbm.NewMemDB()),
+		stateStore:   stateStore,
+		privVal:      privVals[0],
+		votes:        make(map[int64]bool),
+		lastCommits:  make(map[int64]bool),
+		precommits:   make(map[int64]*types.Vote),
+	}
+}
+
+// run starts a State on the stores and the WAL file of the previous run,
+// until it committed height.
+func (b *extensionsBoundary) run(ctx context.Context, height int64) *State {
+	t := b.t
+	t.Helper()
+	cs := newStateWithConfigAndStores(ctx, t, log.NewNopLogger(), b.cfg, b.stateStore, b.privVal, b.app, b.blockStore)
+
+	require.NoError(t, cs.evsw.AddListenerForEvent("test", types.EventNewRoundStepValue, func(data tmevents.EventData) error {
+		rs := data.(*cstypes.RoundState)
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		b.votes[rs.Height] = rs.Votes.ExtensionsEnabled()
+		b.lastCommits[rs.Height] = rs.LastCommit.ExtensionsEnabled()
+		return nil
+	}))
+	require.NoError(t, cs.eventBus.Observe(ctx, func(msg tmpubsub.Message) error {
+		vote := msg.Data().(types.EventDataVote).Vote
+		if vote.Type == tmproto.PrecommitType {
+			b.mtx.Lock()
+			b.precommits[vote.Height] = vote
+			b.mtx.Unlock()
+		}
+		return nil
+	}, types.EventQueryVote))
+
+	require.NoError(t, cs.Start(ctx))
+	require.Eventually(t, func() bool { return b.blockStore.Height() >= height }, 20*time.Second, 10*time.Millisecond)
+	cs.Stop()
+	cs.Wait()
+	return cs
+}
+
+func TestStateCrossesVoteExtensionsEnableHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const enableHeight = 3
+	b := newExtensionsBoundary(ctx, t, enableHeight)
+
+	// the first run stops once the last height without extensions is
+	// committed, the second crosses the enable height after a restart,
+	// reconstructing the last commit of the height below it
+	b.run(ctx, enableHeight-1)
+	cs := b.run(ctx, enableHeight+2)
+	lastHeight := b.blockStore.Height()
+
+	for h := int64(1); h <= lastHeight; h++ {
+		enabled := h >= enableHeight
+
+		// the vote set of the height: NewExtendedHeightVoteSet or
+		// NewHeightVoteSet
+		b.mtx.Lock()
+		votesEnabled, ok := b.votes[h]
+		lastCommitEnabled := b.lastCommits[h]
+		precommit := b.precommits[h]
+		b.mtx.Unlock()
+		require.True(t, ok, "height %d", h)
+		require.Equal(t, enabled, votesEnabled, "height %d", h)
+		if h > 1 {
+			require.Equal(t, h-1 >= enableHeight, lastCommitEnabled, "height %d", h)
+		}
+
+		// the precommit of the validator: extended, or stripped of its
+		// extension
+		require.NotNil(t, precommit, "height %d", h)
+		if enabled {
+			require.Equal(t, []byte("extension"), precommit.Extension, "height %d", h)
+			require.NotEmpty(t, precommit.ExtensionSignature, "height %d", h)
+		} else {
+			require.Empty(t, precommit.Extension, "height %d", h)
+			require.Empty(t, precommit.ExtensionSignature, "height %d", h)
+		}
+
+		// the commit of the height: SaveBlockWithExtendedCommit or SaveBlock
+		commit := b.blockStore.LoadBlockCommit(h)
+		if h == lastHeight {
+			commit = b.blockStore.LoadSeenCommit()
+		}
+		require.NotNil(t, commit, "height %d", h)
+		ec := b.blockStore.LoadBlockExtendedCommit(h)
+		if enabled {
+			require.NotNil(t, ec, "height %d", h)
+			require.NoError(t, ec.EnsureExtensions(), "height %d", h)
+		} else {
+			require.Nil(t, ec, "height %d", h)
+		}
+	}
+
+	// the last commit of the heights around the enable height, as
+	// reconstructed on a restart
+	for _, h := range []int64{enableHeight - 1, enableHeight, enableHeight + 1} {
+		state := cs.GetState()
+		state.LastBlockHeight = h
+		votes, err := cs.loadLastCommit(state)
+		require.NoError(t, err, "height %d", h)
+		require.Equal(t, h >= enableHeight, votes.ExtensionsEnabled(), "height %d", h)
+		require.True(t, votes.HasTwoThirdsMajority(), "height %d", h)
+	}
+
+	// a statesync of the enable height saves its seen commit, and its
+	// backfill the signed header, but not the extended commit the last
+	// commit is reconstructed from
+	synced := store.NewBlockStore(dbm.NewMemDB())
+	meta := b.blockStore.LoadBlockMeta(enableHeight)
+	require.NoError(t, synced.SaveSignedHeader(&types.SignedHeader{
+		Header: &meta.Header,
+		Commit: b.blockStore.LoadBlockCommit(enableHeight),
+	}, meta.BlockID))
+	require.NoError(t, synced.SaveSeenCommit(enableHeight, b.blockStore.LoadBlockCommit(enableHeight)))
+	require.Equal(t, int64(enableHeight), synced.Height())
+	cs.blockStore = synced
+	state := cs.GetState()
+	state.LastBlockHeight = enableHeight
+	var aheadErr *ErrStateAheadOfBlockStore
+	require.ErrorAs(t, cs.checkStateNotAheadOfBlockStore(state), &aheadErr)
+}
@@ -0,0 +1,267 @@
+package consensus
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+)
+
+// FaultPoint identifies a WAL operation of the State into which a
+// FaultInjector can inject a fault.
+//
+// The State handles a failure at each point as it handles a failure of the
+// WAL itself:
+//   - FaultPointPeerMsg, FaultPointTimeout, FaultPointNewStep and
+//     FaultPointCompleteProposal are logged and consensus continues.
+//   - FaultPointSignProposal is logged and the proposal is still signed.
+//   - FaultPointSignVote skips the vote; consensus continues with the votes of
+//     the other validators.
+//   - FaultPointInternalMsg, FaultPointFinalizeCommit and FaultPointEndHeight
+//     halt consensus with a panic, as our own messages and committed blocks
+//     must not be lost.
+type FaultPoint string
+
+const (
+	// FaultPointPeerMsg is the WAL write of a message from a peer.
+	FaultPointPeerMsg FaultPoint = "peer-msg"
+	// FaultPointInternalMsg is the WAL write of a message of this node.
+	FaultPointInternalMsg FaultPoint = "internal-msg"
+	// FaultPointTimeout is the WAL write of a timeout.
+	FaultPointTimeout FaultPoint = "timeout"
+	// FaultPointNewStep is the WAL write of a new round step.
+	FaultPointNewStep FaultPoint = "new-step"
+	// FaultPointSignProposal is the WAL sync before signing a proposal.
+	FaultPointSignProposal FaultPoint = "sign-proposal"
+	// FaultPointSignVote is the WAL sync before signing a vote.
+	FaultPointSignVote FaultPoint = "sign-vote"
+	// FaultPointCompleteProposal is the WAL sync once all the parts of a
+	// proposal are received.
+	FaultPointCompleteProposal FaultPoint = "complete-proposal"
+	// FaultPointFinalizeCommit is the WAL sync when finalizing a commit.
+	FaultPointFinalizeCommit FaultPoint = "finalize-commit"
+	// FaultPointEndHeight is the WAL write of the end of a height.
+	FaultPointEndHeight FaultPoint = "end-height"
+)
+
+// FaultInjector injects faults into the WAL and timeout handling of a State.
+// It is meant for tests hardening the State against failures and is set with
+// WithFaultInjector. Its methods are called from the receive routine.
+type FaultInjector interface {
+	// BeforeWAL is called before the WAL operation at point, at height and
+	// round. It may block to delay the operation. A non-nil error is
+	// returned in place of running the operation.
+	BeforeWAL(point FaultPoint, height int64, round int32) error
+	// TimeoutDeliveries returns how many times a timeout received from the
+	// ticker is handled: 0 drops it, 2 duplicates it.
+	TimeoutDeliveries(height int64, round int32, step cstypes.RoundStepType) int
+}
+
+// WithFaultInjector sets the fault injector of the State.
+func WithFaultInjector(fi FaultInjector) StateOption {
+	return func(cs *State) { cs.faultInjector = fi }
+}
+
+type noopFaultInjector struct{}
+
+func (noopFaultInjector) BeforeWAL(FaultPoint, int64, int32) error { return nil }
+
+func (noopFaultInjector) TimeoutDeliveries(int64, int32, cstypes.RoundStepType) int { return 1 }
+
+// walWrite writes msg to the WAL, unless a fault is injected at point.
+func (cs *State) walWrite(point FaultPoint, msg WALMessage) error {
+	if err := cs.faultInjector.BeforeWAL(point, cs.roundState.Height(), cs.roundState.Round()); err != nil {
+		return err
+	}
+	for _, msg := range walMessages(msg) {
+		if err := cs.wal.Write(msg); err != nil {
+			cs.recordWALWrite(false, err)
+			return err
+		}
+	}
+	cs.recordWALWrite(false, nil)
+	return nil
+}
+
+// walWriteSync writes msg to the WAL and syncs it, unless a fault is injected
+// at point.
+func (cs *State) walWriteSync(point FaultPoint, msg WALMessage) error {
+	if err := cs.faultInjector.BeforeWAL(point, cs.roundState.Height(), cs.roundState.Round()); err != nil {
+		return err
+	}
+	err := cs.wal.WriteSync(msg)
+	cs.recordWALWrite(true, err)
+	return err
+}
+
+// walFlushAndSync syncs the WAL, unless a fault is injected at point.
+func (cs *State) walFlushAndSync(point FaultPoint) error {
+	if err := cs.faultInjector.BeforeWAL(point, cs.roundState.Height(), cs.roundState.Round()); err != nil {
+		return err
+	}
+	err := cs.wal.FlushAndSync()
+	cs.recordWALSync(err)
+	return err
+}
+
+// AnyHeight and AnyRound make a fault rule match every height and round.
+const (
+	AnyHeight int64 = 0
+	AnyRound  int32 = -1
+)
+
+// WALFault is a rule of a ScriptedFaultInjector injecting a fault into a WAL
+// operation.
+type WALFault struct {
+	Point  FaultPoint
+	Height int64
+	Round  int32
+
+	// Probability of injecting the fault into a matching operation. Zero
+	// means always.
+	Probability float64
+	// Times is the number of times the fault is injected. Zero means
+	// unlimited.
+	Times int
+
+	// Delay is waited before the operation.
+	Delay time.Duration
+	// Err, if set, is returned in place of running the operation.
+	Err error
+}
+
+// TimeoutFault is a rule of a ScriptedFaultInjector dropping or duplicating a
+// timeout.
+type TimeoutFault struct {
+	Height int64
+	Round  int32
+	Step   cstypes.RoundStepType
+
+	// Probability of injecting the fault into a matching timeout. Zero means
+	// always.
+	Probability float64
+	// Times is the number of times the fault is injected. Zero means
+	// unlimited.
+	Times int
+
+	// Deliveries is the number of times a matching timeout is handled: 0
+	// drops it, 2 duplicates it.
+	Deliveries int
+}
+
+type faultTrigger struct {
+	height      int64
+	round       int32
+	probability float64
+	times       int
+	injected    int
+}
+
+func (ft *faultTrigger) fire(rng *rand.Rand, height int64, round int32) bool {
+	if (ft.height != AnyHeight && ft.height != height) || (ft.round != AnyRound && ft.round != round) {
+		return false
+	}
+	if ft.times > 0 && ft.injected >= ft.times {
+		return false
+	}
+	if ft.probability > 0 && rng.Float64() >= ft.probability {
+		return false
+	}
+	ft.injected++
+	return true
+}
+
+type walFaultRule struct {
+	faultTrigger
+	fault WALFault
+}
+
+type timeoutFaultRule struct {
+	faultTrigger
+	fault TimeoutFault
+}
+
+// ScriptedFaultInjector is a FaultInjector injecting the faults of its rules.
+// When several rules match, the first one added wins.
+type ScriptedFaultInjector struct {
+	mtx           sync.Mutex
+	rng           *rand.Rand
+	walFaults     []*walFaultRule
+	timeoutFaults []*timeoutFaultRule
+	injected      int
+}
+
+var _ FaultInjector = (*ScriptedFaultInjector)(nil)
+
+// NewScriptedFaultInjector returns a ScriptedFaultInjector without rules. The
+// probabilistic rules are drawn from a source seeded with seed.
+func NewScriptedFaultInjector(seed int64) *ScriptedFaultInjector {
+	// nolint:gosec // G404: Use of weak random number generator
+	return &ScriptedFaultInjector{rng: rand.New(rand.NewSource(seed))}
+}
+
+// AddWALFault adds a rule injecting a fault into the WAL.
+func (fi *ScriptedFaultInjector) AddWALFault(fault WALFault) {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+
+	fi.walFaults = append(fi.walFaults, &walFaultRule{
+		faultTrigger: faultTrigger{height: fault.Height, round: fault.Round, probability: fault.Probability, times: fault.Times},
+		fault:        fault,
+	})
+}
+
+// AddTimeoutFault adds a rule dropping or duplicating timeouts.
+func (fi *ScriptedFaultInjector) AddTimeoutFault(fault TimeoutFault) {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+
+	fi.timeoutFaults = append(fi.timeoutFaults, &timeoutFaultRule{
+		faultTrigger: faultTrigger{height: fault.Height, round: fault.Round, probability: fault.Probability, times: fault.Times},
+		fault:        fault,
+	})
+}
+
+// Injected returns the number of faults injected so far.
+func (fi *ScriptedFaultInjector) Injected() int {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	return fi.injected
+}
+
+// BeforeWAL implements FaultInjector.
+func (fi *ScriptedFaultInjector) BeforeWAL(point FaultPoint, height int64, round int32) error {
+	fi.mtx.Lock()
+	var fault *WALFault
+	for _, rule := range fi.walFaults {
+		if rule.fault.Point == point && rule.fire(fi.rng, height, round) {
+			fault = &rule.fault
+			fi.injected++
+			break
+		}
+	}
+	fi.mtx.Unlock()
+
+	if fault == nil {
+		return nil
+	}
+	if fault.Delay > 0 {
+		time.Sleep(fault.Delay)
+	}
+	return fault.Err
+}
+
+// TimeoutDeliveries implements FaultInjector.
+func (fi *ScriptedFaultInjector) TimeoutDeliveries(height int64, round int32, step cstypes.RoundStepType) int {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+
+	for _, rule := range fi.timeoutFaults {
+		if rule.fault.Step == step && rule.fire(fi.rng, height, round) {
+			fi.injected++
+			return rule.fault.Deliveries
+		}
+	}
+	return 1
+}
@@ -0,0 +1,49 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+)
+
+func TestScriptedFaultInjector(t *testing.T) {
+	errFault := errors.New("fault")
+
+	fi := NewScriptedFaultInjector(1)
+	fi.AddWALFault(WALFault{Point: FaultPointSignVote, Height: 2, Round: AnyRound, Times: 2, Err: errFault})
+	fi.AddWALFault(WALFault{Point: FaultPointSignVote, Height: AnyHeight, Round: 1, Err: errFault})
+	fi.AddTimeoutFault(TimeoutFault{Height: 3, Round: 0, Step: cstypes.RoundStepPrecommitWait, Deliveries: 2})
+	fi.AddTimeoutFault(TimeoutFault{Height: 4, Round: AnyRound, Step: cstypes.RoundStepPropose, Times: 1, Deliveries: 0})
+
+	// height and round targeted rules
+	require.NoError(t, fi.BeforeWAL(FaultPointSignVote, 1, 0))
+	require.NoError(t, fi.BeforeWAL(FaultPointSignProposal, 2, 0))
+	require.ErrorIs(t, fi.BeforeWAL(FaultPointSignVote, 2, 0), errFault)
+	require.ErrorIs(t, fi.BeforeWAL(FaultPointSignVote, 2, 3), errFault)
+	require.NoError(t, fi.BeforeWAL(FaultPointSignVote, 2, 0))
+	require.ErrorIs(t, fi.BeforeWAL(FaultPointSignVote, 5, 1), errFault)
+
+	require.Equal(t, 1, fi.TimeoutDeliveries(3, 0, cstypes.RoundStepPrevoteWait))
+	require.Equal(t, 2, fi.TimeoutDeliveries(3, 0, cstypes.RoundStepPrecommitWait))
+	require.Equal(t, 1, fi.TimeoutDeliveries(3, 1, cstypes.RoundStepPrecommitWait))
+	require.Equal(t, 0, fi.TimeoutDeliveries(4, 2, cstypes.RoundStepPropose))
+	require.Equal(t, 1, fi.TimeoutDeliveries(4, 2, cstypes.RoundStepPropose))
+	require.Equal(t, 5, fi.Injected())
+
+	// probabilistic rules are reproducible with the seed
+	draw := func() []bool {
+		fi := NewScriptedFaultInjector(42)
+		fi.AddWALFault(WALFault{Point: FaultPointPeerMsg, Round: AnyRound, Probability: 0.5, Err: errFault})
+		faults := make([]bool, 100)
+		for i := range faults {
+			faults[i] = fi.BeforeWAL(FaultPointPeerMsg, int64(i+1), 0) != nil
+		}
+		require.Greater(t, fi.Injected(), 20)
+		require.Less(t, fi.Injected(), 80)
+		return faults
+	}
+	require.Equal(t, draw(), draw())
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"sync/atomic"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// BlockPartGossip counts the block part bytes received from peers in a height.
+type BlockPartGossip struct {
+	Height int64
+	// ReceivedBytes is the size of all the parts of the height received,
+	// including duplicates and parts of other rounds' blocks.
+	ReceivedBytes int64
+	// UselessBytes is the size of the parts received in the height that could
+	// not be used: parts of another height, parts received while no block was
+	// expected and parts of another block.
+	UselessBytes int64
+}
+
+// blockPartGossip accumulates the block part bytes received from peers in the
+// current height. It is written under the State mutex and may be read
+// without it.
+type blockPartGossip struct {
+	height        atomic.Int64
+	receivedBytes atomic.Int64
+	uselessBytes  atomic.Int64
+}
+
+func (g *blockPartGossip) reset(height int64) {
+	g.height.Store(height)
+	g.receivedBytes.Store(0)
+	g.uselessBytes.Store(0)
+}
+
+func (g *blockPartGossip) load() BlockPartGossip {
+	return BlockPartGossip{
+		Height:        g.height.Load(),
+		ReceivedBytes: g.receivedBytes.Load(),
+		UselessBytes:  g.uselessBytes.Load(),
+	}
+}
+
+// recordBlockPart counts the bytes of part received from peerID at height.
+// Parts of another height are only counted as useless. Our own parts are not
+// counted.
+func (cs *State) recordBlockPart(peerID types.NodeID, height int64, part *types.Part, useless bool) {
+	if peerID == "" {
+		return
+	}
+	size := int64(len(part.Bytes))
+	if height == cs.blockPartGossip.height.Load() {
+		cs.blockPartGossip.receivedBytes.Add(size)
+	} else {
+		useless = true
+	}
+	if useless {
+		cs.blockPartGossip.uselessBytes.Add(size)
+		cs.metrics.BlockPartUselessBytes.Add(float64(size))
+	}
+}
+
+// recordBlockPartAmplification exports how many times the size of the block
+// of blockSize bytes was received from peers in the current height. Nothing is
+// exported if the block was not received from peers.
+func (cs *State) recordBlockPartAmplification(blockSize int64) {
+	received := cs.blockPartGossip.receivedBytes.Load()
+	if received == 0 || blockSize <= 0 {
+		return
+	}
+	cs.metrics.BlockPartAmplification.Observe(float64(received) / float64(blockSize))
+	if wasted := received - blockSize; wasted > 0 {
+		cs.metrics.BlockPartWastedBytes.Add(float64(wasted))
+	}
+}
@@ -0,0 +1,148 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/types"
+)
+
+// HeightEvidencePackage is the log of the messages of a height, signed off by
+// a validator to report a byzantine fault of another validator.
+type HeightEvidencePackage struct {
+	ChainID string `json:"chain_id"`
+	Height  int64  `json:"height,string"`
+	// ValidatorAddress is the address of the validator signing off the
+	// package.
+	ValidatorAddress types.Address `json:"validator_address"`
+	// Messages are the messages of the height as written to the WAL, in
+	// order: the messages received from the peers, with their peer IDs, our
+	// own messages, the timeouts and the steps of the state machine. The
+	// time of a message is the time it was written to the WAL, right after
+	// it was received.
+	Messages []TimedWALMessage `json:"messages"`
+	// FinalRoundState is the last step of the height in the WAL.
+	FinalRoundState types.EventDataRoundState `json:"final_round_state"`
+	// OwnVotes are the votes of the validator in the height.
+	OwnVotes []*types.Vote `json:"own_votes"`
+}
+
+// signedHeightEvidencePackage is the serialization of a HeightEvidencePackage.
+// The package is kept as the bytes whose digest was signed.
+type signedHeightEvidencePackage struct {
+	Package   json.RawMessage `json:"package"`
+	Signature []byte          `json:"signature"`
+}
+
+// ExportHeightEvidencePackage returns the log of the messages of height in the
+// WAL, up to now if height is the current height, signed off by the private
+// validator. The same WAL always exports the same bytes.
+func (cs *State) ExportHeightEvidencePackage(height int64) ([]byte, error) {
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator == nil || pubKey == nil {
+		return nil, errPubKeyIsNotSet
+	}
+	signer, ok := privValidator.(types.HeightEvidencePackageSigner)
+	if !ok {
+		return nil, fmt.Errorf("private validator %T can not sign height evidence packages", privValidator)
+	}
+
+	cs.mtx.RLock()
+	wal, chainID, currentHeight := cs.wal, cs.state.ChainID, cs.roundState.Height()
+	cs.mtx.RUnlock()
+	if height <= 0 || height > currentHeight {
+		return nil, fmt.Errorf("can not export height %d, the current height is %d", height, currentHeight)
+	}
+
+	msgs, err := heightWALMessages(wal, height)
+	if err != nil {
+		return nil, err
+	}
+	pkg := HeightEvidencePackage{
+		ChainID:          chainID,
+		Height:           height,
+		ValidatorAddress: pubKey.Address(),
+		Messages:         msgs,
+		OwnVotes:         []*types.Vote{},
+	}
+	for _, msg := range msgs {
+		switch m := msg.Msg.(type) {
+		case types.EventDataRoundState:
+			pkg.FinalRoundState = m
+		case msgInfo:
+			if vm, ok := m.Msg.(*VoteMessage); ok && m.PeerID == "" && bytes.Equal(vm.Vote.ValidatorAddress, pkg.ValidatorAddress) {
+				pkg.OwnVotes = append(pkg.OwnVotes, vm.Vote)
+			}
+		}
+	}
+
+	bz, err := json.Marshal(pkg)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(bz)
+	sig, err := signer.SignHeightEvidencePackage(context.Background(), chainID, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("signing height evidence package: %w", err)
+	}
+	return json.Marshal(signedHeightEvidencePackage{Package: bz, Signature: sig})
+}
+
+// heightWALMessages returns the messages of wal between the #ENDHEIGHT of the
+// previous height and the one of height, or the end of the WAL.
+func heightWALMessages(wal WAL, height int64) ([]TimedWALMessage, error) {
+	if err := wal.FlushAndSync(); err != nil {
+		return nil, err
+	}
+	rd, found, err := wal.SearchForEndHeight(height-1, &WALSearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("the WAL does not contain height %d", height)
+	}
+	defer rd.Close()
+
+	msgs := []TimedWALMessage{}
+	dec := NewWALDecoder(rd)
+	for {
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if endHeight, ok := msg.EndHeight(); ok && endHeight.Height == height {
+			return msgs, nil
+		}
+		msgs = append(msgs, *msg)
+	}
+}
+
+// VerifyHeightEvidencePackage checks that bz is a height evidence package
+// signed off by the validator of pubKey, and returns it.
+func VerifyHeightEvidencePackage(bz []byte, pubKey crypto.PubKey) (*HeightEvidencePackage, error) {
+	var signed signedHeightEvidencePackage
+	if err := json.Unmarshal(bz, &signed); err != nil {
+		return nil, fmt.Errorf("decoding height evidence package: %w", err)
+	}
+	var pkg HeightEvidencePackage
+	if err := json.Unmarshal(signed.Package, &pkg); err != nil {
+		return nil, fmt.Errorf("decoding height evidence package: %w", err)
+	}
+	if !bytes.Equal(pubKey.Address(), pkg.ValidatorAddress) {
+		return nil, fmt.Errorf("height evidence package of validator %v, not %v", pkg.ValidatorAddress, pubKey.Address())
+	}
+	digest := sha256.Sum256(signed.Package)
+	if !pubKey.VerifySignature(types.HeightEvidencePackageSignBytes(pkg.ChainID, digest[:]), signed.Signature) {
+		return nil, errors.New("invalid height evidence package signature")
+	}
+	return &pkg, nil
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+func TestStateExportHeightEvidencePackage(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := NewMemWAL()
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithWAL(wal)}})
+	height := cs.roundState.Height()
+	pubKey := cs.getPrivValidatorPubKey()
+	require.NotNil(t, pubKey)
+
+	require.NoError(t, cs.Start(ctx))
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height+1
+	}, 10*time.Second, 10*time.Millisecond)
+	cs.Stop()
+
+	bz, err := cs.ExportHeightEvidencePackage(height)
+	require.NoError(t, err)
+	pkg, err := VerifyHeightEvidencePackage(bz, pubKey)
+	require.NoError(t, err)
+	require.Equal(t, cs.state.ChainID, pkg.ChainID)
+	require.Equal(t, height, pkg.Height)
+	require.EqualValues(t, pubKey.Address(), pkg.ValidatorAddress)
+	require.NotEmpty(t, pkg.Messages)
+	require.Equal(t, height, pkg.FinalRoundState.Height)
+	require.Equal(t, cstypes.RoundStepCommit.String(), pkg.FinalRoundState.Step)
+
+	// our prevote and precommit for the committed block
+	require.Len(t, pkg.OwnVotes, 2)
+	require.Equal(t, tmproto.PrevoteType, pkg.OwnVotes[0].Type)
+	require.Equal(t, tmproto.PrecommitType, pkg.OwnVotes[1].Type)
+	meta := cs.blockStore.LoadBlockMeta(height)
+	for _, vote := range pkg.OwnVotes {
+		require.Equal(t, height, vote.Height)
+		require.Equal(t, meta.BlockID, vote.BlockID)
+	}
+
+	// the export is deterministic
+	again, err := cs.ExportHeightEvidencePackage(height)
+	require.NoError(t, err)
+	require.Equal(t, bz, again)
+
+	// the package can not be altered, nor be attributed to another validator
+	var signed signedHeightEvidencePackage
+	require.NoError(t, json.Unmarshal(bz, &signed))
+	signed.Package = bytes.Replace(signed.Package, []byte(`"chain_id":"`), []byte(`"chain_id":"other-`), 1)
+	tampered, err := json.Marshal(signed)
+	require.NoError(t, err)
+	_, err = VerifyHeightEvidencePackage(tampered, pubKey)
+	require.Error(t, err)
+	_, err = VerifyHeightEvidencePackage(bz, ed25519.GenPrivKey().PubKey())
+	require.Error(t, err)
+
+	// the heights not reached yet can not be exported
+	_, err = cs.ExportHeightEvidencePackage(cs.roundState.Height() + 1)
+	require.Error(t, err)
+}
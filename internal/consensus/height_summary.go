@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// polkaTimes records when the prevotes of each round of the current height
+// first reached +2/3 for a block.
+type polkaTimes struct {
+	height int64
+	rounds map[int32]time.Time
+}
+
+// markPolka records the time prevotes, the prevotes of the round of vote,
+// reached +2/3 for a block, if they just did.
+func (cs *State) markPolka(vote *types.Vote, prevotes *types.VoteSet) {
+	pt := &cs.polkaTimes
+	if pt.height != vote.Height || pt.rounds == nil {
+		pt.height = vote.Height
+		pt.rounds = make(map[int32]time.Time)
+	}
+	if _, ok := pt.rounds[vote.Round]; ok {
+		return
+	}
+	if blockID, ok := prevotes.TwoThirdsMajority(); ok && !blockID.IsNil() {
+		pt.rounds[vote.Round] = time.Now()
+	}
+}
+
+// heightSummary summarizes height, whose block was just committed and
+// applied in applyTime. It must be called before the State moves to the next
+// height.
+func (cs *State) heightSummary(height int64, block *types.Block, applyTime, finality time.Duration) types.EventDataHeightSummary {
+	commitRound := cs.roundState.CommitRound()
+	precommits := cs.roundState.Votes().Precommits(commitRound)
+	summary := types.EventDataHeightSummary{
+		Height:        height,
+		Rounds:        commitRound + 1,
+		Proposer:      cs.roundState.Validators().GetProposer().Address,
+		BlockHash:     block.Hash(),
+		NumTxs:        len(block.Txs),
+		BlockSize:     block.Size(),
+		Validators:    cs.roundState.Validators().Size(),
+		ApplyDuration: applyTime,
+		Finality:      finality,
+	}
+	summary.ProposalPeer, summary.BlockPeer = cs.proposalDeliveryOf(commitRound, block)
+	for _, vote := range precommits.List() {
+		if block.HashesTo(vote.BlockID.Hash) {
+			summary.CommitSignatures++
+		}
+	}
+
+	if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil && cs.roundState.Validators().HasAddress(pubKey.Address()) {
+		addr := pubKey.Address()
+		summary.Proposed = cs.proposalTimeline.height == height && !cs.proposalTimeline.signed.IsZero()
+		summary.Prevoted = cs.roundState.Votes().Prevotes(commitRound).GetByAddress(addr) != nil
+		summary.Precommitted = precommits.GetByAddress(addr) != nil
+	}
+
+	var polka time.Time
+	if cs.polkaTimes.height == height {
+		polka = cs.polkaTimes.rounds[commitRound]
+	}
+	if proposal := cs.roundState.Proposal(); proposal != nil && proposal.Round == commitRound &&
+		!polka.IsZero() && !cs.roundState.ProposalReceiveTime().IsZero() {
+		summary.ProposeToPolka = polka.Sub(cs.roundState.ProposalReceiveTime())
+	}
+	if commitTime := cs.roundState.CommitTime(); !polka.IsZero() && !commitTime.IsZero() {
+		summary.PolkaToCommit = commitTime.Sub(polka)
+	}
+	return summary
+}
+
+// publishHeightSummary publishes the summary of a committed height.
+func (cs *State) publishHeightSummary(summary types.EventDataHeightSummary) {
+	if err := cs.publishEvent(types.EventHeightSummaryValue, summary); err != nil {
+		cs.logger.Error("failed publishing height summary", "height", summary.Height, "err", err)
+	}
+	cs.recordRingEvent(EventRingRecord{
+		Kind:      EventRingCommit,
+		Height:    summary.Height,
+		Round:     summary.Rounds - 1,
+		BlockHash: summary.BlockHash,
+		NumTxs:    summary.NumTxs,
+	})
+}
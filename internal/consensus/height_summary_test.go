@@ -0,0 +1,71 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateHeightSummary(t *testing.T) {
+	config := configSetup(t)
+
+	for _, tc := range []struct {
+		name string
+		// skipRounds are the rounds this node does not propose in
+		skipRounds int32
+	}{
+		{name: "single round"},
+		{name: "multiple rounds", skipRounds: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// a single validator, proposing in each round
+			cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+			cs.decideProposal = func(ctx context.Context, height int64, round int32) {
+				if round >= tc.skipRounds {
+					cs.defaultDecideProposal(ctx, height, round)
+				}
+			}
+			pubKey, err := cs.privValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			// the node keeps committing heights after the first one
+			sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+				ClientID: testSubscriber,
+				Query:    types.EventQueryHeightSummary,
+				Limit:    10,
+			})
+			require.NoError(t, err)
+
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+			startTestRound(ctx, cs, height, round)
+			nextCtx, nextCancel := context.WithTimeout(ctx, ensureTimeout)
+			defer nextCancel()
+			msg, err := sub.Next(nextCtx)
+			require.NoError(t, err)
+			summary := msg.Data().(types.EventDataHeightSummary)
+			block := cs.blockStore.LoadBlock(height)
+			require.Equal(t, height, summary.Height)
+			require.Equal(t, tc.skipRounds+1, summary.Rounds)
+			require.Equal(t, pubKey.Address(), summary.Proposer)
+			require.Equal(t, block.Hash(), summary.BlockHash)
+			require.Equal(t, len(block.Txs), summary.NumTxs)
+			require.Equal(t, block.Size(), summary.BlockSize)
+			require.Equal(t, 1, summary.CommitSignatures)
+			require.Equal(t, 1, summary.Validators)
+			require.True(t, summary.Proposed)
+			require.True(t, summary.Prevoted)
+			require.True(t, summary.Precommitted)
+			require.Positive(t, summary.ProposeToPolka)
+			require.Positive(t, summary.PolkaToCommit)
+			require.Positive(t, summary.ApplyDuration)
+			require.Equal(t, types.PeerOriginSelf, summary.ProposalPeer)
+			require.Equal(t, types.PeerOriginSelf, summary.BlockPeer)
+		})
+	}
+}
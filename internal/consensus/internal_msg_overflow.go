@@ -0,0 +1,156 @@
+package consensus
+
+import (
+	"context"
+	"sync"
+)
+
+// internalMsgOverflowCap is the number of internal messages the overflow
+// holds at most. Once it is reached, a critical error is logged and the
+// senders block until the overflow is drained below it: the messages are
+// signed by this node and dropping them could stall consensus. It is far
+// above the number of messages a single transition of the receive routine
+// sends, so that the receive routine itself never blocks on it.
+var internalMsgOverflowCap = 100000
+
+// internalMsgOverflow queues the internal messages sent while the internal
+// message queue is full, in order, until a single drainer moves them to the
+// queue. Once it holds messages, the following ones are queued behind them so
+// that the receive routine gets the internal messages in the order they were
+// sent.
+type internalMsgOverflow struct {
+	mtx      sync.Mutex
+	msgs     []overflowMsg
+	bytes    int
+	draining bool
+	// whether the cap was reached since the overflow was last empty
+	capExceeded bool
+	// room is closed once the overflow is drained below the cap, for the
+	// senders blocked on it; nil if none is
+	room chan struct{}
+}
+
+// overflowMsg is an internal message of the overflow and its encoded size.
+type overflowMsg struct {
+	mi   msgInfo
+	size int
+}
+
+// sendInternalMessage sends a msg into the receiveRoutine regarding our own
+// proposal, block part, or vote. When the internal message queue is full, the
+// message is queued in the overflow; it only blocks while the overflow is at
+// its cap.
+func (cs *State) sendInternalMessage(ctx context.Context, mi msgInfo) {
+	o := &cs.internalMsgOverflow
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if len(o.msgs) == 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case cs.internalMsgQueue <- mi:
+			return
+		default:
+		}
+	}
+
+	for len(o.msgs) >= internalMsgOverflowCap {
+		if !o.capExceeded {
+			o.capExceeded = true
+			cs.logger.Error("CONSENSUS FAILURE!!! the internal messages overflowing the internal msg queue reached their cap; "+
+				"the receive routine is not processing messages, blocking the senders",
+				"depth", len(o.msgs), "bytes", o.bytes, "cap", internalMsgOverflowCap)
+		}
+		if o.room == nil {
+			o.room = make(chan struct{})
+		}
+		room := o.room
+		o.mtx.Unlock()
+		select {
+		case <-ctx.Done():
+			o.mtx.Lock()
+			return
+		case <-room:
+		}
+		o.mtx.Lock()
+	}
+
+	size := internalMsgSize(mi)
+	o.msgs = append(o.msgs, overflowMsg{mi: mi, size: size})
+	o.bytes += size
+	cs.metrics.InternalMsgOverflowDepth.Set(float64(len(o.msgs)))
+	cs.metrics.InternalMsgOverflowBytes.Set(float64(o.bytes))
+	if !o.draining {
+		o.draining = true
+		cs.logger.Debug("internal msg queue is full; queueing in the overflow")
+		cs.spawn(func() { cs.drainInternalMsgOverflow(ctx) })
+	}
+}
+
+// drainInternalMsgOverflow moves the messages of the overflow to the internal
+// message queue, in order, until the overflow is empty or ctx is done.
+func (cs *State) drainInternalMsgOverflow(ctx context.Context) {
+	o := &cs.internalMsgOverflow
+	for {
+		o.mtx.Lock()
+		if len(o.msgs) == 0 {
+			o.draining = false
+			o.capExceeded = false
+			o.mtx.Unlock()
+			return
+		}
+		// the message stays in the overflow while it is sent, so that the
+		// messages sent meanwhile are queued behind it
+		msg := o.msgs[0]
+		o.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			o.mtx.Lock()
+			o.msgs, o.bytes, o.draining, o.capExceeded = nil, 0, false, false
+			o.releaseSenders()
+			o.mtx.Unlock()
+			cs.metrics.InternalMsgOverflowDepth.Set(0)
+			cs.metrics.InternalMsgOverflowBytes.Set(0)
+			return
+		case cs.internalMsgQueue <- msg.mi:
+		}
+
+		o.mtx.Lock()
+		o.msgs[0] = overflowMsg{}
+		o.msgs = o.msgs[1:]
+		o.bytes -= msg.size
+		if len(o.msgs) < internalMsgOverflowCap {
+			o.releaseSenders()
+		}
+		cs.metrics.InternalMsgOverflowDepth.Set(float64(len(o.msgs)))
+		cs.metrics.InternalMsgOverflowBytes.Set(float64(o.bytes))
+		o.mtx.Unlock()
+	}
+}
+
+// releaseSenders wakes up the senders blocked on the cap. The caller must
+// hold o.mtx.
+func (o *internalMsgOverflow) releaseSenders() {
+	if o.room != nil {
+		close(o.room)
+		o.room = nil
+	}
+}
+
+// internalMsgSize returns the encoded size of the message of mi.
+func internalMsgSize(mi msgInfo) int {
+	if composite, ok := mi.Msg.(*ProposalAndBlockPartsMessage); ok {
+		var size int
+		for _, msg := range composite.msgs() {
+			size += internalMsgSize(msgInfo{Msg: msg})
+		}
+		return size
+	}
+	pb, err := MsgToProto(mi.Msg)
+	if err != nil {
+		return 0
+	}
+	return pb.Size()
+}
@@ -0,0 +1,116 @@
+package consensus
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateInternalMsgOverflow(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	depth := generic.NewGauge("internal_msg_overflow_depth")
+	cs.metrics.InternalMsgOverflowDepth = depth
+
+	// the receive routine is not running, so the messages above the size of
+	// the queue overflow
+	const msgs = 50000
+	goroutines := runtime.NumGoroutine()
+	for i := 1; i <= msgs; i++ {
+		cs.sendInternalMessage(ctx, msgInfo{Msg: &HasVoteMessage{Height: int64(i)}})
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines+1)
+	overflow := msgs - cap(cs.internalMsgQueue)
+	require.Equal(t, float64(overflow), depth.Value())
+	cs.internalMsgOverflow.mtx.Lock()
+	require.False(t, cs.internalMsgOverflow.capExceeded)
+	require.Positive(t, cs.internalMsgOverflow.bytes)
+	cs.internalMsgOverflow.mtx.Unlock()
+
+	// the messages are received in the order they were sent
+	for i := 1; i <= msgs; i++ {
+		select {
+		case mi := <-cs.internalMsgQueue:
+			require.Equal(t, int64(i), mi.Msg.(*HasVoteMessage).Height)
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+	require.Eventually(t, func() bool {
+		cs.internalMsgOverflow.mtx.Lock()
+		defer cs.internalMsgOverflow.mtx.Unlock()
+		return !cs.internalMsgOverflow.draining
+	}, time.Second, time.Millisecond)
+	require.Zero(t, depth.Value())
+	require.Zero(t, cs.internalMsgOverflow.bytes)
+	require.False(t, cs.internalMsgOverflow.capExceeded)
+
+	// once drained, the messages go to the queue directly
+	cs.sendInternalMessage(ctx, msgInfo{Msg: &HasVoteMessage{Height: msgs + 1}})
+	require.Len(t, cs.internalMsgQueue, 1)
+}
+
+func TestStateInternalMsgOverflowCap(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	defer func(cap int) { internalMsgOverflowCap = cap }(internalMsgOverflowCap)
+	internalMsgOverflowCap = 100
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	depth := generic.NewGauge("internal_msg_overflow_depth")
+	cs.metrics.InternalMsgOverflowDepth = depth
+
+	// the receive routine is not running: the queue and the overflow fill up
+	// to the cap
+	full := cap(cs.internalMsgQueue) + internalMsgOverflowCap
+	for i := 1; i <= full; i++ {
+		cs.sendInternalMessage(ctx, msgInfo{Msg: &HasVoteMessage{Height: int64(i)}})
+	}
+	require.Equal(t, float64(internalMsgOverflowCap), depth.Value())
+
+	// the sender of the next message blocks
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		cs.sendInternalMessage(ctx, msgInfo{Msg: &HasVoteMessage{Height: int64(full + 1)}})
+	}()
+	select {
+	case <-sent:
+		t.Fatal("expected the sender to block at the cap")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cs.internalMsgOverflow.mtx.Lock()
+	require.True(t, cs.internalMsgOverflow.capExceeded)
+	require.Len(t, cs.internalMsgOverflow.msgs, internalMsgOverflowCap)
+	cs.internalMsgOverflow.mtx.Unlock()
+
+	// until a message is received, and all of them are received in order
+	for i := 1; i <= full+1; i++ {
+		select {
+		case mi := <-cs.internalMsgQueue:
+			require.Equal(t, int64(i), mi.Msg.(*HasVoteMessage).Height)
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected the sender to be unblocked")
+	}
+	require.Eventually(t, func() bool {
+		cs.internalMsgOverflow.mtx.Lock()
+		defer cs.internalMsgOverflow.mtx.Unlock()
+		return !cs.internalMsgOverflow.draining
+	}, time.Second, time.Millisecond)
+	require.False(t, cs.internalMsgOverflow.capExceeded)
+}
@@ -117,6 +117,7 @@ func invalidDoPrevoteFunc(
 	// - disable privValidator (so we don't do normal precommits)
 	go func() {
 		cs.mtx.Lock()
+		cs.privValidatorMtx.Lock()
 		cs.privValidator = pv
 
 		pubKey, err := cs.privValidator.GetPubKey(ctx)
@@ -145,6 +146,7 @@ func invalidDoPrevoteFunc(
 
 		precommit.Signature = p.Signature
 		cs.privValidator = nil // disable priv val so we don't do normal votes
+		cs.privValidatorMtx.Unlock()
 		cs.mtx.Unlock()
 
 		r.mtx.Lock()
@@ -0,0 +1,70 @@
+package consensus
+
+import (
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Outcomes of the precommits for the previous height.
+const (
+	// lastCommitVoteAccepted is the outcome of a precommit added to the last
+	// commit during the timeoutCommit window.
+	lastCommitVoteAccepted = "accepted"
+	// lastCommitVoteIgnored is the outcome of a precommit arriving after the
+	// NewHeight step, once the timeoutCommit window is over.
+	lastCommitVoteIgnored = "ignored"
+)
+
+// LastCommitCompleteness is the number of precommits of the last commit
+// received out of the size of the validator set of the previous height.
+type LastCommitCompleteness struct {
+	Votes      int
+	Validators int
+}
+
+// recordLastCommitVote records the outcome of a precommit for the previous
+// height and its arrival since the start of the NewHeight step.
+func (cs *State) recordLastCommitVote(vote *types.Vote, outcome string) {
+	offset := tmtime.Now().Sub(cs.newHeightStart)
+	if offset < 0 {
+		offset = 0
+	}
+	cs.metrics.LastCommitVoteArrival.With("outcome", outcome).Observe(offset.Seconds())
+	cs.metrics.LastCommitVotes.With("outcome", outcome).Add(1)
+	if cs.config.LastCommitValidatorMetrics {
+		cs.metrics.LastCommitVotesByValidator.With(
+			"validator_address", vote.ValidatorAddress.String(),
+			"outcome", outcome,
+		).Add(1)
+	}
+
+	switch outcome {
+	case lastCommitVoteIgnored:
+		cs.logger.Debug("precommit vote came in after commit timeout and has been ignored",
+			"vote", vote, "since_new_height", offset, "step", cs.roundState.Step())
+	default:
+		cs.logger.Debug("added vote to last precommits",
+			"last_commit", cs.roundState.LastCommit().StringShort(), "since_new_height", offset)
+	}
+}
+
+// lastCommitCompleteness returns the completeness of the last commit during
+// the NewHeight step, and nil outside of it.
+func (cs *State) lastCommitCompleteness() *LastCommitCompleteness {
+	if cs.roundState.Step() != cstypes.RoundStepNewHeight {
+		return nil
+	}
+	lastCommit := cs.roundState.LastCommit()
+	if lastCommit == nil {
+		return nil
+	}
+	completeness := &LastCommitCompleteness{Validators: lastCommit.Size()}
+	votes := lastCommit.BitArray()
+	for i := 0; i < votes.Size(); i++ {
+		if votes.GetIndex(i) {
+			completeness.Votes++
+		}
+	}
+	return completeness
+}
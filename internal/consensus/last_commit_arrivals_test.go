@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otrace "go.opentelemetry.io/otel/trace"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateClassifiesLastCommitVotes(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.LastCommitValidatorMetrics = true
+	votes := newLabeledCounter()
+	cs.metrics.LastCommitVotes = votes
+	byValidator := newLabeledCounter()
+	cs.metrics.LastCommitVotesByValidator = byValidator
+
+	// move to the next height, whose last commit has no precommit yet
+	height := cs.roundState.Height()
+	vals := cs.state.Validators.Copy()
+	cs.roundState.SetLastCommit(types.NewVoteSet(cs.state.ChainID, height, 0, tmproto.PrecommitType, vals))
+	state := cs.state.Copy()
+	state.LastBlockHeight = height
+	state.LastValidators = state.Validators
+	require.True(t, cs.updateToState(state, stateUpdateSourceFinalize))
+	require.Equal(t, cstypes.RoundStepNewHeight, cs.roundState.Step())
+	require.Equal(t, &LastCommitCompleteness{Votes: 0, Validators: 4}, cs.Status().LastCommit)
+
+	blockID := types.BlockID{Hash: make([]byte, 32), PartSetHeader: types.PartSetHeader{Total: 1, Hash: make([]byte, 32)}}
+	precommit := func(vs *validatorStub) *types.Vote {
+		vs.Height = height
+		vote := signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), blockID)
+		// the last commit does not carry vote extensions
+		vote.Extension, vote.ExtensionSignature = nil, nil
+		return vote
+	}
+	span := otrace.SpanFromContext(ctx)
+
+	// a late precommit arriving during the timeoutCommit window is accepted
+	late := precommit(vss[1])
+	added, err := cs.addVote(ctx, late, "peer", types.VoteUnverified, span)
+	require.NoError(t, err)
+	require.True(t, added)
+	require.Equal(t, 1.0, votes.values["outcome,accepted"])
+	require.Equal(t, 1.0, byValidator.values["validator_address,"+late.ValidatorAddress.String()+",outcome,accepted"])
+	require.Equal(t, &LastCommitCompleteness{Votes: 1, Validators: 4}, cs.Status().LastCommit)
+
+	// once past the NewHeight step, it is ignored
+	cs.updateRoundStep(0, cstypes.RoundStepNewRound)
+	require.Nil(t, cs.Status().LastCommit)
+	tooLate := precommit(vss[2])
+	added, err = cs.addVote(ctx, tooLate, "peer", types.VoteUnverified, span)
+	require.NoError(t, err)
+	require.False(t, added)
+	require.Equal(t, 1.0, votes.values["outcome,ignored"])
+	require.Equal(t, 1.0, byValidator.values["validator_address,"+tooLate.ValidatorAddress.String()+",outcome,ignored"])
+	require.Equal(t, 1.0, votes.values["outcome,accepted"])
+	require.Nil(t, cs.roundState.LastCommit().GetByAddress(tooLate.ValidatorAddress))
+
+	// the per validator tallies are behind a flag
+	cs.config.LastCommitValidatorMetrics = false
+	added, err = cs.addVote(ctx, precommit(vss[3]), "peer", types.VoteUnverified, span)
+	require.NoError(t, err)
+	require.False(t, added)
+	require.Equal(t, 2.0, votes.values["outcome,ignored"])
+	require.Len(t, byValidator.values, 2)
+}
@@ -0,0 +1,100 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// lateProposal is a proposal of an earlier round of the height, received once
+// the round was skipped, for the block +2/3 prevoted for in its round while
+// this node lacked it. Its block is received in place of dropping the
+// proposal, for the block to become the valid block of the height.
+type lateProposal struct {
+	proposal *types.Proposal
+	parts    *types.PartSet
+}
+
+// setLateProposal sets proposal, of an earlier round of the height, as the
+// late proposal if it is signed by the proposer of its round, +2/3 prevoted
+// for its block in the round and the block would be the valid block of the
+// height but this node lacks it. It returns whether it did.
+func (cs *State) setLateProposal(proposal *types.Proposal) bool {
+	if proposal.Height != cs.roundState.Height() || proposal.Round < 0 || proposal.Round >= cs.roundState.Round() {
+		return false
+	}
+	if cs.lateProposal != nil && cs.lateProposal.proposal.Round >= proposal.Round {
+		return false
+	}
+	if cs.roundState.ValidRound() >= proposal.Round {
+		return false
+	}
+	blockID, ok := cs.roundState.Votes().Prevotes(proposal.Round).TwoThirdsMajority()
+	if !ok || blockID.IsNil() || !blockID.Equals(proposal.BlockID) {
+		return false
+	}
+	if cs.roundState.ProposalBlock().HashesTo(blockID.Hash) || cs.roundState.ValidBlock().HashesTo(blockID.Hash) {
+		return false
+	}
+	if proposal.POLRound < -1 || (proposal.POLRound >= 0 && proposal.POLRound >= proposal.Round) {
+		return false
+	}
+
+	validators := cs.state.Validators.Copy()
+	if proposal.Round > 0 {
+		validators.IncrementProposerPriority(proposal.Round)
+	}
+	proposer := validators.GetProposer()
+	p := proposal.ToProto()
+	if !proposer.PubKey.VerifySignature(types.ProposalSignBytes(cs.state.ChainID, p), proposal.Signature) {
+		return false
+	}
+
+	cs.logger.Info("accepting the late proposal of a skipped round for its polka block",
+		"height", proposal.Height, "round", proposal.Round, "current_round", cs.roundState.Round(),
+		"block_hash", blockID.Hash)
+	cs.lateProposal = &lateProposal{
+		proposal: proposal,
+		parts:    types.NewPartSetFromHeader(blockID.PartSetHeader),
+	}
+	return true
+}
+
+// addLateProposalBlockPart adds the part of msg to the block of the late
+// proposal, if it is one of its parts. Once the block is complete, it becomes
+// the valid block of the height, unless a later round set one meanwhile.
+func (cs *State) addLateProposalBlockPart(msg *BlockPartMessage) (handled, added bool, err error) {
+	late := cs.lateProposal
+	if late == nil || msg.Height != late.proposal.Height || msg.Round != late.proposal.Round {
+		return false, false, nil
+	}
+	added, err = late.parts.AddPart(msg.Part)
+	if err != nil || !added || !late.parts.IsComplete() {
+		return true, added, err
+	}
+
+	cs.lateProposal = nil
+	block, err := cs.blockFromParts(late.parts)
+	if err != nil {
+		return true, added, err
+	}
+	if !block.HashesTo(late.proposal.BlockID.Hash) {
+		return true, added, nil
+	}
+	round := late.proposal.Round
+	if cs.roundState.ValidRound() >= round {
+		return true, added, nil
+	}
+
+	cs.logger.Info("updating valid block to the block of a late proposal",
+		"valid_round", round, "valid_block_hash", block.Hash())
+	cs.roundState.SetValidRound(round)
+	cs.roundState.SetValidBlock(block)
+	cs.roundState.SetValidBlockParts(late.parts)
+
+	roundState := cs.roundState.CopyInternal()
+	cs.evsw.FireEvent(types.EventValidBlockValue, roundState)
+	if err := cs.publishEvent(types.EventValidBlockValue, cs.roundState.RoundStateEvent()); err != nil {
+		return true, added, err
+	}
+	cs.recordRingEvent(EventRingRecord{Kind: EventRingValidBlock, Round: round, Height: block.Height, BlockHash: block.Hash()})
+	return true, added, nil
+}
@@ -0,0 +1,65 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateAcceptsLateProposalOfPolka(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 4})
+	height, lateRound := cs.roundState.Height(), int32(1)
+	validBlockCh := subscribe(ctx, t, cs.eventBus, types.EventQueryValidBlock)
+
+	// the proposer of the round the node skips past
+	validators := cs.state.Validators.Copy()
+	validators.IncrementProposerPriority(lateRound)
+	var proposer *validatorStub
+	for _, vs := range vss {
+		pubKey, err := vs.GetPubKey(ctx)
+		require.NoError(t, err)
+		if bytes.Equal(pubKey.Address(), validators.GetProposer().Address) {
+			proposer = vs
+		}
+	}
+	require.NotNil(t, proposer)
+	proposal, block := decideProposal(ctx, t, cs, proposer, height, lateRound)
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	cs.enterNewRound(ctx, height, 3, "test")
+
+	// a proposal of the skipped round without a polka is dropped
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	require.Nil(t, cs.lateProposal)
+
+	incrementRound(vss[1:]...)
+	for _, vs := range vss[1:] {
+		vote := signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), proposal.BlockID)
+		cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	require.Equal(t, int32(-1), cs.roundState.ValidRound())
+
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	require.NotNil(t, cs.lateProposal)
+	for i := 0; i < int(parts.Total()); i++ {
+		msg := &BlockPartMessage{Height: height, Round: lateRound, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+
+	require.Nil(t, cs.lateProposal)
+	require.Equal(t, lateRound, cs.roundState.ValidRound())
+	require.True(t, cs.roundState.ValidBlock().HashesTo(block.Hash()))
+	require.Equal(t, int32(3), cs.roundState.Round())
+	ensureMessageBeforeTimeout(t, validBlockCh, ensureTimeout)
+}
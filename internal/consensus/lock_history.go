@@ -0,0 +1,144 @@
+package consensus
+
+import (
+	"bytes"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otrace "go.opentelemetry.io/otel/trace"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+// lockHistoryHeights is the number of most recent heights whose lock events
+// are kept.
+const lockHistoryHeights = 5
+
+// LockTrigger describes why the node locked on a block.
+type LockTrigger string
+
+const (
+	// LockTriggerNewLock is a lock on the proposal block after +2/3 prevotes
+	// for it.
+	LockTriggerNewLock LockTrigger = "new-lock"
+	// LockTriggerPolkaRelock is a relock on the already locked block after
+	// +2/3 prevotes for it in a later round.
+	LockTriggerPolkaRelock LockTrigger = "polka-relock"
+)
+
+// LockEvent records a single lock or relock of the node in enterPrecommit.
+// Locks are only released when the node moves to the next height.
+type LockEvent struct {
+	Height    int64
+	Round     int32
+	BlockHash tmbytes.HexBytes
+	Trigger   LockTrigger
+	// PrevotePower is the voting power that prevoted for the block in Round
+	// when the lock happened, out of TotalPower.
+	PrevotePower int64
+	TotalPower   int64
+	Time         time.Time
+}
+
+// LockInfo is the block the node is locked on and the valid block of the
+// current height.
+type LockInfo struct {
+	Height int64
+	// LockedRound is -1 and LockedBlockHash nil if the node is not locked.
+	LockedRound     int32
+	LockedBlockHash tmbytes.HexBytes
+	// ValidRound is -1 and ValidBlockHash nil if there is no valid block.
+	ValidRound     int32
+	ValidBlockHash tmbytes.HexBytes
+	// LockedIsValid is whether the locked block is the valid block.
+	LockedIsValid bool
+}
+
+func newLockInfo(height int64, lockedRound int32, lockedBlock *types.Block, validRound int32, validBlock *types.Block) LockInfo {
+	info := LockInfo{
+		Height:      height,
+		LockedRound: lockedRound,
+		ValidRound:  validRound,
+	}
+	if lockedBlock != nil {
+		info.LockedBlockHash = lockedBlock.Hash()
+	}
+	if validBlock != nil {
+		info.ValidBlockHash = validBlock.Hash()
+	}
+	info.LockedIsValid = info.LockedBlockHash != nil && bytes.Equal(info.LockedBlockHash, info.ValidBlockHash)
+	return info
+}
+
+// LockInfo returns the block the node is locked on and the valid block of the
+// current height.
+// While the WAL is replayed, it returns those before the replay.
+func (cs *State) LockInfo() LockInfo {
+	rs := cs.GetRoundState()
+	return newLockInfo(rs.Height, rs.LockedRound, rs.LockedBlock, rs.ValidRound, rs.ValidBlock)
+}
+
+// lockInfo returns the LockInfo of the round state.
+func (cs *State) lockInfo() LockInfo {
+	return newLockInfo(cs.roundState.Height(), cs.roundState.LockedRound(), cs.roundState.LockedBlock(),
+		cs.roundState.ValidRound(), cs.roundState.ValidBlock())
+}
+
+// recordLock appends a lock event for the block locked in round to the lock
+// history and adds it to span.
+func (cs *State) recordLock(span otrace.Span, round int32, blockID types.BlockID, trigger LockTrigger) {
+	vals := cs.roundState.Validators()
+	prevotes := cs.roundState.Votes().Prevotes(round).BitArrayByBlockID(blockID)
+
+	event := LockEvent{
+		Height:     cs.roundState.Height(),
+		Round:      round,
+		BlockHash:  blockID.Hash,
+		Trigger:    trigger,
+		TotalPower: vals.TotalVotingPower(),
+		Time:       tmtime.Now(),
+	}
+	for i, val := range vals.Validators {
+		if prevotes.GetIndex(i) {
+			event.PrevotePower += val.VotingPower
+		}
+	}
+
+	// drop the events of heights that are no longer kept
+	keep := 0
+	for keep < len(cs.lockHistory) && cs.lockHistory[keep].Height <= event.Height-lockHistoryHeights {
+		keep++
+	}
+	cs.lockHistory = append(cs.lockHistory[keep:], event)
+	cs.logDecision(DecisionRecord{
+		Height:    event.Height,
+		Round:     round,
+		Kind:      DecisionLock,
+		Reason:    string(trigger),
+		BlockHash: blockID.Hash,
+	})
+
+	info := cs.lockInfo()
+	span.SetAttributes(
+		attribute.String("lock.trigger", string(trigger)),
+		attribute.String("lock.block_hash", blockID.Hash.String()),
+		attribute.Int64("lock.prevote_power", event.PrevotePower),
+		attribute.Int64("lock.total_power", event.TotalPower),
+		attribute.Int("lock.locked_round", int(info.LockedRound)),
+		attribute.Int("lock.valid_round", int(info.ValidRound)),
+		attribute.String("lock.valid_block_hash", info.ValidBlockHash.String()),
+		attribute.Bool("lock.locked_is_valid", info.LockedIsValid),
+	)
+}
+
+// LockHistory returns the lock events of the last few heights, oldest first.
+func (cs *State) LockHistory() []LockEvent {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	history := make([]LockEvent, len(cs.lockHistory))
+	copy(history, cs.lockHistory)
+	return history
+}
@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// The operations the holds of the consensus state mutex are measured by,
+// besides the handling of each type of message.
+const (
+	lockHoldTimeout             = "handle_timeout"
+	lockHoldTxsAvailable        = "handle_txs_available"
+	lockHoldProposalCandidate   = "validate_proposal_candidate"
+	lockHoldUpdateToState       = "update_to_state"
+	lockHoldFinalizeCommitCheck = "finalize_commit_check"
+	lockHoldFinalizeCommitSave  = "finalize_commit_save"
+	lockHoldFinalizeCommitApply = "finalize_commit_apply"
+	lockHoldPubKeyRecovery      = "pubkey_recovery_catch_up"
+)
+
+// lockHoldStackDepth is the number of frames of the stack context logged
+// with a hold over config.LockHoldWarnThreshold.
+const lockHoldStackDepth = 8
+
+var nopLockHoldDuration = discard.NewHistogram()
+
+// lockHoldMsgOp returns the operation the handling of msg is measured by.
+func lockHoldMsgOp(msg Message) string {
+	switch msg.(type) {
+	case *ProposalMessage:
+		return "handle_proposal"
+	case *BlockPartMessage:
+		return "handle_block_part"
+	case *ProposalAndBlockPartsMessage:
+		return "handle_proposal_and_block_parts"
+	case *VoteMessage:
+		return "handle_vote"
+	default:
+		return "handle_msg"
+	}
+}
+
+// lockHold measures the time cs.mtx is held by an operation, or a section of
+// one. It is not timed if neither the metrics nor the warnings are enabled.
+type lockHold struct {
+	cs    *State
+	op    string
+	start time.Time
+}
+
+// lockFor locks cs.mtx for op. The hold ends with the unlock of the returned
+// lockHold.
+func (cs *State) lockFor(op string) lockHold {
+	cs.mtx.Lock()
+	return cs.holdSection(op)
+}
+
+// holdSection starts measuring op, a section of an operation holding cs.mtx
+// already. It ends with the end of the returned lockHold.
+func (cs *State) holdSection(op string) lockHold {
+	h := lockHold{cs: cs, op: op}
+	if cs.config.LockHoldWarnThreshold > 0 || cs.metrics.LockHoldDuration != nopLockHoldDuration {
+		h.start = time.Now()
+	}
+	return h
+}
+
+// end records the hold, once: it is a no-op for a hold ended already.
+func (h *lockHold) end() {
+	if h.start.IsZero() {
+		return
+	}
+	held := time.Since(h.start)
+	h.start = time.Time{}
+
+	cs := h.cs
+	if cs.metrics.LockHoldDuration != nopLockHoldDuration {
+		cs.metrics.LockHoldDuration.With("operation", h.op).Observe(held.Seconds())
+	}
+	if threshold := cs.config.LockHoldWarnThreshold; threshold > 0 && held > threshold {
+		cs.metrics.LockHoldOutliers.With("operation", h.op).Add(1)
+		cs.logger.Error("consensus state lock held for too long",
+			"operation", h.op,
+			"held", held,
+			"threshold", threshold,
+			"height", cs.roundState.Height(),
+			"round", cs.roundState.Round(),
+			"step", cs.roundState.Step(),
+			"stack", lockHoldStack(),
+		)
+	}
+}
+
+// unlock ends the hold and unlocks cs.mtx.
+func (h *lockHold) unlock() {
+	h.end()
+	h.cs.mtx.Unlock()
+}
+
+// lockHoldStack returns the functions of the stack releasing a hold, from the
+// innermost, without the lockHold ones.
+func lockHoldStack() string {
+	pcs := make([]uintptr, lockHoldStackDepth)
+	// skip runtime.Callers, lockHoldStack and lockHold.end
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var funcs []string
+	for {
+		frame, more := frames.Next()
+		if name := frame.Function[strings.LastIndexByte(frame.Function, '/')+1:]; !strings.HasSuffix(name, "(*lockHold).unlock") {
+			funcs = append(funcs, name)
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(funcs, " <- ")
+}
@@ -0,0 +1,107 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// labeledHistogram counts the observations of each set of label values.
+type labeledHistogram struct {
+	counts map[string]int
+	labels string
+}
+
+func newLabeledHistogram() *labeledHistogram {
+	return &labeledHistogram{counts: make(map[string]int)}
+}
+
+func (h *labeledHistogram) With(labelValues ...string) metrics.Histogram {
+	return &labeledHistogram{counts: h.counts, labels: strings.Join(labelValues, ",")}
+}
+
+func (h *labeledHistogram) Observe(float64) { h.counts[h.labels]++ }
+
+func TestStateLockHoldOutlier(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+	cs.config.LockHoldWarnThreshold = 20 * time.Millisecond
+	durations := newLabeledHistogram()
+	outliers := newLabeledCounter()
+	cs.metrics.LockHoldDuration = durations
+	cs.metrics.LockHoldOutliers = outliers
+
+	// the handling of a message is measured by its type
+	vote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer"}, false)
+	require.Equal(t, 1, durations.counts["operation,handle_vote"])
+	require.Empty(t, outliers.values)
+	require.NotContains(t, logs.String(), "lock held for too long")
+
+	// a hold over the threshold is counted and logged with its stack context
+	hold := cs.lockFor("test_hold")
+	time.Sleep(2 * cs.config.LockHoldWarnThreshold)
+	hold.unlock()
+	require.Equal(t, 1.0, outliers.values["operation,test_hold"])
+	require.Contains(t, logs.String(), "consensus state lock held for too long")
+	require.Contains(t, logs.String(), `"operation":"test_hold"`)
+	require.Contains(t, logs.String(), "consensus.TestStateLockHoldOutlier")
+	require.NotContains(t, logs.String(), "lockHold")
+
+	// the hold is recorded once, however often it is ended
+	section := cs.holdSection("test_section")
+	time.Sleep(2 * cs.config.LockHoldWarnThreshold)
+	section.end()
+	section.end()
+	require.Equal(t, 1.0, outliers.values["operation,test_section"])
+}
+
+func BenchmarkStateLockHold(b *testing.B) {
+	config := configSetup(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, b, makeStateArgs{config: config, logger: log.NewNopLogger()})
+	for _, bc := range []struct {
+		name      string
+		threshold time.Duration
+		lock      func()
+	}{
+		{name: "mutex", lock: func() {
+			cs.mtx.Lock()
+			defer cs.mtx.Unlock()
+		}},
+		{name: "nop-metrics-no-warnings", lock: func() {
+			hold := cs.lockFor(lockHoldTimeout)
+			defer hold.unlock()
+		}},
+		{name: "nop-metrics", threshold: 100 * time.Millisecond, lock: func() {
+			hold := cs.lockFor(lockHoldTimeout)
+			defer hold.unlock()
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			cs.config.LockHoldWarnThreshold = bc.threshold
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bc.lock()
+			}
+		})
+	}
+}
@@ -106,13 +106,13 @@ func TestMempoolProgressInHigherRound(t *testing.T) {
 	newBlockCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewBlock)
 	newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
 	timeoutCh := subscribe(ctx, t, cs.eventBus, types.EventQueryTimeoutPropose)
-	cs.setProposal = func(proposal *types.Proposal, recvTime time.Time) error {
+	cs.setProposal = func(proposal *types.Proposal, peerID types.NodeID, recvTime time.Time) error {
 		if cs.roundState.Height() == 2 && cs.roundState.Round() == 0 {
 			// dont set the proposal in round 0 so we timeout and
 			// go to next round
 			return nil
 		}
-		return cs.defaultSetProposal(proposal, recvTime)
+		return cs.defaultSetProposal(proposal, peerID, recvTime)
 	}
 	startTestRound(ctx, cs, height, round)
 
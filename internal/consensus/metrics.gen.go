@@ -76,6 +76,24 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "missing_validators_power",
 			Help:      "Total power of the missing validators.",
 		}, append(labels, "validator_address")).With(labelsAndValues...),
+		MissingValidatorsTotalPower: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "missing_validators_total_power",
+			Help:      "Total power of all the missing validators.",
+		}, labels).With(labelsAndValues...),
+		AbsentValidators: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "absent_validators",
+			Help:      "Number of validators who have not voted in the current height after the absentee grace period.",
+		}, labels).With(labelsAndValues...),
+		AbsentValidatorsPower: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "absent_validators_power",
+			Help:      "Total power of the absent validators.",
+		}, labels).With(labelsAndValues...),
 		ByzantineValidators: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -138,6 +156,18 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "block_parts",
 			Help:      "Number of block parts transmitted by each peer.",
 		}, append(labels, "peer_id")).With(labelsAndValues...),
+		ProposalsDelivered: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposals_delivered",
+			Help:      "Number of committed proposals first delivered by each peer, or by this node for its own proposals (peer_id self).",
+		}, append(labels, "peer_id")).With(labelsAndValues...),
+		BlocksCompleted: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "blocks_completed",
+			Help:      "Number of committed blocks completed by a part from each peer, or by this node for its own proposals (peer_id self).",
+		}, append(labels, "peer_id")).With(labelsAndValues...),
 		StepDuration: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -160,6 +190,50 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "block_gossip_parts_received",
 			Help:      "Number of block parts received by the node, separated by whether the part was relevant to the block the node is trying to gather or not.",
 		}, append(labels, "matches_current")).With(labelsAndValues...),
+		PartsHave: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "parts_have",
+			Help:      "Number of parts of the proposal block of the current round received.",
+		}, labels).With(labelsAndValues...),
+		PartsTotal: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "parts_total",
+			Help:      "Number of parts of the proposal block of the current round.",
+		}, labels).With(labelsAndValues...),
+		BlockPartFirstDelay: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_first_delay",
+			Help:      "Seconds from the receipt of a proposal to the receipt of its first block part.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
+		BlockPartSpread: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_spread",
+			Help:      "Seconds from the receipt of the first block part of a proposal to the receipt of its last one.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
+		BlockPartMaxGap: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_max_gap",
+			Help:      "Longest seconds between the receipts of two consecutive block parts of a proposal.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
+		BlockPartMeanGap: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_mean_gap",
+			Help:      "Mean seconds between the receipts of two consecutive block parts of a proposal.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
 		ProposalBlockCreatedOnPropose: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -178,6 +252,46 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "proposal_missing_txs",
 			Help:      "Number of missing txs when trying to create proposal.",
 		}, labels).With(labelsAndValues...),
+		BlockReconstructionTime: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_reconstruction_time",
+			Help:      "Number of seconds taken to rebuild a proposal block from the mempool.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
+		ProposalBlockSources: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_block_sources",
+			Help:      "Number of proposal blocks rebuilt from tx keys or assembled from block parts.",
+		}, append(labels, "source")).With(labelsAndValues...),
+		ProposalBlockLatency: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_block_latency",
+			Help:      "Seconds from the receipt of a proposal to its block being available.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, append(labels, "source")).With(labelsAndValues...),
+		ProposalKeyRebuildFallbacks: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_key_rebuild_fallbacks",
+			Help:      "Number of heights whose proposal block was assembled from block parts after its rebuild from tx keys missed txs.",
+		}, labels).With(labelsAndValues...),
+		RebuildDivergences: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "rebuild_divergences",
+			Help:      "Number of committed blocks rebuilt from tx keys diverging from the block their stored parts encode.",
+		}, labels).With(labelsAndValues...),
+		RebuildPartSetMismatches: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "rebuild_part_set_mismatches",
+			Help:      "Number of proposal blocks rebuilt from tx keys whose part set did not match the proposal.",
+		}, labels).With(labelsAndValues...),
 		MissingTxs: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -210,18 +324,92 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "vote_extension_receive_count",
 			Help:      "Number of vote extensions received labeled by application response status.",
 		}, append(labels, "status")).With(labelsAndValues...),
+		VoteExtensionRejections: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "vote_extension_rejections",
+			Help:      "Number of vote extensions rejected, by validator address and reason.",
+		}, append(labels, "validator_address", "reason")).With(labelsAndValues...),
 		ProposalReceiveCount: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "proposal_receive_count",
 			Help:      "Total number of proposals received by the node since process start labeled by application response status.",
 		}, append(labels, "status")).With(labelsAndValues...),
+		ProposalRejections: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_rejections",
+			Help:      "Number of proposals received that were not set, by reason.",
+		}, append(labels, "reason")).With(labelsAndValues...),
+		PostCommitProposalDelay: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "post_commit_proposal_delay",
+			Help:      "Time in seconds between the commit of a height and the receipt of a proposal for the committed block.",
+
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5},
+		}, labels).With(labelsAndValues...),
 		ProposalCreateCount: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "proposal_create_count",
 			Help:      "Total number of proposals created by the node since process start.",
 		}, labels).With(labelsAndValues...),
+		ProposalEvidenceBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_evidence_bytes",
+			Help:      "Size in bytes of the evidence of the last block created by the node.",
+		}, labels).With(labelsAndValues...),
+		ProposalEvidenceCount: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_evidence_count",
+			Help:      "Number of evidence in the last block created by the node.",
+		}, labels).With(labelsAndValues...),
+		ProposalEvidenceTrimmed: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_evidence_trimmed",
+			Help:      "Number of blocks created by the node whose evidence was trimmed to the evidence ceiling.",
+		}, labels).With(labelsAndValues...),
+		ProposalCandidates: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_candidates",
+			Help:      "Number of submitted proposal candidates by outcome.",
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		PrecommitWaitSkipped: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "precommit_wait_skipped",
+			Help:      "Number of times the precommit wait was not entered because it was already triggered in the round.",
+		}, labels).With(labelsAndValues...),
+		DoubleSignRefusals: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "double_sign_refusals",
+			Help:      "Number of proposals the private validator refused to sign because it already signed a conflicting one.",
+		}, labels).With(labelsAndValues...),
+		SignHRSRegressions: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "sign_hrs_regressions",
+			Help:      "Number of sign requests refused because they did not advance the height, round and step of the last one.",
+		}, labels).With(labelsAndValues...),
+		SigningHalted: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signing_halted",
+			Help:      "Whether signing is halted by a conflicting vote of this node's key.",
+		}, labels).With(labelsAndValues...),
+		ValidatorSetMember: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "validator_set_member",
+			Help:      "Whether the key of this node is in the validator set of the current height.",
+		}, labels).With(labelsAndValues...),
 		RoundVotingPowerPercent: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -278,6 +466,268 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 
 			Buckets: stdprometheus.ExponentialBucketsRange(0.01, 10, 10),
 		}, labels).With(labelsAndValues...),
+		BlockFinalityLatency: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_finality_latency",
+			Help:      "Time in seconds from the timestamp of a block to its application completing locally.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.1, 100, 8),
+		}, labels).With(labelsAndValues...),
+		BlockFinalityClockSkew: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_finality_clock_skew",
+			Help:      "Number of blocks applied before their timestamp.",
+		}, labels).With(labelsAndValues...),
+		ProposalDisseminationLatency: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_dissemination_latency",
+			Help:      "Number of seconds between the dissemination stages of proposals signed by this node.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, append(labels, "phase")).With(labelsAndValues...),
+		ProposerWaitSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposer_wait_seconds",
+			Help:      "Number of seconds this node waited to propose until its clock passed the previous block time.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.001, 10, 16),
+		}, labels).With(labelsAndValues...),
+		BlockRecoverySeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_recovery_seconds",
+			Help:      "Number of seconds between committing a block the node did not have and receiving it.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.01, 10, 10),
+		}, labels).With(labelsAndValues...),
+		ReceiveRoutineStalls: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "receive_routine_stalls",
+			Help:      "Number of times the consensus receive routine was detected as stalled.",
+		}, labels).With(labelsAndValues...),
+		StateUpdatesIgnored: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "state_updates_ignored",
+			Help:      "Number of state updates ignored by consensus, by the source of the update.",
+		}, append(labels, "source")).With(labelsAndValues...),
+		StatsMsgsDropped: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "stats_msgs_dropped",
+			Help:      "Number of peer statistics messages dropped because the stats queue was full.",
+		}, labels).With(labelsAndValues...),
+		InternalMsgOverflowDepth: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "internal_msg_overflow_depth",
+			Help:      "Number of internal messages waiting for room in the internal message queue.",
+		}, labels).With(labelsAndValues...),
+		InternalMsgOverflowBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "internal_msg_overflow_bytes",
+			Help:      "Encoded size in bytes of the internal messages waiting for room in the internal message queue.",
+		}, labels).With(labelsAndValues...),
+		PeerMsgsBelowReplayFloor: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_msgs_below_replay_floor",
+			Help:      "Number of peer messages dropped because their height is below the height the WAL replay ended at.",
+		}, labels).With(labelsAndValues...),
+		SignerTimestampSkews: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signer_timestamp_skews",
+			Help:      "Number of votes of this node whose timestamp was changed by the signer beyond the tolerance.",
+		}, labels).With(labelsAndValues...),
+		SignerTimestampSkew: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signer_timestamp_skew",
+			Help:      "Difference in seconds between the timestamp of the last vote of this node after and before its signing.",
+		}, labels).With(labelsAndValues...),
+		PeerMsgs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_msgs",
+			Help:      "Number of consensus messages received from each peer, by message type and outcome.",
+		}, append(labels, "peer_id", "msg_type", "outcome")).With(labelsAndValues...),
+		LockHoldDuration: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "lock_hold_duration",
+			Help:      "Time in seconds the consensus state mutex is held, by operation.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.0001, 10, 16),
+		}, append(labels, "operation")).With(labelsAndValues...),
+		LockHoldOutliers: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "lock_hold_outliers",
+			Help:      "Number of times the consensus state mutex was held for longer than the warning threshold, by operation.",
+		}, append(labels, "operation")).With(labelsAndValues...),
+		PastBlockSignatures: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "past_block_signatures",
+			Help:      "Number of signatures of this node's key found in past blocks at startup, by outcome.",
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		PubKeyMissingSeconds: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "pub_key_missing_seconds",
+			Help:      "Number of seconds the private validator was set without its pubkey.",
+		}, labels).With(labelsAndValues...),
+		WALOpenRetries: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "walopen_retries",
+			Help:      "Number of times opening the WAL was retried after a transient error.",
+		}, labels).With(labelsAndValues...),
+		NonValidatorVotes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "non_validator_votes",
+			Help:      "Number of votes from addresses outside the validator set, by whether they were validators at the previous height.",
+		}, append(labels, "kind")).With(labelsAndValues...),
+		StepBudgetExceeded: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "step_budget_exceeded",
+			Help:      "Number of seconds in which the state machine exceeded its step budget and throttled peer messages.",
+		}, labels).With(labelsAndValues...),
+		DecisionLogDropped: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "decision_log_dropped",
+			Help:      "Number of decision log records dropped because the writer fell behind.",
+		}, labels).With(labelsAndValues...),
+		EventRingDropped: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "event_ring_dropped",
+			Help:      "Number of event ring records dropped because the writer fell behind.",
+		}, labels).With(labelsAndValues...),
+		EventBusBreakerState: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "event_bus_breaker_state",
+			Help:      "State of the circuit breaker of the event bus publishes: 0 closed, 1 open, 2 half-open.",
+		}, labels).With(labelsAndValues...),
+		EventBusSkippedEvents: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "event_bus_skipped_events",
+			Help:      "Number of events not published to the event bus while its circuit breaker was open.",
+		}, append(labels, "event")).With(labelsAndValues...),
+		BlockPartAmplification: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_amplification",
+			Help:      "Size of the block parts received in a height divided by the size of the committed block.",
+
+			Buckets: []float64{1, 1.5, 2, 3, 5, 10, 20},
+		}, labels).With(labelsAndValues...),
+		BlockPartWastedBytes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_wasted_bytes",
+			Help:      "Number of block part bytes received in excess of the size of the committed block.",
+		}, labels).With(labelsAndValues...),
+		BlockPartUselessBytes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_part_useless_bytes",
+			Help:      "Number of block part bytes received for another height or block, or while no block was expected.",
+		}, labels).With(labelsAndValues...),
+		VoteExtensionBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "vote_extension_bytes",
+			Help:      "Size in bytes of the vote extensions held in the vote sets of the current height.",
+		}, labels).With(labelsAndValues...),
+		VoteExtensionSize: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "vote_extension_size",
+			Help:      "Size in bytes of the vote extensions added to the vote sets.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(16, 1048576, 9),
+		}, labels).With(labelsAndValues...),
+		AwaitingPOL: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "awaiting_pol",
+			Help:      "Whether the proposal of the current round waits for the prevotes of its POL round.",
+		}, labels).With(labelsAndValues...),
+		POLSolicitations: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "polsolicitations",
+			Help:      "Number of times the prevotes of the POL round of a proposal were solicited from peers.",
+		}, labels).With(labelsAndValues...),
+		ProposalSolicitations: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_solicitations",
+			Help:      "Number of times the proposal of a round was solicited from peers as the prevote of its proposer was received before it.",
+		}, labels).With(labelsAndValues...),
+		ProposeGrace: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "propose_grace",
+			Help:      "Number of extensions of the propose timeout by the propose grace, by outcome.",
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		BlockValidations: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "block_validations",
+			Help:      "Number of validations of blocks, by outcome: validated, or cached if an earlier outcome was reused.",
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		StepTransitions: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "step_transitions",
+			Help:      "Number of transitions of the state machine to a step, by the cause of the transition.",
+		}, append(labels, "step", "entry")).With(labelsAndValues...),
+		OwnPrecommitsExcluded: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "own_precommits_excluded",
+			Help:      "Number of committed blocks whose commit excluded the precommit this validator signed for them.",
+		}, append(labels, "validator_address")).With(labelsAndValues...),
+		OwnPrecommitLateness: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "own_precommit_lateness",
+			Help:      "Seconds between +2/3 precommits for the last block whose commit excluded our precommit and our last precommit for it.",
+		}, append(labels, "validator_address")).With(labelsAndValues...),
+		LastCommitVoteArrival: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "last_commit_vote_arrival",
+			Help:      "Seconds between the start of the NewHeight step and the arrival of the precommits for the previous height.",
+
+			Buckets: stdprometheus.ExponentialBucketsRange(0.01, 10, 10),
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		LastCommitVotes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "last_commit_votes",
+			Help:      "Number of precommits for the previous height accepted into the last commit or ignored after the timeoutCommit window.",
+		}, append(labels, "outcome")).With(labelsAndValues...),
+		LastCommitVotesByValidator: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "last_commit_votes_by_validator",
+			Help:      "Number of precommits for the previous height accepted into the last commit or ignored, by validator.",
+		}, append(labels, "validator_address", "outcome")).With(labelsAndValues...),
 		StepLatency: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -305,6 +755,9 @@ func NopMetrics() *Metrics {
 		ValidatorMissedBlocks:         discard.NewGauge(),
 		MissingValidators:             discard.NewGauge(),
 		MissingValidatorsPower:        discard.NewGauge(),
+		MissingValidatorsTotalPower:   discard.NewGauge(),
+		AbsentValidators:              discard.NewGauge(),
+		AbsentValidatorsPower:         discard.NewGauge(),
 		ByzantineValidators:           discard.NewGauge(),
 		ByzantineValidatorsPower:      discard.NewGauge(),
 		BlockIntervalSeconds:          discard.NewHistogram(),
@@ -315,19 +768,45 @@ func NopMetrics() *Metrics {
 		BlockSyncing:                  discard.NewGauge(),
 		StateSyncing:                  discard.NewGauge(),
 		BlockParts:                    discard.NewCounter(),
+		ProposalsDelivered:            discard.NewCounter(),
+		BlocksCompleted:               discard.NewCounter(),
 		StepDuration:                  discard.NewHistogram(),
 		BlockGossipReceiveLatency:     discard.NewHistogram(),
 		BlockGossipPartsReceived:      discard.NewCounter(),
+		PartsHave:                     discard.NewGauge(),
+		PartsTotal:                    discard.NewGauge(),
+		BlockPartFirstDelay:           discard.NewHistogram(),
+		BlockPartSpread:               discard.NewHistogram(),
+		BlockPartMaxGap:               discard.NewHistogram(),
+		BlockPartMeanGap:              discard.NewHistogram(),
 		ProposalBlockCreatedOnPropose: discard.NewCounter(),
 		ProposalTxs:                   discard.NewGauge(),
 		ProposalMissingTxs:            discard.NewGauge(),
+		BlockReconstructionTime:       discard.NewHistogram(),
+		ProposalBlockSources:          discard.NewCounter(),
+		ProposalBlockLatency:          discard.NewHistogram(),
+		ProposalKeyRebuildFallbacks:   discard.NewCounter(),
+		RebuildDivergences:            discard.NewCounter(),
+		RebuildPartSetMismatches:      discard.NewCounter(),
 		MissingTxs:                    discard.NewGauge(),
 		QuorumPrevoteDelay:            discard.NewGauge(),
 		FullPrevoteDelay:              discard.NewGauge(),
 		ProposalTimestampDifference:   discard.NewHistogram(),
 		VoteExtensionReceiveCount:     discard.NewCounter(),
+		VoteExtensionRejections:       discard.NewCounter(),
 		ProposalReceiveCount:          discard.NewCounter(),
+		ProposalRejections:            discard.NewCounter(),
+		PostCommitProposalDelay:       discard.NewHistogram(),
 		ProposalCreateCount:           discard.NewCounter(),
+		ProposalEvidenceBytes:         discard.NewGauge(),
+		ProposalEvidenceCount:         discard.NewGauge(),
+		ProposalEvidenceTrimmed:       discard.NewCounter(),
+		ProposalCandidates:            discard.NewCounter(),
+		PrecommitWaitSkipped:          discard.NewCounter(),
+		DoubleSignRefusals:            discard.NewCounter(),
+		SignHRSRegressions:            discard.NewCounter(),
+		SigningHalted:                 discard.NewGauge(),
+		ValidatorSetMember:            discard.NewGauge(),
 		RoundVotingPowerPercent:       discard.NewGauge(),
 		LateVotes:                     discard.NewCounter(),
 		FinalRound:                    discard.NewHistogram(),
@@ -336,6 +815,47 @@ func NopMetrics() *Metrics {
 		ConsensusTime:                 discard.NewHistogram(),
 		CompleteProposalTime:          discard.NewHistogram(),
 		ApplyBlockLatency:             discard.NewHistogram(),
+		BlockFinalityLatency:          discard.NewHistogram(),
+		BlockFinalityClockSkew:        discard.NewCounter(),
+		ProposalDisseminationLatency:  discard.NewHistogram(),
+		ProposerWaitSeconds:           discard.NewHistogram(),
+		BlockRecoverySeconds:          discard.NewHistogram(),
+		ReceiveRoutineStalls:          discard.NewCounter(),
+		StateUpdatesIgnored:           discard.NewCounter(),
+		StatsMsgsDropped:              discard.NewCounter(),
+		InternalMsgOverflowDepth:      discard.NewGauge(),
+		InternalMsgOverflowBytes:      discard.NewGauge(),
+		PeerMsgsBelowReplayFloor:      discard.NewCounter(),
+		SignerTimestampSkews:          discard.NewCounter(),
+		SignerTimestampSkew:           discard.NewGauge(),
+		PeerMsgs:                      discard.NewCounter(),
+		LockHoldDuration:              discard.NewHistogram(),
+		LockHoldOutliers:              discard.NewCounter(),
+		PastBlockSignatures:           discard.NewCounter(),
+		PubKeyMissingSeconds:          discard.NewCounter(),
+		WALOpenRetries:                discard.NewCounter(),
+		NonValidatorVotes:             discard.NewCounter(),
+		StepBudgetExceeded:            discard.NewCounter(),
+		DecisionLogDropped:            discard.NewCounter(),
+		EventRingDropped:              discard.NewCounter(),
+		EventBusBreakerState:          discard.NewGauge(),
+		EventBusSkippedEvents:         discard.NewCounter(),
+		BlockPartAmplification:        discard.NewHistogram(),
+		BlockPartWastedBytes:          discard.NewCounter(),
+		BlockPartUselessBytes:         discard.NewCounter(),
+		VoteExtensionBytes:            discard.NewGauge(),
+		VoteExtensionSize:             discard.NewHistogram(),
+		AwaitingPOL:                   discard.NewGauge(),
+		POLSolicitations:              discard.NewCounter(),
+		ProposalSolicitations:         discard.NewCounter(),
+		ProposeGrace:                  discard.NewCounter(),
+		BlockValidations:              discard.NewCounter(),
+		StepTransitions:               discard.NewCounter(),
+		OwnPrecommitsExcluded:         discard.NewCounter(),
+		OwnPrecommitLateness:          discard.NewGauge(),
+		LastCommitVoteArrival:         discard.NewHistogram(),
+		LastCommitVotes:               discard.NewCounter(),
+		LastCommitVotesByValidator:    discard.NewCounter(),
 		StepLatency:                   discard.NewGauge(),
 		StepCount:                     discard.NewGauge(),
 	}
@@ -45,6 +45,13 @@ type Metrics struct {
 	MissingValidators metrics.Gauge
 	// Total power of the missing validators.
 	MissingValidatorsPower metrics.Gauge `metrics_labels:"validator_address"`
+	// Total power of all the missing validators.
+	MissingValidatorsTotalPower metrics.Gauge
+	// Number of validators who have not voted in the current height after
+	// the absentee grace period.
+	AbsentValidators metrics.Gauge
+	// Total power of the absent validators.
+	AbsentValidatorsPower metrics.Gauge
 	// Number of validators who tried to double sign.
 	ByzantineValidators metrics.Gauge
 	// Total power of the byzantine validators.
@@ -68,6 +75,12 @@ type Metrics struct {
 
 	// Number of block parts transmitted by each peer.
 	BlockParts metrics.Counter `metrics_labels:"peer_id"`
+	// Number of committed proposals first delivered by each peer, or by
+	// this node for its own proposals (peer_id self).
+	ProposalsDelivered metrics.Counter `metrics_labels:"peer_id"`
+	// Number of committed blocks completed by a part from each peer, or by
+	// this node for its own proposals (peer_id self).
+	BlocksCompleted metrics.Counter `metrics_labels:"peer_id"`
 
 	// Histogram of durations for each step in the consensus protocol.
 	StepDuration metrics.Histogram `metrics_labels:"step" metrics_buckettype:"exprange" metrics_bucketsizes:"0.1, 100, 8"`
@@ -82,6 +95,28 @@ type Metrics struct {
 	// was relevant to the block the node is trying to gather or not.
 	BlockGossipPartsReceived metrics.Counter `metrics_labels:"matches_current"`
 
+	// PartsHave and PartsTotal are the number of parts of the proposal block
+	// of the current round received and its number of parts.
+	//metrics:Number of parts of the proposal block of the current round received.
+	PartsHave metrics.Gauge
+	//metrics:Number of parts of the proposal block of the current round.
+	PartsTotal metrics.Gauge
+
+	// BlockPartFirstDelay is the time from the receipt of a proposal to the
+	// receipt of the first of its block parts.
+	//metrics:Seconds from the receipt of a proposal to the receipt of its first block part.
+	BlockPartFirstDelay metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+	// BlockPartSpread is the time from the receipt of the first block part of
+	// a proposal to the receipt of its last one.
+	//metrics:Seconds from the receipt of the first block part of a proposal to the receipt of its last one.
+	BlockPartSpread metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+	// BlockPartMaxGap and BlockPartMeanGap are the longest and mean times
+	// between the receipts of two consecutive block parts of a proposal.
+	//metrics:Longest seconds between the receipts of two consecutive block parts of a proposal.
+	BlockPartMaxGap metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+	//metrics:Mean seconds between the receipts of two consecutive block parts of a proposal.
+	BlockPartMeanGap metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+
 	// Number of proposal blocks created on propose received.
 	ProposalBlockCreatedOnPropose metrics.Counter `metrics_labels:"success"`
 
@@ -91,6 +126,40 @@ type Metrics struct {
 	// Number of missing txs when trying to create proposal.
 	ProposalMissingTxs metrics.Gauge
 
+	// BlockReconstructionTime is the time taken to fetch the txs of a
+	// proposal from the mempool when only tx keys are gossiped.
+	//metrics:Number of seconds taken to rebuild a proposal block from the mempool.
+	BlockReconstructionTime metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+
+	// ProposalBlockSources is the number of proposal blocks of the other
+	// validators by how they were obtained: rebuilt from the mempool with the
+	// tx keys of the proposal, or assembled from its block parts.
+	//metrics:Number of proposal blocks rebuilt from tx keys or assembled from block parts.
+	ProposalBlockSources metrics.Counter `metrics_labels:"source"`
+
+	// ProposalBlockLatency is the time from the receipt of a proposal to its
+	// block being available, by source of the block.
+	//metrics:Seconds from the receipt of a proposal to its block being available.
+	ProposalBlockLatency metrics.Histogram `metrics_labels:"source" metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+
+	// ProposalKeyRebuildFallbacks is the number of heights whose proposal
+	// block could not be rebuilt from tx keys for missing txs, and was then
+	// assembled from its block parts.
+	//metrics:Number of heights whose proposal block was assembled from block parts after its rebuild from tx keys missed txs.
+	ProposalKeyRebuildFallbacks metrics.Counter
+
+	// RebuildDivergences is the number of committed blocks rebuilt from tx
+	// keys that serialize otherwise than the block their stored parts encode.
+	// Any is critical.
+	//metrics:Number of committed blocks rebuilt from tx keys diverging from the block their stored parts encode.
+	RebuildDivergences metrics.Counter
+
+	// RebuildPartSetMismatches is the number of proposal blocks rebuilt from
+	// tx keys whose part set did not match the part set header of their
+	// proposal, and were discarded for the parts of the proposal.
+	//metrics:Number of proposal blocks rebuilt from tx keys whose part set did not match the proposal.
+	RebuildPartSetMismatches metrics.Counter
+
 	//Number of missing txs when a proposal is received
 	MissingTxs metrics.Gauge `metrics_labels:"proposer_address"`
 
@@ -124,6 +193,13 @@ type Metrics struct {
 	//metrics:Number of vote extensions received labeled by application response status.
 	VoteExtensionReceiveCount metrics.Counter `metrics_labels:"status"`
 
+	// VoteExtensionRejections is the number of vote extensions rejected, by
+	// the address of their validator and the reason: 'signature' if the
+	// signature of the extension is invalid, 'application' if the application
+	// rejected it.
+	//metrics:Number of vote extensions rejected, by validator address and reason.
+	VoteExtensionRejections metrics.Counter `metrics_labels:"validator_address, reason"`
+
 	// ProposalReceiveCount is the total number of proposals received by this node
 	// since process start.
 	// The metric is annotated by the status of the proposal from the application,
@@ -131,11 +207,68 @@ type Metrics struct {
 	//metrics:Total number of proposals received by the node since process start labeled by application response status.
 	ProposalReceiveCount metrics.Counter `metrics_labels:"status"`
 
+	// ProposalRejections is the number of proposals received that were not
+	// set, by reason: nil, duplicate, wrong-height, wrong-round,
+	// invalid-pol-round, invalid-signature, duplicate-tx-keys,
+	// too-many-tx-keys or post-commit.
+	//metrics:Number of proposals received that were not set, by reason.
+	ProposalRejections metrics.Counter `metrics_labels:"reason"`
+
+	// PostCommitProposalDelay is the time in seconds between the commit of a
+	// height and the receipt of a proposal for the committed block, which is
+	// not set once the height is committed.
+	//metrics:Time in seconds between the commit of a height and the receipt of a proposal for the committed block.
+	PostCommitProposalDelay metrics.Histogram `metrics_bucketsizes:"0.01, 0.05, 0.1, 0.5, 1, 5"`
+
 	// ProposalCreationCount is the total number of proposals created by this node
 	// since process start.
 	//metrics:Total number of proposals created by the node since process start.
 	ProposalCreateCount metrics.Counter
 
+	// ProposalEvidenceBytes is the size of the evidence of the last block
+	// created by this node, and ProposalEvidenceCount its number of evidence.
+	//metrics:Size in bytes of the evidence of the last block created by the node.
+	ProposalEvidenceBytes metrics.Gauge
+	//metrics:Number of evidence in the last block created by the node.
+	ProposalEvidenceCount metrics.Gauge
+	// ProposalEvidenceTrimmed is the number of blocks created by this node
+	// whose evidence was trimmed to config.MaxProposalEvidenceFraction of the
+	// block max bytes.
+	//metrics:Number of blocks created by the node whose evidence was trimmed to the evidence ceiling.
+	ProposalEvidenceTrimmed metrics.Counter
+
+	// ProposalCandidates is the number of proposal candidates submitted with
+	// SubmitProposalCandidate, by whether they were proposed, rejected on
+	// submission, or dropped when deciding the proposal.
+	//metrics:Number of submitted proposal candidates by outcome.
+	ProposalCandidates metrics.Counter `metrics_labels:"outcome"`
+
+	// PrecommitWaitSkipped is the number of times the precommit wait was not
+	// entered because it was already triggered in the round.
+	//metrics:Number of times the precommit wait was not entered because it was already triggered in the round.
+	PrecommitWaitSkipped metrics.Counter
+
+	// DoubleSignRefusals is the number of times the private validator refused
+	// to sign a proposal conflicting with one it already signed.
+	//metrics:Number of proposals the private validator refused to sign because it already signed a conflicting one.
+	DoubleSignRefusals metrics.Counter
+
+	// SignHRSRegressions is the number of sign requests this node refused to
+	// send to its private validator because they did not advance the height,
+	// round and step of the last one.
+	//metrics:Number of sign requests refused because they did not advance the height, round and step of the last one.
+	SignHRSRegressions metrics.Counter `metrics_name:"sign_hrs_regressions"`
+
+	// SigningHalted is 1 while this node does not sign as a vote of its key
+	// conflicting with one it already signed was received.
+	//metrics:Whether signing is halted by a conflicting vote of this node's key.
+	SigningHalted metrics.Gauge
+
+	// ValidatorSetMember is 1 while the key of this node is in the validator
+	// set of the current height.
+	//metrics:Whether the key of this node is in the validator set of the current height.
+	ValidatorSetMember metrics.Gauge
+
 	// RoundVotingPowerPercent is the percentage of the total voting power received
 	// with a round. The value begins at 0 for each round and approaches 1.0 as
 	// additional voting power is observed. The metric is labeled by vote type.
@@ -174,6 +307,226 @@ type Metrics struct {
 	// ApplyBlockLatency measures how long it takes to execute ApplyBlock in finalize commit step
 	ApplyBlockLatency metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.01, 10, 10"`
 
+	// BlockFinalityLatency is the time in seconds from the timestamp of a
+	// block, set by its proposer, to its application completing locally.
+	//metrics:Time in seconds from the timestamp of a block to its application completing locally.
+	BlockFinalityLatency metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.1, 100, 8"`
+
+	// BlockFinalityClockSkew is the number of blocks applied before their
+	// timestamp, the clock of their proposer being ahead of ours, which are
+	// not observed by BlockFinalityLatency.
+	//metrics:Number of blocks applied before their timestamp.
+	BlockFinalityClockSkew metrics.Counter
+
+	// ProposalDisseminationLatency measures, for proposals signed by this
+	// validator, the seconds between signing, processing the proposal, the
+	// first prevote of another validator and +2/3 prevotes for the block.
+	//metrics:Number of seconds between the dissemination stages of proposals signed by this node.
+	ProposalDisseminationLatency metrics.Histogram `metrics_labels:"phase" metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+
+	// ProposerWaitSeconds is the time this validator waited before proposing
+	// because the previous block time was ahead of its clock.
+	//metrics:Number of seconds this node waited to propose until its clock passed the previous block time.
+	ProposerWaitSeconds metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.001, 10, 16"`
+
+	// BlockRecoverySeconds is the time between committing a block this node
+	// did not have and receiving all its parts.
+	//metrics:Number of seconds between committing a block the node did not have and receiving it.
+	BlockRecoverySeconds metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"0.01, 10, 10"`
+
+	// ReceiveRoutineStalls is the number of times the watchdog found the
+	// receive routine not processing pending messages.
+	//metrics:Number of times the consensus receive routine was detected as stalled.
+	ReceiveRoutineStalls metrics.Counter
+
+	// StateUpdatesIgnored is the number of state updates that did not change
+	// the consensus state because they were not newer than it.
+	//metrics:Number of state updates ignored by consensus, by the source of the update.
+	StateUpdatesIgnored metrics.Counter `metrics_labels:"source"`
+
+	// StatsMsgsDropped is the number of peer statistics messages dropped
+	// because the reactor did not drain the stats queue in time.
+	//metrics:Number of peer statistics messages dropped because the stats queue was full.
+	StatsMsgsDropped metrics.Counter
+
+	// InternalMsgOverflowDepth and InternalMsgOverflowBytes are the number
+	// and encoded size of the internal messages waiting for room in the full
+	// internal message queue.
+	//metrics:Number of internal messages waiting for room in the internal message queue.
+	InternalMsgOverflowDepth metrics.Gauge
+	//metrics:Encoded size in bytes of the internal messages waiting for room in the internal message queue.
+	InternalMsgOverflowBytes metrics.Gauge
+
+	// PeerMsgsBelowReplayFloor is the number of peer messages dropped before
+	// being written to the WAL because they are for a height below the one
+	// the WAL replay ended at.
+	//metrics:Number of peer messages dropped because their height is below the height the WAL replay ended at.
+	PeerMsgsBelowReplayFloor metrics.Counter
+
+	// SignerTimestampSkews is the number of votes of this node whose
+	// timestamp was changed by the signer by more than
+	// config.SignerSkewTolerance.
+	//metrics:Number of votes of this node whose timestamp was changed by the signer beyond the tolerance.
+	SignerTimestampSkews metrics.Counter
+
+	// SignerTimestampSkew is the difference, in seconds, between the
+	// timestamp of the last vote of this node after and before its signing.
+	//metrics:Difference in seconds between the timestamp of the last vote of this node after and before its signing.
+	SignerTimestampSkew metrics.Gauge
+
+	// PeerMsgs is the number of consensus messages received from each peer,
+	// by message type and outcome: received, wal_written, added, duplicate or
+	// invalid. It is only updated if config.PeerStatsMetrics is set.
+	//metrics:Number of consensus messages received from each peer, by message type and outcome.
+	PeerMsgs metrics.Counter `metrics_labels:"peer_id, msg_type, outcome"`
+
+	// LockHoldDuration is the time, in seconds, the consensus state mutex is
+	// held by an operation: the handling of a message, by type, or of a
+	// timeout, and the sections of the commit of a block.
+	//metrics:Time in seconds the consensus state mutex is held, by operation.
+	LockHoldDuration metrics.Histogram `metrics_labels:"operation" metrics_buckettype:"exprange" metrics_bucketsizes:"0.0001, 10, 16"`
+
+	// LockHoldOutliers is the number of times the consensus state mutex was
+	// held by an operation for longer than config.LockHoldWarnThreshold.
+	//metrics:Number of times the consensus state mutex was held for longer than the warning threshold, by operation.
+	LockHoldOutliers metrics.Counter `metrics_labels:"operation"`
+
+	// PastBlockSignatures is the number of times a signature of this node's
+	// key was found in a past block by the double sign check at startup,
+	// labeled 'refused' if the start was refused and 'acknowledged' if the
+	// operator acknowledged it.
+	//metrics:Number of signatures of this node's key found in past blocks at startup, by outcome.
+	PastBlockSignatures metrics.Counter `metrics_labels:"outcome"`
+
+	// PubKeyMissingSeconds is the time the private validator was set while
+	// its pubkey could not be fetched, during which this node neither
+	// proposes nor votes.
+	//metrics:Number of seconds the private validator was set without its pubkey.
+	PubKeyMissingSeconds metrics.Counter
+
+	// WALOpenRetries is the number of times opening the WAL was retried
+	// after failing with a transient error.
+	//metrics:Number of times opening the WAL was retried after a transient error.
+	WALOpenRetries metrics.Counter
+
+	// NonValidatorVotes is the number of votes from addresses outside the
+	// validator set of their height, labeled 'rotated_out' if the address was
+	// in the validator set of the previous height and 'unknown' otherwise.
+	//metrics:Number of votes from addresses outside the validator set, by whether they were validators at the previous height.
+	NonValidatorVotes metrics.Counter `metrics_labels:"kind"`
+
+	// StepBudgetExceeded is the number of seconds in which the state machine
+	// exceeded its budget of steps, delaying the processing of peer messages.
+	//metrics:Number of seconds in which the state machine exceeded its step budget and throttled peer messages.
+	StepBudgetExceeded metrics.Counter
+
+	// DecisionLogDropped is the number of records of the decision log dropped
+	// because its writer fell behind.
+	//metrics:Number of decision log records dropped because the writer fell behind.
+	DecisionLogDropped metrics.Counter
+
+	// EventRingDropped is the number of records of the event ring dropped
+	// because its writer fell behind.
+	//metrics:Number of event ring records dropped because the writer fell behind.
+	EventRingDropped metrics.Counter
+
+	// EventBusBreakerState is the state of the circuit breaker of the event
+	// bus publishes: 0 closed, 1 open, 2 half-open.
+	//metrics:State of the circuit breaker of the event bus publishes: 0 closed, 1 open, 2 half-open.
+	EventBusBreakerState metrics.Gauge
+	// EventBusSkippedEvents is the number of events not published to the
+	// event bus while its circuit breaker was open.
+	//metrics:Number of events not published to the event bus while its circuit breaker was open.
+	EventBusSkippedEvents metrics.Counter `metrics_labels:"event"`
+
+	// BlockPartAmplification is the size of the block parts received from
+	// peers in a height, including duplicates and parts of other rounds'
+	// blocks, divided by the size of the committed block.
+	//metrics:Size of the block parts received in a height divided by the size of the committed block.
+	BlockPartAmplification metrics.Histogram `metrics_bucketsizes:"1, 1.5, 2, 3, 5, 10, 20"`
+
+	// BlockPartWastedBytes is the number of block part bytes received from
+	// peers in excess of the size of the committed blocks.
+	//metrics:Number of block part bytes received in excess of the size of the committed block.
+	BlockPartWastedBytes metrics.Counter
+
+	// BlockPartUselessBytes is the number of block part bytes received from
+	// peers that could not be used: parts of another height, parts received
+	// while no block was expected, and parts of another block.
+	//metrics:Number of block part bytes received for another height or block, or while no block was expected.
+	BlockPartUselessBytes metrics.Counter
+
+	// VoteExtensionBytes is the size in bytes of the vote extensions held in
+	// the vote sets of the current height.
+	//metrics:Size in bytes of the vote extensions held in the vote sets of the current height.
+	VoteExtensionBytes metrics.Gauge
+
+	// VoteExtensionSize is the size in bytes of the vote extensions added to
+	// the vote sets.
+	//metrics:Size in bytes of the vote extensions added to the vote sets.
+	VoteExtensionSize metrics.Histogram `metrics_buckettype:"exprange" metrics_bucketsizes:"16, 1048576, 9"`
+
+	// AwaitingPOL is 1 while the proposal of the current round waits for the
+	// prevotes of its POL round, which were solicited from peers.
+	//metrics:Whether the proposal of the current round waits for the prevotes of its POL round.
+	AwaitingPOL metrics.Gauge
+
+	// POLSolicitations is the number of times the prevotes of the POL round
+	// of a proposal were solicited from peers.
+	//metrics:Number of times the prevotes of the POL round of a proposal were solicited from peers.
+	POLSolicitations metrics.Counter
+
+	// ProposalSolicitations is the number of times the proposal of a round
+	// was solicited from peers as the prevote of its proposer for a block was
+	// received before it.
+	//metrics:Number of times the proposal of a round was solicited from peers as the prevote of its proposer was received before it.
+	ProposalSolicitations metrics.Counter
+
+	// ProposeGrace is the number of times the propose timeout of a round was
+	// extended by config.ProposeGrace, by outcome: saved if the proposal was
+	// complete as the prevote step was entered, expired otherwise.
+	//metrics:Number of extensions of the propose timeout by the propose grace, by outcome.
+	ProposeGrace metrics.Counter `metrics_labels:"outcome"`
+
+	// BlockValidations is the number of validations of the blocks of the
+	// current height, by outcome: validated if the block was validated,
+	// cached if the outcome of its earlier validation was reused.
+	//metrics:Number of validations of blocks, by outcome: validated, or cached if an earlier outcome was reused.
+	BlockValidations metrics.Counter `metrics_labels:"outcome"`
+
+	// StepTransitions is the number of transitions of the state machine to a
+	// step, labeled by the step and the cause of the transition. Causes
+	// outside of the known set are labeled 'other'.
+	//metrics:Number of transitions of the state machine to a step, by the cause of the transition.
+	StepTransitions metrics.Counter `metrics_labels:"step, entry"`
+
+	// OwnPrecommitsExcluded is the number of blocks committed while the
+	// precommit this validator signed for them was excluded from their commit.
+	//metrics:Number of committed blocks whose commit excluded the precommit this validator signed for them.
+	OwnPrecommitsExcluded metrics.Counter `metrics_labels:"validator_address"`
+	// OwnPrecommitLateness is the time between +2/3 precommits for the last
+	// block whose commit excluded our precommit and our last precommit for it.
+	// It is negative if we signed our precommit before.
+	//metrics:Seconds between +2/3 precommits for the last block whose commit excluded our precommit and our last precommit for it.
+	OwnPrecommitLateness metrics.Gauge `metrics_labels:"validator_address"`
+
+	// LastCommitVoteArrival is the time between the start of the NewHeight
+	// step and the arrival of the precommits for the previous height, by
+	// whether they were accepted into the last commit or ignored after the
+	// timeoutCommit window.
+	//metrics:Seconds between the start of the NewHeight step and the arrival of the precommits for the previous height.
+	LastCommitVoteArrival metrics.Histogram `metrics_labels:"outcome" metrics_buckettype:"exprange" metrics_bucketsizes:"0.01, 10, 10"`
+
+	// LastCommitVotes is the number of precommits for the previous height
+	// accepted into the last commit or ignored after the timeoutCommit window.
+	//metrics:Number of precommits for the previous height accepted into the last commit or ignored after the timeoutCommit window.
+	LastCommitVotes metrics.Counter `metrics_labels:"outcome"`
+
+	// LastCommitVotesByValidator is LastCommitVotes by validator, tallied
+	// when config.LastCommitValidatorMetrics is set.
+	//metrics:Number of precommits for the previous height accepted into the last commit or ignored, by validator.
+	LastCommitVotesByValidator metrics.Counter `metrics_labels:"validator_address, outcome"`
+
 	StepLatency                 metrics.Gauge `metrics_labels:"step"`
 	lastRecordedStepLatencyNano int64
 	StepCount                   metrics.Gauge `metrics_labels:"step"`
@@ -248,6 +601,19 @@ func (m *Metrics) MarkPrevoteLatency(validator string, seconds float64) {
 	m.PrevoteLatency.With("validator_address", validator).Observe(seconds)
 }
 
+func (m *Metrics) MarkProposalLatency(l ProposalLatency) {
+	for phase, d := range map[string]time.Duration{
+		"signed_to_processed":        l.SignedToProcessed,
+		"processed_to_first_prevote": l.ProcessedToFirstPrevote,
+		"first_prevote_to_quorum":    l.FirstPrevoteToQuorum,
+		"signed_to_quorum":           l.SignedToQuorum,
+	} {
+		if d > 0 {
+			m.ProposalDisseminationLatency.With("phase", phase).Observe(d.Seconds())
+		}
+	}
+}
+
 func (m *Metrics) MarkStep(s cstypes.RoundStepType) {
 	if !m.stepStart.IsZero() {
 		stepTime := time.Since(m.stepStart).Seconds()
@@ -0,0 +1,136 @@
+package consensus
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// msgDebugLogKey is the type of message and the outcome of its handling the
+// per-message debug lines are limited by.
+type msgDebugLogKey struct {
+	msgType string
+	outcome string
+}
+
+func (k msgDebugLogKey) String() string { return k.msgType + "/" + k.outcome }
+
+// The per-message debug lines of the hot paths of the state machine.
+var (
+	msgDebugLogVoteReceived         = msgDebugLogKey{"vote", "received"}
+	msgDebugLogVoteWrongHeight      = msgDebugLogKey{"vote", "wrong-height"}
+	msgDebugLogPrevoteAdded         = msgDebugLogKey{"prevote", "added"}
+	msgDebugLogPrecommitAdded       = msgDebugLogKey{"precommit", "added"}
+	msgDebugLogBlockPartWrongHeight = msgDebugLogKey{"block-part", "wrong-height"}
+	msgDebugLogBlockPartWrongRound  = msgDebugLogKey{"block-part", "wrong-round"}
+	msgDebugLogBlockPartUnexpected  = msgDebugLogKey{"block-part", "unexpected"}
+	msgDebugLogBlockPartError       = msgDebugLogKey{"block-part", "error"}
+)
+
+// msgDebugLogBucket is the token bucket of the debug lines of a key.
+type msgDebugLogBucket struct {
+	tokens float64
+	last   time.Time
+	// lines suppressed since the last summary
+	suppressed int64
+}
+
+// msgDebugLogLimiter limits the debug lines logged per message, which at
+// debug level flood the log of large validator sets, with a token bucket per
+// type of message and outcome. The suppressed lines are counted and reported
+// in a periodic summary.
+type msgDebugLogLimiter struct {
+	mtx          sync.Mutex
+	buckets      map[msgDebugLogKey]*msgDebugLogBucket
+	summaryStart time.Time
+
+	// bypasses the limit
+	verbose atomic.Bool
+}
+
+// allow takes a token from the bucket of key at now, the bucket being filled
+// at rate tokens per second up to burst, and returns whether the line may be
+// logged. A line that may not is counted as suppressed. A rate of 0 allows
+// every line.
+func (l *msgDebugLogLimiter) allow(key msgDebugLogKey, rate float64, burst int, now time.Time) bool {
+	if rate <= 0 || l.verbose.Load() {
+		return true
+	}
+	capacity := math.Max(float64(burst), 1)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[msgDebugLogKey]*msgDebugLogBucket)
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &msgDebugLogBucket{tokens: capacity, last: now}
+		l.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed.Seconds()*rate)
+		bucket.last = now
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true
+	}
+	bucket.suppressed++
+	return false
+}
+
+// summary returns the number of lines suppressed per key since the last
+// summary, once interval passed since it, and resets the counts. It returns
+// nil if the interval did not pass or no line was suppressed.
+func (l *msgDebugLogLimiter) summary(interval time.Duration, now time.Time) map[string]int64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.summaryStart.IsZero() {
+		l.summaryStart = now
+	}
+	if now.Sub(l.summaryStart) < interval {
+		return nil
+	}
+	l.summaryStart = now
+
+	var suppressed map[string]int64
+	for key, bucket := range l.buckets {
+		if bucket.suppressed == 0 {
+			continue
+		}
+		if suppressed == nil {
+			suppressed = make(map[string]int64)
+		}
+		suppressed[key.String()] = bucket.suppressed
+		bucket.suppressed = 0
+	}
+	return suppressed
+}
+
+// SetVerboseLogging makes the consensus state log every per-message debug
+// line, regardless of config.MsgDebugLogRate, for short targeted captures. It
+// restores the limit once set back to false.
+func (cs *State) SetVerboseLogging(verbose bool) {
+	if cs.msgDebugLogs.verbose.Swap(verbose) != verbose {
+		cs.logger.Info("per-message debug logging changed", "verbose", verbose)
+	}
+}
+
+// logMsgDebug logs a per-message debug line unless the lines of key exceed
+// config.MsgDebugLogRate, and the summary of the suppressed lines once
+// config.MsgDebugLogSummaryInterval passed since the last one.
+func (cs *State) logMsgDebug(key msgDebugLogKey, msg string, keyvals ...interface{}) {
+	now := time.Now()
+	if cs.msgDebugLogs.allow(key, cs.config.MsgDebugLogRate, cs.config.MsgDebugLogBurst, now) {
+		cs.logger.Debug(msg, keyvals...)
+	}
+	if suppressed := cs.msgDebugLogs.summary(cs.config.MsgDebugLogSummaryInterval, now); suppressed != nil {
+		cs.logger.Debug("suppressed per-message debug lines",
+			"interval", cs.config.MsgDebugLogSummaryInterval,
+			"max_per_second", cs.config.MsgDebugLogRate,
+			"suppressed", suppressed,
+		)
+	}
+}
@@ -0,0 +1,90 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestMsgDebugLogLimiter(t *testing.T) {
+	var l msgDebugLogLimiter
+	start := time.Now()
+
+	// disabled
+	for i := 0; i < 10; i++ {
+		require.True(t, l.allow(msgDebugLogVoteReceived, 0, 1, start))
+	}
+	require.Nil(t, l.summary(0, start))
+
+	// the burst is allowed, then a line per 1/rate
+	for i := 0; i < 3; i++ {
+		require.True(t, l.allow(msgDebugLogVoteReceived, 2, 3, start))
+	}
+	require.False(t, l.allow(msgDebugLogVoteReceived, 2, 3, start))
+	require.False(t, l.allow(msgDebugLogVoteReceived, 2, 3, start.Add(100*time.Millisecond)))
+	require.True(t, l.allow(msgDebugLogVoteReceived, 2, 3, start.Add(500*time.Millisecond)))
+	require.False(t, l.allow(msgDebugLogVoteReceived, 2, 3, start.Add(500*time.Millisecond)))
+
+	// the keys have their own bucket
+	require.True(t, l.allow(msgDebugLogPrevoteAdded, 2, 1, start))
+	require.False(t, l.allow(msgDebugLogPrevoteAdded, 2, 1, start))
+
+	// the suppressed lines are summarized once per interval
+	require.Nil(t, l.summary(time.Second, start.Add(500*time.Millisecond)))
+	require.Equal(t, map[string]int64{"vote/received": 3, "prevote/added": 1},
+		l.summary(time.Second, start.Add(time.Second)))
+	require.Nil(t, l.summary(time.Second, start.Add(2*time.Second)))
+
+	// verbose logging bypasses the limit
+	l.verbose.Store(true)
+	for i := 0; i < 10; i++ {
+		require.True(t, l.allow(msgDebugLogPrevoteAdded, 2, 1, start.Add(2*time.Second)))
+	}
+	l.verbose.Store(false)
+	require.True(t, l.allow(msgDebugLogPrevoteAdded, 2, 1, start.Add(2*time.Second)))
+	require.False(t, l.allow(msgDebugLogPrevoteAdded, 2, 1, start.Add(2*time.Second)))
+}
+
+func TestStateMsgDebugLogRateLimit(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+	cs.config.MsgDebugLogRate = 0.001
+	cs.config.MsgDebugLogBurst = 2
+	cs.config.MsgDebugLogSummaryInterval = time.Hour
+
+	addVotes := func(voteType tmproto.SignedMsgType) {
+		for _, vs := range vss[1:] {
+			vote := signVote(ctx, t, vs, voteType, config.ChainID(), types.BlockID{})
+			cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer"}, false)
+		}
+	}
+
+	// the lines over the burst are suppressed and counted
+	addVotes(tmproto.PrecommitType)
+	require.Equal(t, 2, strings.Count(logs.String(), `"adding vote"`))
+	require.Equal(t, 2, strings.Count(logs.String(), `"added vote to precommit"`))
+	require.Equal(t, map[string]int64{"vote/received": 1, "precommit/added": 1},
+		cs.msgDebugLogs.summary(0, time.Now()))
+
+	// verbose logging logs every line
+	logs.Reset()
+	cs.SetVerboseLogging(true)
+	addVotes(tmproto.PrevoteType)
+	require.Equal(t, 3, strings.Count(logs.String(), `"adding vote"`))
+	require.Equal(t, 3, strings.Count(logs.String(), `"added vote to prevote"`))
+	require.Nil(t, cs.msgDebugLogs.summary(0, time.Now()))
+	cs.SetVerboseLogging(false)
+}
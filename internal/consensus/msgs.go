@@ -28,6 +28,7 @@ func init() {
 	jsontypes.MustRegister(&ProposalMessage{})
 	jsontypes.MustRegister(&ProposalPOLMessage{})
 	jsontypes.MustRegister(&BlockPartMessage{})
+	jsontypes.MustRegister(&ProposalAndBlockPartsMessage{})
 	jsontypes.MustRegister(&VoteMessage{})
 	jsontypes.MustRegister(&HasVoteMessage{})
 	jsontypes.MustRegister(&VoteSetMaj23Message{})
@@ -213,6 +214,58 @@ func (m *BlockPartMessage) String() string {
 	return fmt.Sprintf("[BlockPart H:%v R:%v P:%v]", m.Height, m.Round, m.Part)
 }
 
+// ProposalAndBlockPartsMessage is a proposal and all the parts of its block,
+// input to the State as a unit: the parts are added along with the proposal,
+// or not at all. It is not gossiped, and is written to the WAL as the
+// ProposalMessage and BlockPartMessages it is made of.
+type ProposalAndBlockPartsMessage struct {
+	Proposal *types.Proposal
+	Parts    []*types.Part
+}
+
+func (*ProposalAndBlockPartsMessage) TypeTag() string {
+	return "tendermint/ProposalAndBlockParts"
+}
+
+// ValidateBasic performs basic validation. The parts must be all the parts of
+// the block of the proposal, in order.
+func (m *ProposalAndBlockPartsMessage) ValidateBasic() error {
+	if err := m.Proposal.ValidateBasic(); err != nil {
+		return fmt.Errorf("wrong Proposal: %w", err)
+	}
+	header := m.Proposal.BlockID.PartSetHeader
+	if len(m.Parts) != int(header.Total) {
+		return fmt.Errorf("expected %d parts, got %d", header.Total, len(m.Parts))
+	}
+	for i, part := range m.Parts {
+		if err := part.ValidateBasic(); err != nil {
+			return fmt.Errorf("wrong Part %d: %w", i, err)
+		}
+		if part.Index != uint32(i) {
+			return fmt.Errorf("expected part %d, got part %d", i, part.Index)
+		}
+		if err := part.Proof.Verify(header.Hash, part.Bytes); err != nil {
+			return fmt.Errorf("wrong proof of Part %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// msgs returns the proposal message and the block part messages m is made of.
+func (m *ProposalAndBlockPartsMessage) msgs() []Message {
+	msgs := make([]Message, 0, 1+len(m.Parts))
+	msgs = append(msgs, &ProposalMessage{m.Proposal})
+	for _, part := range m.Parts {
+		msgs = append(msgs, &BlockPartMessage{Height: m.Proposal.Height, Round: m.Proposal.Round, Part: part})
+	}
+	return msgs
+}
+
+// String returns a string representation.
+func (m *ProposalAndBlockPartsMessage) String() string {
+	return fmt.Sprintf("[ProposalAndBlockParts %v parts:%d]", m.Proposal, len(m.Parts))
+}
+
 // VoteMessage is sent when voting for a proposal (or lack thereof).
 type VoteMessage struct {
 	Vote *types.Vote
@@ -614,8 +667,9 @@ func WALToProto(msg WALMessage) (*tmcons.WALMessage, error) {
 		pb = tmcons.WALMessage{
 			Sum: &tmcons.WALMessage_MsgInfo{
 				MsgInfo: &tmcons.MsgInfo{
-					Msg:    *consMsg,
-					PeerID: string(msg.PeerID),
+					Msg:         *consMsg,
+					PeerID:      string(msg.PeerID),
+					ReceiveTime: msg.ReceiveTime,
 				},
 			},
 		}
@@ -641,6 +695,16 @@ func WALToProto(msg WALMessage) (*tmcons.WALMessage, error) {
 			},
 		}
 
+	case RestartAcknowledgmentMessage:
+		pb = tmcons.WALMessage{
+			Sum: &tmcons.WALMessage_RestartAcknowledgment{
+				RestartAcknowledgment: &tmcons.RestartAcknowledgment{
+					Height:          msg.Height,
+					SignatureHeight: msg.SignatureHeight,
+				},
+			},
+		}
+
 	default:
 		return nil, fmt.Errorf("to proto: wal message not recognized: %T", msg)
 	}
@@ -671,8 +735,9 @@ func WALFromProto(msg *tmcons.WALMessage) (WALMessage, error) {
 			return nil, fmt.Errorf("msgInfo from proto error: %w", err)
 		}
 		pb = msgInfo{
-			Msg:    walMsg,
-			PeerID: types.NodeID(msg.MsgInfo.PeerID),
+			Msg:         walMsg,
+			PeerID:      types.NodeID(msg.MsgInfo.PeerID),
+			ReceiveTime: msg.MsgInfo.ReceiveTime,
 		}
 
 	case *tmcons.WALMessage_TimeoutInfo:
@@ -682,14 +747,17 @@ func WALFromProto(msg *tmcons.WALMessage) (WALMessage, error) {
 			return nil, fmt.Errorf("denying message due to possible overflow: %w", err)
 		}
 
-		pb = timeoutInfo{
+		ti := timeoutInfo{
 			Duration: msg.TimeoutInfo.Duration,
 			Height:   msg.TimeoutInfo.Height,
 			Round:    msg.TimeoutInfo.Round,
 			Step:     cstypes.RoundStepType(tis),
 		}
+		if err := ti.ValidateBasic(); err != nil {
+			return nil, fmt.Errorf("invalid timeout info: %w", err)
+		}
 
-		return pb, nil
+		return ti, nil
 
 	case *tmcons.WALMessage_EndHeight:
 		pb := EndHeightMessage{
@@ -698,6 +766,12 @@ func WALFromProto(msg *tmcons.WALMessage) (WALMessage, error) {
 
 		return pb, nil
 
+	case *tmcons.WALMessage_RestartAcknowledgment:
+		return RestartAcknowledgmentMessage{
+			Height:          msg.RestartAcknowledgment.Height,
+			SignatureHeight: msg.RestartAcknowledgment.SignatureHeight,
+		}, nil
+
 	default:
 		return nil, fmt.Errorf("from proto: wal message not recognized: %T", msg)
 	}
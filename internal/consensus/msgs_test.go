@@ -307,6 +307,17 @@ func TestWALMsgProto(t *testing.T) {
 				},
 			},
 		}, false},
+		{"successful RestartAcknowledgmentMessage", RestartAcknowledgmentMessage{
+			Height:          12,
+			SignatureHeight: 10,
+		}, &tmcons.WALMessage{
+			Sum: &tmcons.WALMessage_RestartAcknowledgment{
+				RestartAcknowledgment: &tmcons.RestartAcknowledgment{
+					Height:          12,
+					SignatureHeight: 10,
+				},
+			},
+		}, false},
 		{"failure", nil, &tmcons.WALMessage{}, true},
 	}
 	for _, tt := range testsCases {
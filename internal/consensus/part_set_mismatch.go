@@ -0,0 +1,49 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// discardMismatchedRebuild reports that block, rebuilt from the tx keys of
+// the proposal of round, matches none of the part set headers of its
+// serializations to that of the proposal, e.g. as the txs are ordered
+// otherwise than in the block of the proposer. The block is discarded: the
+// proposal block is assembled from the parts of the proposal instead, which
+// the rebuilt parts would have failed the proofs of.
+func (cs *State) discardMismatchedRebuild(round int32, block *types.Block) {
+	proposal := cs.roundState.Proposal()
+	header := proposal.BlockID.PartSetHeader
+	mismatch := types.EventDataPartSetMismatch{
+		Height:              block.Height,
+		Round:               round,
+		TxCount:             len(block.Txs),
+		ProposalTxCount:     len(proposal.TxKeys),
+		RebuiltBlockHash:    block.Hash(),
+		ProposalBlockHash:   proposal.BlockID.Hash,
+		ProposalPartSetHash: header.Hash,
+	}
+	// as this node would propose it
+	if partSet, err := cs.makeProposalBlockParts(block); err == nil {
+		mismatch.RebuiltPartSetHash = partSet.Header().Hash
+	}
+
+	cs.metrics.RebuildPartSetMismatches.Add(1)
+	cs.logger.Error("the block rebuilt from tx keys does not match the part set header of the proposal; "+
+		"waiting for the block parts",
+		"height", mismatch.Height,
+		"round", round,
+		"tx_count", mismatch.TxCount,
+		"proposal_tx_count", mismatch.ProposalTxCount,
+		"rebuilt_block_hash", mismatch.RebuiltBlockHash,
+		"rebuilt_part_set_hash", mismatch.RebuiltPartSetHash,
+		"proposal_block_hash", mismatch.ProposalBlockHash,
+		"proposal_part_set_hash", mismatch.ProposalPartSetHash,
+	)
+	if err := cs.publishEvent(types.EventPartSetMismatchValue, mismatch); err != nil {
+		cs.logger.Error("failed publishing part set mismatch", "err", err)
+	}
+
+	if parts := cs.roundState.ProposalBlockParts(); parts == nil || !parts.HasHeader(header) {
+		cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(header))
+	}
+}
@@ -0,0 +1,72 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/internal/mempool"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateDiscardsMismatchedRebuild(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	cs.config.GossipTransactionKeyOnly = true
+	mismatches := generic.NewCounter("rebuild_part_set_mismatches")
+	cs.metrics.RebuildPartSetMismatches = mismatches
+	mismatchCh := subscribe(ctx, t, cs.eventBus, types.EventQueryPartSetMismatch)
+
+	txs := types.Txs{types.Tx("a=1"), types.Tx("b=2")}
+	for _, tx := range txs {
+		require.NoError(t, assertMempool(t, cs.txNotifier).CheckTx(ctx, tx, nil, mempool.TxInfo{}))
+	}
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, txs, created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	// vss[1] proposes the block with its tx keys in the reverse order, so
+	// the rebuilt block orders its txs otherwise
+	cs.enterNewRound(ctx, height, round, "test")
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	txKeys := []types.TxKey{txs[1].Key(), txs[0].Key()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, txKeys,
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+
+	msg := ensureMessageBeforeTimeout(t, mismatchCh, ensureTimeout)
+	mismatch := msg.Data().(types.EventDataPartSetMismatch)
+	require.Equal(t, height, mismatch.Height)
+	require.Equal(t, round, mismatch.Round)
+	require.Equal(t, 2, mismatch.TxCount)
+	require.Equal(t, 2, mismatch.ProposalTxCount)
+	require.NotEqual(t, block.Hash(), mismatch.RebuiltBlockHash)
+	require.NotEqual(t, parts.Header().Hash, mismatch.RebuiltPartSetHash)
+	require.Equal(t, block.Hash(), mismatch.ProposalBlockHash)
+	require.Equal(t, parts.Header().Hash, mismatch.ProposalPartSetHash)
+	require.Equal(t, 1.0, mismatches.Value())
+	require.Nil(t, cs.roundState.ProposalBlock())
+
+	// the proposal block is assembled from its parts within the round
+	for i := 0; i < int(parts.Total()); i++ {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	require.True(t, cs.roundState.ProposalBlock().HashesTo(block.Hash()))
+	require.Equal(t, round, cs.roundState.Round())
+	require.Equal(t, 1.0, mismatches.Value())
+}
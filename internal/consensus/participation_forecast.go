@@ -0,0 +1,103 @@
+package consensus
+
+import (
+	"bytes"
+
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// maxParticipationForecastHeights bounds the heights a participation
+// forecast projects.
+const maxParticipationForecastHeights = 10000
+
+// ParticipationForecast is a best-effort forecast of the participation of this
+// node in the next heights. It assumes that the validator set does not change
+// after the next height and that every height is committed in round 0: a
+// validator set update or a later commit round changes the proposers of the
+// following heights.
+type ParticipationForecast struct {
+	// Address is the address of this node, nil if its private validator is
+	// not set, in which case it is in no validator set.
+	Address types.Address
+	// InValidatorSet is whether this node is in the validator set of the
+	// current height.
+	InValidatorSet bool
+	Heights        []HeightForecast
+}
+
+// HeightForecast is the forecast of the participation of this node in a
+// height.
+type HeightForecast struct {
+	Height int64
+	// InValidatorSet is whether this node is projected in the validator set
+	// of the height, and so to sign its votes.
+	InValidatorSet bool
+	// Proposer is the projected proposer of round 0 of the height.
+	Proposer   types.Address
+	IsProposer bool
+	// VoteExtensionsEnabled is whether the precommits of the height must be
+	// extended, per the consensus params of the current height.
+	VoteExtensionsEnabled bool
+}
+
+// ProposerHeights returns the heights this node is projected to propose in
+// round 0.
+func (f ParticipationForecast) ProposerHeights() []int64 {
+	var heights []int64
+	for _, h := range f.Heights {
+		if h.IsProposer {
+			heights = append(heights, h.Height)
+		}
+	}
+	return heights
+}
+
+// ParticipationForecast forecasts, best-effort, whether this node is in the
+// validator set and proposes round 0 of each of the next n heights, from the
+// current one, and whether their precommits must be extended. See
+// ParticipationForecast for its assumptions. It only takes a read lock.
+func (cs *State) ParticipationForecast(n int) ParticipationForecast {
+	if n > maxParticipationForecastHeights {
+		n = maxParticipationForecastHeights
+	}
+	var address types.Address
+	if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil {
+		address = pubKey.Address()
+	}
+	return forecastParticipation(cs.GetState(), address, n)
+}
+
+// forecastParticipation projects the participation of address in the n
+// heights following state.
+func forecastParticipation(state sm.State, address types.Address, n int) ParticipationForecast {
+	forecast := ParticipationForecast{
+		Address:        address,
+		InValidatorSet: address != nil && state.Validators.HasAddress(address),
+	}
+	if n <= 0 || state.Validators == nil || state.NextValidators == nil {
+		return forecast
+	}
+
+	// the validators of the next height are known, those after are assumed
+	// to be the same; the priorities are incremented once per height
+	vals := state.Validators
+	next := state.NextValidators.Copy()
+	height := state.LastBlockHeight + 1
+	for i := 0; i < n; i, height = i+1, height+1 {
+		if i == 1 {
+			vals = next
+		} else if i > 1 {
+			vals.IncrementProposerPriority(1)
+		}
+		proposer := vals.GetProposer().Address
+		forecast.Heights = append(forecast.Heights, HeightForecast{
+			Height:                height,
+			InValidatorSet:        address != nil && vals.HasAddress(address),
+			Proposer:              proposer,
+			IsProposer:            address != nil && bytes.Equal(proposer, address),
+			VoteExtensionsEnabled: state.ConsensusParams.ABCI.VoteExtensionsEnabled(height),
+		})
+	}
+	return forecast
+}
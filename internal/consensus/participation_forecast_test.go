@@ -0,0 +1,69 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	sm "github.com/tendermint/tendermint/internal/state"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateParticipationForecast(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	pubKey, err := cs.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	commit := func(state sm.State, updates []*types.Validator) sm.State {
+		header := &types.Header{Height: state.LastBlockHeight + 1, Time: tmtime.Now()}
+		next, err := state.Update(types.BlockID{}, header, nil, nil, updates)
+		require.NoError(t, err)
+		return next
+	}
+
+	// this node has more power from the next height, and vote extensions
+	// are enabled a few heights later
+	state := commit(cs.GetState(), []*types.Validator{types.NewValidator(pubKey, 30)})
+	enableHeight := state.LastBlockHeight + 5
+	state.ConsensusParams.ABCI.VoteExtensionsEnableHeight = enableHeight
+	cs.state = state
+
+	forecast := cs.ParticipationForecast(20)
+	require.Equal(t, pubKey.Address(), forecast.Address)
+	require.True(t, forecast.InValidatorSet)
+	require.Len(t, forecast.Heights, 20)
+
+	// the forecast matches the proposers of round 0 of the heights as they
+	// are committed
+	var proposed []int64
+	for _, h := range forecast.Heights {
+		require.Equal(t, state.LastBlockHeight+1, h.Height)
+		proposer := state.Validators.GetProposer().Address
+		require.Equal(t, proposer, h.Proposer)
+		require.Equal(t, bytes.Equal(proposer, pubKey.Address()), h.IsProposer)
+		require.True(t, h.InValidatorSet)
+		require.Equal(t, h.Height >= enableHeight, h.VoteExtensionsEnabled)
+		if h.IsProposer {
+			proposed = append(proposed, h.Height)
+		}
+		state = commit(state, nil)
+	}
+	require.NotEmpty(t, proposed)
+	require.Equal(t, proposed, forecast.ProposerHeights())
+
+	// another node is in no validator set
+	other := forecastParticipation(cs.GetState(), ed25519.GenPrivKey().PubKey().Address(), 5)
+	require.False(t, other.InValidatorSet)
+	require.Len(t, other.Heights, 5)
+	require.Empty(t, other.ProposerHeights())
+	for _, h := range other.Heights {
+		require.False(t, h.InValidatorSet)
+	}
+}
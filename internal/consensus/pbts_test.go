@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/metrics/generic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -435,6 +436,37 @@ func TestProposerWaitTime(t *testing.T) {
 	}
 }
 
+func TestProposerWaitRecorded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := configSetup(t)
+
+	const gap = 100 * time.Millisecond
+	clock := new(tmtimemocks.Source)
+	cs, _ := makeState(ctx, t, makeStateArgs{config: cfg, validators: 1, options: []StateOption{WithClock(clock)}})
+	waits := generic.NewHistogram("proposer_wait_seconds", 2)
+	cs.metrics.ProposerWaitSeconds = waits
+
+	// the previous block time is ahead of our clock on entering the propose
+	// step, and behind it once the wait is over
+	lastBlockTime := cs.state.LastBlockTime
+	clock.On("Now").Return(lastBlockTime.Add(-gap)).Once()
+	clock.On("Now").Return(lastBlockTime.Add(time.Hour))
+
+	_, ok := cs.GetLastProposerWait()
+	require.False(t, ok)
+
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	wait, ok := cs.GetLastProposerWait()
+	require.True(t, ok)
+	require.Equal(t, ProposerWait{Height: 1, Round: 0, Duration: gap}, wait)
+	require.Equal(t, gap.Seconds(), waits.Quantile(0.5))
+}
+
 func TestTimelyProposal(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
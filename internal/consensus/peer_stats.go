@@ -0,0 +1,170 @@
+package consensus
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// The outcomes the messages of a peer are counted by.
+const (
+	// peerMsgReceived is a message taken from the peer message queue.
+	peerMsgReceived = "received"
+	// peerMsgWALWritten is a message written to the WAL.
+	peerMsgWALWritten = "wal_written"
+	// peerMsgAdded is a message new to the state: the proposal of the round,
+	// or a block part or vote it did not have.
+	peerMsgAdded = "added"
+	// peerMsgDuplicate is a valid message not new to the state: already
+	// received, or for another height or round.
+	peerMsgDuplicate = "duplicate"
+	// peerMsgInvalid is a message the state rejected.
+	peerMsgInvalid = "invalid"
+)
+
+// PeerMsgCounts are the counts of the messages of a type received from a
+// peer, by outcome. A message is received, then written to the WAL, then
+// either added, duplicate or invalid.
+type PeerMsgCounts struct {
+	Received   uint64
+	WALWritten uint64
+	Added      uint64
+	Duplicate  uint64
+	Invalid    uint64
+}
+
+func (c *PeerMsgCounts) add(outcome string) {
+	switch outcome {
+	case peerMsgReceived:
+		c.Received++
+	case peerMsgWALWritten:
+		c.WALWritten++
+	case peerMsgAdded:
+		c.Added++
+	case peerMsgDuplicate:
+		c.Duplicate++
+	case peerMsgInvalid:
+		c.Invalid++
+	}
+}
+
+// PeerStats are the counts of the messages received from a peer since the
+// start of the current height, by message type.
+type PeerStats struct {
+	Proposals  PeerMsgCounts
+	BlockParts PeerMsgCounts
+	Votes      PeerMsgCounts
+}
+
+// counts returns the counts of the type of msg, or nil if it is not counted.
+func (s *PeerStats) counts(msg Message) *PeerMsgCounts {
+	switch msg.(type) {
+	case *ProposalMessage:
+		return &s.Proposals
+	case *BlockPartMessage:
+		return &s.BlockParts
+	case *VoteMessage:
+		return &s.Votes
+	default:
+		return nil
+	}
+}
+
+// peerMsgType returns the label value of the type of msg in the metrics, or
+// an empty string if it is not counted.
+func peerMsgType(msg Message) string {
+	switch msg.(type) {
+	case *ProposalMessage:
+		return "proposal"
+	case *BlockPartMessage:
+		return "block_part"
+	case *VoteMessage:
+		return "vote"
+	default:
+		return ""
+	}
+}
+
+type peerStatsEntry struct {
+	peerID types.NodeID
+	stats  PeerStats
+}
+
+// peerStats counts the messages of the most recently heard from peers. It is
+// reset at each height.
+type peerStats struct {
+	mtx   sync.Mutex
+	peers map[types.NodeID]*list.Element
+	// entries from the least to the most recently heard from peer
+	lru *list.List
+}
+
+// add counts the outcome of msg for peerID, evicting the least recently heard
+// from peer if maxPeers are counted already.
+func (ps *peerStats) add(maxPeers int, peerID types.NodeID, msg Message, outcome string) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	if ps.peers == nil {
+		ps.peers = make(map[types.NodeID]*list.Element)
+		ps.lru = list.New()
+	}
+	e, ok := ps.peers[peerID]
+	if ok {
+		ps.lru.MoveToBack(e)
+	} else {
+		for ps.lru.Len() >= maxPeers {
+			front := ps.lru.Front()
+			delete(ps.peers, front.Value.(*peerStatsEntry).peerID)
+			ps.lru.Remove(front)
+		}
+		e = ps.lru.PushBack(&peerStatsEntry{peerID: peerID})
+		ps.peers[peerID] = e
+	}
+	if counts := e.Value.(*peerStatsEntry).stats.counts(msg); counts != nil {
+		counts.add(outcome)
+	}
+}
+
+func (ps *peerStats) reset() {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	ps.peers, ps.lru = nil, nil
+}
+
+func (ps *peerStats) snapshot() map[types.NodeID]PeerStats {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	stats := make(map[types.NodeID]PeerStats, len(ps.peers))
+	for peerID, e := range ps.peers {
+		stats[peerID] = e.Value.(*peerStatsEntry).stats
+	}
+	return stats
+}
+
+// recordPeerMsg counts the outcome of mi for its peer, and in the metrics if
+// config.PeerStatsMetrics is set. Internal messages and the messages replayed
+// from the WAL are not counted.
+func (cs *State) recordPeerMsg(mi msgInfo, outcome string) {
+	if mi.PeerID == "" || cs.replayMode {
+		return
+	}
+	msgType := peerMsgType(mi.Msg)
+	if msgType == "" {
+		return
+	}
+	if cs.config.PeerStatsMaxPeers > 0 {
+		cs.peerStats.add(cs.config.PeerStatsMaxPeers, mi.PeerID, mi.Msg, outcome)
+	}
+	if cs.config.PeerStatsMetrics {
+		cs.metrics.PeerMsgs.With("peer_id", string(mi.PeerID), "msg_type", msgType, "outcome", outcome).Add(1)
+	}
+}
+
+// PeerStats returns the counts of the messages received from each peer since
+// the start of the current height, for the config.PeerStatsMaxPeers most
+// recently heard from peers.
+func (cs *State) PeerStats() map[types.NodeID]PeerStats {
+	return cs.peerStats.snapshot()
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStatePeerStats(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	cs.config.PeerStatsMaxPeers = 2
+	cs.config.PeerStatsMetrics = true
+	msgs := newLabeledCounter()
+	cs.metrics.PeerMsgs = msgs
+	height := cs.roundState.Height()
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	prevote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	precommit := signVote(ctx, t, vss[1], tmproto.PrecommitType, config.ChainID(), types.BlockID{})
+	precommit.Signature[0] ^= 0xff
+	send := func(peerID types.NodeID, msg Message) {
+		cs.peerMsgQueue <- msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}
+	}
+
+	// the state waits for the round to start: the prevote is the only vote,
+	// and no block part is expected
+	routinesCtx, cancelRoutines := context.WithCancel(ctx)
+	cs.startRoutines(routinesCtx, 0)
+	send("peer1", &VoteMessage{prevote})
+	send("peer2", &VoteMessage{prevote})
+	send("peer2", &VoteMessage{precommit})
+	send("peer1", &BlockPartMessage{Height: height, Round: 0, Part: parts.GetPart(0)})
+	expected := map[types.NodeID]PeerStats{
+		"peer1": {
+			Votes:      PeerMsgCounts{Received: 1, WALWritten: 1, Added: 1},
+			BlockParts: PeerMsgCounts{Received: 1, WALWritten: 1, Duplicate: 1},
+		},
+		"peer2": {
+			Votes: PeerMsgCounts{Received: 2, WALWritten: 2, Duplicate: 1, Invalid: 1},
+		},
+	}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, cs.PeerStats())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the least recently heard from peer is evicted
+	send("peer3", &VoteMessage{prevote})
+	delete(expected, "peer2")
+	expected["peer3"] = PeerStats{Votes: PeerMsgCounts{Received: 1, WALWritten: 1, Duplicate: 1}}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, cs.PeerStats())
+	}, 5*time.Second, 10*time.Millisecond)
+	cancelRoutines()
+	cs.routines.Wait()
+
+	// the metrics are kept for all the peers
+	require.Equal(t, 1.0, msgs.values["peer_id,peer1,msg_type,vote,outcome,added"])
+	require.Equal(t, 2.0, msgs.values["peer_id,peer2,msg_type,vote,outcome,received"])
+	require.Equal(t, 1.0, msgs.values["peer_id,peer2,msg_type,vote,outcome,invalid"])
+	require.Equal(t, 1.0, msgs.values["peer_id,peer3,msg_type,vote,outcome,duplicate"])
+}
@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/libs/bits"
+	"github.com/tendermint/tendermint/types"
+)
+
+// POLNeeded is fired on the internal event switch with
+// types.EventPOLNeededValue when a proposal references a POL round we have no
+// 2/3 majority of prevotes for, so that the prevotes can be solicited from
+// peers. Until they arrive, the proposal is not complete.
+type POLNeeded struct {
+	Height   int64
+	Round    int32
+	POLRound int32
+	BlockID  types.BlockID
+
+	// Prevotes are the prevotes of POLRound we have.
+	Prevotes *bits.BitArray
+}
+
+// checkProposalPOL solicits the prevotes of the POL round of the proposal if
+// we have no 2/3 majority of them, which may happen if we skipped past the
+// POL round without receiving them. The solicitation is made once per
+// proposal.
+func (cs *State) checkProposalPOL() {
+	proposal := cs.roundState.Proposal()
+	if proposal == nil || proposal.POLRound < 0 || cs.roundState.AwaitingPOLRound() == proposal.POLRound {
+		return
+	}
+	votes := cs.roundState.Votes()
+	if votes.Prevotes(proposal.POLRound).HasTwoThirdsMajority() {
+		return
+	}
+
+	cs.logger.Info("soliciting the prevotes of the POL round of the proposal",
+		"height", proposal.Height, "round", proposal.Round, "pol_round", proposal.POLRound)
+	// accept the prevotes of the POL round from any peer
+	votes.TrackRound(proposal.POLRound)
+	cs.roundState.SetAwaitingPOLRound(proposal.POLRound)
+	cs.metrics.AwaitingPOL.Set(1)
+	cs.metrics.POLSolicitations.Add(1)
+	cs.evsw.FireEvent(types.EventPOLNeededValue, &POLNeeded{
+		Height:   proposal.Height,
+		Round:    proposal.Round,
+		POLRound: proposal.POLRound,
+		BlockID:  proposal.BlockID,
+		Prevotes: votes.Prevotes(proposal.POLRound).BitArray(),
+	})
+}
+
+// markPOLReceived stops waiting for the prevotes of round once we have a 2/3
+// majority of them.
+func (cs *State) markPOLReceived(round int32) {
+	if cs.roundState.AwaitingPOLRound() != round || !cs.roundState.Votes().Prevotes(round).HasTwoThirdsMajority() {
+		return
+	}
+	cs.logger.Info("received the prevotes of the POL round of the proposal",
+		"height", cs.roundState.Height(), "round", cs.roundState.Round(), "pol_round", round)
+	cs.clearAwaitingPOL()
+}
+
+// clearAwaitingPOL stops waiting for the prevotes of a POL round.
+func (cs *State) clearAwaitingPOL() {
+	cs.roundState.SetAwaitingPOLRound(-1)
+	cs.metrics.AwaitingPOL.Set(0)
+}
@@ -0,0 +1,88 @@
+package consensus
+
+import (
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// precommitDelay is the delay of the precommit of this node in a round for
+// config.PrecommitPrevoteFraction of the prevote power. It is a local policy:
+// the node waits in the prevote wait step, as it does for any +2/3 prevotes,
+// and precommits as it would have once the prevotes are in or the delay is
+// over.
+type precommitDelay struct {
+	height   int64
+	round    int32
+	deadline time.Time
+}
+
+// precommitDelayWait returns the longest the precommit of round may be
+// delayed for, or 0 if the policy is disabled.
+func (cs *State) precommitDelayWait(round int32) time.Duration {
+	if cs.config.PrecommitPrevoteFraction <= 0 {
+		return 0
+	}
+	wait := cs.voteTimeout(round)
+	if max := cs.config.PrecommitPrevoteWait; max > 0 && max < wait {
+		wait = max
+	}
+	return wait
+}
+
+// delayPrecommit reports whether the precommit of this node for the polka of
+// round, at the current height, is delayed for more prevotes. The first delay
+// of a round enters the prevote wait step with a timeout of
+// precommitDelayWait, unless the node waits for prevotes already: the prevote
+// wait timeout scheduled then bounds the delay.
+func (cs *State) delayPrecommit(height int64, round int32) bool {
+	wait := cs.precommitDelayWait(round)
+	if wait <= 0 || cs.roundState.Round() != round || cs.roundState.Step() >= cstypes.RoundStepPrecommit {
+		return false
+	}
+	prevotes := cs.roundState.Votes().Prevotes(round)
+	if blockID, ok := prevotes.TwoThirdsMajority(); !ok || blockID.IsNil() {
+		return false
+	}
+	pubKey := cs.getPrivValidatorPubKey()
+	vals := cs.roundState.Validators()
+	if pubKey == nil || !vals.HasAddress(pubKey.Address()) {
+		return false
+	}
+	power := prevotePower(vals, prevotes)
+	if float64(power) >= cs.config.PrecommitPrevoteFraction*float64(vals.TotalVotingPower()) {
+		return false
+	}
+
+	now := time.Now()
+	pd := &cs.precommitDelay
+	if pd.height != height || pd.round != round {
+		*pd = precommitDelay{height: height, round: round, deadline: now.Add(wait)}
+		cs.logger.Info("delaying precommit for more prevotes",
+			"height", height,
+			"round", round,
+			"prevote_power", power,
+			"total_power", vals.TotalVotingPower(),
+			"wait", wait,
+		)
+		if cs.roundState.Step() < cstypes.RoundStepPrevoteWait {
+			cs.updateRoundStep(round, cstypes.RoundStepPrevoteWait)
+			cs.newStep("")
+			cs.scheduleTimeout(wait, height, round, cstypes.RoundStepPrevoteWait)
+		}
+	}
+	return now.Before(pd.deadline)
+}
+
+// prevotePower returns the voting power of vals that prevoted in prevotes.
+func prevotePower(vals *types.ValidatorSet, prevotes *types.VoteSet) int64 {
+	votes := prevotes.BitArray()
+	var power int64
+	for i, val := range vals.Validators {
+		if votes.GetIndex(i) {
+			power += val.VotingPower
+		}
+	}
+	return power
+}
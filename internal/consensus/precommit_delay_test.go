@@ -0,0 +1,78 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStatePrecommitDelay(t *testing.T) {
+	config := configSetup(t)
+	const wait = 300 * time.Millisecond
+
+	for _, tc := range []struct {
+		name string
+		// whether the last validator prevotes during the delay
+		allPrevotes bool
+	}{
+		{name: "partial prevotes"},
+		{name: "all prevotes", allPrevotes: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// 4 validators of equal power: +2/3 is 3 of them, 85% all of them
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			cs.config.PrecommitPrevoteFraction = 0.85
+			cs.config.PrecommitPrevoteWait = wait
+			// the delay is bounded by the vote timeout
+			cs.config.UnsafeVoteTimeoutOverride = time.Minute
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+
+			proposalCh := subscribe(ctx, t, cs.eventBus, types.EventQueryCompleteProposal)
+			newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
+			pubKey, err := cs.privValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			voteCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+
+			startTestRound(ctx, cs, height, round)
+			ensureNewRound(t, newRoundCh, height, round)
+			ensureNewProposal(t, proposalCh, height, round)
+			rs := cs.GetRoundState()
+			blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+			ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+
+			// a polka of 3 validators of 4 delays the precommit
+			signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[1:3]...)
+			start := time.Now()
+			require.Eventually(t, func() bool {
+				return cs.GetRoundState().Step == cstypes.RoundStepPrevoteWait
+			}, time.Second, time.Millisecond)
+			ensureNoMessageBeforeTimeout(t, voteCh, wait/3, "unexpected precommit during the delay")
+
+			if tc.allPrevotes {
+				// until the prevote power reaches the fraction
+				signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[3])
+				ensurePrecommitMatch(t, voteCh, height, round, blockID.Hash)
+				require.Less(t, time.Since(start), wait)
+			} else {
+				// or the delay is over
+				ensureMessageBeforeTimeout(t, voteCh, 2*wait)
+				require.GreaterOrEqual(t, time.Since(start), wait)
+				require.Equal(t, blockID.Hash.Bytes(), cs.GetRoundState().Votes.Precommits(round).GetByAddress(pubKey.Address()).BlockID.Hash.Bytes())
+			}
+
+			// and the block is committed in the round
+			signAddVotes(ctx, t, cs, tmproto.PrecommitType, config.ChainID(), blockID, vss[1:3]...)
+			ensureNewRound(t, newRoundCh, height+1, 0)
+			require.Equal(t, blockID.Hash, cs.blockStore.LoadBlock(height).Hash())
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package consensus
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// signedPrecommit is a precommit for a block this node signed.
+type signedPrecommit struct {
+	round     int32
+	blockHash []byte
+	signedAt  time.Time
+}
+
+// precommitInclusion tracks the precommits this node signed for blocks in the
+// current height and, over a window of heights, whether the precommits it
+// signed for the committed blocks were excluded from their commit.
+type precommitInclusion struct {
+	// mtx guards height and signed, recorded as the votes of this node are
+	// signed, without the State mutex
+	mtx    sync.Mutex
+	height int64
+	signed []signedPrecommit
+
+	// whether our precommit was excluded from the commit, for the last heights
+	// we signed one for the committed block in, oldest first
+	outcomes []bool
+	// set once the exclusion rate exceeded its threshold, until it no longer
+	// does
+	alerting bool
+	// lateness of our last precommit excluded from a commit
+	lateness time.Duration
+}
+
+// recordSigned records a precommit for a block signed at signedAt.
+func (pi *precommitInclusion) recordSigned(vote *types.Vote, signedAt time.Time) {
+	pi.mtx.Lock()
+	defer pi.mtx.Unlock()
+	if vote.Height != pi.height {
+		pi.height = vote.Height
+		pi.signed = nil
+	}
+	pi.signed = append(pi.signed, signedPrecommit{
+		round:     vote.Round,
+		blockHash: vote.BlockID.Hash,
+		signedAt:  signedAt,
+	})
+}
+
+// lastSigned returns the last precommit signed for blockHash at height, and
+// false if none was.
+func (pi *precommitInclusion) lastSigned(height int64, blockHash []byte) (signedPrecommit, bool) {
+	pi.mtx.Lock()
+	defer pi.mtx.Unlock()
+	if height != pi.height {
+		return signedPrecommit{}, false
+	}
+	for i := len(pi.signed) - 1; i >= 0; i-- {
+		if bytes.Equal(pi.signed[i].blockHash, blockHash) {
+			return pi.signed[i], true
+		}
+	}
+	return signedPrecommit{}, false
+}
+
+// observe records whether our precommit was excluded from a commit, in a
+// window of the given size. It returns the number of exclusions in the window
+// and their rate, and whether the rate just exceeded threshold. The rate is
+// only compared to threshold once the window is full.
+func (pi *precommitInclusion) observe(excluded bool, window int, threshold float64) (int, float64, bool) {
+	if window <= 0 {
+		pi.outcomes, pi.alerting = nil, false
+		return 0, 0, false
+	}
+	pi.outcomes = append(pi.outcomes, excluded)
+	if len(pi.outcomes) > window {
+		pi.outcomes = pi.outcomes[len(pi.outcomes)-window:]
+	}
+
+	count := 0
+	for _, e := range pi.outcomes {
+		if e {
+			count++
+		}
+	}
+	rate := float64(count) / float64(len(pi.outcomes))
+	if len(pi.outcomes) < window || rate <= threshold {
+		pi.alerting = false
+		return count, rate, false
+	}
+	alert := !pi.alerting
+	pi.alerting = true
+	return count, rate, alert
+}
+
+// recordSignedPrecommit records a precommit for a block this node signed.
+func (cs *State) recordSignedPrecommit(vote *types.Vote) {
+	if vote.Type != tmproto.PrecommitType || vote.BlockID.IsNil() {
+		return
+	}
+	cs.precommitInclusion.recordSigned(vote, tmtime.Now())
+}
+
+// checkOwnPrecommitInclusion checks whether the precommit this node signed
+// for the block committed at height, if any, was excluded from the seen
+// commit of the block, the precommits of the commit round for the block. The
+// PrecommitsExcluded event is published when the rate of exclusions over
+// config.PrecommitExclusionWindow heights exceeds
+// config.PrecommitExclusionThreshold.
+//
+// It must be called before the state is updated to the next height.
+func (cs *State) checkOwnPrecommitInclusion(height int64) {
+	_, pubKey := cs.getPrivValidator()
+	if pubKey == nil {
+		return
+	}
+	precommits := cs.roundState.Votes().Precommits(cs.roundState.CommitRound())
+	if precommits == nil {
+		return
+	}
+	blockID, ok := precommits.TwoThirdsMajority()
+	if !ok || blockID.IsNil() {
+		return
+	}
+	signed, ok := cs.precommitInclusion.lastSigned(height, blockID.Hash)
+	if !ok {
+		return
+	}
+
+	address := pubKey.Address()
+	vote := precommits.GetByAddress(address)
+	excluded := vote == nil || !vote.BlockID.Equals(blockID)
+	if excluded {
+		lateness := signed.signedAt.Sub(cs.roundState.CommitTime())
+		cs.precommitInclusion.lateness = lateness
+		label := []string{"validator_address", address.String()}
+		cs.metrics.OwnPrecommitsExcluded.With(label...).Add(1)
+		cs.metrics.OwnPrecommitLateness.With(label...).Set(lateness.Seconds())
+		cs.logger.Info(
+			"our precommit for the committed block was excluded from its commit",
+			"height", height,
+			"commit_round", cs.roundState.CommitRound(),
+			"signed_round", signed.round,
+			"lateness", lateness,
+		)
+	}
+
+	window := cs.config.PrecommitExclusionWindow
+	count, rate, alert := cs.precommitInclusion.observe(excluded, window, cs.config.PrecommitExclusionThreshold)
+	if !alert || cs.eventBus == nil {
+		return
+	}
+	if err := cs.publishEvent(types.EventPrecommitsExcludedValue, types.EventDataPrecommitsExcluded{
+		Height:           height,
+		ValidatorAddress: address,
+		Excluded:         count,
+		Window:           window,
+		Rate:             rate,
+		Lateness:         cs.precommitInclusion.lateness,
+	}); err != nil {
+		cs.logger.Error("failed publishing precommits excluded", "err", err)
+	}
+}
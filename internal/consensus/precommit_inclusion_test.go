@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestPrecommitInclusionObserve(t *testing.T) {
+	var pi precommitInclusion
+
+	// the rate is only compared to the threshold once the window is full
+	for _, excluded := range []bool{true, true} {
+		_, _, alert := pi.observe(excluded, 3, 0.5)
+		require.False(t, alert)
+	}
+	count, rate, alert := pi.observe(false, 3, 0.5)
+	require.Equal(t, 2, count)
+	require.InDelta(t, 2.0/3, rate, 1e-9)
+	require.True(t, alert)
+
+	// the alert is published once while the rate exceeds the threshold
+	_, _, alert = pi.observe(true, 3, 0.5)
+	require.False(t, alert)
+
+	// and again once it exceeded it after dropping below it
+	for _, excluded := range []bool{false, false} {
+		_, _, alert = pi.observe(excluded, 3, 0.5)
+		require.False(t, alert)
+	}
+	count, _, alert = pi.observe(true, 3, 0.5)
+	require.Equal(t, 1, count)
+	require.False(t, alert)
+	_, _, alert = pi.observe(true, 3, 0.5)
+	require.True(t, alert)
+
+	// a window of 0 disables the alert
+	_, _, alert = pi.observe(true, 0, 0.5)
+	require.False(t, alert)
+	require.Empty(t, pi.outcomes)
+}
+
+func TestStateDetectsExcludedOwnPrecommit(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.PrecommitExclusionWindow = 2
+	cs.config.PrecommitExclusionThreshold = 0.5
+	excluded := newLabeledCounter()
+	cs.metrics.OwnPrecommitsExcluded = excluded
+	excludedCh := subscribe(ctx, t, cs.eventBus, types.EventQueryPrecommitsExcluded)
+	pubKey := cs.getPrivValidatorPubKey()
+	require.NotNil(t, pubKey)
+	label := "validator_address," + pubKey.Address().String()
+
+	height := cs.roundState.Height()
+	blockID := types.BlockID{
+		Hash:          tmrand.Bytes(32),
+		PartSetHeader: types.PartSetHeader{Total: 1, Hash: tmrand.Bytes(32)},
+	}
+	ownPrecommit := &types.Vote{
+		Type:             tmproto.PrecommitType,
+		Height:           height,
+		Round:            1,
+		BlockID:          blockID,
+		ValidatorAddress: pubKey.Address(),
+	}
+
+	// the other validators precommit the block in round 1, which is committed
+	// without our precommit
+	cs.roundState.Votes().SetRound(1)
+	incrementRound(vss...)
+	for _, vote := range signVotes(ctx, t, tmproto.PrecommitType, config.ChainID(), blockID, vss[1:]...) {
+		added, err := cs.roundState.Votes().AddVote(vote, "peer")
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+	commitTime := time.Now()
+	cs.roundState.SetCommitRound(1)
+	cs.roundState.SetCommitTime(commitTime)
+
+	// we signed our precommit too late for the commit
+	cs.precommitInclusion.recordSigned(ownPrecommit, commitTime.Add(2*time.Second))
+	cs.checkOwnPrecommitInclusion(height)
+	require.Equal(t, 1.0, excluded.values[label])
+	require.Equal(t, 2*time.Second, cs.precommitInclusion.lateness)
+	// the window is not full yet
+	ensureNoMessageBeforeTimeout(t, excludedCh, 100*time.Millisecond, "unexpected PrecommitsExcluded event")
+
+	cs.checkOwnPrecommitInclusion(height)
+	require.Equal(t, 2.0, excluded.values[label])
+	msg := ensureMessageBeforeTimeout(t, excludedCh, ensureTimeout)
+	event, ok := msg.Data().(types.EventDataPrecommitsExcluded)
+	require.True(t, ok)
+	require.Equal(t, height, event.Height)
+	require.Equal(t, pubKey.Address(), event.ValidatorAddress)
+	require.Equal(t, 2, event.Excluded)
+	require.Equal(t, 2, event.Window)
+	require.Equal(t, 2*time.Second, event.Lateness)
+
+	// our precommit is not excluded once it is in the commit
+	vss[0].Height = height
+	signed := signVote(ctx, t, vss[0], tmproto.PrecommitType, config.ChainID(), blockID)
+	added, err := cs.roundState.Votes().AddVote(signed, "")
+	require.NoError(t, err)
+	require.True(t, added)
+	cs.checkOwnPrecommitInclusion(height)
+	require.Equal(t, 2.0, excluded.values[label])
+
+	// nor is the commit of a height we signed no precommit in
+	cs.checkOwnPrecommitInclusion(height + 1)
+	require.Equal(t, 2.0, excluded.values[label])
+}
@@ -0,0 +1,97 @@
+package consensus
+
+import (
+	"bytes"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// validProposal is what the prevote for a valid proposal is decided on: the
+// proposal, the POL of its POL round and our lock.
+type validProposal struct {
+	Round     int32
+	BlockHash []byte
+	POLRound  int32
+	// POLBlockID is the block +2/3 prevoted in POLRound; HasPOL is false if
+	// we hold no such majority.
+	POLBlockID types.BlockID
+	HasPOL     bool
+
+	LockedRound int32
+	LockedBlock []byte
+}
+
+// decideValidProposalPrevote decides whether to prevote a proposal whose
+// block is valid, following lines 22-30 of the algorithm in
+// https://arxiv.org/abs/1807.04938, and returns the reason of the decision.
+// Otherwise we prevote nil.
+//
+// Our ValidBlock plays no part in the decision: a proposal whose POL round
+// we hold the prevotes of is prevoted following line 29 even if we have since
+// updated ValidBlock to another block from a later POL. Whether the prevotes
+// of the POL round are for the proposed block is all that matters.
+func decideValidProposalPrevote(p validProposal) (prevote bool, reason string) {
+	lockedOnProposal := len(p.LockedBlock) > 0 && bytes.Equal(p.LockedBlock, p.BlockHash)
+
+	/*
+		22: upon <PROPOSAL, h_p, round_p, v, −1> from proposer(h_p, round_p) while step_p = propose do
+		23: if valid(v) && (lockedRound_p = −1 || lockedValue_p = v) then
+		24: broadcast <PREVOTE, h_p, round_p, id(v)>
+
+		Here, the POL round of the proposal corresponds to the -1 in the above
+		algorithm rule. This means that the proposer is producing a new
+		proposal that has not previously seen a 2/3 majority by the network.
+
+		If we have already locked on a value that is different from the
+		proposed value, we prevote nil since we are locked on a different
+		value. Otherwise, if we're not locked on a block or the proposal
+		matches our locked block, we prevote the proposal.
+	*/
+	if p.POLRound == -1 {
+		switch {
+		case p.LockedRound == -1:
+			return true, "valid-unlocked"
+		case lockedOnProposal:
+			return true, "valid-locked"
+		default:
+			return false, "locked-on-other"
+		}
+	}
+
+	/*
+		28: upon <PROPOSAL, h_p, round_p, v, v_r> from proposer(h_p, round_p) AND 2f + 1 <PREVOTE, h_p, v_r, id(v)> while
+		step_p = propose && (v_r ≥ 0 && v_r < round_p) do
+		29: if valid(v) && (lockedRound_p ≤ v_r || lockedValue_p = v) then
+		30: broadcast <PREVOTE, h_p, round_p, id(v)>
+
+		This rule is a bit confusing but breaks down as follows:
+
+		If we see a proposal in the current round for value 'v' that lists its
+		valid round as 'v_r' AND this validator saw a 2/3 majority of the
+		voting power prevote 'v' in round 'v_r', then we will issue a prevote
+		for 'v' in this round if 'v' is valid and either matches our locked
+		value OR 'v_r' is a round greater than or equal to our current locked
+		round.
+
+		'v_r' can be a round greater than to our current locked round if a 2/3
+		majority of the network prevoted a value in round 'v_r' but we did not
+		lock on it, possibly because we missed the proposal in round 'v_r'.
+
+		Without the 2/3 majority prevoting 'v' in round 'v_r', neither rule
+		applies and we prevote nil, even if we are locked on 'v'.
+	*/
+	switch {
+	case p.POLRound < -1 || p.POLRound >= p.Round:
+		return false, "invalid-pol-round"
+	case !p.HasPOL:
+		return false, "missing-pol"
+	case !bytes.Equal(p.POLBlockID.Hash, p.BlockHash) || len(p.BlockHash) == 0:
+		return false, "pol-for-other-block"
+	case p.LockedRound <= p.POLRound:
+		return true, "valid-pol"
+	case lockedOnProposal:
+		return true, "valid-locked"
+	default:
+		return false, "locked-on-other"
+	}
+}
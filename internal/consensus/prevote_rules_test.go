@@ -0,0 +1,70 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestDecideValidProposalPrevote(t *testing.T) {
+	v := []byte("proposed block")
+	other := []byte("other block")
+	polFor := func(hash []byte) types.BlockID { return types.BlockID{Hash: hash} }
+
+	// the proposal is of round 3, with POL round 1 unless said otherwise
+	for _, tc := range []struct {
+		name        string
+		polRound    int32
+		pol         []byte // block +2/3 prevoted in the POL round; nil if none
+		lockedRound int32
+		lockedBlock []byte
+
+		prevote bool
+		reason  string
+	}{
+		// lines 22-24: a new proposal
+		{"new proposal, unlocked", -1, nil, -1, nil, true, "valid-unlocked"},
+		{"new proposal, locked on it", -1, nil, 2, v, true, "valid-locked"},
+		{"new proposal, locked on other", -1, nil, 2, other, false, "locked-on-other"},
+
+		// lines 28-30: a proposal with a POL we hold
+		{"POL, unlocked", 1, v, -1, nil, true, "valid-pol"},
+		{"POL, locked on other before the POL round", 1, v, 0, other, true, "valid-pol"},
+		{"POL, locked on other in the POL round", 1, v, 1, other, true, "valid-pol"},
+		{"POL, locked on other after the POL round", 1, v, 2, other, false, "locked-on-other"},
+		{"POL, locked on it before the POL round", 1, v, 0, v, true, "valid-pol"},
+		{"POL, locked on it after the POL round", 1, v, 2, v, true, "valid-locked"},
+		// our ValidBlock is not an input: it may be another block from a
+		// later POL, without us being locked on it
+		{"POL, valid block is other from a later round, unlocked", 1, v, -1, nil, true, "valid-pol"},
+		{"POL, valid block is other from a later round, locked on it", 1, v, 2, other, false, "locked-on-other"},
+
+		// lines 28-30 do not apply without the POL of the proposed block
+		{"no POL, unlocked", 1, nil, -1, nil, false, "missing-pol"},
+		{"no POL, locked on it", 1, nil, 2, v, false, "missing-pol"},
+		{"POL for other, unlocked", 1, other, -1, nil, false, "pol-for-other-block"},
+		{"POL for other, locked on it", 1, other, 2, v, false, "pol-for-other-block"},
+		{"POL for other, locked on other", 1, other, 0, other, false, "pol-for-other-block"},
+
+		// v_r must be before the round of the proposal
+		{"POL round of the proposal", 3, v, -1, nil, false, "invalid-pol-round"},
+		{"POL round after the proposal", 4, v, -1, nil, false, "invalid-pol-round"},
+		{"negative POL round", -2, v, -1, nil, false, "invalid-pol-round"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prevote, reason := decideValidProposalPrevote(validProposal{
+				Round:       3,
+				BlockHash:   v,
+				POLRound:    tc.polRound,
+				POLBlockID:  polFor(tc.pol),
+				HasPOL:      tc.pol != nil,
+				LockedRound: tc.lockedRound,
+				LockedBlock: tc.lockedBlock,
+			})
+			require.Equal(t, tc.prevote, prevote)
+			require.Equal(t, tc.reason, reason)
+		})
+	}
+}
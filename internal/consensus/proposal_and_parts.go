@@ -0,0 +1,99 @@
+package consensus
+
+import (
+	"context"
+
+	otrace "go.opentelemetry.io/otel/trace"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+// SetProposalAndBlockParts inputs the proposal and all the parts of its block
+// as a single message: they are processed at once, so that a change of round,
+// or the cancellation of ctx, cannot leave only some of them delivered.
+func (cs *State) SetProposalAndBlockParts(
+	ctx context.Context,
+	proposal *types.Proposal,
+	parts *types.PartSet,
+	peerID types.NodeID,
+) error {
+	msg := &ProposalAndBlockPartsMessage{Proposal: proposal, Parts: make([]*types.Part, parts.Total())}
+	for i := range msg.Parts {
+		msg.Parts[i] = parts.GetPart(i)
+	}
+	queue := cs.peerMsgQueue
+	if peerID == "" {
+		queue = cs.internalMsgQueue
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case queue <- msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}:
+		return nil
+	}
+}
+
+// walMessages returns the messages msg is written to the WAL as: a
+// ProposalAndBlockPartsMessage is written as the messages it is made of, which
+// the WAL can encode and replays the same way.
+func walMessages(msg WALMessage) []WALMessage {
+	mi, ok := msg.(msgInfo)
+	if !ok {
+		return []WALMessage{msg}
+	}
+	composite, ok := mi.Msg.(*ProposalAndBlockPartsMessage)
+	if !ok {
+		return []WALMessage{msg}
+	}
+	var msgs []WALMessage
+	for _, m := range composite.msgs() {
+		msgs = append(msgs, msgInfo{Msg: m, PeerID: mi.PeerID, ReceiveTime: mi.ReceiveTime})
+	}
+	return msgs
+}
+
+// handleProposalAndBlockParts sets the proposal of msg and adds all its
+// parts, without releasing cs.mtx in between. The parts are added whenever
+// the State expects them, as those of a BlockPartMessage, even if the
+// proposal is not the one of the current round; none is added if the
+// proposal is invalid. It returns whether the proposal or a part was added.
+func (cs *State) handleProposalAndBlockParts(
+	ctx context.Context,
+	msg *ProposalAndBlockPartsMessage,
+	mi msgInfo,
+	fsyncUponCompletion bool,
+	span otrace.Span,
+) (bool, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return false, err
+	}
+	proposal := msg.Proposal
+	err := cs.classifyProposalRejection(mi.PeerID, cs.setProposal(proposal, mi.PeerID, mi.ReceiveTime))
+	cs.logProposalDecision(proposal, mi.PeerID, err)
+	if err != nil {
+		return false, err
+	}
+	added := cs.roundState.Proposal() == proposal
+	if added {
+		cs.checkProposalPOL()
+		if mi.PeerID == "" {
+			cs.markProposalProcessed(proposal)
+		}
+	}
+
+	var complete bool
+	for _, part := range msg.Parts {
+		partAdded, err := cs.addProposalBlockPart(
+			&BlockPartMessage{Height: proposal.Height, Round: proposal.Round, Part: part}, mi.PeerID, mi.ReceiveTime)
+		if err != nil {
+			return added, err
+		}
+		added = added || partAdded
+		complete = complete || partAdded && cs.roundState.ProposalBlockParts().IsComplete()
+	}
+	if complete {
+		cs.fsyncAndCompleteProposal(ctx, fsyncUponCompletion, proposal.Height, span, false)
+	}
+	return added, nil
+}
@@ -0,0 +1,131 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// TestStateSetProposalAndBlockPartsRacesRound races the proposal of a round,
+// with its block parts, against the prevotes moving the State to the next
+// round: either the proposal is set and its block completed, or neither.
+func TestStateSetProposalAndBlockPartsRacesRound(t *testing.T) {
+	config := configSetup(t)
+
+	for i := 0; i < 20; i++ {
+		func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			height := cs.roundState.Height()
+			proposalCh := subscribe(ctx, t, cs.eventBus, types.EventQueryCompleteProposal)
+
+			// enter a round whose proposer is not cs
+			var (
+				round    int32
+				proposer *validatorStub
+			)
+			for proposer == nil || proposer == vss[0] {
+				round++
+				cs.enterNewRound(ctx, height, round, "test")
+				address := cs.roundState.Validators().GetProposer().Address
+				for _, vs := range vss {
+					pubKey, err := vs.PrivValidator.GetPubKey(ctx)
+					require.NoError(t, err)
+					if bytes.Equal(pubKey.Address(), address) {
+						proposer = vs
+					}
+				}
+			}
+			proposal, block := decideProposal(ctx, t, cs, proposer, height, round)
+			parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+			require.NoError(t, err)
+
+			var set bool
+			cs.setProposal = func(p *types.Proposal, peerID types.NodeID, recvTime time.Time) error {
+				err := cs.defaultSetProposal(p, peerID, recvTime)
+				set = set || cs.roundState.Proposal() == p
+				return err
+			}
+			var votes []*types.Vote
+			for _, vs := range vss[1:] {
+				vs.Round = round + 1
+				votes = append(votes, signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), types.BlockID{}))
+			}
+
+			// the State stays in the next round once the prevotes are added
+			cs.state.ConsensusParams.Timeout.Propose = time.Hour
+			cs.state.ConsensusParams.Timeout.Vote = time.Hour
+			routinesCtx, cancelRoutines := context.WithCancel(ctx)
+			cs.startRoutines(routinesCtx, 0)
+			go func() {
+				for _, vote := range votes {
+					cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer", ReceiveTime: tmtime.Now()}
+				}
+			}()
+			require.NoError(t, cs.SetProposalAndBlockParts(ctx, proposal, parts, ""))
+			require.Eventually(t, func() bool {
+				return cs.GetRoundState().Round == round+1 &&
+					len(cs.internalMsgQueue) == 0 && len(cs.peerMsgQueue) == 0
+			}, 5*time.Second, time.Millisecond)
+			cancelRoutines()
+			cs.routines.Wait()
+
+			var completed bool
+			for done := false; !done; {
+				select {
+				case msg := <-proposalCh:
+					completed = completed || msg.Data().(types.EventDataCompleteProposal).Round == round
+				case <-time.After(50 * time.Millisecond):
+					done = true
+				}
+			}
+			require.Equal(t, set, completed)
+		}()
+	}
+}
+
+func TestStateSetProposalAndBlockPartsCanceled(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	proposal, block := decideProposal(ctx, t, cs, vss[1], cs.roundState.Height(), 0)
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	// the proposal and its parts are queued as a single message, or not at all
+	canceled, cancelSubmit := context.WithCancel(ctx)
+	cancelSubmit()
+	if err := cs.SetProposalAndBlockParts(canceled, proposal, parts, ""); err != nil {
+		require.Empty(t, cs.internalMsgQueue)
+	} else {
+		require.Len(t, cs.internalMsgQueue, 1)
+	}
+	for len(cs.internalMsgQueue) < cap(cs.internalMsgQueue) {
+		cs.internalMsgQueue <- msgInfo{}
+	}
+	require.ErrorIs(t, cs.SetProposalAndBlockParts(canceled, proposal, parts, ""), context.Canceled)
+	require.Len(t, cs.internalMsgQueue, cap(cs.internalMsgQueue))
+
+	// the message is written to the WAL as the messages it is made of
+	msgs := walMessages(msgInfo{
+		Msg:    &ProposalAndBlockPartsMessage{Proposal: proposal, Parts: []*types.Part{parts.GetPart(0)}},
+		PeerID: "peer",
+	})
+	require.Len(t, msgs, 2)
+	require.Equal(t, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer"}, msgs[0])
+	require.Equal(t, msgInfo{
+		Msg:    &BlockPartMessage{Height: proposal.Height, Round: proposal.Round, Part: parts.GetPart(0)},
+		PeerID: "peer",
+	}, msgs[1])
+}
@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"time"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Sources of the proposal blocks of the other validators.
+const (
+	// ProposalBlockFromTxKeys is the source of a proposal block rebuilt from
+	// the mempool with the tx keys of its proposal, when
+	// config.GossipTransactionKeyOnly is set.
+	ProposalBlockFromTxKeys = "tx-keys"
+	// ProposalBlockFromParts is the source of a proposal block assembled from
+	// its block parts.
+	ProposalBlockFromParts = "parts"
+)
+
+// proposalBlockSource is how the last proposal block of the other validators
+// in the current height was obtained.
+type proposalBlockSource struct {
+	height int64
+	// a rebuild from tx keys missed txs in the height, and whether a later
+	// assembly from block parts was counted
+	missingTxs       bool
+	fallbackRecorded bool
+
+	blockHash tmbytes.HexBytes
+	source    string
+	latency   time.Duration
+}
+
+// reset starts tracking height if it is not tracked yet.
+func (s *proposalBlockSource) reset(height int64) {
+	if s.height != height {
+		*s = proposalBlockSource{height: height}
+	}
+}
+
+// recordProposalBlockMissingTxs records that the rebuild of the proposal block
+// at height from the tx keys of its proposal missed txs.
+func (cs *State) recordProposalBlockMissingTxs(height int64) {
+	cs.proposalBlockSource.reset(height)
+	cs.proposalBlockSource.missingTxs = true
+}
+
+// recordProposalBlockSource records that the proposal block at height and
+// round was obtained from source, unless it is our own proposal.
+func (cs *State) recordProposalBlockSource(height int64, round int32, block *types.Block, source string) {
+	if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil && cs.isProposer(pubKey.Address()) {
+		return
+	}
+
+	s := &cs.proposalBlockSource
+	s.reset(height)
+	s.blockHash = block.Hash()
+	s.source = source
+	s.latency = 0
+	if cs.roundState.Proposal() != nil {
+		s.latency = time.Since(cs.roundState.ProposalReceiveTime())
+	}
+	cs.metrics.ProposalBlockSources.With("source", source).Add(1)
+	cs.metrics.ProposalBlockLatency.With("source", source).Observe(s.latency.Seconds())
+	if source == ProposalBlockFromParts && s.missingTxs && !s.fallbackRecorded {
+		s.fallbackRecorded = true
+		cs.metrics.ProposalKeyRebuildFallbacks.Add(1)
+	}
+	cs.logger.Debug("proposal block available",
+		"height", height, "round", round, "source", source, "latency", s.latency)
+}
+
+// proposalBlockSourceOf returns the source of block if it is the last
+// proposal block of the other validators at height, and the time from the
+// receipt of its proposal to its availability.
+func (cs *State) proposalBlockSourceOf(height int64, block *types.Block) (string, time.Duration) {
+	s := cs.proposalBlockSource
+	if s.height != height || s.source == "" || !block.HashesTo(s.blockHash) {
+		return "", 0
+	}
+	return s.source, s.latency
+}
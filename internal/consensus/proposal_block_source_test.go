@@ -0,0 +1,74 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateProposalBlockSource(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	cs.config.GossipTransactionKeyOnly = true
+	sources := newLabeledCounter()
+	cs.metrics.ProposalBlockSources = sources
+	fallbacks := generic.NewCounter("proposal_key_rebuild_fallbacks")
+	cs.metrics.ProposalKeyRebuildFallbacks = fallbacks
+
+	height := cs.roundState.Height()
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+
+	// propose enters round, whose proposer is vss[1], and receives the
+	// proposal of block from a peer
+	propose := func(round int32, block *types.Block) *types.PartSet {
+		cs.enterNewRound(ctx, height, round, "test")
+		parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+		require.NoError(t, err)
+		blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+		proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+			block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+		p := proposal.ToProto()
+		require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+		proposal.Signature = p.Signature
+		cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+		return parts
+	}
+
+	// the txs of the block are in the mempool
+	propose(1, created)
+	require.NotNil(t, cs.roundState.ProposalBlock())
+	require.Equal(t, 1.0, sources.values["source,"+ProposalBlockFromTxKeys])
+	source, _ := cs.proposalBlockSourceOf(height, created)
+	require.Equal(t, ProposalBlockFromTxKeys, source)
+
+	// a tx of the block is missing from the mempool, so the block is
+	// assembled from its parts
+	missing := cs.state.MakeBlock(height, types.Txs{types.Tx("missing")}, created.LastCommit, nil, pubKey.Address())
+	parts := propose(3, missing)
+	require.Nil(t, cs.roundState.ProposalBlock())
+	require.Zero(t, fallbacks.Value())
+	for i := 0; i < int(parts.Total()); i++ {
+		msg := &BlockPartMessage{Height: height, Round: 3, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	require.True(t, cs.roundState.ProposalBlock().HashesTo(missing.Hash()))
+	require.Equal(t, 1.0, sources.values["source,"+ProposalBlockFromParts])
+	require.Equal(t, 1.0, fallbacks.Value())
+	source, _ = cs.proposalBlockSourceOf(height, missing)
+	require.Equal(t, ProposalBlockFromParts, source)
+
+	// the source is that of the last proposal block
+	source, _ = cs.proposalBlockSourceOf(height, created)
+	require.Empty(t, source)
+}
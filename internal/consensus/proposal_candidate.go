@@ -0,0 +1,154 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Outcomes of the proposal candidates.
+const (
+	// proposalCandidateProposed is the outcome of a candidate proposed instead
+	// of a block created by the block executor.
+	proposalCandidateProposed = "proposed"
+	// proposalCandidateRejected is the outcome of a candidate refused by
+	// SubmitProposalCandidate.
+	proposalCandidateRejected = "rejected"
+	// proposalCandidateDropped is the outcome of a candidate that was stale or
+	// no longer valid when deciding the proposal.
+	proposalCandidateDropped = "dropped"
+)
+
+var (
+	ErrInvalidProposalCandidate = errors.New("invalid proposal candidate")
+	ErrStaleProposalCandidate   = errors.New("stale proposal candidate")
+)
+
+// proposalCandidate is a block built outside of the node for a height this
+// node proposes at. It is read without the State mutex, which is held while
+// the proposal is decided.
+type proposalCandidate struct {
+	mtx      sync.Mutex
+	block    *types.Block
+	deadline time.Time
+}
+
+func (pc *proposalCandidate) set(block *types.Block, deadline time.Time) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	pc.block = block
+	pc.deadline = deadline
+}
+
+// get returns the candidate for height if its deadline is after now, and
+// whether a candidate was dropped because it is for another height or its
+// deadline passed.
+func (pc *proposalCandidate) get(height int64, now time.Time) (block *types.Block, dropped bool) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	if pc.block == nil {
+		return nil, false
+	}
+	if pc.block.Height != height || !now.Before(pc.deadline) {
+		pc.block = nil
+		return nil, true
+	}
+	return pc.block, false
+}
+
+// SubmitProposalCandidate submits block to be proposed instead of the block
+// created by the block executor, when this node proposes at the current
+// height before deadline. The candidate replaces any previous one.
+//
+// The block must be proposed by this node, for the current height, and be
+// valid for the current state. It is never proposed when the round must
+// re-propose the valid block, nor when we are locked on a block, and it is
+// validated again when deciding the proposal: a candidate that became invalid
+// is dropped for the block created by the block executor.
+func (cs *State) SubmitProposalCandidate(ctx context.Context, block *types.Block, deadline time.Time) error {
+	if err := cs.validateProposalCandidate(ctx, block, deadline); err != nil {
+		cs.metrics.ProposalCandidates.With("outcome", proposalCandidateRejected).Add(1)
+		return err
+	}
+	cs.proposalCandidate.set(block, deadline)
+	return nil
+}
+
+// validateProposalCandidate validates block as a proposal candidate for the
+// current height.
+func (cs *State) validateProposalCandidate(ctx context.Context, block *types.Block, deadline time.Time) error {
+	if block == nil {
+		return fmt.Errorf("%w: nil block", ErrInvalidProposalCandidate)
+	}
+	if !tmtime.Now().Before(deadline) {
+		return fmt.Errorf("%w: deadline %v passed", ErrStaleProposalCandidate, deadline)
+	}
+	pubKey := cs.getPrivValidatorPubKey()
+	if pubKey == nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProposalCandidate, errPubKeyIsNotSet)
+	}
+	if !bytes.Equal(block.ProposerAddress, pubKey.Address()) {
+		return fmt.Errorf("%w: proposer %v is not this validator", ErrInvalidProposalCandidate, block.ProposerAddress)
+	}
+
+	// the block executor caches the blocks it validated, which is not safe
+	// to share with the receive routine
+	hold := cs.lockFor(lockHoldProposalCandidate)
+	defer hold.unlock()
+	if height := cs.roundState.Height(); block.Height != height {
+		return fmt.Errorf("%w: height %d, current height %d", ErrStaleProposalCandidate, block.Height, height)
+	}
+	return cs.validateProposalCandidateBlock(ctx, block)
+}
+
+// validateProposalCandidateBlock validates block, proposed at the current
+// height, against the current state.
+func (cs *State) validateProposalCandidateBlock(ctx context.Context, block *types.Block) error {
+	if maxBytes, size := cs.state.ConsensusParams.Block.MaxBytes, int64(block.Size()); size > maxBytes {
+		return fmt.Errorf("%w: size %d exceeds the max block size %d", ErrInvalidProposalCandidate, size, maxBytes)
+	}
+	if err := cs.validateBlock(ctx, block); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProposalCandidate, err)
+	}
+	return nil
+}
+
+// proposalCandidateFor returns the submitted candidate to propose at height
+// and its parts, or nil if there is none, it is stale, or it is no longer
+// valid. The callers re-propose the valid block instead if there is one.
+func (cs *State) proposalCandidateFor(ctx context.Context, height int64) (*types.Block, *types.PartSet) {
+	block, dropped := cs.proposalCandidate.get(height, tmtime.Now())
+	if dropped {
+		cs.metrics.ProposalCandidates.With("outcome", proposalCandidateDropped).Add(1)
+	}
+	if block == nil {
+		return nil, nil
+	}
+
+	logger := cs.logger.With("height", height, "candidate", block.Hash())
+	// never propose another block than the one we are locked on
+	if cs.roundState.LockedBlock() != nil {
+		logger.Info("not proposing the proposal candidate; locked on a block",
+			"locked_block", cs.roundState.LockedBlock().Hash())
+		return nil, nil
+	}
+	if err := cs.validateProposalCandidateBlock(ctx, block); err != nil {
+		logger.Error("dropping the proposal candidate", "err", err)
+		cs.proposalCandidate.set(nil, time.Time{})
+		cs.metrics.ProposalCandidates.With("outcome", proposalCandidateDropped).Add(1)
+		return nil, nil
+	}
+	blockParts, err := cs.makeProposalBlockParts(block)
+	if err != nil {
+		logger.Error("unable to create the proposal candidate part set", "err", err)
+		return nil, nil
+	}
+	cs.metrics.ProposalCandidates.With("outcome", proposalCandidateProposed).Add(1)
+	return block, blockParts
+}
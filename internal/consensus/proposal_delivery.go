@@ -0,0 +1,72 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// proposalDelivery records, for the current height, the peers that delivered
+// the proposal of each round and the part completing each proposal block.
+// It is accessed under the State mutex.
+type proposalDelivery struct {
+	height int64
+	// the peer of the proposal of each round
+	proposals map[int32]types.NodeID
+	// the peer of the completing part of each block, by block hash
+	blocks map[string]types.NodeID
+}
+
+// deliveryPeer returns peerID as recorded in the delivery of a proposal or a
+// block part: types.PeerOriginSelf for the messages of this node.
+func deliveryPeer(peerID types.NodeID) types.NodeID {
+	if peerID == "" {
+		return types.PeerOriginSelf
+	}
+	return peerID
+}
+
+// heightProposalDelivery returns the proposal delivery of height.
+func (cs *State) heightProposalDelivery(height int64) *proposalDelivery {
+	pd := &cs.proposalDelivery
+	if pd.height != height || pd.proposals == nil {
+		*pd = proposalDelivery{
+			height:    height,
+			proposals: make(map[int32]types.NodeID),
+			blocks:    make(map[string]types.NodeID),
+		}
+	}
+	return pd
+}
+
+// markProposalDelivered records that peerID delivered proposal.
+func (cs *State) markProposalDelivered(proposal *types.Proposal, peerID types.NodeID) {
+	cs.heightProposalDelivery(proposal.Height).proposals[proposal.Round] = deliveryPeer(peerID)
+}
+
+// markBlockCompleted records that the part of peerID completed block.
+func (cs *State) markBlockCompleted(block *types.Block, peerID types.NodeID) {
+	pd := cs.heightProposalDelivery(block.Height)
+	if _, ok := pd.blocks[string(block.Hash())]; !ok {
+		pd.blocks[string(block.Hash())] = deliveryPeer(peerID)
+	}
+}
+
+// proposalDeliveryOf returns the peers that delivered the proposal of round
+// and completed block, at the height of block, empty if unknown.
+func (cs *State) proposalDeliveryOf(round int32, block *types.Block) (proposalPeer, blockPeer types.NodeID) {
+	pd := &cs.proposalDelivery
+	if pd.height != block.Height {
+		return "", ""
+	}
+	return pd.proposals[round], pd.blocks[string(block.Hash())]
+}
+
+// recordProposalDelivery counts the peers that delivered the proposal and
+// the block of a committed height.
+func (cs *State) recordProposalDelivery(summary types.EventDataHeightSummary) {
+	if summary.ProposalPeer != "" {
+		cs.metrics.ProposalsDelivered.With("peer_id", string(summary.ProposalPeer)).Add(1)
+	}
+	if summary.BlockPeer != "" {
+		cs.metrics.BlocksCompleted.With("peer_id", string(summary.BlockPeer)).Add(1)
+	}
+}
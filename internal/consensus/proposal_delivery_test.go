@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateProposalDelivery(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	proposals, blocks := newLabeledCounter(), newLabeledCounter()
+	cs.metrics.ProposalsDelivered = proposals
+	cs.metrics.BlocksCompleted = blocks
+	sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryHeightSummary,
+	})
+	require.NoError(t, err)
+
+	// vss[1] proposes a block of 4 parts in round 1
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, types.Txs{tmrand.Bytes(3 * int(types.BlockPartSizeBytes))},
+		created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, parts.Total())
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+	proposal.Signature = p.Signature
+
+	send := func(peerID types.NodeID, msg Message) {
+		cs.peerMsgQueue <- msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}
+	}
+
+	// peer-a delivers the proposal and the first parts, peer-b the last part
+	// of the block, the first parts again then the last part
+	startTestRound(ctx, cs, height, round)
+	send("peer-a", &ProposalMessage{proposal})
+	send("peer-b", &ProposalMessage{proposal})
+	for i := 0; i < 3; i++ {
+		send("peer-a", &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)})
+		send("peer-b", &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)})
+	}
+	send("peer-b", &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(3)})
+	send("peer-a", &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(3)})
+	incrementRound(vss[1])
+	signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[1])
+	signAddVotes(ctx, t, cs, tmproto.PrecommitType, config.ChainID(), blockID, vss[1])
+
+	nextCtx, nextCancel := context.WithTimeout(ctx, ensureTimeout)
+	defer nextCancel()
+	msg, err := sub.Next(nextCtx)
+	require.NoError(t, err)
+	summary := msg.Data().(types.EventDataHeightSummary)
+	require.Equal(t, height, summary.Height)
+	require.Equal(t, pubKey.Address(), summary.Proposer)
+	require.Equal(t, types.NodeID("peer-a"), summary.ProposalPeer)
+	require.Equal(t, types.NodeID("peer-b"), summary.BlockPeer)
+	require.Equal(t, map[string]float64{"peer_id,peer-a": 1}, proposals.values)
+	require.Equal(t, map[string]float64{"peer_id,peer-b": 1}, blocks.values)
+}
@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"bytes"
+	"time"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ProposalLatency breaks down the time a proposal signed by this validator
+// took to disseminate. Durations of stages that were not observed are zero,
+// e.g. FirstPrevoteToQuorum for a single validator network.
+type ProposalLatency struct {
+	Height int64
+	Round  int32
+
+	// SignedToProcessed is the time from signing the proposal until the state
+	// machine processed it from the internal queue.
+	SignedToProcessed time.Duration
+	// ProcessedToFirstPrevote is the time from processing the proposal until
+	// the first prevote for its block from another validator.
+	ProcessedToFirstPrevote time.Duration
+	// FirstPrevoteToQuorum is the time from the first prevote of another
+	// validator until +2/3 prevotes for the block.
+	FirstPrevoteToQuorum time.Duration
+	// SignedToQuorum is the time from signing the proposal until +2/3
+	// prevotes for the block.
+	SignedToQuorum time.Duration
+}
+
+// proposalTimeline records when a proposal signed by this validator reached
+// each stage of its dissemination. It only tracks the latest proposal.
+type proposalTimeline struct {
+	height    int64
+	round     int32
+	blockHash tmbytes.HexBytes
+
+	signed       time.Time
+	processed    time.Time
+	firstPrevote time.Time
+	quorum       time.Time
+}
+
+func (pt *proposalTimeline) tracks(height int64, round int32, blockHash []byte) bool {
+	return !pt.signed.IsZero() && pt.height == height && pt.round == round &&
+		bytes.Equal(pt.blockHash, blockHash)
+}
+
+func (pt *proposalTimeline) latency() ProposalLatency {
+	since := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	return ProposalLatency{
+		Height:                  pt.height,
+		Round:                   pt.round,
+		SignedToProcessed:       since(pt.signed, pt.processed),
+		ProcessedToFirstPrevote: since(pt.processed, pt.firstPrevote),
+		FirstPrevoteToQuorum:    since(pt.firstPrevote, pt.quorum),
+		SignedToQuorum:          since(pt.signed, pt.quorum),
+	}
+}
+
+// markProposalSigned starts tracking a proposal signed by this validator.
+func (cs *State) markProposalSigned(proposal *types.Proposal) {
+	cs.proposalTimeline = proposalTimeline{
+		height:    proposal.Height,
+		round:     proposal.Round,
+		blockHash: proposal.BlockID.Hash,
+		signed:    time.Now(),
+	}
+}
+
+// markProposalProcessed records that our own proposal was processed from the
+// internal queue.
+func (cs *State) markProposalProcessed(proposal *types.Proposal) {
+	pt := &cs.proposalTimeline
+	if pt.tracks(proposal.Height, proposal.Round, proposal.BlockID.Hash) && pt.processed.IsZero() {
+		pt.processed = time.Now()
+	}
+}
+
+// markProposalPrevote records the first prevote of another validator for our
+// own proposal and the time +2/3 prevotes for it are reached. Once the quorum
+// is reached, the latency breakdown is exported.
+func (cs *State) markProposalPrevote(vote *types.Vote, prevotes *types.VoteSet) {
+	pt := &cs.proposalTimeline
+	if !pt.tracks(vote.Height, vote.Round, vote.BlockID.Hash) || !pt.quorum.IsZero() {
+		return
+	}
+
+	now := time.Now()
+	pubKey := cs.getPrivValidatorPubKey()
+	isOwnVote := pubKey != nil && bytes.Equal(vote.ValidatorAddress, pubKey.Address())
+	if !isOwnVote && pt.firstPrevote.IsZero() {
+		pt.firstPrevote = now
+	}
+
+	if blockID, ok := prevotes.TwoThirdsMajority(); ok && bytes.Equal(blockID.Hash, pt.blockHash) {
+		pt.quorum = now
+
+		latency := pt.latency()
+		cs.metrics.MarkProposalLatency(latency)
+		cs.lastProposalLatency = &latency
+	}
+}
+
+// GetLastProposalLatency returns the latency breakdown of the latest proposal
+// signed by this validator that reached +2/3 prevotes, if any.
+func (cs *State) GetLastProposalLatency() (ProposalLatency, bool) {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	if cs.lastProposalLatency == nil {
+		return ProposalLatency{}, false
+	}
+	return *cs.lastProposalLatency, true
+}
@@ -0,0 +1,147 @@
+package consensus
+
+import (
+	"bytes"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ProposalNeeded is fired on the internal event switch with
+// types.EventProposalNeededValue when the prevote of the proposer of the
+// current round for a block is received while this node has no proposal, so
+// that the proposal and its block parts can be solicited from the peers that
+// have the block.
+type ProposalNeeded struct {
+	Height  int64
+	Round   int32
+	BlockID types.BlockID
+
+	// Peers are the peers prevotes for BlockID were received from, that of
+	// the prevote of the proposer first, which are likely to have the block.
+	Peers []types.NodeID
+}
+
+// Outcomes of the propose grace.
+const (
+	// the proposal was completed during the grace
+	proposeGraceSaved = "saved"
+	// the grace elapsed without the proposal
+	proposeGraceExpired = "expired"
+)
+
+// proposeGrace tracks the solicitation of the proposal of a round and the
+// extension of its propose timeout, once each per round.
+type proposeGrace struct {
+	height int64
+	round  int32
+	// solicited is set once the proposal was solicited
+	solicited bool
+	// applied is set once the propose timeout was extended, until the
+	// prevote step is entered
+	applied bool
+}
+
+// proposeGraceDuration returns the extension of the propose timeout of round
+// once the block is known to exist, bounded by the vote timeout, or 0 if it
+// is disabled.
+func (cs *State) proposeGraceDuration(round int32) time.Duration {
+	grace := cs.config.ProposeGrace
+	if timeout := cs.voteTimeout(round); grace > timeout {
+		grace = timeout
+	}
+	return grace
+}
+
+// roundProposeGrace returns the propose grace of the current round.
+func (cs *State) roundProposeGrace() *proposeGrace {
+	pg := &cs.proposeGrace
+	if height, round := cs.roundState.Height(), cs.roundState.Round(); pg.height != height || pg.round != round {
+		*pg = proposeGrace{height: height, round: round}
+	}
+	return pg
+}
+
+// recordPrevotePeer records the peer a prevote of the current height for a
+// block was received from.
+func (cs *State) recordPrevotePeer(vote *types.Vote, peerID types.NodeID) {
+	if peerID == "" || vote.BlockID.IsNil() {
+		return
+	}
+	cs.prevotePeers.add(vote.Height, vote.BlockID.Hash, peerID)
+}
+
+// checkProposerPrevote solicits the proposal of the current round if vote is
+// the prevote of its proposer for a block while this node is still waiting
+// for the proposal: the block exists, and the proposal was likely outpaced by
+// the prevote in the gossip.
+func (cs *State) checkProposerPrevote(vote *types.Vote, peerID types.NodeID) {
+	if vote.Round != cs.roundState.Round() || vote.BlockID.IsNil() ||
+		cs.roundState.Step() != cstypes.RoundStepPropose || cs.roundState.Proposal() != nil {
+		return
+	}
+	proposer := cs.roundState.Validators().GetProposer()
+	if proposer == nil || !bytes.Equal(proposer.Address, vote.ValidatorAddress) {
+		return
+	}
+	pg := cs.roundProposeGrace()
+	if pg.solicited {
+		return
+	}
+	pg.solicited = true
+
+	peers := []types.NodeID{}
+	if peerID != "" {
+		peers = append(peers, peerID)
+	}
+	for _, p := range cs.prevotePeers.get(vote.Height, vote.BlockID.Hash) {
+		if p != peerID {
+			peers = append(peers, p)
+		}
+	}
+	cs.logger.Info("received the prevote of the proposer before its proposal; soliciting the proposal",
+		"height", vote.Height, "round", vote.Round, "block_hash", vote.BlockID.Hash, "peer", peerID)
+	cs.metrics.ProposalSolicitations.Add(1)
+	cs.evsw.FireEvent(types.EventProposalNeededValue, &ProposalNeeded{
+		Height:  vote.Height,
+		Round:   vote.Round,
+		BlockID: vote.BlockID,
+		Peers:   peers,
+	})
+}
+
+// extendProposeTimeout extends the propose timeout of ti by the propose
+// grace, once per round, if the proposal was solicited because the block
+// exists. It reports whether the timeout was extended.
+func (cs *State) extendProposeTimeout(ti timeoutInfo) bool {
+	grace := cs.proposeGraceDuration(ti.Round)
+	if grace <= 0 || cs.roundState.Proposal() != nil && cs.isProposalComplete() {
+		return false
+	}
+	pg := cs.roundProposeGrace()
+	if !pg.solicited || pg.applied {
+		return false
+	}
+	pg.applied = true
+	cs.logger.Info("extending the propose timeout; the proposer prevoted for a block",
+		"height", ti.Height, "round", ti.Round, "grace", grace)
+	cs.scheduleTimeout(grace, ti.Height, ti.Round, cstypes.RoundStepPropose)
+	return true
+}
+
+// endProposeGrace records the outcome of the propose grace of the current
+// round, if the propose timeout was extended, as the prevote step is
+// entered.
+func (cs *State) endProposeGrace() {
+	pg := cs.roundProposeGrace()
+	if !pg.applied {
+		return
+	}
+	pg.applied = false
+	outcome := proposeGraceExpired
+	if cs.isProposalComplete() {
+		outcome = proposeGraceSaved
+	}
+	cs.metrics.ProposeGrace.With("outcome", outcome).Add(1)
+}
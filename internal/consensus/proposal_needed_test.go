@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	tmevents "github.com/tendermint/tendermint/libs/events"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateSolicitsProposalOnProposerPrevote(t *testing.T) {
+	config := configSetup(t)
+	const (
+		proposeTimeout = 300 * time.Millisecond
+		voteTimeout    = 200 * time.Millisecond
+	)
+
+	for _, tc := range []struct {
+		name string
+		// whether the proposal is delivered during the grace
+		deliver bool
+	}{
+		{name: "grace saves the round", deliver: true},
+		{name: "grace expires"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+			vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+			height, round := cs1.roundState.Height(), int32(1)
+			cs1.config.UnsafeProposeTimeoutOverride = proposeTimeout
+			cs1.config.UnsafeProposeTimeoutDeltaOverride = time.Nanosecond
+			cs1.config.UnsafeVoteTimeoutOverride = voteTimeout
+			cs1.config.UnsafeVoteTimeoutDeltaOverride = time.Nanosecond
+			// the grace is bounded by the vote timeout
+			cs1.config.ProposeGrace = time.Minute
+			solicitations := generic.NewCounter("proposal_solicitations")
+			cs1.metrics.ProposalSolicitations = solicitations
+			grace := newLabeledCounter()
+			cs1.metrics.ProposeGrace = grace
+
+			incrementRound(vs2, vs3, vs4)
+
+			newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+			pv1, err := cs1.privValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+			proposalNeededCh := make(chan *ProposalNeeded, 2)
+			require.NoError(t, cs1.evsw.AddListenerForEvent("test", types.EventProposalNeededValue, func(data tmevents.EventData) error {
+				proposalNeededCh <- data.(*ProposalNeeded)
+				return nil
+			}))
+
+			// vs2 proposes in round 1
+			prop, propBlock := decideProposal(ctx, t, cs1, vs2, height, round)
+			partSet, err := propBlock.MakePartSet(types.BlockPartSizeBytes)
+			require.NoError(t, err)
+			blockID := types.BlockID{Hash: propBlock.Hash(), PartSetHeader: partSet.Header()}
+
+			startTestRound(ctx, cs1, height, round)
+			start := time.Now()
+			ensureNewRound(t, newRoundCh, height, round)
+
+			addVote := func(vs *validatorStub, peerID types.NodeID) {
+				vote := signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), blockID)
+				cs1.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: peerID}
+			}
+			// the prevote of another validator does not solicit the proposal
+			addVote(vs3, "peer-b")
+			// that of the proposer does, once, from the peers that prevoted
+			addVote(vs2, "peer-a")
+			addVote(vs4, "peer-c")
+
+			select {
+			case proposalNeeded := <-proposalNeededCh:
+				require.Equal(t, &ProposalNeeded{
+					Height:  height,
+					Round:   round,
+					BlockID: blockID,
+					Peers:   []types.NodeID{"peer-a", "peer-b"},
+				}, proposalNeeded)
+			case <-time.After(ensureTimeout):
+				t.Fatal("no ProposalNeeded event")
+			}
+
+			// the propose timeout is extended
+			ensureNoMessageBeforeTimeout(t, voteCh, proposeTimeout+voteTimeout/2-time.Since(start),
+				"unexpected prevote before the end of the grace")
+			require.Equal(t, 1.0, solicitations.Value())
+			require.Empty(t, proposalNeededCh)
+
+			if tc.deliver {
+				require.NoError(t, cs1.SetProposalAndBlock(ctx, prop, propBlock, partSet, "peer-a"))
+				ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+				require.Equal(t, 1.0, grace.values["outcome,"+proposeGraceSaved])
+				return
+			}
+			// by the vote timeout at most
+			ensurePrevoteMatch(t, voteCh, height, round, nil)
+			require.GreaterOrEqual(t, time.Since(start), proposeTimeout+voteTimeout)
+			require.Less(t, time.Since(start), proposeTimeout+2*voteTimeout)
+			require.Equal(t, 1.0, grace.values["outcome,"+proposeGraceExpired])
+		})
+	}
+}
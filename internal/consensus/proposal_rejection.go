@@ -0,0 +1,147 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// Proposal rejection sentinel errors, in addition to
+// ErrInvalidProposalSignature and ErrInvalidProposalPOLRound.
+var (
+	ErrNilProposal         = errors.New("nil proposal")
+	ErrDuplicateProposal   = errors.New("already have a proposal for the round")
+	ErrProposalWrongHeight = errors.New("proposal for another height")
+	ErrProposalWrongRound  = errors.New("proposal for another round")
+	ErrProposalPostCommit  = errors.New("proposal for a committed round")
+
+	ErrProposalDuplicateTxKeys = errors.New("proposal with duplicate tx keys")
+	ErrProposalTooManyTxKeys   = errors.New("proposal with more tx keys than fit in a block")
+)
+
+// ProposalRejectionReason is why a proposal was not set.
+type ProposalRejectionReason string
+
+const (
+	ProposalRejectionNil              ProposalRejectionReason = "nil"
+	ProposalRejectionDuplicate        ProposalRejectionReason = "duplicate"
+	ProposalRejectionWrongHeight      ProposalRejectionReason = "wrong-height"
+	ProposalRejectionWrongRound       ProposalRejectionReason = "wrong-round"
+	ProposalRejectionInvalidPOLRound  ProposalRejectionReason = "invalid-pol-round"
+	ProposalRejectionInvalidSignature ProposalRejectionReason = "invalid-signature"
+	ProposalRejectionDuplicateTxKeys  ProposalRejectionReason = "duplicate-tx-keys"
+	ProposalRejectionTooManyTxKeys    ProposalRejectionReason = "too-many-tx-keys"
+	ProposalRejectionPostCommit       ProposalRejectionReason = "post-commit"
+)
+
+// proposalRejectionErrs are the sentinel errors of the rejection reasons.
+var proposalRejectionErrs = map[ProposalRejectionReason]error{
+	ProposalRejectionNil:              ErrNilProposal,
+	ProposalRejectionDuplicate:        ErrDuplicateProposal,
+	ProposalRejectionWrongHeight:      ErrProposalWrongHeight,
+	ProposalRejectionWrongRound:       ErrProposalWrongRound,
+	ProposalRejectionInvalidPOLRound:  ErrInvalidProposalPOLRound,
+	ProposalRejectionInvalidSignature: ErrInvalidProposalSignature,
+	ProposalRejectionDuplicateTxKeys:  ErrProposalDuplicateTxKeys,
+	ProposalRejectionTooManyTxKeys:    ErrProposalTooManyTxKeys,
+	ProposalRejectionPostCommit:       ErrProposalPostCommit,
+}
+
+// ProposalRejectionError is returned by setProposal for a proposal it did not
+// set. errors.Is matches it against the sentinel error of its reason.
+type ProposalRejectionError struct {
+	Reason ProposalRejectionReason
+	// Height and Round are those of the proposal, zero for a nil proposal.
+	Height int64
+	Round  int32
+	// Proposer is the validator that signed the proposal, set when the
+	// proposal is rejected past the verification of its signature.
+	Proposer types.Address
+}
+
+func newProposalRejection(reason ProposalRejectionReason, proposal *types.Proposal) *ProposalRejectionError {
+	err := &ProposalRejectionError{Reason: reason}
+	if proposal != nil {
+		err.Height, err.Round = proposal.Height, proposal.Round
+	}
+	return err
+}
+
+func (e *ProposalRejectionError) Error() string {
+	if len(e.Proposer) > 0 {
+		return fmt.Sprintf("%v (height %d, round %d, proposer %v)", e.Unwrap(), e.Height, e.Round, e.Proposer)
+	}
+	return fmt.Sprintf("%v (height %d, round %d)", e.Unwrap(), e.Height, e.Round)
+}
+
+func (e *ProposalRejectionError) Unwrap() error {
+	return proposalRejectionErrs[e.Reason]
+}
+
+// Ignorable reports whether the proposal was not set as it does not apply to
+// the round, which honest peers gossip as well, or arrived once the round was
+// committed.
+func (e *ProposalRejectionError) Ignorable() bool {
+	switch e.Reason {
+	case ProposalRejectionNil, ProposalRejectionDuplicate, ProposalRejectionWrongHeight, ProposalRejectionWrongRound,
+		ProposalRejectionPostCommit:
+		return true
+	default:
+		return false
+	}
+}
+
+// Punishable reports whether the peer that sent the proposal, or the
+// proposer, misbehaved. Honest peers verify the signature of the proposals
+// they gossip, while an invalid POL round is the fault of the proposer. The
+// tx keys that cannot be those of a valid block are reported as well, with
+// the proposer that signed them.
+func (e *ProposalRejectionError) Punishable() bool {
+	switch e.Reason {
+	case ProposalRejectionInvalidSignature, ProposalRejectionDuplicateTxKeys, ProposalRejectionTooManyTxKeys:
+		return true
+	default:
+		return false
+	}
+}
+
+// classifyProposalRejection counts the rejection of a proposal received from
+// peerID, reports the peer if it misbehaved, and returns err, or nil if the
+// rejection is ignorable.
+func (cs *State) classifyProposalRejection(peerID types.NodeID, err error) error {
+	var rejection *ProposalRejectionError
+	if !errors.As(err, &rejection) {
+		return err
+	}
+	cs.metrics.ProposalRejections.With("reason", string(rejection.Reason)).Add(1)
+	if rejection.Punishable() && cs.reportPeerMisbehavior != nil && peerID != "" {
+		cs.reportPeerMisbehavior(peerID, err)
+	}
+	if rejection.Ignorable() {
+		return nil
+	}
+	return err
+}
+
+// minEncodedTxBytes is the size of the smallest tx encoded in the data of a
+// block.
+var minEncodedTxBytes = types.ComputeProtoSizeForTxs([]types.Tx{{}})
+
+// checkProposalTxKeys returns why the tx keys of proposal, gossiped in place
+// of its block in key-only mode, cannot be those of a valid block of at most
+// maxBytes: they are duplicated, or more than the smallest txs fit in. The
+// keys are checked before any tx is fetched from the mempool for them.
+func checkProposalTxKeys(proposal *types.Proposal, maxBytes int64) (ProposalRejectionReason, bool) {
+	if int64(len(proposal.TxKeys))*minEncodedTxBytes > maxBytes {
+		return ProposalRejectionTooManyTxKeys, false
+	}
+	seen := make(map[types.TxKey]struct{}, len(proposal.TxKeys))
+	for _, key := range proposal.TxKeys {
+		if _, ok := seen[key]; ok {
+			return ProposalRejectionDuplicateTxKeys, false
+		}
+		seen[key] = struct{}{}
+	}
+	return "", true
+}
@@ -0,0 +1,292 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/internal/mempool"
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateProposalRejections(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		malleate   func(cs *State, proposal *types.Proposal) *types.Proposal
+		reason     ProposalRejectionReason
+		sentinel   error
+		ignorable  bool
+		punishable bool
+	}{
+		{
+			name:     "accepted",
+			malleate: func(_ *State, proposal *types.Proposal) *types.Proposal { return proposal },
+		},
+		{
+			name:      "nil",
+			malleate:  func(*State, *types.Proposal) *types.Proposal { return nil },
+			reason:    ProposalRejectionNil,
+			sentinel:  ErrNilProposal,
+			ignorable: true,
+		},
+		{
+			name: "duplicate",
+			malleate: func(cs *State, proposal *types.Proposal) *types.Proposal {
+				cs.roundState.SetProposal(proposal)
+				return proposal
+			},
+			reason:    ProposalRejectionDuplicate,
+			sentinel:  ErrDuplicateProposal,
+			ignorable: true,
+		},
+		{
+			name: "wrong height",
+			malleate: func(_ *State, proposal *types.Proposal) *types.Proposal {
+				proposal.Height++
+				return proposal
+			},
+			reason:    ProposalRejectionWrongHeight,
+			sentinel:  ErrProposalWrongHeight,
+			ignorable: true,
+		},
+		{
+			name: "wrong round",
+			malleate: func(_ *State, proposal *types.Proposal) *types.Proposal {
+				proposal.Round++
+				return proposal
+			},
+			reason:    ProposalRejectionWrongRound,
+			sentinel:  ErrProposalWrongRound,
+			ignorable: true,
+		},
+		{
+			name: "invalid POL round",
+			malleate: func(_ *State, proposal *types.Proposal) *types.Proposal {
+				proposal.POLRound = proposal.Round
+				return proposal
+			},
+			reason:   ProposalRejectionInvalidPOLRound,
+			sentinel: ErrInvalidProposalPOLRound,
+		},
+		{
+			name: "invalid signature",
+			malleate: func(_ *State, proposal *types.Proposal) *types.Proposal {
+				proposal.Signature = make([]byte, len(proposal.Signature))
+				return proposal
+			},
+			reason:     ProposalRejectionInvalidSignature,
+			sentinel:   ErrInvalidProposalSignature,
+			punishable: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := configSetup(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			rejections := newLabeledCounter()
+			cs.metrics.ProposalRejections = rejections
+			var reported []error
+			cs.reportPeerMisbehavior = func(peerID types.NodeID, err error) {
+				require.Equal(t, types.NodeID("peer"), peerID)
+				reported = append(reported, err)
+			}
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+			proposer := cs.roundState.Validators().GetProposer().Address
+			var proposal *types.Proposal
+			for _, vs := range vss {
+				pubKey, err := vs.PrivValidator.GetPubKey(ctx)
+				require.NoError(t, err)
+				if bytes.Equal(pubKey.Address(), proposer) {
+					proposal, _ = decideProposal(ctx, t, cs, vs, height, round)
+				}
+			}
+			require.NotNil(t, proposal)
+			proposal = tc.malleate(cs, proposal)
+
+			err := cs.defaultSetProposal(proposal, "", tmtime.Now())
+			if tc.reason == "" {
+				require.NoError(t, err)
+				require.Equal(t, proposal, cs.roundState.Proposal())
+				return
+			}
+			var rejection *ProposalRejectionError
+			require.True(t, errors.As(err, &rejection))
+			require.Equal(t, tc.reason, rejection.Reason)
+			require.ErrorIs(t, err, tc.sentinel)
+			require.Equal(t, tc.ignorable, rejection.Ignorable())
+			require.Equal(t, tc.punishable, rejection.Punishable())
+
+			// the peer is reported only if it misbehaved, and the ignorable
+			// rejections are no errors of the message
+			classified := cs.classifyProposalRejection("peer", err)
+			if tc.ignorable {
+				require.NoError(t, classified)
+			} else {
+				require.ErrorIs(t, classified, tc.sentinel)
+			}
+			if tc.punishable {
+				require.Equal(t, []error{err}, reported)
+			} else {
+				require.Empty(t, reported)
+			}
+			require.Equal(t, float64(1), rejections.values["reason,"+string(tc.reason)])
+		})
+	}
+}
+
+func TestStateRejectsInvalidProposalTxKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		txKeys   func(txs types.Txs) []types.TxKey
+		maxBytes int64
+		reason   ProposalRejectionReason
+		sentinel error
+	}{
+		{
+			name: "duplicate",
+			txKeys: func(txs types.Txs) []types.TxKey {
+				return []types.TxKey{txs[0].Key(), txs[1].Key(), txs[0].Key()}
+			},
+			reason:   ProposalRejectionDuplicateTxKeys,
+			sentinel: ErrProposalDuplicateTxKeys,
+		},
+		{
+			name: "over max bytes",
+			txKeys: func(txs types.Txs) []types.TxKey {
+				keys := make([]types.TxKey, 0, 1000)
+				for i := 0; i < cap(keys); i++ {
+					keys = append(keys, types.Tx(fmt.Sprintf("k%d=v", i)).Key())
+				}
+				return keys
+			},
+			maxBytes: 1000,
+			reason:   ProposalRejectionTooManyTxKeys,
+			sentinel: ErrProposalTooManyTxKeys,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := configSetup(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+			cs.config.GossipTransactionKeyOnly = true
+			if tc.maxBytes > 0 {
+				cs.state.ConsensusParams.Block.MaxBytes = tc.maxBytes
+			}
+			rejections := newLabeledCounter()
+			cs.metrics.ProposalRejections = rejections
+			var reported []error
+			cs.reportPeerMisbehavior = func(peerID types.NodeID, err error) {
+				require.Equal(t, types.NodeID("peer"), peerID)
+				reported = append(reported, err)
+			}
+
+			txs := types.Txs{types.Tx("a=1"), types.Tx("b=2")}
+			for _, tx := range txs {
+				require.NoError(t, assertMempool(t, cs.txNotifier).CheckTx(ctx, tx, nil, mempool.TxInfo{}))
+			}
+			height, round := cs.roundState.Height(), int32(1)
+			created, err := cs.createProposalBlock(ctx)
+			require.NoError(t, err)
+			pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			block := cs.state.MakeBlock(height, txs, created.LastCommit, nil, pubKey.Address())
+			parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+			require.NoError(t, err)
+
+			// vss[1], the proposer of the round, signs the crafted tx keys
+			cs.enterNewRound(ctx, height, round, "test")
+			blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+			proposal := types.NewProposal(height, round, -1, blockID, block.Time, tc.txKeys(txs),
+				block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+			p := proposal.ToProto()
+			require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+			proposal.Signature = p.Signature
+			cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+
+			// the proposal is rejected before its txs are fetched
+			require.Nil(t, cs.roundState.Proposal())
+			require.Nil(t, cs.blockReconstruction)
+			require.Equal(t, float64(1), rejections.values["reason,"+string(tc.reason)])
+			require.Len(t, reported, 1)
+			require.ErrorIs(t, reported[0], tc.sentinel)
+			var rejection *ProposalRejectionError
+			require.True(t, errors.As(reported[0], &rejection))
+			require.Equal(t, pubKey.Address(), rejection.Proposer)
+		})
+	}
+}
+
+func TestStatePostCommitProposal(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 4})
+	rejections, delays, differences := newLabeledCounter(), newLabeledHistogram(), newLabeledHistogram()
+	cs.metrics.ProposalRejections = rejections
+	cs.metrics.PostCommitProposalDelay = delays
+	cs.metrics.ProposalTimestampDifference = differences
+
+	// vss[1] proposes in round 1, either of two blocks
+	height, round := cs.roundState.Height(), int32(1)
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	propose := func(txs types.Txs) (*types.Proposal, *types.PartSet) {
+		block := cs.state.MakeBlock(height, txs, created.LastCommit, nil, pubKey.Address())
+		parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+		require.NoError(t, err)
+		blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+		proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+			block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+		p := proposal.ToProto()
+		require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+		proposal.Signature = p.Signature
+		return proposal, parts
+	}
+	committed, parts := propose(types.Txs{types.Tx("a=1")})
+	conflicting, _ := propose(types.Txs{types.Tx("b=2")})
+
+	// the other validators precommit the block before the State received it
+	cs.enterNewRound(ctx, height, round, "test")
+	incrementRound(vss[1:]...)
+	for _, vs := range vss[1:] {
+		vote := signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), committed.BlockID)
+		cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	require.Equal(t, cstypes.RoundStepCommit, cs.roundState.Step())
+	require.Nil(t, cs.roundState.Proposal())
+	before := *cs.roundState.CopyInternal()
+
+	// a conflicting proposal is counted and changes nothing
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{conflicting}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	require.Equal(t, map[string]float64{"reason,post-commit": 1}, rejections.values)
+	require.Equal(t, before, *cs.roundState.CopyInternal())
+
+	// the proposal of the committed block only has its receive time recorded
+	cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{committed}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	require.Equal(t, map[string]float64{"reason,post-commit": 1}, rejections.values)
+	require.Equal(t, map[string]int{"": 1}, delays.counts)
+	require.Empty(t, differences.counts)
+	require.Equal(t, before, *cs.roundState.CopyInternal())
+
+	// the parts of the committed block complete the commit
+	for i := 0; i < int(parts.Total()); i++ {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	require.Equal(t, height, cs.blockStore.Height())
+	require.Equal(t, committed.BlockID.Hash, cs.blockStore.LoadBlock(height).Hash())
+}
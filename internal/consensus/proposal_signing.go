@@ -0,0 +1,132 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tendermint/tendermint/privval"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// signProposalRetryInterval is the time to wait before signing a proposal
+// again after a transient failure of the private validator.
+const signProposalRetryInterval = 100 * time.Millisecond
+
+// doubleSignRefusalFile is the name of the marker file written next to the
+// WAL when the private validator refuses to sign a conflicting proposal.
+const doubleSignRefusalFile = "double_sign_refusal.json"
+
+// ErrDoubleSignRefusalRecorded is returned on start if the private validator
+// refused to sign a conflicting proposal since the marker file was removed.
+var ErrDoubleSignRefusalRecorded = errors.New("private validator refused to sign a conflicting proposal")
+
+// doubleSignRefusal is the content of the marker file.
+type doubleSignRefusal struct {
+	Height int64     `json:"height,string"`
+	Round  int32     `json:"round"`
+	Error  string    `json:"error"`
+	Time   time.Time `json:"time"`
+}
+
+// signProposal signs proposal, retrying transient failures of the private
+// validator until ctx is done.
+func (cs *State) signProposal(ctx context.Context, proposal *tmproto.Proposal) error {
+	privValidator, _ := cs.getPrivValidator()
+	if privValidator == nil {
+		return errors.New("no private validator to sign the proposal")
+	}
+	if err := cs.advanceSignHRS(proposal.Height, proposal.Round, tmproto.ProposalType, proposal.BlockID.Hash, cs.replayMode); err != nil {
+		return err
+	}
+	for {
+		if cs.SigningHalted() {
+			return errSigningHalted
+		}
+		signBytes := types.ProposalSignBytes(cs.state.ChainID, proposal)
+		err := privValidator.SignProposal(ctx, cs.state.ChainID, proposal)
+		cs.recordProposalSignRequest(cs.state.ChainID, signBytes, proposal, err)
+		if err == nil {
+			cs.markSignHRSSigned(proposal.Height, proposal.Round, tmproto.ProposalType)
+			return nil
+		}
+		if !privval.IsTransientError(err) {
+			return err
+		}
+
+		cs.logger.Info("failed signing proposal; retrying",
+			"height", proposal.Height, "round", proposal.Round, "err", err)
+		timer := time.NewTimer(signProposalRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// recordDoubleSignRefusal reports that the private validator refused to sign
+// the proposal of height and round because it already signed a conflicting
+// one, which means another instance of this validator may be running or its
+// sign state was lost.
+func (cs *State) recordDoubleSignRefusal(height int64, round int32, err error) {
+	cs.logger.Error("CONSENSUS FAILURE!!! private validator refused to sign a conflicting proposal; "+
+		"check that no other instance of this validator is running",
+		"height", height, "round", round, "err", err)
+	cs.metrics.DoubleSignRefusals.Add(1)
+
+	if err := cs.publishEvent(types.EventDoubleSignRefusalValue, types.EventDataDoubleSignRefusal{
+		Height: height,
+		Round:  round,
+		Error:  err.Error(),
+	}); err != nil {
+		cs.logger.Error("failed publishing double sign refusal", "err", err)
+	}
+
+	if err := cs.saveDoubleSignRefusal(doubleSignRefusal{
+		Height: height,
+		Round:  round,
+		Error:  err.Error(),
+		Time:   time.Now(),
+	}); err != nil {
+		cs.logger.Error("failed saving double sign refusal", "err", err)
+	}
+}
+
+func (cs *State) doubleSignRefusalPath() string {
+	return filepath.Join(filepath.Dir(cs.config.WalFile()), doubleSignRefusalFile)
+}
+
+func (cs *State) saveDoubleSignRefusal(refusal doubleSignRefusal) error {
+	data, err := json.Marshal(refusal)
+	if err != nil {
+		return err
+	}
+	path := cs.doubleSignRefusalPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadDoubleSignRefusal returns the recorded double sign refusal, if any.
+func (cs *State) loadDoubleSignRefusal() (*doubleSignRefusal, error) {
+	data, err := os.ReadFile(cs.doubleSignRefusalPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	refusal := new(doubleSignRefusal)
+	if err := json.Unmarshal(data, refusal); err != nil {
+		return nil, fmt.Errorf("invalid double sign refusal file %s: %w", cs.doubleSignRefusalPath(), err)
+	}
+	return refusal, nil
+}
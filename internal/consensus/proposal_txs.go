@@ -0,0 +1,80 @@
+package consensus
+
+import (
+	"sync"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// proposalTxKeys indexes the tx keys of the proposal of the current round, so
+// that ProposalContainsTx does not scan them. It is reset in each round.
+type proposalTxKeys struct {
+	mtx      sync.RWMutex
+	proposal *types.Proposal
+	keys     map[types.TxKey]struct{}
+}
+
+func (pk *proposalTxKeys) set(proposal *types.Proposal) {
+	keys := make(map[types.TxKey]struct{}, len(proposal.TxKeys))
+	for _, key := range proposal.TxKeys {
+		keys[key] = struct{}{}
+	}
+	pk.mtx.Lock()
+	defer pk.mtx.Unlock()
+	pk.proposal, pk.keys = proposal, keys
+}
+
+func (pk *proposalTxKeys) reset() {
+	pk.mtx.Lock()
+	defer pk.mtx.Unlock()
+	pk.proposal, pk.keys = nil, nil
+}
+
+// contains returns whether proposal contains key, and false if proposal is
+// not the indexed one.
+func (pk *proposalTxKeys) contains(proposal *types.Proposal, key types.TxKey) (contains, indexed bool) {
+	pk.mtx.RLock()
+	defer pk.mtx.RUnlock()
+	if pk.proposal != proposal {
+		return false, false
+	}
+	_, contains = pk.keys[key]
+	return contains, true
+}
+
+// ProposalContainsTx returns whether the tx of key is part of the proposal of
+// the current round, and the height and round of that proposal. If there is
+// no proposal, it returns false and the current height and round.
+// If config.GossipTransactionKeyOnly is not set, the txs of the proposal block
+// are consulted instead of the tx keys of the proposal, and it returns false
+// until the block is complete.
+func (cs *State) ProposalContainsTx(key types.TxKey) (bool, int64, int32) {
+	rs := cs.GetRoundState()
+	if rs.Proposal == nil {
+		return false, rs.Height, rs.Round
+	}
+	height, round := rs.Proposal.Height, rs.Proposal.Round
+
+	if !cs.config.GossipTransactionKeyOnly {
+		if rs.ProposalBlock == nil {
+			return false, height, round
+		}
+		for _, tx := range rs.ProposalBlock.Txs {
+			if tx.Key() == key {
+				return true, height, round
+			}
+		}
+		return false, height, round
+	}
+
+	if contains, indexed := cs.proposalTxKeys.contains(rs.Proposal, key); indexed {
+		return contains, height, round
+	}
+	// the round state is a snapshot taken before the WAL replay
+	for _, txKey := range rs.Proposal.TxKeys {
+		if txKey == key {
+			return true, height, round
+		}
+	}
+	return false, height, round
+}
@@ -0,0 +1,74 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateProposalContainsTx(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	height := cs.roundState.Height()
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	included, excluded := types.Tx("included"), types.Tx("excluded")
+	block := cs.state.MakeBlock(height, types.Txs{included}, created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	// propose enters round, whose proposer is vss[1], and receives the
+	// proposal of block from a peer
+	propose := func(round int32) {
+		cs.enterNewRound(ctx, height, round, "test")
+		blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+		proposal := types.NewProposal(height, round, -1, blockID, block.Time, block.GetTxKeys(),
+			block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+		p := proposal.ToProto()
+		require.NoError(t, vss[1].SignProposal(ctx, config.ChainID(), p))
+		proposal.Signature = p.Signature
+		cs.handleMsg(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	requireContains := func(tx types.Tx, expected bool, round int32) {
+		t.Helper()
+		contains, h, r := cs.ProposalContainsTx(tx.Key())
+		require.Equal(t, expected, contains)
+		require.Equal(t, height, h)
+		require.Equal(t, round, r)
+	}
+
+	// no proposal
+	cs.enterNewRound(ctx, height, 1, "test")
+	requireContains(included, false, 1)
+
+	// full block mode: the txs are known once the block is complete
+	propose(1)
+	requireContains(included, false, 1)
+	for i := 0; i < int(parts.Total()); i++ {
+		msg := &BlockPartMessage{Height: height, Round: 1, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	requireContains(included, true, 1)
+	requireContains(excluded, false, 1)
+
+	// key-only mode: the tx keys of the proposal are consulted, although its
+	// txs are not in the mempool
+	cs.config.GossipTransactionKeyOnly = true
+	propose(3)
+	require.Nil(t, cs.roundState.ProposalBlock())
+	requireContains(included, true, 3)
+	requireContains(excluded, false, 3)
+
+	// the index is reset in the next round
+	cs.enterNewRound(ctx, height, 5, "test")
+	requireContains(included, false, 5)
+}
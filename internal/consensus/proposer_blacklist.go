@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// proposerBlacklist tracks validators whose proposals the application keeps
+// rejecting in ProcessProposal. Once a validator reaches threshold consecutive
+// rejections, its new proposals are prevoted nil as soon as they are received
+// for the next heights heights, instead of waiting for the block parts and the
+// propose timeout.
+//
+// The blacklist only makes us prevote nil earlier than we otherwise would. It
+// never applies to a proposal re-proposing a block with a polka, and it does
+// not affect precommits, so safety is unaffected.
+type proposerBlacklist struct {
+	threshold int
+	heights   int64
+
+	// consecutive ProcessProposal rejections by proposer address
+	rejections map[string]int
+	// last height a proposer is blacklisted at, by proposer address
+	until map[string]int64
+}
+
+// newProposerBlacklist returns a blacklist, or nil if threshold is 0.
+func newProposerBlacklist(threshold int, heights int64) *proposerBlacklist {
+	if threshold <= 0 {
+		return nil
+	}
+	return &proposerBlacklist{
+		threshold:  threshold,
+		heights:    heights,
+		rejections: make(map[string]int),
+		until:      make(map[string]int64),
+	}
+}
+
+// recordProcessProposal records whether the application accepted the
+// proposal of proposer at height.
+func (bl *proposerBlacklist) recordProcessProposal(proposer types.Address, height int64, accepted bool) {
+	if bl == nil {
+		return
+	}
+
+	key := string(proposer)
+	if accepted {
+		delete(bl.rejections, key)
+		return
+	}
+
+	bl.rejections[key]++
+	if bl.rejections[key] >= bl.threshold {
+		delete(bl.rejections, key)
+		bl.until[key] = height + bl.heights
+	}
+}
+
+// isBlacklisted returns whether proposals of proposer at height are to be
+// prevoted nil right away. Expired entries are removed.
+func (bl *proposerBlacklist) isBlacklisted(proposer types.Address, height int64) bool {
+	if bl == nil {
+		return false
+	}
+
+	key := string(proposer)
+	until, ok := bl.until[key]
+	if !ok {
+		return false
+	}
+	if height > until {
+		delete(bl.until, key)
+		return false
+	}
+	return true
+}
+
+// isBlacklistedProposal returns whether the proposal of the current round is
+// from a blacklisted proposer and may be prevoted nil without waiting for its
+// block.
+func (cs *State) isBlacklistedProposal() bool {
+	proposal := cs.roundState.Proposal()
+	if proposal == nil || proposal.POLRound != -1 {
+		return false
+	}
+	// never prevote nil early on a block that already has a polka
+	if blockID, ok := cs.roundState.Votes().Prevotes(proposal.Round).TwoThirdsMajority(); ok &&
+		blockID.Equals(proposal.BlockID) {
+		return false
+	}
+	proposer := cs.roundState.Validators().GetProposer().Address
+	return cs.proposerBlacklist.isBlacklisted(proposer, proposal.Height)
+}
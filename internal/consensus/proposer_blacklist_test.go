@@ -0,0 +1,37 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestProposerBlacklist(t *testing.T) {
+	proposer := types.Address("proposer")
+	other := types.Address("other")
+
+	require.Nil(t, newProposerBlacklist(0, 10))
+	var disabled *proposerBlacklist
+	disabled.recordProcessProposal(proposer, 1, false)
+	require.False(t, disabled.isBlacklisted(proposer, 1))
+
+	bl := newProposerBlacklist(2, 3)
+
+	// an accepted proposal resets the consecutive rejections
+	bl.recordProcessProposal(proposer, 1, false)
+	bl.recordProcessProposal(proposer, 2, true)
+	bl.recordProcessProposal(proposer, 3, false)
+	require.False(t, bl.isBlacklisted(proposer, 4))
+
+	bl.recordProcessProposal(proposer, 4, false)
+	require.False(t, bl.isBlacklisted(other, 5))
+	for h := int64(5); h <= 7; h++ {
+		require.True(t, bl.isBlacklisted(proposer, h), "height %d", h)
+	}
+
+	// the entry expires after the configured number of heights
+	require.False(t, bl.isBlacklisted(proposer, 8))
+	require.Empty(t, bl.until)
+}
@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// ValidatorPriority is the proposer priority of a validator.
+type ValidatorPriority struct {
+	Address          types.Address
+	VotingPower      int64
+	ProposerPriority int64
+}
+
+// ProposerRecord records the proposer of the round a height was committed in.
+type ProposerRecord struct {
+	Height int64
+	Round  int32
+	// Proposer is the address of the proposer of Round.
+	Proposer types.Address
+	// PriorityBefore is the proposer priority of the proposer when it was
+	// chosen, the highest of the validator set, and PriorityAfter its
+	// priority once decreased by TotalPower for being chosen.
+	PriorityBefore int64
+	PriorityAfter  int64
+	TotalPower     int64
+}
+
+// ProposerPriorities returns the proposer priority of each validator of the
+// current round, in the order of the validator set.
+func (cs *State) ProposerPriorities() []ValidatorPriority {
+	vals := cs.roundState.Validators()
+	if vals == nil {
+		return nil
+	}
+	priorities := make([]ValidatorPriority, len(vals.Validators))
+	for i, val := range vals.Validators {
+		priorities[i] = ValidatorPriority{
+			Address:          val.Address,
+			VotingPower:      val.VotingPower,
+			ProposerPriority: val.ProposerPriority,
+		}
+	}
+	return priorities
+}
+
+// recordProposer appends the proposer of the commit round of height to the
+// proposer history. The validators of the round state are those of the commit
+// round, whose proposer priorities were incremented to choose its proposer.
+func (cs *State) recordProposer(height int64) {
+	keep := cs.config.ProposerHistoryHeights
+	if keep <= 0 {
+		cs.proposerHistory = nil
+		return
+	}
+
+	vals := cs.roundState.Validators()
+	proposer := vals.GetProposer()
+	if proposer == nil {
+		return
+	}
+	record := ProposerRecord{
+		Height:        height,
+		Round:         cs.roundState.CommitRound(),
+		Proposer:      proposer.Address,
+		PriorityAfter: proposer.ProposerPriority,
+		TotalPower:    vals.TotalVotingPower(),
+	}
+	record.PriorityBefore = record.PriorityAfter + record.TotalPower
+
+	if len(cs.proposerHistory) >= keep {
+		cs.proposerHistory = cs.proposerHistory[len(cs.proposerHistory)-keep+1:]
+	}
+	cs.proposerHistory = append(cs.proposerHistory, record)
+}
+
+// ProposerHistory returns the proposers of the last n committed heights, at
+// most config.ProposerHistoryHeights, oldest first.
+func (cs *State) ProposerHistory(n int) []ProposerRecord {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	if n > len(cs.proposerHistory) {
+		n = len(cs.proposerHistory)
+	}
+	if n <= 0 {
+		return nil
+	}
+	history := make([]ProposerRecord, n)
+	copy(history, cs.proposerHistory[len(cs.proposerHistory)-n:])
+	return history
+}
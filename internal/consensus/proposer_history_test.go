@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateRecordsProposerRotation(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.ProposerHistoryHeights = 100
+
+	validators := make([]*types.Validator, 4)
+	for i := range validators {
+		validators[i] = types.NewValidator(ed25519.GenPrivKey().PubKey(), int64(i+1))
+	}
+	vals := types.NewValidatorSet(validators)
+	totalPower := vals.TotalVotingPower()
+
+	counts := make(map[string]int)
+	for height := int64(1); height <= 100; height++ {
+		prev := vals
+		vals = prev.CopyIncrementProposerPriority(1)
+		cs.roundState.SetValidators(vals)
+		cs.roundState.SetCommitRound(0)
+		cs.recordProposer(height)
+
+		// the proposer is the validator with the highest priority once the
+		// priorities are incremented by the voting powers
+		var highest int64
+		for _, val := range prev.Validators {
+			if priority := val.ProposerPriority + val.VotingPower; priority > highest {
+				highest = priority
+			}
+		}
+		records := cs.ProposerHistory(1)
+		require.Len(t, records, 1)
+		record := records[0]
+		require.Equal(t, height, record.Height)
+		require.Equal(t, vals.GetProposer().Address, record.Proposer)
+		require.Equal(t, highest, record.PriorityBefore)
+		require.Equal(t, highest-totalPower, record.PriorityAfter)
+		counts[record.Proposer.String()]++
+
+		priorities := cs.ProposerPriorities()
+		require.Len(t, priorities, len(vals.Validators))
+		for i, val := range vals.Validators {
+			require.Equal(t, val.Address, priorities[i].Address)
+			require.Equal(t, val.ProposerPriority, priorities[i].ProposerPriority)
+		}
+	}
+
+	// over 100 heights, each validator proposed in proportion to its power
+	for _, val := range vals.Validators {
+		require.Equal(t, int(10*val.VotingPower), counts[val.Address.String()])
+	}
+
+	history := cs.ProposerHistory(1000)
+	require.Len(t, history, 100)
+	require.EqualValues(t, 1, history[0].Height)
+	require.EqualValues(t, 100, history[99].Height)
+
+	// only the last config.ProposerHistoryHeights heights are kept
+	cs.recordProposer(101)
+	history = cs.ProposerHistory(1000)
+	require.Len(t, history, 100)
+	require.EqualValues(t, 2, history[0].Height)
+	require.EqualValues(t, 101, history[99].Height)
+
+	cs.config.ProposerHistoryHeights = 0
+	cs.recordProposer(102)
+	require.Empty(t, cs.ProposerHistory(1000))
+}
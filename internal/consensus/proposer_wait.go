@@ -0,0 +1,54 @@
+package consensus
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otrace "go.opentelemetry.io/otel/trace"
+)
+
+// proposerWaitWarnThreshold is the proposer wait above which our clock is
+// reported to be behind the clock of the previous proposer.
+const proposerWaitWarnThreshold = 500 * time.Millisecond
+
+// ProposerWait is the time this validator waited before proposing because the
+// previous block time was ahead of its clock.
+type ProposerWait struct {
+	Height   int64
+	Round    int32
+	Duration time.Duration
+}
+
+// recordProposerWait records the wait computed when entering the propose step
+// of height and round. The propose step is entered again once the wait is
+// over, so only the first wait of a round is recorded.
+func (cs *State) recordProposerWait(span otrace.Span, height int64, round int32, wait time.Duration) {
+	span.SetAttributes(attribute.Int64("proposer_wait_ns", wait.Nanoseconds()))
+
+	if last := cs.lastProposerWait; last != nil && last.Height == height && last.Round == round {
+		return
+	}
+	cs.lastProposerWait = &ProposerWait{Height: height, Round: round, Duration: wait}
+	cs.metrics.ProposerWaitSeconds.Observe(wait.Seconds())
+
+	if wait > proposerWaitWarnThreshold {
+		cs.logger.Info(
+			"waiting to propose until the previous block time; the local clock may be behind",
+			"height", height,
+			"round", round,
+			"wait", wait,
+		)
+	}
+}
+
+// GetLastProposerWait returns the latest time this validator waited before
+// proposing, if any.
+func (cs *State) GetLastProposerWait() (ProposerWait, bool) {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	if cs.lastProposerWait == nil {
+		return ProposerWait{}, false
+	}
+	return *cs.lastProposerWait, true
+}
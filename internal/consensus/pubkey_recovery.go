@@ -0,0 +1,122 @@
+package consensus
+
+import (
+	"context"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// pubKeyRecoveryBackoff is the interval the pubkey of the private validator
+// is checked at, and the wait before the first retry to fetch it once it is
+// missing. The wait is doubled after each failed retry, up to
+// pubKeyRecoveryMaxBackoff.
+var (
+	pubKeyRecoveryBackoff    = 500 * time.Millisecond
+	pubKeyRecoveryMaxBackoff = 30 * time.Second
+)
+
+// recoverPrivValidatorPubKey fetches the pubkey of the private validator
+// again while it is missing, e.g. because the remote signer was down when
+// the private validator was set. Without its pubkey, this node neither
+// proposes nor votes, and a chain this node is needed to make progress on
+// would never reach the refresh of the pubkey after a commit. Once fetched,
+// the actions of the current step this node skipped are caught up on.
+func (cs *State) recoverPrivValidatorPubKey(ctx context.Context) {
+	backoff := pubKeyRecoveryBackoff
+	// last time the missing pubkey was accounted for; zero if it is not
+	// missing
+	var missingSince time.Time
+	accountMissing := func(now time.Time) {
+		if !missingSince.IsZero() {
+			cs.metrics.PubKeyMissingSeconds.Add(now.Sub(missingSince).Seconds())
+		}
+		missingSince = now
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		// set or cleared by SetPrivValidator, or refreshed after a commit
+		if privValidator, pubKey := cs.getPrivValidator(); privValidator == nil || pubKey != nil {
+			accountMissing(time.Now())
+			missingSince = time.Time{}
+			backoff = pubKeyRecoveryBackoff
+			continue
+		}
+		accountMissing(time.Now())
+
+		cs.mtx.RLock()
+		height, round, step := cs.roundState.Height(), cs.roundState.Round(), cs.roundState.Step()
+		cs.mtx.RUnlock()
+
+		err := cs.updatePrivValidatorPubKey(ctx)
+		if err != nil || cs.getPrivValidatorPubKey() == nil {
+			if backoff *= 2; backoff > pubKeyRecoveryMaxBackoff {
+				backoff = pubKeyRecoveryMaxBackoff
+			}
+			cs.logger.Error("failed to get private validator pubkey; retrying", "backoff", backoff, "err", err)
+			continue
+		}
+
+		accountMissing(time.Now())
+		missingSince = time.Time{}
+		backoff = pubKeyRecoveryBackoff
+		cs.logger.Info("recovered private validator pubkey", "height", height, "round", round, "step", step)
+		cs.catchUpAfterPubKeyRecovery(ctx, height, round, step)
+	}
+}
+
+// catchUpAfterPubKeyRecovery takes the action this node skipped without its
+// pubkey in the step of height and round it was recovered in: the proposal
+// if it is the proposer, or its vote. The proposal and the votes are sent
+// through the internal queue. Nothing is done if the State moved to another
+// step since, as the pubkey was available when it was entered.
+func (cs *State) catchUpAfterPubKeyRecovery(
+	ctx context.Context,
+	height int64,
+	round int32,
+	step cstypes.RoundStepType,
+) {
+	hold := cs.lockFor(lockHoldPubKeyRecovery)
+	defer hold.unlock()
+
+	if cs.roundState.Height() != height || cs.roundState.Round() != round || cs.roundState.Step() != step {
+		return
+	}
+	pubKey := cs.getPrivValidatorPubKey()
+	if pubKey == nil || !cs.roundState.Validators().HasAddress(pubKey.Address()) {
+		return
+	}
+	addr := pubKey.Address()
+
+	switch step {
+	case cstypes.RoundStepPropose:
+		if cs.isProposer(addr) && cs.roundState.Proposal() == nil {
+			cs.decideProposal(ctx, height, round)
+		}
+
+	case cstypes.RoundStepPrevote, cstypes.RoundStepPrevoteWait:
+		if cs.roundState.Votes().Prevotes(round).GetByAddress(addr) == nil {
+			cs.doPrevote(ctx, height, round)
+		}
+
+	case cstypes.RoundStepPrecommit, cstypes.RoundStepPrecommitWait:
+		if cs.roundState.Votes().Precommits(round).GetByAddress(addr) != nil {
+			return
+		}
+		// the precommit step locks on the block it precommits
+		if cs.roundState.LockedRound() == round && cs.roundState.LockedBlock() != nil {
+			cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "pubkey-recovered-lock",
+				cs.roundState.LockedBlock().Hash(), cs.roundState.LockedBlockParts().Header())
+			return
+		}
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "pubkey-recovered", nil, types.PartSetHeader{})
+	}
+}
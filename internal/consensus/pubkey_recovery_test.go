@@ -0,0 +1,90 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// pubKeyFailingPV fails to return its pubkey while failing is set, as a
+// remote signer that is down.
+type pubKeyFailingPV struct {
+	types.PrivValidator
+	failing atomic.Bool
+}
+
+func (pv *pubKeyFailingPV) GetPubKey(ctx context.Context) (crypto.PubKey, error) {
+	if pv.failing.Load() {
+		return nil, errors.New("signer is down")
+	}
+	return pv.PrivValidator.GetPubKey(ctx)
+}
+
+func TestStatePubKeyRecovery(t *testing.T) {
+	config := configSetup(t)
+	backoff := pubKeyRecoveryBackoff
+	pubKeyRecoveryBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { pubKeyRecoveryBackoff = backoff })
+
+	for _, tc := range []struct {
+		name string
+		// the step the signer recovers in
+		step cstypes.RoundStepType
+	}{
+		{name: "propose step", step: cstypes.RoundStepPropose},
+		{name: "prevote step", step: cstypes.RoundStepPrevote},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// a single validator, which cannot progress without its own votes
+			cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+			if tc.step == cstypes.RoundStepPropose {
+				cs.config.UnsafeProposeTimeoutOverride = time.Minute
+			}
+			missing := generic.NewCounter("")
+			cs.metrics.PubKeyMissingSeconds = missing
+			privValidator, pubKey := cs.getPrivValidator()
+			pv := &pubKeyFailingPV{PrivValidator: privValidator}
+			pv.failing.Store(true)
+			cs.SetPrivValidator(ctx, pv)
+			require.Nil(t, cs.getPrivValidatorPubKey())
+
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+			voteCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+			startTestRound(ctx, cs, height, round)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				cs.recoverPrivValidatorPubKey(ctx)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			// the node skips its actions until the signer recovers
+			require.Eventually(t, func() bool {
+				return cs.GetRoundState().Step == tc.step
+			}, time.Second, time.Millisecond)
+			ensureNoMessageBeforeTimeout(t, voteCh, 50*time.Millisecond, "unexpected vote without a pubkey")
+			pv.failing.Store(false)
+
+			// and votes in the same height once it does
+			ensurePrevote(t, voteCh, height, round)
+			ensurePrecommit(t, voteCh, height, round)
+			require.Equal(t, pubKey, cs.getPrivValidatorPubKey())
+			require.Positive(t, missing.Value())
+		})
+	}
+}
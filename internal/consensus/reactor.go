@@ -218,7 +218,7 @@ func (r *Reactor) OnStart(ctx context.Context) error {
 		if err := r.state.Start(ctx); err != nil {
 			return err
 		}
-	} else if err := r.state.updateStateFromStore(); err != nil {
+	} else if _, err := r.state.updateStateFromStore(); err != nil {
 		return err
 	}
 
@@ -270,7 +270,7 @@ func (r *Reactor) SwitchToConsensus(ctx context.Context, state sm.State, skipWAL
 
 	// NOTE: The line below causes broadcastNewRoundStepRoutine() to broadcast a
 	// NewRoundStepMessage.
-	r.state.updateToState(state)
+	r.state.updateToState(state, stateUpdateSourceSwitch)
 	if err := r.state.Start(ctx); err != nil {
 		panic(fmt.Sprintf(`failed to start consensus state: %v
 
@@ -339,6 +339,32 @@ func (r *Reactor) broadcastNewValidBlockMessage(ctx context.Context, rs *cstypes
 	})
 }
 
+// broadcastProposalPOLMessage lets peers know which prevotes of the POL round
+// of a proposal we have, so that they send us the missing ones.
+func (r *Reactor) broadcastProposalPOLMessage(ctx context.Context, polNeeded *POLNeeded, dataCh *p2p.Channel) error {
+	return dataCh.Send(ctx, p2p.Envelope{
+		Broadcast: true,
+		Message: &tmcons.ProposalPOL{
+			Height:           polNeeded.Height,
+			ProposalPolRound: polNeeded.POLRound,
+			ProposalPol:      *polNeeded.Prevotes.ToProto(),
+		},
+	})
+}
+
+// requestProposalMessage sends our round step to the peers likely to have the
+// block of a proposal we miss, so that they gossip the proposal and its block
+// parts to us.
+func (r *Reactor) requestProposalMessage(ctx context.Context, proposalNeeded *ProposalNeeded, stateCh *p2p.Channel) error {
+	msg := makeRoundStepMessage(r.getRoundState())
+	for _, peerID := range proposalNeeded.Peers {
+		if err := stateCh.Send(ctx, p2p.Envelope{To: peerID, Message: msg}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Reactor) broadcastHasVoteMessage(ctx context.Context, vote *types.Vote, stateCh *p2p.Channel) error {
 	return stateCh.Send(ctx, p2p.Envelope{
 		Broadcast: true,
@@ -399,6 +425,28 @@ func (r *Reactor) subscribeToBroadcastEvents(ctx context.Context, stateCh *p2p.C
 	if err != nil {
 		r.logger.Error("failed to add listener for events", "err", err)
 	}
+
+	err = r.state.evsw.AddListenerForEvent(
+		listenerIDConsensus,
+		types.EventPOLNeededValue,
+		func(data tmevents.EventData) error {
+			return r.broadcastProposalPOLMessage(ctx, data.(*POLNeeded), r.channels.data)
+		},
+	)
+	if err != nil {
+		r.logger.Error("failed to add listener for events", "err", err)
+	}
+
+	err = r.state.evsw.AddListenerForEvent(
+		listenerIDConsensus,
+		types.EventProposalNeededValue,
+		func(data tmevents.EventData) error {
+			return r.requestProposalMessage(ctx, data.(*ProposalNeeded), stateCh)
+		},
+	)
+	if err != nil {
+		r.logger.Error("failed to add listener for events", "err", err)
+	}
 }
 
 func makeRoundStepMessage(rs *cstypes.RoundState) *tmcons.NewRoundStep {
@@ -1145,7 +1193,7 @@ func (r *Reactor) handleDataMessage(ctx context.Context, envelope *p2p.Envelope,
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case r.state.peerMsgQueue <- msgInfo{pMsg, envelope.From, tmtime.Now()}:
+		case r.state.peerMsgQueue <- msgInfo{Msg: pMsg, PeerID: envelope.From, ReceiveTime: tmtime.Now()}:
 		}
 	case *tmcons.ProposalPOL:
 		ps.ApplyProposalPOLMessage(msgI.(*ProposalPOLMessage))
@@ -1155,7 +1203,7 @@ func (r *Reactor) handleDataMessage(ctx context.Context, envelope *p2p.Envelope,
 		ps.SetHasProposalBlockPart(bpMsg.Height, bpMsg.Round, int(bpMsg.Part.Index))
 		r.Metrics.BlockParts.With("peer_id", string(envelope.From)).Add(1)
 		select {
-		case r.state.peerMsgQueue <- msgInfo{bpMsg, envelope.From, tmtime.Now()}:
+		case r.state.peerMsgQueue <- msgInfo{Msg: bpMsg, PeerID: envelope.From, ReceiveTime: tmtime.Now()}:
 			return nil
 		case <-ctx.Done():
 			return ctx.Err()
@@ -1201,7 +1249,7 @@ func (r *Reactor) handleVoteMessage(ctx context.Context, envelope *p2p.Envelope,
 		}
 
 		select {
-		case r.state.peerMsgQueue <- msgInfo{vMsg, envelope.From, tmtime.Now()}:
+		case r.state.peerMsgQueue <- msgInfo{Msg: vMsg, PeerID: envelope.From, ReceiveTime: tmtime.Now()}:
 			return nil
 		case <-ctx.Done():
 			return ctx.Err()
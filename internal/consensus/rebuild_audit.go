@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// auditRebuiltBlock compares, off the critical path, the serialization of the
+// block committed at height, rebuilt from the mempool with the tx keys of its
+// proposal, to the block its stored parts encode. A divergence is reported
+// but does not affect consensus: the height is already committed.
+func (cs *State) auditRebuiltBlock(height int64, block *types.Block) {
+	if cs.config.RebuildAuditPath == "" {
+		return
+	}
+	cs.spawn(func() {
+		if err := cs.checkRebuiltBlock(height, block); err != nil {
+			cs.logger.Error("failed to audit the rebuilt block", "height", height, "err", err)
+		}
+	})
+}
+
+// checkRebuiltBlock dumps the serializations of block and of the block stored
+// at height to the audit directory if they differ, and reports it.
+func (cs *State) checkRebuiltBlock(height int64, block *types.Block) error {
+	pbb, err := block.ToProto()
+	if err != nil {
+		return err
+	}
+	rebuilt, err := proto.Marshal(pbb)
+	if err != nil {
+		return err
+	}
+	committed, err := cs.storedBlockBytes(height)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(rebuilt, committed) {
+		return nil
+	}
+
+	cs.metrics.RebuildDivergences.Add(1)
+	dir := cs.config.RebuildAuditDir()
+	prefix := filepath.Join(dir, fmt.Sprintf("%d-%X", height, block.Hash()))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+"-rebuilt.pb", rebuilt, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+"-committed.pb", committed, 0600); err != nil {
+		return err
+	}
+	cs.logger.Error("the block rebuilt from tx keys diverges from the committed block",
+		"height", height, "hash", block.Hash(), "dir", dir)
+
+	if cs.eventBus == nil {
+		return nil
+	}
+	return cs.publishEvent(types.EventRebuildDivergenceValue, types.EventDataRebuildDivergence{
+		Height:    height,
+		BlockHash: block.Hash(),
+		Dir:       dir,
+	})
+}
+
+// storedBlockBytes returns the serialization of the block stored at height,
+// decompressed from its parts.
+func (cs *State) storedBlockBytes(height int64) ([]byte, error) {
+	meta := cs.blockStore.LoadBlockMeta(height)
+	if meta == nil {
+		return nil, fmt.Errorf("no block stored at height %d", height)
+	}
+	var buf []byte
+	for i := 0; i < int(meta.BlockID.PartSetHeader.Total); i++ {
+		part := cs.blockStore.LoadBlockPart(height, i)
+		if part == nil {
+			return nil, fmt.Errorf("part %d of the block at height %d is missing", i, height)
+		}
+		buf = append(buf, part.Bytes...)
+	}
+	return types.BlockBytesFromPartsBytes(buf, types.MaxBlockSizeBytes)
+}
@@ -0,0 +1,77 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateRebuildAudit(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	dir := t.TempDir()
+	cs.config.RebuildAuditPath = dir
+	divergences := generic.NewCounter("rebuild_divergences")
+	cs.metrics.RebuildDivergences = divergences
+	divergenceCh := subscribe(ctx, t, cs.eventBus, types.EventQueryRebuildDivergence)
+
+	height := cs.roundState.Height()
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	parts, err := block.MakePartSetWithCodec(types.BlockPartSizeBytes, types.FlateBlockPartCodec{})
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	cs.blockStore.SaveBlock(block, parts, &types.Commit{Height: height, BlockID: blockID})
+
+	// the block matches its compressed parts
+	require.NoError(t, cs.checkRebuiltBlock(height, block))
+	require.Zero(t, divergences.Value())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// a rebuilt block with another tx diverges from the committed block
+	rebuilt := cs.state.MakeBlock(height, types.Txs{types.Tx("diverged")}, block.LastCommit, nil, block.ProposerAddress)
+	cs.auditRebuiltBlock(height, rebuilt)
+	select {
+	case msg := <-divergenceCh:
+		require.Equal(t, types.EventDataRebuildDivergence{
+			Height:    height,
+			BlockHash: rebuilt.Hash(),
+			Dir:       dir,
+		}, msg.Data())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the divergence was not published")
+	}
+	require.Equal(t, 1.0, divergences.Value())
+
+	prefix := filepath.Join(dir, fmt.Sprintf("%d-%X", height, rebuilt.Hash()))
+	committed, err := os.ReadFile(prefix + "-committed.pb")
+	require.NoError(t, err)
+	stored, err := cs.storedBlockBytes(height)
+	require.NoError(t, err)
+	require.Equal(t, stored, committed)
+	dumped, err := os.ReadFile(prefix + "-rebuilt.pb")
+	require.NoError(t, err)
+	pbb, err := rebuilt.ToProto()
+	require.NoError(t, err)
+	rebuiltBytes, err := pbb.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, rebuiltBytes, dumped)
+
+	// the audit is disabled
+	cs.config.RebuildAuditPath = ""
+	cs.auditRebuiltBlock(height, rebuilt)
+	ensureNoMessageBeforeTimeout(t, divergenceCh, 100*time.Millisecond, "the audit is disabled")
+}
@@ -13,6 +13,7 @@ import (
 	abciclient "github.com/tendermint/tendermint/abci/client"
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto/merkle"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
 	"github.com/tendermint/tendermint/internal/eventbus"
 	"github.com/tendermint/tendermint/internal/proxy"
 	sm "github.com/tendermint/tendermint/internal/state"
@@ -22,6 +23,11 @@ import (
 
 var crc32c = crc32.MakeTable(crc32.Castagnoli)
 
+// ErrReplayTimeoutMismatch is returned by the replay of a timeout of the WAL
+// whose duration differs from the one of the current configuration, when
+// config.StrictReplayTimeouts is set.
+var ErrReplayTimeoutMismatch = errors.New("replayed timeout differs from the configured timeout")
+
 // Functionality to replay blocks and messages on recovery from a crash.
 // There are two general failure scenarios:
 //
@@ -44,6 +50,11 @@ func (cs *State) readReplayMessage(ctx context.Context, msg *TimedWALMessage, ne
 	if _, ok := msg.Msg.(EndHeightMessage); ok {
 		return nil
 	}
+	// The restart acknowledgments are only kept for audit.
+	if m, ok := msg.Msg.(RestartAcknowledgmentMessage); ok {
+		cs.logger.Info("Replay: Restart acknowledgment", "height", m.Height, "signature_height", m.SignatureHeight)
+		return nil
+	}
 
 	// for logging
 	switch m := msg.Msg.(type) {
@@ -85,6 +96,9 @@ func (cs *State) readReplayMessage(ctx context.Context, msg *TimedWALMessage, ne
 		cs.handleMsg(ctx, m, false)
 	case timeoutInfo:
 		cs.logger.Info("Replay: Timeout", "height", m.Height, "round", m.Round, "step", m.Step, "dur", m.Duration)
+		if err := cs.checkReplayedTimeout(m); err != nil {
+			return err
+		}
 		roundState := cs.roundState.CopyInternal()
 		cs.handleTimeout(ctx, m, *roundState)
 	default:
@@ -93,6 +107,50 @@ func (cs *State) readReplayMessage(ctx context.Context, msg *TimedWALMessage, ne
 	return nil
 }
 
+// configuredTimeout returns the duration the current configuration schedules
+// the timeout of step in round for. The timeouts whose duration depends on
+// the time they are scheduled at are not returned.
+func (cs *State) configuredTimeout(round int32, step cstypes.RoundStepType) (time.Duration, bool) {
+	switch step {
+	case cstypes.RoundStepPropose:
+		return cs.proposeTimeout(round), true
+	case cstypes.RoundStepPrevoteWait, cstypes.RoundStepPrecommitWait:
+		return cs.voteTimeout(round), true
+	default:
+		return 0, false
+	}
+}
+
+// checkReplayedTimeout compares the duration of a timeout of the WAL with the
+// one the current configuration computes. If they differ, the timeout
+// configuration changed since the WAL was written and the replay may not
+// follow the transitions of the original run: the replay is aborted if
+// config.StrictReplayTimeouts is set, a warning is logged otherwise.
+func (cs *State) checkReplayedTimeout(ti timeoutInfo) error {
+	configured, ok := cs.configuredTimeout(ti.Round, ti.Step)
+	if !ok || configured == ti.Duration {
+		return nil
+	}
+	// the prevote wait of a delayed precommit is shorter
+	if ti.Step == cstypes.RoundStepPrevoteWait && ti.Duration == cs.precommitDelayWait(ti.Round) {
+		return nil
+	}
+	// the propose timeout is extended by the propose grace
+	if ti.Step == cstypes.RoundStepPropose && ti.Duration == cs.proposeGraceDuration(ti.Round) {
+		return nil
+	}
+	if cs.config.StrictReplayTimeouts {
+		return fmt.Errorf("%w: the %v timeout of height %d round %d lasted %v, the current configuration computes %v; "+
+			"restore the timeout configuration the WAL was written with, or unset strict-replay-timeouts",
+			ErrReplayTimeoutMismatch, ti.Step, ti.Height, ti.Round, ti.Duration, configured)
+	}
+	cs.logger.Error("Replay: the timeout configuration changed since the WAL was written; "+
+		"the replay may not follow the transitions of the original run",
+		"height", ti.Height, "round", ti.Round, "step", ti.Step,
+		"wal_duration", ti.Duration, "configured_duration", configured)
+	return nil
+}
+
 // Replay only those messages since the last block.  `timeoutRoutine` should
 // run concurrently to read off tickChan.
 func (cs *State) catchupReplay(ctx context.Context, csHeight int64) error {
@@ -159,17 +217,46 @@ LOOP:
 			return err
 		}
 
+		// Timeouts and messages past the next height cannot affect the
+		// replay and are skipped instead of being handed to the state.
+		if height, ok := walMessageHeight(msg.Msg); ok && height > cs.roundState.Height()+1 {
+			cs.logger.Error("Replay: skipping message beyond the next height",
+				"msg_height", height, "height", cs.roundState.Height())
+			continue
+		}
+
 		// NOTE: since the priv key is set when the msgs are received
 		// it will attempt to eg double sign but we can just ignore it
 		// since the votes will be replayed and we'll get to the next step
+		cs.seedSignHRS(msg.Msg)
 		if err := cs.readReplayMessage(ctx, msg, nil); err != nil {
 			return err
 		}
+		cs.markMsgReplayed()
 	}
 	cs.logger.Info("Replay: Done")
 	return nil
 }
 
+// walMessageHeight returns the height of a timeout or consensus message of the
+// WAL.
+func walMessageHeight(msg WALMessage) (int64, bool) {
+	switch m := msg.(type) {
+	case timeoutInfo:
+		return m.Height, true
+	case msgInfo:
+		switch msg := m.Msg.(type) {
+		case *ProposalMessage:
+			return msg.Proposal.Height, true
+		case *BlockPartMessage:
+			return msg.Height, true
+		case *VoteMessage:
+			return msg.Vote.Height, true
+		}
+	}
+	return 0, false
+}
+
 //--------------------------------------------------------------------------------
 
 // Parses marker lines of the form:
@@ -592,3 +679,36 @@ Did you reset Tendermint without resetting your application's data?`,
 
 	return nil
 }
+
+// belowReplayFloor returns whether mi, received from a peer, is for a height
+// below the one the WAL replay ended at. Such messages are stale right after
+// the startup and are dropped before being written to the WAL. The votes of
+// the height below are kept, as its late precommits are added to the last
+// commit.
+func (cs *State) belowReplayFloor(mi msgInfo) bool {
+	if cs.replayFloorHeight == 0 {
+		return false
+	}
+	var height int64
+	floor := cs.replayFloorHeight
+	switch msg := mi.Msg.(type) {
+	case *ProposalMessage:
+		height = msg.Proposal.Height
+	case *BlockPartMessage:
+		height = msg.Height
+	case *ProposalAndBlockPartsMessage:
+		height = msg.Proposal.Height
+	case *VoteMessage:
+		height = msg.Vote.Height
+		floor--
+	default:
+		return false
+	}
+	if height >= floor {
+		return false
+	}
+	cs.metrics.PeerMsgsBelowReplayFloor.Add(1)
+	cs.logger.Debug("dropping peer message below the replay floor",
+		"height", height, "floor", floor, "peer", mi.PeerID)
+	return true
+}
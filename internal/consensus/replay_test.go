@@ -8,11 +8,14 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/fortytw2/leaktest"
+	"github.com/go-kit/kit/metrics/generic"
 	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +27,7 @@ import (
 	"github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/encoding"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
 	"github.com/tendermint/tendermint/internal/eventbus"
 	"github.com/tendermint/tendermint/internal/mempool"
 	"github.com/tendermint/tendermint/internal/proxy"
@@ -292,6 +296,409 @@ func (w *crashingWAL) Start(ctx context.Context) error { return w.next.Start(ctx
 func (w *crashingWAL) Stop()                           { w.next.Stop() }
 func (w *crashingWAL) Wait()                           { w.next.Wait() }
 
+// TestWALCrashAfterBlockSave simulates a crash after the block was saved but
+// before finalizeCommit wrote the EndHeightMessage for its height, and checks
+// that the WAL never implies a block was saved before it actually was.
+func TestWALCrashAfterBlockSave(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consensusReplayConfig, err := ResetConfig(t.TempDir(), "crash_after_block_save")
+	require.NoError(t, err)
+
+	blockDB := dbm.NewMemDB()
+	blockStore := store.NewBlockStore(blockDB)
+	state, err := sm.MakeGenesisStateFromFile(consensusReplayConfig.GenesisFile())
+	require.NoError(t, err)
+	privValidator := loadPrivValidator(t, consensusReplayConfig)
+	cs := newStateWithConfigAndBlockStore(
+		ctx,
+		t,
+		log.NewNopLogger(),
+		consensusReplayConfig,
+		state,
+		privValidator,
+		kvstore.NewApplication(),
+		blockStore,
+	)
+
+	csWal, err := cs.OpenWAL(ctx, cs.config.WalFile())
+	require.NoError(t, err)
+	cs.wal = csWal
+
+	const crashHeight = 1
+	crashingStore := &crashingBlockStore{
+		BlockStore:  blockStore,
+		wal:         csWal,
+		crashHeight: crashHeight,
+		crashCh:     make(chan bool, 1),
+	}
+	cs.blockStore = crashingStore
+
+	csCtx, csCancel := context.WithCancel(ctx)
+	defer csCancel()
+	require.NoError(t, cs.Start(csCtx))
+
+	select {
+	case endHeightFound := <-crashingStore.crashCh:
+		require.False(t, endHeightFound, "EndHeightMessage written before the block was saved")
+	case <-time.After(10 * time.Second):
+		t.Fatal("block store did not crash for 10 seconds")
+	}
+	csCancel()
+	cs.Wait()
+
+	// the block was saved, but the WAL does not mark its height as ended
+	require.EqualValues(t, crashHeight, blockStore.Height())
+	rd, found, err := csWal.SearchForEndHeight(crashHeight, &WALSearchOptions{IgnoreDataCorruptionErrors: true})
+	require.NoError(t, err)
+	if found {
+		rd.Close()
+	}
+	require.False(t, found, "EndHeightMessage written although finalizeCommit crashed")
+
+	// make sure we can make blocks after a crash
+	startNewStateAndWaitForBlock(ctx, t, consensusReplayConfig, crashHeight, blockDB, sm.NewStore(dbm.NewMemDB()))
+}
+
+// crashingBlockStore simulates a crash right after the block at crashHeight was
+// saved. It reports on crashCh whether the EndHeightMessage for that height was
+// already in the WAL at the time of the crash.
+type crashingBlockStore struct {
+	*store.BlockStore
+	wal         WAL
+	crashHeight int64
+	crashCh     chan bool
+}
+
+func (bs *crashingBlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	bs.BlockStore.SaveBlock(block, blockParts, seenCommit)
+	bs.maybeCrash(block.Height)
+}
+
+func (bs *crashingBlockStore) SaveBlockWithExtendedCommit(
+	block *types.Block,
+	blockParts *types.PartSet,
+	seenCommit *types.ExtendedCommit,
+) {
+	bs.BlockStore.SaveBlockWithExtendedCommit(block, blockParts, seenCommit)
+	bs.maybeCrash(block.Height)
+}
+
+func (bs *crashingBlockStore) maybeCrash(height int64) {
+	if height != bs.crashHeight {
+		return
+	}
+	rd, found, err := bs.wal.SearchForEndHeight(height, &WALSearchOptions{IgnoreDataCorruptionErrors: true})
+	if err == nil && found {
+		rd.Close()
+	}
+	bs.crashCh <- found
+	runtime.Goexit()
+}
+
+// TestStopWaitsForBlockSave stops the State while finalizeCommit is saving a
+// block, and checks the save completed once the State stopped, so that the
+// block store is not closed under it.
+func TestStopWaitsForBlockSave(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consensusReplayConfig, err := ResetConfig(t.TempDir(), "stop_during_block_save")
+	require.NoError(t, err)
+
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	state, err := sm.MakeGenesisStateFromFile(consensusReplayConfig.GenesisFile())
+	require.NoError(t, err)
+	privValidator := loadPrivValidator(t, consensusReplayConfig)
+	cs := newStateWithConfigAndBlockStore(
+		ctx,
+		t,
+		log.NewNopLogger(),
+		consensusReplayConfig,
+		state,
+		privValidator,
+		kvstore.NewApplication(),
+		blockStore,
+	)
+	slowStore := &slowSavingBlockStore{
+		BlockStore: blockStore,
+		delay:      200 * time.Millisecond,
+		savingCh:   make(chan struct{}, 1),
+	}
+	cs.blockStore = slowStore
+
+	csCtx, csCancel := context.WithCancel(ctx)
+	defer csCancel()
+	require.NoError(t, cs.Start(csCtx))
+
+	select {
+	case <-slowStore.savingCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("block was not saved for 10 seconds")
+	}
+	csCancel()
+	cs.Wait()
+
+	require.EqualValues(t, 1, blockStore.Height())
+}
+
+// slowSavingBlockStore takes delay to save each block, and signals on
+// savingCh as a save starts.
+type slowSavingBlockStore struct {
+	*store.BlockStore
+	delay    time.Duration
+	savingCh chan struct{}
+}
+
+func (bs *slowSavingBlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	bs.saving()
+	bs.BlockStore.SaveBlock(block, blockParts, seenCommit)
+}
+
+func (bs *slowSavingBlockStore) SaveBlockWithExtendedCommit(
+	block *types.Block,
+	blockParts *types.PartSet,
+	seenCommit *types.ExtendedCommit,
+) {
+	bs.saving()
+	bs.BlockStore.SaveBlockWithExtendedCommit(block, blockParts, seenCommit)
+}
+
+func (bs *slowSavingBlockStore) saving() {
+	select {
+	case bs.savingCh <- struct{}{}:
+	default:
+	}
+	time.Sleep(bs.delay)
+}
+
+// TestQueriesDuringCatchupReplay queries a State concurrently with a long
+// catchup replay, and checks the queries are served from the block store and
+// the state before the replay.
+func TestQueriesDuringCatchupReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := ResetConfig(t.TempDir(), "queries_during_replay")
+	require.NoError(t, err)
+	logger := log.NewNopLogger()
+
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	state, err := sm.MakeGenesisStateFromFile(cfg.GenesisFile())
+	require.NoError(t, err)
+	privValidator := loadPrivValidator(t, cfg)
+
+	// commit a few blocks to query
+	cs1 := newStateWithConfigAndBlockStore(ctx, t, logger, cfg, state, privValidator, kvstore.NewApplication(), blockStore)
+	cs1.doWALCatchup = false
+	cs1Ctx, cs1Cancel := context.WithCancel(ctx)
+	require.NoError(t, cs1.Start(cs1Ctx))
+	require.Eventually(t, func() bool { return cs1.GetLastHeight() >= 2 }, 10*time.Second, 10*time.Millisecond)
+	cs1Cancel()
+	cs1.Wait()
+	require.Equal(t, StartupPhaseRunning, cs1.StartupPhase())
+
+	// keep the restarted State from starting a new height after the replay
+	cs2Cfg := *cfg.Consensus
+	cs2Cfg.UnsafeCommitTimeoutOverride = time.Hour
+	bypassCommitTimeout := false
+	cs2Cfg.UnsafeBypassCommitTimeoutOverride = &bypassCommitTimeout
+	cs2, err := NewState(logger, &cs2Cfg, cs1.stateStore, cs1.blockExec, blockStore,
+		cs1.txNotifier, cs1.evpool, cs1.eventBus, nil)
+	require.NoError(t, err)
+	cs2.SetPrivValidator(ctx, privValidator)
+	require.Equal(t, StartupPhaseBootstrapping, cs2.StartupPhase())
+
+	lastHeight := cs2.GetLastHeight()
+	require.GreaterOrEqual(t, blockStore.Height(), lastHeight)
+	wantHeight, wantVals := cs2.GetValidators()
+
+	replayWAL := newPipeWAL(lastHeight)
+	cs2.wal = replayWAL
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- cs2.Start(ctx) }()
+
+	// timeouts of the previous height are ignored by the replay
+	staleTimeout := func() *TimedWALMessage {
+		return &TimedWALMessage{Time: time.Now(), Msg: timeoutInfo{
+			Duration: time.Second, Height: lastHeight, Round: 0, Step: cstypes.RoundStepPropose,
+		}}
+	}
+	enc := NewWALEncoder(replayWAL.w)
+	const firstMsgs = 10
+	for i := 0; i < firstMsgs; i++ {
+		require.NoError(t, enc.Encode(staleTimeout()))
+	}
+	require.Eventually(t, func() bool {
+		return cs2.Status().ReplayedMsgs == firstMsgs
+	}, 5*time.Second, 10*time.Millisecond)
+	status := cs2.Status()
+	require.Equal(t, StartupPhaseReplayingWAL, status.Phase)
+	require.Equal(t, lastHeight+1, status.Height)
+	require.Equal(t, firstMsgs, status.ReplayedMsgs)
+
+	checkQueries := func() {
+		require.Equal(t, lastHeight, cs2.GetLastHeight())
+		require.Equal(t, lastHeight+1, cs2.GetRoundState().Height)
+		height, vals := cs2.GetValidators()
+		require.Equal(t, wantHeight, height)
+		require.Equal(t, wantVals, vals)
+		commit := cs2.LoadCommit(lastHeight)
+		require.NotNil(t, commit)
+		require.Equal(t, lastHeight, commit.Height)
+	}
+
+	// query concurrently with the rest of the replay
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if cs2.StartupPhase() != StartupPhaseReplayingWAL {
+					return
+				}
+				checkQueries()
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, enc.Encode(staleTimeout()))
+	}
+	close(stop)
+	wg.Wait()
+	require.Equal(t, StartupPhaseReplayingWAL, cs2.StartupPhase())
+
+	// end the replay
+	require.NoError(t, replayWAL.w.Close())
+	select {
+	case err := <-startErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("state did not start after the replay")
+	}
+	require.Equal(t, StartupPhaseRunning, cs2.StartupPhase())
+	require.Equal(t, firstMsgs+1000, cs2.Status().ReplayedMsgs)
+	checkQueries()
+
+	cancel()
+	cs2.Wait()
+}
+
+// TestCatchupReplayRepairsInvalidTimeout checks a decodable timeout with an
+// invalid step in the WAL is handled as a corruption and repaired, instead of
+// crashing the replay.
+func TestCatchupReplayRepairsInvalidTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{validators: 1})
+	height := cs.roundState.Height()
+	walFile := filepath.Join(t.TempDir(), "wal")
+	cs.config.SetWalFile(walFile)
+
+	var data bytes.Buffer
+	enc := NewWALEncoder(&data)
+	require.NoError(t, enc.Encode(&TimedWALMessage{Time: time.Now(), Msg: EndHeightMessage{height - 1}}))
+	require.NoError(t, enc.Encode(&TimedWALMessage{Time: time.Now(), Msg: timeoutInfo{
+		Duration: time.Second, Height: height, Round: 0, Step: cstypes.RoundStepNewHeight,
+	}}))
+	data.Write(encodeRawTimeoutInfo(t, height, 0, 0x09))
+	require.NoError(t, os.WriteFile(walFile, data.Bytes(), 0600))
+
+	require.NoError(t, cs.Start(ctx))
+	require.FileExists(t, walFile+".CORRUPTED")
+	require.Equal(t, StartupPhaseRunning, cs.StartupPhase())
+
+	// the repaired WAL only keeps the records before the invalid timeout
+	require.Eventually(t, func() bool { return cs.blockStore.Height() >= height }, 10*time.Second, 10*time.Millisecond)
+	cancel()
+	cs.Wait()
+	cs.wal.Wait()
+	require.NoError(t, IterateWAL(walFile, func(msg TimedWALMessage) (bool, error) {
+		if ti, ok := msg.TimeoutInfo(); ok {
+			require.NoError(t, ti.ValidateBasic())
+		}
+		return false, nil
+	}))
+}
+
+// TestCatchupReplayTimeoutMismatch checks the replay of a WAL written with
+// another timeout configuration logs a warning, or aborts the start with
+// StrictReplayTimeouts.
+func TestCatchupReplayTimeoutMismatch(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%t", strict), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, _ := makeState(ctx, t, makeStateArgs{validators: 1})
+			height := cs.roundState.Height()
+			walFile := filepath.Join(t.TempDir(), "wal")
+			cs.config.SetWalFile(walFile)
+			cs.config.StrictReplayTimeouts = strict
+
+			// the WAL was written with a propose timeout override, which was
+			// removed before the restart
+			cs.config.UnsafeProposeTimeoutOverride = cs.proposeTimeout(0) + time.Second
+			written := cs.proposeTimeout(0)
+			cs.config.UnsafeProposeTimeoutOverride = 0
+
+			var data bytes.Buffer
+			enc := NewWALEncoder(&data)
+			require.NoError(t, enc.Encode(&TimedWALMessage{Time: time.Now(), Msg: EndHeightMessage{height - 1}}))
+			require.NoError(t, enc.Encode(&TimedWALMessage{Time: time.Now(), Msg: timeoutInfo{
+				Duration: written, Height: height, Round: 0, Step: cstypes.RoundStepPropose,
+			}}))
+			require.NoError(t, os.WriteFile(walFile, data.Bytes(), 0600))
+
+			// the timeouts whose duration depends on the time are not checked
+			require.NoError(t, cs.checkReplayedTimeout(timeoutInfo{
+				Duration: time.Hour, Height: height, Round: 0, Step: cstypes.RoundStepNewHeight,
+			}))
+
+			err := cs.Start(ctx)
+			if strict {
+				require.ErrorIs(t, err, ErrReplayTimeoutMismatch)
+				return
+			}
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return cs.blockStore.Height() >= height }, 10*time.Second, 10*time.Millisecond)
+			cancel()
+			cs.Wait()
+		})
+	}
+}
+
+// pipeWAL is a WAL whose messages after the end of endHeight are read from a
+// pipe, so a test can feed the catchup replay message by message.
+type pipeWAL struct {
+	nilWAL
+	endHeight int64
+	r         *io.PipeReader
+	w         *io.PipeWriter
+}
+
+func newPipeWAL(endHeight int64) *pipeWAL {
+	r, w := io.Pipe()
+	return &pipeWAL{endHeight: endHeight, r: r, w: w}
+}
+
+func (w *pipeWAL) SearchForEndHeight(height int64, options *WALSearchOptions) (io.ReadCloser, bool, error) {
+	if height != w.endHeight {
+		return nil, false, nil
+	}
+	return w.r, true, nil
+}
+
 // ------------------------------------------------------------------------------------------
 type simulatorTestSuite struct {
 	GenesisState sm.State
@@ -711,23 +1118,26 @@ func testHandshakeReplay(
 		testConfig, err := ResetConfig(t.TempDir(), fmt.Sprintf("%s_%v_s", t.Name(), mode))
 		require.NoError(t, err)
 		defer func() { _ = os.RemoveAll(testConfig.RootDir) }()
-		walBody, err := WALWithNBlocks(ctx, t, logger, numBlocks)
+		script := WALFixtureScript{Seed: int64(mode), Validators: 1}
+		for i := 0; i < numBlocks; i++ {
+			script.Heights = append(script.Heights, WALFixtureCommitted())
+		}
+		walBody, err := GenerateWALFixture(ctx, script)
 		require.NoError(t, err)
+		genDoc, err := script.GenesisDoc()
+		require.NoError(t, err)
+		require.NoError(t, genDoc.SaveAs(testConfig.GenesisFile()))
+		cfg = testConfig
 		walFile := tempWALWithData(t, walBody)
 		cfg.Consensus.SetWalFile(walFile)
 
-		privVal, err := privval.LoadFilePV(cfg.PrivValidator.KeyFile(), cfg.PrivValidator.StateFile())
-		require.NoError(t, err)
-
 		wal, err := NewWAL(ctx, logger, walFile)
 		require.NoError(t, err)
 		err = wal.Start(ctx)
 		require.NoError(t, err)
 		t.Cleanup(func() { cancel(); wal.Wait() })
 		chain, extCommits = makeBlockchainFromWAL(t, wal)
-		pubKey, err := privVal.GetPubKey(ctx)
-		require.NoError(t, err)
-		stateDB, genesisState, store = stateAndStore(t, cfg, pubKey, kvstore.ProtocolVersion)
+		stateDB, genesisState, store = stateAndStore(t, cfg, genDoc.Validators[0].PubKey, kvstore.ProtocolVersion)
 
 	}
 	stateStore := sm.NewStore(stateDB)
@@ -1112,22 +1522,6 @@ func makeBlockchainFromWAL(t *testing.T, wal WAL) ([]*types.Block, []*types.Exte
 			}
 		}
 	}
-	// grab the last block too
-	bz, err := io.ReadAll(thisBlockParts.GetReader())
-	require.NoError(t, err)
-
-	var pbb = new(tmproto.Block)
-	require.NoError(t, proto.Unmarshal(bz, pbb))
-
-	block, err := types.BlockFromProto(pbb)
-	require.NoError(t, err)
-
-	require.Equal(t, block.Height, height+1, "read bad block from wal. got height %d, expected %d", block.Height, height+1)
-	commitHeight := thisBlockExtCommit.Height
-	require.Equal(t, commitHeight, height+1, "commit does not match. got height %d, expected %d", commitHeight, height+1)
-
-	blocks = append(blocks, block)
-	extCommits = append(extCommits, thisBlockExtCommit)
 	return blocks, extCommits
 }
 
@@ -1300,3 +1694,135 @@ type initChainApp struct {
 func (ica *initChainApp) InitChain(_ context.Context, req *abci.RequestInitChain) (*abci.ResponseInitChain, error) {
 	return &abci.ResponseInitChain{Validators: ica.vals}, nil
 }
+
+// recordingWAL is a WAL recording the messages written to it.
+type recordingWAL struct {
+	nilWAL
+	mtx  sync.Mutex
+	msgs []WALMessage
+}
+
+func (w *recordingWAL) Write(m WALMessage) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.msgs = append(w.msgs, m)
+	return nil
+}
+
+func (w *recordingWAL) WriteSync(m WALMessage) error { return w.Write(m) }
+
+func (w *recordingWAL) written() []WALMessage {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]WALMessage(nil), w.msgs...)
+}
+
+func TestReplayFloorDropsStalePeerMsgs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{validators: 1})
+	height := cs.roundState.Height()
+	wal := &recordingWAL{}
+	cs.wal = wal
+	dropped := generic.NewCounter("peer_msgs_below_replay_floor")
+	cs.metrics.PeerMsgsBelowReplayFloor = dropped
+
+	// the floor is the height the WAL replay ended at
+	require.NoError(t, cs.Start(ctx))
+	require.Equal(t, height, cs.replayFloorHeight)
+	cancel()
+	cs.Wait()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	cs, vss := makeState(ctx, t, makeStateArgs{validators: 1})
+	cs.wal = wal
+	cs.metrics.PeerMsgsBelowReplayFloor = dropped
+	floor := height + 2
+	cs.replayFloorHeight = floor
+	cs.startRoutines(ctx, 0)
+
+	vote := func(msgType tmproto.SignedMsgType, height int64) *VoteMessage {
+		vss[0].Height = height
+		return &VoteMessage{signVote(ctx, t, vss[0], msgType, cs.state.ChainID, types.BlockID{})}
+	}
+	stale := []Message{
+		vote(tmproto.PrevoteType, floor-2),
+		&BlockPartMessage{Height: floor - 1, Part: &types.Part{}},
+		&ProposalMessage{&types.Proposal{Height: floor - 1}},
+	}
+	// the late precommits of the height below the floor are kept for the
+	// last commit
+	kept := []Message{vote(tmproto.PrecommitType, floor-1), vote(tmproto.PrevoteType, floor)}
+	for _, msg := range append(stale, kept...) {
+		cs.peerMsgQueue <- msgInfo{Msg: msg, PeerID: "peer", ReceiveTime: time.Now()}
+	}
+
+	// the stale messages are not written to the WAL
+	require.Eventually(t, func() bool {
+		written := 0
+		for _, m := range wal.written() {
+			if mi, ok := m.(msgInfo); ok && (mi.Msg == kept[0] || mi.Msg == kept[1]) {
+				written++
+			}
+		}
+		return written == len(kept)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, float64(len(stale)), dropped.Value())
+	for _, m := range wal.written() {
+		if mi, ok := m.(msgInfo); ok {
+			require.NotContains(t, stale, mi.Msg)
+		}
+	}
+}
+
+func TestReplayKeepsProposalReceiveTime(t *testing.T) {
+	cfg := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: cfg, validators: 2})
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+	height := cs.roundState.Height()
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	pubKey, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block := cs.state.MakeBlock(height, nil, created.LastCommit, nil, pubKey.Address())
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, 1, -1, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, pubKey.Address())
+	p := proposal.ToProto()
+	require.NoError(t, vss[1].SignProposal(ctx, cfg.ChainID(), p))
+	proposal.Signature = p.Signature
+
+	// the live run moves to round 1, whose proposer is vss[1], and receives
+	// the proposal just before it is late
+	ti := timeoutInfo{Duration: cs.voteTimeout(0), Height: height, Round: 0, Step: cstypes.RoundStepPrecommitWait}
+	sp := cs.state.ConsensusParams.Synchrony.SynchronyParamsOrDefaults()
+	mi := msgInfo{
+		Msg:         &ProposalMessage{proposal},
+		PeerID:      "peer",
+		ReceiveTime: proposal.Timestamp.Add(sp.MessageDelay + sp.Precision - time.Millisecond),
+	}
+	require.NoError(t, wal.Write(ti))
+	cs.handleTimeout(ctx, ti, *cs.roundState.CopyInternal())
+	require.NoError(t, wal.Write(mi))
+	cs.handleMsg(ctx, mi, false)
+	require.Equal(t, mi.ReceiveTime, cs.roundState.ProposalReceiveTime())
+	require.True(t, cs.proposalIsTimely())
+
+	// a State on the same stores replays the WAL with the same receive time
+	replayed, err := NewState(log.NewNopLogger(), cs.config, cs.stateStore, cs.blockExec, cs.blockStore,
+		cs.txNotifier, cs.evpool, cs.eventBus, nil, WithWAL(wal))
+	require.NoError(t, err)
+	replayed.wal = wal
+	require.NoError(t, replayed.catchupReplay(ctx, height))
+	require.NotNil(t, replayed.roundState.Proposal())
+	require.Equal(t, mi.ReceiveTime, replayed.roundState.ProposalReceiveTime())
+	require.Equal(t, cs.proposalIsTimely(), replayed.proposalIsTimely())
+}
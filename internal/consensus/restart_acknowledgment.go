@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/tendermint/tendermint/internal/jsontypes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// The outcomes of a signature of this node's key found in a past block.
+const (
+	pastBlockSignatureRefused      = "refused"
+	pastBlockSignatureAcknowledged = "acknowledged"
+)
+
+// ErrPastBlockSignature is returned on start if a signature of this node's
+// key is found in one of the last config.DoubleSignCheckHeight blocks, and
+// the operator did not acknowledge it with config.AcknowledgeRestartAtHeight.
+// It wraps ErrSignatureFoundInPastBlocks.
+type ErrPastBlockSignature struct {
+	// Height is the height of the most recent block signed.
+	Height int64
+	// Index is the index of the signature in the commit of the block.
+	Index     int
+	Signature types.CommitSig
+	// Acknowledged is the height acknowledged by the operator, if any.
+	Acknowledged int64
+}
+
+func (e *ErrPastBlockSignature) Error() string {
+	msg := fmt.Sprintf("%v at height %d (index %d, timestamp %v)",
+		ErrSignatureFoundInPastBlocks, e.Height, e.Index, e.Signature.Timestamp)
+	if e.Acknowledged > 0 {
+		return fmt.Sprintf("%s; the acknowledged height %d does not match", msg, e.Acknowledged)
+	}
+	return msg
+}
+
+func (e *ErrPastBlockSignature) Unwrap() error { return ErrSignatureFoundInPastBlocks }
+
+// RestartAcknowledgmentMessage records in the WAL that the node started at
+// Height although its key signed the block at SignatureHeight, as
+// acknowledged by the operator.
+type RestartAcknowledgmentMessage struct {
+	Height          int64 `json:"height,string"`
+	SignatureHeight int64 `json:"signature_height,string"`
+}
+
+func (RestartAcknowledgmentMessage) TypeTag() string {
+	return "tendermint/wal/RestartAcknowledgmentMessage"
+}
+
+func init() {
+	jsontypes.MustRegister(RestartAcknowledgmentMessage{})
+}
+
+// checkPastBlockSignature handles err, a signature of this node's key found
+// at startup: the start is refused unless the operator acknowledged the
+// height of the signature, in which case the acknowledgment is written to the
+// WAL.
+func (cs *State) checkPastBlockSignature(height int64, err *ErrPastBlockSignature) error {
+	logger := cs.logger.With("height", height, "signature_height", err.Height, "signature_index", err.Index,
+		"signature", err.Signature)
+	if cs.config.AcknowledgeRestartAtHeight != err.Height {
+		err.Acknowledged = cs.config.AcknowledgeRestartAtHeight
+		cs.metrics.PastBlockSignatures.With("outcome", pastBlockSignatureRefused).Add(1)
+		logger.Error("found signature from the same key in a past block; refusing to start. "+
+			"If this node was restored with the same key, restart with "+
+			fmt.Sprintf("acknowledge-restart-at-height = %d", err.Height),
+			"acknowledged_height", cs.config.AcknowledgeRestartAtHeight)
+		return err
+	}
+
+	ack := RestartAcknowledgmentMessage{Height: height, SignatureHeight: err.Height}
+	if werr := cs.wal.WriteSync(ack); werr != nil {
+		return fmt.Errorf("failed to write the restart acknowledgment to the WAL: %w", werr)
+	}
+	cs.metrics.PastBlockSignatures.With("outcome", pastBlockSignatureAcknowledged).Add(1)
+	logger.Info("found signature from the same key in a past block; starting as acknowledged")
+	return nil
+}
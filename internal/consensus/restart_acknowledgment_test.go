@@ -0,0 +1,118 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStatePastBlockSignature(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.DoubleSignCheckHeight = 10
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+	cs.wal = wal
+	outcomes := newLabeledCounter()
+	cs.metrics.PastBlockSignatures = outcomes
+
+	// the node signed the block of the current height before a restore
+	height := cs.roundState.Height()
+	pubKey, err := cs.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	sig := types.CommitSig{
+		BlockIDFlag:      types.BlockIDFlagCommit,
+		ValidatorAddress: pubKey.Address(),
+		Timestamp:        tmtime.Now(),
+		Signature:        []byte("signature"),
+	}
+	cs.blockStore.SaveBlock(block, parts, &types.Commit{
+		Height:     height,
+		BlockID:    blockID,
+		Signatures: []types.CommitSig{types.NewCommitSigAbsent(), sig},
+	})
+
+	// the start is refused, with the signature found
+	err = cs.checkDoubleSigningRisk(height + 1)
+	require.True(t, errors.Is(err, ErrSignatureFoundInPastBlocks))
+	var pastErr *ErrPastBlockSignature
+	require.True(t, errors.As(err, &pastErr))
+	require.Equal(t, height, pastErr.Height)
+	require.Equal(t, 1, pastErr.Index)
+	require.Equal(t, sig, pastErr.Signature)
+	require.Equal(t, 1.0, outcomes.values["outcome,refused"])
+
+	// the acknowledgment of another height does not allow the start
+	cs.config.AcknowledgeRestartAtHeight = height + 1
+	err = cs.checkDoubleSigningRisk(height + 1)
+	require.True(t, errors.As(err, &pastErr))
+	require.Equal(t, height+1, pastErr.Acknowledged)
+	require.Contains(t, err.Error(), "does not match")
+	require.Equal(t, 2.0, outcomes.values["outcome,refused"])
+
+	// the acknowledgment of the height allows it, and is written to the WAL
+	cs.config.AcknowledgeRestartAtHeight = height
+	require.NoError(t, cs.checkDoubleSigningRisk(height+1))
+	require.Equal(t, 1.0, outcomes.values["outcome,acknowledged"])
+
+	r, found, err := wal.SearchForEndHeight(0, nil)
+	require.NoError(t, err)
+	require.True(t, found)
+	dec := NewWALDecoder(r)
+	var acks []TimedWALMessage
+	for {
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if _, ok := msg.Msg.(RestartAcknowledgmentMessage); ok {
+			acks = append(acks, *msg)
+		}
+	}
+	require.Len(t, acks, 1)
+	require.Equal(t, RestartAcknowledgmentMessage{Height: height + 1, SignatureHeight: height}, acks[0].Msg)
+
+	// and skipped by the replay
+	require.NoError(t, cs.readReplayMessage(ctx, &acks[0], nil))
+}
+
+func TestStateDoubleSignRefusalDefaultConfig(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	require.Zero(t, cs.config.DoubleSignCheckHeight)
+	height := cs.roundState.Height()
+	require.NoError(t, cs.checkDoubleSigningRisk(height))
+
+	// the start is refused once a refusal is recorded, although the double
+	// sign check is disabled
+	require.NoError(t, cs.saveDoubleSignRefusal(doubleSignRefusal{
+		Height: height,
+		Error:  "error signing proposal: conflicting data",
+		Time:   tmtime.Now(),
+	}))
+	require.ErrorIs(t, cs.checkDoubleSigningRisk(height), ErrDoubleSignRefusalRecorded)
+	require.ErrorIs(t, cs.Start(ctx), ErrDoubleSignRefusalRecorded)
+
+	// and allowed once the marker file is removed
+	require.NoError(t, os.Remove(cs.doubleSignRefusalPath()))
+	require.NoError(t, cs.checkDoubleSigningRisk(height))
+}
@@ -0,0 +1,63 @@
+package consensus
+
+import (
+	"context"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// roundSkipHistory is the number of most recent round skips that are kept.
+const roundSkipHistory = 10
+
+// skipToRound enters round of height, higher than the current round, as
+// +2/3 of votes of any kind for it were received, and reports the validators
+// whose votes justified the skip.
+func (cs *State) skipToRound(ctx context.Context, height int64, round int32, entryLabel string, votes *types.VoteSet) {
+	vals := cs.roundState.Validators()
+	voted := votes.BitArray()
+	skip := types.EventDataRoundSkip{
+		Height:      height,
+		Round:       cs.roundState.Round(),
+		TargetRound: round,
+		Type:        tmproto.SignedMsgType(votes.Type()),
+		Reason:      entryLabel,
+		Validators:  []tmbytes.HexBytes{},
+		TotalPower:  vals.TotalVotingPower(),
+	}
+	for i, val := range vals.Validators {
+		if voted.GetIndex(i) {
+			skip.Validators = append(skip.Validators, val.Address)
+			skip.VotingPower += val.VotingPower
+		}
+	}
+
+	cs.logger.Info("skipping to a higher round",
+		"height", height,
+		"round", skip.Round,
+		"target_round", round,
+		"reason", entryLabel,
+		"voting_power", skip.VotingPower,
+		"total_power", skip.TotalPower,
+	)
+	if len(cs.roundSkips) == roundSkipHistory {
+		cs.roundSkips = cs.roundSkips[1:]
+	}
+	cs.roundSkips = append(cs.roundSkips, skip)
+	if err := cs.publishEvent(types.EventRoundSkipValue, skip); err != nil {
+		cs.logger.Error("failed publishing round skip", "err", err)
+	}
+
+	cs.enterNewRound(ctx, height, round, entryLabel)
+}
+
+// RecentRoundSkips returns the last round skips, oldest first.
+func (cs *State) RecentRoundSkips() []types.EventDataRoundSkip {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	skips := make([]types.EventDataRoundSkip, len(cs.roundSkips))
+	copy(skips, cs.roundSkips)
+	return skips
+}
@@ -0,0 +1,52 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateRoundSkip(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+
+	newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
+	skipCh := subscribe(ctx, t, cs.eventBus, types.EventQueryRoundSkip)
+
+	startTestRound(ctx, cs, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+
+	// +2/3 nil prevotes for a later round pull this node forward
+	incrementRound(vss[1:]...)
+	incrementRound(vss[1:]...)
+	signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), types.BlockID{}, vss[1:]...)
+	msg := ensureMessageBeforeTimeout(t, skipCh, ensureTimeout)
+	ensureNewRound(t, newRoundCh, height, round+2)
+
+	skip := msg.Data().(types.EventDataRoundSkip)
+	require.Equal(t, height, skip.Height)
+	require.Equal(t, round, skip.Round)
+	require.Equal(t, round+2, skip.TargetRound)
+	require.Equal(t, tmproto.PrevoteType, skip.Type)
+	require.Equal(t, "prevote-future", skip.Reason)
+	var voters []tmbytes.HexBytes
+	for _, vs := range vss[1:] {
+		pubKey, err := vs.PrivValidator.GetPubKey(ctx)
+		require.NoError(t, err)
+		voters = append(voters, tmbytes.HexBytes(pubKey.Address()))
+	}
+	require.ElementsMatch(t, voters, skip.Validators)
+	require.Equal(t, int64(3), skip.VotingPower)
+	require.Equal(t, int64(4), skip.TotalPower)
+
+	require.Equal(t, []types.EventDataRoundSkip{skip}, cs.RecentRoundSkips())
+}
@@ -0,0 +1,136 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+// ErrSignHRSRegression is returned when this node is about to ask its private
+// validator to sign at a height, round and step not above those of its last
+// sign request. The request is not sent: the state machine regressed, and the
+// private validator would refuse it or, without sign state, double sign.
+var ErrSignHRSRegression = errors.New("sign request does not advance the height, round and step of the last one")
+
+// SignHRS is the height, round and step of a sign request.
+type SignHRS struct {
+	Height int64
+	Round  int32
+	Step   cstypes.RoundStepType
+}
+
+// signHRSMark is the high-water mark of the sign requests of this node. The
+// votes are signed outside of cs.mtx, so it has its own lock.
+type signHRSMark struct {
+	mtx sync.Mutex
+	hrs SignHRS
+	// the block of the last sign request
+	blockHash []byte
+	// set once a sign request at hrs was signed
+	signed bool
+}
+
+// signStep returns the step a message of msgType is signed at.
+func signStep(msgType tmproto.SignedMsgType) cstypes.RoundStepType {
+	switch msgType {
+	case tmproto.ProposalType:
+		return cstypes.RoundStepPropose
+	case tmproto.PrevoteType:
+		return cstypes.RoundStepPrevote
+	default:
+		return cstypes.RoundStepPrecommit
+	}
+}
+
+// SignHRS returns the height, round and step of the last sign request of this
+// node, or of its last proposal or vote replayed from the WAL if greater.
+func (cs *State) SignHRS() SignHRS {
+	m := &cs.signHRS
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.hrs
+}
+
+// advanceSignHRS raises the mark to the request to sign the message of
+// msgType for the block of blockHash at height and round, or returns
+// ErrSignHRSRegression if the request is below the mark. A request at the
+// mark is only allowed for the same block, or if no request at the mark was
+// signed, as the private validator signs it again. The requests re-decided
+// during the replay lag behind the proposals and votes replayed from the WAL,
+// which were signed before the restart: their regressions are expected and
+// only refused.
+func (cs *State) advanceSignHRS(
+	height int64,
+	round int32,
+	msgType tmproto.SignedMsgType,
+	blockHash []byte,
+	replay bool,
+) error {
+	req := SignHRS{Height: height, Round: round, Step: signStep(msgType)}
+	m := &cs.signHRS
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	cmp := CompareHRS(req.Height, req.Round, req.Step, m.hrs.Height, m.hrs.Round, m.hrs.Step)
+	if cmp > 0 || (cmp == 0 && (!m.signed || bytes.Equal(blockHash, m.blockHash))) {
+		if cmp > 0 {
+			m.signed = false
+		}
+		m.hrs, m.blockHash = req, blockHash
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %v/%v/%v requested, %v/%v/%v already requested", ErrSignHRSRegression,
+		req.Height, req.Round, req.Step, m.hrs.Height, m.hrs.Round, m.hrs.Step)
+	if replay {
+		cs.logger.Debug("not signing again during the replay", "type", msgType, "err", err)
+		return err
+	}
+	cs.logger.Error("CONSENSUS FAILURE!!! refusing to send a sign request regressing the height, round or step",
+		"type", msgType, "height", req.Height, "round", req.Round, "step", req.Step,
+		"last_height", m.hrs.Height, "last_round", m.hrs.Round, "last_step", m.hrs.Step)
+	cs.metrics.SignHRSRegressions.Add(1)
+	return err
+}
+
+// markSignHRSSigned records that the sign request at height, round and the
+// step of msgType was signed.
+func (cs *State) markSignHRSSigned(height int64, round int32, msgType tmproto.SignedMsgType) {
+	m := &cs.signHRS
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.hrs == (SignHRS{Height: height, Round: round, Step: signStep(msgType)}) {
+		m.signed = true
+	}
+}
+
+// seedSignHRS raises the mark to the proposal or vote of this node of msg,
+// replayed from the WAL, which was signed before the restart.
+func (cs *State) seedSignHRS(msg WALMessage) {
+	mi, ok := msg.(msgInfo)
+	if !ok || mi.PeerID != "" {
+		return
+	}
+	var req SignHRS
+	var blockHash []byte
+	switch m := mi.Msg.(type) {
+	case *ProposalMessage:
+		req = SignHRS{Height: m.Proposal.Height, Round: m.Proposal.Round, Step: signStep(tmproto.ProposalType)}
+		blockHash = m.Proposal.BlockID.Hash
+	case *VoteMessage:
+		req = SignHRS{Height: m.Vote.Height, Round: m.Vote.Round, Step: signStep(m.Vote.Type)}
+		blockHash = m.Vote.BlockID.Hash
+	default:
+		return
+	}
+
+	m := &cs.signHRS
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if CompareHRS(req.Height, req.Round, req.Step, m.hrs.Height, m.hrs.Round, m.hrs.Step) > 0 {
+		m.hrs, m.blockHash, m.signed = req, blockHash, true
+	}
+}
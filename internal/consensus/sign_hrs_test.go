@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateRefusesSignHRSRegression(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	regressions := generic.NewCounter("sign_hrs_regressions")
+	cs.metrics.SignHRSRegressions = regressions
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+
+	_, err := cs.signVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	require.NoError(t, err)
+	require.Equal(t, SignHRS{Height: height, Round: round, Step: cstypes.RoundStepPrecommit}, cs.Status().SignHRS)
+
+	// the same request is signed again
+	_, err = cs.signVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	require.NoError(t, err)
+
+	// a precommit for another block, a prevote and a proposal of the round
+	// regress
+	_, err = cs.signVote(ctx, tmproto.PrecommitType, []byte("block"), types.PartSetHeader{})
+	require.ErrorIs(t, err, ErrSignHRSRegression)
+	_, err = cs.signVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	require.ErrorIs(t, err, ErrSignHRSRegression)
+	cs.decideProposal(ctx, height, round)
+	require.Empty(t, cs.internalMsgQueue)
+	require.Equal(t, 3.0, regressions.Value())
+	require.Equal(t, SignHRS{Height: height, Round: round, Step: cstypes.RoundStepPrecommit}, cs.SignHRS())
+
+	// the next round is signed
+	cs.enterNewRound(ctx, height, round+1, "test")
+	_, err = cs.signVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	require.NoError(t, err)
+	require.Equal(t, SignHRS{Height: height, Round: round + 1, Step: cstypes.RoundStepPrevote}, cs.SignHRS())
+}
+
+func TestStateSeedsSignHRSFromWAL(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	regressions := generic.NewCounter("sign_hrs_regressions")
+	cs.metrics.SignHRSRegressions = regressions
+	height := cs.roundState.Height()
+
+	// the votes of the peers do not seed the mark
+	vs := vss[1]
+	vs.Round = 2
+	vote := signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	cs.seedSignHRS(msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer"})
+	require.Equal(t, SignHRS{}, cs.SignHRS())
+
+	// the vote of this node replayed from the WAL does
+	cs.seedSignHRS(msgInfo{Msg: &VoteMessage{vote}})
+	require.Equal(t, SignHRS{Height: height, Round: 2, Step: cstypes.RoundStepPrevote}, cs.SignHRS())
+
+	// the votes re-decided during the replay below it are not signed again,
+	// without counting a regression
+	cs.replayMode = true
+	_, err := cs.signVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	cs.replayMode = false
+	require.ErrorIs(t, err, ErrSignHRSRegression)
+	require.Zero(t, regressions.Value())
+}
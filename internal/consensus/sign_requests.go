@@ -0,0 +1,161 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// SignRequest is a proposal or vote this node asked its private validator to
+// sign, with the exact bytes it asked to be signed, for signer audits.
+type SignRequest struct {
+	Time   time.Time `json:"time"`
+	Height int64     `json:"height"`
+	Round  int32     `json:"round"`
+	// Type is tmproto.ProposalType for proposals, the type of the vote
+	// otherwise.
+	Type tmproto.SignedMsgType `json:"type"`
+	// SignBytes are the canonical sign-bytes the private validator was asked
+	// to sign.
+	SignBytes tmbytes.HexBytes `json:"sign_bytes"`
+	// SignedBytes are the sign-bytes of the message as returned by the
+	// private validator, if it changed them, typically their timestamp. The
+	// signature is over them.
+	SignedBytes tmbytes.HexBytes `json:"signed_bytes,omitempty"`
+	Signature   tmbytes.HexBytes `json:"signature,omitempty"`
+	// ExtensionSignBytes and ExtensionSignature are those of the extension
+	// of a precommit, if it was extended.
+	ExtensionSignBytes tmbytes.HexBytes `json:"extension_sign_bytes,omitempty"`
+	ExtensionSignature tmbytes.HexBytes `json:"extension_signature,omitempty"`
+	// Error is why the private validator failed to sign.
+	Error string `json:"error,omitempty"`
+}
+
+// signRequests keeps the last sign requests of this node, and appends them to
+// the sign request log if enabled. The votes are signed outside of cs.mtx, so
+// it has its own lock.
+type signRequests struct {
+	mtx      sync.Mutex
+	requests []SignRequest
+	// next is the index of the oldest request once requests is full
+	next int
+	file *os.File
+}
+
+// RecentSignRequests returns the last config.SignRequestHistory proposals and
+// votes this node asked its private validator to sign, oldest first.
+func (cs *State) RecentSignRequests() []SignRequest {
+	sr := &cs.signRequests
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+	requests := make([]SignRequest, 0, len(sr.requests))
+	requests = append(requests, sr.requests[sr.next:]...)
+	return append(requests, sr.requests[:sr.next]...)
+}
+
+// recordProposalSignRequest records the request to sign proposal, whose
+// sign-bytes were signBytes, as returned by the private validator with err.
+func (cs *State) recordProposalSignRequest(chainID string, signBytes []byte, proposal *tmproto.Proposal, err error) {
+	req := SignRequest{
+		Height:    proposal.Height,
+		Round:     proposal.Round,
+		Type:      tmproto.ProposalType,
+		SignBytes: signBytes,
+		Signature: proposal.Signature,
+	}
+	if signed := types.ProposalSignBytes(chainID, proposal); !bytes.Equal(signed, signBytes) {
+		req.SignedBytes = signed
+	}
+	cs.recordSignRequest(req, err)
+}
+
+// recordVoteSignRequest records the request to sign vote, whose sign-bytes
+// were signBytes, as returned by the private validator with err.
+func (cs *State) recordVoteSignRequest(chainID string, signBytes []byte, vote *tmproto.Vote, err error) {
+	req := SignRequest{
+		Height:             vote.Height,
+		Round:              vote.Round,
+		Type:               vote.Type,
+		SignBytes:          signBytes,
+		Signature:          vote.Signature,
+		ExtensionSignature: vote.ExtensionSignature,
+	}
+	if signed := types.VoteSignBytes(chainID, vote); !bytes.Equal(signed, signBytes) {
+		req.SignedBytes = signed
+	}
+	if len(vote.ExtensionSignature) > 0 {
+		req.ExtensionSignBytes = types.VoteExtensionSignBytes(chainID, vote)
+	}
+	cs.recordSignRequest(req, err)
+}
+
+func (cs *State) recordSignRequest(req SignRequest, err error) {
+	size, path := cs.config.SignRequestHistory, cs.config.SignRequestLogPath
+	if size == 0 && path == "" {
+		return
+	}
+	req.Time = time.Now()
+	if err != nil {
+		req.Error = err.Error()
+	}
+
+	sr := &cs.signRequests
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+	if size > 0 {
+		if len(sr.requests) < size {
+			sr.requests = append(sr.requests, req)
+		} else {
+			sr.requests[sr.next] = req
+			sr.next = (sr.next + 1) % len(sr.requests)
+		}
+	}
+	if path != "" {
+		if err := sr.write(cs.config.SignRequestLogFile(), req); err != nil {
+			cs.logger.Error("failed to write the sign request log", "err", err)
+		}
+	}
+}
+
+// write appends req to the sign request log at path, opening it first if
+// needed.
+func (sr *signRequests) write(path string, req SignRequest) error {
+	if sr.file == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open the sign request log: %w", err)
+		}
+		sr.file = file
+	}
+	bz, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = sr.file.Write(append(bz, '\n'))
+	return err
+}
+
+// closeSignRequestLog closes the sign request log, if open.
+func (cs *State) closeSignRequestLog() {
+	sr := &cs.signRequests
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+	if sr.file == nil {
+		return
+	}
+	if err := sr.file.Close(); err != nil {
+		cs.logger.Error("failed to close the sign request log", "err", err)
+	}
+	sr.file = nil
+}
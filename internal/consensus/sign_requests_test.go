@@ -0,0 +1,69 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateRecordsSignRequests(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.SignRequestHistory = 2
+	cs.config.SignRequestLogPath = filepath.Join(t.TempDir(), "sign_requests.log")
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+
+	proposalCh := subscribe(ctx, t, cs.eventBus, types.EventQueryCompleteProposal)
+	pubKey, err := cs.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+
+	// this node proposes, prevotes and precommits
+	startTestRound(ctx, cs, height, round)
+	ensureNewProposal(t, proposalCh, height, round)
+	rs := cs.GetRoundState()
+	blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+	ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+	signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[1:]...)
+	ensurePrecommitMatch(t, voteCh, height, round, blockID.Hash)
+
+	// the history keeps the last two
+	requests := cs.RecentSignRequests()
+	require.Len(t, requests, 2)
+	for i, msgType := range []tmproto.SignedMsgType{tmproto.PrevoteType, tmproto.PrecommitType} {
+		req := requests[i]
+		require.Equal(t, msgType, req.Type)
+		require.Equal(t, height, req.Height)
+		require.Equal(t, round, req.Round)
+		require.Empty(t, req.Error)
+		require.Empty(t, req.SignedBytes)
+		require.True(t, pubKey.VerifySignature(req.SignBytes, req.Signature))
+		if len(req.ExtensionSignature) > 0 {
+			require.True(t, pubKey.VerifySignature(req.ExtensionSignBytes, req.ExtensionSignature))
+		}
+	}
+	vote := cs.GetRoundState().Votes.Precommits(round).GetByAddress(pubKey.Address())
+	require.Equal(t, types.VoteSignBytes(config.ChainID(), vote.ToProto()), requests[1].SignBytes.Bytes())
+
+	// the log has them all
+	cs.closeSignRequestLog()
+	data, err := os.ReadFile(cs.config.SignRequestLogPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	var proposal SignRequest
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &proposal))
+	require.Equal(t, tmproto.ProposalType, proposal.Type)
+	require.True(t, pubKey.VerifySignature(proposal.SignBytes, proposal.Signature))
+}
@@ -0,0 +1,47 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// ErrSignerSkewExceeded is returned when the signer changed the timestamp of
+// a vote of this node beyond config.SignerSkewLimit. The vote is not
+// broadcast: the other nodes could reject it or mismeasure its latency.
+var ErrSignerSkewExceeded = errors.New("the signer changed the vote timestamp beyond the limit")
+
+// checkSignerSkew records the difference between the timestamp of vote, as
+// returned by the signer, and the timestamp it was sent to the signer with.
+// A difference beyond config.SignerSkewTolerance is logged and counted; an
+// error is returned beyond config.SignerSkewLimit.
+func (cs *State) checkSignerSkew(vote *types.Vote, sent time.Time) error {
+	skew := vote.Timestamp.Sub(sent)
+	cs.signerSkew.Store(int64(skew))
+	cs.metrics.SignerTimestampSkew.Set(skew.Seconds())
+
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs <= cs.config.SignerSkewTolerance {
+		return nil
+	}
+	cs.metrics.SignerTimestampSkews.Add(1)
+	cs.logger.Error("the signer changed the timestamp of the vote",
+		"height", vote.Height, "round", vote.Round, "type", vote.Type,
+		"sent", sent, "signed", vote.Timestamp, "skew", skew)
+
+	if limit := cs.config.SignerSkewLimit; limit > 0 && abs > limit {
+		return fmt.Errorf("%w: skew %v, limit %v", ErrSignerSkewExceeded, skew, limit)
+	}
+	return nil
+}
+
+// SignerSkew returns the difference between the timestamp of the last vote of
+// this node after and before its signing.
+func (cs *State) SignerSkew() time.Duration {
+	return time.Duration(cs.signerSkew.Load())
+}
@@ -0,0 +1,97 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// restampingSigner is a private validator that shifts the timestamps of the
+// votes it signs by the given skews, in order, then by the last one.
+type restampingSigner struct {
+	types.PrivValidator
+
+	mtx   sync.Mutex
+	skews []time.Duration
+	calls int
+}
+
+func (s *restampingSigner) SignVote(ctx context.Context, chainID string, vote *tmproto.Vote) error {
+	s.mtx.Lock()
+	skew := s.skews[0]
+	if len(s.skews) > 1 {
+		s.skews = s.skews[1:]
+	}
+	s.calls++
+	s.mtx.Unlock()
+
+	vote.Timestamp = vote.Timestamp.Add(skew)
+	return s.PrivValidator.SignVote(ctx, chainID, vote)
+}
+
+func TestStateSignerSkew(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	privValidator := cs.privValidator
+	cs.config.SignerSkewTolerance = 100 * time.Millisecond
+	skews := generic.NewCounter("signer_timestamp_skews")
+	cs.metrics.SignerTimestampSkews = skews
+	sign := func(skews ...time.Duration) (*types.Vote, *restampingSigner) {
+		signer := &restampingSigner{PrivValidator: privValidator, skews: skews}
+		cs.privValidator = signer
+		start := time.Now()
+		queued := len(cs.internalMsgQueue)
+		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.voteSigner.wait()
+		var vote *types.Vote
+		if len(cs.internalMsgQueue) > queued {
+			vote = (<-cs.internalMsgQueue).Msg.(*VoteMessage).Vote
+			require.NoError(t, vote.ValidateBasic())
+			require.WithinDuration(t, start.Add(cs.SignerSkew()), vote.Timestamp, time.Second)
+		}
+		return vote, signer
+	}
+
+	// a skew within the tolerance is only recorded
+	vote, signer := sign(10 * time.Millisecond)
+	require.NotNil(t, vote)
+	require.Equal(t, 1, signer.calls)
+	require.Equal(t, 10*time.Millisecond, cs.SignerSkew())
+	require.Equal(t, 10*time.Millisecond, cs.Status().SignerSkew)
+	require.Zero(t, skews.Value())
+
+	// a skew beyond the tolerance is counted, the vote is kept
+	vote, signer = sign(-2 * time.Second)
+	require.NotNil(t, vote)
+	require.Equal(t, 1, signer.calls)
+	require.Equal(t, -2*time.Second, cs.Status().SignerSkew)
+	require.Equal(t, 1.0, skews.Value())
+
+	// a skew beyond the limit is signed again
+	cs.config.SignerSkewLimit = time.Second
+	vote, signer = sign(2*time.Second, 0)
+	require.NotNil(t, vote)
+	require.Equal(t, 2, signer.calls)
+	require.Zero(t, cs.SignerSkew())
+	require.Equal(t, 2.0, skews.Value())
+
+	// the vote is dropped if the skew is still beyond the limit
+	vote, signer = sign(2 * time.Second)
+	require.Nil(t, vote)
+	require.Equal(t, 2, signer.calls)
+	require.Equal(t, 4.0, skews.Value())
+
+	_, err := cs.signVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	require.True(t, errors.Is(err, ErrSignerSkewExceeded))
+}
@@ -0,0 +1,138 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// conflictingSelfVoteFile is the name of the marker file written next to the
+// WAL when signing is halted by a conflicting vote of this node's key.
+const conflictingSelfVoteFile = "conflicting_self_vote.json"
+
+// errSigningHalted is returned when asked to sign while signing is halted.
+var errSigningHalted = errors.New("signing is halted by a conflicting vote of this node's key")
+
+// conflictingSelfVote is the content of the marker file.
+type conflictingSelfVote struct {
+	Height     int64                 `json:"height,string"`
+	Round      int32                 `json:"round"`
+	Type       tmproto.SignedMsgType `json:"type"`
+	BlockHashA tmbytes.HexBytes      `json:"block_hash_a"`
+	BlockHashB tmbytes.HexBytes      `json:"block_hash_b"`
+	Time       time.Time             `json:"time"`
+}
+
+// SigningHalted reports whether this node stopped proposing and voting as a
+// vote of its key conflicting with one it already signed was received.
+func (cs *State) SigningHalted() bool {
+	return cs.signingHalted.Load()
+}
+
+// handleConflictingSelfVote reports the conflicting votes of this node's key
+// of voteErr, which means another instance of this validator may be running
+// or its sign state was lost. Unless disabled, signing is halted, and the
+// halt is persisted so that a restart does not resume signing until the
+// operator removes the marker file.
+func (cs *State) handleConflictingSelfVote(voteErr *types.ErrVoteConflictingVotes) {
+	vote := voteErr.VoteB
+	halt := cs.config.HaltOnConflictingSelfVote
+	cs.logger.Error("CONSENSUS FAILURE!!! found conflicting vote from ourselves; did you unsafe_reset a validator? "+
+		"check that no other instance of this validator is running",
+		"height", vote.Height,
+		"round", vote.Round,
+		"type", vote.Type,
+		"halt_signing", halt,
+	)
+
+	if err := cs.publishEvent(types.EventConflictingSelfVoteValue, types.EventDataConflictingSelfVote{
+		Height:     vote.Height,
+		Round:      vote.Round,
+		Type:       vote.Type,
+		BlockHashA: voteErr.VoteA.BlockID.Hash,
+		BlockHashB: voteErr.VoteB.BlockID.Hash,
+		Halted:     halt,
+	}); err != nil {
+		cs.logger.Error("failed publishing conflicting self vote", "err", err)
+	}
+	if !halt {
+		return
+	}
+
+	cs.haltSigning()
+	if err := cs.saveConflictingSelfVote(conflictingSelfVote{
+		Height:     vote.Height,
+		Round:      vote.Round,
+		Type:       vote.Type,
+		BlockHashA: voteErr.VoteA.BlockID.Hash,
+		BlockHashB: voteErr.VoteB.BlockID.Hash,
+		Time:       time.Now(),
+	}); err != nil {
+		cs.logger.Error("failed saving conflicting self vote", "err", err)
+	}
+}
+
+// haltSigning stops this node from proposing and voting. It keeps following
+// consensus.
+func (cs *State) haltSigning() {
+	if cs.signingHalted.Swap(true) {
+		return
+	}
+	cs.logger.Error("halted signing; this node follows consensus without proposing or voting until restarted "+
+		"without the marker file", "file", cs.conflictingSelfVotePath())
+	cs.metrics.SigningHalted.Set(1)
+}
+
+// checkSigningHalt halts signing on start if a conflicting vote of this
+// node's key was recorded before the restart.
+func (cs *State) checkSigningHalt() error {
+	recorded, err := cs.loadConflictingSelfVote()
+	if err != nil || recorded == nil {
+		return err
+	}
+	cs.logger.Error("found conflicting vote from ourselves before the last restart; "+
+		"remove the marker file once the cause is resolved to resume signing",
+		"height", recorded.Height, "round", recorded.Round, "type", recorded.Type, "time", recorded.Time,
+		"file", cs.conflictingSelfVotePath())
+	cs.haltSigning()
+	return nil
+}
+
+func (cs *State) conflictingSelfVotePath() string {
+	return filepath.Join(filepath.Dir(cs.config.WalFile()), conflictingSelfVoteFile)
+}
+
+func (cs *State) saveConflictingSelfVote(recorded conflictingSelfVote) error {
+	data, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	path := cs.conflictingSelfVotePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadConflictingSelfVote returns the recorded conflicting self vote, if any.
+func (cs *State) loadConflictingSelfVote() (*conflictingSelfVote, error) {
+	data, err := os.ReadFile(cs.conflictingSelfVotePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	recorded := new(conflictingSelfVote)
+	if err := json.Unmarshal(data, recorded); err != nil {
+		return nil, fmt.Errorf("invalid conflicting self vote file %s: %w", cs.conflictingSelfVotePath(), err)
+	}
+	return recorded, nil
+}
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateHaltsSigningOnConflictingSelfVote(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		halt bool
+	}{
+		{name: "halt", halt: true},
+		{name: "disabled"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := configSetup(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+			cs.config.HaltOnConflictingSelfVote = tc.halt
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+			vss[0].Height = height
+
+			proposalCh := subscribe(ctx, t, cs.eventBus, types.EventQueryCompleteProposal)
+			newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
+			conflictCh := subscribe(ctx, t, cs.eventBus, types.EventQueryConflictingSelfVote)
+			pubKey, err := cs.privValidator.GetPubKey(ctx)
+			require.NoError(t, err)
+			voteCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+
+			startTestRound(ctx, cs, height, round)
+			ensureNewRound(t, newRoundCh, height, round)
+			ensureNewProposal(t, proposalCh, height, round)
+			rs := cs.GetRoundState()
+			blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+			ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+
+			// a peer relays a nil prevote of our key
+			conflicting := signVote(ctx, t, vss[0], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+			cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{conflicting}, PeerID: "peer-a"}
+			msg := ensureMessageBeforeTimeout(t, conflictCh, ensureTimeout)
+			require.Equal(t, types.EventDataConflictingSelfVote{
+				Height:     height,
+				Round:      round,
+				Type:       tmproto.PrevoteType,
+				BlockHashA: blockID.Hash,
+				BlockHashB: nil,
+				Halted:     tc.halt,
+			}, msg.Data())
+			require.Equal(t, tc.halt, cs.SigningHalted())
+
+			// this node follows the polka with or without its precommit
+			signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), blockID, vss[1:]...)
+			if tc.halt {
+				ensureNoMessageBeforeTimeout(t, voteCh, ensureTimeout/10, "unexpected vote after the halt")
+			} else {
+				ensurePrecommitMatch(t, voteCh, height, round, blockID.Hash)
+			}
+			signAddVotes(ctx, t, cs, tmproto.PrecommitType, config.ChainID(), blockID, vss[1:]...)
+			ensureNewRound(t, newRoundCh, height+1, 0)
+			require.Equal(t, blockID.Hash, cs.blockStore.LoadBlock(height).Hash())
+
+			// the halt persists across a restart, which starts unhalted
+			cs.signingHalted.Store(false)
+			require.NoError(t, cs.checkSigningHalt())
+			require.Equal(t, tc.halt, cs.SigningHalted())
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// StartupPhase is the phase of the startup of a State.
+type StartupPhase int32
+
+const (
+	// StartupPhaseBootstrapping is the phase of a State that has not replayed
+	// the WAL yet.
+	StartupPhaseBootstrapping StartupPhase = iota
+	// StartupPhaseReplayingWAL is the phase of a State replaying the WAL to
+	// catch up with the messages of the current height.
+	StartupPhaseReplayingWAL
+	// StartupPhaseRunning is the phase of a State whose receive routine runs.
+	StartupPhaseRunning
+)
+
+func (p StartupPhase) String() string {
+	switch p {
+	case StartupPhaseBootstrapping:
+		return "bootstrapping"
+	case StartupPhaseReplayingWAL:
+		return "replaying-wal"
+	case StartupPhaseRunning:
+		return "running"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is the startup phase of a State, the progress of its WAL replay and
+// diagnostics of the current height.
+type Status struct {
+	Phase StartupPhase
+	// Height is the height the queries of the State are served at.
+	Height int64
+	// ReplayedMsgs is the number of WAL messages replayed so far.
+	ReplayedMsgs int
+	// BlockParts counts the block part bytes received in the current height.
+	BlockParts BlockPartGossip
+	// BlockPartsHave, BlockPartsTotal, BlockPartsBitmap and
+	// BlockPartsByteSize are the progress of the download of the proposal
+	// block of the current round, as returned by State.BlockPartsStatus.
+	BlockPartsHave     int
+	BlockPartsTotal    int
+	BlockPartsBitmap   []byte
+	BlockPartsByteSize int64
+	// AwaitingPOLRound is the POL round of the proposal of the current round
+	// whose prevotes were solicited from peers; -1 if none.
+	AwaitingPOLRound int32
+	// Transitions are the last transitions of the state machine in its
+	// current height, oldest first.
+	Transitions []StepTransition
+	// LastCommit is the completeness of the last commit during the NewHeight
+	// step, the timeoutCommit window; nil outside of it.
+	LastCommit *LastCommitCompleteness
+	// Lock is the block the State is locked on and the valid block of the
+	// current height.
+	Lock LockInfo
+	// SignerSkew is the difference between the timestamp of the last vote of
+	// this node after and before its signing.
+	SignerSkew time.Duration
+	// SignHRS is the height, round and step of the last sign request of
+	// this node.
+	SignHRS SignHRS
+	// VoteExtensionRejections are the rejected vote extensions of the
+	// current height by validator.
+	VoteExtensionRejections []VoteExtensionRejections
+	// EventBus is the circuit breaker of the event bus publishes.
+	EventBus EventBusBreakerStatus
+	// Membership is the membership of the key of this node in the validator
+	// set of the current height.
+	Membership ValidatorMembership
+}
+
+// startupState tracks the startup phase of a State. While the WAL is being
+// replayed, the queries of the State are served from a snapshot taken when the
+// replay started, consistent with the block store, instead of the round state
+// the replay is mutating.
+type startupState struct {
+	mtx          sync.RWMutex
+	phase        StartupPhase
+	replayedMsgs int
+
+	// snapshot of the State when the replay started; nil outside of the
+	// replay
+	roundState      *cstypes.RoundState
+	lastBlockHeight int64
+	validators      []*types.Validator
+}
+
+// StartupPhase returns the startup phase of the State.
+func (cs *State) StartupPhase() StartupPhase {
+	cs.startup.mtx.RLock()
+	defer cs.startup.mtx.RUnlock()
+	return cs.startup.phase
+}
+
+// Status returns the startup phase of the State, the height its queries are
+// served at, the progress of the WAL replay and diagnostics of the current
+// height.
+func (cs *State) Status() Status {
+	cs.startup.mtx.RLock()
+	phase, replayed, rs := cs.startup.phase, cs.startup.replayedMsgs, cs.startup.roundState
+	cs.startup.mtx.RUnlock()
+
+	currentHeight := cs.roundState.Height()
+	height := currentHeight
+	if rs != nil {
+		height = rs.Height
+	}
+	have, total, bitmap, byteSize := cs.BlockPartsStatus()
+	return Status{
+		Phase:            phase,
+		Height:           height,
+		ReplayedMsgs:     replayed,
+		BlockParts:       cs.blockPartGossip.load(),
+		AwaitingPOLRound: cs.roundState.AwaitingPOLRound(),
+		Transitions:      cs.stepTransitions.load(currentHeight),
+		LastCommit:       cs.lastCommitCompleteness(),
+		Lock:             cs.lockInfo(),
+		SignerSkew:       cs.SignerSkew(),
+		SignHRS:          cs.SignHRS(),
+		EventBus:         cs.EventBusBreaker(),
+		Membership:       cs.ValidatorMembership(),
+
+		VoteExtensionRejections: cs.voteExtensionRejections.load(currentHeight),
+
+		BlockPartsHave:     have,
+		BlockPartsTotal:    total,
+		BlockPartsBitmap:   bitmap,
+		BlockPartsByteSize: byteSize,
+	}
+}
+
+// startReplayPhase snapshots the State and enters StartupPhaseReplayingWAL.
+// It is called before each attempt of the catchup replay.
+func (cs *State) startReplayPhase() {
+	cs.mtx.RLock()
+	rs := cs.roundState.CopyInternal()
+	lastBlockHeight := cs.state.LastBlockHeight
+	validators := cs.state.Validators.Copy().Validators
+	cs.mtx.RUnlock()
+
+	cs.startup.mtx.Lock()
+	defer cs.startup.mtx.Unlock()
+	cs.startup.phase = StartupPhaseReplayingWAL
+	cs.startup.replayedMsgs = 0
+	cs.startup.roundState = rs
+	cs.startup.lastBlockHeight = lastBlockHeight
+	cs.startup.validators = validators
+}
+
+// markMsgReplayed counts a replayed WAL message.
+func (cs *State) markMsgReplayed() {
+	cs.startup.mtx.Lock()
+	defer cs.startup.mtx.Unlock()
+	cs.startup.replayedMsgs++
+}
+
+// startRunningPhase drops the replay snapshot and enters StartupPhaseRunning.
+func (cs *State) startRunningPhase() {
+	cs.startup.mtx.Lock()
+	defer cs.startup.mtx.Unlock()
+	cs.startup.phase = StartupPhaseRunning
+	cs.startup.roundState = nil
+	cs.startup.validators = nil
+}
+
+// replaySnapshot returns the round state, last block height and validators
+// snapshotted when the replay started, and false outside of the replay.
+func (cs *State) replaySnapshot() (*cstypes.RoundState, int64, []*types.Validator, bool) {
+	cs.startup.mtx.RLock()
+	defer cs.startup.mtx.RUnlock()
+	if cs.startup.roundState == nil {
+		return nil, 0, nil, false
+	}
+	return cs.startup.roundState, cs.startup.lastBlockHeight, cs.startup.validators, true
+}
@@ -6,15 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
+	"runtime"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gogo/protobuf/proto"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/trace"
 	otrace "go.opentelemetry.io/otel/trace"
@@ -44,18 +44,64 @@ var (
 	ErrInvalidProposalPOLRound    = errors.New("error invalid proposal POL round")
 	ErrAddingVote                 = errors.New("error adding vote")
 	ErrSignatureFoundInPastBlocks = errors.New("found signature from the same key")
+	ErrStateNotBootstrapped       = errors.New("consensus state is not bootstrapped: the state store is empty; " +
+		"save the genesis state to the state store or seed the State with Bootstrap before starting it")
 
 	errPubKeyIsNotSet = errors.New("pubkey is not set. Look for \"Can't get private validator pubkey\" errors")
 )
 
+// ErrVoteFromNonValidator is returned for a vote from an address that is not
+// in the validator set of the height of the vote.
+type ErrVoteFromNonValidator struct {
+	Address types.Address
+	// RotatedOut is set if the address was in the validator set of the
+	// previous height, as expected from a validator that just left the set.
+	RotatedOut bool
+}
+
+func (e *ErrVoteFromNonValidator) Error() string {
+	if e.RotatedOut {
+		return fmt.Sprintf("vote from %v, which is no longer a validator", e.Address)
+	}
+	return fmt.Sprintf("vote from %v, which is not a validator", e.Address)
+}
+
+// ErrStateAheadOfBlockStore is returned when loading a state from the state
+// store that is ahead of the block store, which does not have the commit the
+// last commit of the state is reconstructed from, or its extended commit if
+// vote extensions are enabled, as left by an interrupted statesync. The node
+// must be synced again, or its state rolled back to the height of the block
+// store.
+type ErrStateAheadOfBlockStore struct {
+	StateHeight      int64
+	BlockStoreHeight int64
+}
+
+func (e *ErrStateAheadOfBlockStore) Error() string {
+	return fmt.Sprintf("state store at height %d is ahead of block store at height %d",
+		e.StateHeight, e.BlockStoreHeight)
+}
+
 var msgQueueSize = 1000
 var heartbeatIntervalInSecs = 10
 
+// stopRoutinesTimeout bounds how long OnStop waits for the goroutines of the
+// State to exit.
+var stopRoutinesTimeout = 10 * time.Second
+
+// onStopCommitPollInterval is the interval OnStop checks whether the commit
+// it waits for finished at, besides the signal of finalizeCommit.
+var onStopCommitPollInterval = 10 * time.Millisecond
+
 // msgs from the reactor which may update the state
 type msgInfo struct {
 	Msg         Message
 	PeerID      types.NodeID
 	ReceiveTime time.Time
+
+	// Verification is how much of the vote of a VoteMessage its sender
+	// verified. It is not written to the WAL, so replayed votes are verified.
+	Verification types.VoteVerification
 }
 
 func (msgInfo) TypeTag() string { return "tendermint/wal/MsgInfo" }
@@ -83,6 +129,7 @@ func (m *msgInfo) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	m.PeerID = msg.PeerID
+	m.ReceiveTime = msg.ReceiveTime
 	return nil
 }
 
@@ -100,11 +147,37 @@ func (ti *timeoutInfo) String() string {
 	return fmt.Sprintf("%v ; %d/%d %v", ti.Duration, ti.Height, ti.Round, ti.Step)
 }
 
+// ValidateBasic performs basic validation. Timeouts are only scheduled for
+// the steps handled by handleTimeout.
+func (ti *timeoutInfo) ValidateBasic() error {
+	if ti.Height <= 0 {
+		return fmt.Errorf("invalid height %d", ti.Height)
+	}
+	if ti.Round < 0 {
+		return fmt.Errorf("negative round %d", ti.Round)
+	}
+	switch ti.Step {
+	case cstypes.RoundStepNewHeight, cstypes.RoundStepNewRound, cstypes.RoundStepPropose,
+		cstypes.RoundStepPrevoteWait, cstypes.RoundStepPrecommitWait:
+		return nil
+	default:
+		return fmt.Errorf("invalid timeout step %v", ti.Step)
+	}
+}
+
 // interface to the mempool
 type txNotifier interface {
 	TxsAvailable() <-chan struct{}
 }
 
+// txsCommittedNotifier is optionally implemented by the txNotifier to learn
+// which txs were committed as soon as their block is saved, before ApplyBlock
+// and the mempool Update, e.g. to stop gossiping them. The notification is
+// advisory: the txs are still removed by Update.
+type txsCommittedNotifier interface {
+	TxsCommitted(txKeys []types.TxKey, height int64)
+}
+
 // interface to the evidence pool
 type evidencePool interface {
 	// reports conflicting votes to the evidence pool to be processed into evidence
@@ -120,10 +193,19 @@ type State struct {
 	logger log.Logger
 
 	// config details
-	config            *config.ConsensusConfig
-	mempoolConfig     *config.MempoolConfig
-	privValidator     types.PrivValidator // for signing votes
-	privValidatorType types.PrivValidatorType
+	config        *config.ConsensusConfig
+	mempoolConfig *config.MempoolConfig
+
+	// the private validator signing our votes, and its pubkey, memoized for
+	// the duration of one block to avoid extra requests to HSM. They are
+	// guarded by privValidatorMtx rather than mtx, so that they can be read
+	// and set while the state machine runs.
+	privValidatorMtx    sync.RWMutex
+	privValidator       types.PrivValidator
+	privValidatorType   types.PrivValidatorType
+	privValidatorPubKey crypto.PubKey
+	// privValidatorGen is incremented each time the private validator is set
+	privValidatorGen uint64
 
 	// store blocks and commits
 	blockStore sm.BlockStore
@@ -145,9 +227,6 @@ type State struct {
 	mtx        sync.RWMutex
 	roundState cstypes.SafeRoundState
 	state      sm.State // State until height-1.
-	// privValidator pubkey, memoized for the duration of one block
-	// to avoid extra requests to HSM
-	privValidatorPubKey crypto.PubKey
 
 	// state changes may be triggered by: msgs from peers,
 	// msgs from ourself, or by timeouts
@@ -155,6 +234,13 @@ type State struct {
 	internalMsgQueue chan msgInfo
 	timeoutTicker    TimeoutTicker
 
+	// internal messages waiting for room in internalMsgQueue
+	internalMsgOverflow internalMsgOverflow
+	// WAL health and errors of handleMsg for the debug dump
+	debugState debugState
+	// votes of this node waiting to be signed
+	voteSigner voteSigner
+
 	// information about about added votes and block parts are written on this channel
 	// so statistics can be computed by reactor
 	statsMsgQueue chan msgInfo
@@ -162,12 +248,24 @@ type State struct {
 	// we use eventBus to trigger msg broadcasts in the reactor,
 	// and to notify external subscribers, eg. through a websocket
 	eventBus *eventbus.EventBus
+	// ownsEventBus is set if eventBus is private to the State, which starts
+	// and stops it
+	ownsEventBus bool
+	// circuit breaker of the publishes to eventBus
+	eventBusBreaker eventBusBreaker
+	// tooling is set for a State created by NewStateForTooling
+	tooling bool
 
 	// a Write-Ahead Log ensures we can recover from any kind of crash
 	// and helps us avoid signing conflicting votes
 	wal          WAL
 	replayMode   bool // so we don't log signing errors during replay
 	doWALCatchup bool // determines if we even try to do the catchup
+	// opens the WAL in place of the WAL file of the config; nil for the file
+	walProvider WALProvider
+	// true if the WAL was set with WithWAL, which cannot be reopened after
+	// a repair
+	walFixed bool
 
 	// for tests where we want to limit the number of transitions the state makes
 	nSteps int
@@ -175,7 +273,7 @@ type State struct {
 	// some functions can be overwritten for testing
 	decideProposal func(ctx context.Context, height int64, round int32)
 	doPrevote      func(ctx context.Context, height int64, round int32)
-	setProposal    func(proposal *types.Proposal, t time.Time) error
+	setProposal    func(proposal *types.Proposal, peerID types.NodeID, t time.Time) error
 
 	// synchronous pubsub between consensus state and reactor.
 	// state only emits EventNewRoundStep, EventValidBlock, and EventVote
@@ -187,11 +285,192 @@ type State struct {
 	// wait the channel event happening for shutting down the state gracefully
 	onStopCh chan *cstypes.RoundState
 
+	// goroutines spawned by the State, and the cancellation of the context of
+	// those started by OnStart; OnStop waits for them to exit
+	routines       sync.WaitGroup
+	cancelRoutines context.CancelFunc
+
+	// unix nano time of the last receiveRoutine iteration, checked by the
+	// watchdog to detect a wedged state machine
+	lastActivity atomic.Int64
+
+	// difference in nanoseconds between the timestamp of the last vote of
+	// this node after and before its signing
+	signerSkew atomic.Int64
+
+	// set once signing is halted by a conflicting vote of this node's key
+	signingHalted atomic.Bool
+
+	// filters the vote extensions stored with committed blocks; nil retains all
+	voteExtensionRetention VoteExtensionRetentionPolicy
+
+	// produces the extensions of our own precommits; nil uses the application
+	voteExtensionProvider VoteExtensionProvider
+
+	// locks and relocks of the last few heights, oldest first
+	lockHistory []LockEvent
+	// outcomes of the validation of the blocks of the current height
+	blockValidations blockValidations
+	// the last roundSkipHistory round skips, oldest first
+	roundSkips []types.EventDataRoundSkip
+	// the proposal of a skipped round of the height whose polka block is
+	// received to become the valid block; nil if none
+	lateProposal *lateProposal
+	// the height of the last #ENDHEIGHT written to the WAL by this process
+	lastEndHeight int64
+	// limits the debug lines logged per message
+	msgDebugLogs msgDebugLogLimiter
+	// proposers of the last config.ProposerHistoryHeights committed heights,
+	// oldest first
+	proposerHistory []ProposerRecord
+	// when the current height entered RoundStepNewHeight, the start of the
+	// timeoutCommit window the precommits for the previous height are
+	// accepted in
+	newHeightStart time.Time
+
+	// when the propose step of the current round times out
+	proposeDeadline time.Time
+	// latest fetch of proposal txs from the mempool in key-only mode
+	blockReconstruction *blockReconstruction
+
+	// proposers whose proposals are prevoted nil early; nil if disabled
+	proposerBlacklist *proposerBlacklist
+
+	// faults injected into the WAL and timeouts by tests
+	faultInjector FaultInjector
+
+	// startup phase, and the snapshot queries are served from during the
+	// WAL replay
+	startup startupState
+
+	// height the WAL replay ended at; the peer messages of lower heights,
+	// except the votes of the height below, are dropped before being written
+	// to the WAL. 0 if the WAL was not replayed.
+	replayFloorHeight int64
+
+	// log of the decisions of the state machine; nil if disabled
+	decisionLog *decisionLog
+	// the ring of the events published, written for their inspection after
+	// a crash; nil if config.EventRingPath is not set
+	eventRing *eventRing
+	// the last proposals and votes this node asked its private validator to
+	// sign
+	signRequests signRequests
+	// the height, round and step of the last sign request of this node
+	signHRS signHRSMark
+
+	// block part bytes received from peers in the current height
+	blockPartGossip blockPartGossip
+	blockGossip     blockGossipProgress
+	blockPartTiming blockPartTiming
+	blockPartWaits  blockPartWaits
+	stepTransitions stepTransitions
+
+	// rejected vote extensions of the current height by validator
+	voteExtensionRejections voteExtensionRejections
+	// vote extension bytes held in the vote sets of the current height
+	voteExtensionMemory voteExtensionMemory
+
+	// last height whose block was applied
+	lastApplied lastApplied
+
+	// source of the local time the proposer waits for to pass the previous
+	// block time
+	clock            tmtime.Source
+	lastProposerWait *ProposerWait
+
+	// peers precommits were received from, and the committed block this node
+	// is waiting for
+	precommitPeers votePeers
+	blockRecovery  *blockRecovery
+
+	// peers prevotes were received from, and the solicitation of the
+	// proposal of the current round once its proposer prevoted for a block
+	prevotePeers votePeers
+	proposeGrace proposeGrace
+
+	// where the precommits of each round were received from, to decide
+	// whether the commit timeout can be bypassed
+	precommitSources precommitSources
+
+	// steps of the state machine counted against config.MaxStepsPerSecond
+	stepBudget stepBudget
+
+	// precommits this node signed, checked against the commits of the blocks
+	precommitInclusion precommitInclusion
+
+	// reports peers sending suspicious messages; nil if not configured
+	reportPeerMisbehavior func(peerID types.NodeID, err error)
+
+	// senders allowed to add pre-verified votes; nil if disabled
+	preVerifiedVotes *preVerifiedVotes
+
+	// last height whose committed txs were notified to the txNotifier
+	txsCommittedHeight int64
+
+	// validator set changes of the current height
+	validatorSetDiff ValidatorDiff
+
+	// membership of the key of this node in the validator set
+	validatorMembership validatorMembership
+
+	// factor the base propose and vote timeouts are multiplied by, and the
+	// size of the validator set it was computed for
+	timeoutScale           float64
+	timeoutScaleValidators int
+
+	// state updates ignored by updateToState
+	lastIgnoredStateUpdate         *IgnoredStateUpdate
+	consecutiveIgnoredStateUpdates int
+	lastIgnoredStateUpdateWarn     time.Time
+
+	// dissemination of the latest proposal signed by this validator
+	proposalTimeline    proposalTimeline
+	lastProposalLatency *ProposalLatency
+
+	// times the prevotes of each round of the current height reached +2/3
+	// for a block
+	polkaTimes polkaTimes
+	// peers that delivered the proposals and blocks of the current height
+	proposalDelivery proposalDelivery
+	// delay of the precommit of this node for more prevotes
+	precommitDelay precommitDelay
+
+	// validators that have not voted in the current height
+	absentees absenteeTracker
+
+	// block submitted to be proposed instead of the one created by blockExec
+	proposalCandidate proposalCandidate
+
+	// tx keys of the proposal of the current round
+	proposalTxKeys proposalTxKeys
+
+	// counts of the messages of the peers in the current height
+	peerStats peerStats
+
+	// updates the per-validator metrics of the committed heights
+	validatorMetrics validatorMetrics
+
+	// how the proposal block of the other validators was obtained
+	proposalBlockSource proposalBlockSource
+
+	// tracer of the spans of the current height: heightTracer if the height
+	// is sampled, a no-op tracer otherwise
 	tracer                otrace.Tracer
+	heightTracer          otrace.Tracer
+	tracerProvider        otrace.TracerProvider
+	ownedTracerProvider   *trace.TracerProvider
 	tracerProviderOptions []trace.TracerProviderOption
 	heightSpan            otrace.Span
 	heightBeingTraced     int64
 	tracingCtx            context.Context
+	// span of the current round, below heightSpan
+	roundSpan        otrace.Span
+	roundBeingTraced int32
+	roundTracingCtx  context.Context
+	// no span is started when neither tracer provider options nor a provider
+	// were given, see startSpan
+	tracingDisabled bool
 }
 
 // StateOption sets an optional parameter on the State.
@@ -233,7 +512,11 @@ func NewState(
 		evpool:           evpool,
 		evsw:             tmevents.NewEventSwitch(),
 		metrics:          NopMetrics(),
+		faultInjector:    noopFaultInjector{},
+		clock:            tmtime.DefaultSource{},
 		onStopCh:         make(chan *cstypes.RoundState),
+
+		proposerBlacklist: newProposerBlacklist(cfg.ProposerBlacklistThreshold, cfg.ProposerBlacklistHeights),
 	}
 
 	// set function defaults (may be overwritten before calling Start)
@@ -251,51 +534,147 @@ func NewState(
 	// node-fragments gracefully while letting the nodes
 	// themselves avoid this.
 	if !cs.skipBootstrapping {
-		if err := cs.updateStateFromStore(); err != nil {
+		if _, err := cs.updateStateFromStore(); err != nil {
 			return nil, err
 		}
 	}
 
-	tp := trace.NewTracerProvider(traceProviderOps...)
-	cs.tracer = tp.Tracer("tm-consensus-state")
-	cs.tracerProviderOptions = traceProviderOps
+	cs.setupTracer(traceProviderOps)
 
 	return cs, nil
 }
 
-func (cs *State) updateStateFromStore() error {
+// updateStateFromStore loads the latest state from the state store and
+// updates the consensus state to it. It returns whether the consensus state
+// was updated.
+func (cs *State) updateStateFromStore() (bool, error) {
 	state, err := cs.stateStore.Load()
 	if err != nil {
-		return fmt.Errorf("loading state: %w", err)
+		return false, fmt.Errorf("loading state: %w", err)
 	}
 	if state.IsEmpty() {
-		return nil
+		return false, nil
 	}
 
 	eq, err := state.Equals(cs.state)
 	if err != nil {
-		return fmt.Errorf("comparing state: %w", err)
+		return false, fmt.Errorf("comparing state: %w", err)
 	}
 	// if the new state is equivalent to the old state, we should not trigger a state update.
 	if eq {
-		return nil
+		cs.recordIgnoredStateUpdate(stateUpdateSourceStoreLoad, "state unchanged",
+			cs.state.LastBlockHeight+1, state.LastBlockHeight+1)
+		return false, nil
 	}
 
 	// We have no votes, so reconstruct LastCommit from SeenCommit.
 	if state.LastBlockHeight > 0 {
+		if err := cs.checkStateNotAheadOfBlockStore(state); err != nil {
+			return false, err
+		}
 		cs.reconstructLastCommit(state)
 	}
 
-	cs.updateToState(state)
+	return cs.updateToState(state, stateUpdateSourceStoreLoad), nil
+}
 
+// Bootstrap seeds the State with state, reconstructing its last commit from
+// the block store, as NewState does with the state of the state store unless
+// SkipStateStoreBootstrap is set. It is for embedders starting the State
+// from a state that is not in the state store, and must be called before
+// Start.
+func (cs *State) Bootstrap(state sm.State) error {
+	if cs.IsRunning() {
+		return errors.New("can't bootstrap a running consensus state")
+	}
+	if state.IsEmpty() {
+		return errors.New("can't bootstrap the consensus state with an empty state")
+	}
+
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	if state.LastBlockHeight > 0 {
+		if err := cs.checkStateNotAheadOfBlockStore(state); err != nil {
+			return err
+		}
+		votes, err := cs.loadLastCommit(state)
+		if err != nil {
+			return err
+		}
+		cs.roundState.SetLastCommit(votes)
+	}
+	if !cs.updateToState(state, stateUpdateSourceBootstrap) {
+		return fmt.Errorf("can't bootstrap the consensus state at height %d: it is at height %d",
+			state.LastBlockHeight+1, cs.state.LastBlockHeight+1)
+	}
 	return nil
 }
 
+// checkStateNotAheadOfBlockStore returns ErrStateAheadOfBlockStore if the last
+// commit of state can not be reconstructed because the block store is behind
+// it. Statesync only saves the seen commit of the height it restores, which is
+// enough unless vote extensions are enabled, and its backfill saves the signed
+// header of that height without the extended commit.
+func (cs *State) checkStateNotAheadOfBlockStore(state sm.State) error {
+	blockStoreHeight := cs.blockStore.Height()
+	if !state.ConsensusParams.ABCI.VoteExtensionsEnabled(state.LastBlockHeight) {
+		if state.LastBlockHeight <= blockStoreHeight {
+			return nil
+		}
+		if seen := cs.blockStore.LoadSeenCommit(); seen != nil && seen.Height == state.LastBlockHeight {
+			return nil
+		}
+	} else if cs.blockStore.LoadBlockExtendedCommit(state.LastBlockHeight) != nil {
+		return nil
+	}
+	return &ErrStateAheadOfBlockStore{StateHeight: state.LastBlockHeight, BlockStoreHeight: blockStoreHeight}
+}
+
 // StateMetrics sets the metrics.
 func StateMetrics(metrics *Metrics) StateOption {
 	return func(cs *State) { cs.metrics = metrics }
 }
 
+// WithClock sets the source of the local time the proposer compares to the
+// previous block time.
+func WithClock(clock tmtime.Source) StateOption {
+	return func(cs *State) { cs.clock = clock }
+}
+
+// WithPeerMisbehaviorReporter sets the function the State reports peers
+// sending suspicious messages to.
+func WithPeerMisbehaviorReporter(report func(peerID types.NodeID, err error)) StateOption {
+	return func(cs *State) { cs.reportPeerMisbehavior = report }
+}
+
+// WithWALProvider sets the function OnStart opens the WAL with, in place of
+// the WAL file of the config, to store the WAL on another device or backend.
+func WithWALProvider(provider WALProvider) StateOption {
+	return func(cs *State) {
+		cs.walProvider = provider
+		cs.walFixed = false
+	}
+}
+
+// WithWAL sets the WAL OnStart starts, in place of the WAL file of the config.
+// The WAL is not repaired when it is corrupted.
+func WithWAL(wal WAL) StateOption {
+	return func(cs *State) {
+		var opened bool
+		cs.walProvider = func(ctx context.Context, _ log.Logger, _ string) (WAL, error) {
+			if opened {
+				return nil, errWALReopened
+			}
+			if err := wal.Start(ctx); err != nil {
+				return nil, err
+			}
+			opened = true
+			return wal, nil
+		}
+		cs.walFixed = true
+	}
+}
+
 // String returns a string.
 func (cs *State) String() string {
 	// better not to access shared variables
@@ -311,16 +690,35 @@ func (cs *State) GetState() sm.State {
 
 // GetLastHeight returns the last height committed.
 // If there were no blocks, returns 0.
+// While the WAL is replayed, it returns the last height before the replay.
 func (cs *State) GetLastHeight() int64 {
+	if rs, _, _, ok := cs.replaySnapshot(); ok {
+		return rs.Height - 1
+	}
 	return cs.roundState.Height() - 1
 }
 
 // GetRoundState returns a shallow copy of the internal consensus state.
+// While the WAL is replayed, it returns the state before the replay.
 func (cs *State) GetRoundState() *cstypes.RoundState {
+	if rs, _, _, ok := cs.replaySnapshot(); ok {
+		rsCopy := *rs
+		return &rsCopy
+	}
 	rs := cs.roundState.CopyInternal()
 	return rs
 }
 
+// GetLastActivityAge returns the time since the receive routine last
+// processed a message, or 0 if it has not been started.
+func (cs *State) GetLastActivityAge() time.Duration {
+	last := cs.lastActivity.Load()
+	if last == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, last))
+}
+
 // GetRoundStateJSON returns a json of RoundState.
 func (cs *State) GetRoundStateJSON() ([]byte, error) {
 	return json.Marshal(*cs.roundState.CopyInternal())
@@ -333,43 +731,78 @@ func (cs *State) GetRoundStateSimpleJSON() ([]byte, error) {
 }
 
 // GetValidators returns a copy of the current validators.
+// While the WAL is replayed, it returns the validators before the replay.
 func (cs *State) GetValidators() (int64, []*types.Validator) {
+	if _, height, vals, ok := cs.replaySnapshot(); ok {
+		valsCopy := make([]*types.Validator, len(vals))
+		for i, val := range vals {
+			valsCopy[i] = val.Copy()
+		}
+		return height, valsCopy
+	}
 	cs.mtx.RLock()
 	defer cs.mtx.RUnlock()
 	return cs.state.LastBlockHeight, cs.state.Validators.Copy().Validators
 }
 
 // SetPrivValidator sets the private validator account for signing votes. It
-// immediately requests pubkey and caches it.
+// immediately requests pubkey and caches it. It is safe to call while the State
+// is running.
 func (cs *State) SetPrivValidator(ctx context.Context, priv types.PrivValidator) {
-	cs.mtx.Lock()
-	defer cs.mtx.Unlock()
-
-	cs.privValidator = priv
-
+	var pvType types.PrivValidatorType
 	if priv != nil {
 		switch t := priv.(type) {
 		case *privval.RetrySignerClient:
-			cs.privValidatorType = types.RetrySignerClient
+			pvType = types.RetrySignerClient
 		case *privval.FilePV:
-			cs.privValidatorType = types.FileSignerClient
+			pvType = types.FileSignerClient
 		case *privval.SignerClient:
-			cs.privValidatorType = types.SignerSocketClient
+			pvType = types.SignerSocketClient
 		case *tmgrpc.SignerClient:
-			cs.privValidatorType = types.SignerGRPCClient
+			pvType = types.SignerGRPCClient
 		case types.MockPV:
-			cs.privValidatorType = types.MockSignerClient
+			pvType = types.MockSignerClient
 		case *types.ErroringMockPV:
-			cs.privValidatorType = types.ErrorMockSignerClient
+			pvType = types.ErrorMockSignerClient
 		default:
 			cs.logger.Error("unsupported priv validator type", "err",
 				fmt.Errorf("error privValidatorType %s", t))
 		}
 	}
 
-	if err := cs.updatePrivValidatorPubKey(ctx); err != nil {
-		cs.logger.Error("failed to get private validator pubkey", "err", err)
+	// the pubkey is fetched before priv is set, so that it is never observed
+	// without its pubkey
+	var pubKey crypto.PubKey
+	if priv != nil {
+		var err error
+		if pubKey, err = cs.fetchPrivValidatorPubKey(ctx, priv, pvType); err != nil {
+			cs.logger.Error("failed to get private validator pubkey", "err", err)
+		}
 	}
+
+	cs.privValidatorMtx.Lock()
+	defer cs.privValidatorMtx.Unlock()
+	cs.privValidator = priv
+	cs.privValidatorType = pvType
+	cs.privValidatorPubKey = pubKey
+	cs.privValidatorGen++
+}
+
+// getPrivValidator returns the private validator and its memoized pubkey.
+// The private validator is nil if the node is not a validator, and the pubkey
+// is nil if it could not be fetched.
+func (cs *State) getPrivValidator() (types.PrivValidator, crypto.PubKey) {
+	cs.privValidatorMtx.RLock()
+	defer cs.privValidatorMtx.RUnlock()
+	return cs.privValidator, cs.privValidatorPubKey
+}
+
+// getPrivValidatorPubKey returns the memoized pubkey of the private
+// validator, or nil.
+func (cs *State) getPrivValidatorPubKey() crypto.PubKey {
+	cs.privValidatorMtx.RLock()
+	defer cs.privValidatorMtx.RUnlock()
+	return cs.privValidatorPubKey
 }
 
 // SetTimeoutTicker sets the local timer. It may be useful to overwrite for
@@ -381,10 +814,19 @@ func (cs *State) SetTimeoutTicker(timeoutTicker TimeoutTicker) {
 }
 
 // LoadCommit loads the commit for a given height.
+// While the WAL is replayed, it is loaded from the block store without waiting
+// for the replayed messages.
 func (cs *State) LoadCommit(height int64) *types.Commit {
+	if _, _, _, ok := cs.replaySnapshot(); ok {
+		return cs.loadCommit(height)
+	}
 	cs.mtx.RLock()
 	defer cs.mtx.RUnlock()
 
+	return cs.loadCommit(height)
+}
+
+func (cs *State) loadCommit(height int64) *types.Commit {
 	if height == cs.blockStore.Height() {
 		commit := cs.blockStore.LoadSeenCommit()
 		// NOTE: Retrieving the height of the most recent block and retrieving
@@ -402,10 +844,28 @@ func (cs *State) LoadCommit(height int64) *types.Commit {
 // OnStart loads the latest state via the WAL, and starts the timeout and
 // receive routines.
 func (cs *State) OnStart(ctx context.Context) error {
-	if err := cs.updateStateFromStore(); err != nil {
+	// the routines and services started here are stopped by OnStop even if
+	// ctx is not canceled
+	ctx, cs.cancelRoutines = context.WithCancel(ctx)
+
+	if err := cs.checkTooling(ctx); err != nil {
 		return err
 	}
 
+	updated, err := cs.updateStateFromStore()
+	if err != nil {
+		return err
+	}
+	cs.logger.Info("loaded state from store", "updated", updated, "height", cs.roundState.Height())
+	// with SkipStateStoreBootstrap, the state may be seeded by neither
+	if cs.state.IsEmpty() {
+		return ErrStateNotBootstrapped
+	}
+
+	if err := cs.checkVoteExtensionProvider(ctx); err != nil {
+		cs.logger.Error("the application may reject the vote extensions of this node", "err", err)
+	}
+
 	// We may set the WAL in testing before calling Start, so only OpenWAL if its
 	// still the nilWAL.
 	if _, ok := cs.wal.(nilWAL); ok {
@@ -430,20 +890,29 @@ func (cs *State) OnStart(ctx context.Context) error {
 
 	LOOP:
 		for {
+			cs.startReplayPhase()
 			err := cs.catchupReplay(ctx, cs.roundState.Height())
 			switch {
 			case err == nil:
 				break LOOP
 
+			case errors.Is(err, ErrReplayTimeoutMismatch):
+				return err
+
 			case !IsDataCorruptionError(err):
 				cs.logger.Error("error on catchup replay; proceeding to start state anyway", "err", err)
 				break LOOP
 
 			case repairAttempted:
 				return err
+
+			case cs.walRepairFile() == "":
+				cs.logger.Error("the WAL is corrupted and does not support file repair", "err", err)
+				return err
 			}
 
-			cs.logger.Error("the WAL file is corrupted; attempting repair", "err", err)
+			walFile := cs.walRepairFile()
+			cs.logger.Error("the WAL file is corrupted; attempting repair", "file", walFile, "err", err)
 
 			// 1) prep work
 			cs.wal.Stop()
@@ -451,15 +920,15 @@ func (cs *State) OnStart(ctx context.Context) error {
 			repairAttempted = true
 
 			// 2) backup original WAL file
-			corruptedFile := fmt.Sprintf("%s.CORRUPTED", cs.config.WalFile())
-			if err := tmos.CopyFile(cs.config.WalFile(), corruptedFile); err != nil {
+			corruptedFile := fmt.Sprintf("%s.CORRUPTED", walFile)
+			if err := tmos.CopyFile(walFile, corruptedFile); err != nil {
 				return err
 			}
 
-			cs.logger.Debug("backed up WAL file", "src", cs.config.WalFile(), "dst", corruptedFile)
+			cs.logger.Debug("backed up WAL file", "src", walFile, "dst", corruptedFile)
 
 			// 3) try to repair (WAL file will be overwritten!)
-			if err := repairWalFile(corruptedFile, cs.config.WalFile()); err != nil {
+			if err := repairWalFile(corruptedFile, walFile); err != nil {
 				cs.logger.Error("the WAL repair failed", "err", err)
 				return err
 			}
@@ -471,17 +940,35 @@ func (cs *State) OnStart(ctx context.Context) error {
 				return err
 			}
 		}
+		cs.replayFloorHeight = cs.roundState.Height()
 	}
 
 	// Double Signing Risk Reduction
 	if err := cs.checkDoubleSigningRisk(cs.roundState.Height()); err != nil {
 		return err
 	}
+	if err := cs.checkSigningHalt(); err != nil {
+		return err
+	}
+
+	if err := cs.openDecisionLog(ctx); err != nil {
+		return err
+	}
+	if err := cs.openEventRing(); err != nil {
+		return err
+	}
+	cs.startRunningPhase()
+
+	cs.startValidatorMetrics(ctx)
 
 	// now start the receiveRoutine
-	go cs.receiveRoutine(ctx, 0)
+	cs.spawn(func() { cs.receiveRoutine(ctx, 0) })
 	// start heartbeater
-	go cs.heartbeater(ctx)
+	cs.spawn(func() { cs.heartbeater(ctx) })
+	// start watchdog
+	cs.spawn(func() { cs.watchdog(ctx) })
+	// start the recovery of a missing private validator pubkey
+	cs.spawn(func() { cs.recoverPrivValidatorPubKey(ctx) })
 
 	// schedule the first round!
 	// use GetRoundState so we don't race the receiveRoutine for access
@@ -501,12 +988,49 @@ func (cs *State) startRoutines(ctx context.Context, maxSteps int) {
 		return
 	}
 
-	go cs.receiveRoutine(ctx, maxSteps)
+	cs.spawn(func() { cs.receiveRoutine(ctx, maxSteps) })
+}
+
+// spawn runs f in a goroutine OnStop waits for.
+func (cs *State) spawn(f func()) {
+	cs.routines.Add(1)
+	go func() {
+		defer cs.routines.Done()
+		f()
+	}()
 }
 
-// loadWalFile loads WAL data from file. It overwrites cs.wal.
+// stopRoutines cancels the goroutines and services started by OnStart and
+// waits for all the goroutines of the State to exit, for at most
+// stopRoutinesTimeout.
+func (cs *State) stopRoutines() {
+	if cs.cancelRoutines != nil {
+		cs.cancelRoutines()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cs.routines.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(stopRoutinesTimeout):
+		cs.logger.Error("OnStop: timeout waiting for the consensus routines to exit", "time", stopRoutinesTimeout)
+	}
+}
+
+// loadWalFile loads WAL data from file, or from the WAL provider if one was
+// set. It overwrites cs.wal. Opening the WAL is retried while it fails with a
+// transient error.
 func (cs *State) loadWalFile(ctx context.Context) error {
-	wal, err := cs.OpenWAL(ctx, cs.config.WalFile())
+	walFile := cs.config.WalFile()
+	wal, err := cs.openWALWithRetry(ctx, walFile, func() (WAL, error) {
+		if cs.walProvider != nil {
+			return cs.walProvider(ctx, cs.logger.With("wal", walFile), walFile)
+		}
+		return cs.OpenWAL(ctx, walFile)
+	})
 	if err != nil {
 		cs.logger.Error("failed to load state WAL", "err", err)
 		return err
@@ -516,6 +1040,21 @@ func (cs *State) loadWalFile(ctx context.Context) error {
 	return nil
 }
 
+// walRepairFile returns the path of the file of the WAL to repair when it is
+// corrupted; empty if it may not be repaired. The WAL opened by the State is
+// the WAL file of the config, while the WAL of a provider is only repaired if
+// it implements WALFileRepairer, and the WAL set with WithWAL never is.
+func (cs *State) walRepairFile() string {
+	if cs.walProvider == nil {
+		return cs.config.WalFile()
+	}
+	repairer, ok := cs.wal.(WALFileRepairer)
+	if !ok || cs.walFixed {
+		return ""
+	}
+	return repairer.RepairFile()
+}
+
 func (cs *State) getOnStopCh() chan *cstypes.RoundState {
 	cs.mtx.RLock()
 	defer cs.mtx.RUnlock()
@@ -526,21 +1065,54 @@ func (cs *State) getOnStopCh() chan *cstypes.RoundState {
 // OnStop implements service.Service.
 func (cs *State) OnStop() {
 	// If the node is committing a new block, wait until it is finished!
-	if cs.GetRoundState().Step == cstypes.RoundStepCommit {
+	if rs := cs.GetRoundState(); rs.Step == cstypes.RoundStepCommit {
 		cs.mtx.RLock()
 		commitTimeout := cs.state.ConsensusParams.Timeout.Commit
 		cs.mtx.RUnlock()
-		select {
-		case <-cs.getOnStopCh():
-		case <-time.After(commitTimeout):
-			cs.logger.Error("OnStop: timeout waiting for commit to finish", "time", commitTimeout)
-		}
+		cs.waitForCommit(rs.Height, commitTimeout)
 	}
 
+	// WAL is stopped in receiveRoutine.
+	cs.stopRoutines()
+
 	if cs.timeoutTicker.IsRunning() {
 		cs.timeoutTicker.Stop()
 	}
-	// WAL is stopped in receiveRoutine.
+
+	if cs.ownsEventBus && cs.eventBus.IsRunning() {
+		cs.eventBus.Stop()
+	}
+
+	cs.shutdownTracerProvider()
+}
+
+// waitForCommit waits for the commit of height to finish, for at most
+// timeout. The end of the commit is signaled on onStopCh by finalizeCommit;
+// the step is also polled, as the signal is missed if it is sent before the
+// wait, and the State may leave the commit step otherwise.
+func (cs *State) waitForCommit(height int64, timeout time.Duration) {
+	onStopCh := cs.getOnStopCh()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(onStopCommitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case rs := <-onStopCh:
+			// the steps are signaled by the reactor too
+			if rs.Height != height || rs.Step != cstypes.RoundStepCommit {
+				return
+			}
+		case <-ticker.C:
+			if rs := cs.GetRoundState(); rs.Height != height || rs.Step != cstypes.RoundStepCommit {
+				return
+			}
+		case <-timer.C:
+			cs.logger.Error("OnStop: timeout waiting for commit to finish", "time", timeout)
+			return
+		}
+	}
 }
 
 // OpenWAL opens a file to log all consensus messages and timeouts for
@@ -573,14 +1145,14 @@ func (cs *State) AddVote(ctx context.Context, vote *types.Vote, peerID types.Nod
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.internalMsgQueue <- msgInfo{&VoteMessage{vote}, "", tmtime.Now()}:
+		case cs.internalMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: "", ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	} else {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.peerMsgQueue <- msgInfo{&VoteMessage{vote}, peerID, tmtime.Now()}:
+		case cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: peerID, ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	}
@@ -595,14 +1167,14 @@ func (cs *State) SetProposal(ctx context.Context, proposal *types.Proposal, peer
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.internalMsgQueue <- msgInfo{&ProposalMessage{proposal}, "", tmtime.Now()}:
+		case cs.internalMsgQueue <- msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "", ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	} else {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.peerMsgQueue <- msgInfo{&ProposalMessage{proposal}, peerID, tmtime.Now()}:
+		case cs.peerMsgQueue <- msgInfo{Msg: &ProposalMessage{proposal}, PeerID: peerID, ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	}
@@ -616,14 +1188,14 @@ func (cs *State) AddProposalBlockPart(ctx context.Context, height int64, round i
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.internalMsgQueue <- msgInfo{&BlockPartMessage{height, round, part}, "", tmtime.Now()}:
+		case cs.internalMsgQueue <- msgInfo{Msg: &BlockPartMessage{height, round, part}, PeerID: "", ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	} else {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case cs.peerMsgQueue <- msgInfo{&BlockPartMessage{height, round, part}, peerID, tmtime.Now()}:
+		case cs.peerMsgQueue <- msgInfo{Msg: &BlockPartMessage{height, round, part}, PeerID: peerID, ReceiveTime: tmtime.Now()}:
 			return nil
 		}
 	}
@@ -631,7 +1203,8 @@ func (cs *State) AddProposalBlockPart(ctx context.Context, height int64, round i
 	// TODO: wait for event?!
 }
 
-// SetProposalAndBlock inputs the proposal and all block parts.
+// SetProposalAndBlock inputs the proposal and all block parts, as
+// SetProposalAndBlockParts does.
 func (cs *State) SetProposalAndBlock(
 	ctx context.Context,
 	proposal *types.Proposal,
@@ -639,19 +1212,7 @@ func (cs *State) SetProposalAndBlock(
 	parts *types.PartSet,
 	peerID types.NodeID,
 ) error {
-
-	if err := cs.SetProposal(ctx, proposal, peerID); err != nil {
-		return err
-	}
-
-	for i := 0; i < int(parts.Total()); i++ {
-		part := parts.GetPart(i)
-		if err := cs.AddProposalBlockPart(ctx, proposal.Height, proposal.Round, part, peerID); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return cs.SetProposalAndBlockParts(ctx, proposal, parts, peerID)
 }
 
 //------------------------------------------------------------
@@ -688,46 +1249,36 @@ func (cs *State) scheduleTimeout(duration time.Duration, height int64, round int
 	cs.timeoutTicker.ScheduleTimeout(timeoutInfo{duration, height, round, step})
 }
 
-// send a msg into the receiveRoutine regarding our own proposal, block part, or vote
-func (cs *State) sendInternalMessage(ctx context.Context, mi msgInfo) {
-	select {
-	case <-ctx.Done():
-	case cs.internalMsgQueue <- mi:
-	default:
-		// NOTE: using the go-routine means our votes can
-		// be processed out of order.
-		// TODO: use CList here for strict determinism and
-		// attempt push to internalMsgQueue in receiveRoutine
-		cs.logger.Debug("internal msg queue is full; using a go-routine")
-		go func() {
-			select {
-			case <-ctx.Done():
-			case cs.internalMsgQueue <- mi:
-			}
-		}()
-	}
-}
-
 // Reconstruct the LastCommit from either SeenCommit or the ExtendedCommit. SeenCommit
 // and ExtendedCommit are saved along with the block. If VoteExtensions are required
 // the method will panic on an absent ExtendedCommit or an ExtendedCommit without
-// extension data.
+// extension data for +2/3 of the voting power. Precommits whose extension was
+// filtered out by the vote extension retention policy are added without it.
 func (cs *State) reconstructLastCommit(state sm.State) {
-	extensionsEnabled := cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(state.LastBlockHeight)
+	votes, err := cs.loadLastCommit(state)
+	if err != nil {
+		panic(err.Error())
+	}
+	cs.roundState.SetLastCommit(votes)
+}
+
+// loadLastCommit returns the votes of the last commit of state from the block
+// store.
+func (cs *State) loadLastCommit(state sm.State) (*types.VoteSet, error) {
+	extensionsEnabled := state.ConsensusParams.ABCI.VoteExtensionsEnabled(state.LastBlockHeight)
 	if !extensionsEnabled {
 		votes, err := cs.votesFromSeenCommit(state)
 		if err != nil {
-			panic(fmt.Sprintf("failed to reconstruct last commit; %s", err))
+			return nil, fmt.Errorf("failed to reconstruct last commit; %w", err)
 		}
-		cs.roundState.SetLastCommit(votes)
-		return
+		return votes, nil
 	}
 
 	votes, err := cs.votesFromExtendedCommit(state)
 	if err != nil {
-		panic(fmt.Sprintf("failed to reconstruct last extended commit; %s", err))
+		return nil, fmt.Errorf("failed to reconstruct last extended commit; %w", err)
 	}
-	cs.roundState.SetLastCommit(votes)
+	return votes, nil
 }
 
 func (cs *State) votesFromExtendedCommit(state sm.State) (*types.VoteSet, error) {
@@ -735,7 +1286,10 @@ func (cs *State) votesFromExtendedCommit(state sm.State) (*types.VoteSet, error)
 	if ec == nil {
 		return nil, fmt.Errorf("extended commit for height %v not found", state.LastBlockHeight)
 	}
-	vs := ec.ToExtendedVoteSet(state.ChainID, state.LastValidators)
+	if err := ec.EnsureQuorumExtensions(state.LastValidators); err != nil {
+		return nil, err
+	}
+	vs := ec.ToFilteredExtendedVoteSet(state.ChainID, state.LastValidators)
 	if !vs.HasTwoThirdsMajority() {
 		return nil, errors.New("extended commit does not have +2/3 majority")
 	}
@@ -760,7 +1314,13 @@ func (cs *State) votesFromSeenCommit(state sm.State) (*types.VoteSet, error) {
 
 // Updates State and increments height to match that of state.
 // The round becomes 0 and cs.Step becomes cstypes.RoundStepNewHeight.
-func (cs *State) updateToState(state sm.State) {
+// updateToState updates the consensus state to state, received from source.
+// It returns false if the update was ignored because state is not newer than
+// the current state.
+func (cs *State) updateToState(state sm.State, source string) bool {
+	hold := cs.holdSection(lockHoldUpdateToState)
+	defer hold.end()
+
 	if cs.roundState.CommitRound() > -1 && 0 < cs.roundState.Height() && cs.roundState.Height() != state.LastBlockHeight {
 		panic(fmt.Sprintf(
 			"updateToState() expected state height of %v but found %v",
@@ -792,13 +1352,17 @@ func (cs *State) updateToState(state sm.State) {
 		if state.LastBlockHeight <= cs.state.LastBlockHeight {
 			cs.logger.Debug(
 				"ignoring updateToState()",
+				"source", source,
 				"new_height", state.LastBlockHeight+1,
 				"old_height", cs.state.LastBlockHeight+1,
 			)
-			cs.newStep()
-			return
+			cs.recordIgnoredStateUpdate(source, "state not newer",
+				cs.state.LastBlockHeight+1, state.LastBlockHeight+1)
+			cs.newStep("")
+			return false
 		}
 	}
+	cs.consecutiveIgnoredStateUpdates = 0
 
 	// Reset fields based on state.
 	validators := state.Validators
@@ -833,7 +1397,14 @@ func (cs *State) updateToState(state sm.State) {
 
 	// RoundState fields
 	cs.updateHeight(height)
+	cs.blockPartGossip.reset(height)
+	cs.resetVoteExtensionMemory(height)
+	cs.blockValidations.reset(state)
+	cs.lateProposal = nil
+	cs.peerStats.reset()
+	cs.precommitSources.prune(height)
 	cs.updateRoundStep(0, cstypes.RoundStepNewHeight)
+	cs.newHeightStart = tmtime.Now()
 
 	if cs.roundState.CommitTime().IsZero() {
 		// "Now" makes it easier to sync up dev nodes.
@@ -849,14 +1420,17 @@ func (cs *State) updateToState(state sm.State) {
 	cs.roundState.SetValidators(validators)
 	cs.roundState.SetProposal(nil)
 	cs.roundState.SetProposalReceiveTime(time.Time{})
+	cs.proposalTxKeys.reset()
 	cs.roundState.SetProposalBlock(nil)
 	cs.roundState.SetProposalBlockParts(nil)
+	cs.resetBlockPartsMetrics()
 	cs.roundState.SetLockedRound(-1)
 	cs.roundState.SetLockedBlock(nil)
 	cs.roundState.SetLockedBlockParts(nil)
 	cs.roundState.SetValidRound(-1)
 	cs.roundState.SetValidBlock(nil)
 	cs.roundState.SetValidBlockParts(nil)
+	cs.clearAwaitingPOL()
 	if state.ConsensusParams.ABCI.VoteExtensionsEnabled(height) {
 		cs.roundState.SetVotes(cstypes.NewExtendedHeightVoteSet(state.ChainID, height, validators))
 	} else {
@@ -864,27 +1438,36 @@ func (cs *State) updateToState(state sm.State) {
 	}
 	cs.roundState.SetCommitRound(-1)
 	cs.roundState.SetLastValidators(state.LastValidators)
-	cs.roundState.SetTriggeredTimeoutPrecommit(false)
+	cs.roundState.SetTriggeredTimeoutPrecommitRound(-1)
 
 	cs.state = state
+	cs.lastApplied.set(state.LastBlockHeight, state.AppHash)
+	cs.updateValidatorSetDiff(height, state)
+	cs.updateValidatorMembership(height, state)
+	cs.updateTimeoutScale(validators.Size())
 
 	// Finally, broadcast RoundState
-	cs.newStep()
+	cs.newStep("")
+	return true
 }
 
-func (cs *State) newStep() {
+// newStep broadcasts the step entered because of entryLabel.
+func (cs *State) newStep(entryLabel string) {
 	rs := cs.roundState.RoundStateEvent()
-	if err := cs.wal.Write(rs); err != nil {
+	if err := cs.walWrite(FaultPointNewStep, rs); err != nil {
 		cs.logger.Error("failed writing to WAL", "err", err)
 	}
+	rs.Entry = entryLabel
 
 	cs.nSteps++
+	cs.recordStep()
 
 	// newStep is called by updateToState in NewState before the eventBus is set!
 	if cs.eventBus != nil {
-		if err := cs.eventBus.PublishEventNewRoundStep(rs); err != nil {
+		if err := cs.publishEvent(types.EventNewRoundStepValue, rs); err != nil {
 			cs.logger.Error("failed publishing new round step", "err", err)
 		}
+		cs.recordRingEvent(EventRingRecord{Kind: EventRingNewRoundStep})
 
 		roundState := cs.roundState.CopyInternal()
 		cs.evsw.FireEvent(types.EventNewRoundStepValue, roundState)
@@ -907,6 +1490,82 @@ func (cs *State) fireHeartbeatEvent() {
 	cs.evsw.FireEvent(types.EventNewRoundStepValue, roundState)
 }
 
+// watchdog reports the receive routine as stalled if it has not made
+// progress for WatchdogTimeout while messages are waiting in its queues. A
+// stall is reported once until the receive routine makes progress again.
+func (cs *State) watchdog(ctx context.Context) {
+	timeout := cs.config.WatchdogTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	var (
+		lastSeen     int64
+		stalledSince time.Time
+		reported     bool
+	)
+	for {
+		select {
+		case now := <-ticker.C:
+			last := cs.lastActivity.Load()
+			peerMsgs, internalMsgs := len(cs.peerMsgQueue), len(cs.internalMsgQueue)
+			if last != lastSeen || peerMsgs+internalMsgs == 0 {
+				lastSeen = last
+				stalledSince = now
+				reported = false
+				continue
+			}
+			if !reported && now.Sub(stalledSince) >= timeout {
+				reported = true
+				cs.reportStall(time.Unix(0, last), peerMsgs, internalMsgs)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (cs *State) reportStall(lastActivity time.Time, peerMsgs, internalMsgs int) {
+	height, round, step := cs.roundState.Height(), cs.roundState.Round(), cs.roundState.Step()
+
+	cs.logger.Error("CONSENSUS STALLED: receive routine is not processing messages",
+		"height", height,
+		"round", round,
+		"step", step,
+		"last_activity", lastActivity,
+		"peer_msg_queue_size", peerMsgs,
+		"internal_msg_queue_size", internalMsgs,
+		"goroutines", string(goroutineDump()),
+	)
+	cs.metrics.ReceiveRoutineStalls.Add(1)
+
+	if err := cs.publishEvent(types.EventConsensusStalledValue, types.EventDataConsensusStalled{
+		Height:               height,
+		Round:                round,
+		Step:                 step.String(),
+		LastActivity:         lastActivity,
+		PeerMsgQueueSize:     peerMsgs,
+		InternalMsgQueueSize: internalMsgs,
+	}); err != nil {
+		cs.logger.Error("failed publishing consensus stalled event", "err", err)
+	}
+}
+
+// goroutineDump returns the stack traces of all goroutines.
+func goroutineDump() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
 //-----------------------------------------
 // the main go routines
 
@@ -924,6 +1583,9 @@ func (cs *State) receiveRoutine(ctx context.Context, maxSteps int) {
 		// close wal now that we're done writing to it
 		cs.wal.Stop()
 		cs.wal.Wait()
+		cs.closeDecisionLog()
+		cs.closeEventRing()
+		cs.closeSignRequestLog()
 	}
 
 	defer func() {
@@ -970,6 +1632,8 @@ func (cs *State) receiveRoutine(ctx context.Context, maxSteps int) {
 	}()
 
 	for {
+		cs.lastActivity.Store(time.Now().UnixNano())
+
 		if maxSteps > 0 {
 			if cs.nSteps >= maxSteps {
 				cs.logger.Debug("reached max steps; exiting receive routine")
@@ -978,20 +1642,36 @@ func (cs *State) receiveRoutine(ctx context.Context, maxSteps int) {
 			}
 		}
 
+		// Peer messages are not processed while the step budget is exceeded.
+		peerMsgQueue, throttle := cs.peerMsgQueueOrThrottle()
+		var throttleC <-chan time.Time
+		if throttle != nil {
+			throttleC = throttle.C
+		}
+
 		select {
 		case <-cs.txNotifier.TxsAvailable():
 			cs.handleTxsAvailable(ctx)
 
-		case mi := <-cs.peerMsgQueue:
-			if err := cs.wal.Write(mi); err != nil {
+		case <-throttleC:
+			// the throttling of peer messages is over
+
+		case mi := <-peerMsgQueue:
+			cs.recordPeerMsg(mi, peerMsgReceived)
+			if cs.belowReplayFloor(mi) {
+				break
+			}
+			if err := cs.walWrite(FaultPointPeerMsg, mi); err != nil {
 				cs.logger.Error("failed writing to WAL", "err", err)
+			} else {
+				cs.recordPeerMsg(mi, peerMsgWALWritten)
 			}
 			// handles proposals, block parts, votes
 			// may generate internal events (votes, complete proposals, 2/3 majorities)
 			cs.handleMsg(ctx, mi, false)
 
 		case mi := <-cs.internalMsgQueue:
-			err := cs.wal.Write(mi)
+			err := cs.walWrite(FaultPointInternalMsg, mi)
 			if err != nil {
 				panic(fmt.Errorf(
 					"failed to write %v msg to consensus WAL due to %w; check your file system and restart the node",
@@ -1003,26 +1683,35 @@ func (cs *State) receiveRoutine(ctx context.Context, maxSteps int) {
 			cs.handleMsg(ctx, mi, true)
 
 		case ti := <-cs.timeoutTicker.Chan(): // tockChan:
-			if err := cs.wal.Write(ti); err != nil {
-				cs.logger.Error("failed writing to WAL", "err", err)
+			if err := ti.ValidateBasic(); err != nil {
+				cs.logger.Error("dropping invalid timeout", "timeout", ti.String(), "err", err)
+				break
 			}
+			for i := cs.faultInjector.TimeoutDeliveries(ti.Height, ti.Round, ti.Step); i > 0; i-- {
+				if err := cs.walWrite(FaultPointTimeout, ti); err != nil {
+					cs.logger.Error("failed writing to WAL", "err", err)
+				}
 
-			// if the timeout is relevant to the rs
-			// go to the next step
-			cs.handleTimeout(ctx, ti, *cs.roundState.CopyInternal())
+				// if the timeout is relevant to the rs
+				// go to the next step
+				cs.handleTimeout(ctx, ti, *cs.roundState.CopyInternal())
+			}
 
 		case <-ctx.Done():
 			onExit(cs)
 			return
 
 		}
+		if throttle != nil {
+			throttle.Stop()
+		}
 		// TODO should we handle context cancels here?
 	}
 }
 func (cs *State) fsyncAndCompleteProposal(ctx context.Context, fsyncUponCompletion bool, height int64, span otrace.Span, onPropose bool) {
 	cs.metrics.ProposalBlockCreatedOnPropose.With("success", strconv.FormatBool(onPropose)).Add(1)
 	if fsyncUponCompletion {
-		if err := cs.wal.FlushAndSync(); err != nil { // fsync
+		if err := cs.walFlushAndSync(FaultPointCompleteProposal); err != nil { // fsync
 			cs.logger.Error("Error flushing wal after receiving all block parts", "error", err)
 		}
 	}
@@ -1032,13 +1721,26 @@ func (cs *State) fsyncAndCompleteProposal(ctx context.Context, fsyncUponCompleti
 
 // We only used tx key based dissemination if configured to do so and we are a validator
 func (cs *State) gossipTransactionKeyOnly() bool {
-	return cs.config.GossipTransactionKeyOnly && cs.privValidatorPubKey != nil
+	return cs.config.GossipTransactionKeyOnly && cs.getPrivValidatorPubKey() != nil
 }
 
 // state transitions on complete-proposal, 2/3-any, 2/3-one
+// sendStats forwards a message that was added to the round state to the
+// reactor for peer statistics. It never blocks: if the reactor does not keep
+// up, the message is dropped rather than halting the state machine.
+func (cs *State) sendStats(mi msgInfo) {
+	select {
+	case cs.statsMsgQueue <- mi:
+	default:
+		cs.metrics.StatsMsgsDropped.Add(1)
+	}
+}
+
 func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion bool) {
-	cs.mtx.Lock()
-	defer cs.mtx.Unlock()
+	// the deferred unlock is bound to the variable, which the block part
+	// handling resets when it yields the lock
+	hold := cs.lockFor(lockHoldMsgOp(mi.Msg))
+	defer hold.unlock()
 	var (
 		added bool
 		err   error
@@ -1050,15 +1752,26 @@ func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion
 
 	switch msg := msg.(type) {
 	case *ProposalMessage:
-		spanCtx, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.handleProposalMsg")
-		span.SetAttributes(attribute.Int("round", int(msg.Proposal.Round)))
+		spanCtx, span := cs.startMsgSpan(ctx, "cs.state.handleProposalMsg", msg.Proposal.Height, msg.Proposal.Round)
 		defer span.End()
 
 		// will not cause transition.
 		// once proposal is set, we can receive block parts
-		if err = cs.setProposal(msg.Proposal, mi.ReceiveTime); err == nil {
-			if cs.gossipTransactionKeyOnly() {
-				isProposer := cs.isProposer(cs.privValidatorPubKey.Address())
+		err = cs.classifyProposalRejection(peerID, cs.setProposal(msg.Proposal, peerID, mi.ReceiveTime))
+		cs.logProposalDecision(msg.Proposal, peerID, err)
+		added = err == nil && (cs.roundState.Proposal() == msg.Proposal ||
+			cs.lateProposal != nil && cs.lateProposal.proposal == msg.Proposal)
+		if err == nil {
+			cs.checkProposalPOL()
+			if peerID == "" {
+				cs.markProposalProcessed(msg.Proposal)
+			}
+			if cs.roundState.Proposal() == msg.Proposal && cs.isBlacklistedProposal() {
+				// no need to wait for the block, we prevote nil anyway
+				cs.enterPrevote(ctx, msg.Proposal.Height, msg.Proposal.Round, "blacklistedProposer")
+			} else if cs.roundState.Proposal() == msg.Proposal && cs.gossipTransactionKeyOnly() {
+				pubKey := cs.getPrivValidatorPubKey()
+				isProposer := pubKey != nil && cs.isProposer(pubKey.Address())
 				if !isProposer && cs.roundState.ProposalBlock() == nil {
 					created := cs.tryCreateProposalBlock(spanCtx, msg.Proposal.Height, msg.Proposal.Round, msg.Proposal.Header, msg.Proposal.LastCommit, msg.Proposal.Evidence, msg.Proposal.ProposerAddress)
 					if created {
@@ -1069,19 +1782,24 @@ func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion
 		}
 
 	case *BlockPartMessage:
+		if handled, lateAdded, lateErr := cs.addLateProposalBlockPart(msg); handled {
+			added, err = lateAdded, lateErr
+			break
+		}
 		// If we have already created block parts, we can exit early if block part matches
 		if cs.config.GossipTransactionKeyOnly && cs.roundState.Proposal() != nil && cs.roundState.ProposalBlockParts() != nil {
 			// Check hash proof matches. If so, we can return
 			if msg.Part.Proof.Verify(cs.roundState.ProposalBlockParts().Hash(), msg.Part.Bytes) != nil {
+				cs.recordBlockPart(peerID, msg.Height, msg.Part, true)
+				cs.recordPeerMsg(mi, peerMsgDuplicate)
 				return
 			}
 		}
-		_, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.handleBlockPartMsg")
-		span.SetAttributes(attribute.Int("round", int(msg.Round)))
+		_, span := cs.startMsgSpan(ctx, "cs.state.handleBlockPartMsg", msg.Height, msg.Round)
 		defer span.End()
 
 		// if the proposal is complete, we'll enterPrevote or tryFinalizeCommit
-		added, err = cs.addProposalBlockPart(msg, peerID)
+		added, err = cs.addProposalBlockPart(msg, peerID, mi.ReceiveTime)
 		// We unlock here to yield to any routines that need to read the the RoundState.
 		// Previously, this code held the lock from the point at which the final block
 		// part was received until the block executed against the application.
@@ -1093,22 +1811,18 @@ func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion
 		// of RoundState and only locking when switching out State's copy of
 		// RoundState with the updated copy or by emitting RoundState events in
 		// more places for routines depending on it to listen for.
-		cs.mtx.Unlock()
+		hold.unlock()
 
-		cs.mtx.Lock()
+		hold = cs.lockFor(lockHoldMsgOp(msg))
 		if added && cs.roundState.ProposalBlockParts().IsComplete() {
 			cs.fsyncAndCompleteProposal(ctx, fsyncUponCompletion, msg.Height, span, false)
 		}
 		if added {
-			select {
-			case cs.statsMsgQueue <- mi:
-			case <-ctx.Done():
-				return
-			}
+			cs.sendStats(mi)
 		}
 
 		if err != nil && msg.Round != cs.roundState.Round() {
-			cs.logger.Debug(
+			cs.logMsgDebug(msgDebugLogBlockPartWrongRound,
 				"received block part from wrong round",
 				"height", cs.roundState.Height(),
 				"cs_round", cs.roundState.Round(),
@@ -1116,23 +1830,24 @@ func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion
 			)
 			err = nil
 		} else if err != nil {
-			cs.logger.Debug("added block part but received error", "error", err, "height", cs.roundState.Height(), "cs_round", cs.roundState.Round(), "block_round", msg.Round)
+			cs.logMsgDebug(msgDebugLogBlockPartError, "added block part but received error", "error", err, "height", cs.roundState.Height(), "cs_round", cs.roundState.Round(), "block_round", msg.Round)
 		}
 
+	case *ProposalAndBlockPartsMessage:
+		_, span := cs.startMsgSpan(ctx, "cs.state.handleProposalAndBlockPartsMsg", msg.Proposal.Height, msg.Proposal.Round)
+		defer span.End()
+
+		added, err = cs.handleProposalAndBlockParts(ctx, msg, mi, fsyncUponCompletion, span)
+
 	case *VoteMessage:
-		_, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.handleVoteMsg")
-		span.SetAttributes(attribute.Int("round", int(msg.Vote.Round)))
+		_, span := cs.startMsgSpan(ctx, "cs.state.handleVoteMsg", msg.Vote.Height, msg.Vote.Round)
 		defer span.End()
 
 		// attempt to add the vote and dupeout the validator if its a duplicate signature
 		// if the vote gives us a 2/3-any or 2/3-one, we transition
-		added, err = cs.tryAddVote(ctx, msg.Vote, peerID, span)
+		added, err = cs.tryAddVote(ctx, msg.Vote, peerID, mi.Verification, span)
 		if added {
-			select {
-			case cs.statsMsgQueue <- mi:
-			case <-ctx.Done():
-				return
-			}
+			cs.sendStats(mi)
 		}
 
 		// TODO: punish peer
@@ -1153,7 +1868,17 @@ func (cs *State) handleMsg(ctx context.Context, mi msgInfo, fsyncUponCompletion
 		return
 	}
 
+	switch {
+	case err != nil:
+		cs.recordPeerMsg(mi, peerMsgInvalid)
+	case added:
+		cs.recordPeerMsg(mi, peerMsgAdded)
+	default:
+		cs.recordPeerMsg(mi, peerMsgDuplicate)
+	}
+
 	if err != nil {
+		cs.recordHandleMsgError(msg, peerID, err)
 		cs.logger.Error(
 			"failed to process message",
 			"height", cs.roundState.Height(),
@@ -1178,10 +1903,16 @@ func (cs *State) handleTimeout(
 		cs.logger.Debug("ignoring tock because we are ahead", "height", rs.Height, "round", rs.Round, "step", rs.Step)
 		return
 	}
+	// timeouts are only scheduled for the current round, so one for a later
+	// round can only come from a corrupted WAL
+	if ti.Round > rs.Round {
+		cs.logger.Error("ignoring tock for a round we have not entered", "timeout", ti.String(), "round", rs.Round)
+		return
+	}
 
 	// the timeout will now cause a state transition
-	cs.mtx.Lock()
-	defer cs.mtx.Unlock()
+	hold := cs.lockFor(lockHoldTimeout)
+	defer hold.unlock()
 	cs.metrics.MarkStepLatency(rs.Step)
 
 	switch ti.Step {
@@ -1194,21 +1925,25 @@ func (cs *State) handleTimeout(
 		cs.enterPropose(ctx, ti.Height, 0, "timeout")
 
 	case cstypes.RoundStepPropose:
-		if err := cs.eventBus.PublishEventTimeoutPropose(cs.roundState.RoundStateEvent()); err != nil {
+		// the proposer prevoted for a block: wait a little longer for it
+		if cs.extendProposeTimeout(ti) {
+			return
+		}
+		if err := cs.publishEvent(types.EventTimeoutProposeValue, cs.roundState.RoundStateEvent()); err != nil {
 			cs.logger.Error("failed publishing timeout propose", "err", err)
 		}
 
 		cs.enterPrevote(ctx, ti.Height, ti.Round, "timeout")
 
 	case cstypes.RoundStepPrevoteWait:
-		if err := cs.eventBus.PublishEventTimeoutWait(cs.roundState.RoundStateEvent()); err != nil {
+		if err := cs.publishEvent(types.EventTimeoutWaitValue, cs.roundState.RoundStateEvent()); err != nil {
 			cs.logger.Error("failed publishing timeout wait", "err", err)
 		}
 
 		cs.enterPrecommit(ctx, ti.Height, ti.Round, "timeout")
 
 	case cstypes.RoundStepPrecommitWait:
-		if err := cs.eventBus.PublishEventTimeoutWait(cs.roundState.RoundStateEvent()); err != nil {
+		if err := cs.publishEvent(types.EventTimeoutWaitValue, cs.roundState.RoundStateEvent()); err != nil {
 			cs.logger.Error("failed publishing timeout wait", "err", err)
 		}
 
@@ -1223,8 +1958,8 @@ func (cs *State) handleTimeout(
 }
 
 func (cs *State) handleTxsAvailable(ctx context.Context) {
-	cs.mtx.Lock()
-	defer cs.mtx.Unlock()
+	hold := cs.lockFor(lockHoldTxsAvailable)
+	defer hold.unlock()
 
 	// We only need to do this for round 0.
 	if cs.roundState.Round() != 0 {
@@ -1269,17 +2004,8 @@ func (cs *State) getTracingCtx(defaultCtx context.Context) context.Context {
 // NOTE: cs.StartTime was already set for height.
 func (cs *State) enterNewRound(ctx context.Context, height int64, round int32, entryLabel string) {
 	if height > cs.heightBeingTraced {
-		if cs.heightSpan != nil {
-			cs.heightSpan.End()
-		}
-		cs.heightBeingTraced = height
-		cs.tracingCtx, cs.heightSpan = cs.tracer.Start(ctx, "cs.state.Height")
-		cs.heightSpan.SetAttributes(attribute.Int64("height", height))
+		cs.startHeightSpan(ctx, height)
 	}
-	_, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.enterNewRound")
-	span.SetAttributes(attribute.Int("round", int(round)))
-	span.SetAttributes(attribute.String("entry", entryLabel))
-	defer span.End()
 
 	// TODO: remove panics in this function and return an error
 
@@ -1293,11 +2019,18 @@ func (cs *State) enterNewRound(ctx context.Context, height int64, round int32, e
 		return
 	}
 
+	cs.startRoundSpan(round)
+	_, span := cs.startSpan(cs.getRoundTracingCtx(ctx), "cs.state.enterNewRound",
+		attribute.Int("round", int(round)), attribute.String("entry", entryLabel))
+	defer span.End()
+
 	if now := tmtime.Now(); cs.roundState.StartTime().After(now) {
 		logger.Debug("need to set a buffer and log message here for sanity", "start_time", cs.roundState.StartTime(), "now", now)
 	}
 
 	logger.Debug("entering new round", "current", fmt.Sprintf("%v/%v/%v", cs.roundState.Height(), cs.roundState.Round(), cs.roundState.Step()))
+	cs.startAbsenteeRound(height)
+	cs.logDecision(DecisionRecord{Height: height, Round: round, Kind: DecisionNewRound, Reason: entryLabel})
 
 	// increment validators if necessary
 	validators := cs.roundState.Validators()
@@ -1313,6 +2046,7 @@ func (cs *State) enterNewRound(ctx context.Context, height int64, round int32, e
 	// Setup new round
 	// we don't fire newStep for this step,
 	// but we fire an event, so update the round step first
+	entry := cs.recordTransition(height, round, cstypes.RoundStepNewRound, entryLabel)
 	cs.updateRoundStep(round, cstypes.RoundStepNewRound)
 	cs.roundState.SetValidators(validators)
 	if round == 0 {
@@ -1325,6 +2059,9 @@ func (cs *State) enterNewRound(ctx context.Context, height int64, round int32, e
 		cs.roundState.SetProposalReceiveTime(time.Time{})
 		cs.roundState.SetProposalBlock(nil)
 		cs.roundState.SetProposalBlockParts(nil)
+		cs.resetBlockPartsMetrics()
+		cs.proposalTxKeys.reset()
+		cs.clearAwaitingPOL()
 	}
 
 	r, err := tmmath.SafeAddInt32(round, 1)
@@ -1333,9 +2070,11 @@ func (cs *State) enterNewRound(ctx context.Context, height int64, round int32, e
 	}
 
 	cs.roundState.Votes().SetRound(r) // also track next round (round+1) to allow round-skipping
-	cs.roundState.SetTriggeredTimeoutPrecommit(false)
+	cs.roundState.SetTriggeredTimeoutPrecommitRound(-1)
 
-	if err := cs.eventBus.PublishEventNewRound(cs.roundState.NewRoundEvent()); err != nil {
+	newRound := cs.roundState.NewRoundEvent()
+	newRound.Entry = entry
+	if err := cs.publishEvent(types.EventNewRoundValue, newRound); err != nil {
 		cs.logger.Error("failed publishing new round", "err", err)
 	}
 	// Wait for txs to be available in the mempool
@@ -1378,9 +2117,8 @@ func (cs *State) needProofBlock(height int64) bool {
 //
 // Enter (!CreateEmptyBlocks) : after enterNewRound(height,round), once txs are in the mempool
 func (cs *State) enterPropose(ctx context.Context, height int64, round int32, entryLabel string) {
-	spanCtx, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.enterPropose")
-	span.SetAttributes(attribute.Int("round", int(round)))
-	span.SetAttributes(attribute.String("entry", entryLabel))
+	spanCtx, span := cs.startSpan(cs.getRoundTracingCtx(ctx), "cs.state.enterPropose",
+		attribute.Int("round", int(round)), attribute.String("entry", entryLabel))
 	defer span.End()
 
 	logger := cs.logger.With("height", height, "round", round)
@@ -1395,9 +2133,10 @@ func (cs *State) enterPropose(ctx context.Context, height int64, round int32, en
 
 	// If this validator is the proposer of this round, and the previous block time is later than
 	// our local clock time, wait to propose until our local clock time has passed the block time.
-	if cs.privValidatorPubKey != nil && cs.isProposer(cs.privValidatorPubKey.Address()) {
-		proposerWaitTime := proposerWaitTime(tmtime.DefaultSource{}, cs.state.LastBlockTime)
+	if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil && cs.isProposer(pubKey.Address()) {
+		proposerWaitTime := proposerWaitTime(cs.clock, cs.state.LastBlockTime)
 		if proposerWaitTime > 0 {
+			cs.recordProposerWait(span, height, round, proposerWaitTime)
 			cs.scheduleTimeout(proposerWaitTime, height, round, cstypes.RoundStepNewRound)
 			return
 		}
@@ -1407,8 +2146,9 @@ func (cs *State) enterPropose(ctx context.Context, height int64, round int32, en
 
 	defer func() {
 		// Done enterPropose:
+		entry := cs.recordTransition(height, round, cstypes.RoundStepPropose, entryLabel)
 		cs.updateRoundStep(round, cstypes.RoundStepPropose)
-		cs.newStep()
+		cs.newStep(entry)
 
 		// If we have the whole proposal + POL, then goto Prevote now.
 		// else, we'll enterPrevote when the rest of the proposal is received (in AddProposalBlockPart),
@@ -1421,31 +2161,38 @@ func (cs *State) enterPropose(ctx context.Context, height int64, round int32, en
 	}()
 
 	// If we don't get the proposal and all block parts quick enough, enterPrevote
+	cs.proposeDeadline = time.Now().Add(cs.proposeTimeout(round))
 	cs.scheduleTimeout(cs.proposeTimeout(round), height, round, cstypes.RoundStepPropose)
 
 	// Nothing more to do if we're not a validator
-	if cs.privValidator == nil {
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator == nil {
 		logger.Debug("propose step; not proposing since node is not a validator")
 		return
 	}
 
-	if cs.privValidatorPubKey == nil {
+	if pubKey == nil {
 		// If this node is a validator & proposer in the current round, it will
 		// miss the opportunity to create a block.
 		logger.Error("propose step; empty priv validator public key", "err", errPubKeyIsNotSet)
 		return
 	}
 
-	addr := cs.privValidatorPubKey.Address()
+	addr := pubKey.Address()
 
 	// if not a validator, we're done
 	if !cs.roundState.Validators().HasAddress(addr) {
-		logger.Debug("propose step; not proposing since node is not in the validator set",
+		cs.notValidatorLogger(logger)("propose step; not proposing since node is not in the validator set",
 			"addr", addr,
 			"vals", cs.roundState.Validators())
 		return
 	}
 
+	if cs.SigningHalted() {
+		logger.Debug("propose step; not proposing since signing is halted")
+		return
+	}
+
 	if cs.isProposer(addr) {
 		logger.Debug(
 			"propose step; our turn to propose",
@@ -1466,8 +2213,7 @@ func (cs *State) isProposer(address []byte) bool {
 }
 
 func (cs *State) defaultDecideProposal(ctx context.Context, height int64, round int32) {
-	_, span := cs.tracer.Start(ctx, "cs.state.decideProposal")
-	span.SetAttributes(attribute.Int("round", int(round)))
+	_, span := cs.startSpan(ctx, "cs.state.decideProposal", attribute.Int("round", int(round)))
 	defer span.End()
 
 	var block *types.Block
@@ -1477,6 +2223,9 @@ func (cs *State) defaultDecideProposal(ctx context.Context, height int64, round
 	if cs.roundState.ValidBlock() != nil {
 		// If there is valid block, choose that.
 		block, blockParts = cs.roundState.ValidBlock(), cs.roundState.ValidBlockParts()
+	} else if candidate, candidateParts := cs.proposalCandidateFor(ctx, height); candidate != nil {
+		// Propose the block submitted with SubmitProposalCandidate.
+		block, blockParts = candidate, candidateParts
 	} else {
 		// Create a new proposal block from state/txs from the mempool.
 		var err error
@@ -1488,7 +2237,9 @@ func (cs *State) defaultDecideProposal(ctx context.Context, height int64, round
 			return
 		}
 		cs.metrics.ProposalCreateCount.Add(1)
-		blockParts, err = block.MakePartSet(types.BlockPartSizeBytes)
+		cs.metrics.ProposalEvidenceBytes.Set(float64(block.Evidence.ByteSize()))
+		cs.metrics.ProposalEvidenceCount.Set(float64(len(block.Evidence)))
+		blockParts, err = cs.makeProposalBlockParts(block)
 		if err != nil {
 			cs.logger.Error("unable to create proposal block part set", "error", err)
 			return
@@ -1497,31 +2248,42 @@ func (cs *State) defaultDecideProposal(ctx context.Context, height int64, round
 
 	// Flush the WAL. Otherwise, we may not recompute the same proposal to sign,
 	// and the privValidator will refuse to sign anything.
-	if err := cs.wal.FlushAndSync(); err != nil {
+	if err := cs.walFlushAndSync(FaultPointSignProposal); err != nil {
 		cs.logger.Error("failed flushing WAL to disk")
 	}
 
 	// Make proposal
+	pubKey := cs.getPrivValidatorPubKey()
+	if pubKey == nil {
+		cs.logger.Error("propose step; empty priv validator public key", "err", errPubKeyIsNotSet)
+		return
+	}
 	propBlockID := types.BlockID{Hash: block.Hash(), PartSetHeader: blockParts.Header()}
-	proposal := types.NewProposal(height, round, cs.roundState.ValidRound(), propBlockID, block.Header.Time, block.GetTxKeys(), block.Header, block.LastCommit, block.Evidence, cs.privValidatorPubKey.Address())
+	proposal := types.NewProposal(height, round, cs.roundState.ValidRound(), propBlockID, block.Header.Time, block.GetTxKeys(), block.Header, block.LastCommit, block.Evidence, pubKey.Address())
 	p := proposal.ToProto()
 
 	// wait the max amount we would wait for a proposal
 	ctxto, cancel := context.WithTimeout(ctx, cs.state.ConsensusParams.Timeout.Propose)
 	defer cancel()
-	if err := cs.privValidator.SignProposal(ctxto, cs.state.ChainID, p); err == nil {
+	err := cs.signProposal(ctxto, p)
+	if err == nil {
 		proposal.Signature = p.Signature
+		cs.markProposalSigned(proposal)
 
 		// send proposal and block parts on internal msg queue
-		cs.sendInternalMessage(ctx, msgInfo{&ProposalMessage{proposal}, "", tmtime.Now()})
+		cs.sendInternalMessage(ctx, msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "", ReceiveTime: tmtime.Now()})
 
 		for i := 0; i < int(blockParts.Total()); i++ {
 			part := blockParts.GetPart(i)
-			cs.sendInternalMessage(ctx, msgInfo{&BlockPartMessage{cs.roundState.Height(), cs.roundState.Round(), part}, "", tmtime.Now()})
+			cs.sendInternalMessage(ctx, msgInfo{Msg: &BlockPartMessage{cs.roundState.Height(), cs.roundState.Round(), part}, PeerID: "", ReceiveTime: tmtime.Now()})
 		}
 
 		cs.logger.Debug("signed proposal", "height", height, "round", round, "proposal", proposal)
 	} else if !cs.replayMode {
+		if privval.IsConflictingDataError(err) {
+			cs.recordDoubleSignRefusal(height, round, err)
+			return
+		}
 		cs.logger.Error("propose step; failed signing proposal", "height", height, "round", round, "err", err)
 	}
 }
@@ -1550,7 +2312,8 @@ func (cs *State) isProposalComplete() bool {
 // NOTE: keep it side-effect free for clarity.
 // CONTRACT: cs.privValidator is not nil.
 func (cs *State) createProposalBlock(ctx context.Context) (*types.Block, error) {
-	if cs.privValidator == nil {
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator == nil {
 		return nil, errors.New("entered createProposalBlock with privValidator being nil")
 	}
 
@@ -1571,19 +2334,36 @@ func (cs *State) createProposalBlock(ctx context.Context) (*types.Block, error)
 		return nil, nil
 	}
 
-	if cs.privValidatorPubKey == nil {
+	if pubKey == nil {
 		// If this node is a validator & proposer in the current round, it will
 		// miss the opportunity to create a block.
 		cs.logger.Error("propose step; empty priv validator public key", "err", errPubKeyIsNotSet)
 		return nil, nil
 	}
 
-	proposerAddr := cs.privValidatorPubKey.Address()
+	proposerAddr := pubKey.Address()
 
-	ret, err := cs.blockExec.CreateProposalBlock(ctx, cs.roundState.Height(), cs.state, lastExtCommit, proposerAddr)
+	// the evidence is limited to a fraction of the block, for the txs
+	maxEvidenceBytes := cs.state.ConsensusParams.Evidence.MaxBytes
+	if fraction := cs.config.MaxProposalEvidenceFraction; fraction > 0 {
+		ceiling := int64(fraction * float64(cs.state.ConsensusParams.Block.MaxBytes))
+		if ceiling < maxEvidenceBytes {
+			maxEvidenceBytes = ceiling
+		}
+	}
+	ret, trimmed, err := cs.blockExec.CreateProposalBlockWithEvidenceLimit(ctx, cs.roundState.Height(), cs.state,
+		lastExtCommit, proposerAddr, maxEvidenceBytes)
 	if err != nil {
 		panic(err)
 	}
+	if trimmed > 0 {
+		cs.metrics.ProposalEvidenceTrimmed.Add(1)
+		cs.logger.Info("trimmed the evidence of the proposal block to its ceiling",
+			"height", cs.roundState.Height(),
+			"max_evidence_bytes", maxEvidenceBytes,
+			"trimmed_bytes", trimmed,
+		)
+	}
 	return ret, nil
 }
 
@@ -1595,9 +2375,8 @@ func (cs *State) createProposalBlock(ctx context.Context) (*types.Block, error)
 // locked on or matches a block that received a POL in a round later than our
 // locked round, prevote for the proposal, otherwise vote nil.
 func (cs *State) enterPrevote(ctx context.Context, height int64, round int32, entryLabel string) {
-	_, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.enterPrevote")
-	span.SetAttributes(attribute.Int("round", int(round)))
-	span.SetAttributes(attribute.String("entry", entryLabel))
+	_, span := cs.startSpan(cs.getRoundTracingCtx(ctx), "cs.state.enterPrevote",
+		attribute.Int("round", int(round)), attribute.String("entry", entryLabel))
 	defer span.End()
 
 	logger := cs.logger.With("height", height, "round", round)
@@ -1613,10 +2392,13 @@ func (cs *State) enterPrevote(ctx context.Context, height int64, round int32, en
 
 	defer func() {
 		// Done enterPrevote:
+		entry := cs.recordTransition(height, round, cstypes.RoundStepPrevote, entryLabel)
 		cs.updateRoundStep(round, cstypes.RoundStepPrevote)
-		cs.newStep()
+		cs.newStep(entry)
 	}()
 
+	cs.endProposeGrace()
+
 	logger.Debug("entering prevote step", "current", fmt.Sprintf("%v/%v/%v", cs.roundState.Height(), cs.roundState.Round(), cs.roundState.Step()), "time", time.Now().UnixMilli())
 
 	// Sign and broadcast vote as necessary
@@ -1636,13 +2418,20 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 
 	// Check that a proposed block was not received within this round (and thus executing this from a timeout).
 	if !cs.config.GossipTransactionKeyOnly && cs.roundState.ProposalBlock() == nil {
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "no-proposal-block", nil, types.PartSetHeader{})
 		return
 	}
 
 	if cs.roundState.Proposal() == nil {
 		logger.Info("prevote step: did not receive proposal; prevoting nil")
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "no-proposal", nil, types.PartSetHeader{})
+		return
+	}
+
+	if cs.isBlacklistedProposal() {
+		logger.Info("prevote step: proposer is blacklisted after repeated rejections; prevoting nil",
+			"proposer", cs.roundState.Validators().GetProposer().Address)
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "blacklisted-proposer", nil, types.PartSetHeader{})
 		return
 	}
 
@@ -1653,18 +2442,18 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 			if cs.roundState.ProposalBlockParts().IsComplete() {
 				block, err := cs.getBlockFromBlockParts()
 				if err != nil {
-					cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+					cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "invalid-block-parts", nil, types.PartSetHeader{})
 					return
 				}
 				// We have full proposal block and txs. Build proposal block with txKeys
-				proposalBlock := cs.buildProposalBlock(height, block.Header, block.LastCommit, block.Evidence, block.ProposerAddress, txKeys)
+				proposalBlock := cs.buildProposalBlock(height, round, block.Header, block.LastCommit, block.Evidence, block.ProposerAddress, txKeys, cs.reconstructionDeadline())
 				if proposalBlock == nil {
-					cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+					cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "block-reconstruction-failed", nil, types.PartSetHeader{})
 					return
 				}
 				cs.roundState.SetProposalBlock(proposalBlock)
 			} else {
-				cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+				cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "incomplete-block-parts", nil, types.PartSetHeader{})
 				return
 			}
 		}
@@ -1673,12 +2462,12 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 			block, err := cs.getBlockFromBlockParts()
 			if err != nil {
 				cs.logger.Error("Encountered error building block from parts", "block parts", cs.roundState.ProposalBlockParts())
-				cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+				cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "invalid-block-parts", nil, types.PartSetHeader{})
 				return
 			}
 			if block == nil {
 				logger.Error("prevote step: ProposalBlock is nil")
-				cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+				cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "no-proposal-block", nil, types.PartSetHeader{})
 				return
 			}
 			cs.roundState.SetProposalBlock(block)
@@ -1687,7 +2476,7 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 
 	if !cs.roundState.Proposal().Timestamp.Equal(cs.roundState.ProposalBlock().Header.Time) {
 		logger.Info("prevote step: proposal timestamp not equal; prevoting nil")
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "timestamp-mismatch", nil, types.PartSetHeader{})
 		return
 	}
 
@@ -1702,17 +2491,17 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 			sp.MessageDelay,
 			"precision",
 			sp.Precision)
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "untimely-proposal", nil, types.PartSetHeader{})
 		return
 	}
 
 	// Validate proposal block, from Tendermint's perspective
-	err := cs.blockExec.ValidateBlock(ctx, cs.state, cs.roundState.ProposalBlock())
+	err := cs.validateBlock(ctx, cs.roundState.ProposalBlock())
 	if err != nil {
 		// ProposalBlock is invalid, prevote nil.
 		logger.Error("prevote step: consensus deems this block invalid; prevoting nil",
 			"err", err)
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "invalid-block", nil, types.PartSetHeader{})
 		return
 	}
 
@@ -1731,6 +2520,7 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 		panic(fmt.Sprintf("ProcessProposal: %v", err))
 	}
 	cs.metrics.MarkProposalProcessed(isAppValid)
+	cs.proposerBlacklist.recordProcessProposal(cs.roundState.Validators().GetProposer().Address, height, isAppValid)
 
 	// Vote nil if the Application rejected the block
 	if !isAppValid {
@@ -1750,71 +2540,29 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 			"proposerAddress", proposerAddress,
 			"numberOfTxs", numberOfTxs)
 
-		cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, "app-rejected", nil, types.PartSetHeader{})
 		return
 	}
 
-	/*
-		22: upon <PROPOSAL, h_p, round_p, v, −1> from proposer(h_p, round_p) while step_p = propose do
-		23: if valid(v) && (lockedRound_p = −1 || lockedValue_p = v) then
-		24: broadcast <PREVOTE, h_p, round_p, id(v)>
-
-		Here, cs.Proposal.POLRound corresponds to the -1 in the above algorithm rule.
-		This means that the proposer is producing a new proposal that has not previously
-		seen a 2/3 majority by the network.
-
-		If we have already locked on a different value that is different from the proposed value,
-		we prevote nil since we are locked on a different value. Otherwise, if we're not locked on a block
-		or the proposal matches our locked block, we prevote the proposal.
-	*/
-	if cs.roundState.Proposal().POLRound == -1 {
-		if cs.roundState.LockedRound() == -1 {
-			logger.Info("prevote step: ProposalBlock is valid and there is no locked block; prevoting the proposal")
-			cs.signAddVote(ctx, tmproto.PrevoteType, cs.roundState.ProposalBlock().Hash(), cs.roundState.ProposalBlockParts().Header())
-			return
-		}
-		if cs.roundState.ProposalBlock().HashesTo(cs.roundState.LockedBlock().Hash()) {
-			logger.Info("prevote step: ProposalBlock is valid and matches our locked block; prevoting the proposal")
-			cs.signAddVote(ctx, tmproto.PrevoteType, cs.roundState.ProposalBlock().Hash(), cs.roundState.ProposalBlockParts().Header())
-			return
-		}
-	}
-
-	/*
-		28: upon <PROPOSAL, h_p, round_p, v, v_r> from proposer(h_p, round_p) AND 2f + 1 <PREVOTE, h_p, v_r, id(v)> while
-		step_p = propose && (v_r ≥ 0 && v_r < round_p) do
-		29: if valid(v) && (lockedRound_p ≤ v_r || lockedValue_p = v) then
-		30: broadcast <PREVOTE, h_p, round_p, id(v)>
-
-		This rule is a bit confusing but breaks down as follows:
-
-		If we see a proposal in the current round for value 'v' that lists its valid round as 'v_r'
-		AND this validator saw a 2/3 majority of the voting power prevote 'v' in round 'v_r', then we will
-		issue a prevote for 'v' in this round if 'v' is valid and either matches our locked value OR
-		'v_r' is a round greater than or equal to our current locked round.
-
-		'v_r' can be a round greater than to our current locked round if a 2/3 majority of
-		the network prevoted a value in round 'v_r' but we did not lock on it, possibly because we
-		missed the proposal in round 'v_r'.
-	*/
-	blockID, ok := cs.roundState.Votes().Prevotes(cs.roundState.Proposal().POLRound).TwoThirdsMajority()
-	if ok && cs.roundState.ProposalBlock().HashesTo(blockID.Hash) && cs.roundState.Proposal().POLRound >= 0 && cs.roundState.Proposal().POLRound < cs.roundState.Round() {
-		if cs.roundState.LockedRound() <= cs.roundState.Proposal().POLRound {
-			logger.Info("prevote step: ProposalBlock is valid and received a 2/3" +
-				"majority in a round later than the locked round; prevoting the proposal")
-			cs.signAddVote(ctx, tmproto.PrevoteType, cs.roundState.ProposalBlock().Hash(), cs.roundState.ProposalBlockParts().Header())
-			return
-		}
-		if cs.roundState.ProposalBlock().HashesTo(cs.roundState.LockedBlock().Hash()) {
-			logger.Info("prevote step: ProposalBlock is valid and matches our locked block; prevoting the proposal")
-			cs.signAddVote(ctx, tmproto.PrevoteType, cs.roundState.ProposalBlock().Hash(), cs.roundState.ProposalBlockParts().Header())
-			return
-		}
+	proposal, proposalBlock := cs.roundState.Proposal(), cs.roundState.ProposalBlock()
+	polBlockID, hasPOL := cs.roundState.Votes().Prevotes(proposal.POLRound).TwoThirdsMajority()
+	prevote, reason := decideValidProposalPrevote(validProposal{
+		Round:       cs.roundState.Round(),
+		BlockHash:   proposalBlock.Hash(),
+		POLRound:    proposal.POLRound,
+		POLBlockID:  polBlockID,
+		HasPOL:      hasPOL,
+		LockedRound: cs.roundState.LockedRound(),
+		LockedBlock: cs.roundState.LockedBlock().Hash(),
+	})
+	if !prevote {
+		logger.Info("prevote step: ProposalBlock is valid but was not our locked block or "+
+			"did not receive a more recent majority; prevoting nil", "reason", reason, "pol_round", proposal.POLRound)
+		cs.signAddDecidedVote(ctx, tmproto.PrevoteType, reason, nil, types.PartSetHeader{})
+		return
 	}
-
-	logger.Info("prevote step: ProposalBlock is valid but was not our locked block or " +
-		"did not receive a more recent majority; prevoting nil")
-	cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	logger.Info("prevote step: ProposalBlock is valid; prevoting the proposal", "reason", reason, "pol_round", proposal.POLRound)
+	cs.signAddDecidedVote(ctx, tmproto.PrevoteType, reason, proposalBlock.Hash(), cs.roundState.ProposalBlockParts().Header())
 }
 
 // Enter: any +2/3 prevotes at next round.
@@ -1842,7 +2590,7 @@ func (cs *State) enterPrevoteWait(height int64, round int32) {
 	defer func() {
 		// Done enterPrevoteWait:
 		cs.updateRoundStep(round, cstypes.RoundStepPrevoteWait)
-		cs.newStep()
+		cs.newStep("")
 	}()
 
 	// Wait for some more prevotes; enterPrecommit
@@ -1855,9 +2603,8 @@ func (cs *State) enterPrevoteWait(height int64, round int32) {
 // Lock & precommit the ProposalBlock if we have enough prevotes for it (a POL in this round)
 // else, precommit nil otherwise.
 func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32, entryLabel string) {
-	_, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.enterPrecommit")
-	span.SetAttributes(attribute.Int("round", int(round)))
-	span.SetAttributes(attribute.String("entry", entryLabel))
+	_, span := cs.startSpan(cs.getRoundTracingCtx(ctx), "cs.state.enterPrecommit",
+		attribute.Int("round", int(round)), attribute.String("entry", entryLabel))
 	defer span.End()
 
 	logger := cs.logger.With("height", height, "round", round)
@@ -1874,11 +2621,13 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 	}
 
 	logger.Debug("entering precommit step", "current", fmt.Sprintf("%v/%v/%v", cs.roundState.Height(), cs.roundState.Round(), cs.roundState.Step()), "time", time.Now().UnixMilli())
+	cs.updateAbsentees()
 
 	defer func() {
 		// Done enterPrecommit:
+		entry := cs.recordTransition(height, round, cstypes.RoundStepPrecommit, entryLabel)
 		cs.updateRoundStep(round, cstypes.RoundStepPrecommit)
-		cs.newStep()
+		cs.newStep(entry)
 	}()
 
 	// check for a polka
@@ -1892,14 +2641,15 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 			logger.Info("precommit step; no +2/3 prevotes during enterPrecommit; precommitting nil")
 		}
 
-		cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "no-polka", nil, types.PartSetHeader{})
 		return
 	}
 
 	// At this point +2/3 prevoted for a particular block or nil.
-	if err := cs.eventBus.PublishEventPolka(cs.roundState.RoundStateEvent()); err != nil {
+	if err := cs.publishEvent(types.EventPolkaValue, cs.roundState.RoundStateEvent()); err != nil {
 		logger.Error("failed publishing polka", "err", err)
 	}
+	cs.recordRingEvent(EventRingRecord{Kind: EventRingPolka, BlockHash: blockID.Hash})
 
 	// the latest POLRound should be this round.
 	polRound, _ := cs.roundState.Votes().POLInfo()
@@ -1910,22 +2660,31 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 	// +2/3 prevoted nil. Precommit nil.
 	if blockID.IsNil() {
 		logger.Info("precommit step: +2/3 prevoted for nil; precommitting nil")
-		cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "polka-nil", nil, types.PartSetHeader{})
 		return
 	}
 	// At this point, +2/3 prevoted for a particular block.
 
+	// The block of the proposal may already be in the block store, e.g.
+	// saved by a previous process before a restart. A block is only loaded
+	// for the proposal of the round, so that it is never paired with the
+	// proposal of another block.
+	if proposal := cs.roundState.Proposal(); proposal != nil && proposal.BlockID.Equals(blockID) &&
+		!cs.roundState.ProposalBlock().HashesTo(blockID.Hash) && cs.loadProposalBlockFromStore(ctx, blockID) {
+		logger.Info("precommit step: loaded +2/3 prevoted block from the block store", "block_hash", blockID.Hash)
+	}
+
 	// If we never received a proposal for this block, we must precommit nil
 	if cs.roundState.Proposal() == nil || cs.roundState.ProposalBlock() == nil {
 		logger.Info("precommit step; did not receive proposal, precommitting nil")
-		cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "no-proposal", nil, types.PartSetHeader{})
 		return
 	}
 
 	// If the proposal time does not match the block time, precommit nil.
 	if !cs.roundState.Proposal().Timestamp.Equal(cs.roundState.ProposalBlock().Header.Time) {
 		logger.Info("precommit step: proposal timestamp not equal; precommitting nil")
-		cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "timestamp-mismatch", nil, types.PartSetHeader{})
 		return
 	}
 
@@ -1933,12 +2692,14 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 	if cs.roundState.LockedBlock().HashesTo(blockID.Hash) {
 		logger.Info("precommit step: +2/3 prevoted locked block; relocking")
 		cs.roundState.SetLockedRound(round)
+		cs.recordLock(span, round, blockID, LockTriggerPolkaRelock)
 
-		if err := cs.eventBus.PublishEventRelock(cs.roundState.RoundStateEvent()); err != nil {
+		if err := cs.publishEvent(types.EventRelockValue, cs.roundState.LockEvent()); err != nil {
 			logger.Error("precommit step: failed publishing event relock", "err", err)
 		}
+		cs.recordRingEvent(EventRingRecord{Kind: EventRingRelock, BlockHash: blockID.Hash})
 
-		cs.signAddVote(ctx, tmproto.PrecommitType, blockID.Hash, blockID.PartSetHeader)
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "relock", blockID.Hash, blockID.PartSetHeader)
 		return
 	}
 
@@ -1949,19 +2710,21 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 		logger.Info("precommit step: +2/3 prevoted proposal block; locking", "hash", blockID.Hash)
 
 		// Validate the block.
-		if err := cs.blockExec.ValidateBlock(ctx, cs.state, cs.roundState.ProposalBlock()); err != nil {
+		if err := cs.validateBlock(ctx, cs.roundState.ProposalBlock()); err != nil {
 			panic(fmt.Sprintf("precommit step: +2/3 prevoted for an invalid block %v; relocking", err))
 		}
 
 		cs.roundState.SetLockedRound(round)
 		cs.roundState.SetLockedBlock(cs.roundState.ProposalBlock())
 		cs.roundState.SetLockedBlockParts(cs.roundState.ProposalBlockParts())
+		cs.recordLock(span, round, blockID, LockTriggerNewLock)
 
-		if err := cs.eventBus.PublishEventLock(cs.roundState.RoundStateEvent()); err != nil {
+		if err := cs.publishEvent(types.EventLockValue, cs.roundState.LockEvent()); err != nil {
 			logger.Error("precommit step: failed publishing event lock", "err", err)
 		}
+		cs.recordRingEvent(EventRingRecord{Kind: EventRingLock, BlockHash: blockID.Hash})
 
-		cs.signAddVote(ctx, tmproto.PrecommitType, blockID.Hash, blockID.PartSetHeader)
+		cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "lock", blockID.Hash, blockID.PartSetHeader)
 		return
 	}
 
@@ -1971,24 +2734,31 @@ func (cs *State) enterPrecommit(ctx context.Context, height int64, round int32,
 
 	if !cs.roundState.ProposalBlockParts().HasHeader(blockID.PartSetHeader) {
 		cs.roundState.SetProposalBlock(nil)
-		cs.metrics.MarkBlockGossipStarted()
+		cs.markBlockGossipStarted(blockID.PartSetHeader)
 		cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(blockID.PartSetHeader))
 	}
 
-	cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	cs.signAddDecidedVote(ctx, tmproto.PrecommitType, "polka-unknown-block", nil, types.PartSetHeader{})
 }
 
 // Enter: any +2/3 precommits for next round.
 func (cs *State) enterPrecommitWait(height int64, round int32) {
 	logger := cs.logger.With("height", height, "round", round)
 
-	if cs.roundState.Height() != height || round < cs.roundState.Round() || (cs.roundState.Round() == round && cs.roundState.TriggeredTimeoutPrecommit()) {
+	// the precommit wait is triggered once per round: the flag records the
+	// round it was triggered for, so that a flag left over from a previous
+	// round never prevents the wait of the current one
+	triggered := cs.roundState.TriggeredTimeoutPrecommitRound()
+	if cs.roundState.Height() != height || round < cs.roundState.Round() || triggered == round {
 		logger.Debug(
 			"entering precommit wait step with invalid args",
-			"triggered_timeout", cs.roundState.TriggeredTimeoutPrecommit(),
+			"triggered_timeout_round", triggered,
 			"current", fmt.Sprintf("%v/%v", cs.roundState.Height(), cs.roundState.Round()),
 			"time", time.Now().UnixMilli(),
 		)
+		if cs.roundState.Height() == height && round >= cs.roundState.Round() {
+			cs.metrics.PrecommitWaitSkipped.Add(1)
+		}
 		return
 	}
 
@@ -2003,8 +2773,8 @@ func (cs *State) enterPrecommitWait(height int64, round int32) {
 
 	defer func() {
 		// Done enterPrecommitWait:
-		cs.roundState.SetTriggeredTimeoutPrecommit(true)
-		cs.newStep()
+		cs.roundState.SetTriggeredTimeoutPrecommitRound(round)
+		cs.newStep("")
 	}()
 
 	// wait for some more precommits; enterNewRound
@@ -2013,9 +2783,8 @@ func (cs *State) enterPrecommitWait(height int64, round int32) {
 
 // Enter: +2/3 precommits for block
 func (cs *State) enterCommit(ctx context.Context, height int64, commitRound int32, entryLabel string) {
-	spanCtx, span := cs.tracer.Start(cs.getTracingCtx(ctx), "cs.state.enterCommit")
-	span.SetAttributes(attribute.Int("round", int(commitRound)))
-	span.SetAttributes(attribute.String("entry", entryLabel))
+	spanCtx, span := cs.startSpan(cs.getRoundTracingCtx(ctx), "cs.state.enterCommit",
+		attribute.Int("round", int(commitRound)), attribute.String("entry", entryLabel))
 	defer span.End()
 
 	logger := cs.logger.With("height", height, "commit_round", commitRound)
@@ -2034,10 +2803,11 @@ func (cs *State) enterCommit(ctx context.Context, height int64, commitRound int3
 	defer func() {
 		// Done enterCommit:
 		// keep cs.Round the same, commitRound points to the right Precommits set.
+		entry := cs.recordTransition(height, cs.roundState.Round(), cstypes.RoundStepCommit, entryLabel)
 		cs.updateRoundStep(cs.roundState.Round(), cstypes.RoundStepCommit)
 		cs.roundState.SetCommitRound(commitRound)
 		cs.roundState.SetCommitTime(tmtime.Now())
-		cs.newStep()
+		cs.newStep(entry)
 
 		// Maybe finalize immediately.
 		cs.tryFinalizeCommit(spanCtx, height)
@@ -2057,6 +2827,20 @@ func (cs *State) enterCommit(ctx context.Context, height int64, commitRound int3
 		cs.roundState.SetProposalBlockParts(cs.roundState.LockedBlockParts())
 	}
 
+	// If we don't have the block being committed, try the block store before
+	// setting up to get it.
+	if !cs.roundState.ProposalBlock().HashesTo(blockID.Hash) && cs.loadProposalBlockFromStore(ctx, blockID) {
+		logger.Info("commit is for a block in the block store; set ProposalBlock from the store", "block_hash", blockID.Hash)
+
+		if err := cs.publishEvent(types.EventValidBlockValue, cs.roundState.RoundStateEvent()); err != nil {
+			logger.Error("failed publishing valid block", "err", err)
+		}
+		cs.recordRingEvent(EventRingRecord{Kind: EventRingValidBlock, BlockHash: blockID.Hash})
+
+		roundState := cs.roundState.CopyInternal()
+		cs.evsw.FireEvent(types.EventValidBlockValue, roundState)
+	}
+
 	// If we don't have the block being committed, set up to get it.
 	if !cs.roundState.ProposalBlock().HashesTo(blockID.Hash) {
 		if !cs.roundState.ProposalBlockParts().HasHeader(blockID.PartSetHeader) {
@@ -2069,19 +2853,48 @@ func (cs *State) enterCommit(ctx context.Context, height int64, commitRound int3
 			// We're getting the wrong block.
 			// Set up ProposalBlockParts and keep waiting.
 			cs.roundState.SetProposalBlock(nil)
-			cs.metrics.MarkBlockGossipStarted()
+			cs.markBlockGossipStarted(blockID.PartSetHeader)
 			cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(blockID.PartSetHeader))
 
-			if err := cs.eventBus.PublishEventValidBlock(cs.roundState.RoundStateEvent()); err != nil {
+			if err := cs.publishEvent(types.EventValidBlockValue, cs.roundState.RoundStateEvent()); err != nil {
 				logger.Error("failed publishing valid block", "err", err)
 			}
+			cs.recordRingEvent(EventRingRecord{Kind: EventRingValidBlock, BlockHash: blockID.Hash})
 
 			roundState := cs.roundState.CopyInternal()
 			cs.evsw.FireEvent(types.EventValidBlockValue, roundState)
+
+			// Request the block from the peers that precommitted it rather
+			// than waiting for it to be gossiped.
+			cs.fireBlockNeeded(height, commitRound, blockID)
 		}
 	}
 }
 
+// loadProposalBlockFromStore sets the block of blockID as the proposal block if
+// it is in the block store at the current height and is valid. It returns
+// whether the block was set.
+func (cs *State) loadProposalBlockFromStore(ctx context.Context, blockID types.BlockID) bool {
+	block := cs.blockStore.LoadBlockByHash(blockID.Hash)
+	if block == nil || block.Height != cs.roundState.Height() {
+		return false
+	}
+
+	blockParts, err := cs.makeBlockPartsMatching(block, blockID.PartSetHeader)
+	if err != nil {
+		return false
+	}
+
+	if err := cs.validateBlock(ctx, block); err != nil {
+		cs.logger.Error("block from the block store is invalid", "block_hash", blockID.Hash, "err", err)
+		return false
+	}
+
+	cs.roundState.SetProposalBlock(block)
+	cs.roundState.SetProposalBlockParts(blockParts)
+	return true
+}
+
 // If we have the block AND +2/3 commits for it, finalize.
 func (cs *State) tryFinalizeCommit(ctx context.Context, height int64) {
 	logger := cs.logger.With("height", height)
@@ -2107,15 +2920,19 @@ func (cs *State) tryFinalizeCommit(ctx context.Context, height int64) {
 		)
 		return
 	}
+	cs.markBlockRecovered(height, cs.roundState.ProposalBlock())
 
 	cs.finalizeCommit(ctx, height)
 }
 
 // Increment height and goto cstypes.RoundStepNewHeight
 func (cs *State) finalizeCommit(ctx context.Context, height int64) {
-	spanCtx, span := cs.tracer.Start(ctx, "cs.state.finalizeCommit")
+	spanCtx, span := cs.startSpan(ctx, "cs.state.finalizeCommit")
 	defer span.End()
 	logger := cs.logger.With("height", height)
+	// the deferred end is bound to the variable, which each section resets
+	section := cs.holdSection(lockHoldFinalizeCommitCheck)
+	defer section.end()
 
 	if cs.roundState.Height() != height || cs.roundState.Step() != cstypes.RoundStepCommit {
 		logger.Debug(
@@ -2141,7 +2958,7 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 		panic("cannot finalize commit; proposal block does not hash to commit hash")
 	}
 
-	if err := cs.blockExec.ValidateBlock(ctx, cs.state, block); err != nil {
+	if err := cs.validateCommittedBlock(ctx, block); err != nil {
 		panic(fmt.Errorf("+2/3 committed an invalid block: %w", err))
 	}
 
@@ -2154,27 +2971,82 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 	)
 	logger.Debug(fmt.Sprintf("%v", block))
 
+	consensusTime := time.Since(cs.roundState.StartTime())
+	saveStartTime := time.Now()
+	section.end()
+	section = cs.holdSection(lockHoldFinalizeCommitSave)
+
 	// Save to blockStore.
+	//
+	// The block is saved in the background so that the save overlaps syncing
+	// the WAL entries of this height to disk; both complete before the
+	// EndHeightMessage{} below is written.
+	saveErrCh := make(chan error, 1)
+	// saveDone is closed once the save exits, whether it completed or not
+	saveDone := make(chan struct{})
 	if cs.blockStore.Height() < block.Height {
 		// NOTE: the seenCommit is local justification to commit this block,
 		// but may differ from the LastCommit included in the next block
-		_, storeBlockSpan := cs.tracer.Start(spanCtx, "cs.state.finalizeCommit.saveblockstore")
-		defer storeBlockSpan.End()
 		seenExtendedCommit := cs.roundState.Votes().Precommits(cs.roundState.CommitRound()).MakeExtendedCommit()
-		if cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(block.Height) {
-			cs.blockStore.SaveBlockWithExtendedCommit(block, blockParts, seenExtendedCommit)
-		} else {
-			cs.blockStore.SaveBlock(block, blockParts, seenExtendedCommit.ToCommit())
+		extensionsEnabled := cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(block.Height)
+		if extensionsEnabled {
+			seenExtendedCommit = cs.retainVoteExtensions(seenExtendedCommit, cs.state.Validators)
 		}
+		cs.spawn(func() {
+			defer close(saveDone)
+			defer func() {
+				if r := recover(); r != nil {
+					saveErrCh <- fmt.Errorf("%v", r)
+				}
+			}()
+			_, storeBlockSpan := cs.startSpan(spanCtx, "cs.state.finalizeCommit.saveblockstore")
+			defer storeBlockSpan.End()
+			if extensionsEnabled {
+				cs.blockStore.SaveBlockWithExtendedCommit(block, blockParts, seenExtendedCommit)
+			} else {
+				cs.blockStore.SaveBlock(block, blockParts, seenExtendedCommit.ToCommit())
+			}
+			saveErrCh <- nil
+		})
 		// Calculate consensus time
 		cs.metrics.ConsensusTime.Observe(time.Since(cs.roundState.StartTime()).Seconds())
 	} else {
 		// Happens during replay if we already saved the block but didn't commit
 		logger.Debug("calling finalizeCommit on already stored block", "height", block.Height)
+		saveErrCh <- nil
+		close(saveDone)
 	}
 
+	// Create a copy of the state for staging and an event cache for txs.
+	stateCopy := cs.state.Copy()
+
+	_, fsyncSpan := cs.startSpan(spanCtx, "cs.state.finalizeCommit.fsync")
+	defer fsyncSpan.End()
+	if err := cs.walFlushAndSync(FaultPointFinalizeCommit); err != nil {
+		panic(fmt.Errorf(
+			"failed to flush consensus WAL due to %w; check your file system and restart the node",
+			err,
+		))
+	}
+
+	// Wait for the block to be saved. If the node is shutting down,
+	// EndHeightMessage{} must not be written, but the save is still waited
+	// for so that the block store is not closed under it.
+	select {
+	case err := <-saveErrCh:
+		if err != nil {
+			panic(fmt.Errorf("failed to save block %d: %w", block.Height, err))
+		}
+	case <-ctx.Done():
+		<-saveDone
+		return
+	}
+	saveTime := time.Since(saveStartTime)
+	cs.notifyTxsCommitted(block)
+
 	// Write EndHeightMessage{} for this height, implying that the blockstore
-	// has saved the block.
+	// has saved the block. It must only be written once the save above has
+	// completed.
 	//
 	// If we crash before writing this EndHeightMessage{}, we will recover by
 	// running ApplyBlock during the ABCI handshake when we restart.  If we
@@ -2186,22 +3058,18 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 	// Either way, the State should not be resumed until we
 	// successfully call ApplyBlock (ie. later here, or in Handshake after
 	// restart).
-	endMsg := EndHeightMessage{height}
-	_, fsyncSpan := cs.tracer.Start(spanCtx, "cs.state.finalizeCommit.fsync")
-	defer fsyncSpan.End()
-	if err := cs.wal.WriteSync(endMsg); err != nil { // NOTE: fsync
+	if err := cs.writeEndHeight(height); err != nil { // NOTE: fsync
 		panic(fmt.Errorf(
 			"failed to write %v msg to consensus WAL due to %w; check your file system and restart the node",
-			endMsg, err,
+			EndHeightMessage{height}, err,
 		))
 	}
 	fsyncSpan.End()
-
-	// Create a copy of the state for staging and an event cache for txs.
-	stateCopy := cs.state.Copy()
+	section.end()
 
 	// Execute and commit the block, update and save the state, and update the mempool.
 	// NOTE The block.AppHash won't reflect these txs until the next block.
+	section = cs.holdSection(lockHoldFinalizeCommitApply)
 	startTime := time.Now()
 	stateCopy, err := cs.blockExec.ApplyBlock(spanCtx,
 		stateCopy,
@@ -2212,17 +3080,48 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 		block,
 		cs.tracer,
 	)
-	cs.metrics.ApplyBlockLatency.Observe(float64(time.Since(startTime).Milliseconds()))
+	applyTime := time.Since(startTime)
+	section.end()
+	cs.metrics.ApplyBlockLatency.Observe(float64(applyTime.Milliseconds()))
 	if err != nil {
 		logger.Error("failed to apply block", "err", err)
 		return
 	}
+	finality := cs.blockFinality(block, cs.clock.Now())
+	blockSource, blockLatency := cs.proposalBlockSourceOf(height, block)
+	cs.logDecision(DecisionRecord{
+		Height:    height,
+		Round:     cs.roundState.CommitRound(),
+		Kind:      DecisionCommit,
+		BlockHash: block.Hash(),
+		Timing: &CommitTiming{
+			Consensus:            consensusTime,
+			Save:                 saveTime,
+			Apply:                applyTime,
+			Finality:             finality,
+			ProposalBlockSource:  blockSource,
+			ProposalBlockLatency: blockLatency,
+		},
+	})
+	if blockSource == ProposalBlockFromTxKeys {
+		cs.auditRebuiltBlock(height, block)
+	}
 
 	// must be called before we update state
 	cs.RecordMetrics(height, block)
+	summary := cs.heightSummary(height, block, applyTime, finality)
+	cs.recordProposer(height)
+	cs.recordBlockPartAmplification(blockParts.ByteSize())
 
 	// NewHeightStep!
-	cs.updateToState(stateCopy)
+	cs.endRoundSpan()
+	cs.updateToState(stateCopy, stateUpdateSourceFinalize)
+
+	// Signal an OnStop waiting for the commit to finish.
+	select {
+	case cs.onStopCh <- cs.roundState.CopyInternal():
+	default:
+	}
 
 	// Private validator might have changed it's key pair => refetch pubkey.
 	if err := cs.updatePrivValidatorPubKey(ctx); err != nil {
@@ -2233,15 +3132,32 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 	// Schedule Round0 to start soon.
 	cs.scheduleRound0(cs.roundState.GetInternalPointer())
 
+	cs.publishBlockApplied(block, stateCopy.AppHash, applyTime)
+	cs.recordProposalDelivery(summary)
+	cs.publishHeightSummary(summary)
+
 	// By here,
 	// * cs.Height has been increment to height+1
 	// * cs.Step is now cstypes.RoundStepNewHeight
 	// * cs.StartTime is set to when we will start round0.
 }
 
+// notifyTxsCommitted hints the txNotifier, if it implements
+// txsCommittedNotifier, about the txs of the saved block. It is notified once
+// per height, and not during replay.
+func (cs *State) notifyTxsCommitted(block *types.Block) {
+	notifier, ok := cs.txNotifier.(txsCommittedNotifier)
+	if !ok || cs.replayMode || block.Height <= cs.txsCommittedHeight {
+		return
+	}
+	cs.txsCommittedHeight = block.Height
+	notifier.TxsCommitted(block.GetTxKeys(), block.Height)
+}
+
 func (cs *State) RecordMetrics(height int64, block *types.Block) {
 	cs.metrics.Validators.Set(float64(cs.roundState.Validators().Size()))
 	cs.metrics.ValidatorsPower.Set(float64(cs.roundState.Validators().TotalVotingPower()))
+	cs.checkOwnPrecommitInclusion(height)
 
 	var (
 		missingValidators      int
@@ -2264,40 +3180,31 @@ func (cs *State) RecordMetrics(height int64, block *types.Block) {
 			return
 		}
 
-		if cs.privValidator != nil {
-			if cs.privValidatorPubKey == nil {
+		if privValidator, pubKey := cs.getPrivValidator(); privValidator != nil {
+			if pubKey == nil {
 				// Metrics won't be updated, but it's not critical.
 				cs.logger.Error("recordMetrics", "err", errPubKeyIsNotSet)
 			} else {
-				address = cs.privValidatorPubKey.Address()
+				address = pubKey.Address()
 			}
 		}
 
 		for i, val := range cs.roundState.LastValidators().Validators {
-			commitSig := block.LastCommit.Signatures[i]
-			if commitSig.BlockIDFlag == types.BlockIDFlagAbsent {
+			if block.LastCommit.Signatures[i].BlockIDFlag == types.BlockIDFlagAbsent {
 				missingValidators++
 				missingValidatorsPower += val.VotingPower
-				cs.metrics.MissingValidatorsPower.With("validator_address", val.Address.String()).Set(float64(val.VotingPower))
-			} else {
-				cs.metrics.MissingValidatorsPower.With("validator_address", val.Address.String()).Set(0)
 			}
-
-			if bytes.Equal(val.Address, address) {
-				label := []string{
-					"validator_address", val.Address.String(),
-				}
-				cs.metrics.ValidatorPower.With(label...).Set(float64(val.VotingPower))
-				if commitSig.BlockIDFlag == types.BlockIDFlagCommit {
-					cs.metrics.ValidatorLastSignedHeight.With(label...).Set(float64(height))
-				} else {
-					cs.metrics.ValidatorMissedBlocks.With(label...).Add(float64(1))
-				}
-			}
-
 		}
+		// the per-validator metrics are updated in the background
+		cs.recordValidatorMetrics(validatorMetricsJob{
+			height:     height,
+			validators: cs.roundState.LastValidators().Validators,
+			signatures: block.LastCommit.Signatures,
+			address:    address,
+		})
 	}
 	cs.metrics.MissingValidators.Set(float64(missingValidators))
+	cs.metrics.MissingValidatorsTotalPower.Set(float64(missingValidatorsPower))
 
 	// NOTE: byzantine validators power and count is only for consensus evidence i.e. duplicate vote
 	var (
@@ -2360,22 +3267,35 @@ func (cs *State) RecordMetrics(height int64, block *types.Block) {
 
 //-----------------------------------------------------------------------------
 
-func (cs *State) defaultSetProposal(proposal *types.Proposal, recvTime time.Time) error {
+func (cs *State) defaultSetProposal(proposal *types.Proposal, peerID types.NodeID, recvTime time.Time) error {
 	// Already have one
 	// TODO: possibly catch double proposals
-	if cs.roundState.Proposal() != nil || proposal == nil {
-		return nil
+	if proposal == nil {
+		return newProposalRejection(ProposalRejectionNil, proposal)
+	}
+	if proposal.Height == cs.roundState.Height() && cs.roundState.Step() >= cstypes.RoundStepCommit {
+		return cs.setPostCommitProposal(proposal, recvTime)
+	}
+	if cs.roundState.Proposal() != nil {
+		return newProposalRejection(ProposalRejectionDuplicate, proposal)
 	}
 
 	// Does not apply
-	if proposal.Height != cs.roundState.Height() || proposal.Round != cs.roundState.Round() {
-		return nil
+	if proposal.Height != cs.roundState.Height() {
+		return newProposalRejection(ProposalRejectionWrongHeight, proposal)
+	}
+	if proposal.Round != cs.roundState.Round() {
+		// the proposal of a skipped round may complete its polka
+		if proposal.Round < cs.roundState.Round() && cs.setLateProposal(proposal) {
+			return nil
+		}
+		return newProposalRejection(ProposalRejectionWrongRound, proposal)
 	}
 
 	// Verify POLRound, which must be -1 or in range [0, proposal.Round).
 	if proposal.POLRound < -1 ||
 		(proposal.POLRound >= 0 && proposal.POLRound >= proposal.Round) {
-		return ErrInvalidProposalPOLRound
+		return newProposalRejection(ProposalRejectionInvalidPOLRound, proposal)
 	}
 
 	p := proposal.ToProto()
@@ -2383,18 +3303,27 @@ func (cs *State) defaultSetProposal(proposal *types.Proposal, recvTime time.Time
 	if !cs.roundState.Validators().GetProposer().PubKey.VerifySignature(
 		types.ProposalSignBytes(cs.state.ChainID, p), proposal.Signature,
 	) {
-		return ErrInvalidProposalSignature
+		return newProposalRejection(ProposalRejectionInvalidSignature, proposal)
+	}
+	if cs.config.GossipTransactionKeyOnly {
+		if reason, ok := checkProposalTxKeys(proposal, cs.state.ConsensusParams.Block.MaxBytes); !ok {
+			rejection := newProposalRejection(reason, proposal)
+			rejection.Proposer = cs.roundState.Validators().GetProposer().Address
+			return rejection
+		}
 	}
 
 	proposal.Signature = p.Signature
 	cs.roundState.SetProposal(proposal)
 	cs.roundState.SetProposalReceiveTime(recvTime)
+	cs.markProposalDelivered(proposal, peerID)
+	cs.proposalTxKeys.set(proposal)
 	cs.calculateProposalTimestampDifferenceMetric()
 	// We don't update cs.ProposalBlockParts if it is already set.
 	// This happens if we're already in cstypes.RoundStepCommit or if there is a valid block in the current round.
 	// TODO: We can check if Proposal is for a different block as this is a sign of misbehavior!
 	if cs.roundState.ProposalBlockParts() == nil {
-		cs.metrics.MarkBlockGossipStarted()
+		cs.markBlockGossipStarted(proposal.BlockID.PartSetHeader)
 		cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(proposal.BlockID.PartSetHeader))
 	}
 
@@ -2402,28 +3331,45 @@ func (cs *State) defaultSetProposal(proposal *types.Proposal, recvTime time.Time
 	return nil
 }
 
+// setPostCommitProposal handles a proposal received once its height is
+// committed. The block of the commit may be assembled from another part set
+// header than that of the proposal, and the proposal would skew the metrics
+// of the commit: it is not set. A proposal for the committed block only has
+// its receive time recorded, any other is rejected.
+func (cs *State) setPostCommitProposal(proposal *types.Proposal, recvTime time.Time) error {
+	blockID, ok := cs.roundState.Votes().Precommits(cs.roundState.CommitRound()).TwoThirdsMajority()
+	if !ok || !proposal.BlockID.Equals(blockID) {
+		return newProposalRejection(ProposalRejectionPostCommit, proposal)
+	}
+	cs.metrics.PostCommitProposalDelay.Observe(recvTime.Sub(cs.roundState.CommitTime()).Seconds())
+	return nil
+}
+
 // NOTE: block is not necessarily valid.
 // Asynchronously triggers either enterPrevote (before we timeout of propose) or tryFinalizeCommit,
 // once we have the full block.
 func (cs *State) addProposalBlockPart(
 	msg *BlockPartMessage,
 	peerID types.NodeID,
+	receiveTime time.Time,
 ) (added bool, err error) {
 	height, round, part := msg.Height, msg.Round, msg.Part
 
 	// Blocks might be reused, so round mismatch is OK
 	if cs.roundState.Height() != height {
-		cs.logger.Debug("received block part from wrong height", "height", height, "round", round)
+		cs.logMsgDebug(msgDebugLogBlockPartWrongHeight, "received block part from wrong height", "height", height, "round", round)
 		cs.metrics.BlockGossipPartsReceived.With("matches_current", "false").Add(1)
+		cs.recordBlockPart(peerID, height, part, true)
 		return false, nil
 	}
 
 	// We're not expecting a block part.
 	if cs.roundState.ProposalBlockParts() == nil {
 		cs.metrics.BlockGossipPartsReceived.With("matches_current", "false").Add(1)
+		cs.recordBlockPart(peerID, height, part, true)
 		// NOTE: this can happen when we've gone to a higher round and
 		// then receive parts from the previous round - not necessarily a bad peer.
-		cs.logger.Debug(
+		cs.logMsgDebug(msgDebugLogBlockPartUnexpected,
 			"received a block part when we are not expecting any",
 			"height", height,
 			"round", round,
@@ -2434,6 +3380,7 @@ func (cs *State) addProposalBlockPart(
 	}
 
 	added, err = cs.roundState.ProposalBlockParts().AddPart(part)
+	cs.recordBlockPart(peerID, height, part, err != nil)
 	if err != nil {
 		if errors.Is(err, types.ErrPartSetInvalidProof) || errors.Is(err, types.ErrPartSetUnexpectedIndex) {
 			cs.metrics.BlockGossipPartsReceived.With("matches_current", "false").Add(1)
@@ -2442,6 +3389,13 @@ func (cs *State) addProposalBlockPart(
 	}
 
 	cs.metrics.BlockGossipPartsReceived.With("matches_current", "true").Add(1)
+	cs.metrics.PartsHave.Set(float64(cs.roundState.ProposalBlockParts().Count()))
+	cs.metrics.PartsTotal.Set(float64(cs.roundState.ProposalBlockParts().Total()))
+	if added {
+		cs.markBlockGossipProgress(cs.roundState.ProposalBlockParts())
+		cs.markBlockPartReceived(receiveTime)
+		cs.markBlockPartWait(cs.roundState.ProposalBlockParts(), int(part.Index), receiveTime)
+	}
 
 	if cs.roundState.ProposalBlockParts().ByteSize() > cs.state.ConsensusParams.Block.MaxBytes {
 		return added, fmt.Errorf("total size of proposal block parts exceeds maximum block bytes (%d > %d)",
@@ -2457,10 +3411,12 @@ func (cs *State) addProposalBlockPart(
 		}
 
 		cs.roundState.SetProposalBlock(block)
+		cs.markBlockCompleted(block, peerID)
+		cs.recordProposalBlockSource(height, round, block, ProposalBlockFromParts)
 		// NOTE: it's possible to receive complete proposal blocks for future rounds without having the proposal
 		cs.logger.Info("received complete proposal block", "height", cs.roundState.ProposalBlock().Height, "hash", cs.roundState.ProposalBlock().Hash(), "time", time.Now().UnixMilli())
 
-		if err := cs.eventBus.PublishEventCompleteProposal(cs.roundState.CompleteProposalEvent()); err != nil {
+		if err := cs.publishEvent(types.EventCompleteProposalValue, cs.roundState.CompleteProposalEvent()); err != nil {
 			cs.logger.Error("failed publishing event complete proposal", "err", err)
 		}
 	}
@@ -2468,28 +3424,8 @@ func (cs *State) addProposalBlockPart(
 	return added, nil
 }
 
-func (cs *State) getBlockFromBlockParts() (*types.Block, error) {
-	bz, err := io.ReadAll(cs.roundState.ProposalBlockParts().GetReader())
-	if err != nil {
-		return nil, err
-	}
-
-	var pbb = new(tmproto.Block)
-	err = proto.Unmarshal(bz, pbb)
-	if err != nil {
-		return nil, err
-	}
-
-	block, err := types.BlockFromProto(pbb)
-	if err != nil {
-		return nil, err
-	}
-	return block, nil
-}
-
 func (cs *State) tryCreateProposalBlock(ctx context.Context, height int64, round int32, header types.Header, lastCommit *types.Commit, evidence []types.Evidence, proposerAddress types.Address) bool {
-	_, span := cs.tracer.Start(ctx, "cs.state.tryCreateProposalBlock")
-	span.SetAttributes(attribute.Int("round", int(round)))
+	_, span := cs.startSpan(ctx, "cs.state.tryCreateProposalBlock", attribute.Int("round", int(round)))
 	defer span.End()
 
 	// Blocks might be reused, so round mismatch is OK
@@ -2502,27 +3438,36 @@ func (cs *State) tryCreateProposalBlock(ctx context.Context, height int64, round
 	if cs.roundState.Proposal() == nil {
 		return false
 	}
-	block := cs.buildProposalBlock(height, header, lastCommit, evidence, proposerAddress, cs.roundState.Proposal().TxKeys)
+	block := cs.buildProposalBlock(height, round, header, lastCommit, evidence, proposerAddress, cs.roundState.Proposal().TxKeys, cs.reconstructionDeadline())
 	if block == nil {
 		return false
 	}
-	cs.roundState.SetProposalBlock(block)
-	partSet, err := block.MakePartSet(types.BlockPartSizeBytes)
+	// the parts of the proposal may be compressed
+	partSet, err := cs.makeBlockPartsMatching(block, cs.roundState.Proposal().BlockID.PartSetHeader)
 	if err != nil {
+		cs.discardMismatchedRebuild(round, block)
 		return false
 	}
+	cs.roundState.SetProposalBlock(block)
 	cs.roundState.SetProposalBlockParts(partSet)
+	cs.recordProposalBlockSource(height, round, block, ProposalBlockFromTxKeys)
 	// NOTE: it's possible to receive complete proposal blocks for future rounds without having the proposal
 	cs.metrics.MarkBlockGossipComplete()
+	cs.markBlockGossipProgress(partSet)
 	return true
 }
 
 // Build a proposal block from mempool txs. If cs.config.GossipTransactionKeyOnly=true
-// proposals only contain txKeys so we rebuild the block using mempool txs
-func (cs *State) buildProposalBlock(height int64, header types.Header, lastCommit *types.Commit, evidence []types.Evidence, proposerAddress types.Address, txKeys []types.TxKey) *types.Block {
-	txs, missingTxs := cs.blockExec.SafeGetTxsByKeys(txKeys)
+// proposals only contain txKeys so we rebuild the block using mempool txs.
+// Returns nil if the txs could not be fetched from the mempool by deadline.
+func (cs *State) buildProposalBlock(height int64, round int32, header types.Header, lastCommit *types.Commit, evidence []types.Evidence, proposerAddress types.Address, txKeys []types.TxKey, deadline time.Time) *types.Block {
+	txs, missingTxs, ok := cs.fetchProposalTxs(height, round, txKeys, deadline)
+	if !ok {
+		return nil
+	}
 	if len(missingTxs) > 0 {
 		cs.metrics.ProposalMissingTxs.Set(float64(len(missingTxs)))
+		cs.recordProposalBlockMissingTxs(height)
 		cs.logger.Debug("Missing txs when trying to build block", "missing_txs", cs.blockExec.GetMissingTxs(txKeys))
 		return nil
 	}
@@ -2559,12 +3504,13 @@ func (cs *State) handleCompleteProposal(ctx context.Context, height int64, handl
 	}
 
 	// Do not count prevote/precommit/commit into handleBlockPartMsg's span
+	cs.recordBlockPartTiming(handleBlockPartSpan)
 	handleBlockPartSpan.End()
 
 	if cs.roundState.Step() <= cstypes.RoundStepPropose && cs.isProposalComplete() {
 		// Move onto the next step
 		cs.enterPrevote(ctx, height, cs.roundState.Round(), "complete-proposal")
-		if hasTwoThirds { // this is optimisation as this will be triggered when prevote is added
+		if hasTwoThirds && !cs.delayPrecommit(height, cs.roundState.Round()) { // this is optimisation as this will be triggered when prevote is added
 			cs.enterPrecommit(ctx, height, cs.roundState.Round(), "complete-proposal")
 		}
 	} else if cs.roundState.Step() == cstypes.RoundStepCommit {
@@ -2574,26 +3520,27 @@ func (cs *State) handleCompleteProposal(ctx context.Context, height int64, handl
 }
 
 // Attempt to add the vote. if its a duplicate signature, dupeout the validator
-func (cs *State) tryAddVote(ctx context.Context, vote *types.Vote, peerID types.NodeID, handleVoteMsgSpan otrace.Span) (bool, error) {
-	added, err := cs.addVote(ctx, vote, peerID, handleVoteMsgSpan)
+func (cs *State) tryAddVote(
+	ctx context.Context,
+	vote *types.Vote,
+	peerID types.NodeID,
+	verified types.VoteVerification,
+	handleVoteMsgSpan otrace.Span,
+) (bool, error) {
+	added, err := cs.addVote(ctx, vote, peerID, verified, handleVoteMsgSpan)
 	if err != nil {
 		// If the vote height is off, we'll just ignore it,
 		// But if it's a conflicting sig, add it to the cs.evpool.
 		// If it's otherwise invalid, punish peer.
 		//nolint: gocritic
 		if voteErr, ok := err.(*types.ErrVoteConflictingVotes); ok {
-			if cs.privValidatorPubKey == nil {
+			pubKey := cs.getPrivValidatorPubKey()
+			if pubKey == nil {
 				return false, errPubKeyIsNotSet
 			}
 
-			if bytes.Equal(vote.ValidatorAddress, cs.privValidatorPubKey.Address()) {
-				cs.logger.Error(
-					"found conflicting vote from ourselves; did you unsafe_reset a validator?",
-					"height", vote.Height,
-					"round", vote.Round,
-					"type", vote.Type,
-				)
-
+			if bytes.Equal(vote.ValidatorAddress, pubKey.Address()) {
+				cs.handleConflictingSelfVote(voteErr)
 				return added, err
 			}
 
@@ -2608,13 +3555,31 @@ func (cs *State) tryAddVote(ctx context.Context, vote *types.Vote, peerID types.
 			return added, err
 		} else if errors.Is(err, types.ErrVoteNonDeterministicSignature) {
 			cs.logger.Debug("vote has non-deterministic signature", "err", err)
+		} else if nonValErr, ok := err.(*ErrVoteFromNonValidator); ok {
+			// A validator that just left the set may still be voting for the
+			// height it was removed at.
+			if nonValErr.RotatedOut {
+				cs.logger.Debug("ignoring vote from a validator that left the set", "err", err, "peer", peerID)
+				return added, nil
+			}
+
+			if cs.reportPeerMisbehavior != nil && peerID != "" {
+				cs.reportPeerMisbehavior(peerID, err)
+			}
+			return added, err
 		} else {
 			// Either
 			// 1) bad peer OR
 			// 2) not a bad peer? this can also err sometimes with "Unexpected step" OR
 			// 3) tmkms use with multiple validators connecting to a single tmkms instance
 			//		(https://github.com/tendermint/tendermint/issues/3839).
-			cs.logger.Info("failed attempting to add vote", "err", err)
+			if verified != types.VoteUnverified {
+				// the sender is trusted to only pass valid votes
+				cs.logger.Error("failed attempting to add pre-verified vote",
+					"err", err, "peer", peerID, "verification", verified, "vote", vote)
+			} else {
+				cs.logger.Info("failed attempting to add vote", "err", err)
+			}
 			return added, ErrAddingVote
 		}
 	}
@@ -2626,9 +3591,10 @@ func (cs *State) addVote(
 	ctx context.Context,
 	vote *types.Vote,
 	peerID types.NodeID,
+	verified types.VoteVerification,
 	handleVoteMsgSpan otrace.Span,
 ) (added bool, err error) {
-	cs.logger.Debug(
+	cs.logMsgDebug(msgDebugLogVoteReceived,
 		"adding vote",
 		"vote_height", vote.Height,
 		"vote_type", vote.Type,
@@ -2644,17 +3610,18 @@ func (cs *State) addVote(
 	if vote.Height+1 == cs.roundState.Height() && vote.Type == tmproto.PrecommitType {
 		if cs.roundState.Step() != cstypes.RoundStepNewHeight {
 			// Late precommit at prior height is ignored
-			cs.logger.Debug("precommit vote came in after commit timeout and has been ignored", "vote", vote)
+			cs.recordLastCommitVote(vote, lastCommitVoteIgnored)
 			return
 		}
 
-		added, err = cs.roundState.LastCommit().AddVote(vote)
+		added, err = cs.roundState.LastCommit().AddPreVerifiedVote(vote, verified)
 		if !added {
 			return
 		}
+		cs.recordLastCommitVote(vote, lastCommitVoteAccepted)
+		cs.addPrecommitSource(vote, peerID)
 
-		cs.logger.Debug("added vote to last precommits", "last_commit", cs.roundState.LastCommit().StringShort())
-		if err := cs.eventBus.PublishEventVote(types.EventDataVote{Vote: vote}); err != nil {
+		if err := cs.publishEvent(types.EventVoteValue, types.EventDataVote{Vote: vote}); err != nil {
 			return added, err
 		}
 
@@ -2662,7 +3629,7 @@ func (cs *State) addVote(
 
 		handleVoteMsgSpan.End()
 		// if we can skip timeoutCommit and have all the votes now,
-		if cs.bypassCommitTimeout() && cs.roundState.LastCommit().HasAll() {
+		if cs.roundState.LastCommit().HasAll() && cs.canBypassCommitTimeout(vote.Height, vote.Round) {
 			// go straight to new round (skip timeout commit)
 			// cs.scheduleTimeout(time.Duration(0), cs.Height, 0, cstypes.RoundStepNewHeight)
 			cs.enterNewRound(ctx, cs.roundState.Height(), 0, "skip-timeout")
@@ -2674,10 +3641,23 @@ func (cs *State) addVote(
 	// Height mismatch is ignored.
 	// Not necessarily a bad peer, but not favorable behavior.
 	if vote.Height != cs.roundState.Height() {
-		cs.logger.Debug("vote ignored and not added", "vote_height", vote.Height, "cs_height", cs.roundState.Height(), "peer", peerID)
+		cs.logMsgDebug(msgDebugLogVoteWrongHeight, "vote ignored and not added", "vote_height", vote.Height, "cs_height", cs.roundState.Height(), "peer", peerID)
 		return
 	}
 
+	if !cs.roundState.Validators().HasAddress(vote.ValidatorAddress) {
+		nonValErr := &ErrVoteFromNonValidator{
+			Address:    vote.ValidatorAddress,
+			RotatedOut: cs.state.LastValidators != nil && cs.state.LastValidators.HasAddress(vote.ValidatorAddress),
+		}
+		kind := "unknown"
+		if nonValErr.RotatedOut {
+			kind = "rotated_out"
+		}
+		cs.metrics.NonValidatorVotes.With("kind", kind).Add(1)
+		return false, nonValErr
+	}
+
 	// Check to see if the chain is configured to extend votes.
 	if cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(cs.roundState.Height()) {
 		// The chain is configured to extend votes, check that the vote is
@@ -2685,8 +3665,8 @@ func (cs *State) addVote(
 		// corresponding public key.
 
 		var myAddr []byte
-		if cs.privValidatorPubKey != nil {
-			myAddr = cs.privValidatorPubKey.Address()
+		if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil {
+			myAddr = pubKey.Address()
 		}
 		// Verify VoteExtension if precommit and not nil
 		// https://github.com/tendermint/tendermint/issues/8487
@@ -2697,14 +3677,18 @@ func (cs *State) addVote(
 			// consensus reactor when the vote was received.
 			// Here, we verify the signature of the vote extension included in the vote
 			// message.
-			_, val := cs.state.Validators.GetByIndex(vote.ValidatorIndex)
-			if err := vote.VerifyExtension(cs.state.ChainID, val.PubKey); err != nil {
-				return false, err
+			if verified != types.VoteAndExtensionVerified {
+				_, val := cs.state.Validators.GetByIndex(vote.ValidatorIndex)
+				if err := vote.VerifyExtension(cs.state.ChainID, val.PubKey); err != nil {
+					cs.recordVoteExtensionRejection(vote, voteExtensionRejectedSignature, err)
+					return false, err
+				}
 			}
 
 			err := cs.blockExec.VerifyVoteExtension(ctx, vote)
 			cs.metrics.MarkVoteExtensionReceived(err == nil)
 			if err != nil {
+				cs.recordVoteExtensionRejection(vote, voteExtensionRejectedApplication, err)
 				return false, err
 			}
 		}
@@ -2721,18 +3705,20 @@ func (cs *State) addVote(
 	}
 
 	height := cs.roundState.Height()
-	added, err = cs.roundState.Votes().AddVote(vote, peerID)
+	added, err = cs.roundState.Votes().AddPreVerifiedVote(vote, peerID, verified)
 	if !added {
 		// Either duplicate, or error upon cs.Votes.AddByIndex()
 		return
 	}
+	cs.recordVoteExtension(vote)
 	if vote.Round == cs.roundState.Round() {
 		vals := cs.state.Validators
 		_, val := vals.GetByIndex(vote.ValidatorIndex)
 		cs.metrics.MarkVoteReceived(vote.Type, val.VotingPower, vals.TotalVotingPower())
 	}
+	cs.markAbsenteeVote(vote)
 
-	if err := cs.eventBus.PublishEventVote(types.EventDataVote{Vote: vote}); err != nil {
+	if err := cs.publishEvent(types.EventVoteValue, types.EventDataVote{Vote: vote}); err != nil {
 		return added, err
 	}
 	cs.evsw.FireEvent(types.EventVoteValue, vote)
@@ -2740,7 +3726,11 @@ func (cs *State) addVote(
 	switch vote.Type {
 	case tmproto.PrevoteType:
 		prevotes := cs.roundState.Votes().Prevotes(vote.Round)
-		cs.logger.Debug("added vote to prevote", "vote", vote, "prevotes", prevotes.StringShort())
+		cs.logMsgDebug(msgDebugLogPrevoteAdded, "added vote to prevote", "vote", vote, "prevotes", prevotes.StringShort())
+		cs.markProposalPrevote(vote, prevotes)
+		cs.markPolka(vote, prevotes)
+		cs.recordPrevotePeer(vote, peerID)
+		cs.checkProposerPrevote(vote, peerID)
 
 		// Check to see if >2/3 of the voting power on the network voted for any non-nil block.
 		if blockID, ok := prevotes.TwoThirdsMajority(); ok && !blockID.IsNil() {
@@ -2766,15 +3756,16 @@ func (cs *State) addVote(
 				}
 
 				if !cs.roundState.ProposalBlockParts().HasHeader(blockID.PartSetHeader) {
-					cs.metrics.MarkBlockGossipStarted()
+					cs.markBlockGossipStarted(blockID.PartSetHeader)
 					cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(blockID.PartSetHeader))
 				}
 
 				roundState := cs.roundState.CopyInternal()
 				cs.evsw.FireEvent(types.EventValidBlockValue, roundState)
-				if err := cs.eventBus.PublishEventValidBlock(cs.roundState.RoundStateEvent()); err != nil {
+				if err := cs.publishEvent(types.EventValidBlockValue, cs.roundState.RoundStateEvent()); err != nil {
 					return added, err
 				}
+				cs.recordRingEvent(EventRingRecord{Kind: EventRingValidBlock, BlockHash: blockID.Hash})
 			}
 		}
 
@@ -2783,17 +3774,20 @@ func (cs *State) addVote(
 		switch {
 		case cs.roundState.Round() < vote.Round && prevotes.HasTwoThirdsAny():
 			// Round-skip if there is any 2/3+ of votes ahead of us
-			cs.enterNewRound(ctx, height, vote.Round, "prevote-future")
+			cs.skipToRound(ctx, height, vote.Round, "prevote-future", prevotes)
 
 		case cs.roundState.Round() == vote.Round && cstypes.RoundStepPrevote <= cs.roundState.Step(): // current round
 			blockID, ok := prevotes.TwoThirdsMajority()
 			if ok && (cs.isProposalComplete() || blockID.IsNil()) {
-				cs.enterPrecommit(ctx, height, vote.Round, "prevote-future")
+				if !cs.delayPrecommit(height, vote.Round) {
+					cs.enterPrecommit(ctx, height, vote.Round, "prevote-future")
+				}
 			} else if prevotes.HasTwoThirdsAny() {
 				cs.enterPrevoteWait(height, vote.Round)
 			}
 
 		case cs.roundState.Proposal() != nil && 0 <= cs.roundState.Proposal().POLRound && cs.roundState.Proposal().POLRound == vote.Round:
+			cs.markPOLReceived(vote.Round)
 			// If the proposal is now complete, enter prevote of cs.Round.
 			if cs.isProposalComplete() {
 				cs.enterPrevote(ctx, height, cs.roundState.Round(), "prevote-future")
@@ -2802,7 +3796,9 @@ func (cs *State) addVote(
 
 	case tmproto.PrecommitType:
 		precommits := cs.roundState.Votes().Precommits(vote.Round)
-		cs.logger.Debug("added vote to precommit",
+		cs.recordPrecommitPeer(vote, peerID)
+		cs.addPrecommitSource(vote, peerID)
+		cs.logMsgDebug(msgDebugLogPrecommitAdded, "added vote to precommit",
 			"height", vote.Height,
 			"round", vote.Round,
 			"validator", vote.ValidatorAddress.String(),
@@ -2818,14 +3814,18 @@ func (cs *State) addVote(
 
 			if !blockID.IsNil() {
 				cs.enterCommit(ctx, height, vote.Round, "precommit-two-thirds")
-				if cs.bypassCommitTimeout() && precommits.HasAll() {
+				if precommits.HasAll() && cs.canBypassCommitTimeout(height, vote.Round) {
 					cs.enterNewRound(ctx, cs.roundState.Height(), 0, "precommit-skip-round")
 				}
 			} else {
 				cs.enterPrecommitWait(height, vote.Round)
 			}
 		} else if cs.roundState.Round() <= vote.Round && precommits.HasTwoThirdsAny() {
-			cs.enterNewRound(ctx, height, vote.Round, "precommit-two-thirds-any")
+			if cs.roundState.Round() < vote.Round {
+				cs.skipToRound(ctx, height, vote.Round, "precommit-two-thirds-any", precommits)
+			} else {
+				cs.enterNewRound(ctx, height, vote.Round, "precommit-two-thirds-any")
+			}
 			cs.enterPrecommitWait(height, vote.Round)
 		}
 
@@ -2843,107 +3843,88 @@ func (cs *State) signVote(
 	hash []byte,
 	header types.PartSetHeader,
 ) (*types.Vote, error) {
-	// Flush the WAL. Otherwise, we may not recompute the same vote to sign,
-	// and the privValidator will refuse to sign anything.
-	if err := cs.wal.FlushAndSync(); err != nil {
+	req, err := cs.newVoteSignRequest(ctx, msgType, hash, header)
+	if err != nil {
 		return nil, err
 	}
-
-	if cs.privValidatorPubKey == nil {
-		return nil, errPubKeyIsNotSet
-	}
-
-	addr := cs.privValidatorPubKey.Address()
-	valIdx, _ := cs.roundState.Validators().GetByAddress(addr)
-
-	vote := &types.Vote{
-		ValidatorAddress: addr,
-		ValidatorIndex:   valIdx,
-		Height:           cs.roundState.Height(),
-		Round:            cs.roundState.Round(),
-		Timestamp:        tmtime.Now(),
-		Type:             msgType,
-		BlockID:          types.BlockID{Hash: hash, PartSetHeader: header},
-	}
-
-	// If the signedMessageType is for precommit,
-	// use our local precommit Timeout as the max wait time for getting a singed commit. The same goes for prevote.
-	timeout := time.Second
-	if msgType == tmproto.PrecommitType && !vote.BlockID.IsNil() {
-		timeout = cs.voteTimeout(cs.roundState.Round())
-		// if the signedMessage type is for a non-nil precommit, add
-		// VoteExtension
-		if cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(cs.roundState.Height()) {
-			ext, err := cs.blockExec.ExtendVote(ctx, vote)
-			if err != nil {
-				return nil, err
-			}
-			vote.Extension = ext
-		}
-	}
-
-	v := vote.ToProto()
-
-	ctxto, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	err := cs.privValidator.SignVote(ctxto, cs.state.ChainID, v)
-	vote.Signature = v.Signature
-	vote.ExtensionSignature = v.ExtensionSignature
-	vote.Timestamp = v.Timestamp
-
-	return vote, err
+	return cs.signVoteRequest(ctx, req)
 }
 
-// sign the vote and publish on internalMsgQueue
+// signAddVote decides on the vote. It is signed with cs.mtx released, then
+// published on internalMsgQueue.
 func (cs *State) signAddVote(
 	ctx context.Context,
 	msgType tmproto.SignedMsgType,
 	hash []byte,
 	header types.PartSetHeader,
-) *types.Vote {
-	if cs.privValidator == nil { // the node does not have a key
-		return nil
+) {
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator == nil { // the node does not have a key
+		return
 	}
 
-	if cs.privValidatorPubKey == nil {
+	if pubKey == nil {
 		// Vote won't be signed, but it's not critical.
 		cs.logger.Error("signAddVote", "err", errPubKeyIsNotSet)
-		return nil
+		return
 	}
 
 	// If the node not in the validator set, do nothing.
-	if !cs.roundState.Validators().HasAddress(cs.privValidatorPubKey.Address()) {
-		return nil
+	if !cs.roundState.Validators().HasAddress(pubKey.Address()) {
+		cs.notValidatorLogger(cs.logger)("not voting since node is not in the validator set",
+			"height", cs.roundState.Height(), "round", cs.roundState.Round(), "type", msgType)
+		return
 	}
 
-	// TODO: pass pubKey to signVote
-	vote, err := cs.signVote(ctx, msgType, hash, header)
-	if err != nil {
-		cs.logger.Error("failed signing vote", "height", cs.roundState.Height(), "round", cs.roundState.Round(), "vote", vote, "err", err)
-		return nil
+	if cs.SigningHalted() {
+		cs.logger.Debug("not voting since signing is halted", "height", cs.roundState.Height(), "round", cs.roundState.Round())
+		return
 	}
-	if !cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(vote.Height) {
-		// The signer will sign the extension, make sure to remove the data on the way out
-		vote.StripExtension()
+
+	req, err := cs.newVoteSignRequest(ctx, msgType, hash, header)
+	if err != nil {
+		cs.logger.Error("failed signing vote", "height", cs.roundState.Height(), "round", cs.roundState.Round(), "err", err)
+		return
 	}
-	cs.sendInternalMessage(ctx, msgInfo{&VoteMessage{vote}, "", tmtime.Now()})
-	cs.logger.Info("signed and pushed vote", "height", cs.roundState.Height(), "round", cs.roundState.Round(), "vote", vote)
-	return vote
+	cs.submitVoteSignRequest(ctx, req)
 }
 
 // updatePrivValidatorPubKey get's the private validator public key and
 // memoizes it. This func returns an error if the private validator is not
 // responding or responds with an error.
 func (cs *State) updatePrivValidatorPubKey(rctx context.Context) error {
-	if cs.privValidator == nil {
+	cs.privValidatorMtx.RLock()
+	privValidator, pvType, gen := cs.privValidator, cs.privValidatorType, cs.privValidatorGen
+	cs.privValidatorMtx.RUnlock()
+	if privValidator == nil {
 		return nil
 	}
 
+	pubKey, err := cs.fetchPrivValidatorPubKey(rctx, privValidator, pvType)
+	if err != nil {
+		return err
+	}
+
+	cs.privValidatorMtx.Lock()
+	defer cs.privValidatorMtx.Unlock()
+	// the private validator may have been replaced while its key was fetched
+	if cs.privValidatorGen == gen {
+		cs.privValidatorPubKey = pubKey
+	}
+	return nil
+}
+
+// fetchPrivValidatorPubKey requests the public key of privValidator, with a
+// timeout depending on the State step.
+func (cs *State) fetchPrivValidatorPubKey(
+	rctx context.Context,
+	privValidator types.PrivValidator,
+	pvType types.PrivValidatorType,
+) (crypto.PubKey, error) {
 	timeout := cs.voteTimeout(cs.roundState.Round())
 
 	// no GetPubKey retry beyond the proposal/voting in RetrySignerClient
-	if cs.roundState.Step() >= cstypes.RoundStepPrecommit && cs.privValidatorType == types.RetrySignerClient {
+	if cs.roundState.Step() >= cstypes.RoundStepPrecommit && pvType == types.RetrySignerClient {
 		timeout = 0
 	}
 
@@ -2951,18 +3932,26 @@ func (cs *State) updatePrivValidatorPubKey(rctx context.Context) error {
 	// this helps in avoiding blocking of the remote signer connection.
 	ctxto, cancel := context.WithTimeout(rctx, timeout)
 	defer cancel()
-	pubKey, err := cs.privValidator.GetPubKey(ctxto)
-	if err != nil {
-		return err
-	}
-	cs.privValidatorPubKey = pubKey
-	return nil
+	return privValidator.GetPubKey(ctxto)
 }
 
 // look back to check existence of the node's consensus votes before joining consensus
 func (cs *State) checkDoubleSigningRisk(height int64) error {
-	if cs.privValidator != nil && cs.privValidatorPubKey != nil && cs.config.DoubleSignCheckHeight > 0 && height > 0 {
-		valAddr := cs.privValidatorPubKey.Address()
+	refusal, err := cs.loadDoubleSignRefusal()
+	if err != nil {
+		return err
+	}
+	if refusal != nil {
+		cs.logger.Error("private validator refused to sign a conflicting proposal before the last restart; "+
+			"remove the marker file once the cause is resolved",
+			"height", refusal.Height, "round", refusal.Round, "err", refusal.Error, "time", refusal.Time,
+			"file", cs.doubleSignRefusalPath())
+		return ErrDoubleSignRefusalRecorded
+	}
+
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator != nil && pubKey != nil && cs.config.DoubleSignCheckHeight > 0 && height > 0 {
+		valAddr := pubKey.Address()
 		doubleSignCheckHeight := cs.config.DoubleSignCheckHeight
 		if doubleSignCheckHeight > height {
 			doubleSignCheckHeight = height
@@ -2973,8 +3962,11 @@ func (cs *State) checkDoubleSigningRisk(height int64) error {
 			if lastCommit != nil {
 				for sigIdx, s := range lastCommit.Signatures {
 					if s.BlockIDFlag == types.BlockIDFlagCommit && bytes.Equal(s.ValidatorAddress, valAddr) {
-						cs.logger.Info("found signature from the same key", "sig", s, "idx", sigIdx, "height", height-i)
-						return ErrSignatureFoundInPastBlocks
+						return cs.checkPastBlockSignature(height, &ErrPastBlockSignature{
+							Height:    height - i,
+							Index:     sigIdx,
+							Signature: s,
+						})
 					}
 				}
 			}
@@ -3068,7 +4060,7 @@ func repairWalFile(src, dst string) error {
 
 func (cs *State) proposeTimeout(round int32) time.Duration {
 	tp := cs.state.ConsensusParams.Timeout.TimeoutParamsOrDefaults()
-	p := tp.Propose
+	p := cs.scaleTimeout(tp.Propose)
 	if cs.config.UnsafeProposeTimeoutOverride != 0 {
 		p = cs.config.UnsafeProposeTimeoutOverride
 	}
@@ -3083,7 +4075,7 @@ func (cs *State) proposeTimeout(round int32) time.Duration {
 
 func (cs *State) voteTimeout(round int32) time.Duration {
 	tp := cs.state.ConsensusParams.Timeout.TimeoutParamsOrDefaults()
-	v := tp.Vote
+	v := cs.scaleTimeout(tp.Vote)
 	if cs.config.UnsafeVoteTimeoutOverride != 0 {
 		v = cs.config.UnsafeVoteTimeoutOverride
 	}
@@ -3112,6 +4104,11 @@ func (cs *State) bypassCommitTimeout() bool {
 }
 
 func (cs *State) calculateProposalTimestampDifferenceMetric() {
+	// the timeliness of a proposal received once its height is committed
+	// says nothing of the proposer
+	if cs.roundState.Step() >= cstypes.RoundStepCommit {
+		return
+	}
 	if cs.roundState.Proposal() != nil && cs.roundState.Proposal().POLRound == -1 {
 		sp := cs.state.ConsensusParams.Synchrony.SynchronyParamsOrDefaults()
 		isTimely := cs.roundState.Proposal().IsTimely(cs.roundState.ProposalReceiveTime(), sp, cs.roundState.Round())
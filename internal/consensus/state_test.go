@@ -3,26 +3,47 @@ package consensus
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	otrace "go.opentelemetry.io/otel/trace"
 
+	abciclient "github.com/tendermint/tendermint/abci/client"
 	"github.com/tendermint/tendermint/abci/example/kvstore"
 	abci "github.com/tendermint/tendermint/abci/types"
 	abcimocks "github.com/tendermint/tendermint/abci/types/mocks"
 	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/encoding"
 	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
 	"github.com/tendermint/tendermint/internal/eventbus"
+	"github.com/tendermint/tendermint/internal/mempool"
 	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
 	tmquery "github.com/tendermint/tendermint/internal/pubsub/query"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/internal/store"
 	"github.com/tendermint/tendermint/internal/test/factory"
 	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmevents "github.com/tendermint/tendermint/libs/events"
 	"github.com/tendermint/tendermint/libs/log"
 	tmrand "github.com/tendermint/tendermint/libs/rand"
 	tmtime "github.com/tendermint/tendermint/libs/time"
+	"github.com/tendermint/tendermint/privval"
 	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 	"github.com/tendermint/tendermint/types"
 )
@@ -835,6 +856,114 @@ func TestStateLock_POLRelock(t *testing.T) {
 
 	// We should now be locked on the same block but with an updated locked round.
 	validatePrecommit(ctx, t, cs1, round, round, vss[0], blockID.Hash, blockID.Hash)
+
+	// Check that both the lock and the relock were recorded.
+	history := cs1.LockHistory()
+	require.Len(t, history, 2)
+	for i, trigger := range []LockTrigger{LockTriggerNewLock, LockTriggerPolkaRelock} {
+		require.Equal(t, height, history[i].Height)
+		require.Equal(t, int32(i), history[i].Round)
+		require.Equal(t, blockID.Hash, history[i].BlockHash)
+		require.Equal(t, trigger, history[i].Trigger)
+		// we lock as soon as the prevotes of cs1, vs2 and vs3 form a quorum
+		require.EqualValues(t, 3, history[i].PrevotePower)
+		require.EqualValues(t, 4, history[i].TotalPower)
+	}
+}
+
+// TestStateLockInfo tests the lock info reported as the validator locks,
+// relocks and commits a block.
+func TestStateLockInfo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := configSetup(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cs1, vss := makeState(ctx, t, makeStateArgs{
+		config:  config,
+		options: []StateOption{WithTracerProvider(tp)},
+	})
+	vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+	height, round := cs1.roundState.Height(), cs1.roundState.Round()
+
+	timeoutWaitCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryTimeoutWait)
+	proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+	newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+	newBlockCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewBlock)
+
+	requireLockInfo := func(expected LockInfo) {
+		t.Helper()
+		require.Equal(t, expected, cs1.LockInfo())
+		require.Equal(t, expected, cs1.Status().Lock)
+	}
+	requireLockInfo(LockInfo{Height: height, LockedRound: -1, ValidRound: -1})
+
+	// round 0: cs1 proposes a block and locks on it
+	startTestRound(ctx, cs1, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+	ensureNewProposal(t, proposalCh, height, round)
+	rs := cs1.GetRoundState()
+	theBlock, theBlockParts := rs.ProposalBlock, rs.ProposalBlockParts
+	blockID := types.BlockID{Hash: theBlock.Hash(), PartSetHeader: theBlockParts.Header()}
+	ensurePrevote(t, voteCh, height, round)
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3, vs4)
+	ensurePrecommit(t, voteCh, height, round)
+	requireLockInfo(LockInfo{
+		Height:          height,
+		LockedRound:     0,
+		LockedBlockHash: blockID.Hash,
+		ValidRound:      0,
+		ValidBlockHash:  blockID.Hash,
+		LockedIsValid:   true,
+	})
+
+	signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), types.BlockID{}, vs2, vs3, vs4)
+	ensureNewTimeout(t, timeoutWaitCh, height, round, cs1.voteTimeout(round).Nanoseconds())
+
+	// round 1: the block is proposed again and cs1 relocks on it
+	incrementRound(vs2, vs3, vs4)
+	round++
+	pubKey, err := vss[0].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	propR1 := types.NewProposal(height, round, cs1.roundState.ValidRound(), blockID, theBlock.Header.Time, theBlock.GetTxKeys(), theBlock.Header, theBlock.LastCommit, theBlock.Evidence, pubKey.Address())
+	p := propR1.ToProto()
+	require.NoError(t, vs2.SignProposal(ctx, cs1.state.ChainID, p))
+	propR1.Signature = p.Signature
+	require.NoError(t, cs1.SetProposalAndBlock(ctx, propR1, theBlock, theBlockParts, ""))
+	ensureNewRound(t, newRoundCh, height, round)
+	ensureNewProposal(t, proposalCh, height, round)
+	ensurePrevote(t, voteCh, height, round)
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3, vs4)
+	ensurePrecommit(t, voteCh, height, round)
+	requireLockInfo(LockInfo{
+		Height:          height,
+		LockedRound:     1,
+		LockedBlockHash: blockID.Hash,
+		ValidRound:      1,
+		ValidBlockHash:  blockID.Hash,
+		LockedIsValid:   true,
+	})
+
+	// the lock info is added to the span of the relock
+	var attrs []attribute.KeyValue
+	for _, span := range exporter.GetSpans() {
+		for _, attr := range span.Attributes {
+			if span.Name == "cs.state.enterPrecommit" && attr.Key == "lock.trigger" {
+				attrs = span.Attributes
+			}
+		}
+	}
+	require.Contains(t, attrs, attribute.Int("lock.locked_round", 1))
+	require.Contains(t, attrs, attribute.Bool("lock.locked_is_valid", true))
+
+	// the block is committed and the next height starts unlocked
+	signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), blockID, vs2, vs3, vs4)
+	ensureNewBlock(t, newBlockCh, height)
+	requireLockInfo(LockInfo{Height: height + 1, LockedRound: -1, ValidRound: -1})
 }
 
 // TestStateLock_PrevoteNilWhenLockedAndMissProposal tests that a validator prevotes nil
@@ -2207,6 +2336,98 @@ func TestVerifyVoteExtensionNotCalledOnAbsentPrecommit(t *testing.T) {
 
 }
 
+// TestVoteExtensionProvider tests that a node configured with a vote extension
+// provider extends its precommits with it instead of calling the application.
+func TestVoteExtensionProvider(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := abcimocks.NewApplication(t)
+	m.On("ProcessProposal", mock.Anything, mock.Anything).Return(&abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_ACCEPT}, nil)
+	m.On("PrepareProposal", mock.Anything, mock.Anything).Return(&abci.ResponsePrepareProposal{}, nil)
+	m.On("VerifyVoteExtension", mock.Anything, mock.Anything).Return(&abci.ResponseVerifyVoteExtension{
+		Status: abci.ResponseVerifyVoteExtension_ACCEPT,
+	}, nil)
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config, application: m})
+	height, round := cs1.roundState.Height(), cs1.roundState.Round()
+	cs1.state.ConsensusParams.ABCI.VoteExtensionsEnableHeight = cs1.roundState.Height()
+	cs1.voteExtensionProvider = func(_ context.Context, vote *types.Vote) ([]byte, error) {
+		return []byte(fmt.Sprintf("provided %d", vote.Height)), nil
+	}
+	require.NoError(t, cs1.checkVoteExtensionProvider(ctx))
+
+	proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+
+	startTestRound(ctx, cs1, height, round)
+	ensureNewProposal(t, proposalCh, height, round)
+	rs := cs1.GetRoundState()
+	blockID := types.BlockID{
+		Hash:          rs.ProposalBlock.Hash(),
+		PartSetHeader: rs.ProposalBlockParts.Header(),
+	}
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vss[1:]...)
+	ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+
+	msg := ensureMessageBeforeTimeout(t, voteCh, ensureTimeout)
+	precommit := msg.Data().(types.EventDataVote).Vote
+	require.Equal(t, tmproto.PrecommitType, precommit.Type)
+	require.Equal(t, []byte(fmt.Sprintf("provided %d", height)), precommit.Extension)
+	require.NotEmpty(t, precommit.ExtensionSignature)
+
+	m.AssertNotCalled(t, "ExtendVote", mock.Anything, mock.Anything)
+}
+
+// TestVoteExtensionProviderSelfCheck tests that the self-check of the vote
+// extension provider only runs when vote extensions are enabled and the node
+// has a key, and that the State starts although the application rejects the
+// extensions produced by the provider.
+func TestVoteExtensionProviderSelfCheck(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := abcimocks.NewApplication(t)
+	m.On("VerifyVoteExtension", mock.Anything, mock.Anything).Return(&abci.ResponseVerifyVoteExtension{
+		Status: abci.ResponseVerifyVoteExtension_REJECT,
+	}, nil)
+	m.On("PrepareProposal", mock.Anything, mock.Anything).Return(&abci.ResponsePrepareProposal{}, nil).Maybe()
+	m.On("ProcessProposal", mock.Anything, mock.Anything).Return(&abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_ACCEPT}, nil).Maybe()
+	cs1, _ := makeState(ctx, t, makeStateArgs{config: config, application: m})
+	var provided int
+	cs1.voteExtensionProvider = func(context.Context, *types.Vote) ([]byte, error) {
+		provided++
+		return []byte("rejected"), nil
+	}
+
+	// vote extensions are disabled
+	cs1.state.ConsensusParams.ABCI.VoteExtensionsEnableHeight = 0
+	require.NoError(t, cs1.checkVoteExtensionProvider(ctx))
+	require.Zero(t, provided)
+
+	// the node has no key
+	cs1.state.ConsensusParams.ABCI.VoteExtensionsEnableHeight = cs1.state.LastBlockHeight + 1
+	privValidator := cs1.privValidator
+	cs1.SetPrivValidator(ctx, nil)
+	require.NoError(t, cs1.checkVoteExtensionProvider(ctx))
+	require.Zero(t, provided)
+	cs1.SetPrivValidator(ctx, privValidator)
+
+	err := cs1.checkVoteExtensionProvider(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "vote extension provider self-check")
+	require.Equal(t, 1, provided)
+	m.AssertCalled(t, "VerifyVoteExtension", mock.Anything, mock.MatchedBy(func(req *abci.RequestVerifyVoteExtension) bool {
+		return bytes.Equal(req.VoteExtension, []byte("rejected"))
+	}))
+
+	require.NoError(t, cs1.Start(ctx))
+	require.True(t, cs1.IsRunning())
+}
+
 // TestPrepareProposalReceivesVoteExtensions tests that the PrepareProposal method
 // is called with the vote extensions from the previous height. The test functions
 // be completing a consensus height with a mock application as the proposer. The
@@ -2602,6 +2823,214 @@ func TestEmitNewValidBlockEventOnCommitWithoutBlock(t *testing.T) {
 
 }
 
+// What we want:
+// P0 restarts after a previous process saved B to the block store, but not to
+// the round state. P0 receives 2/3+ Precommit for B: it loads B from the block
+// store and finalizes without waiting for the block parts.
+func TestCommitBlockFromBlockStore(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+	height, round := cs1.roundState.Height(), int32(1)
+
+	incrementRound(vs2, vs3, vs4)
+
+	newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+	newBlockCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewBlock)
+
+	_, propBlock := decideProposal(ctx, t, cs1, vs2, vs2.Height, vs2.Round)
+	partSet, err := propBlock.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{
+		Hash:          propBlock.Hash(),
+		PartSetHeader: partSet.Header(),
+	}
+	cs1.blockStore.SaveBlock(propBlock, partSet, &types.Commit{Height: height, BlockID: blockID})
+
+	// start round in which PO is not proposer
+	startTestRound(ctx, cs1, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+
+	// vs2, vs3 and vs4 send precommit for propBlock
+	signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), blockID, vs2, vs3, vs4)
+	ensureNewBlock(t, newBlockCh, height)
+	require.Equal(t, height+1, cs1.GetRoundState().Height)
+}
+
+// What we want:
+// P0 receives the proposal of A, while B is in the block store. P0 receives
+// 2/3+ Prevote for B: it does not pair B with the proposal of A, and
+// precommits nil while waiting for B.
+func TestPrecommitIgnoresStoredBlockOfOtherProposal(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+	height, round := cs1.roundState.Height(), int32(1)
+
+	incrementRound(vs2, vs3, vs4)
+
+	newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+	proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+
+	proposal, blockA := decideProposal(ctx, t, cs1, vs2, vs2.Height, vs2.Round)
+	partsA, err := blockA.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+
+	blockB := cs1.state.MakeBlock(height, types.Txs{types.Tx("other")}, blockA.LastCommit, nil, blockA.ProposerAddress)
+	partsB, err := blockB.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockIDB := types.BlockID{Hash: blockB.Hash(), PartSetHeader: partsB.Header()}
+	cs1.blockStore.SaveBlock(blockB, partsB, &types.Commit{Height: height, BlockID: blockIDB})
+
+	// start round in which PO is not proposer
+	startTestRound(ctx, cs1, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+	require.NoError(t, cs1.SetProposalAndBlock(ctx, proposal, blockA, partsA, "some peer"))
+	ensureNewProposal(t, proposalCh, height, round)
+	ensurePrevoteMatch(t, voteCh, height, round, blockA.Hash())
+
+	// vs2, vs3 and vs4 prevote for B
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockIDB, vs2, vs3, vs4)
+	ensurePrecommitMatch(t, voteCh, height, round, nil)
+	require.False(t, cs1.GetRoundState().ProposalBlock.HashesTo(blockB.Hash()))
+}
+
+// What we want:
+// P0 receives 2/3+ Precommit for B it does not have. It fires BlockNeeded with
+// the peers the precommits for B were received from, and records the time
+// until it receives B.
+func TestEmitBlockNeededOnCommitWithoutBlock(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+	height, round := cs1.roundState.Height(), int32(1)
+	recovery := generic.NewHistogram("block_recovery_seconds", 2)
+	cs1.metrics.BlockRecoverySeconds = recovery
+
+	incrementRound(vs2, vs3, vs4)
+
+	newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+	blockNeededCh := make(chan *BlockNeeded, 1)
+	require.NoError(t, cs1.evsw.AddListenerForEvent("test", types.EventBlockNeededValue, func(data tmevents.EventData) error {
+		blockNeededCh <- data.(*BlockNeeded)
+		return nil
+	}))
+
+	prop, propBlock := decideProposal(ctx, t, cs1, vs2, vs2.Height, vs2.Round)
+	partSet, err := propBlock.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{
+		Hash:          propBlock.Hash(),
+		PartSetHeader: partSet.Header(),
+	}
+
+	// start round in which PO is not proposer
+	startTestRound(ctx, cs1, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+
+	// a prevote and a precommit of our own peer do not make candidates
+	addVote := func(vs *validatorStub, voteType tmproto.SignedMsgType, peerID types.NodeID) {
+		vote := signVote(ctx, t, vs, voteType, config.ChainID(), blockID)
+		cs1.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: peerID}
+	}
+	addVote(vs2, tmproto.PrevoteType, "peer-x")
+	addVote(vs3, tmproto.PrecommitType, "peer-b")
+	addVote(vs2, tmproto.PrecommitType, "peer-a")
+	addVote(vs4, tmproto.PrecommitType, "")
+
+	select {
+	case blockNeeded := <-blockNeededCh:
+		require.Equal(t, &BlockNeeded{
+			Height:  height,
+			Round:   round,
+			BlockID: blockID,
+			Peers:   []types.NodeID{"peer-a", "peer-b"},
+		}, blockNeeded)
+	case <-time.After(ensureTimeout):
+		t.Fatal("no BlockNeeded event")
+	}
+
+	require.NoError(t, cs1.SetProposalAndBlock(ctx, prop, propBlock, partSet, "peer-a"))
+	ensureNewRound(t, newRoundCh, height+1, 0)
+	require.Positive(t, recovery.Quantile(0.5))
+}
+
+// What we want:
+// P0 skips round 0 and receives a proposal for B in round 1 with POLRound 0,
+// whose prevotes it never saw. It solicits them with POLNeeded and prevotes B
+// once they arrive.
+func TestSolicitPOLOfProposalFromSkippedRound(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+	height, round := cs1.roundState.Height(), int32(1)
+	awaitingPOL := generic.NewGauge("awaiting_pol")
+	solicitations := generic.NewCounter("pol_solicitations")
+	cs1.metrics.AwaitingPOL = awaitingPOL
+	cs1.metrics.POLSolicitations = solicitations
+
+	newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+	polNeededCh := make(chan *POLNeeded, 1)
+	require.NoError(t, cs1.evsw.AddListenerForEvent("test", types.EventPOLNeededValue, func(data tmevents.EventData) error {
+		polNeededCh <- data.(*POLNeeded)
+		return nil
+	}))
+
+	// the proposal of round 1 claims a POL in round 0
+	prop, propBlock := decideProposal(ctx, t, cs1, vs2, height, round)
+	prop.POLRound = 0
+	p := prop.ToProto()
+	require.NoError(t, vs2.SignProposal(ctx, config.ChainID(), p))
+	prop.Signature = p.Signature
+	partSet, err := propBlock.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: propBlock.Hash(), PartSetHeader: partSet.Header()}
+	polPrevotes := signVotes(ctx, t, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3, vs4)
+
+	startTestRound(ctx, cs1, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+
+	require.NoError(t, cs1.SetProposalAndBlock(ctx, prop, propBlock, partSet, "peer-a"))
+	select {
+	case polNeeded := <-polNeededCh:
+		require.Equal(t, height, polNeeded.Height)
+		require.Equal(t, round, polNeeded.Round)
+		require.Equal(t, int32(0), polNeeded.POLRound)
+		require.Equal(t, blockID, polNeeded.BlockID)
+		require.True(t, polNeeded.Prevotes.IsEmpty())
+	case <-time.After(ensureTimeout):
+		t.Fatal("no POLNeeded event")
+	}
+	require.Equal(t, int32(0), cs1.Status().AwaitingPOLRound)
+	require.Equal(t, 1.0, awaitingPOL.Value())
+	require.Equal(t, 1.0, solicitations.Value())
+
+	// the proposal is complete once the POL arrives
+	addVotes(cs1, polPrevotes...)
+	ensurePrevoteMatch(t, voteCh, height, round, propBlock.Hash())
+	require.Equal(t, int32(-1), cs1.Status().AwaitingPOLRound)
+	require.Equal(t, 0.0, awaitingPOL.Value())
+	require.Equal(t, 1.0, solicitations.Value())
+}
+
 // What we want:
 // P0 receives 2/3+ Precommit for B for round 0, while being in round 1. It emits NewValidBlock event.
 // After receiving block, it executes block and moves to the next height.
@@ -2726,10 +3155,11 @@ func TestStartNextHeightCorrectlyAfterTimeout(t *testing.T) {
 
 	ensureNewTimeout(t, timeoutProposeCh, height+1, round, cs1.proposeTimeout(round).Nanoseconds())
 	rs = cs1.GetRoundState()
-	assert.False(
+	assert.EqualValues(
 		t,
-		rs.TriggeredTimeoutPrecommit,
-		"triggeredTimeoutPrecommit should be false at the beginning of each round")
+		-1,
+		rs.TriggeredTimeoutPrecommitRound,
+		"triggeredTimeoutPrecommitRound should be -1 at the beginning of each round")
 }
 
 func TestResetTimeoutPrecommitUponNewHeight(t *testing.T) {
@@ -2788,10 +3218,98 @@ func TestResetTimeoutPrecommitUponNewHeight(t *testing.T) {
 	ensureNewProposal(t, proposalCh, height+1, 0)
 
 	rs = cs1.GetRoundState()
-	assert.False(
+	assert.EqualValues(
 		t,
-		rs.TriggeredTimeoutPrecommit,
-		"triggeredTimeoutPrecommit should be false at the beginning of each height")
+		-1,
+		rs.TriggeredTimeoutPrecommitRound,
+		"triggeredTimeoutPrecommitRound should be -1 at the beginning of each height")
+}
+
+// recordingTicker records the timeouts scheduled instead of firing them.
+type recordingTicker struct {
+	mtx       sync.Mutex
+	scheduled []timeoutInfo
+}
+
+func (r *recordingTicker) Start(context.Context) error { return nil }
+func (r *recordingTicker) Stop()                       {}
+func (r *recordingTicker) IsRunning() bool             { return false }
+func (r *recordingTicker) Chan() <-chan timeoutInfo    { return nil }
+
+func (r *recordingTicker) ScheduleTimeout(ti timeoutInfo) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.scheduled = append(r.scheduled, ti)
+}
+
+// precommitWaits returns the rounds the precommit wait was scheduled for.
+func (r *recordingTicker) precommitWaits() []int32 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	var rounds []int32
+	for _, ti := range r.scheduled {
+		if ti.Step == cstypes.RoundStepPrecommitWait {
+			rounds = append(rounds, ti.Round)
+		}
+	}
+	return rounds
+}
+
+// The precommit wait flag of a previous round, left over when the round
+// advanced while a tock of that round was still in flight, must not prevent
+// the precommit wait of the current round.
+func TestStatePrecommitWaitFlagIsPerRound(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	ticker := &recordingTicker{}
+	cs1.timeoutTicker = ticker
+	skipped := generic.NewCounter("precommit_wait_skipped")
+	cs1.metrics.PrecommitWaitSkipped = skipped
+	height := cs1.roundState.Height()
+
+	addNilPrecommits := func(round int32) {
+		cs1.roundState.Votes().SetRound(round + 1)
+		for _, vote := range signVotes(ctx, t, tmproto.PrecommitType, config.ChainID(), types.BlockID{}, vss[1:3]...) {
+			_, err := cs1.roundState.Votes().AddVote(vote, "peer")
+			require.NoError(t, err)
+		}
+	}
+
+	// +2/3 any precommits in round 0 trigger its precommit wait, once
+	cs1.enterNewRound(ctx, height, 0, "test")
+	vss[1].Height, vss[2].Height = height, height
+	addNilPrecommits(0)
+	vss[0].Height = height
+	vote := signVote(ctx, t, vss[0], tmproto.PrecommitType, config.ChainID(), types.BlockID{})
+	_, err := cs1.roundState.Votes().AddVote(vote, "")
+	require.NoError(t, err)
+	cs1.enterPrecommitWait(height, 0)
+	require.Equal(t, []int32{0}, ticker.precommitWaits())
+	require.EqualValues(t, 0, cs1.roundState.TriggeredTimeoutPrecommitRound())
+	cs1.enterPrecommitWait(height, 0)
+	require.Equal(t, []int32{0}, ticker.precommitWaits())
+	require.Equal(t, 1.0, skipped.Value())
+
+	// the round advances, but the flag of round 0 is left over
+	cs1.enterNewRound(ctx, height, 1, "test")
+	cs1.roundState.SetTriggeredTimeoutPrecommitRound(0)
+	// the delayed tock of the precommit wait of round 0 arrives
+	cs1.handleTimeout(ctx, timeoutInfo{Height: height, Round: 0, Step: cstypes.RoundStepPrecommitWait}, *cs1.roundState.CopyInternal())
+	require.EqualValues(t, 1, cs1.roundState.Round())
+
+	// +2/3 any precommits in round 1 still trigger its precommit wait
+	incrementRound(vss...)
+	addNilPrecommits(1)
+	vote = signVote(ctx, t, vss[0], tmproto.PrecommitType, config.ChainID(), types.BlockID{})
+	_, err = cs1.roundState.Votes().AddVote(vote, "")
+	require.NoError(t, err)
+	cs1.enterPrecommitWait(height, 1)
+	require.Equal(t, []int32{0, 1}, ticker.precommitWaits())
+	require.EqualValues(t, 1, cs1.roundState.TriggeredTimeoutPrecommitRound())
+	require.Equal(t, 1.0, skipped.Value())
 }
 
 //------------------------------------------------------------------------------------------
@@ -2875,45 +3393,119 @@ func TestStateHalt1(t *testing.T) {
 	ensureNewRound(t, newRoundCh, height+1, 0)
 }
 
-func TestStateOutputsBlockPartsStats(t *testing.T) {
+func TestStateStopWaitsForRoutines(t *testing.T) {
+	config := configSetup(t)
+
+	// the States are started with a context outliving them, so that only
+	// Stop ends their routines
+	startCtx, cancelStart := context.WithCancel(context.Background())
+	defer cancelStart()
+
+	churn := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+		require.NoError(t, cs.Start(startCtx))
+		require.Eventually(t, func() bool { return cs.blockStore.Height() >= 1 },
+			10*time.Second, 10*time.Millisecond)
+		cs.Stop()
+	}
+
+	// the first start also starts process-wide goroutines, such as the
+	// signal handler of the WAL files
+	churn()
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		churn()
+	}
+	require.Eventually(t, func() bool { return runtime.NumGoroutine() <= before },
+		5*time.Second, 10*time.Millisecond, "goroutines leaked by Stop")
+}
+
+func TestStatePublishesBlockApplied(t *testing.T) {
 	config := configSetup(t)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// create dummy peer
 	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
-	peerID, err := types.NewNodeID("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	height := cs.roundState.Height()
+	sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryBlockApplied,
+		Limit:    10,
+	})
 	require.NoError(t, err)
 
-	// 1) new block part
-	parts := types.NewPartSetFromData(tmrand.Bytes(100), 10)
-	msg := &BlockPartMessage{
-		Height: 1,
-		Round:  0,
-		Part:   parts.GetPart(0),
-	}
+	lastHeight, _ := cs.LastApplied()
+	require.Equal(t, height-1, lastHeight)
 
-	cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(parts.Header()))
-	cs.handleMsg(ctx, msgInfo{msg, peerID, tmtime.Now()}, false)
+	require.NoError(t, cs.Start(ctx))
+
+	var events []types.EventDataBlockApplied
+	for len(events) < 3 {
+		nextCtx, nextCancel := context.WithTimeout(ctx, 10*time.Second)
+		msg, err := sub.Next(nextCtx)
+		nextCancel()
+		require.NoError(t, err, "no BlockApplied event")
+		event := msg.Data().(types.EventDataBlockApplied)
+		events = append(events, event)
+
+		lastHeight, _ := cs.LastApplied()
+		require.GreaterOrEqual(t, lastHeight, event.Height)
+	}
+
+	// once per height, with the app hash the next block commits to
+	for i, event := range events {
+		require.Equal(t, height+int64(i), event.Height)
+		require.Equal(t, cs.blockStore.LoadBlockMeta(event.Height).BlockID.Hash, event.BlockHash)
+		require.Positive(t, event.ApplyDuration)
+		if i < len(events)-1 {
+			require.Equal(t, cs.blockStore.LoadBlock(event.Height+1).AppHash, event.AppHash)
+		}
+	}
+}
+
+func TestStateOutputsBlockPartsStats(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// create dummy peer
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	peerID, err := types.NewNodeID("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	require.NoError(t, err)
+
+	// 1) new block part
+	parts := types.NewPartSetFromData(tmrand.Bytes(100), 10)
+	msg := &BlockPartMessage{
+		Height: 1,
+		Round:  0,
+		Part:   parts.GetPart(0),
+	}
+
+	cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(parts.Header()))
+	cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	statsMessage := <-cs.statsMsgQueue
 	require.Equal(t, msg, statsMessage.Msg, "")
 	require.Equal(t, peerID, statsMessage.PeerID, "")
 
 	// sending the same part from different peer
-	cs.handleMsg(ctx, msgInfo{msg, "peer2", tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer2", ReceiveTime: tmtime.Now()}, false)
 
 	// sending the part with the same height, but different round
 	msg.Round = 1
-	cs.handleMsg(ctx, msgInfo{msg, peerID, tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	// sending the part from the smaller height
 	msg.Height = 0
-	cs.handleMsg(ctx, msgInfo{msg, peerID, tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	// sending the part from the bigger height
 	msg.Height = 3
-	cs.handleMsg(ctx, msgInfo{msg, peerID, tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	select {
 	case <-cs.statsMsgQueue:
@@ -2923,6 +3515,122 @@ func TestStateOutputsBlockPartsStats(t *testing.T) {
 
 }
 
+func TestStateBlockPartGossipAmplification(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	amplification := generic.NewHistogram("block_part_amplification", 2)
+	wasted := generic.NewCounter("block_part_wasted_bytes")
+	useless := generic.NewCounter("block_part_useless_bytes")
+	cs.metrics.BlockPartAmplification = amplification
+	cs.metrics.BlockPartWastedBytes = wasted
+	cs.metrics.BlockPartUselessBytes = useless
+	height := cs.roundState.Height()
+
+	parts := types.NewPartSetFromData(tmrand.Bytes(100), 10)
+	otherParts := types.NewPartSetFromData(tmrand.Bytes(100), 10)
+	cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(parts.Header()))
+	send := func(peerID types.NodeID, height int64, round int32, part *types.Part) {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: part}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
+	}
+
+	// all but the last part, some of them twice
+	for i := 0; i < 9; i++ {
+		send("peer1", height, 0, parts.GetPart(i))
+	}
+	send("peer2", height, 0, parts.GetPart(0))
+	send("peer2", height, 1, parts.GetPart(1))
+	// our own parts are not counted
+	send("", height, 0, parts.GetPart(2))
+	require.Equal(t, BlockPartGossip{Height: height, ReceivedBytes: 110}, cs.Status().BlockParts)
+
+	// a part of another block and a part of another height are useless
+	send("peer2", height, 1, otherParts.GetPart(9))
+	send("peer2", height-1, 0, otherParts.GetPart(1))
+	require.Equal(t, BlockPartGossip{Height: height, ReceivedBytes: 120, UselessBytes: 20},
+		cs.Status().BlockParts)
+
+	// with a proposal set, parts of another block are dropped early
+	cs.config.GossipTransactionKeyOnly = true
+	cs.roundState.SetProposal(&types.Proposal{Height: height})
+	send("peer2", height, 0, otherParts.GetPart(2))
+	require.Equal(t, BlockPartGossip{Height: height, ReceivedBytes: 130, UselessBytes: 30},
+		cs.Status().BlockParts)
+	require.Equal(t, 30.0, useless.Value())
+
+	cs.recordBlockPartAmplification(parts.ByteSize())
+	require.Equal(t, 1.3, amplification.Quantile(0.5))
+	require.Equal(t, 30.0, wasted.Value())
+
+	// the numbers are reset for the next height
+	cs.blockPartGossip.reset(height + 1)
+	require.Equal(t, BlockPartGossip{Height: height + 1}, cs.Status().BlockParts)
+}
+
+func TestStatePublishesBlockGossipProgress(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	cs.config.BlockGossipProgressThresholds = []int{25, 50, 75}
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	proposer := cs.roundState.Validators().GetProposer().Address
+	sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryBlockGossip,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+
+	parts := types.NewPartSetFromData(tmrand.Bytes(100), 10)
+	header := parts.Header()
+	cs.markBlockGossipStarted(header)
+	cs.roundState.SetProposalBlockParts(types.NewPartSetFromHeader(header))
+	// the parts, some of them twice
+	for i := 0; i < int(header.Total); i++ {
+		msg := &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)}
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer1", ReceiveTime: tmtime.Now()}, false)
+		cs.handleMsg(ctx, msgInfo{Msg: msg, PeerID: "peer2", ReceiveTime: tmtime.Now()}, false)
+	}
+	// the gossip of the round already started
+	cs.markBlockGossipStarted(header)
+
+	expected := []types.EventDataBlockGossip{
+		{Stage: types.BlockGossipStarted},
+		{Stage: types.BlockGossipProgress, Parts: 3, Percent: 25},
+		{Stage: types.BlockGossipProgress, Parts: 5, Percent: 50},
+		{Stage: types.BlockGossipProgress, Parts: 8, Percent: 75},
+		{Stage: types.BlockGossipComplete, Parts: 10, Percent: 100},
+	}
+	for _, want := range expected {
+		nextCtx, nextCancel := context.WithTimeout(ctx, time.Second)
+		msg, err := sub.Next(nextCtx)
+		nextCancel()
+		require.NoError(t, err, "no %s event", want.Stage)
+		event := msg.Data().(types.EventDataBlockGossip)
+
+		require.Equal(t, height, event.Height)
+		require.Equal(t, round, event.Round)
+		require.Equal(t, proposer, event.ProposerAddress)
+		require.Equal(t, header, event.PartSetHeader)
+		require.Equal(t, want.Stage, event.Stage)
+		require.Equal(t, want.Parts, event.Parts)
+		require.Equal(t, want.Percent, event.Percent)
+		if event.Stage == types.BlockGossipComplete {
+			require.Positive(t, event.Elapsed)
+		}
+	}
+
+	nextCtx, nextCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer nextCancel()
+	_, err = sub.Next(nextCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "each event is published once")
+}
+
 func TestGossipTransactionKeyOnlyConfig(t *testing.T) {
 	config := configSetup(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -2952,7 +3660,7 @@ func TestGossipTransactionKeyOnlyConfig(t *testing.T) {
 	proposalMsg := ProposalMessage{&proposal}
 	peerID, err := types.NewNodeID("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
 	startTestRound(ctx, cs1, height, round)
-	cs1.handleMsg(ctx, msgInfo{&proposalMsg, peerID, time.Now()}, false)
+	cs1.handleMsg(ctx, msgInfo{Msg: &proposalMsg, PeerID: peerID, ReceiveTime: time.Now()}, false)
 	rs := cs1.GetRoundState()
 	// Proposal, ProposalBlock and ProposalBlockParts sohuld be set since gossip-tx-key is true
 	if rs.Proposal == nil {
@@ -2984,20 +3692,20 @@ func TestStateOutputVoteStats(t *testing.T) {
 	vote := signVote(ctx, t, vss[1], tmproto.PrecommitType, config.ChainID(), blockID)
 
 	voteMessage := &VoteMessage{vote}
-	cs.handleMsg(ctx, msgInfo{voteMessage, peerID, tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: voteMessage, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	statsMessage := <-cs.statsMsgQueue
 	require.Equal(t, voteMessage, statsMessage.Msg, "")
 	require.Equal(t, peerID, statsMessage.PeerID, "")
 
 	// sending the same part from different peer
-	cs.handleMsg(ctx, msgInfo{&VoteMessage{vote}, "peer2", tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer2", ReceiveTime: tmtime.Now()}, false)
 
 	// sending the vote for the bigger height
 	incrementHeight(vss[1])
 	vote = signVote(ctx, t, vss[1], tmproto.PrecommitType, config.ChainID(), blockID)
 
-	cs.handleMsg(ctx, msgInfo{&VoteMessage{vote}, peerID, tmtime.Now()}, false)
+	cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: peerID, ReceiveTime: tmtime.Now()}, false)
 
 	select {
 	case <-cs.statsMsgQueue:
@@ -3007,6 +3715,1059 @@ func TestStateOutputVoteStats(t *testing.T) {
 
 }
 
+func TestStateAddVerifiedVote(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	blockID := types.BlockID{Hash: tmrand.Bytes(crypto.HashSize)}
+	vote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), blockID)
+	vote.Signature = tmrand.Bytes(len(vote.Signature))
+
+	// pre-verified votes must be enabled
+	require.ErrorIs(t, cs.AddVerifiedVote(ctx, vote, ""), ErrPreVerifiedVotesDisabled)
+
+	WithPreVerifiedVotes([]types.NodeID{"", "relay"}, false)(cs)
+	require.ErrorIs(t, cs.AddVerifiedVote(ctx, vote, "peer"), ErrUntrustedVoteSender)
+
+	// the bad signature is detected unless the vote was pre-verified
+	require.NoError(t, cs.AddVote(ctx, vote, "relay"))
+	cs.handleMsg(ctx, <-cs.peerMsgQueue, false)
+	require.Nil(t, cs.roundState.Votes().Prevotes(0).GetByIndex(1))
+
+	require.NoError(t, cs.AddVerifiedVote(ctx, vote, "relay"))
+	mi := <-cs.peerMsgQueue
+	require.Equal(t, types.VoteSignatureVerified, mi.Verification)
+	cs.handleMsg(ctx, mi, false)
+	require.Equal(t, vote, cs.roundState.Votes().Prevotes(0).GetByIndex(1))
+
+	// internal senders use the internal queue
+	WithPreVerifiedVotes([]types.NodeID{""}, true)(cs)
+	require.NoError(t, cs.AddVerifiedVote(ctx, vote, ""))
+	mi = <-cs.internalMsgQueue
+	require.Equal(t, types.VoteAndExtensionVerified, mi.Verification)
+}
+
+func TestStateWatchdogDetectsStall(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, validators: 2})
+	timeout := 100 * time.Millisecond
+	cs.config.WatchdogTimeout = timeout
+	stalledCh := subscribe(ctx, t, cs.eventBus, types.EventQueryConsensusStalled)
+
+	cs.startRoutines(ctx, 0)
+	go cs.watchdog(ctx)
+
+	// an idle receive routine is not reported
+	ensureNoMessageBeforeTimeout(t, stalledCh, 3*timeout,
+		"idle receive routine should not be reported as stalled")
+
+	// block handleMsg on the first message while the second one stays queued
+	cs.mtx.Lock()
+	blockID := types.BlockID{Hash: tmrand.Bytes(crypto.HashSize)}
+	vote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), blockID)
+	cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer1", ReceiveTime: tmtime.Now()}
+	cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer2", ReceiveTime: tmtime.Now()}
+
+	msg := ensureMessageBeforeTimeout(t, stalledCh, 10*timeout)
+	stalled, ok := msg.Data().(types.EventDataConsensusStalled)
+	require.True(t, ok)
+	require.Equal(t, cs.roundState.Height(), stalled.Height)
+	require.Equal(t, 1, stalled.PeerMsgQueueSize)
+	require.GreaterOrEqual(t, cs.GetLastActivityAge(), timeout)
+
+	// the stall is reported once
+	ensureNoMessageBeforeTimeout(t, stalledCh, 3*timeout,
+		"stall should only be reported once")
+
+	cs.mtx.Unlock()
+	require.Eventually(t, func() bool {
+		return len(cs.peerMsgQueue) == 0 && cs.GetLastActivityAge() < timeout
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStateVoteExtensionRetention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{validators: 4})
+	cs.voteExtensionRetention = RetainQuorumVoteExtensions
+	incrementHeight(vss[0])
+	cs.state.ConsensusParams.ABCI.VoteExtensionsEnableHeight = 1
+
+	propBlock, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	blockParts, err := propBlock.MakePartSet(types.BlockPartSizeBytes)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: propBlock.Hash(), PartSetHeader: blockParts.Header()}
+
+	voteSet := types.NewExtendedVoteSet(cs.state.ChainID, 1, 0, tmproto.PrecommitType, cs.state.Validators)
+	for _, vote := range signVotes(ctx, t, tmproto.PrecommitType, cs.state.ChainID, blockID, vss...) {
+		added, err := voteSet.AddVote(vote)
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+
+	// the extension of the last validator is not needed for +2/3 of the
+	// voting power, but its signature is kept
+	ec := cs.retainVoteExtensions(voteSet.MakeExtendedCommit(), cs.state.Validators)
+	require.Error(t, ec.EnsureExtensions())
+	require.NoError(t, ec.EnsureQuorumExtensions(cs.state.Validators))
+	for i, ecs := range ec.ExtendedSignatures {
+		require.Equal(t, types.BlockIDFlagCommit, ecs.BlockIDFlag)
+		require.NotEmpty(t, ecs.Signature)
+		require.Equal(t, i < 3, len(ecs.Extension) > 0)
+	}
+	cs.blockStore.SaveBlockWithExtendedCommit(propBlock, blockParts, ec)
+
+	// restart at the next height
+	cs.state.LastBlockHeight = 1
+	cs.state.LastValidators = cs.state.Validators.Copy()
+	cs.roundState.SetHeight(2)
+	cs.reconstructLastCommit(cs.state)
+	require.True(t, cs.roundState.LastCommit().HasAll())
+
+	block, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, block)
+	require.Len(t, block.LastCommit.Signatures, 4)
+	for _, sig := range block.LastCommit.Signatures {
+		require.Equal(t, types.BlockIDFlagCommit, sig.BlockIDFlag)
+	}
+}
+
+func TestStateProposalLatency(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3 := vss[1], vss[2]
+	height, round := cs1.roundState.Height(), cs1.roundState.Round()
+
+	proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+
+	startTestRound(ctx, cs1, height, round)
+	ensureNewProposal(t, proposalCh, height, round)
+	rs := cs1.GetRoundState()
+	blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+	ensurePrevoteMatch(t, voteCh, height, round, blockID.Hash)
+
+	_, ok := cs1.GetLastProposalLatency()
+	require.False(t, ok)
+
+	// the prevotes of the other validators arrive with known delays
+	const delay = 50 * time.Millisecond
+	time.Sleep(delay)
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2)
+	require.Eventually(t, func() bool {
+		return cs1.GetRoundState().Votes.Prevotes(round).GetByIndex(vs2.Index) != nil
+	}, time.Second, time.Millisecond)
+	time.Sleep(delay)
+	signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs3)
+	ensurePrecommit(t, voteCh, height, round)
+
+	latency, ok := cs1.GetLastProposalLatency()
+	require.True(t, ok)
+	require.Equal(t, height, latency.Height)
+	require.Equal(t, round, latency.Round)
+	require.Positive(t, latency.SignedToProcessed)
+	require.GreaterOrEqual(t, latency.ProcessedToFirstPrevote, delay)
+	require.GreaterOrEqual(t, latency.FirstPrevoteToQuorum, delay)
+	require.Less(t, latency.FirstPrevoteToQuorum, 4*delay)
+	require.Equal(t, latency.SignedToQuorum,
+		latency.SignedToProcessed+latency.ProcessedToFirstPrevote+latency.FirstPrevoteToQuorum)
+}
+
+func TestStateStatsQueueDoesNotBlock(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	dropped := generic.NewCounter("stats_msgs_dropped")
+	cs.metrics.StatsMsgsDropped = dropped
+
+	// nothing reads the stats queue, as if the reactor had stalled
+	for len(cs.statsMsgQueue) < cap(cs.statsMsgQueue) {
+		cs.statsMsgQueue <- msgInfo{}
+	}
+
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 3
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Positive(t, dropped.Value())
+	require.Len(t, cs.statsMsgQueue, cap(cs.statsMsgQueue))
+}
+
+// recordingTxNotifier is a txNotifier that records the txs committed hints.
+type recordingTxNotifier struct {
+	txNotifier
+	cs *State
+
+	mtx   sync.Mutex
+	calls []txsCommittedCall
+}
+
+type txsCommittedCall struct {
+	height int64
+	txKeys []types.TxKey
+	// last block height of the consensus state when notified
+	appliedHeight int64
+}
+
+func (n *recordingTxNotifier) TxsCommitted(txKeys []types.TxKey, height int64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.calls = append(n.calls, txsCommittedCall{height: height, txKeys: txKeys, appliedHeight: n.cs.state.LastBlockHeight})
+}
+
+func (n *recordingTxNotifier) Calls() []txsCommittedCall {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return append([]txsCommittedCall(nil), n.calls...)
+}
+
+func TestStateTxsCommittedHint(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	notifier := &recordingTxNotifier{txNotifier: cs.txNotifier, cs: cs}
+	cs.txNotifier = notifier
+
+	mp := assertMempool(t, notifier.txNotifier)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, mp.CheckTx(ctx, []byte(fmt.Sprintf("key%d=value", i)), nil, mempool.TxInfo{}))
+	}
+
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	// the hint is sent once per height, before the block is applied
+	calls := notifier.Calls()
+	require.GreaterOrEqual(t, len(calls), 3)
+	var numTxs int
+	for i, call := range calls {
+		height := int64(i + 1)
+		require.Equal(t, height, call.height)
+		require.Equal(t, height-1, call.appliedHeight)
+		block := cs.blockStore.LoadBlock(height)
+		require.Equal(t, block.GetTxKeys(), call.txKeys)
+		numTxs += len(call.txKeys)
+	}
+	require.Equal(t, 3, numTxs)
+
+	// a height already notified, or a block replayed, is not notified again
+	block := cs.blockStore.LoadBlock(cs.blockStore.Height())
+	cs.notifyTxsCommitted(block)
+	cs.replayMode = true
+	cs.txsCommittedHeight = 0
+	cs.notifyTxsCommitted(block)
+	require.Len(t, notifier.Calls(), len(calls))
+}
+
+func TestStateValidatorSetDiff(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	diffSub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryValidatorSetDiff,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	// at the initial height, all validators are added
+	vals := cs.state.Validators.Copy()
+	diff := cs.validatorSetDiff
+	require.Equal(t, int64(1), diff.Height)
+	require.Len(t, diff.Added, vals.Size())
+	require.Empty(t, diff.Removed)
+	require.Empty(t, diff.PowerChanged)
+	for i, change := range diff.Added {
+		require.Equal(t, vals.Validators[i].Address, change.Address)
+		require.Zero(t, change.OldPower)
+		require.Equal(t, vals.Validators[i].VotingPower, change.NewPower)
+	}
+
+	nextState := func(vals *types.ValidatorSet) sm.State {
+		state := cs.state.Copy()
+		state.LastBlockHeight++
+		state.LastValidators = state.Validators
+		state.Validators = vals
+		return state
+	}
+	cs.roundState.SetLastCommit(types.NewVoteSet(cs.state.ChainID, cs.roundState.Height(), 0, tmproto.PrecommitType, vals))
+
+	// no change, no event
+	require.True(t, cs.updateToState(nextState(vals.Copy()), stateUpdateSourceFinalize))
+	require.True(t, cs.validatorSetDiff.IsEmpty())
+	require.Equal(t, int64(2), cs.validatorSetDiff.Height)
+
+	added, _, err := factory.Validator(ctx, 5)
+	require.NoError(t, err)
+	updated := vals.Validators[1].Copy()
+	updated.VotingPower = 20
+	removed := vals.Validators[3].Copy()
+	removed.VotingPower = 0
+	newVals := vals.Copy()
+	require.NoError(t, newVals.UpdateWithChangeSet([]*types.Validator{added, updated, removed}))
+
+	require.True(t, cs.updateToState(nextState(newVals), stateUpdateSourceFinalize))
+	expected := ValidatorDiff{
+		Height:       3,
+		Added:        []types.ValidatorPowerChange{{Address: added.Address, NewPower: 5}},
+		Removed:      []types.ValidatorPowerChange{{Address: removed.Address, OldPower: vals.Validators[3].VotingPower}},
+		PowerChanged: []types.ValidatorPowerChange{{Address: updated.Address, OldPower: vals.Validators[1].VotingPower, NewPower: 20}},
+	}
+	require.Equal(t, expected, cs.validatorSetDiff)
+
+	// the event of the initial height may be delivered first
+	var event types.EventDataValidatorSetDiff
+	for event.Height != expected.Height {
+		msgCtx, msgCancel := context.WithTimeout(ctx, ensureTimeout)
+		msg, err := diffSub.Next(msgCtx)
+		msgCancel()
+		require.NoError(t, err)
+		var ok bool
+		event, ok = msg.Data().(types.EventDataValidatorSetDiff)
+		require.True(t, ok)
+		require.NotEqual(t, int64(2), event.Height)
+	}
+	require.Equal(t, types.EventDataValidatorSetDiff{
+		Height:       expected.Height,
+		Added:        expected.Added,
+		Removed:      expected.Removed,
+		PowerChanged: expected.PowerChanged,
+	}, event)
+}
+
+// failingSigner is a private validator that fails to sign proposals with the
+// given errors, in order, before signing them.
+type failingSigner struct {
+	types.PrivValidator
+
+	mtx   sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (s *failingSigner) SignProposal(ctx context.Context, chainID string, proposal *tmproto.Proposal) error {
+	s.mtx.Lock()
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		s.mtx.Unlock()
+		return err
+	}
+	s.mtx.Unlock()
+	return s.PrivValidator.SignProposal(ctx, chainID, proposal)
+}
+
+func (s *failingSigner) Calls() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.calls
+}
+
+func TestStateSignProposalErrors(t *testing.T) {
+	setup := func(ctx context.Context, t *testing.T, errs ...error) (*State, *failingSigner, *generic.Counter) {
+		config := configSetup(t)
+		cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+		signer := &failingSigner{PrivValidator: cs.privValidator, errs: errs}
+		cs.privValidator = signer
+		refusals := generic.NewCounter("double_sign_refusals")
+		cs.metrics.DoubleSignRefusals = refusals
+		return cs, signer, refusals
+	}
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs1, signer, refusals := setup(ctx, t, privval.ErrNoConnection, privval.ErrReadTimeout)
+		// leave room for the retries in the signing budget
+		cs1.state.ConsensusParams.Timeout.Propose = 10 * signProposalRetryInterval
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+		proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+
+		startTestRound(ctx, cs1, height, round)
+		msg := ensureMessageBeforeTimeout(t, proposalCh, 10*signProposalRetryInterval)
+		proposal, ok := msg.Data().(types.EventDataCompleteProposal)
+		require.True(t, ok)
+		require.Equal(t, height, proposal.Height)
+		require.Equal(t, round, proposal.Round)
+		require.Equal(t, 3, signer.Calls())
+		require.Zero(t, refusals.Value())
+	})
+
+	t.Run("double sign refusal is recorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		refusal := &privval.RemoteSignerError{Description: "error signing proposal: conflicting data"}
+		cs1, signer, refusals := setup(ctx, t, refusal)
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+		refusalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryDoubleSignRefusal)
+
+		startTestRound(ctx, cs1, height, round)
+		msg := ensureMessageBeforeTimeout(t, refusalCh, ensureTimeout)
+		require.Equal(t, types.EventDataDoubleSignRefusal{
+			Height: height,
+			Round:  round,
+			Error:  refusal.Error(),
+		}, msg.Data())
+		require.Equal(t, 1, signer.Calls())
+		require.Equal(t, float64(1), refusals.Value())
+
+		// the refusal is factored in on the next start
+		cs1.mtx.Lock()
+		defer cs1.mtx.Unlock()
+		recorded, err := cs1.loadDoubleSignRefusal()
+		require.NoError(t, err)
+		require.NotNil(t, recorded)
+		require.Equal(t, height, recorded.Height)
+		require.Equal(t, round, recorded.Round)
+		require.ErrorIs(t, cs1.checkDoubleSigningRisk(height), ErrDoubleSignRefusalRecorded)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs1, signer, refusals := setup(ctx, t, errors.New("signer failure"))
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+		proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+
+		startTestRound(ctx, cs1, height, round)
+		ensureNoNewEventOnChannel(t, proposalCh)
+		require.Equal(t, 1, signer.Calls())
+		require.Zero(t, refusals.Value())
+
+		cs1.mtx.Lock()
+		defer cs1.mtx.Unlock()
+		recorded, err := cs1.loadDoubleSignRefusal()
+		require.NoError(t, err)
+		require.Nil(t, recorded)
+	})
+}
+
+func TestStateCommitCertificate(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+
+	// raise the power of the validator and add a new one in the first block,
+	// which changes the validator set two heights later
+	ownPubKey, err := encoding.PubKeyToProto(cs.privValidatorPubKey)
+	require.NoError(t, err)
+	newPubKey, err := encoding.PubKeyToProto(ed25519.GenPrivKey().PubKey())
+	require.NoError(t, err)
+	mp := assertMempool(t, cs.txNotifier)
+	require.NoError(t, mp.CheckTx(ctx, kvstore.MakeValSetChangeTx(ownPubKey, 10), nil, mempool.TxInfo{}))
+	require.NoError(t, mp.CheckTx(ctx, kvstore.MakeValSetChangeTx(newPubKey, 1), nil, mempool.TxInfo{}))
+
+	_, err = cs.CommitCertificate(1)
+	require.Error(t, err, "no block is committed yet")
+
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 5
+	}, 10*time.Second, 10*time.Millisecond)
+
+	for height := int64(1); height <= 4; height++ {
+		cert, err := cs.CommitCertificate(height)
+		require.NoError(t, err, "height %d", height)
+
+		meta := cs.blockStore.LoadBlockMeta(height)
+		require.Equal(t, height, cert.Height)
+		require.Equal(t, meta.BlockID, cert.BlockID)
+		require.Equal(t, meta.BlockID, cert.Commit.BlockID)
+		require.Equal(t, meta.Header.AppHash, cert.AppHash)
+		require.Equal(t, meta.Header.ValidatorsHash, cert.ValidatorsHash)
+		require.EqualValues(t, cert.ValidatorSet.Hash(), cert.ValidatorsHash)
+
+		// the new validator set signs from height 3 on
+		expectedVals, expectedPower := 1, int64(1)
+		if height >= 3 {
+			expectedVals, expectedPower = 2, 11
+		}
+		require.Equal(t, expectedVals, cert.ValidatorSet.Size(), "height %d", height)
+		require.Equal(t, expectedPower, cert.ValidatorSet.TotalVotingPower(), "height %d", height)
+		require.Len(t, cert.Commit.Signatures, expectedVals)
+
+		data, err := json.Marshal(cert)
+		require.NoError(t, err)
+		decoded := new(CommitCertificate)
+		require.NoError(t, json.Unmarshal(data, decoded))
+		require.Equal(t, cert.BlockID, decoded.BlockID)
+		require.Equal(t, cert.Commit.Hash(), decoded.Commit.Hash())
+		require.EqualValues(t, cert.ValidatorSet.Hash(), decoded.ValidatorSet.Hash())
+		require.True(t, bytes.Equal(cert.AppHash, decoded.AppHash))
+	}
+}
+
+// labeledCounter is a metrics.Counter that keeps a value per set of labels.
+type labeledCounter struct {
+	values map[string]float64
+	labels string
+}
+
+func newLabeledCounter() *labeledCounter {
+	return &labeledCounter{values: make(map[string]float64)}
+}
+
+func (c *labeledCounter) With(labelValues ...string) metrics.Counter {
+	return &labeledCounter{values: c.values, labels: strings.Join(labelValues, ",")}
+}
+
+func (c *labeledCounter) Add(delta float64) { c.values[c.labels] += delta }
+
+func TestStateRecordsStepTransitions(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	transitions := newLabeledCounter()
+	cs.metrics.StepTransitions = transitions
+	height := cs.roundState.Height()
+	subscribe := func(query *tmquery.Query) eventbus.Subscription {
+		sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+			ClientID: testSubscriber,
+			Query:    query,
+			Limit:    20,
+		})
+		require.NoError(t, err)
+		return sub
+	}
+	newRoundCh := subscribe(types.EventQueryNewRound)
+	newStepCh := subscribe(types.EventQueryNewRoundStep)
+
+	cs.enterNewRound(ctx, height, 0, "timeout")
+	cs.enterPrevote(ctx, height, 0, "complete-proposal")
+	cs.enterPrecommit(ctx, height, 0, "no-such-label")
+	cs.enterNewRound(ctx, height, 1, "precommit-two-thirds-any")
+
+	require.Equal(t, map[string]float64{
+		"step,RoundStepNewRound,entry,timeout":                  1,
+		"step,RoundStepPropose,entry,enterNewRound":             2,
+		"step,RoundStepPrevote,entry,complete-proposal":         1,
+		"step,RoundStepPrecommit,entry,other":                   1,
+		"step,RoundStepNewRound,entry,precommit-two-thirds-any": 1,
+	}, transitions.values)
+	require.Equal(t, []StepTransition{
+		{Round: 0, Step: cstypes.RoundStepNewRound, Entry: "timeout"},
+		{Round: 0, Step: cstypes.RoundStepPropose, Entry: "enterNewRound"},
+		{Round: 0, Step: cstypes.RoundStepPrevote, Entry: "complete-proposal"},
+		{Round: 0, Step: cstypes.RoundStepPrecommit, Entry: "other"},
+		{Round: 1, Step: cstypes.RoundStepNewRound, Entry: "precommit-two-thirds-any"},
+		{Round: 1, Step: cstypes.RoundStepPropose, Entry: "enterNewRound"},
+	}, cs.Status().Transitions)
+
+	// the events carry the cause of the transitions
+	next := func(sub eventbus.Subscription) tmpubsub.Message {
+		nextCtx, nextCancel := context.WithTimeout(ctx, time.Second)
+		defer nextCancel()
+		msg, err := sub.Next(nextCtx)
+		require.NoError(t, err)
+		return msg
+	}
+	for _, entry := range []string{"timeout", "precommit-two-thirds-any"} {
+		require.Equal(t, entry, next(newRoundCh).Data().(types.EventDataNewRound).Entry)
+	}
+	for _, entry := range []string{"enterNewRound", "complete-proposal", "other", "enterNewRound"} {
+		event := next(newStepCh).Data().(types.EventDataRoundState)
+		if event.Step == cstypes.RoundStepNewHeight.String() {
+			// published when the state was loaded
+			event = next(newStepCh).Data().(types.EventDataRoundState)
+		}
+		require.Equal(t, entry, event.Entry)
+	}
+
+	// the transitions are kept per height
+	require.Empty(t, cs.stepTransitions.load(height+1))
+}
+
+func TestStateBootstrap(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{
+		config:     config,
+		validators: 1,
+		options:    []StateOption{SkipStateStoreBootstrap},
+	})
+	state, err := cs.stateStore.Load()
+	require.NoError(t, err)
+	// the state store of an embedder may be empty
+	cs.stateStore = sm.NewStore(dbm.NewMemDB())
+
+	// a State seeded by neither refuses to start
+	require.ErrorIs(t, cs.Start(ctx), ErrStateNotBootstrapped)
+	require.False(t, cs.IsRunning())
+
+	require.Error(t, cs.Bootstrap(sm.State{}))
+	require.NoError(t, cs.Bootstrap(state))
+	require.Equal(t, state.InitialHeight, cs.GetRoundState().Height)
+
+	sub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryBlockApplied,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.NoError(t, cs.Start(ctx))
+	require.Error(t, cs.Bootstrap(state))
+
+	// the bootstrapped State decides the height
+	nextCtx, nextCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer nextCancel()
+	msg, err := sub.Next(nextCtx)
+	require.NoError(t, err, "no BlockApplied event")
+	require.Equal(t, state.InitialHeight, msg.Data().(types.EventDataBlockApplied).Height)
+}
+
+func TestStateSetPrivValidatorConcurrently(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	pv, pubKey := cs.getPrivValidator()
+	require.NotNil(t, pv)
+	require.NotNil(t, pubKey)
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+
+	// the private validator is replaced while the heights are decided
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cs.SetPrivValidator(ctx, pv)
+				if pubKey := cs.getPrivValidatorPubKey(); pubKey != nil {
+					require.NotEmpty(t, pubKey.Address())
+				}
+			}
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height+2
+	}, 10*time.Second, 10*time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	gotPV, gotPubKey := cs.getPrivValidator()
+	require.Equal(t, pv, gotPV)
+	require.Equal(t, pubKey, gotPubKey)
+}
+
+func TestStateUpdateFromStoreUnchanged(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the state in the store was already loaded when the State was created
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	ignored := newLabeledCounter()
+	cs.metrics.StateUpdatesIgnored = ignored
+	height := cs.roundState.Height()
+
+	for i := 1; i <= 2; i++ {
+		updated, err := cs.updateStateFromStore()
+		require.NoError(t, err)
+		require.False(t, updated)
+		require.EqualValues(t, i, ignored.values["source,"+stateUpdateSourceStoreLoad])
+	}
+	require.Equal(t, height, cs.roundState.Height())
+
+	last, ok := cs.GetLastIgnoredStateUpdate()
+	require.True(t, ok)
+	require.Equal(t, stateUpdateSourceStoreLoad, last.Source)
+	require.Equal(t, "state unchanged", last.Reason)
+	require.Equal(t, height, last.OldHeight)
+	require.Equal(t, height, last.NewHeight)
+
+	// a state that is not newer is ignored as well
+	require.False(t, cs.updateToState(cs.state.Copy(), stateUpdateSourceSwitch))
+	require.EqualValues(t, 1, ignored.values["source,"+stateUpdateSourceSwitch])
+	require.Equal(t, 3, cs.consecutiveIgnoredStateUpdates)
+	last, ok = cs.GetLastIgnoredStateUpdate()
+	require.True(t, ok)
+	require.Equal(t, stateUpdateSourceSwitch, last.Source)
+	require.Equal(t, "state not newer", last.Reason)
+}
+
+func TestStateAheadOfBlockStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := ResetConfig(t.TempDir(), "state_ahead")
+	require.NoError(t, err)
+	logger := log.NewNopLogger()
+
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	state, err := sm.MakeGenesisStateFromFile(cfg.GenesisFile())
+	require.NoError(t, err)
+	privValidator := loadPrivValidator(t, cfg)
+
+	// commit a few blocks
+	cs1 := newStateWithConfigAndBlockStore(ctx, t, logger, cfg, state, privValidator, kvstore.NewApplication(), blockStore)
+	cs1Ctx, cs1Cancel := context.WithCancel(ctx)
+	require.NoError(t, cs1.Start(cs1Ctx))
+	require.Eventually(t, func() bool { return cs1.GetLastHeight() >= 3 }, 10*time.Second, 10*time.Millisecond)
+	cs1Cancel()
+	cs1.Wait()
+	stateHeight := cs1.GetLastHeight()
+	require.Equal(t, stateHeight, blockStore.Height())
+
+	newState := func(blockStore sm.BlockStore) (*State, error) {
+		return NewState(logger, cfg.Consensus, cs1.stateStore, cs1.blockExec, blockStore, cs1.txNotifier,
+			cs1.evpool, eventbus.NewDefault(logger), nil)
+	}
+
+	// the block store misses the last block, as left by an interrupted
+	// statesync
+	behind := store.NewBlockStore(dbm.NewMemDB())
+	for height := int64(1); height < stateHeight; height++ {
+		block := blockStore.LoadBlock(height)
+		parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+		require.NoError(t, err)
+		behind.SaveBlock(block, parts, blockStore.LoadBlockCommit(height))
+	}
+	_, err = newState(behind)
+	var aheadErr *ErrStateAheadOfBlockStore
+	require.ErrorAs(t, err, &aheadErr)
+	require.Equal(t, stateHeight, aheadErr.StateHeight)
+	require.Equal(t, stateHeight-1, aheadErr.BlockStoreHeight)
+
+	// statesync only saves the seen commit of the height it restores
+	synced := store.NewBlockStore(dbm.NewMemDB())
+	require.NoError(t, synced.SaveSeenCommit(stateHeight, blockStore.LoadSeenCommit()))
+	cs2, err := newState(synced)
+	require.NoError(t, err)
+	require.Equal(t, stateHeight+1, cs2.roundState.Height())
+
+	cs3, err := newState(blockStore)
+	require.NoError(t, err)
+	require.Equal(t, stateHeight+1, cs3.roundState.Height())
+}
+
+// stuffedEvidencePool has more pending evidence than fits in a block.
+type stuffedEvidencePool struct {
+	sm.EmptyEvidencePool
+	evidence []types.Evidence
+}
+
+func (p stuffedEvidencePool) PendingEvidence(maxBytes int64) ([]types.Evidence, int64) {
+	n := 0
+	for n < len(p.evidence) && types.EvidenceList(p.evidence[:n+1]).ByteSize() <= maxBytes {
+		n++
+	}
+	return p.evidence[:n], types.EvidenceList(p.evidence[:n]).ByteSize()
+}
+
+func TestStateProposalEvidenceCeiling(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := log.NewNopLogger()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+
+	pool := stuffedEvidencePool{evidence: make([]types.Evidence, 20)}
+	for i := range pool.evidence {
+		ev, err := types.NewMockDuplicateVoteEvidence(ctx, height, tmtime.Now(), cs.state.ChainID)
+		require.NoError(t, err)
+		pool.evidence[i] = ev
+	}
+	mp, ok := cs.txNotifier.(mempool.Mempool)
+	require.True(t, ok)
+	cs.blockExec = sm.NewBlockExecutor(cs.stateStore, logger, abciclient.NewLocalClient(logger, kvstore.NewApplication()),
+		mp, pool, cs.blockStore, cs.eventBus, sm.NopMetrics())
+
+	evidenceBytes := generic.NewGauge("proposal_evidence_bytes")
+	evidenceCount := generic.NewGauge("proposal_evidence_count")
+	trimmed := generic.NewCounter("proposal_evidence_trimmed")
+	cs.metrics.ProposalEvidenceBytes = evidenceBytes
+	cs.metrics.ProposalEvidenceCount = evidenceCount
+	cs.metrics.ProposalEvidenceTrimmed = trimmed
+
+	// the ceiling leaves room for 5 of the pending evidence
+	ceiling := types.EvidenceList(pool.evidence[:5]).ByteSize() + 10
+	cs.config.MaxProposalEvidenceFraction = float64(ceiling) / float64(cs.state.ConsensusParams.Block.MaxBytes)
+
+	cs.decideProposal(ctx, height, round)
+	require.Equal(t, 5.0, evidenceCount.Value())
+	require.Equal(t, float64(types.EvidenceList(pool.evidence[:5]).ByteSize()), evidenceBytes.Value())
+	require.Equal(t, 1.0, trimmed.Value())
+
+	msg := <-cs.internalMsgQueue
+	proposal, ok := msg.Msg.(*ProposalMessage)
+	require.True(t, ok)
+	require.Len(t, proposal.Proposal.Evidence, 5)
+	for len(cs.internalMsgQueue) > 0 {
+		<-cs.internalMsgQueue
+	}
+
+	// a fraction of 0 sets no ceiling: all the pending evidence is proposed
+	cs.config.MaxProposalEvidenceFraction = 0
+	// the proposal is decided again at the same height and round, which the
+	// sign request mark refuses
+	cs.signHRS.hrs = SignHRS{}
+	cs.decideProposal(ctx, height, round)
+	require.Equal(t, float64(len(pool.evidence)), evidenceCount.Value())
+	require.Equal(t, 1.0, trimmed.Value())
+
+	msg = <-cs.internalMsgQueue
+	proposal, ok = msg.Msg.(*ProposalMessage)
+	require.True(t, ok)
+	require.Len(t, proposal.Proposal.Evidence, len(pool.evidence))
+}
+
+// TestStateProposalCandidate tests that a submitted proposal candidate is
+// proposed instead of the block created by the block executor, unless it is
+// stale or the valid or locked block is to be proposed.
+func TestStateProposalCandidate(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+
+	created, err := cs.createProposalBlock(ctx)
+	require.NoError(t, err)
+	candidate := cs.state.MakeBlock(height, types.Txs{types.Tx("candidate")}, created.LastCommit, nil, created.ProposerAddress)
+	deadline := tmtime.Now().Add(time.Minute)
+
+	// proposedBlockHash decides the proposal and returns the hash of its
+	// block, draining the internal messages
+	proposedBlockHash := func() tmbytes.HexBytes {
+		// the proposals are decided again at the same height and round,
+		// which the sign request mark refuses
+		cs.signHRS.hrs = SignHRS{}
+		cs.decideProposal(ctx, height, round)
+		msg := <-cs.internalMsgQueue
+		proposal, ok := msg.Msg.(*ProposalMessage)
+		require.True(t, ok)
+		for len(cs.internalMsgQueue) > 0 {
+			<-cs.internalMsgQueue
+		}
+		return proposal.Proposal.BlockID.Hash
+	}
+
+	t.Run("invalid candidates are rejected", func(t *testing.T) {
+		err := cs.SubmitProposalCandidate(ctx, candidate, tmtime.Now().Add(-time.Second))
+		require.ErrorIs(t, err, ErrStaleProposalCandidate)
+
+		other := cs.state.MakeBlock(height+1, nil, created.LastCommit, nil, created.ProposerAddress)
+		err = cs.SubmitProposalCandidate(ctx, other, deadline)
+		require.ErrorIs(t, err, ErrStaleProposalCandidate)
+
+		notProposer := cs.state.MakeBlock(height, nil, created.LastCommit, nil, tmrand.Bytes(crypto.AddressSize))
+		err = cs.SubmitProposalCandidate(ctx, notProposer, deadline)
+		require.ErrorIs(t, err, ErrInvalidProposalCandidate)
+
+		wrongAppHash := cs.state.MakeBlock(height, nil, created.LastCommit, nil, created.ProposerAddress)
+		wrongAppHash.AppHash = tmrand.Bytes(crypto.HashSize)
+		err = cs.SubmitProposalCandidate(ctx, wrongAppHash, deadline)
+		require.ErrorIs(t, err, ErrInvalidProposalCandidate)
+
+		require.NotEqual(t, candidate.Hash(), proposedBlockHash())
+	})
+
+	t.Run("the candidate is proposed", func(t *testing.T) {
+		require.NoError(t, cs.SubmitProposalCandidate(ctx, candidate, deadline))
+		require.Equal(t, candidate.Hash(), proposedBlockHash())
+	})
+
+	t.Run("the valid block is re-proposed", func(t *testing.T) {
+		validParts, err := cs.makeProposalBlockParts(created)
+		require.NoError(t, err)
+		cs.roundState.SetValidBlock(created)
+		cs.roundState.SetValidBlockParts(validParts)
+		defer func() {
+			cs.roundState.SetValidBlock(nil)
+			cs.roundState.SetValidBlockParts(nil)
+		}()
+
+		require.Equal(t, created.Hash(), proposedBlockHash())
+	})
+
+	t.Run("the candidate is not proposed when locked", func(t *testing.T) {
+		cs.roundState.SetLockedBlock(created)
+		defer cs.roundState.SetLockedBlock(nil)
+
+		require.NotEqual(t, candidate.Hash(), proposedBlockHash())
+	})
+
+	t.Run("stale candidates are dropped", func(t *testing.T) {
+		require.NoError(t, cs.SubmitProposalCandidate(ctx, candidate, tmtime.Now().Add(100*time.Millisecond)))
+		time.Sleep(200 * time.Millisecond)
+		require.NotEqual(t, candidate.Hash(), proposedBlockHash())
+
+		// the dropped candidate is not proposed once the deadline is moved
+		cs.proposalCandidate.deadline = deadline
+		require.NotEqual(t, candidate.Hash(), proposedBlockHash())
+	})
+}
+
+// slowMempool delays fetching txs by key, as a mempool holding many txs would.
+type slowMempool struct {
+	mempool.Mempool
+	delay time.Duration
+}
+
+func (mp *slowMempool) SafeGetTxsForKeys(txKeys []types.TxKey) (types.Txs, []types.TxKey) {
+	time.Sleep(mp.delay)
+	return mp.Mempool.SafeGetTxsForKeys(txKeys)
+}
+
+// TestStateBlockReconstructionDeadline tests that a slow rebuild of a key-only
+// proposal is abandoned at the propose timeout, so that the validator prevotes
+// nil instead of blocking until the rebuild completes.
+func TestStateBlockReconstructionDeadline(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2 := vss[1]
+	height, round := cs1.roundState.Height(), int32(1)
+
+	cs1.config.GossipTransactionKeyOnly = true
+	cs1.config.BlockReconstructionSoftLimit = 10 * time.Millisecond
+	cs1.config.UnsafeProposeTimeoutOverride = 100 * time.Millisecond
+	cs1.config.UnsafeProposeTimeoutDeltaOverride = time.Nanosecond
+	proposeTimeout := cs1.proposeTimeout(round)
+
+	logger := log.NewNopLogger()
+	slow := &slowMempool{Mempool: assertMempool(t, cs1.txNotifier), delay: 10 * proposeTimeout}
+	cs1.blockExec = sm.NewBlockExecutor(cs1.stateStore, logger, abciclient.NewLocalClient(logger, kvstore.NewApplication()),
+		slow, sm.EmptyEvidencePool{}, cs1.blockStore, cs1.eventBus, sm.NopMetrics())
+
+	pv1, err := cs1.privValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+
+	// vs2 is the proposer of round 1
+	proposal, _ := decideProposal(ctx, t, cs1, vs2, height, round)
+	startTestRound(ctx, cs1, height, round)
+	start := time.Now()
+	cs1.peerMsgQueue <- msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}
+
+	ensurePrevoteMatch(t, voteCh, height, round, nil)
+	require.Less(t, time.Since(start), 2*proposeTimeout)
+
+	// the abandoned reconstruction completes in the background and is reused
+	// by the next attempt for the same proposal
+	cs1.mtx.Lock()
+	br := cs1.blockReconstruction
+	cs1.mtx.Unlock()
+	require.NotNil(t, br)
+	require.Eventually(t, br.isDone, 2*slow.delay, 10*time.Millisecond)
+
+	cs1.mtx.Lock()
+	defer cs1.mtx.Unlock()
+	_, missing, ok := cs1.fetchProposalTxs(height, round, proposal.TxKeys, time.Now())
+	require.True(t, ok)
+	require.Empty(t, missing)
+	require.Same(t, br, cs1.blockReconstruction)
+}
+
+// TestStateBlacklistedProposer tests that a proposal from a proposer that the
+// application kept rejecting is prevoted nil as soon as it is received, while
+// other proposals still wait for the propose timeout.
+func TestStateBlacklistedProposer(t *testing.T) {
+	config := configSetup(t)
+
+	prevoteNilAfter := func(t *testing.T, blacklist bool) (time.Duration, time.Duration) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+		vs2 := vss[1]
+		height, round := cs1.roundState.Height(), int32(1)
+
+		cs1.config.UnsafeProposeTimeoutOverride = 300 * time.Millisecond
+		cs1.config.UnsafeProposeTimeoutDeltaOverride = time.Nanosecond
+		proposeTimeout := cs1.proposeTimeout(round)
+
+		if blacklist {
+			pv2, err := vs2.GetPubKey(ctx)
+			require.NoError(t, err)
+			cs1.proposerBlacklist = newProposerBlacklist(1, 2)
+			cs1.proposerBlacklist.recordProcessProposal(pv2.Address(), height-1, false)
+		}
+
+		pv1, err := cs1.privValidator.GetPubKey(ctx)
+		require.NoError(t, err)
+		voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+
+		// vs2 is the proposer of round 1; only the proposal is sent, never
+		// its block
+		proposal, _ := decideProposal(ctx, t, cs1, vs2, height, round)
+		startTestRound(ctx, cs1, height, round)
+		start := time.Now()
+		cs1.peerMsgQueue <- msgInfo{Msg: &ProposalMessage{proposal}, PeerID: "peer", ReceiveTime: tmtime.Now()}
+
+		msg := ensureMessageBeforeTimeout(t, voteCh, 2*proposeTimeout)
+		vote, ok := msg.Data().(types.EventDataVote)
+		require.True(t, ok)
+		require.Equal(t, tmproto.PrevoteType, vote.Vote.Type)
+		require.Equal(t, round, vote.Vote.Round)
+		require.True(t, vote.Vote.BlockID.IsNil())
+		return time.Since(start), proposeTimeout
+	}
+
+	t.Run("blacklisted", func(t *testing.T) {
+		took, proposeTimeout := prevoteNilAfter(t, true)
+		require.Less(t, took, proposeTimeout/2)
+	})
+	t.Run("not blacklisted", func(t *testing.T) {
+		took, proposeTimeout := prevoteNilAfter(t, false)
+		require.GreaterOrEqual(t, took, proposeTimeout/2)
+	})
+}
+
 func TestSignSameVoteTwice(t *testing.T) {
 	config := configSetup(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -3189,3 +4950,556 @@ func signAddPrecommitWithExtension(ctx context.Context,
 	require.NoError(t, err, "failed to sign vote")
 	addVotes(cs, v)
 }
+
+func TestStateTraceSampling(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cs, _ := makeState(ctx, t, makeStateArgs{
+		config:     config,
+		validators: 1,
+		options:    []StateOption{WithTracerProvider(tp)},
+	})
+	cs.config.TraceSampleInterval = 2
+
+	startTestRound(ctx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 5
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the span of every height is traced, the spans below it only for the
+	// sampled heights
+	heights := make(map[int64]int)
+	children := make(map[int64]int)
+	spans := exporter.GetSpans()
+	for _, span := range spans {
+		if span.Name != "cs.state.Height" {
+			continue
+		}
+		var height int64
+		for _, attr := range span.Attributes {
+			if attr.Key == "height" {
+				height = attr.Value.AsInt64()
+			}
+		}
+		heights[height]++
+		for _, child := range spans {
+			if child.Parent.SpanID() == span.SpanContext.SpanID() {
+				children[height]++
+			}
+		}
+	}
+	for height := int64(1); height <= 4; height++ {
+		require.Equal(t, 1, heights[height], "height %d", height)
+		if height%2 == 0 {
+			require.NotZero(t, children[height], "height %d", height)
+		} else {
+			require.Zero(t, children[height], "height %d", height)
+		}
+	}
+
+	// an injected provider is not shut down by the State
+	cs.shutdownTracerProvider()
+	_, span := tp.Tracer(tracerName).Start(ctx, "test")
+	require.True(t, span.IsRecording())
+	span.End()
+}
+
+// TestStateRoundSpans tests that the spans of the steps and messages of a
+// round are below the span of the round, and those of the messages of other
+// rounds below the span of the height.
+func TestStateRoundSpans(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cs, vss := makeState(ctx, t, makeStateArgs{
+		config:  config,
+		options: []StateOption{WithTracerProvider(tp)},
+	})
+	height := cs.roundState.Height()
+
+	cs.enterNewRound(ctx, height, 0, "test")
+	cs.enterNewRound(ctx, height, 1, "test")
+	// a prevote of the current round, then one of the previous round
+	for _, round := range []int32{1, 0} {
+		vss[1].Round = round
+		vote := signVote(ctx, t, vss[1], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+		cs.handleMsg(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer", ReceiveTime: tmtime.Now()}, false)
+	}
+	cs.startHeightSpan(ctx, height+1)
+
+	attr := func(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+		for _, kv := range span.Attributes {
+			if kv.Key == key {
+				return kv.Value
+			}
+		}
+		return attribute.Value{}
+	}
+	var heightSpan tracetest.SpanStub
+	roundSpans := make(map[int32]tracetest.SpanStub)
+	spans := exporter.GetSpans()
+	for _, span := range spans {
+		switch {
+		case span.Name == "cs.state.Height" && attr(span, "height").AsInt64() == height:
+			heightSpan = span
+		case span.Name == "cs.state.Round":
+			roundSpans[int32(attr(span, "round").AsInt64())] = span
+		}
+	}
+	require.Len(t, roundSpans, 2)
+	for round, roundSpan := range roundSpans {
+		require.Equal(t, heightSpan.SpanContext.SpanID(), roundSpan.Parent.SpanID(), "round %d", round)
+	}
+
+	// children returns the names of the spans below parent, and whether they
+	// are marked as foreign_round
+	children := func(parent tracetest.SpanStub) map[string]bool {
+		names := make(map[string]bool)
+		for _, span := range spans {
+			if span.Parent.SpanID() == parent.SpanContext.SpanID() {
+				names[span.Name] = attr(span, "foreign_round").AsBool()
+			}
+		}
+		return names
+	}
+	require.Equal(t, map[string]bool{
+		"cs.state.Round":         false,
+		"cs.state.handleVoteMsg": true,
+	}, children(heightSpan))
+	require.Equal(t, map[string]bool{
+		"cs.state.enterNewRound": false,
+		"cs.state.enterPropose":  false,
+	}, children(roundSpans[0]))
+	require.Equal(t, map[string]bool{
+		"cs.state.enterNewRound": false,
+		"cs.state.enterPropose":  false,
+		"cs.state.handleVoteMsg": false,
+	}, children(roundSpans[1]))
+}
+
+func TestStateShutdownOwnedTracerProvider(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	// without tracer provider options, no provider is built
+	require.Nil(t, cs.ownedTracerProvider)
+
+	exporter := tracetest.NewInMemoryExporter()
+	cs.setupTracer([]sdktrace.TracerProviderOption{sdktrace.WithSyncer(exporter)})
+	require.NotNil(t, cs.ownedTracerProvider)
+
+	_, span := cs.heightTracer.Start(ctx, "test")
+	span.End()
+	require.Len(t, exporter.GetSpans(), 1)
+
+	// the spans are no longer exported once the provider is shut down
+	cs.shutdownTracerProvider()
+	_, span = cs.heightTracer.Start(ctx, "test")
+	span.End()
+	require.Empty(t, exporter.GetSpans())
+}
+
+func TestStateTracingDisabled(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1})
+	require.True(t, cs.tracingDisabled)
+
+	routinesCtx, stopRoutines := context.WithCancel(ctx)
+	startTestRound(routinesCtx, cs, cs.roundState.Height(), cs.roundState.Round())
+	require.Eventually(t, func() bool {
+		return cs.blockStore.Height() >= 2
+	}, 10*time.Second, 10*time.Millisecond)
+	// the routines are stopped before the tracer is read and replaced
+	stopRoutines()
+	cs.Stop()
+	cs.Wait()
+	cs.routines.Wait()
+
+	// no height span was started
+	require.Nil(t, cs.heightSpan)
+	require.Nil(t, cs.tracingCtx)
+	spanCtx, span := cs.startSpan(ctx, "test", attribute.Int("round", 0))
+	require.Equal(t, ctx, spanCtx)
+	require.False(t, span.IsRecording())
+
+	// a provider enables tracing
+	exporter := tracetest.NewInMemoryExporter()
+	cs.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cs.setupTracer(nil)
+	require.False(t, cs.tracingDisabled)
+	_, span = cs.startSpan(ctx, "test", attribute.Int("round", 0))
+	require.True(t, span.IsRecording())
+	span.End()
+	require.Len(t, exporter.GetSpans(), 1)
+	require.Equal(t, []attribute.KeyValue{attribute.Int("round", 0)}, exporter.GetSpans()[0].Attributes)
+}
+
+// BenchmarkStateHandleVote measures the allocations of the processing of a
+// vote with tracing disabled, and with a tracer provider without exporter,
+// which is what the nodes without tracing used before it could be disabled.
+func BenchmarkStateHandleVote(b *testing.B) {
+	for _, tc := range []struct {
+		name    string
+		options []StateOption
+	}{
+		{"tracing-disabled", nil},
+		{"tracer-provider-without-exporter", []StateOption{WithTracerProvider(sdktrace.NewTracerProvider())}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			config := configSetup(b)
+			cs, vss := makeState(ctx, b, makeStateArgs{config: config, logger: log.NewNopLogger(), options: tc.options})
+			height, round := cs.roundState.Height(), cs.roundState.Round()
+			cs.enterNewRound(ctx, height, round, "bench")
+
+			// the vote is a duplicate once added, which is the fate of most of
+			// the votes gossiped by the peers
+			vote := signVote(ctx, b, vss[1], tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+			mi := msgInfo{Msg: &VoteMessage{vote}, PeerID: "peer"}
+			cs.handleMsg(ctx, mi, false)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cs.handleMsg(ctx, mi, false)
+			}
+		})
+	}
+}
+
+func TestStateAbsentees(t *testing.T) {
+	config := configSetup(t)
+
+	setup := func(ctx context.Context, t *testing.T, gracePeriod time.Duration) (*State, []*validatorStub, types.BlockID, <-chan tmpubsub.Message) {
+		cs1, vss := makeState(ctx, t, makeStateArgs{config: config})
+		cs1.config.AbsenteeGracePeriod = gracePeriod
+		cs1.metrics.AbsentValidators = generic.NewGauge("absent_validators")
+		cs1.metrics.AbsentValidatorsPower = generic.NewGauge("absent_validators_power")
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+
+		pv1, err := cs1.privValidator.GetPubKey(ctx)
+		require.NoError(t, err)
+		voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+		startTestRound(ctx, cs1, height, round)
+		ensurePrevote(t, voteCh, height, round)
+
+		rs := cs1.GetRoundState()
+		blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+		return cs1, vss, blockID, voteCh
+	}
+	address := func(ctx context.Context, t *testing.T, vs *validatorStub) types.Address {
+		pubKey, err := vs.GetPubKey(ctx)
+		require.NoError(t, err)
+		return pubKey.Address()
+	}
+
+	t.Run("withheld votes are reported", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs1, vss, blockID, voteCh := setup(ctx, t, 0)
+		vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+
+		// vs4 withholds its prevote
+		signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3)
+		ensurePrecommit(t, voteCh, 1, 0)
+
+		absentees := cs1.GetAbsentees()
+		require.Equal(t, int64(1), absentees.Height)
+		require.Equal(t, []types.Address{address(ctx, t, vs4)}, absentees.Validators)
+		require.Equal(t, int64(1), absentees.Power)
+		require.Equal(t, float64(1), cs1.metrics.AbsentValidatorsPower.(*generic.Gauge).Value())
+
+		// a late vote removes it from the absentees
+		signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs4)
+		require.Eventually(t, func() bool {
+			return len(cs1.GetAbsentees().Validators) == 0
+		}, time.Second, 10*time.Millisecond)
+		require.Zero(t, cs1.GetAbsentees().Power)
+		require.Zero(t, cs1.metrics.AbsentValidators.(*generic.Gauge).Value())
+	})
+
+	t.Run("not reported within the grace period", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cs1, vss, blockID, voteCh := setup(ctx, t, time.Hour)
+		vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+
+		signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3)
+		ensurePrecommit(t, voteCh, 1, 0)
+		require.Empty(t, cs1.GetAbsentees().Validators)
+
+		// once the grace period elapsed, the next vote reports vs4
+		cs1.mtx.Lock()
+		cs1.config.AbsenteeGracePeriod = 0
+		cs1.mtx.Unlock()
+		signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), blockID, vs2)
+		require.Eventually(t, func() bool {
+			return len(cs1.GetAbsentees().Validators) == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, []types.Address{address(ctx, t, vs4)}, cs1.GetAbsentees().Validators)
+	})
+}
+
+func TestStateFaultInjection(t *testing.T) {
+	config := configSetup(t)
+
+	t.Run("WAL failure when signing a vote skips the vote", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fi := NewScriptedFaultInjector(1)
+		fi.AddWALFault(WALFault{Point: FaultPointSignVote, Height: 1, Round: 0, Times: 1, Err: errors.New("disk full")})
+		cs1, vss := makeState(ctx, t, makeStateArgs{config: config, options: []StateOption{WithFaultInjector(fi)}})
+		vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+
+		pv1, err := cs1.privValidator.GetPubKey(ctx)
+		require.NoError(t, err)
+		voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+		proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+		newBlockCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewBlock)
+
+		startTestRound(ctx, cs1, height, round)
+		ensureNewProposal(t, proposalCh, height, round)
+		rs := cs1.GetRoundState()
+		blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+
+		// the prevote is not signed, but the votes of the others let cs1
+		// precommit and commit the block
+		signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3, vs4)
+		ensurePrecommit(t, voteCh, height, round)
+		validatePrecommit(ctx, t, cs1, round, round, vss[0], blockID.Hash, blockID.Hash)
+		require.Nil(t, cs1.GetRoundState().Votes.Prevotes(round).GetByIndex(0))
+
+		signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), blockID, vs2, vs3, vs4)
+		ensureNewBlock(t, newBlockCh, height)
+		require.Equal(t, 1, fi.Injected())
+	})
+
+	t.Run("delayed fsync when finalizing a commit", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		const delay = 300 * time.Millisecond
+		fi := NewScriptedFaultInjector(1)
+		fi.AddWALFault(WALFault{Point: FaultPointFinalizeCommit, Height: 2, Round: AnyRound, Delay: delay})
+		cs1, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithFaultInjector(fi)}})
+
+		start := time.Now()
+		startTestRound(ctx, cs1, cs1.roundState.Height(), cs1.roundState.Round())
+		require.Eventually(t, func() bool {
+			return cs1.blockStore.Height() >= 3
+		}, 10*time.Second, 10*time.Millisecond)
+		require.GreaterOrEqual(t, time.Since(start), delay)
+		require.Equal(t, 1, fi.Injected())
+	})
+
+	t.Run("duplicated precommit wait timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fi := NewScriptedFaultInjector(1)
+		fi.AddTimeoutFault(TimeoutFault{Height: 1, Round: 0, Step: cstypes.RoundStepPrecommitWait, Deliveries: 2})
+		cs1, vss := makeState(ctx, t, makeStateArgs{config: config, options: []StateOption{WithFaultInjector(fi)}})
+		vs2, vs3, vs4 := vss[1], vss[2], vss[3]
+		height, round := cs1.roundState.Height(), cs1.roundState.Round()
+
+		pv1, err := cs1.privValidator.GetPubKey(ctx)
+		require.NoError(t, err)
+		voteCh := subscribeToVoter(ctx, t, cs1, pv1.Address())
+		proposalCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryCompleteProposal)
+		timeoutWaitCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryTimeoutWait)
+		newRoundCh := subscribe(ctx, t, cs1.eventBus, types.EventQueryNewRound)
+
+		startTestRound(ctx, cs1, height, round)
+		ensureNewRound(t, newRoundCh, height, round)
+		ensureNewProposal(t, proposalCh, height, round)
+		rs := cs1.GetRoundState()
+		blockID := types.BlockID{Hash: rs.ProposalBlock.Hash(), PartSetHeader: rs.ProposalBlockParts.Header()}
+
+		ensurePrevote(t, voteCh, height, round)
+		signAddVotes(ctx, t, cs1, tmproto.PrevoteType, config.ChainID(), blockID, vs2, vs3, vs4)
+		ensurePrecommit(t, voteCh, height, round)
+
+		// +2/3 precommits for anything, but no majority: wait for the timeout
+		signAddVotes(ctx, t, cs1, tmproto.PrecommitType, config.ChainID(), types.BlockID{}, vs2, vs3)
+		ensureNewTimeout(t, timeoutWaitCh, height, round, cs1.voteTimeout(round).Nanoseconds())
+
+		// the duplicated timeout moves cs1 to the next round only once
+		ensureNewRound(t, newRoundCh, height, round+1)
+		ensureNoNewRoundStep(t, newRoundCh)
+		require.Equal(t, round+1, cs1.GetRoundState().Round)
+		require.Equal(t, 1, fi.Injected())
+	})
+}
+
+func TestStateVoteFromNonValidator(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type report struct {
+		peerID types.NodeID
+		err    error
+	}
+	var reports []report
+	cs1, _ := makeState(ctx, t, makeStateArgs{config: config, options: []StateOption{
+		WithPeerMisbehaviorReporter(func(peerID types.NodeID, err error) {
+			reports = append(reports, report{peerID, err})
+		}),
+	}})
+	votes := newLabeledCounter()
+	cs1.metrics.NonValidatorVotes = votes
+
+	rotatedOut := newValidatorStub(types.NewMockPV(), 1)
+	rotatedOutKey, err := rotatedOut.GetPubKey(ctx)
+	require.NoError(t, err)
+	unknown := newValidatorStub(types.NewMockPV(), 2)
+	unknownKey, err := unknown.GetPubKey(ctx)
+	require.NoError(t, err)
+	incrementHeight(rotatedOut, unknown)
+	cs1.state.LastValidators = types.NewValidatorSet([]*types.Validator{types.NewValidator(rotatedOutKey, 1)})
+
+	addVote := func(vs *validatorStub) (bool, error) {
+		vote := signVote(ctx, t, vs, tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+		cs1.mtx.Lock()
+		defer cs1.mtx.Unlock()
+		return cs1.tryAddVote(ctx, vote, "peer", types.VoteUnverified, otrace.SpanFromContext(ctx))
+	}
+
+	// a validator that just left the set is ignored
+	added, err := addVote(rotatedOut)
+	require.False(t, added)
+	require.NoError(t, err)
+	require.Empty(t, reports)
+
+	// an unknown address is reported
+	added, err = addVote(unknown)
+	require.False(t, added)
+	var nonValErr *ErrVoteFromNonValidator
+	require.ErrorAs(t, err, &nonValErr)
+	require.False(t, nonValErr.RotatedOut)
+	require.Equal(t, unknownKey.Address(), nonValErr.Address)
+	require.Len(t, reports, 1)
+	require.Equal(t, types.NodeID("peer"), reports[0].peerID)
+	require.ErrorAs(t, reports[0].err, &nonValErr)
+
+	require.Equal(t, map[string]float64{"kind,rotated_out": 1, "kind,unknown": 1}, votes.values)
+}
+
+func TestStateStepBudgetThrottlesPeerMessages(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	vs2, vs3 := vss[1], vss[2]
+	exceeded := generic.NewCounter("step_budget_exceeded")
+	cs.metrics.StepBudgetExceeded = exceeded
+	cs.config.MaxStepsPerSecond = 5
+
+	// a storm of steps exceeds the budget
+	for i := 0; i < 10; i++ {
+		cs.newStep("")
+	}
+	require.Equal(t, float64(1), exceeded.Value())
+	throttledFor := cs.stepBudget.throttled(time.Now())
+	require.Positive(t, throttledFor)
+
+	cs.startRoutines(ctx, 0)
+	hasPrevote := func(vs *validatorStub) bool {
+		return cs.GetRoundState().Votes.Prevotes(0).GetByIndex(vs.Index) != nil
+	}
+
+	// peer messages are delayed, messages of this node are not
+	peerVote := signVote(ctx, t, vs2, tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	cs.peerMsgQueue <- msgInfo{Msg: &VoteMessage{peerVote}, PeerID: "peer"}
+	internalVote := signVote(ctx, t, vs3, tmproto.PrevoteType, config.ChainID(), types.BlockID{})
+	cs.internalMsgQueue <- msgInfo{Msg: &VoteMessage{internalVote}}
+
+	require.Eventually(t, func() bool { return hasPrevote(vs3) }, time.Second, 10*time.Millisecond)
+	require.False(t, hasPrevote(vs2))
+
+	// the peer message is processed once the throttling is over
+	require.Eventually(t, func() bool { return hasPrevote(vs2) }, 2*throttledFor, 10*time.Millisecond)
+	require.Equal(t, float64(1), exceeded.Value())
+}
+
+// blockingFinalizeApp blocks in FinalizeBlock until release is closed,
+// signaling finalizing once it is called.
+type blockingFinalizeApp struct {
+	abci.Application
+	finalizing chan struct{}
+	release    chan struct{}
+}
+
+func (app *blockingFinalizeApp) FinalizeBlock(ctx context.Context, req *abci.RequestFinalizeBlock) (*abci.ResponseFinalizeBlock, error) {
+	select {
+	case app.finalizing <- struct{}{}:
+	default:
+	}
+	<-app.release
+	return app.Application.FinalizeBlock(ctx, req)
+}
+
+func TestStateOnStopWaitsForCommit(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app := &blockingFinalizeApp{
+		Application: kvstore.NewApplication(),
+		finalizing:  make(chan struct{}, 1),
+		release:     make(chan struct{}),
+	}
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, application: app})
+	cs.state.ConsensusParams.Timeout.Commit = time.Minute
+
+	// the routines are stopped by OnStop, as if started by OnStart
+	routinesCtx, cancelRoutines := context.WithCancel(ctx)
+	cs.cancelRoutines = cancelRoutines
+
+	height := cs.roundState.Height()
+	startTestRound(routinesCtx, cs, height, cs.roundState.Round())
+	select {
+	case <-app.finalizing:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the block to be finalized")
+	}
+	require.Equal(t, cstypes.RoundStepCommit, cs.GetRoundState().Step)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		cs.OnStop()
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("OnStop returned during the commit")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// OnStop returns once the commit finishes, not after the commit timeout
+	close(app.release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStop did not return after the commit")
+	}
+	require.GreaterOrEqual(t, cs.blockStore.Height(), height)
+}
@@ -0,0 +1,74 @@
+package consensus
+
+import (
+	"time"
+)
+
+// Sources of the state updates passed to updateToState.
+const (
+	stateUpdateSourceStoreLoad = "store_load"
+	stateUpdateSourceFinalize  = "finalize"
+	stateUpdateSourceSwitch    = "switch_to_consensus"
+	stateUpdateSourceBootstrap = "bootstrap"
+)
+
+const (
+	// ignoredStateUpdatesWarnThreshold is the number of consecutive ignored
+	// state updates after which a warning is logged.
+	ignoredStateUpdatesWarnThreshold = 3
+	// ignoredStateUpdatesWarnInterval is the minimum interval between two such
+	// warnings.
+	ignoredStateUpdatesWarnInterval = time.Minute
+)
+
+// IgnoredStateUpdate describes a state update that did not change the
+// consensus state, because the new state was not newer than the current one.
+// OldHeight and NewHeight are the consensus heights before and after the
+// update, had it been applied.
+type IgnoredStateUpdate struct {
+	Source    string
+	Reason    string
+	OldHeight int64
+	NewHeight int64
+	Time      time.Time
+}
+
+// recordIgnoredStateUpdate records a state update from source that was
+// ignored, and warns if updates keep being ignored, which usually means a
+// caller is racing the state machine.
+func (cs *State) recordIgnoredStateUpdate(source, reason string, oldHeight, newHeight int64) {
+	now := time.Now()
+	cs.metrics.StateUpdatesIgnored.With("source", source).Add(1)
+	cs.lastIgnoredStateUpdate = &IgnoredStateUpdate{
+		Source:    source,
+		Reason:    reason,
+		OldHeight: oldHeight,
+		NewHeight: newHeight,
+		Time:      now,
+	}
+
+	cs.consecutiveIgnoredStateUpdates++
+	if cs.consecutiveIgnoredStateUpdates >= ignoredStateUpdatesWarnThreshold &&
+		now.Sub(cs.lastIgnoredStateUpdateWarn) >= ignoredStateUpdatesWarnInterval {
+		cs.lastIgnoredStateUpdateWarn = now
+		cs.logger.Error("repeatedly ignoring state updates; the caller may be racing consensus",
+			"consecutive", cs.consecutiveIgnoredStateUpdates,
+			"source", source,
+			"reason", reason,
+			"old_height", oldHeight,
+			"new_height", newHeight,
+		)
+	}
+}
+
+// GetLastIgnoredStateUpdate returns the latest state update that was ignored,
+// if any.
+func (cs *State) GetLastIgnoredStateUpdate() (IgnoredStateUpdate, bool) {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+
+	if cs.lastIgnoredStateUpdate == nil {
+		return IgnoredStateUpdate{}, false
+	}
+	return *cs.lastIgnoredStateUpdate, true
+}
@@ -0,0 +1,105 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// stepBudgetWindow is the window the steps of the state machine are
+	// counted over.
+	stepBudgetWindow = time.Second
+
+	// stepBudgetPersistWindows is the number of consecutive windows the step
+	// budget must be exceeded in for the StepBudgetExceeded event to be
+	// published.
+	stepBudgetPersistWindows = 3
+)
+
+// stepBudget counts the steps of the state machine per window. Once the
+// budget of a window is exceeded, peer messages are throttled until the end
+// of the window.
+//
+// Only the processing of peer messages is delayed: messages of this node,
+// including its own signed votes and proposals, and timeouts are always
+// processed, so throttling never delays our own signing.
+type stepBudget struct {
+	windowStart time.Time
+	steps       int64
+
+	throttledUntil time.Time
+	// number of consecutive windows the budget was exceeded in
+	exceededWindows int
+}
+
+// record counts a step at now against a budget of max steps per window, and
+// returns whether it exceeded the budget of the window.
+func (sb *stepBudget) record(max int64, now time.Time) bool {
+	if max <= 0 {
+		return false
+	}
+
+	if elapsed := now.Sub(sb.windowStart); elapsed >= stepBudgetWindow {
+		if sb.steps <= max || elapsed >= 2*stepBudgetWindow {
+			sb.exceededWindows = 0
+		}
+		sb.windowStart = now
+		sb.steps = 0
+	}
+
+	sb.steps++
+	if sb.steps != max+1 {
+		return false
+	}
+	sb.exceededWindows++
+	sb.throttledUntil = sb.windowStart.Add(stepBudgetWindow)
+	return true
+}
+
+// throttled returns how long peer messages are still throttled for at now.
+func (sb *stepBudget) throttled(now time.Time) time.Duration {
+	if now.Before(sb.throttledUntil) {
+		return sb.throttledUntil.Sub(now)
+	}
+	return 0
+}
+
+// recordStep counts a step of the state machine against its step budget.
+func (cs *State) recordStep() {
+	if !cs.stepBudget.record(cs.config.MaxStepsPerSecond, time.Now()) {
+		return
+	}
+
+	cs.metrics.StepBudgetExceeded.Add(1)
+	cs.logger.Info(
+		"state machine exceeded its step budget; throttling peer messages",
+		"height", cs.roundState.Height(),
+		"round", cs.roundState.Round(),
+		"max_steps_per_second", cs.config.MaxStepsPerSecond,
+		"windows", cs.stepBudget.exceededWindows,
+	)
+
+	// newStep is called by updateToState in NewState before the eventBus is set!
+	if cs.stepBudget.exceededWindows == stepBudgetPersistWindows && cs.eventBus != nil {
+		if err := cs.publishEvent(types.EventStepBudgetExceededValue, types.EventDataStepBudgetExceeded{
+			Height:            cs.roundState.Height(),
+			Round:             cs.roundState.Round(),
+			MaxStepsPerSecond: cs.config.MaxStepsPerSecond,
+			Windows:           cs.stepBudget.exceededWindows,
+		}); err != nil {
+			cs.logger.Error("failed publishing step budget exceeded", "err", err)
+		}
+	}
+}
+
+// peerMsgQueueOrThrottle returns the queue of peer messages, or a nil channel
+// and a channel fired at the end of the throttling if peer messages are
+// throttled.
+func (cs *State) peerMsgQueueOrThrottle() (<-chan msgInfo, *time.Timer) {
+	wait := cs.stepBudget.throttled(time.Now())
+	if wait <= 0 {
+		return cs.peerMsgQueue, nil
+	}
+	return nil, time.NewTimer(wait)
+}
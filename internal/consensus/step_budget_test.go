@@ -0,0 +1,45 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepBudget(t *testing.T) {
+	var sb stepBudget
+	start := time.Now()
+
+	// disabled
+	for i := 0; i < 10; i++ {
+		require.False(t, sb.record(0, start))
+	}
+	require.Zero(t, sb.throttled(start))
+
+	exceed := func(window int) {
+		now := start.Add(time.Duration(window) * stepBudgetWindow)
+		for i := 0; i < 3; i++ {
+			require.False(t, sb.record(3, now))
+		}
+		require.True(t, sb.record(3, now))
+		require.False(t, sb.record(3, now))
+		require.Equal(t, 900*time.Millisecond, sb.throttled(now.Add(100*time.Millisecond)))
+		require.Zero(t, sb.throttled(now.Add(stepBudgetWindow)))
+	}
+
+	// consecutive windows over budget
+	for window := 1; window <= 3; window++ {
+		exceed(window)
+		require.Equal(t, window, sb.exceededWindows)
+	}
+
+	// a window within budget resets the count
+	require.False(t, sb.record(3, start.Add(4*stepBudgetWindow)))
+	exceed(5)
+	require.Equal(t, 1, sb.exceededWindows)
+
+	// so does a window without steps
+	exceed(7)
+	require.Equal(t, 1, sb.exceededWindows)
+}
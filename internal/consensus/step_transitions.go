@@ -0,0 +1,80 @@
+package consensus
+
+import (
+	"sync"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+)
+
+// otherEntryLabel replaces the entry labels outside of knownEntryLabels, so
+// that the cardinality of the entry label of the metrics stays bounded.
+const otherEntryLabel = "other"
+
+// maxStepTransitions is the number of transitions of a height kept for
+// Status; the oldest are dropped beyond it.
+const maxStepTransitions = 128
+
+// knownEntryLabels are the causes the steps of the state machine are entered
+// with.
+var knownEntryLabels = map[string]struct{}{
+	"timeout":                  {},
+	"precommit-wait-timeout":   {},
+	"post-timeout-commit":      {},
+	"enterNewRound":            {},
+	"enterPropose":             {},
+	"blacklistedProposer":      {},
+	"complete-proposal":        {},
+	"skip-timeout":             {},
+	"prevote-future":           {},
+	"precommit-two-thirds":     {},
+	"precommit-two-thirds-any": {},
+	"precommit-skip-round":     {},
+}
+
+// StepTransition is a transition of the state machine to a step of a round,
+// with the cause of the transition.
+type StepTransition struct {
+	Round int32
+	Step  cstypes.RoundStepType
+	Entry string
+}
+
+// stepTransitions are the transitions of the state machine in the current
+// height. It is written under the State mutex and may be read without it.
+type stepTransitions struct {
+	mtx         sync.Mutex
+	height      int64
+	transitions []StepTransition
+}
+
+// load returns the transitions of height.
+func (st *stepTransitions) load(height int64) []StepTransition {
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	if st.height != height {
+		return nil
+	}
+	return append([]StepTransition(nil), st.transitions...)
+}
+
+// recordTransition counts the transition to step of height and round caused
+// by entryLabel, and returns the label it was counted with.
+func (cs *State) recordTransition(height int64, round int32, step cstypes.RoundStepType, entryLabel string) string {
+	if _, ok := knownEntryLabels[entryLabel]; !ok {
+		entryLabel = otherEntryLabel
+	}
+	cs.metrics.StepTransitions.With("step", step.String(), "entry", entryLabel).Add(1)
+
+	st := &cs.stepTransitions
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	if st.height != height {
+		st.height = height
+		st.transitions = st.transitions[:0]
+	}
+	if len(st.transitions) == maxStepTransitions {
+		st.transitions = append(st.transitions[:0], st.transitions[1:]...)
+	}
+	st.transitions = append(st.transitions, StepTransition{Round: round, Step: step, Entry: entryLabel})
+	return entryLabel
+}
@@ -25,7 +25,8 @@ type TimeoutTicker interface {
 
 // timeoutTicker wraps time.Timer,
 // scheduling timeouts only for greater height/round/step
-// than what it's already seen.
+// than what it's already seen, or for the same one once its
+// timeout elapsed.
 // Timeouts are scheduled along the tickChan,
 // and fired on the tockChan.
 type timeoutTicker struct {
@@ -90,6 +91,9 @@ func (t *timeoutTicker) stopTimer() {
 // timeouts of 0 on the tickChan will be immediately relayed to the tockChan
 func (t *timeoutTicker) timeoutRoutine(ctx context.Context) {
 	var ti timeoutInfo
+	// set once the timeout of ti elapsed, after which it may be extended by
+	// a new timeout for the same height/round/step
+	var elapsed bool
 	for {
 		select {
 		case newti := <-t.tickChan:
@@ -102,7 +106,7 @@ func (t *timeoutTicker) timeoutRoutine(ctx context.Context) {
 				if newti.Round < ti.Round {
 					continue
 				} else if newti.Round == ti.Round {
-					if ti.Step > 0 && newti.Step <= ti.Step {
+					if ti.Step > 0 && (newti.Step < ti.Step || newti.Step == ti.Step && !elapsed) {
 						continue
 					}
 				}
@@ -114,10 +118,12 @@ func (t *timeoutTicker) timeoutRoutine(ctx context.Context) {
 			// update timeoutInfo and reset timer
 			// NOTE time.Timer allows duration to be non-positive
 			ti = newti
+			elapsed = false
 			t.timer.Stop()
 			t.timer.Reset(ti.Duration)
 			t.logger.Debug("Internal state machine timeout scheduled", "duration", ti.Duration, "height", ti.Height, "round", ti.Round, "step", ti.Step)
 		case <-t.timer.C:
+			elapsed = true
 			t.logger.Debug("Internal state machine timeout elapsed ", "duration", ti.Duration, "height", ti.Height, "round", ti.Round, "step", ti.Step)
 			// go routine here guarantees timeoutRoutine doesn't block.
 			// Determinism comes from playback in the receiveRoutine.
@@ -0,0 +1,71 @@
+package consensus
+
+import (
+	"math"
+	"time"
+
+	"github.com/tendermint/tendermint/config"
+)
+
+// Timeouts are the propose and vote timeouts of the current height, after the
+// unsafe overrides and the scaling with the size of the validator set.
+type Timeouts struct {
+	// Propose and Vote are the timeouts of round 0, which ProposeDelta and
+	// VoteDelta increase by in each round.
+	Propose      time.Duration
+	ProposeDelta time.Duration
+	Vote         time.Duration
+	VoteDelta    time.Duration
+	// Scale is the factor the base timeouts are multiplied by; 1 if they are
+	// not scaled.
+	Scale float64
+	// Validators is the size of the validator set Scale was computed for.
+	Validators int
+}
+
+// timeoutScale returns the factor the base propose and vote timeouts are
+// multiplied by for a validator set of n validators.
+func timeoutScale(cfg *config.ConsensusConfig, n int) float64 {
+	k, n0 := cfg.TimeoutScalingCoefficient, cfg.TimeoutScalingBaseValidators
+	if k <= 0 || n0 <= 0 || n <= n0 {
+		return 1
+	}
+	return 1 + k*math.Log(float64(n)/float64(n0))
+}
+
+// updateTimeoutScale recomputes the timeout scale for the validator set of
+// the current height. The set comes from the state, so the replay of the WAL
+// computes the timeouts it was written with.
+func (cs *State) updateTimeoutScale(validators int) {
+	cs.timeoutScale = timeoutScale(cs.config, validators)
+	cs.timeoutScaleValidators = validators
+}
+
+// scaleTimeout returns the base timeout d scaled with the size of the
+// validator set.
+func (cs *State) scaleTimeout(d time.Duration) time.Duration {
+	if cs.timeoutScale <= 1 {
+		return d
+	}
+	return time.Duration(float64(d) * cs.timeoutScale)
+}
+
+// EffectiveTimeouts returns the propose and vote timeouts of the current
+// height.
+func (cs *State) EffectiveTimeouts() Timeouts {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+	scale := cs.timeoutScale
+	if scale < 1 {
+		scale = 1
+	}
+	propose, vote := cs.proposeTimeout(0), cs.voteTimeout(0)
+	return Timeouts{
+		Propose:      propose,
+		ProposeDelta: cs.proposeTimeout(1) - propose,
+		Vote:         vote,
+		VoteDelta:    cs.voteTimeout(1) - vote,
+		Scale:        scale,
+		Validators:   cs.timeoutScaleValidators,
+	}
+}
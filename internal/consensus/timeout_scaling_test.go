@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestStateTimeoutScaling(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config.Consensus.TimeoutScalingCoefficient = 0.5
+	config.Consensus.TimeoutScalingBaseValidators = 4
+	state, privVals := makeGenesisState(ctx, t, config, genesisStateArgs{Validators: 16})
+	cs := newStateWithConfig(ctx, t, log.NewNopLogger(), config, state, privVals[0], kvstore.NewApplication())
+	tp := cs.state.ConsensusParams.Timeout.TimeoutParamsOrDefaults()
+	scaled := func(d time.Duration, scale float64) time.Duration {
+		return time.Duration(float64(d) * scale)
+	}
+
+	// the scale is computed for the validator set of the state
+	timeouts := cs.EffectiveTimeouts()
+	scale := 1 + 0.5*math.Log(4)
+	require.Equal(t, Timeouts{
+		Propose:      scaled(tp.Propose, scale),
+		ProposeDelta: tp.ProposeDelta,
+		Vote:         scaled(tp.Vote, scale),
+		VoteDelta:    tp.VoteDelta,
+		Scale:        scale,
+		Validators:   16,
+	}, timeouts)
+	require.Equal(t, timeouts.Propose+3*timeouts.ProposeDelta, cs.proposeTimeout(3))
+
+	for _, tc := range []struct {
+		validators int
+		scale      float64
+	}{
+		{validators: 1, scale: 1},
+		{validators: 4, scale: 1},
+		{validators: 8, scale: 1 + 0.5*math.Log(2)},
+		{validators: 100, scale: 1 + 0.5*math.Log(25)},
+	} {
+		cs.updateTimeoutScale(tc.validators)
+		timeouts := cs.EffectiveTimeouts()
+		require.Equal(t, tc.validators, timeouts.Validators)
+		require.InDelta(t, tc.scale, timeouts.Scale, 1e-9)
+		require.Equal(t, scaled(tp.Propose, timeouts.Scale), timeouts.Propose)
+		require.Equal(t, scaled(tp.Vote, timeouts.Scale), timeouts.Vote)
+	}
+
+	// the overrides are not scaled
+	cs.config.UnsafeProposeTimeoutOverride = time.Second
+	cs.config.UnsafeVoteTimeoutOverride = 2 * time.Second
+	timeouts = cs.EffectiveTimeouts()
+	require.Greater(t, timeouts.Scale, 1.0)
+	require.Equal(t, time.Second, timeouts.Propose)
+	require.Equal(t, 2*time.Second, timeouts.Vote)
+
+	// the scaling is disabled
+	cs.config.TimeoutScalingCoefficient = 0
+	cs.config.UnsafeProposeTimeoutOverride = 0
+	cs.config.UnsafeVoteTimeoutOverride = 0
+	cs.updateTimeoutScale(100)
+	timeouts = cs.EffectiveTimeouts()
+	require.Equal(t, 1.0, timeouts.Scale)
+	require.Equal(t, tp.Propose, timeouts.Propose)
+	require.Equal(t, tp.Vote, timeouts.Vote)
+}
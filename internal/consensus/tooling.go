@@ -0,0 +1,72 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/internal/eventbus"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// ErrToolingSigning is returned by Start for a State created with
+// NewStateForTooling whose private validator is set.
+var ErrToolingSigning = errors.New("a State for tooling can not sign; its private validator must be nil")
+
+// NewStateForTooling returns a State for tooling which exercises consensus
+// without running a node, such as WAL replay analyzers and proposer
+// simulators.
+//
+// evpool and eventBus may be nil. A nil evpool is replaced by one discarding
+// the conflicting votes reported to it. A nil eventBus is replaced by a bus
+// private to the State, started and stopped with it, which the events of the
+// State are published to and lost. blockExec must be complete, including its
+// own event bus.
+//
+// The State can not sign: Start returns ErrToolingSigning if a private
+// validator is set. The rest of the State is functional: it loads its state
+// from the stores, replays its WAL on Start, handles the proposals, block
+// parts and votes it is sent, commits the blocks they decide, and serves its
+// queries, such as GetRoundState, LastApplied and Status.
+func NewStateForTooling(
+	logger log.Logger,
+	cfg *config.ConsensusConfig,
+	store sm.Store,
+	blockExec *sm.BlockExecutor,
+	blockStore sm.BlockStore,
+	txNotifier txNotifier,
+	evpool evidencePool,
+	eventBus *eventbus.EventBus,
+	options ...StateOption,
+) (*State, error) {
+	toolingDefaults := func(cs *State) {
+		cs.tooling = true
+		if cs.evpool == nil {
+			cs.evpool = sm.EmptyEvidencePool{}
+		}
+		if cs.eventBus == nil {
+			cs.eventBus = eventbus.NewDefault(logger)
+			cs.ownsEventBus = true
+		}
+	}
+	// the defaults are installed before the state is loaded from the store,
+	// which publishes a step
+	return NewState(logger, cfg, store, blockExec, blockStore, txNotifier, evpool, eventBus, nil,
+		append([]StateOption{toolingDefaults}, options...)...)
+}
+
+// checkTooling refuses to start a State for tooling that could sign, and
+// starts the private event bus of the State.
+func (cs *State) checkTooling(ctx context.Context) error {
+	if !cs.tooling {
+		return nil
+	}
+	if pv, _ := cs.getPrivValidator(); pv != nil {
+		return ErrToolingSigning
+	}
+	if cs.ownsEventBus {
+		return cs.eventBus.Start(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package consensus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/internal/store"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// precommitBlockingPV does not sign the precommits of height, blocking until
+// their sign request is done. Its first such request is reported on blocked,
+// once the prevote of the height is written to the WAL.
+type precommitBlockingPV struct {
+	types.PrivValidator
+	height  int64
+	blocked chan struct{}
+	once    sync.Once
+}
+
+func (pv *precommitBlockingPV) SignVote(ctx context.Context, chainID string, vote *tmproto.Vote) error {
+	if vote.Height != pv.height || vote.Type != tmproto.PrecommitType {
+		return pv.PrivValidator.SignVote(ctx, chainID, vote)
+	}
+	pv.once.Do(func() { close(pv.blocked) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestStateForToolingReplaysWAL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := ResetConfig(t.TempDir(), "tooling_replay")
+	require.NoError(t, err)
+	logger := log.NewNopLogger()
+
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	state, err := sm.MakeGenesisStateFromFile(cfg.GenesisFile())
+	require.NoError(t, err)
+	privValidator := loadPrivValidator(t, cfg)
+
+	// commit a few blocks, writing the WAL, and stop once the prevote of the
+	// current height is written
+	pv := &precommitBlockingPV{PrivValidator: privValidator, height: 3, blocked: make(chan struct{})}
+	cs1 := newStateWithConfigAndBlockStore(ctx, t, logger, cfg, state, pv, kvstore.NewApplication(), blockStore)
+	cs1.doWALCatchup = false
+	cs1Ctx, cs1Cancel := context.WithCancel(ctx)
+	require.NoError(t, cs1.Start(cs1Ctx))
+	select {
+	case <-pv.blocked:
+	case <-time.After(10 * time.Second):
+		t.Fatal("height 3 was not prevoted")
+	}
+	cs1Cancel()
+	cs1.Wait()
+	require.Equal(t, int64(2), cs1.GetLastHeight())
+
+	// replay the WAL of the current height without an event bus, an evidence
+	// pool or a private validator
+	cs2, err := NewStateForTooling(logger, cfg.Consensus, cs1.stateStore, cs1.blockExec, blockStore,
+		cs1.txNotifier, nil, nil)
+	require.NoError(t, err)
+	require.True(t, cs2.ownsEventBus)
+	require.Equal(t, sm.EmptyEvidencePool{}, cs2.evpool)
+	lastHeight := cs2.GetLastHeight()
+
+	cs2Ctx, cs2Cancel := context.WithCancel(ctx)
+	require.NoError(t, cs2.Start(cs2Ctx))
+	status := cs2.Status()
+	require.Equal(t, StartupPhaseRunning, status.Phase)
+	require.Equal(t, lastHeight+1, status.Height)
+	require.Positive(t, status.ReplayedMsgs)
+	require.True(t, cs2.eventBus.IsRunning())
+
+	cs2Cancel()
+	cs2.Wait()
+	require.False(t, cs2.eventBus.IsRunning())
+}
+
+func TestStateForToolingRefusesToSign(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs1, _ := makeState(ctx, t, makeStateArgs{validators: 1})
+	pv, _ := cs1.getPrivValidator()
+
+	cs2, err := NewStateForTooling(log.NewNopLogger(), cs1.config, cs1.stateStore, cs1.blockExec, cs1.blockStore,
+		cs1.txNotifier, nil, nil)
+	require.NoError(t, err)
+	cs2.SetPrivValidator(ctx, pv)
+	require.ErrorIs(t, cs2.Start(ctx), ErrToolingSigning)
+	require.False(t, cs2.eventBus.IsRunning())
+}
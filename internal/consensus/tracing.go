@@ -0,0 +1,157 @@
+package consensus
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	otrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "tm-consensus-state"
+
+	// tracerShutdownTimeout bounds the flush of the spans on stop.
+	tracerShutdownTimeout = 5 * time.Second
+)
+
+// noopTracer is used below the height span of heights that are not sampled.
+var noopTracer = otrace.NewNoopTracerProvider().Tracer(tracerName)
+
+// noopSpan is the span of the State when tracing is disabled.
+var noopSpan = otrace.SpanFromContext(context.Background())
+
+// WithTracerProvider sets the provider of the tracer of the State, instead of
+// the one built from the tracer provider options passed to NewState. The
+// provider is not shut down when the State stops.
+func WithTracerProvider(tp otrace.TracerProvider) StateOption {
+	return func(cs *State) { cs.tracerProvider = tp }
+}
+
+// setupTracer builds the tracer provider from opts unless one was set with
+// WithTracerProvider. Without either, no span could be exported: tracing is
+// disabled, so that no span is built.
+func (cs *State) setupTracer(opts []trace.TracerProviderOption) {
+	cs.tracerProviderOptions = opts
+	cs.tracingDisabled = cs.tracerProvider == nil && len(opts) == 0
+	if cs.tracingDisabled {
+		cs.heightTracer = noopTracer
+		cs.tracer = noopTracer
+		return
+	}
+
+	if cs.tracerProvider == nil {
+		tp := trace.NewTracerProvider(opts...)
+		cs.tracerProvider = tp
+		cs.ownedTracerProvider = tp
+	}
+	cs.heightTracer = cs.tracerProvider.Tracer(tracerName)
+	cs.tracer = cs.heightTracer
+}
+
+// startSpan starts the span name with attrs, below the span of the current
+// height if ctx is the tracing context. It returns ctx and a no-op span when
+// tracing is disabled, without allocating.
+func (cs *State) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, otrace.Span) {
+	if cs.tracingDisabled {
+		return ctx, noopSpan
+	}
+	ctx, span := cs.tracer.Start(ctx, name)
+	if len(attrs) > 0 {
+		// copied, so that attrs do not escape to the heap when tracing is
+		// disabled
+		span.SetAttributes(append([]attribute.KeyValue(nil), attrs...)...)
+	}
+	return ctx, span
+}
+
+// isHeightSampled returns whether all the spans of height are traced.
+func (cs *State) isHeightSampled(height int64) bool {
+	interval := cs.config.TraceSampleInterval
+	return interval <= 1 || height%interval == 0
+}
+
+// startHeightSpan ends the span of the previous height and starts the span of
+// height. The spans below it are only traced if height is sampled.
+func (cs *State) startHeightSpan(ctx context.Context, height int64) {
+	if cs.tracingDisabled {
+		return
+	}
+	cs.endRoundSpan()
+	if cs.heightSpan != nil {
+		cs.heightSpan.End()
+	}
+	cs.heightBeingTraced = height
+	cs.tracingCtx, cs.heightSpan = cs.heightTracer.Start(ctx, "cs.state.Height")
+	cs.heightSpan.SetAttributes(attribute.Int64("height", height))
+
+	sampled := cs.isHeightSampled(height)
+	cs.heightSpan.SetAttributes(attribute.Bool("sampled", sampled))
+	if sampled {
+		cs.tracer = cs.heightTracer
+	} else {
+		cs.tracer = noopTracer
+	}
+}
+
+// startRoundSpan ends the span of the previous round and starts the span of
+// round below the span of the current height. The spans of the steps of the
+// round are below it.
+func (cs *State) startRoundSpan(round int32) {
+	if cs.tracingDisabled || cs.tracingCtx == nil {
+		return
+	}
+	cs.endRoundSpan()
+	cs.roundTracingCtx, cs.roundSpan = cs.tracer.Start(cs.tracingCtx, "cs.state.Round")
+	cs.roundSpan.SetAttributes(attribute.Int("round", int(round)))
+	cs.roundBeingTraced = round
+}
+
+// endRoundSpan ends the span of the current round, once it is superseded or
+// its height is committed.
+func (cs *State) endRoundSpan() {
+	if cs.roundSpan == nil {
+		return
+	}
+	cs.roundSpan.End()
+	cs.roundSpan = nil
+	cs.roundTracingCtx = nil
+}
+
+// getRoundTracingCtx returns the context of the span of the current round,
+// or that of the current height outside of a round.
+func (cs *State) getRoundTracingCtx(defaultCtx context.Context) context.Context {
+	if cs.roundTracingCtx != nil {
+		return cs.roundTracingCtx
+	}
+	return cs.getTracingCtx(defaultCtx)
+}
+
+// startMsgSpan starts the span name of a message for height and round, below
+// the span of the round if it is the current one. The spans of the messages
+// of other rounds and heights are below the span of the height, marked as
+// foreign_round.
+func (cs *State) startMsgSpan(ctx context.Context, name string, height int64, round int32) (context.Context, otrace.Span) {
+	if cs.tracingDisabled {
+		return ctx, noopSpan
+	}
+	if cs.roundTracingCtx != nil && height == cs.heightBeingTraced && round == cs.roundBeingTraced {
+		return cs.startSpan(cs.roundTracingCtx, name, attribute.Int("round", int(round)))
+	}
+	return cs.startSpan(cs.getTracingCtx(ctx), name,
+		attribute.Int("round", int(round)), attribute.Bool("foreign_round", true))
+}
+
+// shutdownTracerProvider flushes the spans and shuts down the tracer provider
+// if it was built by the State.
+func (cs *State) shutdownTracerProvider() {
+	if cs.ownedTracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	defer cancel()
+	if err := cs.ownedTracerProvider.Shutdown(ctx); err != nil {
+		cs.logger.Error("failed to shut down tracer provider", "err", err)
+	}
+}
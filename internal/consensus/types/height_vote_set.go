@@ -67,6 +67,12 @@ func NewExtendedHeightVoteSet(chainID string, height int64, valSet *types.Valida
 	return hvs
 }
 
+// ExtensionsEnabled returns whether the precommits of the height carry vote
+// extensions.
+func (hvs *HeightVoteSet) ExtensionsEnabled() bool {
+	return hvs.extensionsEnabled
+}
+
 func (hvs *HeightVoteSet) Reset(height int64, valSet *types.ValidatorSet) {
 	hvs.mtx.Lock()
 	defer hvs.mtx.Unlock()
@@ -113,6 +119,17 @@ func (hvs *HeightVoteSet) SetRound(round int32) {
 	hvs.round = round
 }
 
+// TrackRound starts tracking the votes of a past round, so that they are
+// accepted from any peer. It is a no-op if the round is already tracked.
+func (hvs *HeightVoteSet) TrackRound(round int32) {
+	hvs.mtx.Lock()
+	defer hvs.mtx.Unlock()
+	if _, ok := hvs.roundVoteSets[round]; ok {
+		return
+	}
+	hvs.addRound(round)
+}
+
 func (hvs *HeightVoteSet) addRound(round int32) {
 	if _, ok := hvs.roundVoteSets[round]; ok {
 		panic("addRound() for an existing round")
@@ -134,6 +151,24 @@ func (hvs *HeightVoteSet) addRound(round int32) {
 // Duplicate votes return added=false, err=nil.
 // By convention, peerID is "" if origin is self.
 func (hvs *HeightVoteSet) AddVote(vote *types.Vote, peerID types.NodeID) (added bool, err error) {
+	return hvs.addVote(vote, peerID, types.VoteUnverified)
+}
+
+// AddPreVerifiedVote is like AddVote, but skips the signature checks verified
+// says were already made. See VoteSet.AddPreVerifiedVote.
+func (hvs *HeightVoteSet) AddPreVerifiedVote(
+	vote *types.Vote,
+	peerID types.NodeID,
+	verified types.VoteVerification,
+) (added bool, err error) {
+	return hvs.addVote(vote, peerID, verified)
+}
+
+func (hvs *HeightVoteSet) addVote(
+	vote *types.Vote,
+	peerID types.NodeID,
+	verified types.VoteVerification,
+) (added bool, err error) {
 	hvs.mtx.Lock()
 	defer hvs.mtx.Unlock()
 	if !types.IsVoteTypeValid(vote.Type) {
@@ -151,7 +186,7 @@ func (hvs *HeightVoteSet) AddVote(vote *types.Vote, peerID types.NodeID) (added
 			return
 		}
 	}
-	added, err = voteSet.AddVote(vote)
+	added, err = voteSet.AddPreVerifiedVote(vote, verified)
 	return
 }
 
@@ -57,6 +57,37 @@ func TestPeerCatchupRounds(t *testing.T) {
 
 }
 
+func TestTrackRoundAcceptsVotesFromAnyPeer(t *testing.T) {
+	cfg, err := config.ResetTestRoot(t.TempDir(), "consensus_height_vote_set_test")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 10, 1)
+
+	chainID := cfg.ChainID()
+	hvs := NewExtendedHeightVoteSet(chainID, 1, valSet)
+
+	// peer1 used up its catchup rounds
+	for _, round := range []int32{999, 1000} {
+		added, err := hvs.AddVote(makeVoteHR(ctx, t, 1, 0, round, privVals, chainID), "peer1")
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+	require.Nil(t, hvs.Precommits(1001))
+
+	hvs.TrackRound(1001)
+	require.NotNil(t, hvs.Precommits(1001))
+	added, err := hvs.AddVote(makeVoteHR(ctx, t, 1, 0, 1001, privVals, chainID), "peer1")
+	require.NoError(t, err)
+	require.True(t, added)
+
+	// tracking an already tracked round keeps its votes
+	hvs.TrackRound(1001)
+	require.NotNil(t, hvs.Precommits(1001).GetByIndex(0))
+}
+
 func makeVoteHR(
 	ctx context.Context,
 	t *testing.T,
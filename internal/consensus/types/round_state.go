@@ -270,6 +270,18 @@ func (s *SafeRoundState) SetLockedBlockParts(p *types.PartSet) {
 	s.internal.LockedBlockParts = p
 }
 
+func (s *SafeRoundState) AwaitingPOLRound() int32 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.internal.AwaitingPOLRound
+}
+
+func (s *SafeRoundState) SetAwaitingPOLRound(r int32) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.internal.AwaitingPOLRound = r
+}
+
 func (s *SafeRoundState) ValidRound() int32 {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
@@ -318,16 +330,16 @@ func (s *SafeRoundState) SetLastValidators(p *types.ValidatorSet) {
 	s.internal.LastValidators = p
 }
 
-func (s *SafeRoundState) TriggeredTimeoutPrecommit() bool {
+func (s *SafeRoundState) TriggeredTimeoutPrecommitRound() int32 {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
-	return s.internal.TriggeredTimeoutPrecommit
+	return s.internal.TriggeredTimeoutPrecommitRound
 }
 
-func (s *SafeRoundState) SetTriggeredTimeoutPrecommit(p bool) {
+func (s *SafeRoundState) SetTriggeredTimeoutPrecommitRound(r int32) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
-	s.internal.TriggeredTimeoutPrecommit = p
+	s.internal.TriggeredTimeoutPrecommitRound = r
 }
 
 func (s *SafeRoundState) RoundStateEvent() types.EventDataRoundState {
@@ -348,6 +360,12 @@ func (s *SafeRoundState) CompleteProposalEvent() types.EventDataCompleteProposal
 	return s.internal.CompleteProposalEvent()
 }
 
+func (s *SafeRoundState) LockEvent() types.EventDataLock {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.internal.LockEvent()
+}
+
 //-----------------------------------------------------------------------------
 
 // RoundState defines the internal consensus state.
@@ -384,12 +402,18 @@ type RoundState struct {
 	ValidBlock *types.Block `json:"valid_block"` // Last known block of POL mentioned above.
 
 	// Last known block parts of POL mentioned above.
-	ValidBlockParts           *types.PartSet      `json:"valid_block_parts"`
-	Votes                     *HeightVoteSet      `json:"votes"`
-	CommitRound               int32               `json:"commit_round"` //
-	LastCommit                *types.VoteSet      `json:"last_commit"`  // Last precommits at Height-1
-	LastValidators            *types.ValidatorSet `json:"last_validators"`
-	TriggeredTimeoutPrecommit bool                `json:"triggered_timeout_precommit"`
+	ValidBlockParts *types.PartSet      `json:"valid_block_parts"`
+	Votes           *HeightVoteSet      `json:"votes"`
+	CommitRound     int32               `json:"commit_round"` //
+	LastCommit      *types.VoteSet      `json:"last_commit"`  // Last precommits at Height-1
+	LastValidators  *types.ValidatorSet `json:"last_validators"`
+
+	// Round whose precommit wait was triggered; -1 if none in this height.
+	TriggeredTimeoutPrecommitRound int32 `json:"triggered_timeout_precommit_round"`
+
+	// POL round of the proposal whose prevotes were solicited from peers
+	// because we had no 2/3 majority of them; -1 if none.
+	AwaitingPOLRound int32 `json:"awaiting_pol_round"`
 }
 
 // Compressed version of the RoundState for use in RPC
@@ -460,6 +484,19 @@ func (rs *RoundState) CompleteProposalEvent() types.EventDataCompleteProposal {
 	}
 }
 
+// LockEvent returns information about the locked block as an event.
+func (rs *RoundState) LockEvent() types.EventDataLock {
+	return types.EventDataLock{
+		Height: rs.Height,
+		Round:  rs.LockedRound,
+		Step:   rs.Step.String(),
+		BlockID: types.BlockID{
+			Hash:          rs.LockedBlock.Hash(),
+			PartSetHeader: rs.LockedBlockParts.Header(),
+		},
+	}
+}
+
 // RoundStateEvent returns the H/R/S of the RoundState as an event.
 func (rs *RoundState) RoundStateEvent() types.EventDataRoundState {
 	return types.EventDataRoundState{
@@ -487,6 +524,7 @@ func (rs *RoundState) StringIndented(indent string) string {
 %s  LockedBlock:   %v %v
 %s  ValidRound:    %v
 %s  ValidBlock:    %v %v
+%s  AwaitingPOL:   %v
 %s  Votes:         %v
 %s  LastCommit:    %v
 %s  LastValidators:%v
@@ -501,6 +539,7 @@ func (rs *RoundState) StringIndented(indent string) string {
 		indent, rs.LockedBlockParts.StringShort(), rs.LockedBlock.StringShort(),
 		indent, rs.ValidRound,
 		indent, rs.ValidBlockParts.StringShort(), rs.ValidBlock.StringShort(),
+		indent, rs.AwaitingPOLRound,
 		indent, rs.Votes.StringIndented(indent+"  "),
 		indent, rs.LastCommit.StringShort(),
 		indent, rs.LastValidators.StringIndented(indent+"  "),
@@ -0,0 +1,82 @@
+package consensus
+
+import (
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ValidatorDiff lists the changes between the validator set of Height and the
+// validator set of the previous height. At the initial height, all validators
+// are added.
+type ValidatorDiff struct {
+	Height       int64
+	Added        []types.ValidatorPowerChange
+	Removed      []types.ValidatorPowerChange
+	PowerChanged []types.ValidatorPowerChange
+}
+
+// IsEmpty returns true if the validator set did not change.
+func (d ValidatorDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.PowerChanged) == 0
+}
+
+// diffValidatorSets returns the changes from the validator set prev to next,
+// which validates height. Added and changed validators are in the order of
+// next and removed validators in the order of prev.
+func diffValidatorSets(height int64, prev, next *types.ValidatorSet) ValidatorDiff {
+	if prev == nil {
+		prev = &types.ValidatorSet{}
+	}
+	if next == nil {
+		next = &types.ValidatorSet{}
+	}
+
+	diff := ValidatorDiff{Height: height}
+	for _, val := range next.Validators {
+		_, old := prev.GetByAddress(val.Address)
+		switch {
+		case old == nil:
+			diff.Added = append(diff.Added, types.ValidatorPowerChange{
+				Address: val.Address, NewPower: val.VotingPower,
+			})
+		case old.VotingPower != val.VotingPower:
+			diff.PowerChanged = append(diff.PowerChanged, types.ValidatorPowerChange{
+				Address: val.Address, OldPower: old.VotingPower, NewPower: val.VotingPower,
+			})
+		}
+	}
+	for _, val := range prev.Validators {
+		if !next.HasAddress(val.Address) {
+			diff.Removed = append(diff.Removed, types.ValidatorPowerChange{
+				Address: val.Address, OldPower: val.VotingPower,
+			})
+		}
+	}
+	return diff
+}
+
+// updateValidatorSetDiff caches the validator set changes of state and
+// publishes them if there are any.
+func (cs *State) updateValidatorSetDiff(height int64, state sm.State) {
+	diff := diffValidatorSets(height, state.LastValidators, state.Validators)
+	cs.validatorSetDiff = diff
+	if diff.IsEmpty() || cs.eventBus == nil {
+		return
+	}
+
+	if err := cs.publishEvent(types.EventValidatorSetDiffValue, types.EventDataValidatorSetDiff{
+		Height:       diff.Height,
+		Added:        diff.Added,
+		Removed:      diff.Removed,
+		PowerChanged: diff.PowerChanged,
+	}); err != nil {
+		cs.logger.Error("failed publishing validator set diff", "err", err)
+	}
+}
+
+// ValidatorSetDiff returns the validator set changes of the current height.
+func (cs *State) ValidatorSetDiff() ValidatorDiff {
+	cs.mtx.RLock()
+	defer cs.mtx.RUnlock()
+	return cs.validatorSetDiff
+}
@@ -0,0 +1,114 @@
+package consensus
+
+import (
+	"bytes"
+	"sync"
+
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ValidatorMembership is the membership of the key of this node in the
+// validator set of the current height. It is updated when the State moves to
+// a new height, with the key set at that time.
+type ValidatorMembership struct {
+	// Address is the address of the key of this node; nil if the node does
+	// not have a key.
+	Address types.Address
+	// InValidatorSet is true if the key is in the validator set.
+	InValidatorSet bool
+	// ChangedAt is the height the key last entered or left the validator
+	// set; 0 if it did not since the State started.
+	ChangedAt int64
+}
+
+// validatorMembership tracks the membership of the key of this node in the
+// validator set across heights.
+type validatorMembership struct {
+	mtx    sync.Mutex
+	status ValidatorMembership
+	// false until the membership of a height is known
+	known bool
+	// true once the operator was told of the current membership, after
+	// which the per-round logs of a node out of the validator set are
+	// logged at debug
+	acknowledged bool
+}
+
+// updateValidatorMembership updates the membership of the key of this node
+// with the validator set of state. When the key enters or leaves the
+// validator set, the transition is logged and published.
+func (cs *State) updateValidatorMembership(height int64, state sm.State) {
+	var addr types.Address
+	if _, pubKey := cs.getPrivValidator(); pubKey != nil {
+		addr = pubKey.Address()
+	}
+	in := addr != nil && state.Validators.HasAddress(addr)
+	if in {
+		cs.metrics.ValidatorSetMember.Set(1)
+	} else {
+		cs.metrics.ValidatorSetMember.Set(0)
+	}
+
+	m := &cs.validatorMembership
+	m.mtx.Lock()
+	was, known := m.status.InValidatorSet, m.known && bytes.Equal(addr, m.status.Address)
+	m.status.Address = addr
+	m.status.InValidatorSet = in
+	m.known = addr != nil
+	if !known {
+		m.status.ChangedAt = 0
+		m.acknowledged = false
+	}
+	changed := known && was != in
+	if changed {
+		m.status.ChangedAt = height
+		m.acknowledged = true
+	}
+	m.mtx.Unlock()
+	if !changed {
+		return
+	}
+
+	eventValue := types.EventValidatorAddedValue
+	if in {
+		cs.logger.Info("added to the validator set; signing as a validator", "height", height, "addr", addr)
+	} else {
+		eventValue = types.EventValidatorRemovedValue
+		cs.logger.Info("removed from the validator set; continuing as a full node", "height", height, "addr", addr)
+	}
+	if cs.eventBus == nil {
+		return
+	}
+	if err := cs.publishEvent(eventValue, types.EventDataValidatorMembership{
+		Height:  height,
+		Address: addr,
+	}); err != nil {
+		cs.logger.Error("failed publishing validator membership", "err", err)
+	}
+}
+
+// notValidatorLogger returns the log function of the per-round messages of a
+// node whose key is not in the validator set. The first of them is logged at
+// info unless the transition out of the validator set was already logged, and
+// the following ones at debug.
+func (cs *State) notValidatorLogger(logger log.Logger) func(msg string, keyvals ...interface{}) {
+	m := &cs.validatorMembership
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.acknowledged {
+		return logger.Debug
+	}
+	m.acknowledged = true
+	return logger.Info
+}
+
+// ValidatorMembership returns the membership of the key of this node in the
+// validator set of the current height.
+func (cs *State) ValidatorMembership() ValidatorMembership {
+	m := &cs.validatorMembership
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.status
+}
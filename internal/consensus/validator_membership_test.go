@@ -0,0 +1,140 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/internal/eventbus"
+	tmpubsub "github.com/tendermint/tendermint/internal/pubsub"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// logLevels returns the levels of the JSON log lines of logs by message.
+func logLevels(t *testing.T, logs *bytes.Buffer) map[string][]string {
+	t.Helper()
+	levels := make(map[string][]string)
+	dec := json.NewDecoder(logs)
+	for dec.More() {
+		var line struct {
+			Level string `json:"level"`
+			Msg   string `json:"_msg"`
+		}
+		require.NoError(t, dec.Decode(&line))
+		levels[line.Msg] = append(levels[line.Msg], line.Level)
+	}
+	return levels
+}
+
+func TestStateValidatorMembership(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+	member := generic.NewGauge("validator_set_member")
+	cs.metrics.ValidatorSetMember = member
+	removedSub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryValidatorRemoved,
+	})
+	require.NoError(t, err)
+	addedSub, err := cs.eventBus.SubscribeWithArgs(ctx, tmpubsub.SubscribeArgs{
+		ClientID: testSubscriber,
+		Query:    types.EventQueryValidatorAdded,
+	})
+	require.NoError(t, err)
+	nextMembershipEvent := func(sub eventbus.Subscription) types.EventDataValidatorMembership {
+		t.Helper()
+		msgCtx, msgCancel := context.WithTimeout(ctx, ensureTimeout)
+		defer msgCancel()
+		msg, err := sub.Next(msgCtx)
+		require.NoError(t, err)
+		event, ok := msg.Data().(types.EventDataValidatorMembership)
+		require.True(t, ok)
+		return event
+	}
+
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	_, pubKey := cs.getPrivValidator()
+	addr := pubKey.Address()
+	vals := cs.state.Validators.Copy()
+	nextState := func(vals *types.ValidatorSet) sm.State {
+		state := cs.state.Copy()
+		state.LastBlockHeight++
+		state.LastValidators = state.Validators
+		state.Validators = vals
+		return state
+	}
+	cs.roundState.SetLastCommit(types.NewVoteSet(cs.state.ChainID, cs.roundState.Height(), 0, tmproto.PrecommitType, vals))
+
+	// the membership of the first height with the key is not a transition
+	require.True(t, cs.updateToState(nextState(vals.Copy()), stateUpdateSourceFinalize))
+	require.Equal(t, ValidatorMembership{Address: addr, InValidatorSet: true}, cs.Status().Membership)
+	require.Equal(t, 1.0, member.Value())
+
+	// the finalized block removes this node from the validator set
+	_, self := vals.GetByAddress(addr)
+	removed := self.Copy()
+	removed.VotingPower = 0
+	withoutSelf := vals.Copy()
+	require.NoError(t, withoutSelf.UpdateWithChangeSet([]*types.Validator{removed}))
+	require.True(t, cs.updateToState(nextState(withoutSelf), stateUpdateSourceFinalize))
+	require.Equal(t, ValidatorMembership{Address: addr, ChangedAt: 3}, cs.Status().Membership)
+	require.Equal(t, 0.0, member.Value())
+	require.Equal(t, types.EventDataValidatorMembership{Height: 3, Address: addr}, nextMembershipEvent(removedSub))
+
+	// the rounds of a full node are logged at debug once the transition is
+	// logged
+	cs.enterNewRound(ctx, 3, 0, "test")
+	cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	levels := logLevels(t, &logs)
+	require.Equal(t, []string{"info"}, levels["removed from the validator set; continuing as a full node"])
+	require.Equal(t, []string{"debug"}, levels["propose step; not proposing since node is not in the validator set"])
+	require.Equal(t, []string{"debug", "debug"}, levels["not voting since node is not in the validator set"])
+
+	// the converse transition is published as well
+	require.True(t, cs.updateToState(nextState(vals.Copy()), stateUpdateSourceFinalize))
+	require.Equal(t, ValidatorMembership{Address: addr, InValidatorSet: true, ChangedAt: 4}, cs.Status().Membership)
+	require.Equal(t, 1.0, member.Value())
+	require.Equal(t, types.EventDataValidatorMembership{Height: 4, Address: addr}, nextMembershipEvent(addedSub))
+	require.Equal(t, []string{"info"}, logLevels(t, &logs)["added to the validator set; signing as a validator"])
+}
+
+func TestStateNotValidatorLogs(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config})
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	// a key out of the validator set without a logged transition is reported
+	// once at info
+	_, pubKey := cs.getPrivValidator()
+	_, self := cs.state.Validators.GetByAddress(pubKey.Address())
+	removed := self.Copy()
+	removed.VotingPower = 0
+	withoutSelf := cs.state.Validators.Copy()
+	require.NoError(t, withoutSelf.UpdateWithChangeSet([]*types.Validator{removed}))
+	cs.roundState.SetValidators(withoutSelf)
+	cs.signAddVote(ctx, tmproto.PrevoteType, nil, types.PartSetHeader{})
+	cs.signAddVote(ctx, tmproto.PrecommitType, nil, types.PartSetHeader{})
+	require.Equal(t, []string{"info", "debug"}, logLevels(t, &logs)["not voting since node is not in the validator set"])
+}
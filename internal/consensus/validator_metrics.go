@@ -0,0 +1,110 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/types"
+)
+
+// validatorMetricsQueueSize is the number of heights whose per-validator
+// metrics are buffered for the background worker. The metrics of a height are
+// dropped when the worker falls behind.
+const validatorMetricsQueueSize = 16
+
+// validatorMetricsJob is the last commit of a committed height, whose
+// per-validator metrics are updated by the background worker.
+type validatorMetricsJob struct {
+	height     int64
+	validators []*types.Validator
+	signatures []types.CommitSig
+	// address of this validator; nil if the node is not a validator
+	address types.Address
+}
+
+// validatorMetrics updates the per-validator metrics of the committed heights
+// in the background, so that the commit of a height with a large validator
+// set does not wait for them.
+type validatorMetrics struct {
+	// nil until the worker is started; the metrics are then updated in place
+	jobs chan validatorMetricsJob
+
+	// missing validators power last set per validator address, only accessed
+	// by the worker
+	missingPower map[string]float64
+}
+
+// startValidatorMetrics starts the worker updating the per-validator metrics
+// until ctx is done. It must be called before the receive routine starts.
+func (cs *State) startValidatorMetrics(ctx context.Context) {
+	jobs := make(chan validatorMetricsJob, validatorMetricsQueueSize)
+	cs.validatorMetrics.jobs = jobs
+	cs.spawn(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-jobs:
+				cs.updateValidatorMetrics(job)
+			}
+		}
+	})
+}
+
+// recordValidatorMetrics queues the per-validator metrics of job for the
+// worker, or updates them if the worker is not started.
+func (cs *State) recordValidatorMetrics(job validatorMetricsJob) {
+	if cs.validatorMetrics.jobs == nil {
+		cs.updateValidatorMetrics(job)
+		return
+	}
+	select {
+	case cs.validatorMetrics.jobs <- job:
+	default:
+		cs.logger.Debug("dropping the validator metrics of a height; the worker fell behind", "height", job.height)
+	}
+}
+
+// updateValidatorMetrics sets the per-validator metrics of job. The missing
+// validators power gauges are set according to
+// config.MissingValidatorsPowerMetrics.
+func (cs *State) updateValidatorMetrics(job validatorMetricsJob) {
+	mode := cs.config.MissingValidatorsPowerMetrics
+	var missingPower map[string]float64
+	if mode == config.MissingValidatorsPowerChanged {
+		missingPower = make(map[string]float64, len(job.validators))
+	}
+
+	for i, val := range job.validators {
+		commitSig := job.signatures[i]
+		var power float64
+		if commitSig.BlockIDFlag == types.BlockIDFlagAbsent {
+			power = float64(val.VotingPower)
+		}
+		switch mode {
+		case config.MissingValidatorsPowerNone:
+		case config.MissingValidatorsPowerChanged:
+			address := val.Address.String()
+			if last, ok := cs.validatorMetrics.missingPower[address]; !ok || last != power {
+				cs.metrics.MissingValidatorsPower.With("validator_address", address).Set(power)
+			}
+			missingPower[address] = power
+		default:
+			cs.metrics.MissingValidatorsPower.With("validator_address", val.Address.String()).Set(power)
+		}
+
+		if job.address != nil && bytes.Equal(val.Address, job.address) {
+			label := []string{
+				"validator_address", val.Address.String(),
+			}
+			cs.metrics.ValidatorPower.With(label...).Set(float64(val.VotingPower))
+			if commitSig.BlockIDFlag == types.BlockIDFlagCommit {
+				cs.metrics.ValidatorLastSignedHeight.With(label...).Set(float64(job.height))
+			} else {
+				cs.metrics.ValidatorMissedBlocks.With(label...).Add(float64(1))
+			}
+		}
+	}
+	cs.validatorMetrics.missingPower = missingPower
+}
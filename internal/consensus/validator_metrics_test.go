@@ -0,0 +1,177 @@
+package consensus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/types"
+)
+
+// labeledGauge records the values set per label values, and the number of
+// sets.
+type labeledGauge struct {
+	mtx    *sync.Mutex
+	values map[string]float64
+	sets   *int
+	labels string
+}
+
+func newLabeledGauge() *labeledGauge {
+	return &labeledGauge{mtx: new(sync.Mutex), values: make(map[string]float64), sets: new(int)}
+}
+
+func (g *labeledGauge) With(labelValues ...string) metrics.Gauge {
+	return &labeledGauge{mtx: g.mtx, values: g.values, sets: g.sets, labels: strings.Join(labelValues, ",")}
+}
+
+func (g *labeledGauge) Set(value float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.values[g.labels] = value
+	*g.sets++
+}
+
+func (g *labeledGauge) Add(delta float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.values[g.labels] += delta
+}
+
+func (g *labeledGauge) value(labelValues ...string) float64 {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.values[strings.Join(labelValues, ",")]
+}
+
+// makeLastCommit returns a block whose last commit is signed by the last
+// validators of cs but those of absent.
+func makeLastCommit(cs *State, absent ...int) *types.Block {
+	signatures := make([]types.CommitSig, cs.roundState.LastValidators().Size())
+	for i := range signatures {
+		signatures[i].BlockIDFlag = types.BlockIDFlagCommit
+	}
+	for _, i := range absent {
+		signatures[i].BlockIDFlag = types.BlockIDFlagAbsent
+	}
+	return &types.Block{LastCommit: &types.Commit{Signatures: signatures}}
+}
+
+func TestStateValidatorMetrics(t *testing.T) {
+	cfg := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, t, makeStateArgs{config: cfg})
+	cs.roundState.SetLastValidators(cs.roundState.Validators())
+	validators := cs.roundState.LastValidators().Validators
+	height := cs.state.InitialHeight + 1
+	missingPower := newLabeledGauge()
+	cs.metrics.MissingValidatorsPower = missingPower
+	totalPower := generic.NewGauge("missing_validators_total_power")
+	cs.metrics.MissingValidatorsTotalPower = totalPower
+	record := func(absent ...int) {
+		cs.RecordMetrics(height, makeLastCommit(cs, absent...))
+	}
+	requireMissing := func(absent ...int) {
+		t.Helper()
+		for i, val := range validators {
+			expected := 0.0
+			for _, j := range absent {
+				if i == j {
+					expected = float64(val.VotingPower)
+				}
+			}
+			require.Equal(t, expected, missingPower.value("validator_address", val.Address.String()))
+		}
+	}
+
+	// all the gauges are set at each height
+	record(0, 3)
+	require.Equal(t, 4, *missingPower.sets)
+	requireMissing(0, 3)
+	require.Equal(t, float64(2*validators[0].VotingPower), totalPower.Value())
+	record(0, 3)
+	require.Equal(t, 8, *missingPower.sets)
+
+	// only the gauges of the validators whose signing changed are set
+	*missingPower.sets = 0
+	cs.config.MissingValidatorsPowerMetrics = config.MissingValidatorsPowerChanged
+	record(0, 3)
+	require.Equal(t, 4, *missingPower.sets)
+	record(0, 3)
+	require.Equal(t, 4, *missingPower.sets)
+	record(0, 1)
+	require.Equal(t, 6, *missingPower.sets)
+	requireMissing(0, 1)
+
+	// only the aggregates are set
+	cs.config.MissingValidatorsPowerMetrics = config.MissingValidatorsPowerNone
+	record(2)
+	require.Equal(t, 6, *missingPower.sets)
+	requireMissing(0, 1)
+	require.Equal(t, float64(validators[2].VotingPower), totalPower.Value())
+
+	// the worker sets the gauges in the background
+	cs.config.MissingValidatorsPowerMetrics = config.MissingValidatorsPowerAll
+	cs.startValidatorMetrics(ctx)
+	cs.recordValidatorMetrics(validatorMetricsJob{
+		height:     height,
+		validators: validators,
+		signatures: makeLastCommit(cs, 2).LastCommit.Signatures,
+	})
+	require.Eventually(t, func() bool {
+		return missingPower.value("validator_address", validators[2].Address.String()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func BenchmarkRecordMetrics(b *testing.B) {
+	cfg := configSetup(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, _ := makeState(ctx, b, makeStateArgs{config: cfg, validators: 200})
+	cs.roundState.SetLastValidators(cs.roundState.Validators())
+	cs.metrics.MissingValidatorsPower = prometheus.NewGauge(stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+		Name: "missing_validators_power",
+	}, []string{"validator_address"}))
+	height := cs.state.InitialHeight + 1
+	block := makeLastCommit(cs, 0, 50, 100, 150)
+
+	for _, bc := range []struct {
+		name   string
+		mode   string
+		worker bool
+	}{
+		// the gauges are set in the commit path, as before the worker
+		{name: "all-inline", mode: config.MissingValidatorsPowerAll},
+		{name: "all", mode: config.MissingValidatorsPowerAll, worker: true},
+		{name: "changed", mode: config.MissingValidatorsPowerChanged, worker: true},
+		{name: "none", mode: config.MissingValidatorsPowerNone, worker: true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			cs.config.MissingValidatorsPowerMetrics = bc.mode
+			cs.validatorMetrics = validatorMetrics{}
+			if bc.worker {
+				cs.startValidatorMetrics(ctx)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cs.RecordMetrics(height, block)
+			}
+		})
+	}
+}
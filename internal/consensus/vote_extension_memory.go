@@ -0,0 +1,84 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// voteExtensionMemoryTopValidators is the number of validators whose vote
+// extensions take the most memory reported when the soft cap is exceeded.
+const voteExtensionMemoryTopValidators = 5
+
+// voteExtensionMemory accounts for the vote extension bytes held in the vote
+// sets of the current height.
+type voteExtensionMemory struct {
+	height int64
+	bytes  int64
+	// bytes by validator address
+	byValidator map[string]int64
+	// warned is set once the soft cap was exceeded in the height
+	warned bool
+}
+
+func (m *voteExtensionMemory) reset(height int64) {
+	*m = voteExtensionMemory{height: height, byValidator: make(map[string]int64)}
+}
+
+// top returns the n validators whose extensions take the most bytes, as
+// address=bytes, largest first.
+func (m *voteExtensionMemory) top(n int) []string {
+	addrs := make([]string, 0, len(m.byValidator))
+	for addr := range m.byValidator {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		if m.byValidator[addrs[i]] != m.byValidator[addrs[j]] {
+			return m.byValidator[addrs[i]] > m.byValidator[addrs[j]]
+		}
+		return addrs[i] < addrs[j]
+	})
+	if len(addrs) > n {
+		addrs = addrs[:n]
+	}
+	top := make([]string, len(addrs))
+	for i, addr := range addrs {
+		top[i] = fmt.Sprintf("%X=%d", addr, m.byValidator[addr])
+	}
+	return top
+}
+
+// resetVoteExtensionMemory starts accounting for the vote extensions of
+// height.
+func (cs *State) resetVoteExtensionMemory(height int64) {
+	cs.voteExtensionMemory.reset(height)
+	cs.metrics.VoteExtensionBytes.Set(0)
+}
+
+// recordVoteExtension accounts for the extension of vote, added to the vote
+// sets of the current height, and warns once per height if the extensions
+// held exceed config.VoteExtensionMemorySoftCap.
+func (cs *State) recordVoteExtension(vote *types.Vote) {
+	m := &cs.voteExtensionMemory
+	size := int64(len(vote.Extension))
+	if size == 0 || vote.Height != m.height {
+		return
+	}
+	m.bytes += size
+	m.byValidator[string(vote.ValidatorAddress)] += size
+	cs.metrics.VoteExtensionBytes.Set(float64(m.bytes))
+	cs.metrics.VoteExtensionSize.Observe(float64(size))
+
+	softCap := cs.config.VoteExtensionMemorySoftCap
+	if softCap == 0 || m.bytes <= softCap || m.warned {
+		return
+	}
+	m.warned = true
+	cs.logger.Error("vote extensions held in the vote sets exceed the soft cap",
+		"height", m.height,
+		"bytes", m.bytes,
+		"soft_cap", softCap,
+		"top_validators", m.top(voteExtensionMemoryTopValidators),
+	)
+}
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+	otrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateVoteExtensionMemory(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	cs.config.VoteExtensionMemorySoftCap = 300
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+	held := generic.NewGauge("vote_extension_bytes")
+	cs.metrics.VoteExtensionBytes = held
+	sizes := generic.NewHistogram("vote_extension_size", 2)
+	cs.metrics.VoteExtensionSize = sizes
+
+	height := cs.roundState.Height()
+	blockID := types.BlockID{Hash: make([]byte, 32), PartSetHeader: types.PartSetHeader{Total: 1, Hash: make([]byte, 32)}}
+	span := otrace.SpanFromContext(ctx)
+	addPrecommit := func(vs *validatorStub, round int32, extensionSize int) {
+		t.Helper()
+		vs.Height, vs.Round = height, round
+		vote, err := vs.signVote(ctx, tmproto.PrecommitType, config.ChainID(), blockID, make([]byte, extensionSize))
+		require.NoError(t, err)
+		added, err := cs.addVote(ctx, vote, "peer", types.VoteUnverified, span)
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+	warnings := func() int {
+		return strings.Count(logs.String(), "vote extensions held in the vote sets exceed the soft cap")
+	}
+
+	// the extensions of every round are accounted for
+	addPrecommit(vss[1], 0, 100)
+	addPrecommit(vss[2], 0, 50)
+	addPrecommit(vss[1], 1, 100)
+	require.Equal(t, 250.0, held.Value())
+	require.Equal(t, 0, warnings())
+
+	// the soft cap warns once with the largest holders first
+	addPrecommit(vss[3], 0, 60)
+	require.Equal(t, 310.0, held.Value())
+	require.Equal(t, 1, warnings())
+	pub1, err := vss[1].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	pub3, err := vss[3].PrivValidator.GetPubKey(ctx)
+	require.NoError(t, err)
+	top1 := strings.Index(logs.String(), fmt.Sprintf("%X=200", pub1.Address()))
+	require.GreaterOrEqual(t, top1, 0)
+	require.Less(t, top1, strings.Index(logs.String(), fmt.Sprintf("%X=60", pub3.Address())))
+	addPrecommit(vss[2], 1, 50)
+	require.Equal(t, 360.0, held.Value())
+	require.Equal(t, 1, warnings())
+	require.Equal(t, 100.0, sizes.Quantile(0.99))
+
+	// the next height starts from zero
+	cs.resetVoteExtensionMemory(height + 1)
+	require.Zero(t, held.Value())
+	require.Zero(t, cs.voteExtensionMemory.bytes)
+}
@@ -0,0 +1,142 @@
+package consensus
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// The reasons a vote extension is rejected for.
+const (
+	// the signature of the extension is invalid
+	voteExtensionRejectedSignature = "signature"
+	// the application rejected the extension
+	voteExtensionRejectedApplication = "application"
+)
+
+// voteExtensionRejectionLogInterval is the minimum interval between two logs
+// of the rejected vote extensions of a validator. The rejections in between
+// are counted and reported with the next log.
+var voteExtensionRejectionLogInterval = 10 * time.Second
+
+// VoteExtensionRejections counts the rejected vote extensions of a validator
+// in the current height, by reason.
+type VoteExtensionRejections struct {
+	ValidatorAddress types.Address
+	// Signature is the number of extensions whose signature is invalid.
+	Signature int
+	// Application is the number of extensions the application rejected.
+	Application int
+}
+
+// voteExtensionRejections tallies the rejected vote extensions of the current
+// height by validator, and rate limits their logs.
+type voteExtensionRejections struct {
+	mtx        sync.Mutex
+	height     int64
+	validators map[string]*VoteExtensionRejections
+
+	// logs of the rejections of each validator, kept across heights
+	logs map[string]*voteExtensionRejectionLog
+}
+
+type voteExtensionRejectionLog struct {
+	last time.Time
+	// rejections not logged since last
+	suppressed int
+}
+
+// add counts a rejection of the extension of address at height for reason,
+// and returns whether it should be logged at now, with the number of the
+// rejections of address not logged since the last log.
+func (vr *voteExtensionRejections) add(
+	height int64,
+	address types.Address,
+	reason string,
+	now time.Time,
+) (logged bool, suppressed int) {
+	vr.mtx.Lock()
+	defer vr.mtx.Unlock()
+
+	if vr.height != height || vr.validators == nil {
+		vr.height = height
+		vr.validators = make(map[string]*VoteExtensionRejections)
+	}
+	key := string(address)
+	rejections, ok := vr.validators[key]
+	if !ok {
+		rejections = &VoteExtensionRejections{ValidatorAddress: address}
+		vr.validators[key] = rejections
+	}
+	switch reason {
+	case voteExtensionRejectedSignature:
+		rejections.Signature++
+	case voteExtensionRejectedApplication:
+		rejections.Application++
+	}
+
+	if vr.logs == nil {
+		vr.logs = make(map[string]*voteExtensionRejectionLog)
+	}
+	entry, ok := vr.logs[key]
+	if !ok {
+		entry = &voteExtensionRejectionLog{}
+		vr.logs[key] = entry
+	}
+	if !entry.last.IsZero() && now.Sub(entry.last) < voteExtensionRejectionLogInterval {
+		entry.suppressed++
+		return false, 0
+	}
+	suppressed = entry.suppressed
+	entry.last, entry.suppressed = now, 0
+	return true, suppressed
+}
+
+// load returns the rejections of height, ordered by validator address.
+func (vr *voteExtensionRejections) load(height int64) []VoteExtensionRejections {
+	vr.mtx.Lock()
+	defer vr.mtx.Unlock()
+	if vr.height != height || len(vr.validators) == 0 {
+		return nil
+	}
+	rejections := make([]VoteExtensionRejections, 0, len(vr.validators))
+	for _, r := range vr.validators {
+		rejections = append(rejections, *r)
+	}
+	sort.Slice(rejections, func(i, j int) bool {
+		return bytes.Compare(rejections[i].ValidatorAddress, rejections[j].ValidatorAddress) < 0
+	})
+	return rejections
+}
+
+// recordVoteExtensionRejection reports that the extension of vote was
+// rejected for reason with err.
+func (cs *State) recordVoteExtensionRejection(vote *types.Vote, reason string, err error) {
+	address := vote.ValidatorAddress.String()
+	cs.metrics.VoteExtensionRejections.With("validator_address", address, "reason", reason).Add(1)
+
+	if logged, suppressed := cs.voteExtensionRejections.add(
+		vote.Height, vote.ValidatorAddress, reason, time.Now(),
+	); logged {
+		cs.logger.Error("rejected vote extension",
+			"height", vote.Height,
+			"round", vote.Round,
+			"validator", address,
+			"reason", reason,
+			"suppressed", suppressed,
+			"err", err)
+	}
+
+	if err := cs.publishEvent(types.EventVoteExtensionRejectedValue, types.EventDataVoteExtensionRejected{
+		Height:           vote.Height,
+		Round:            vote.Round,
+		ValidatorAddress: vote.ValidatorAddress,
+		Reason:           reason,
+		Error:            err.Error(),
+	}); err != nil {
+		cs.logger.Error("failed publishing vote extension rejection", "err", err)
+	}
+}
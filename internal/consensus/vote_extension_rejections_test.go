@@ -0,0 +1,117 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	otrace "go.opentelemetry.io/otel/trace"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	abcimocks "github.com/tendermint/tendermint/abci/types/mocks"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestStateVoteExtensionRejections(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the application rejects the extensions of a single validator
+	var rejected types.Address
+	m := &abcimocks.Application{}
+	m.On("VerifyVoteExtension", mock.Anything, mock.MatchedBy(func(req *abci.RequestVerifyVoteExtension) bool {
+		return bytes.Equal(req.ValidatorAddress, rejected)
+	})).Return(&abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_REJECT}, nil)
+	m.On("VerifyVoteExtension", mock.Anything, mock.Anything).Return(&abci.ResponseVerifyVoteExtension{
+		Status: abci.ResponseVerifyVoteExtension_ACCEPT,
+	}, nil)
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config, application: m})
+	var logs bytes.Buffer
+	cs.logger = log.NewTMJSONLoggerNoTS(&logs)
+	counter := newLabeledCounter()
+	cs.metrics.VoteExtensionRejections = counter
+	rejectedCh := subscribe(ctx, t, cs.eventBus, types.EventQueryVoteExtensionRejected)
+
+	height := cs.roundState.Height()
+	blockID := types.BlockID{Hash: make([]byte, 32), PartSetHeader: types.PartSetHeader{Total: 1, Hash: make([]byte, 32)}}
+	precommit := func(vs *validatorStub) *types.Vote {
+		vs.Height, vs.Round = height, 0
+		return signVote(ctx, t, vs, tmproto.PrecommitType, config.ChainID(), blockID)
+	}
+	span := otrace.SpanFromContext(ctx)
+	ensureRejected := func(vote *types.Vote, reason string) {
+		t.Helper()
+		select {
+		case msg := <-rejectedCh:
+			event := msg.Data().(types.EventDataVoteExtensionRejected)
+			require.Equal(t, height, event.Height)
+			require.Equal(t, vote.ValidatorAddress, event.ValidatorAddress)
+			require.Equal(t, reason, event.Reason)
+			require.NotEmpty(t, event.Error)
+		case <-time.After(time.Second):
+			t.Fatal("expected a vote extension rejection event")
+		}
+	}
+
+	// an accepted extension is not reported
+	added, err := cs.addVote(ctx, precommit(vss[1]), "peer", types.VoteUnverified, span)
+	require.NoError(t, err)
+	require.True(t, added)
+	require.Empty(t, counter.values)
+
+	// the rejection of the application is attributed to the validator
+	byApp := precommit(vss[2])
+	rejected = byApp.ValidatorAddress
+	_, err = cs.addVote(ctx, byApp, "peer", types.VoteUnverified, span)
+	require.Error(t, err)
+	ensureRejected(byApp, voteExtensionRejectedApplication)
+	appLabels := "validator_address," + byApp.ValidatorAddress.String() + ",reason,application"
+	require.Equal(t, 1.0, counter.values[appLabels])
+
+	// and distinguished from an invalid extension signature
+	bySig := precommit(vss[3])
+	bySig.ExtensionSignature = []byte("invalid")
+	_, err = cs.addVote(ctx, bySig, "peer", types.VoteUnverified, span)
+	require.Error(t, err)
+	ensureRejected(bySig, voteExtensionRejectedSignature)
+	require.Equal(t, 1.0, counter.values["validator_address,"+bySig.ValidatorAddress.String()+",reason,signature"])
+
+	// the logs of a validator are rate limited
+	_, err = cs.addVote(ctx, byApp, "peer", types.VoteUnverified, span)
+	require.Error(t, err)
+	ensureRejected(byApp, voteExtensionRejectedApplication)
+	require.Equal(t, 2.0, counter.values[appLabels])
+	require.Equal(t, 2, strings.Count(logs.String(), "rejected vote extension"))
+
+	interval := voteExtensionRejectionLogInterval
+	voteExtensionRejectionLogInterval = 0
+	t.Cleanup(func() { voteExtensionRejectionLogInterval = interval })
+	_, err = cs.addVote(ctx, byApp, "peer", types.VoteUnverified, span)
+	require.Error(t, err)
+	ensureRejected(byApp, voteExtensionRejectedApplication)
+	require.Equal(t, 3, strings.Count(logs.String(), "rejected vote extension"))
+	require.Contains(t, logs.String(), `"suppressed":1`)
+
+	// the tally of the height is exposed by the status
+	status := cs.Status().VoteExtensionRejections
+	require.Len(t, status, 2)
+	for _, r := range status {
+		switch {
+		case bytes.Equal(r.ValidatorAddress, byApp.ValidatorAddress):
+			require.Equal(t, VoteExtensionRejections{ValidatorAddress: r.ValidatorAddress, Application: 3}, r)
+		case bytes.Equal(r.ValidatorAddress, bySig.ValidatorAddress):
+			require.Equal(t, VoteExtensionRejections{ValidatorAddress: r.ValidatorAddress, Signature: 1}, r)
+		default:
+			t.Fatalf("unexpected rejections of %v", r.ValidatorAddress)
+		}
+	}
+	require.Nil(t, cs.voteExtensionRejections.load(height+1))
+}
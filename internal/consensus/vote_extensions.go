@@ -0,0 +1,150 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// VoteExtensionRetentionPolicy selects which vote extensions are retained in
+// the extended commit that is stored with a committed block. It returns, for
+// every signature of ec, whether the signature's vote extension is kept.
+//
+// Only the vote extension and its signature are removed from the stored
+// commit; the precommit signatures themselves are always kept. The extensions
+// retained must carry more than 2/3 of the voting power of vals, otherwise the
+// policy is ignored and all extensions are stored. Precommits whose extension
+// was filtered out are delivered to the application in PrepareProposal
+// without extension data, and are not gossiped with their extensions to
+// lagging peers.
+type VoteExtensionRetentionPolicy func(ec *types.ExtendedCommit, vals *types.ValidatorSet) []bool
+
+// RetainAllVoteExtensions keeps the vote extensions of every precommit. This
+// is the default policy.
+func RetainAllVoteExtensions(ec *types.ExtendedCommit, _ *types.ValidatorSet) []bool {
+	retain := make([]bool, len(ec.ExtendedSignatures))
+	for i := range retain {
+		retain[i] = true
+	}
+	return retain
+}
+
+// RetainQuorumVoteExtensions keeps the vote extensions of the first
+// precommits, in validator set order, that together carry more than 2/3 of
+// the voting power.
+func RetainQuorumVoteExtensions(ec *types.ExtendedCommit, vals *types.ValidatorSet) []bool {
+	var (
+		retain = make([]bool, len(ec.ExtendedSignatures))
+		needed = vals.TotalVotingPower() * 2 / 3
+		power  int64
+	)
+	for i, ecs := range ec.ExtendedSignatures {
+		if power > needed {
+			break
+		}
+		if ecs.BlockIDFlag != types.BlockIDFlagCommit {
+			continue
+		}
+		retain[i] = true
+		power += vals.Validators[i].VotingPower
+	}
+	return retain
+}
+
+// StateVoteExtensionRetention sets the policy used to filter the vote
+// extensions stored with every committed block.
+func StateVoteExtensionRetention(policy VoteExtensionRetentionPolicy) StateOption {
+	return func(cs *State) { cs.voteExtensionRetention = policy }
+}
+
+// retainVoteExtensions applies the vote extension retention policy to ec,
+// the extended commit for a block signed by vals. ec is not modified.
+func (cs *State) retainVoteExtensions(ec *types.ExtendedCommit, vals *types.ValidatorSet) *types.ExtendedCommit {
+	if cs.voteExtensionRetention == nil {
+		return ec
+	}
+
+	retain := cs.voteExtensionRetention(ec, vals)
+	if len(retain) != len(ec.ExtendedSignatures) {
+		cs.logger.Error("vote extension retention policy returned wrong number of signatures; retaining all",
+			"expected", len(ec.ExtendedSignatures), "got", len(retain))
+		return ec
+	}
+
+	filtered := ec.Clone()
+	for i, keep := range retain {
+		if !keep {
+			filtered.ExtendedSignatures[i].Extension = nil
+			filtered.ExtendedSignatures[i].ExtensionSignature = nil
+		}
+	}
+	if err := filtered.EnsureQuorumExtensions(vals); err != nil {
+		cs.logger.Error("vote extension retention policy dropped too many extensions; retaining all",
+			"height", ec.Height, "err", err)
+		return ec
+	}
+	return filtered
+}
+
+// VoteExtensionProvider produces the vote extension of a non-nil precommit
+// signed by this node, in place of the application's ExtendVote.
+type VoteExtensionProvider func(ctx context.Context, vote *types.Vote) ([]byte, error)
+
+// WithVoteExtensionProvider sets a provider used to extend the precommits of
+// this node instead of calling ExtendVote on the application, e.g. to keep
+// extensions cheap on validators signing with constrained HSMs. The vote
+// extensions of other validators are still verified by the application, which
+// must accept the extensions produced by the provider. This is checked once
+// when the State is started.
+func WithVoteExtensionProvider(provider VoteExtensionProvider) StateOption {
+	return func(cs *State) { cs.voteExtensionProvider = provider }
+}
+
+// extendVote returns the vote extension for our own non-nil precommit.
+func (cs *State) extendVote(ctx context.Context, vote *types.Vote) ([]byte, error) {
+	if cs.voteExtensionProvider != nil {
+		return cs.voteExtensionProvider(ctx, vote)
+	}
+	return cs.blockExec.ExtendVote(ctx, vote)
+}
+
+// checkVoteExtensionProvider verifies that the application accepts the
+// extensions produced by the vote extension provider, if any, when vote
+// extensions are enabled at the next height and this node has a key. It
+// extends a precommit for the last committed block at the current height and
+// runs the result through the application's VerifyVoteExtension. As this
+// probe is not a precommit the application may accept, a failure is only a
+// hint of a misconfiguration.
+func (cs *State) checkVoteExtensionProvider(ctx context.Context) error {
+	if cs.voteExtensionProvider == nil {
+		return nil
+	}
+	if !cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(cs.state.LastBlockHeight + 1) {
+		return nil
+	}
+	pubKey := cs.getPrivValidatorPubKey()
+	if pubKey == nil {
+		return nil
+	}
+
+	vote := &types.Vote{
+		Type:             tmproto.PrecommitType,
+		Height:           cs.roundState.Height(),
+		Round:            cs.roundState.Round(),
+		BlockID:          cs.state.LastBlockID,
+		ValidatorAddress: pubKey.Address(),
+	}
+	vote.ValidatorIndex, _ = cs.state.Validators.GetByAddress(vote.ValidatorAddress)
+
+	ext, err := cs.voteExtensionProvider(ctx, vote)
+	if err != nil {
+		return fmt.Errorf("vote extension provider self-check: failed to extend vote: %w", err)
+	}
+	vote.Extension = ext
+	if err := cs.blockExec.VerifyVoteExtension(ctx, vote); err != nil {
+		return fmt.Errorf("vote extension provider self-check: application rejected extension: %w", err)
+	}
+	return nil
+}
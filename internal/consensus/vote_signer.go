@@ -0,0 +1,205 @@
+package consensus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// voteSignRequest is a vote this node decided on, with what is needed to
+// sign it without cs.mtx.
+type voteSignRequest struct {
+	vote          *types.Vote
+	privValidator types.PrivValidator
+	chainID       string
+	// timeout of the signing by the private validator
+	timeout time.Duration
+	// extensionsEnabled is set if vote extensions are enabled at the height
+	// of the vote
+	extensionsEnabled bool
+	// replay is set if the vote was decided on during the replay of the WAL
+	replay bool
+}
+
+// voteSigner signs the votes this node decided on outside of cs.mtx, so that
+// the round trips to the private validator do not hold up the messages of the
+// peers. The votes are signed one at a time, in the order they were decided
+// on, as the private validator refuses votes regressing in height, round or
+// step. The signed votes are sent through the internal queue, as the votes
+// signed while cs.mtx was held were.
+type voteSigner struct {
+	mtx      sync.Mutex
+	requests []voteSignRequest
+	// idle is set while the signer runs, and closed once it signed all the
+	// requests
+	idle chan struct{}
+}
+
+// newVoteSignRequest builds the request to sign the vote of msgType for the
+// block of hash and header at the current height and round. A non-nil
+// precommit is extended by the application here, as its extension is for the
+// state of the height, which finalizeCommit does not wait for the signer to
+// leave. It must be called with cs.mtx held.
+func (cs *State) newVoteSignRequest(
+	ctx context.Context,
+	msgType tmproto.SignedMsgType,
+	hash []byte,
+	header types.PartSetHeader,
+) (voteSignRequest, error) {
+	// Flush the WAL. Otherwise, we may not recompute the same vote to sign,
+	// and the privValidator will refuse to sign anything.
+	if err := cs.walFlushAndSync(FaultPointSignVote); err != nil {
+		return voteSignRequest{}, err
+	}
+
+	privValidator, pubKey := cs.getPrivValidator()
+	if privValidator == nil || pubKey == nil {
+		return voteSignRequest{}, errPubKeyIsNotSet
+	}
+
+	addr := pubKey.Address()
+	valIdx, _ := cs.roundState.Validators().GetByAddress(addr)
+
+	vote := &types.Vote{
+		ValidatorAddress: addr,
+		ValidatorIndex:   valIdx,
+		Height:           cs.roundState.Height(),
+		Round:            cs.roundState.Round(),
+		Timestamp:        tmtime.Now(),
+		Type:             msgType,
+		BlockID:          types.BlockID{Hash: hash, PartSetHeader: header},
+	}
+	req := voteSignRequest{
+		vote:          vote,
+		privValidator: privValidator,
+		chainID:       cs.state.ChainID,
+		timeout:       time.Second,
+		// read with cs.mtx held, as the vote may be signed once the State
+		// moved on to the next height
+		extensionsEnabled: cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(vote.Height),
+		replay:            cs.replayMode,
+	}
+
+	// If the signedMessageType is for precommit,
+	// use our local precommit Timeout as the max wait time for getting a singed commit. The same goes for prevote.
+	if msgType == tmproto.PrecommitType && !req.vote.BlockID.IsNil() {
+		req.timeout = cs.voteTimeout(cs.roundState.Round())
+		// if the signedMessage type is for a non-nil precommit, add
+		// VoteExtension
+		if req.extensionsEnabled {
+			ext, err := cs.extendVote(ctx, vote)
+			if err != nil {
+				return voteSignRequest{}, err
+			}
+			vote.Extension = ext
+		}
+	}
+	return req, nil
+}
+
+// signVoteRequest signs the vote of req, timestamped as it is sent to the
+// private validator. It does not need cs.mtx.
+func (cs *State) signVoteRequest(ctx context.Context, req voteSignRequest) (*types.Vote, error) {
+	vote := req.vote
+	// the request may have been queued before the halt
+	if cs.SigningHalted() {
+		return vote, errSigningHalted
+	}
+	if err := cs.advanceSignHRS(vote.Height, vote.Round, vote.Type, vote.BlockID.Hash, req.replay); err != nil {
+		return vote, err
+	}
+	vote.Timestamp = tmtime.Now()
+
+	ctxto, cancel := context.WithTimeout(ctx, req.timeout)
+	defer cancel()
+
+	// The vote is signed again, once, if the signer changed its timestamp
+	// beyond config.SignerSkewLimit.
+	for attempt := 1; ; attempt++ {
+		timestamp := vote.Timestamp
+		v := vote.ToProto()
+		signBytes := types.VoteSignBytes(req.chainID, v)
+		err := req.privValidator.SignVote(ctxto, req.chainID, v)
+		cs.recordVoteSignRequest(req.chainID, signBytes, v, err)
+		vote.Signature = v.Signature
+		vote.ExtensionSignature = v.ExtensionSignature
+		vote.Timestamp = v.Timestamp
+		if err != nil {
+			return vote, err
+		}
+		cs.markSignHRSSigned(vote.Height, vote.Round, vote.Type)
+
+		err = cs.checkSignerSkew(vote, timestamp)
+		if err == nil || attempt > 1 {
+			return vote, err
+		}
+		cs.logger.Error("signing the vote again", "height", vote.Height, "round", vote.Round, "type", vote.Type, "err", err)
+		vote.Timestamp = tmtime.Now()
+	}
+}
+
+// wait waits for the queued votes to be signed. It must not be called from
+// the signer, nor with cs.mtx held, as the signer may be held up by a slow
+// private validator.
+func (vs *voteSigner) wait() {
+	vs.mtx.Lock()
+	idle := vs.idle
+	vs.mtx.Unlock()
+	if idle != nil {
+		<-idle
+	}
+}
+
+// submitVoteSignRequest queues req to be signed, and starts the signer if it
+// is not running.
+func (cs *State) submitVoteSignRequest(ctx context.Context, req voteSignRequest) {
+	vs := &cs.voteSigner
+	vs.mtx.Lock()
+	defer vs.mtx.Unlock()
+	vs.requests = append(vs.requests, req)
+	if vs.idle == nil {
+		vs.idle = make(chan struct{})
+		cs.spawn(func() { cs.signVotes(ctx) })
+	}
+}
+
+// signVotes signs the queued requests, in order, until none is left.
+func (cs *State) signVotes(ctx context.Context) {
+	vs := &cs.voteSigner
+	for {
+		vs.mtx.Lock()
+		if len(vs.requests) == 0 || ctx.Err() != nil {
+			close(vs.idle)
+			vs.requests, vs.idle = nil, nil
+			vs.mtx.Unlock()
+			return
+		}
+		req := vs.requests[0]
+		vs.requests[0] = voteSignRequest{}
+		vs.requests = vs.requests[1:]
+		vs.mtx.Unlock()
+
+		cs.signAndSendVote(ctx, req)
+	}
+}
+
+// signAndSendVote signs the vote of req and sends it through the internal
+// queue.
+func (cs *State) signAndSendVote(ctx context.Context, req voteSignRequest) {
+	vote, err := cs.signVoteRequest(ctx, req)
+	if err != nil {
+		cs.logger.Error("failed signing vote", "height", req.vote.Height, "round", req.vote.Round, "vote", vote, "err", err)
+		return
+	}
+	if !req.extensionsEnabled {
+		// The signer will sign the extension, make sure to remove the data on the way out
+		vote.StripExtension()
+	}
+	cs.recordSignedPrecommit(vote)
+	cs.sendInternalMessage(ctx, msgInfo{Msg: &VoteMessage{vote}, PeerID: "", ReceiveTime: tmtime.Now()})
+	cs.logger.Info("signed and pushed vote", "height", vote.Height, "round", vote.Round, "vote", vote)
+}
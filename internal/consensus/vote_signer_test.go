@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// slowSigner is a private validator taking delay to sign each vote, as a
+// remote signer on a slow link.
+type slowSigner struct {
+	types.PrivValidator
+	delay time.Duration
+	// signing receives the votes as their signing starts
+	signing chan *tmproto.Vote
+}
+
+func (s *slowSigner) SignVote(ctx context.Context, chainID string, vote *tmproto.Vote) error {
+	select {
+	case s.signing <- vote:
+	default:
+	}
+	time.Sleep(s.delay)
+	return s.PrivValidator.SignVote(ctx, chainID, vote)
+}
+
+func TestStateVoteSignedWithoutLock(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	signer := &slowSigner{
+		PrivValidator: cs.privValidator,
+		delay:         500 * time.Millisecond,
+		signing:       make(chan *tmproto.Vote, 1),
+	}
+	cs.privValidator = signer
+	pubKey := cs.getPrivValidatorPubKey()
+	peerPubKey, err := vss[1].GetPubKey(ctx)
+	require.NoError(t, err)
+
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	ownCh := subscribeToVoter(ctx, t, cs, pubKey.Address())
+	peerCh := subscribeToVoterBuffered(ctx, t, cs, peerPubKey.Address())
+	// this node proposes, and prevotes for its proposal once it is complete
+	startTestRound(ctx, cs, height, round)
+
+	var prevote *tmproto.Vote
+	select {
+	case prevote = <-signer.signing:
+	case <-time.After(ensureTimeout):
+		t.Fatal("expected the prevote of the node to be signed")
+	}
+	require.Equal(t, tmproto.PrevoteType, prevote.Type)
+	signed := time.Now()
+
+	// the prevote of a peer is processed while the prevote of the node is
+	// being signed
+	blockID, err := types.BlockIDFromProto(&prevote.BlockID)
+	require.NoError(t, err)
+	signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), *blockID, vss[1])
+	ensurePrevote(t, peerCh, height, round)
+	require.Less(t, time.Since(signed), signer.delay)
+	require.Empty(t, ownCh, "unexpected vote of the node before it is signed")
+
+	// then the prevote of the node is added
+	msg := ensureMessageBeforeTimeout(t, ownCh, 2*signer.delay)
+	require.Equal(t, tmproto.PrevoteType, msg.Data().(types.EventDataVote).Vote.Type)
+	require.GreaterOrEqual(t, time.Since(signed), signer.delay)
+}
+
+func TestStateCommitsWhileVoteSigning(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs, vss := makeState(ctx, t, makeStateArgs{config: config})
+	signer := &slowSigner{
+		PrivValidator: cs.privValidator,
+		delay:         500 * time.Millisecond,
+		signing:       make(chan *tmproto.Vote, 1),
+	}
+	cs.privValidator = signer
+
+	height, round := cs.roundState.Height(), cs.roundState.Round()
+	newRoundCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewRound)
+	startTestRound(ctx, cs, height, round)
+	ensureNewRound(t, newRoundCh, height, round)
+
+	var prevote *tmproto.Vote
+	select {
+	case prevote = <-signer.signing:
+	case <-time.After(ensureTimeout):
+		t.Fatal("expected the prevote of the node to be signed")
+	}
+	signed := time.Now()
+	blockID, err := types.BlockIDFromProto(&prevote.BlockID)
+	require.NoError(t, err)
+
+	// the peers commit the block while the votes of the node are being
+	// signed, and the node applies it without waiting for the signer
+	signAddVotes(ctx, t, cs, tmproto.PrevoteType, config.ChainID(), *blockID, vss[1:]...)
+	signAddVotes(ctx, t, cs, tmproto.PrecommitType, config.ChainID(), *blockID, vss[1:]...)
+	ensureNewRound(t, newRoundCh, height+1, 0)
+	require.Less(t, time.Since(signed), signer.delay)
+	require.Equal(t, blockID.Hash, cs.blockStore.LoadBlock(height).Hash())
+}
@@ -3,11 +3,14 @@ package consensus
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
+	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -38,6 +41,67 @@ type TimedWALMessage struct {
 	Msg  WALMessage `json:"msg"`
 }
 
+type timedWALMessageJSON struct {
+	Time time.Time       `json:"time"`
+	Msg  json.RawMessage `json:"msg"`
+}
+
+// MarshalJSON encodes Msg with its registered type tag, so that the message
+// can be decoded back into its concrete type.
+func (m TimedWALMessage) MarshalJSON() ([]byte, error) {
+	var msg json.RawMessage
+	if m.Msg != nil {
+		tagged, ok := m.Msg.(jsontypes.Tagged)
+		if !ok {
+			return nil, fmt.Errorf("wal message %T has no type tag", m.Msg)
+		}
+		var err error
+		if msg, err = jsontypes.Marshal(tagged); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(timedWALMessageJSON{Time: m.Time, Msg: msg})
+}
+
+func (m *TimedWALMessage) UnmarshalJSON(data []byte) error {
+	var msg timedWALMessageJSON
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if err := jsontypes.Unmarshal(msg.Msg, &m.Msg); err != nil {
+		return err
+	}
+	m.Time = msg.Time
+	return nil
+}
+
+// WALMsgInfo is a message received from a peer, or sent internally, as
+// recorded in the WAL. Its ReceiveTime is stored with it; the messages
+// written before it was are given the time of their TimedWALMessage.
+type WALMsgInfo = msgInfo
+
+// WALTimeoutInfo is a timeout of the consensus state machine as recorded in
+// the WAL.
+type WALTimeoutInfo = timeoutInfo
+
+// MsgInfo returns the message if it is a WALMsgInfo.
+func (m TimedWALMessage) MsgInfo() (WALMsgInfo, bool) {
+	mi, ok := m.Msg.(msgInfo)
+	return mi, ok
+}
+
+// TimeoutInfo returns the message if it is a WALTimeoutInfo.
+func (m TimedWALMessage) TimeoutInfo() (WALTimeoutInfo, bool) {
+	ti, ok := m.Msg.(timeoutInfo)
+	return ti, ok
+}
+
+// EndHeight returns the message if it is an EndHeightMessage.
+func (m TimedWALMessage) EndHeight() (EndHeightMessage, bool) {
+	eh, ok := m.Msg.(EndHeightMessage)
+	return eh, ok
+}
+
 // EndHeightMessage marks the end of the given height inside WAL.
 // @internal used by scripts/wal2json util.
 type EndHeightMessage struct {
@@ -71,6 +135,23 @@ type WAL interface {
 	Wait()
 }
 
+// WALProvider opens and starts the WAL of a State, in place of the WAL file at
+// path, the WAL file of the config.
+type WALProvider func(ctx context.Context, logger log.Logger, path string) (WAL, error)
+
+// errWALReopened is returned when the WAL set with WithWAL is opened again.
+var errWALReopened = errors.New("the WAL set with WithWAL cannot be reopened")
+
+// WALFileRepairer is implemented by the WALs of a WALProvider stored in a
+// file the State may repair when it is corrupted. The State then stops the
+// WAL, repairs its file and opens it again with the provider. The WALs of a
+// provider not implementing it are not repaired.
+type WALFileRepairer interface {
+	// RepairFile returns the path of the file of the WAL; empty if it may
+	// not be repaired.
+	RepairFile() string
+}
+
 // Write ahead logger writes msgs to disk before they are processed.
 // Can be used for crash-recovery and deterministic replay.
 // TODO: currently the wal is overwritten during replay catchup, give it a mode
@@ -83,12 +164,20 @@ type BaseWAL struct {
 	group *auto.Group
 
 	enc *WALEncoder
+	// index of the #ENDHEIGHT markers; nil if it could not be loaded, in which
+	// case the WAL is scanned
+	index *WALIndex
 
 	flushTicker   *time.Ticker
 	flushInterval time.Duration
+	// cancelFlushTicks stops processFlushTicks, which is tracked by
+	// flushTicks so that OnStop returns once it stopped writing
+	cancelFlushTicks context.CancelFunc
+	flushTicks       sync.WaitGroup
 }
 
 var _ WAL = &BaseWAL{}
+var _ WALFileRepairer = &BaseWAL{}
 
 // NewWAL returns a new write-ahead logger based on `baseWAL`, which implements
 // WAL. It's flushed and synced to disk every 2s and once when stopped.
@@ -121,7 +210,20 @@ func (wal *BaseWAL) Group() *auto.Group {
 	return wal.group
 }
 
+// Index returns the index of the #ENDHEIGHT markers of the WAL, or nil if the
+// WAL is not started or its index could not be loaded.
+func (wal *BaseWAL) Index() *WALIndex {
+	return wal.index
+}
+
 func (wal *BaseWAL) OnStart(ctx context.Context) error {
+	index, err := loadWALIndex(wal.logger, wal.group, walIndexPath(wal.group.Head.Path))
+	if err != nil {
+		wal.logger.Error("failed to load the WAL index; searches will scan the WAL", "err", err)
+	} else {
+		wal.index = index
+	}
+
 	size, err := wal.group.Head.Size()
 	if err != nil {
 		return err
@@ -135,7 +237,12 @@ func (wal *BaseWAL) OnStart(ctx context.Context) error {
 		return err
 	}
 	wal.flushTicker = time.NewTicker(wal.flushInterval)
-	go wal.processFlushTicks(ctx)
+	ctx, wal.cancelFlushTicks = context.WithCancel(ctx)
+	wal.flushTicks.Add(1)
+	go func() {
+		defer wal.flushTicks.Done()
+		wal.processFlushTicks(ctx)
+	}()
 	return nil
 }
 
@@ -145,13 +252,18 @@ func (wal *BaseWAL) processFlushTicks(ctx context.Context) {
 		case <-wal.flushTicker.C:
 			if err := wal.FlushAndSync(); err != nil {
 				wal.logger.Error("Periodic WAL flush failed", "err", err)
+				continue
 			}
+			wal.persistIndex()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// RepairFile implements WALFileRepairer.
+func (wal *BaseWAL) RepairFile() string { return wal.group.Head.Path }
+
 // FlushAndSync flushes and fsync's the underlying group's data to disk.
 // See auto#FlushAndSync
 func (wal *BaseWAL) FlushAndSync() error {
@@ -163,8 +275,12 @@ func (wal *BaseWAL) FlushAndSync() error {
 // before cleaning up files.
 func (wal *BaseWAL) OnStop() {
 	wal.flushTicker.Stop()
+	wal.cancelFlushTicks()
+	wal.flushTicks.Wait()
 	if err := wal.FlushAndSync(); err != nil {
 		wal.logger.Error("error on flush data to disk", "error", err)
+	} else {
+		wal.persistIndex()
 	}
 	wal.group.Stop()
 	wal.group.Close()
@@ -189,15 +305,45 @@ func (wal *BaseWAL) Write(msg WALMessage) error {
 		return nil
 	}
 
+	endHeight, isEndHeight := msg.(EndHeightMessage)
+	var (
+		pos   WALPosition
+		posOK bool
+	)
+	if isEndHeight && wal.index != nil {
+		segment, offset, err := wal.group.Position()
+		if err != nil {
+			wal.logger.Error("failed to get the WAL position of #ENDHEIGHT", "height", endHeight.Height, "err", err)
+			wal.index.markIncomplete()
+		} else {
+			pos, posOK = WALPosition{Segment: segment, Offset: offset}, true
+		}
+	}
+
 	if err := wal.enc.Encode(&TimedWALMessage{tmtime.Now(), msg}); err != nil {
 		wal.logger.Error("error writing msg to consensus wal. WARNING: recover may not be possible for the current height",
 			"err", err, "msg", msg)
 		return err
 	}
 
+	if posOK {
+		wal.index.add(endHeight.Height, pos)
+	}
 	return nil
 }
 
+// persistIndex writes the index of the WAL to disk. It must be called once the
+// WAL was flushed, so that the index does not reference unwritten markers.
+func (wal *BaseWAL) persistIndex() {
+	if wal.index == nil {
+		return
+	}
+	wal.index.prune(wal.group)
+	if err := wal.index.persist(); err != nil {
+		wal.logger.Error("failed to persist the WAL index", "err", err)
+	}
+}
+
 // WriteSync is called when we receive a msg from ourselves
 // so that we write to disk before sending signed messages.
 // NOTE: calls fsync()
@@ -230,8 +376,35 @@ type WALSearchOptions struct {
 // and returns an auto.GroupReader, whenever it was found or not and an error.
 // Group reader will be nil if found equals false.
 //
+// The position of the EndHeightMessage is taken from the index of the WAL. The
+// WAL is only scanned if the index has a stale position for the height, or
+// does not have it while it may be incomplete or data corruption errors must
+// be reported.
+//
 // CONTRACT: caller must close group reader.
 func (wal *BaseWAL) SearchForEndHeight(
+	height int64,
+	options *WALSearchOptions) (rd io.ReadCloser, found bool, err error) {
+	if wal.index != nil {
+		if pos, ok := wal.index.Lookup(height); ok {
+			if gr, ok := readEndHeightAt(wal.group, height, pos); ok {
+				wal.logger.Info("Found in the WAL index", "height", height, "index", pos.Segment, "offset", pos.Offset)
+				return gr, true, nil
+			}
+			wal.logger.Error("stale WAL index position; searching the WAL", "height", height,
+				"index", pos.Segment, "offset", pos.Offset)
+		} else if options.IgnoreDataCorruptionErrors && wal.index.Complete() {
+			// the scan would skip the corrupted messages the index skipped
+			return nil, false, nil
+		}
+	}
+
+	return wal.searchForEndHeight(height, options)
+}
+
+// searchForEndHeight scans the WAL for the EndHeightMessage with the given
+// height, from the last file of the group.
+func (wal *BaseWAL) searchForEndHeight(
 	height int64,
 	options *WALSearchOptions) (rd io.ReadCloser, found bool, err error) {
 	var (
@@ -275,7 +448,7 @@ func (wal *BaseWAL) SearchForEndHeight(
 				lastHeightFound = m.Height
 				if m.Height == height { // found
 					wal.logger.Info("Found", "height", height, "index", index)
-					return gr, true, nil
+					return wal.lastEndHeightIn(gr, index, height), true, nil
 				}
 			}
 		}
@@ -285,6 +458,35 @@ func (wal *BaseWAL) SearchForEndHeight(
 	return nil, false, nil
 }
 
+// lastEndHeightIn returns a reader positioned after the last #ENDHEIGHT of
+// height in the file index of the WAL, gr being positioned after the first.
+// Duplicate markers are left by the processes that re-wrote the marker of a
+// height after failing to apply its block; the messages before the last one
+// belong to the height.
+func (wal *BaseWAL) lastEndHeightIn(gr *auto.GroupReader, index int, height int64) io.ReadCloser {
+	var (
+		positions int
+		last      WALPosition
+	)
+	err := scanWALFileEndHeights(wal.group.FilePath(index), index, 0, func(h int64, pos WALPosition) {
+		if h == height {
+			positions++
+			last = pos
+		}
+	})
+	if err != nil || positions < 2 {
+		return gr
+	}
+	rd, ok := readEndHeightAt(wal.group, height, last)
+	if !ok {
+		return gr
+	}
+	wal.logger.Info("Found duplicate #ENDHEIGHT markers; replaying from the last one",
+		"height", height, "markers", positions, "index", index)
+	gr.Close()
+	return rd
+}
+
 // A WALEncoder writes custom-encoded WAL messages to an output stream.
 //
 // Format: 4 bytes CRC sum + 4 bytes length + arbitrary-length value
@@ -413,6 +615,11 @@ func (dec *WALDecoder) Decode() (*TimedWALMessage, error) {
 	if err != nil {
 		return nil, DataCorruptionError{fmt.Errorf("failed to convert from proto: %w", err)}
 	}
+	if mi, ok := walMsg.(msgInfo); ok && mi.ReceiveTime.IsZero() {
+		// written before the receive time was stored
+		mi.ReceiveTime = res.Time
+		walMsg = mi
+	}
 	tMsgWal := &TimedWALMessage{
 		Time: res.Time,
 		Msg:  walMsg,
@@ -421,6 +628,36 @@ func (dec *WALDecoder) Decode() (*TimedWALMessage, error) {
 	return tMsgWal, err
 }
 
+// IterateWAL decodes the WAL file at path and calls fn for each message, in
+// order, until fn returns stop or an error. A WAL group is made of several
+// files; path is a single one of them, such as the head.
+//
+// A DataCorruptionError is returned if the file is truncated in the middle of
+// a message or corrupted. fn has been called for all messages before that
+// point.
+func IterateWAL(path string, fn func(TimedWALMessage) (stop bool, err error)) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	dec := NewWALDecoder(fp)
+	for {
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		stop, err := fn(*msg)
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
 type nilWAL struct{}
 
 var _ WAL = nilWAL{}
@@ -0,0 +1,500 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	dbm "github.com/tendermint/tm-db"
+	"go.opentelemetry.io/otel/sdk/trace"
+
+	abciclient "github.com/tendermint/tendermint/abci/client"
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/internal/eventbus"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/internal/store"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// WALFixtureScript is the script of the heights of a WAL fixture. The heights
+// are run by a State which is not a validator: the proposals and the votes
+// are those of the validators of the script, sent as peer messages, and the
+// timeouts are fired when the script says so. The same script, seed
+// included, generates the same bytes.
+type WALFixtureScript struct {
+	// Seed derives the keys of the validators.
+	Seed int64
+	// Validators is the number of validators, of equal power.
+	Validators int
+	// VoteExtensionsEnableHeight is the height vote extensions are enabled
+	// at, 0 for never.
+	VoteExtensionsEnableHeight int64
+	// Heights are the scripts of the heights, from the initial height. Each
+	// height must be committed by its script.
+	Heights []WALFixtureHeight
+	// CorruptTail cuts the last record of the WAL short, as a crash in the
+	// middle of a write would.
+	CorruptTail bool
+}
+
+// WALFixtureHeight is the script of a height, one list of actions by round.
+// The actions of a round are run once the State entered the round.
+type WALFixtureHeight struct {
+	// Txs are the transactions of the blocks proposed at the height.
+	Txs    types.Txs
+	Rounds [][]WALFixtureAction
+}
+
+type walFixtureActionKind int
+
+const (
+	walFixturePropose walFixtureActionKind = iota
+	walFixturePrevote
+	walFixturePrecommit
+	walFixtureTimeout
+)
+
+// WALFixtureAction is an action of a round of a WAL fixture script.
+type WALFixtureAction struct {
+	kind walFixtureActionKind
+	// the validators voting, by index in the validator set, all if empty
+	validators []int
+	nilVote    bool
+	// the number of block parts the proposer withholds
+	withheld int
+	step     cstypes.RoundStepType
+}
+
+// WALFixturePropose is the proposer of the round sending its proposal and
+// all its block parts. The valid block of the height, if any, is proposed
+// again.
+func WALFixturePropose() WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePropose}
+}
+
+// WALFixtureProposeWithholding is the proposer of the round sending its
+// proposal without its last withheld block parts.
+func WALFixtureProposeWithholding(withheld int) WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePropose, withheld: withheld}
+}
+
+// WALFixturePrevote is validators prevoting the block proposed in the round.
+func WALFixturePrevote(validators ...int) WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePrevote, validators: validators}
+}
+
+// WALFixturePrevoteNil is validators prevoting nil.
+func WALFixturePrevoteNil(validators ...int) WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePrevote, validators: validators, nilVote: true}
+}
+
+// WALFixturePrecommit is validators precommitting the block proposed in the
+// round.
+func WALFixturePrecommit(validators ...int) WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePrecommit, validators: validators}
+}
+
+// WALFixturePrecommitNil is validators precommitting nil.
+func WALFixturePrecommitNil(validators ...int) WALFixtureAction {
+	return WALFixtureAction{kind: walFixturePrecommit, validators: validators, nilVote: true}
+}
+
+// WALFixtureTimeout is the timeout of step the State scheduled in the round
+// firing.
+func WALFixtureTimeout(step cstypes.RoundStepType) WALFixtureAction {
+	return WALFixtureAction{kind: walFixtureTimeout, step: step}
+}
+
+// WALFixtureCommitted is the script of a height committing its proposal in
+// round 0, all the validators voting for it.
+func WALFixtureCommitted() WALFixtureHeight {
+	return WALFixtureHeight{Rounds: [][]WALFixtureAction{{
+		WALFixturePropose(),
+		WALFixturePrevote(),
+		WALFixturePrecommit(),
+	}}}
+}
+
+const (
+	walFixtureChainID = "wal-fixture"
+	// walFixtureStep is the time the clock of a fixture advances by between
+	// two messages
+	walFixtureStep = 10 * time.Millisecond
+)
+
+// walFixtureClock is the clock of a fixture, advanced by the fixture only.
+type walFixtureClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (c *walFixtureClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *walFixtureClock) advance(d time.Duration) time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// walFixtureTicker records the timeouts the State schedules, which fire when
+// the fixture fires them.
+type walFixtureTicker struct {
+	mtx       sync.Mutex
+	scheduled []timeoutInfo
+}
+
+func (t *walFixtureTicker) Start(context.Context) error { return nil }
+func (t *walFixtureTicker) Stop()                       {}
+func (t *walFixtureTicker) IsRunning() bool             { return false }
+func (t *walFixtureTicker) Chan() <-chan timeoutInfo    { return nil }
+
+func (t *walFixtureTicker) ScheduleTimeout(ti timeoutInfo) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.scheduled = append(t.scheduled, ti)
+}
+
+// take returns the last timeout of step scheduled at height and round, and
+// forgets the timeouts scheduled so far.
+func (t *walFixtureTicker) take(height int64, round int32, step cstypes.RoundStepType) (timeoutInfo, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	defer func() { t.scheduled = nil }()
+	for i := len(t.scheduled) - 1; i >= 0; i-- {
+		ti := t.scheduled[i]
+		if ti.Height == height && ti.Round == round && ti.Step == step {
+			return ti, true
+		}
+	}
+	return timeoutInfo{}, false
+}
+
+// walFixtureWAL encodes the messages of a fixture, timed by its clock.
+type walFixtureWAL struct {
+	enc   *WALEncoder
+	clock *walFixtureClock
+}
+
+func (w *walFixtureWAL) Write(msg WALMessage) error {
+	return w.enc.Encode(&TimedWALMessage{w.clock.Now(), msg})
+}
+
+func (w *walFixtureWAL) WriteSync(msg WALMessage) error { return w.Write(msg) }
+func (w *walFixtureWAL) FlushAndSync() error            { return nil }
+
+func (w *walFixtureWAL) SearchForEndHeight(int64, *WALSearchOptions) (io.ReadCloser, bool, error) {
+	return nil, false, nil
+}
+
+func (w *walFixtureWAL) Start(context.Context) error { return nil }
+func (w *walFixtureWAL) Stop()                       {}
+func (w *walFixtureWAL) Wait()                       {}
+
+// walFixture runs the script of a WAL fixture.
+type walFixture struct {
+	cs     *State
+	clock  *walFixtureClock
+	ticker *walFixtureTicker
+	// the validators by address
+	privVals map[string]types.PrivValidator
+	// the block proposed in the current round, if any
+	blockID *types.BlockID
+}
+
+// keys returns the keys of the validators of the script, derived from its
+// seed.
+func (script WALFixtureScript) keys() []crypto.PrivKey {
+	keys := make([]crypto.PrivKey, 0, script.Validators)
+	for i := 0; i < script.Validators; i++ {
+		keys = append(keys, ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprintf("%d/%d", script.Seed, i))))
+	}
+	return keys
+}
+
+// GenesisDoc returns the genesis of the chain the WAL fixture of the script
+// is generated on.
+func (script WALFixtureScript) GenesisDoc() (*types.GenesisDoc, error) {
+	genVals := make([]types.GenesisValidator, 0, script.Validators)
+	for _, key := range script.keys() {
+		genVals = append(genVals, types.GenesisValidator{PubKey: key.PubKey(), Power: 10})
+	}
+	params := types.DefaultConsensusParams()
+	params.ABCI.VoteExtensionsEnableHeight = script.VoteExtensionsEnableHeight
+	genDoc := &types.GenesisDoc{
+		ChainID:         walFixtureChainID,
+		GenesisTime:     fixedTime,
+		ConsensusParams: params,
+		Validators:      genVals,
+	}
+	if err := genDoc.ValidateAndComplete(); err != nil {
+		return nil, err
+	}
+	return genDoc, nil
+}
+
+// GenerateWALFixture generates the WAL of script, by running its heights
+// through a State. The State runs a kvstore application, and its clock only
+// advances with the messages of the script.
+func GenerateWALFixture(ctx context.Context, script WALFixtureScript) ([]byte, error) {
+	if script.Validators <= 0 {
+		return nil, errors.New("a WAL fixture needs validators")
+	}
+	privVals := make(map[string]types.PrivValidator, script.Validators)
+	for _, key := range script.keys() {
+		privVals[string(key.PubKey().Address())] = types.NewMockPVWithParams(key, false, false)
+	}
+	genDoc, err := script.GenesisDoc()
+	if err != nil {
+		return nil, err
+	}
+	state, err := sm.MakeGenesisState(genDoc)
+	if err != nil {
+		return nil, err
+	}
+	state.Version.Consensus.App = kvstore.ProtocolVersion
+
+	logger := log.NewNopLogger()
+	db := dbm.NewMemDB()
+	stateStore := sm.NewStore(db)
+	if err := stateStore.Save(state); err != nil {
+		return nil, err
+	}
+	blockStore := store.NewBlockStore(db)
+	eventBus := eventbus.NewDefault(logger)
+	if err := eventBus.Start(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { eventBus.Stop(); eventBus.Wait() }()
+
+	clock := &walFixtureClock{now: fixedTime}
+	mempool := emptyMempool{}
+	evpool := sm.EmptyEvidencePool{}
+	proxyApp := abciclient.NewLocalClient(logger, kvstore.NewApplication())
+	blockExec := sm.NewBlockExecutor(stateStore, logger, proxyApp, mempool, evpool, blockStore, eventBus, sm.NopMetrics())
+	cs, err := NewState(logger, config.TestConsensusConfig(), stateStore, blockExec, blockStore,
+		mempool, evpool, eventBus, []trace.TracerProviderOption{}, WithClock(clock))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	wal := &walFixtureWAL{enc: NewWALEncoder(&buf), clock: clock}
+	// see wal.go#103
+	if err := wal.Write(EndHeightMessage{0}); err != nil {
+		return nil, err
+	}
+	cs.wal = wal
+	ticker := &walFixtureTicker{}
+	cs.SetTimeoutTicker(ticker)
+
+	f := &walFixture{cs: cs, clock: clock, ticker: ticker, privVals: privVals}
+	for i, height := range script.Heights {
+		if err := f.runHeight(ctx, state.InitialHeight+int64(i), height); err != nil {
+			return nil, err
+		}
+	}
+
+	data := buf.Bytes()
+	if script.CorruptTail {
+		if len(data) < 2 {
+			return nil, errors.New("no record to corrupt")
+		}
+		data = data[:len(data)-2]
+	}
+	return data, nil
+}
+
+// runHeight runs the script of height, from the timeout of the commit of
+// the previous height.
+func (f *walFixture) runHeight(ctx context.Context, height int64, script WALFixtureHeight) error {
+	if height == f.cs.state.InitialHeight {
+		// as on the start of the State
+		f.cs.scheduleRound0(f.cs.roundState.CopyInternal())
+	}
+	if err := f.fireTimeout(ctx, height, 0, cstypes.RoundStepNewHeight); err != nil {
+		return err
+	}
+	for round, actions := range script.Rounds {
+		if rs := &f.cs.roundState; rs.Height() != height || rs.Round() != int32(round) {
+			return fmt.Errorf("height %d round %d: the State is at height %d round %d",
+				height, round, rs.Height(), rs.Round())
+		}
+		f.blockID = nil
+		for _, action := range actions {
+			if err := f.run(ctx, height, int32(round), script.Txs, action); err != nil {
+				return fmt.Errorf("height %d round %d: %w", height, round, err)
+			}
+		}
+	}
+	if f.cs.blockStore.Height() != height {
+		return fmt.Errorf("height %d is not committed by its script", height)
+	}
+	return nil
+}
+
+func (f *walFixture) run(ctx context.Context, height int64, round int32, txs types.Txs, action WALFixtureAction) error {
+	switch action.kind {
+	case walFixturePropose:
+		return f.propose(ctx, height, round, txs, action.withheld)
+	case walFixturePrevote, walFixturePrecommit:
+		return f.vote(ctx, height, round, action)
+	case walFixtureTimeout:
+		return f.fireTimeout(ctx, height, round, action.step)
+	default:
+		return fmt.Errorf("unknown action %d", action.kind)
+	}
+}
+
+// propose sends the proposal of the proposer of the round, and its block
+// parts but the last withheld ones.
+func (f *walFixture) propose(ctx context.Context, height int64, round int32, txs types.Txs, withheld int) error {
+	proposer := f.cs.roundState.Validators().GetProposer()
+	privVal := f.privVals[string(proposer.Address)]
+
+	block, parts, polRound := f.cs.roundState.ValidBlock(), f.cs.roundState.ValidBlockParts(), f.cs.roundState.ValidRound()
+	if block == nil {
+		lastCommit := &types.Commit{}
+		if height > f.cs.state.InitialHeight {
+			lastCommit = f.cs.blockStore.LoadSeenCommit()
+		}
+		block = f.cs.state.MakeBlock(height, txs, lastCommit, nil, proposer.Address)
+		block.Header.Time = f.clock.Now()
+		var err error
+		if parts, err = f.cs.makeProposalBlockParts(block); err != nil {
+			return err
+		}
+		polRound = -1
+	}
+	if withheld > int(parts.Total()) {
+		return fmt.Errorf("withholding %d of %d block parts", withheld, parts.Total())
+	}
+
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+	proposal := types.NewProposal(height, round, polRound, blockID, block.Time, block.GetTxKeys(),
+		block.Header, block.LastCommit, block.Evidence, proposer.Address)
+	p := proposal.ToProto()
+	if err := privVal.SignProposal(ctx, f.cs.state.ChainID, p); err != nil {
+		return err
+	}
+	proposal.Signature = p.Signature
+
+	peerID := types.NodeIDFromPubKey(proposer.PubKey)
+	f.send(ctx, peerID, &ProposalMessage{proposal})
+	for i := 0; i < int(parts.Total())-withheld; i++ {
+		f.send(ctx, peerID, &BlockPartMessage{Height: height, Round: round, Part: parts.GetPart(i)})
+	}
+	f.blockID = &blockID
+	return nil
+}
+
+// vote sends the votes of the validators of action.
+func (f *walFixture) vote(ctx context.Context, height int64, round int32, action WALFixtureAction) error {
+	msgType := tmproto.PrevoteType
+	if action.kind == walFixturePrecommit {
+		msgType = tmproto.PrecommitType
+	}
+	var blockID types.BlockID
+	if !action.nilVote {
+		if f.blockID == nil {
+			return errors.New("no block proposed in the round to vote for")
+		}
+		blockID = *f.blockID
+	}
+	vals := f.cs.roundState.Validators()
+	indexes := action.validators
+	if len(indexes) == 0 {
+		for i := range vals.Validators {
+			indexes = append(indexes, i)
+		}
+	}
+	extensions := f.cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(height)
+	for _, index := range indexes {
+		addr, val := vals.GetByIndex(int32(index))
+		if val == nil {
+			return fmt.Errorf("no validator at index %d", index)
+		}
+		vote := &types.Vote{
+			Type:             msgType,
+			Height:           height,
+			Round:            round,
+			BlockID:          blockID,
+			Timestamp:        f.clock.Now(),
+			ValidatorAddress: addr,
+			ValidatorIndex:   int32(index),
+		}
+		if extensions && msgType == tmproto.PrecommitType && !blockID.IsNil() {
+			vote.Extension = []byte(fmt.Sprintf("extension/%d/%d", height, index))
+		}
+		v := vote.ToProto()
+		if err := f.privVals[string(addr)].SignVote(ctx, f.cs.state.ChainID, v); err != nil {
+			return err
+		}
+		vote.Signature = v.Signature
+		vote.ExtensionSignature = v.ExtensionSignature
+		f.send(ctx, types.NodeIDFromPubKey(val.PubKey), &VoteMessage{vote})
+	}
+	return nil
+}
+
+// fireTimeout fires the timeout of step scheduled at height and round, once
+// its duration elapsed on the clock of the fixture.
+func (f *walFixture) fireTimeout(ctx context.Context, height int64, round int32, step cstypes.RoundStepType) error {
+	ti, ok := f.ticker.take(height, round, step)
+	if !ok {
+		return fmt.Errorf("no timeout of %v scheduled", step)
+	}
+	if step == cstypes.RoundStepNewHeight {
+		// scheduled with the time left of the commit timeout on the system
+		// clock, which the fixture does not spend
+		ti.Duration = f.cs.commitTime(time.Time{}).Sub(time.Time{})
+	}
+	if ti.Duration > 0 {
+		f.clock.advance(ti.Duration)
+	}
+	if err := f.cs.walWrite(FaultPointTimeout, ti); err != nil {
+		return err
+	}
+	f.cs.handleTimeout(ctx, ti, *f.cs.roundState.CopyInternal())
+	f.drain(ctx)
+	return nil
+}
+
+// send delivers msg from peerID to the State, as its receive routine would.
+func (f *walFixture) send(ctx context.Context, peerID types.NodeID, msg Message) {
+	mi := msgInfo{Msg: msg, PeerID: peerID, ReceiveTime: f.clock.advance(walFixtureStep)}
+	if err := f.cs.walWrite(FaultPointPeerMsg, mi); err != nil {
+		f.cs.logger.Error("failed writing to WAL", "err", err)
+	}
+	f.cs.handleMsg(ctx, mi, false)
+	f.drain(ctx)
+}
+
+// drain handles the messages the State sent itself.
+func (f *walFixture) drain(ctx context.Context) {
+	for {
+		select {
+		case mi := <-f.cs.internalMsgQueue:
+			if err := f.cs.walWrite(FaultPointInternalMsg, mi); err != nil {
+				f.cs.logger.Error("failed writing to WAL", "err", err)
+			}
+			f.cs.handleMsg(ctx, mi, true)
+		default:
+			return
+		}
+	}
+}
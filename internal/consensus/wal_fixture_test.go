@@ -0,0 +1,194 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// walFixtureWithNHeights returns the WAL of n heights committed in round 0 by
+// 4 validators.
+func walFixtureWithNHeights(ctx context.Context, t *testing.T, n int) []byte {
+	t.Helper()
+	script := WALFixtureScript{Seed: 1, Validators: 4}
+	for i := 0; i < n; i++ {
+		script.Heights = append(script.Heights, WALFixtureCommitted())
+	}
+	data, err := GenerateWALFixture(ctx, script)
+	require.NoError(t, err)
+	return data
+}
+
+// decodeWALFixture decodes the messages of data, up to the first error.
+func decodeWALFixture(t *testing.T, data []byte) ([]*TimedWALMessage, error) {
+	t.Helper()
+	dec := NewWALDecoder(bytes.NewReader(data))
+	var msgs []*TimedWALMessage
+	for {
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func walFixtureEndHeights(msgs []*TimedWALMessage) []int64 {
+	var heights []int64
+	for _, msg := range msgs {
+		if eh, ok := msg.EndHeight(); ok {
+			heights = append(heights, eh.Height)
+		}
+	}
+	return heights
+}
+
+func TestGenerateWALFixtureDeterministic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	script := func(seed int64) WALFixtureScript {
+		return WALFixtureScript{
+			Seed:       seed,
+			Validators: 4,
+			Heights: []WALFixtureHeight{
+				WALFixtureCommitted(),
+				{Txs: types.Txs{types.Tx("a=1"), types.Tx("b=2")}, Rounds: WALFixtureCommitted().Rounds},
+				WALFixtureCommitted(),
+			},
+		}
+	}
+	first, err := GenerateWALFixture(ctx, script(7))
+	require.NoError(t, err)
+	second, err := GenerateWALFixture(ctx, script(7))
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	other, err := GenerateWALFixture(ctx, script(8))
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+
+	msgs, err := decodeWALFixture(t, first)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1, 2, 3}, walFixtureEndHeights(msgs))
+}
+
+func TestGenerateWALFixtureMultiRound(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the proposer of round 0 withholds a block part: the validators vote nil
+	// and the height is committed in round 1
+	data, err := GenerateWALFixture(ctx, WALFixtureScript{
+		Seed:       1,
+		Validators: 4,
+		Heights: []WALFixtureHeight{{
+			Txs: types.Txs{types.Tx(bytes.Repeat([]byte("a"), 2*int(types.BlockPartSizeBytes)))},
+			Rounds: [][]WALFixtureAction{
+				{
+					WALFixtureProposeWithholding(1),
+					WALFixtureTimeout(cstypes.RoundStepPropose),
+					WALFixturePrevoteNil(),
+					WALFixturePrecommitNil(),
+					WALFixtureTimeout(cstypes.RoundStepPrecommitWait),
+				},
+				{
+					WALFixturePropose(),
+					WALFixturePrevote(),
+					WALFixturePrecommit(),
+				},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	msgs, err := decodeWALFixture(t, data)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1}, walFixtureEndHeights(msgs))
+	var timeouts []cstypes.RoundStepType
+	precommits := make(map[int32]int)
+	for _, msg := range msgs {
+		if ti, ok := msg.TimeoutInfo(); ok && ti.Height == 1 {
+			timeouts = append(timeouts, ti.Step)
+		}
+		if mi, ok := msg.MsgInfo(); ok {
+			if vm, ok := mi.Msg.(*VoteMessage); ok && vm.Vote.Type == tmproto.PrecommitType {
+				require.Equal(t, vm.Vote.Round == 0, vm.Vote.BlockID.IsNil())
+				precommits[vm.Vote.Round]++
+			}
+		}
+	}
+	require.Equal(t, []cstypes.RoundStepType{
+		cstypes.RoundStepNewHeight, cstypes.RoundStepPropose, cstypes.RoundStepPrecommitWait,
+	}, timeouts)
+	require.Equal(t, map[int32]int{0: 4, 1: 4}, precommits)
+
+	// a script not matching the State is refused
+	_, err = GenerateWALFixture(ctx, WALFixtureScript{
+		Seed:       1,
+		Validators: 4,
+		Heights: []WALFixtureHeight{{Rounds: [][]WALFixtureAction{{
+			WALFixturePropose(),
+			WALFixtureTimeout(cstypes.RoundStepPrecommitWait),
+		}}}},
+	})
+	require.Error(t, err)
+}
+
+func TestGenerateWALFixtureVoteExtensions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data, err := GenerateWALFixture(ctx, WALFixtureScript{
+		Seed:                       1,
+		Validators:                 4,
+		VoteExtensionsEnableHeight: 2,
+		Heights:                    []WALFixtureHeight{WALFixtureCommitted(), WALFixtureCommitted(), WALFixtureCommitted()},
+	})
+	require.NoError(t, err)
+
+	msgs, err := decodeWALFixture(t, data)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1, 2, 3}, walFixtureEndHeights(msgs))
+	extended := make(map[int64]int)
+	for _, msg := range msgs {
+		if mi, ok := msg.MsgInfo(); ok {
+			if vm, ok := mi.Msg.(*VoteMessage); ok && vm.Vote.Type == tmproto.PrecommitType && len(vm.Vote.Extension) > 0 {
+				require.NotEmpty(t, vm.Vote.ExtensionSignature)
+				extended[vm.Vote.Height]++
+			}
+		}
+	}
+	require.Equal(t, map[int64]int{2: 4, 3: 4}, extended)
+}
+
+func TestGenerateWALFixtureCorruptTail(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	script := WALFixtureScript{Seed: 1, Validators: 4, Heights: []WALFixtureHeight{WALFixtureCommitted(), WALFixtureCommitted()}}
+	intact, err := GenerateWALFixture(ctx, script)
+	require.NoError(t, err)
+	script.CorruptTail = true
+	corrupted, err := GenerateWALFixture(ctx, script)
+	require.NoError(t, err)
+	require.Equal(t, intact[:len(corrupted)], corrupted)
+
+	// the records up to the last one are decoded
+	want, err := decodeWALFixture(t, intact)
+	require.NoError(t, err)
+	msgs, err := decodeWALFixture(t, corrupted)
+	require.True(t, IsDataCorruptionError(err), "err %v", err)
+	require.Equal(t, want[:len(want)-1], msgs)
+}
@@ -1,8 +1,6 @@
 package consensus
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -118,17 +116,6 @@ func WALGenerateNBlocks(ctx context.Context, t *testing.T, logger log.Logger, wr
 	}
 }
 
-// WALWithNBlocks returns a WAL content with numBlocks.
-func WALWithNBlocks(ctx context.Context, t *testing.T, logger log.Logger, numBlocks int) (data []byte, err error) {
-	var b bytes.Buffer
-	wr := bufio.NewWriter(&b)
-
-	WALGenerateNBlocks(ctx, t, logger, wr, numBlocks)
-
-	wr.Flush()
-	return b.Bytes(), nil
-}
-
 func randPort() int {
 	// returns between base and base + spread
 	base, spread := 20000, 20000
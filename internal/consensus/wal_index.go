@@ -0,0 +1,315 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	auto "github.com/tendermint/tendermint/internal/libs/autofile"
+	"github.com/tendermint/tendermint/internal/libs/tempfile"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// walIndexVersion is the version of the encoding of the WAL index file.
+const walIndexVersion = 1
+
+// WALPosition is the position of a message in a WAL: the index of the file of
+// the WAL group the message is in, and its offset in the file.
+type WALPosition struct {
+	Segment int
+	Offset  int64
+}
+
+func (p WALPosition) before(other WALPosition) bool {
+	return p.Segment < other.Segment || (p.Segment == other.Segment && p.Offset < other.Offset)
+}
+
+// WALIndex maps heights to the positions of their #ENDHEIGHT markers in a WAL,
+// so that the WAL can be read from a height without being scanned. It is
+// updated as markers are written and periodically persisted to a file next to
+// the WAL, which is rebuilt by scanning the WAL if it is missing or stale.
+//
+// The positions are hints: they are checked against the WAL when used.
+type WALIndex struct {
+	mtx       sync.RWMutex
+	path      string
+	positions map[int64]WALPosition
+	// height and position of the last marker indexed
+	lastHeight int64
+	last       WALPosition
+	dirty      bool
+	// incomplete is set once a marker could not be indexed
+	incomplete bool
+}
+
+// walIndexPath returns the path of the index file of the WAL at walFile. It
+// does not share the prefix of the files of the WAL group, so that it is not
+// counted in its size.
+func walIndexPath(walFile string) string {
+	return filepath.Join(filepath.Dir(walFile), "index."+filepath.Base(walFile))
+}
+
+// Lookup returns the position of the #ENDHEIGHT marker of height, and false
+// if it is not indexed.
+func (idx *WALIndex) Lookup(height int64) (WALPosition, bool) {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+	pos, ok := idx.positions[height]
+	return pos, ok
+}
+
+// Complete returns whether all the markers of the WAL are indexed, in which
+// case the heights that are not indexed are not in the WAL.
+func (idx *WALIndex) Complete() bool {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+	return !idx.incomplete
+}
+
+func (idx *WALIndex) markIncomplete() {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	idx.incomplete = true
+}
+
+// Len returns the number of indexed heights.
+func (idx *WALIndex) Len() int {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+	return len(idx.positions)
+}
+
+func (idx *WALIndex) add(height int64, pos WALPosition) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	idx.positions[height] = pos
+	if !pos.before(idx.last) {
+		idx.lastHeight, idx.last = height, pos
+	}
+	idx.dirty = true
+}
+
+// prune drops the positions in the files of group that were removed.
+func (idx *WALIndex) prune(group *auto.Group) {
+	minIndex := group.MinIndex()
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	for height, pos := range idx.positions {
+		if pos.Segment < minIndex {
+			delete(idx.positions, height)
+			idx.dirty = true
+		}
+	}
+}
+
+// persist writes the index to its file if it changed since it was last
+// written.
+func (idx *WALIndex) persist() error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	if !idx.dirty {
+		return nil
+	}
+	if err := tempfile.WriteFileAtomic(idx.path, encodeWALIndex(idx.positions), 0600); err != nil {
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+// encodeWALIndex encodes positions as a checksum followed by the version and
+// the varint encoded height, segment and offset of each position, in the order
+// of the heights.
+func encodeWALIndex(positions map[int64]WALPosition) []byte {
+	heights := make([]int64, 0, len(positions))
+	for height := range positions {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	buf := make([]byte, 4, 4+1+len(heights)*3*binary.MaxVarintLen64)
+	buf = append(buf, walIndexVersion)
+	for _, height := range heights {
+		pos := positions[height]
+		buf = binary.AppendVarint(buf, height)
+		buf = binary.AppendUvarint(buf, uint64(pos.Segment))
+		buf = binary.AppendUvarint(buf, uint64(pos.Offset))
+	}
+	binary.BigEndian.PutUint32(buf[0:4], crc32.Checksum(buf[4:], crc32c))
+	return buf
+}
+
+func decodeWALIndex(data []byte) (map[int64]WALPosition, error) {
+	if len(data) < 5 {
+		return nil, errors.New("truncated WAL index")
+	}
+	if crc32.Checksum(data[4:], crc32c) != binary.BigEndian.Uint32(data[0:4]) {
+		return nil, errors.New("WAL index checksum mismatch")
+	}
+	if data[4] != walIndexVersion {
+		return nil, fmt.Errorf("unknown WAL index version %d", data[4])
+	}
+
+	positions := make(map[int64]WALPosition)
+	for rest := data[5:]; len(rest) > 0; {
+		height, n := binary.Varint(rest)
+		if n <= 0 {
+			return nil, errors.New("invalid WAL index height")
+		}
+		rest = rest[n:]
+		segment, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return nil, errors.New("invalid WAL index segment")
+		}
+		rest = rest[n:]
+		offset, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return nil, errors.New("invalid WAL index offset")
+		}
+		rest = rest[n:]
+		positions[height] = WALPosition{Segment: int(segment), Offset: int64(offset)}
+	}
+	return positions, nil
+}
+
+// loadWALIndex loads the index of the WAL of group from path and indexes the
+// markers written after its last position. The index is rebuilt by scanning
+// the whole WAL if the file is missing, corrupted or stale.
+func loadWALIndex(logger log.Logger, group *auto.Group, path string) (*WALIndex, error) {
+	idx := &WALIndex{path: path, positions: make(map[int64]WALPosition)}
+
+	from := WALPosition{Segment: group.MinIndex()}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logger.Info("WAL index not found; rebuilding it", "path", path)
+	case err != nil:
+		return nil, err
+	default:
+		positions, err := decodeWALIndex(data)
+		if err != nil {
+			logger.Error("WAL index is corrupted; rebuilding it", "path", path, "err", err)
+			break
+		}
+		for height, pos := range positions {
+			idx.add(height, pos)
+		}
+		idx.dirty = false
+		idx.prune(group)
+		if len(idx.positions) == 0 {
+			break
+		}
+		gr, ok := readEndHeightAt(group, idx.lastHeight, idx.last)
+		if !ok {
+			logger.Error("WAL index is stale; rebuilding it", "path", path)
+			idx.positions = make(map[int64]WALPosition)
+			idx.lastHeight, idx.last = 0, WALPosition{}
+			break
+		}
+		gr.Close()
+		// the WAL is only scanned past the last indexed position
+		from = idx.last
+	}
+
+	if err := scanWALEndHeights(group, from, idx.add); err != nil {
+		return nil, err
+	}
+	if err := idx.persist(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// scanWALEndHeights calls fn with the height and the position of each
+// #ENDHEIGHT marker in the WAL of group, starting at from. Corrupted messages
+// are skipped.
+func scanWALEndHeights(group *auto.Group, from WALPosition, fn func(int64, WALPosition)) error {
+	for segment := from.Segment; segment <= group.MaxIndex(); segment++ {
+		offset := int64(0)
+		if segment == from.Segment {
+			offset = from.Offset
+		}
+		if err := scanWALFileEndHeights(group.FilePath(segment), segment, offset, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanWALFileEndHeights(path string, segment int, offset int64, fn func(int64, WALPosition)) error {
+	fp, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer fp.Close()
+	if _, err := fp.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	rd := &countingReader{rd: bufio.NewReader(fp), n: offset}
+	dec := NewWALDecoder(rd)
+	for {
+		pos := WALPosition{Segment: segment, Offset: rd.n}
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if IsDataCorruptionError(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if m, ok := msg.Msg.(EndHeightMessage); ok {
+			fn(m.Height, pos)
+		}
+	}
+}
+
+// readEndHeightAt decodes the #ENDHEIGHT marker of height at pos in the WAL of
+// group. It returns a reader positioned after the marker, and false if the
+// marker is not at pos.
+//
+// CONTRACT: caller must close the reader.
+func readEndHeightAt(group *auto.Group, height int64, pos WALPosition) (*auto.GroupReader, bool) {
+	if pos.Segment < group.MinIndex() || pos.Segment > group.MaxIndex() {
+		return nil, false
+	}
+	if _, err := os.Stat(group.FilePath(pos.Segment)); err != nil {
+		return nil, false
+	}
+	gr, err := group.NewReaderAt(pos.Segment, pos.Offset)
+	if err != nil {
+		return nil, false
+	}
+	msg, err := NewWALDecoder(gr).Decode()
+	if err != nil {
+		gr.Close()
+		return nil, false
+	}
+	if m, ok := msg.Msg.(EndHeightMessage); !ok || m.Height != height {
+		gr.Close()
+		return nil, false
+	}
+	return gr, true
+}
+
+// countingReader fills the buffers it reads into from rd, like the readers of
+// a WAL group, and counts the bytes read, starting at n.
+type countingReader struct {
+	rd io.Reader
+	n  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := io.ReadFull(r.rd, p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,173 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// startIndexedWAL starts the WAL at walFile and returns it with a copy of the
+// positions of its index.
+func startIndexedWAL(ctx context.Context, t *testing.T, walFile string) (*BaseWAL, map[int64]WALPosition) {
+	t.Helper()
+	wal, err := NewWAL(ctx, log.NewNopLogger(), walFile)
+	require.NoError(t, err)
+	require.NoError(t, wal.Start(ctx))
+	t.Cleanup(func() {
+		if wal.IsRunning() {
+			wal.Stop()
+		}
+		wal.Wait()
+	})
+
+	index := wal.Index()
+	require.NotNil(t, index)
+	require.True(t, index.Complete())
+	index.mtx.RLock()
+	defer index.mtx.RUnlock()
+	positions := make(map[int64]WALPosition, len(index.positions))
+	for height, pos := range index.positions {
+		positions[height] = pos
+	}
+	return wal, positions
+}
+
+func stopWAL(wal *BaseWAL) {
+	wal.Stop()
+	wal.Wait()
+}
+
+func TestWALIndexRebuild(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	walBody := walFixtureWithNHeights(ctx, t, 5)
+	walFile := tempWALWithData(t, walBody)
+	indexFile := walIndexPath(walFile)
+
+	// the index is built when the WAL is first started
+	wal, positions := startIndexedWAL(ctx, t, walFile)
+	require.Len(t, positions, 6)
+	for height, pos := range positions {
+		gr, ok := readEndHeightAt(wal.Group(), height, pos)
+		require.True(t, ok, "height %d", height)
+		require.NoError(t, gr.Close())
+	}
+
+	// the markers written are indexed, and the index is persisted on stop
+	require.NoError(t, wal.WriteSync(EndHeightMessage{6}))
+	pos, ok := wal.Index().Lookup(6)
+	require.True(t, ok)
+	gr, ok := readEndHeightAt(wal.Group(), 6, pos)
+	require.True(t, ok)
+	require.NoError(t, gr.Close())
+	positions[6] = pos
+	stopWAL(wal)
+	data, err := os.ReadFile(indexFile)
+	require.NoError(t, err)
+	persisted, err := decodeWALIndex(data)
+	require.NoError(t, err)
+	require.Equal(t, positions, persisted)
+
+	testCases := []struct {
+		name    string
+		prepare func(t *testing.T)
+	}{
+		{"missing", func(t *testing.T) {
+			require.NoError(t, os.Remove(indexFile))
+		}},
+		{"corrupted", func(t *testing.T) {
+			data[len(data)-1] ^= 0xff
+			require.NoError(t, os.WriteFile(indexFile, data, 0600))
+			data[len(data)-1] ^= 0xff
+		}},
+		{"stale", func(t *testing.T) {
+			stale := make(map[int64]WALPosition, len(positions))
+			for height, pos := range positions {
+				pos.Offset++
+				stale[height] = pos
+			}
+			require.NoError(t, os.WriteFile(indexFile, encodeWALIndex(stale), 0600))
+		}},
+		{"behind", func(t *testing.T) {
+			// markers written after the index was persisted are indexed
+			behind := make(map[int64]WALPosition, len(positions))
+			for height, pos := range positions {
+				if height < 4 {
+					behind[height] = pos
+				}
+			}
+			require.NoError(t, os.WriteFile(indexFile, encodeWALIndex(behind), 0600))
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.prepare(t)
+			wal, rebuilt := startIndexedWAL(ctx, t, walFile)
+			require.Equal(t, positions, rebuilt)
+			stopWAL(wal)
+
+			data, err := os.ReadFile(indexFile)
+			require.NoError(t, err)
+			persisted, err := decodeWALIndex(data)
+			require.NoError(t, err)
+			require.Equal(t, positions, persisted)
+		})
+	}
+}
+
+// readAllWALMessages decodes the messages of rd until its end.
+func readAllWALMessages(t *testing.T, rd io.ReadCloser) []*TimedWALMessage {
+	t.Helper()
+	defer rd.Close()
+	var msgs []*TimedWALMessage
+	dec := NewWALDecoder(rd)
+	for {
+		msg, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return msgs
+		}
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestWALIndexSearchMatchesScan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	walBody := walFixtureWithNHeights(ctx, t, 5)
+	walFile := tempWALWithData(t, walBody)
+	wal, positions := startIndexedWAL(ctx, t, walFile)
+	require.NoError(t, wal.FlushAndSync())
+
+	// a stale position is not used
+	stale := positions[3]
+	stale.Offset++
+	wal.Index().add(3, stale)
+
+	// the replay of each height reads the same messages from the WAL whether
+	// the EndHeightMessage is found with the index or not
+	options := &WALSearchOptions{IgnoreDataCorruptionErrors: true}
+	for height := int64(0); height <= 8; height++ {
+		indexed, indexedFound, err := wal.SearchForEndHeight(height, options)
+		require.NoError(t, err)
+		scanned, scannedFound, err := wal.searchForEndHeight(height, options)
+		require.NoError(t, err)
+
+		require.Equal(t, height <= 5, indexedFound, "height %d", height)
+		require.Equal(t, scannedFound, indexedFound, "height %d", height)
+		if !scannedFound {
+			continue
+		}
+		msgs := readAllWALMessages(t, indexed)
+		require.NotEmpty(t, msgs)
+		require.Equal(t, readAllWALMessages(t, scanned), msgs, "height %d", height)
+	}
+}
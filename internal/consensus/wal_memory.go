@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	tmtime "github.com/tendermint/tendermint/libs/time"
+)
+
+// MemWAL is a WAL kept in memory, for the ephemeral nodes whose consensus
+// messages need not survive the process. The messages survive the restarts
+// of the States sharing it, which replay them as they would a WAL file.
+type MemWAL struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+	enc *WALEncoder
+	// offsets in buf of the messages following each #ENDHEIGHT
+	endHeights map[int64]int
+}
+
+var _ WAL = &MemWAL{}
+var _ WALFileRepairer = &MemWAL{}
+
+// NewMemWAL returns an empty in-memory WAL.
+func NewMemWAL() *MemWAL {
+	wal := &MemWAL{endHeights: make(map[int64]int)}
+	wal.enc = NewWALEncoder(&wal.buf)
+	return wal
+}
+
+// Start writes the #ENDHEIGHT of height 0 to an empty WAL, like the WAL file.
+// The WAL can be started again once stopped.
+func (wal *MemWAL) Start(context.Context) error {
+	wal.mtx.Lock()
+	empty := wal.buf.Len() == 0
+	wal.mtx.Unlock()
+	if empty {
+		return wal.Write(EndHeightMessage{0})
+	}
+	return nil
+}
+
+func (wal *MemWAL) Stop() {}
+func (wal *MemWAL) Wait() {}
+
+func (wal *MemWAL) Write(msg WALMessage) error {
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	if err := wal.enc.Encode(&TimedWALMessage{tmtime.Now(), msg}); err != nil {
+		return err
+	}
+	// the last of duplicate markers is kept, like in the WAL file
+	if endHeight, ok := msg.(EndHeightMessage); ok {
+		wal.endHeights[endHeight.Height] = wal.buf.Len()
+	}
+	return nil
+}
+
+func (wal *MemWAL) WriteSync(msg WALMessage) error { return wal.Write(msg) }
+
+func (wal *MemWAL) FlushAndSync() error { return nil }
+
+// SearchForEndHeight returns a reader of the messages following the
+// #ENDHEIGHT of height, if it was written.
+func (wal *MemWAL) SearchForEndHeight(height int64, options *WALSearchOptions) (io.ReadCloser, bool, error) {
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	offset, ok := wal.endHeights[height]
+	if !ok {
+		return nil, false, nil
+	}
+	msgs := append([]byte(nil), wal.buf.Bytes()[offset:]...)
+	return io.NopCloser(bytes.NewReader(msgs)), true, nil
+}
+
+// RepairFile implements WALFileRepairer. A MemWAL has no file.
+func (wal *MemWAL) RepairFile() string { return "" }
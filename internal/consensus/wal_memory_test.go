@@ -0,0 +1,249 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cstypes "github.com/tendermint/tendermint/internal/consensus/types"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestMemWALSearchForEndHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+	for height := int64(1); height <= 3; height++ {
+		require.NoError(t, wal.Write(timeoutInfo{Height: height, Step: cstypes.RoundStepPropose}))
+		require.NoError(t, wal.WriteSync(EndHeightMessage{height}))
+	}
+	wal.Stop()
+	// restarting does not write the #ENDHEIGHT of height 0 again
+	require.NoError(t, wal.Start(ctx))
+
+	for height := int64(0); height <= 3; height++ {
+		rd, found, err := wal.SearchForEndHeight(height, &WALSearchOptions{})
+		require.NoError(t, err)
+		require.True(t, found)
+		dec := NewWALDecoder(rd)
+		if height == 3 {
+			_, err = dec.Decode()
+			require.ErrorIs(t, err, io.EOF)
+			continue
+		}
+		msg, err := dec.Decode()
+		require.NoError(t, err)
+		ti, ok := msg.TimeoutInfo()
+		require.True(t, ok)
+		require.Equal(t, height+1, ti.Height)
+	}
+
+	_, found, err := wal.SearchForEndHeight(4, &WALSearchOptions{})
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestStateWithWALProvider(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := NewMemWAL()
+	var openedPath string
+	provider := func(ctx context.Context, _ log.Logger, path string) (WAL, error) {
+		openedPath = path
+		return wal, wal.Start(ctx)
+	}
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithWALProvider(provider)}})
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height+2
+	}, 10*time.Second, 10*time.Millisecond)
+	cs.Stop()
+
+	// the provider was opened in place of the WAL file
+	require.Equal(t, cs.config.WalFile(), openedPath)
+	_, err := os.Stat(cs.config.WalFile())
+	require.True(t, os.IsNotExist(err))
+	for h := height; h <= height+2; h++ {
+		_, found, err := wal.SearchForEndHeight(h, &WALSearchOptions{})
+		require.NoError(t, err)
+		require.True(t, found, "no #ENDHEIGHT %d", h)
+	}
+}
+
+func TestStateReplaysMemWAL(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := NewMemWAL()
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithWAL(wal)}})
+	height := cs.roundState.Height()
+
+	require.NoError(t, cs.Start(ctx))
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height+1
+	}, 10*time.Second, 10*time.Millisecond)
+	cs.Stop()
+
+	// a State restarted on the same stores replays the WAL
+	restarted, err := NewState(log.NewNopLogger(), cs.config, cs.stateStore, cs.blockExec, cs.blockStore,
+		cs.txNotifier, cs.evpool, cs.eventBus, nil, WithWAL(wal))
+	require.NoError(t, err)
+	pv, _ := cs.getPrivValidator()
+	restarted.SetPrivValidator(ctx, pv)
+	restartHeight := restarted.roundState.Height()
+	_, found, err := wal.SearchForEndHeight(restartHeight-1, &WALSearchOptions{})
+	require.NoError(t, err)
+	require.True(t, found)
+
+	require.NoError(t, restarted.Start(ctx))
+	defer restarted.Stop()
+	require.Positive(t, restarted.Status().ReplayedMsgs)
+	require.Eventually(t, func() bool {
+		applied, _ := restarted.LastApplied()
+		return applied >= restartHeight+1
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+// corruptedWAL corrupts the messages following the #ENDHEIGHT markers.
+type corruptedWAL struct {
+	*MemWAL
+}
+
+func (wal corruptedWAL) SearchForEndHeight(height int64, options *WALSearchOptions) (io.ReadCloser, bool, error) {
+	rd, found, err := wal.MemWAL.SearchForEndHeight(height, options)
+	if !found || err != nil {
+		return rd, found, err
+	}
+	msgs, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(msgs) > 0 {
+		// the checksum of the first message
+		msgs[0] ^= 0xff
+	}
+	return io.NopCloser(bytes.NewReader(msgs)), true, nil
+}
+
+func TestStateCorruptedWALWithoutFileRepair(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := corruptedWAL{NewMemWAL()}
+	require.NoError(t, wal.Start(ctx))
+	require.NoError(t, wal.Write(timeoutInfo{Height: 1, Step: cstypes.RoundStepPropose}))
+	cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithWAL(wal)}})
+
+	err := cs.Start(ctx)
+	require.Error(t, err)
+	require.True(t, IsDataCorruptionError(err))
+	// the WAL file of the config was not repaired in place of the WAL
+	_, err = os.Stat(cs.config.WalFile() + ".CORRUPTED")
+	require.True(t, os.IsNotExist(err))
+}
+
+// fileWAL hides the WALFileRepairer implementation of its WAL.
+type fileWAL struct {
+	WAL
+}
+
+func TestStateRepairsProviderWAL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// writeCorruptedWAL writes a WAL whose last timeout has an invalid step.
+	writeCorruptedWAL := func(t *testing.T, path string, height int64) {
+		t.Helper()
+		var data bytes.Buffer
+		enc := NewWALEncoder(&data)
+		require.NoError(t, enc.Encode(&TimedWALMessage{Time: time.Now(), Msg: EndHeightMessage{height - 1}}))
+		data.Write(encodeRawTimeoutInfo(t, height, 0, 0x09))
+		require.NoError(t, os.WriteFile(path, data.Bytes(), 0600))
+	}
+	for _, tc := range []struct {
+		name     string
+		option   func(walFile string, opened *int) StateOption
+		repaired bool
+	}{
+		{
+			name: "provider opting in",
+			option: func(walFile string, opened *int) StateOption {
+				return WithWALProvider(func(ctx context.Context, logger log.Logger, _ string) (WAL, error) {
+					*opened++
+					wal, err := NewWAL(ctx, logger, walFile)
+					if err != nil {
+						return nil, err
+					}
+					return wal, wal.Start(ctx)
+				})
+			},
+			repaired: true,
+		},
+		{
+			name: "provider not opting in",
+			option: func(walFile string, opened *int) StateOption {
+				return WithWALProvider(func(ctx context.Context, logger log.Logger, _ string) (WAL, error) {
+					*opened++
+					wal, err := NewWAL(ctx, logger, walFile)
+					if err != nil {
+						return nil, err
+					}
+					return fileWAL{wal}, wal.Start(ctx)
+				})
+			},
+		},
+		{
+			name: "WithWAL",
+			option: func(walFile string, opened *int) StateOption {
+				*opened++
+				wal, err := NewWAL(ctx, log.NewNopLogger(), walFile)
+				require.NoError(t, err)
+				return WithWAL(wal)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := configSetup(t)
+			walFile := filepath.Join(t.TempDir(), "wal")
+			var opened int
+			cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{tc.option(walFile, &opened)}})
+			writeCorruptedWAL(t, walFile, cs.roundState.Height())
+
+			err := cs.Start(ctx)
+			// the WAL file of the config is never repaired in place of the
+			// WAL of the provider
+			_, statErr := os.Stat(cs.config.WalFile() + ".CORRUPTED")
+			require.True(t, os.IsNotExist(statErr))
+			if !tc.repaired {
+				require.True(t, IsDataCorruptionError(err), "err %v", err)
+				require.NoFileExists(t, walFile+".CORRUPTED")
+				require.Equal(t, 1, opened)
+				cs.wal.Stop()
+				return
+			}
+			require.NoError(t, err)
+			require.FileExists(t, walFile+".CORRUPTED")
+			// the repaired WAL is opened again with the provider
+			require.Equal(t, 2, opened)
+			cs.Stop()
+			cs.Wait()
+			cs.wal.Wait()
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// walOpenAttempts bounds how many times OnStart tries to open the WAL when it
+// fails with a transient error.
+var walOpenAttempts = 6
+
+// walOpenBackoff is the wait before the first retry to open the WAL. It is
+// doubled after each retry.
+var walOpenBackoff = 100 * time.Millisecond
+
+// isTransientWALOpenError returns whether opening the WAL failed with an error
+// another attempt may not hit: the WAL file is locked or busy, e.g. by a
+// previous process still shutting down, or the WAL file or its directory is
+// missing, e.g. while the directory is recreated. Opening the WAL file
+// creates its directory if missing.
+func isTransientWALOpenError(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EWOULDBLOCK) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, fs.ErrNotExist)
+}
+
+// openWALWithRetry opens the WAL with open, retrying with backoff while it
+// fails with a transient error, for at most walOpenAttempts attempts.
+func (cs *State) openWALWithRetry(ctx context.Context, walFile string, open func() (WAL, error)) (WAL, error) {
+	backoff := walOpenBackoff
+	for attempt := 1; ; attempt++ {
+		wal, err := open()
+		switch {
+		case err == nil:
+			return wal, nil
+		case !isTransientWALOpenError(err):
+			return nil, err
+		case attempt >= walOpenAttempts:
+			return nil, fmt.Errorf("failed to open the WAL %s after %d attempts: %w", walFile, attempt, err)
+		}
+
+		cs.metrics.WALOpenRetries.Add(1)
+		cs.logger.Info("failed to open the WAL; retrying",
+			"file", walFile, "attempt", attempt, "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
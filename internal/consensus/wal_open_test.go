@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestStateOpenWALRetry(t *testing.T) {
+	config := configSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backoff := walOpenBackoff
+	walOpenBackoff = time.Millisecond
+	t.Cleanup(func() { walOpenBackoff = backoff })
+
+	// lockedWAL returns a provider failing with err until its attempt release.
+	lockedWAL := func(err error, release int, attempts *int) WALProvider {
+		wal := NewMemWAL()
+		return func(ctx context.Context, _ log.Logger, path string) (WAL, error) {
+			*attempts++
+			if release == 0 || *attempts < release {
+				return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+			}
+			return wal, wal.Start(ctx)
+		}
+	}
+	newState := func(provider WALProvider) (*State, *generic.Counter) {
+		cs, _ := makeState(ctx, t, makeStateArgs{config: config, validators: 1, options: []StateOption{WithWALProvider(provider)}})
+		retries := generic.NewCounter("wal_open_retries")
+		cs.metrics.WALOpenRetries = retries
+		return cs, retries
+	}
+
+	// the WAL is locked by a previous process released after two attempts
+	var attempts int
+	cs, retries := newState(lockedWAL(syscall.EWOULDBLOCK, 3, &attempts))
+	require.NoError(t, cs.Start(ctx))
+	height := cs.roundState.Height()
+	require.Eventually(t, func() bool {
+		applied, _ := cs.LastApplied()
+		return applied >= height
+	}, 10*time.Second, 10*time.Millisecond)
+	cs.Stop()
+	require.Equal(t, 3, attempts)
+	require.Equal(t, 2.0, retries.Value())
+
+	// the WAL is never released
+	attempts = 0
+	cs, retries = newState(lockedWAL(syscall.EWOULDBLOCK, 0, &attempts))
+	err := cs.Start(ctx)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, syscall.EWOULDBLOCK))
+	require.False(t, IsDataCorruptionError(err))
+	require.Contains(t, err.Error(), "after 6 attempts")
+	require.Equal(t, walOpenAttempts, attempts)
+	require.Equal(t, float64(walOpenAttempts-1), retries.Value())
+
+	// an error which is not transient is not retried
+	attempts = 0
+	cs, retries = newState(lockedWAL(syscall.EACCES, 0, &attempts))
+	err = cs.Start(ctx)
+	require.True(t, errors.Is(err, syscall.EACCES))
+	require.Equal(t, 1, attempts)
+	require.Zero(t, retries.Value())
+}
+
+func TestIsTransientWALOpenError(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		transient bool
+	}{
+		{err: &fs.PathError{Op: "open", Err: syscall.EAGAIN}, transient: true},
+		{err: &fs.PathError{Op: "open", Err: syscall.EBUSY}, transient: true},
+		{err: &fs.PathError{Op: "open", Err: syscall.ENOENT}, transient: true},
+		{err: &fs.PathError{Op: "open", Err: syscall.EACCES}},
+		{err: DataCorruptionError{errors.New("checksum mismatch")}},
+		{err: errors.New("unexpected")},
+	} {
+		require.Equal(t, tc.transient, isTransientWALOpenError(tc.err), tc.err.Error())
+	}
+}
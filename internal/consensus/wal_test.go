@@ -3,6 +3,11 @@ package consensus
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -10,6 +15,7 @@ import (
 	"time"
 
 	"github.com/fortytw2/leaktest"
+	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -18,6 +24,7 @@ import (
 	"github.com/tendermint/tendermint/internal/libs/autofile"
 	"github.com/tendermint/tendermint/libs/log"
 	tmtime "github.com/tendermint/tendermint/libs/time"
+	tmcons "github.com/tendermint/tendermint/proto/tendermint/consensus"
 	tmtypes "github.com/tendermint/tendermint/types"
 )
 
@@ -101,6 +108,42 @@ func TestWALEncoderDecoder(t *testing.T) {
 	}
 }
 
+func TestWALMsgInfoReceiveTime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal := NewMemWAL()
+	require.NoError(t, wal.Start(ctx))
+
+	// the record is timed with its write time, the receive time is kept in
+	// the message
+	received := tmtime.Now().Add(-time.Hour)
+	written := tmtime.Now()
+	part := &tmtypes.Part{Index: 0, Bytes: []byte("part"), Proof: merkle.Proof{Total: 1, LeafHash: make([]byte, 32)}}
+	mi := msgInfo{Msg: &BlockPartMessage{Height: 1, Round: 0, Part: part}, PeerID: "peer", ReceiveTime: received}
+	require.NoError(t, wal.Write(mi))
+	r, found, err := wal.SearchForEndHeight(0, nil)
+	require.NoError(t, err)
+	require.True(t, found)
+	decoded, err := NewWALDecoder(r).Decode()
+	require.NoError(t, err)
+	require.False(t, decoded.Time.Before(written))
+	decodedMi, ok := decoded.MsgInfo()
+	require.True(t, ok)
+	require.Equal(t, received.UTC(), decodedMi.ReceiveTime)
+
+	// the messages written without their receive time get the time of their
+	// record
+	b := new(bytes.Buffer)
+	mi.ReceiveTime = time.Time{}
+	require.NoError(t, NewWALEncoder(b).Encode(&TimedWALMessage{Time: written, Msg: mi}))
+	decoded, err = NewWALDecoder(b).Decode()
+	require.NoError(t, err)
+	decodedMi, ok = decoded.MsgInfo()
+	require.True(t, ok)
+	require.Equal(t, written.UTC(), decodedMi.ReceiveTime)
+}
+
 func TestWALWrite(t *testing.T) {
 	walDir := t.TempDir()
 	walFile := filepath.Join(walDir, "wal")
@@ -143,10 +186,7 @@ func TestWALSearchForEndHeight(t *testing.T) {
 
 	logger := log.NewNopLogger()
 
-	walBody, err := WALWithNBlocks(ctx, t, logger, 6)
-	if err != nil {
-		t.Fatal(err)
-	}
+	walBody := walFixtureWithNHeights(ctx, t, 5)
 	walFile := tempWALWithData(t, walBody)
 
 	wal, err := NewWAL(ctx, logger, walFile)
@@ -208,3 +248,207 @@ func TestWALPeriodicSync(t *testing.T) {
 
 	t.Cleanup(leaktest.Check(t))
 }
+
+// walTestMessages returns one message of each kind found in a WAL, and their
+// encoding.
+func walTestMessages(tb testing.TB) ([]TimedWALMessage, []byte) {
+	tb.Helper()
+
+	now := tmtime.Now()
+	msgs := []TimedWALMessage{
+		{Time: now, Msg: tmtypes.EventDataRoundState{Height: 1, Round: 0, Step: "RoundStepPropose"}},
+		{Time: now, Msg: msgInfo{
+			Msg:         &BlockPartMessage{Height: 1, Round: 0, Part: &tmtypes.Part{Index: 0, Bytes: []byte("part"), Proof: merkle.Proof{Total: 1, LeafHash: make([]byte, 32)}}},
+			PeerID:      "peer",
+			ReceiveTime: now,
+		}},
+		{Time: now, Msg: timeoutInfo{Duration: time.Second, Height: 1, Round: 0, Step: types.RoundStepPropose}},
+		{Time: now, Msg: EndHeightMessage{1}},
+		{Time: now, Msg: RestartAcknowledgmentMessage{Height: 2, SignatureHeight: 1}},
+	}
+
+	b := new(bytes.Buffer)
+	enc := NewWALEncoder(b)
+	for i := range msgs {
+		require.NoError(tb, enc.Encode(&msgs[i]))
+	}
+	return msgs, b.Bytes()
+}
+
+func TestIterateWAL(t *testing.T) {
+	msgs, data := walTestMessages(t)
+	walFile := filepath.Join(t.TempDir(), "wal")
+	require.NoError(t, os.WriteFile(walFile, data, 0600))
+
+	var decoded []TimedWALMessage
+	require.NoError(t, IterateWAL(walFile, func(msg TimedWALMessage) (bool, error) {
+		decoded = append(decoded, msg)
+		return false, nil
+	}))
+	require.Len(t, decoded, len(msgs))
+	for i := range msgs {
+		assert.Equal(t, msgs[i].Time.UTC(), decoded[i].Time)
+		assert.Equal(t, msgs[i].Msg, decoded[i].Msg)
+	}
+
+	mi, ok := decoded[1].MsgInfo()
+	require.True(t, ok)
+	assert.Equal(t, tmtypes.NodeID("peer"), mi.PeerID)
+	ti, ok := decoded[2].TimeoutInfo()
+	require.True(t, ok)
+	assert.Equal(t, types.RoundStepPropose, ti.Step)
+	eh, ok := decoded[3].EndHeight()
+	require.True(t, ok)
+	assert.Equal(t, int64(1), eh.Height)
+	_, ok = decoded[3].MsgInfo()
+	assert.False(t, ok)
+
+	// stop and errors end the iteration
+	var n int
+	require.NoError(t, IterateWAL(walFile, func(TimedWALMessage) (bool, error) {
+		n++
+		return n == 2, nil
+	}))
+	assert.Equal(t, 2, n)
+	errStop := errors.New("stop")
+	assert.ErrorIs(t, IterateWAL(walFile, func(TimedWALMessage) (bool, error) {
+		return false, errStop
+	}), errStop)
+}
+
+func TestIterateWALTruncated(t *testing.T) {
+	msgs, data := walTestMessages(t)
+	walFile := filepath.Join(t.TempDir(), "wal")
+
+	// offsets at which a message ends
+	var ends []int
+	for i := range msgs {
+		b := new(bytes.Buffer)
+		require.NoError(t, NewWALEncoder(b).Encode(&msgs[i]))
+		end := b.Len()
+		if len(ends) > 0 {
+			end += ends[len(ends)-1]
+		}
+		ends = append(ends, end)
+	}
+
+	for size := 0; size < len(data); size++ {
+		require.NoError(t, os.WriteFile(walFile, data[:size], 0600))
+
+		var n int
+		err := IterateWAL(walFile, func(msg TimedWALMessage) (bool, error) {
+			require.Equal(t, msgs[n].Msg, msg.Msg)
+			n++
+			return false, nil
+		})
+
+		complete := 0
+		for complete < len(ends) && ends[complete] <= size {
+			complete++
+		}
+		require.Equal(t, complete, n, "size %d", size)
+		if size == 0 || complete > 0 && ends[complete-1] == size {
+			require.NoError(t, err, "size %d", size)
+		} else {
+			require.True(t, IsDataCorruptionError(err), "size %d: %v", size, err)
+		}
+	}
+}
+
+func TestTimedWALMessageJSON(t *testing.T) {
+	msgs, _ := walTestMessages(t)
+	mi := msgs[1].Msg.(msgInfo)
+	mi.ReceiveTime = tmtime.Now()
+	msgs[1].Msg = mi
+
+	for _, msg := range msgs {
+		data, err := json.Marshal(msg)
+		require.NoError(t, err)
+
+		var decoded TimedWALMessage
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.True(t, msg.Time.Equal(decoded.Time))
+		assert.Equal(t, msg.Msg, decoded.Msg)
+	}
+
+	_, err := json.Marshal(TimedWALMessage{Msg: struct{}{}})
+	assert.Error(t, err)
+}
+
+func FuzzIterateWAL(f *testing.F) {
+	_, data := walTestMessages(f)
+	f.Add(data)
+	f.Add(data[:len(data)/2])
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	f.Add(corrupted)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		walFile := filepath.Join(t.TempDir(), "wal")
+		require.NoError(t, os.WriteFile(walFile, data, 0600))
+
+		err := IterateWAL(walFile, func(msg TimedWALMessage) (bool, error) {
+			// every decoded message can be written back
+			require.NoError(t, NewWALEncoder(new(bytes.Buffer)).Encode(&msg))
+			return false, nil
+		})
+		if err != nil {
+			require.True(t, IsDataCorruptionError(err), "unexpected error: %v", err)
+		}
+	})
+}
+
+// encodeRawTimeoutInfo encodes a WAL record of a timeout with arbitrary field
+// values, as a corrupted but decodable record would be.
+func encodeRawTimeoutInfo(tb testing.TB, height int64, round int32, step uint32) []byte {
+	tb.Helper()
+
+	data, err := proto.Marshal(&tmcons.TimedWALMessage{
+		Time: tmtime.Now(),
+		Msg: &tmcons.WALMessage{Sum: &tmcons.WALMessage_TimeoutInfo{TimeoutInfo: &tmcons.TimeoutInfo{
+			Duration: time.Second,
+			Height:   height,
+			Round:    round,
+			Step:     step,
+		}}},
+	})
+	require.NoError(tb, err)
+
+	msg := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint32(msg[0:4], crc32.Checksum(data, crc32c))
+	binary.BigEndian.PutUint32(msg[4:8], uint32(len(data)))
+	copy(msg[8:], data)
+	return msg
+}
+
+func FuzzWALDecodeTimeoutInfo(f *testing.F) {
+	f.Add(int64(1), int32(0), uint32(types.RoundStepPropose))
+	f.Add(int64(1), int32(0), uint32(0))
+	f.Add(int64(1), int32(0), uint32(types.RoundStepPrevote))
+	f.Add(int64(1), int32(0), uint32(0x09))
+	f.Add(int64(1), int32(0), uint32(0x101))
+	f.Add(int64(1), int32(-1), uint32(types.RoundStepNewHeight))
+	f.Add(int64(0), int32(0), uint32(types.RoundStepNewHeight))
+	f.Add(int64(-5), int32(0), uint32(types.RoundStepNewHeight))
+	f.Add(int64(1), int32(math.MaxInt32), uint32(types.RoundStepPropose))
+	f.Add(int64(1), int32(math.MaxInt32), uint32(types.RoundStepPrecommitWait))
+
+	f.Fuzz(func(t *testing.T, height int64, round int32, step uint32) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		msg, err := NewWALDecoder(bytes.NewReader(encodeRawTimeoutInfo(t, height, round, step))).Decode()
+		if err != nil {
+			require.True(t, IsDataCorruptionError(err), "unexpected error: %v", err)
+			return
+		}
+		ti, ok := msg.TimeoutInfo()
+		require.True(t, ok)
+		require.NoError(t, ti.ValidateBasic())
+
+		// a decoded timeout is replayed without panicking
+		cs, _ := makeState(ctx, t, makeStateArgs{validators: 1})
+		cs.replayMode = true
+		require.NoError(t, cs.readReplayMessage(ctx, msg, nil))
+	})
+}
@@ -0,0 +1,442 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/internal/eventbus"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// errRecordingPrivValidator is returned by the recording private validator
+// for every signing request so that no vote or proposal is ever produced.
+var errRecordingPrivValidator = errors.New("recording private validator does not sign")
+
+// WALVerificationDeps are the components used to re-execute a WAL. The state
+// and block stores must be at the height the WAL should be replayed from, and
+// the block executor must be connected to the application the WAL was
+// recorded against.
+type WALVerificationDeps struct {
+	Logger     log.Logger
+	Config     *config.ConsensusConfig
+	StateStore sm.Store
+	BlockStore sm.BlockStore
+	BlockExec  *sm.BlockExecutor
+
+	// EventBus is optional; a private event bus is used if it is nil.
+	EventBus *eventbus.EventBus
+
+	// PubKey identifies the validator whose decisions are verified.
+	PubKey crypto.PubKey
+}
+
+// DecisionComparison compares a decision recorded in the WAL by the validator
+// with the decision made for the same height, round and type during
+// re-execution. Recorded or Replayed is nil when only one side made the
+// decision.
+type DecisionComparison struct {
+	Height int64
+	Round  int32
+	Type   tmproto.SignedMsgType
+
+	Recorded *types.BlockID
+	Replayed *types.BlockID
+
+	// Whether the vote carried a vote extension. Only relevant for precommits.
+	RecordedExtension bool
+	ReplayedExtension bool
+
+	// Partial is set for decisions at a height that the WAL does not close
+	// with an #ENDHEIGHT marker, since the recording may have stopped before
+	// the decision was written.
+	Partial bool
+}
+
+// Matches returns true if the recorded and replayed decisions agree. A
+// decision made on one side only does not match.
+func (d DecisionComparison) Matches() bool {
+	if d.Recorded == nil || d.Replayed == nil {
+		return d.Recorded == nil && d.Replayed == nil
+	}
+	return d.Recorded.Equals(*d.Replayed) && d.RecordedExtension == d.ReplayedExtension
+}
+
+// DeterminismReport is the result of VerifyWALDeterminism.
+type DeterminismReport struct {
+	// Decisions are sorted by height, round and type.
+	Decisions []DecisionComparison
+}
+
+// Divergences returns the non-partial decisions that do not match.
+func (r DeterminismReport) Divergences() []DecisionComparison {
+	var divergences []DecisionComparison
+	for _, d := range r.Decisions {
+		if !d.Partial && !d.Matches() {
+			divergences = append(divergences, d)
+		}
+	}
+	return divergences
+}
+
+// IsDeterministic returns true if no divergences were found.
+func (r DeterminismReport) IsDeterministic() bool {
+	return len(r.Divergences()) == 0
+}
+
+// VerifyWALDeterminism feeds the WAL at walPath through a State whose private
+// validator never signs, recording the proposals, prevotes and precommits the
+// state machine decides on. These decisions are compared against the votes
+// and proposals the validator recorded in the WAL itself.
+//
+// Messages are applied as they are during catchup replay: timeouts are driven
+// by the recorded timeoutInfo entries and the validator's own recorded votes
+// are fed back so that the state machine follows the original execution.
+//
+// A fresh proposal block depends on the mempool, the evidence pool and the
+// clock of the validator, so it is rebuilt from the transactions, evidence and
+// time of the block the validator recorded, the rest of the block coming from
+// the replayed state. A proposal the validator did not record is replayed as
+// a nil BlockID.
+func VerifyWALDeterminism(
+	ctx context.Context,
+	walPath string,
+	deps WALVerificationDeps,
+	options ...StateOption,
+) (DeterminismReport, error) {
+	if deps.PubKey == nil {
+		return DeterminismReport{}, errors.New("validator public key is required")
+	}
+	if deps.Logger == nil {
+		deps.Logger = log.NewNopLogger()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if deps.EventBus == nil {
+		deps.EventBus = eventbus.NewDefault(deps.Logger)
+		if err := deps.EventBus.Start(ctx); err != nil {
+			return DeterminismReport{}, fmt.Errorf("failed to start event bus: %w", err)
+		}
+		defer func() { deps.EventBus.Stop(); deps.EventBus.Wait() }()
+	}
+
+	cs, err := NewState(deps.Logger, deps.Config, deps.StateStore, deps.BlockExec,
+		deps.BlockStore, emptyMempool{}, sm.EmptyEvidencePool{}, deps.EventBus, nil, options...)
+	if err != nil {
+		return DeterminismReport{}, err
+	}
+
+	blocks, err := cs.recordedProposalBlocks(walPath)
+	if err != nil {
+		return DeterminismReport{}, err
+	}
+
+	pv := newRecordingPrivValidator(deps.PubKey)
+	cs.privValidatorMtx.Lock()
+	cs.privValidator = pv
+	cs.privValidatorPubKey = deps.PubKey
+	cs.privValidatorMtx.Unlock()
+	cs.SetTimeoutTicker(&walReplayTicker{c: make(chan timeoutInfo)})
+
+	// The proposals are only decided on: the recorded proposal and its block
+	// parts are fed from the WAL like any other internal message.
+	cs.decideProposal = func(ctx context.Context, height int64, round int32) {
+		var blockID types.BlockID
+		if cs.roundState.ValidBlock() != nil {
+			blockID = types.BlockID{
+				Hash:          cs.roundState.ValidBlock().Hash(),
+				PartSetHeader: cs.roundState.ValidBlockParts().Header(),
+			}
+		} else if recorded, ok := blocks[decisionKey{height, round, tmproto.ProposalType}]; ok {
+			blockID = cs.rebuildProposalBlockID(height, recorded, deps.PubKey.Address())
+		}
+		pv.record(height, round, tmproto.ProposalType, blockID, false)
+	}
+
+	cs.replayMode = true
+	defer func() { cs.replayMode = false }()
+
+	fp, err := os.Open(walPath)
+	if err != nil {
+		return DeterminismReport{}, err
+	}
+	defer fp.Close()
+
+	startHeight := cs.roundState.Height() - 1
+	if cs.roundState.Height() == cs.state.InitialHeight {
+		startHeight = 0
+	}
+
+	var (
+		dec       = NewWALDecoder(fp)
+		started   bool
+		endHeight int64
+		recorded  = make(map[decisionKey]decision)
+		ownAddr   = deps.PubKey.Address()
+	)
+	for {
+		msg, err := dec.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return DeterminismReport{}, err
+		}
+
+		if m, ok := msg.Msg.(EndHeightMessage); ok {
+			if m.Height == startHeight {
+				started = true
+			}
+			if started {
+				endHeight = m.Height
+			}
+			continue
+		}
+		if !started {
+			continue
+		}
+
+		if mi, ok := msg.Msg.(msgInfo); ok {
+			if mi.PeerID == "" {
+				switch m := mi.Msg.(type) {
+				case *ProposalMessage:
+					key := decisionKey{m.Proposal.Height, m.Proposal.Round, tmproto.ProposalType}
+					if _, ok := recorded[key]; !ok {
+						recorded[key] = decision{blockID: m.Proposal.BlockID}
+					}
+				case *VoteMessage:
+					if bytes.Equal(m.Vote.ValidatorAddress, ownAddr) {
+						key := decisionKey{m.Vote.Height, m.Vote.Round, m.Vote.Type}
+						if _, ok := recorded[key]; !ok {
+							recorded[key] = decision{blockID: m.Vote.BlockID, extension: len(m.Vote.Extension) > 0}
+						}
+					}
+				}
+			}
+		}
+
+		if err := cs.readReplayMessage(ctx, msg, nil); err != nil {
+			return DeterminismReport{}, err
+		}
+	}
+	if !started {
+		return DeterminismReport{}, fmt.Errorf("WAL does not contain #ENDHEIGHT %d", startHeight)
+	}
+	// the votes decided on last may still be waiting for the signer
+	cs.voteSigner.wait()
+
+	return newDeterminismReport(recorded, pv.decisions(), endHeight), nil
+}
+
+// recordedProposalBlocks returns the blocks of the proposals this node
+// recorded in the WAL at walPath, by height and round. The proposals whose
+// block parts were not all recorded are left out.
+func (cs *State) recordedProposalBlocks(walPath string) (map[decisionKey]*types.Block, error) {
+	fp, err := os.Open(walPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var (
+		dec    = NewWALDecoder(fp)
+		parts  = make(map[decisionKey]*types.PartSet)
+		blocks = make(map[decisionKey]*types.Block)
+	)
+	for {
+		msg, err := dec.Decode()
+		if err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		mi, ok := msg.Msg.(msgInfo)
+		if !ok || mi.PeerID != "" {
+			continue
+		}
+		switch m := mi.Msg.(type) {
+		case *ProposalMessage:
+			key := decisionKey{m.Proposal.Height, m.Proposal.Round, tmproto.ProposalType}
+			if _, ok := parts[key]; !ok {
+				parts[key] = types.NewPartSetFromHeader(m.Proposal.BlockID.PartSetHeader)
+			}
+		case *BlockPartMessage:
+			key := decisionKey{m.Height, m.Round, tmproto.ProposalType}
+			ps, ok := parts[key]
+			if !ok || blocks[key] != nil {
+				continue
+			}
+			if _, err := ps.AddPart(m.Part); err != nil {
+				return nil, fmt.Errorf("block part of the proposal at %d/%d: %w", m.Height, m.Round, err)
+			}
+			if ps.IsComplete() {
+				block, err := cs.blockFromParts(ps)
+				if err != nil {
+					return nil, fmt.Errorf("block of the proposal at %d/%d: %w", m.Height, m.Round, err)
+				}
+				blocks[key] = block
+			}
+		}
+	}
+}
+
+// rebuildProposalBlockID returns the BlockID of the block this node proposes
+// at height, the current height, with the transactions, evidence and time of
+// recorded, the block it proposed when the WAL was recorded. It returns a nil
+// BlockID if no block can be proposed.
+func (cs *State) rebuildProposalBlockID(height int64, recorded *types.Block, proposerAddr []byte) types.BlockID {
+	lastExtCommit := &types.ExtendedCommit{}
+	if height != cs.state.InitialHeight {
+		if !cs.roundState.LastCommit().HasTwoThirdsMajority() {
+			return types.BlockID{}
+		}
+		lastExtCommit = cs.roundState.LastCommit().MakeExtendedCommit()
+	}
+	block := cs.state.MakeBlock(height, recorded.Txs, lastExtCommit.ToCommit(), recorded.Evidence, proposerAddr)
+	block.Header.Time = recorded.Header.Time
+	parts, err := cs.makeProposalBlockParts(block)
+	if err != nil {
+		return types.BlockID{}
+	}
+	return types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+}
+
+func newDeterminismReport(recorded, replayed map[decisionKey]decision, endHeight int64) DeterminismReport {
+	keys := make(map[decisionKey]struct{}, len(recorded))
+	for k := range recorded {
+		keys[k] = struct{}{}
+	}
+	for k := range replayed {
+		keys[k] = struct{}{}
+	}
+
+	report := DeterminismReport{Decisions: make([]DecisionComparison, 0, len(keys))}
+	for k := range keys {
+		d := DecisionComparison{
+			Height:  k.height,
+			Round:   k.round,
+			Type:    k.msgType,
+			Partial: k.height > endHeight,
+		}
+		if r, ok := recorded[k]; ok {
+			blockID := r.blockID
+			d.Recorded = &blockID
+			d.RecordedExtension = r.extension
+		}
+		if r, ok := replayed[k]; ok {
+			blockID := r.blockID
+			d.Replayed = &blockID
+			d.ReplayedExtension = r.extension
+		}
+		report.Decisions = append(report.Decisions, d)
+	}
+
+	sort.Slice(report.Decisions, func(i, j int) bool {
+		a, b := report.Decisions[i], report.Decisions[j]
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		return a.Type < b.Type
+	})
+	return report
+}
+
+type decisionKey struct {
+	height  int64
+	round   int32
+	msgType tmproto.SignedMsgType
+}
+
+type decision struct {
+	blockID   types.BlockID
+	extension bool
+}
+
+// recordingPrivValidator records the first signing request for every
+// height, round and type and refuses to sign.
+type recordingPrivValidator struct {
+	pubKey crypto.PubKey
+
+	mtx      sync.Mutex
+	recorded map[decisionKey]decision
+}
+
+var _ types.PrivValidator = (*recordingPrivValidator)(nil)
+
+func newRecordingPrivValidator(pubKey crypto.PubKey) *recordingPrivValidator {
+	return &recordingPrivValidator{
+		pubKey:   pubKey,
+		recorded: make(map[decisionKey]decision),
+	}
+}
+
+func (pv *recordingPrivValidator) GetPubKey(context.Context) (crypto.PubKey, error) {
+	return pv.pubKey, nil
+}
+
+func (pv *recordingPrivValidator) SignVote(_ context.Context, _ string, vote *tmproto.Vote) error {
+	blockID, err := types.BlockIDFromProto(&vote.BlockID)
+	if err != nil {
+		return err
+	}
+	pv.record(vote.Height, vote.Round, vote.Type, *blockID, len(vote.Extension) > 0)
+	return errRecordingPrivValidator
+}
+
+func (pv *recordingPrivValidator) SignProposal(_ context.Context, _ string, proposal *tmproto.Proposal) error {
+	return errRecordingPrivValidator
+}
+
+func (pv *recordingPrivValidator) record(
+	height int64,
+	round int32,
+	msgType tmproto.SignedMsgType,
+	blockID types.BlockID,
+	extension bool,
+) {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	key := decisionKey{height, round, msgType}
+	if _, ok := pv.recorded[key]; !ok {
+		pv.recorded[key] = decision{blockID: blockID, extension: extension}
+	}
+}
+
+func (pv *recordingPrivValidator) decisions() map[decisionKey]decision {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	decisions := make(map[decisionKey]decision, len(pv.recorded))
+	for k, v := range pv.recorded {
+		decisions[k] = v
+	}
+	return decisions
+}
+
+// walReplayTicker drops every scheduled timeout. While verifying a WAL,
+// timeouts are driven exclusively by the recorded timeoutInfo entries.
+type walReplayTicker struct {
+	c chan timeoutInfo
+}
+
+func (t *walReplayTicker) Start(context.Context) error { return nil }
+func (t *walReplayTicker) Stop()                       {}
+func (t *walReplayTicker) IsRunning() bool             { return false }
+func (t *walReplayTicker) ScheduleTimeout(timeoutInfo) {}
+func (t *walReplayTicker) Chan() <-chan timeoutInfo    { return t.c }
@@ -0,0 +1,266 @@
+package consensus
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	sm "github.com/tendermint/tendermint/internal/state"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Run go test -update from within this package to record the WAL fixtures
+// in testdata again.
+var update = flag.Bool("update", false, "update the WAL fixtures in testdata")
+
+// rejectingApplication rejects every proposal, so that re-executing a WAL
+// recorded against a kvstore application diverges at every prevote.
+type rejectingApplication struct {
+	*kvstore.Application
+}
+
+func (rejectingApplication) ProcessProposal(context.Context, *abci.RequestProcessProposal) (*abci.ResponseProcessProposal, error) {
+	return &abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_REJECT}, nil
+}
+
+// rejectingOnceApplication rejects the first proposal at height, so that the
+// WAL recorded against it diverges from a kvstore application at the prevote
+// of round 0 of height.
+type rejectingOnceApplication struct {
+	*kvstore.Application
+	height int64
+
+	mtx      sync.Mutex
+	rejected bool
+}
+
+func (app *rejectingOnceApplication) ProcessProposal(
+	ctx context.Context,
+	req *abci.RequestProcessProposal,
+) (*abci.ResponseProcessProposal, error) {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	if req.Height == app.height && !app.rejected {
+		app.rejected = true
+		return &abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_REJECT}, nil
+	}
+	return app.Application.ProcessProposal(ctx, req)
+}
+
+// walVerifyGenesisState returns the genesis state the WAL fixtures of the
+// determinism verification are recorded from, of a single validator whose
+// key is derived from a fixed secret.
+func walVerifyGenesisState(t *testing.T) (sm.State, types.PrivValidator) {
+	t.Helper()
+	key := ed25519.GenPrivKeyFromSecret([]byte("wal-verify"))
+	genDoc := &types.GenesisDoc{
+		ChainID:         walFixtureChainID,
+		GenesisTime:     fixedTime,
+		ConsensusParams: types.DefaultConsensusParams(),
+		Validators:      []types.GenesisValidator{{PubKey: key.PubKey(), Power: 10}},
+	}
+	require.NoError(t, genDoc.ValidateAndComplete())
+	state, err := sm.MakeGenesisState(genDoc)
+	require.NoError(t, err)
+	return state, types.NewMockPVWithParams(key, false, false)
+}
+
+// recordWAL runs a single validator on app until numBlocks blocks are
+// committed and returns the WAL it wrote.
+func recordWAL(
+	ctx context.Context,
+	t *testing.T,
+	cfg *config.Config,
+	state sm.State,
+	pv types.PrivValidator,
+	app abci.Application,
+	numBlocks int64,
+) []byte {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	walFile := filepath.Join(t.TempDir(), "wal")
+	cs := newStateWithConfig(ctx, t, log.NewNopLogger(), cfg, state, pv, app)
+	wal, err := cs.OpenWAL(ctx, walFile)
+	require.NoError(t, err)
+	cs.wal = wal
+
+	newBlockCh := subscribe(ctx, t, cs.eventBus, types.EventQueryNewBlock)
+	require.NoError(t, cs.Start(ctx))
+	for h := int64(1); h <= numBlocks; h++ {
+		ensureNewBlock(t, newBlockCh, h)
+	}
+
+	cancel()
+	wal.Wait()
+	cs.Wait()
+	data, err := os.ReadFile(walFile)
+	require.NoError(t, err)
+	return data
+}
+
+// walVerifyFixture returns the path of the WAL fixture name, recording it
+// first on app with -update.
+func walVerifyFixture(
+	ctx context.Context,
+	t *testing.T,
+	cfg *config.Config,
+	name string,
+	app abci.Application,
+) string {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		t.Logf("Updating WAL fixture %s", path)
+		state, pv := walVerifyGenesisState(t)
+		require.NoError(t, os.MkdirAll("testdata", 0755))
+		require.NoError(t, os.WriteFile(path, recordWAL(ctx, t, cfg, state, pv, app, 3), 0644))
+	}
+	return path
+}
+
+func TestVerifyWALDeterminism(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := configSetup(t)
+	kvstoreWAL := walVerifyFixture(ctx, t, cfg, "wal_verify_kvstore.wal", kvstore.NewApplication())
+	divergingWAL := walVerifyFixture(ctx, t, cfg, "wal_verify_rejected_once.wal",
+		&rejectingOnceApplication{Application: kvstore.NewApplication(), height: 2})
+
+	state, pv := walVerifyGenesisState(t)
+	pubKey, err := pv.GetPubKey(ctx)
+	require.NoError(t, err)
+
+	// the recorded prevote was for the block, the replayed one for nil
+	prevoteRejected := func(t *testing.T, d DecisionComparison) {
+		require.NotNil(t, d.Recorded)
+		require.False(t, d.Recorded.IsNil())
+		require.NotNil(t, d.Replayed)
+		require.True(t, d.Replayed.IsNil())
+	}
+
+	testCases := []struct {
+		name        string
+		wal         string
+		app         abci.Application
+		divergences []DecisionComparison
+		check       func(*testing.T, DecisionComparison)
+	}{
+		{
+			name: "same application",
+			wal:  kvstoreWAL,
+			app:  kvstore.NewApplication(),
+		},
+		{
+			name: "rejecting application",
+			wal:  kvstoreWAL,
+			app:  rejectingApplication{kvstore.NewApplication()},
+			divergences: []DecisionComparison{
+				{Height: 1, Round: 0, Type: tmproto.PrevoteType},
+				{Height: 2, Round: 0, Type: tmproto.PrevoteType},
+				{Height: 3, Round: 0, Type: tmproto.PrevoteType},
+			},
+			check: prevoteRejected,
+		},
+		{
+			name: "diverging WAL",
+			wal:  divergingWAL,
+			app:  kvstore.NewApplication(),
+			divergences: []DecisionComparison{
+				{Height: 2, Round: 0, Type: tmproto.PrevoteType},
+			},
+			check: func(t *testing.T, d DecisionComparison) {
+				// the recorded prevote was for nil, the replayed one for the
+				// block
+				require.NotNil(t, d.Recorded)
+				require.True(t, d.Recorded.IsNil())
+				require.NotNil(t, d.Replayed)
+				require.False(t, d.Replayed.IsNil())
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The state used to build the dependencies is never started.
+			cs := newStateWithConfig(ctx, t, log.NewNopLogger(), cfg, state, pv, tc.app)
+			report, err := VerifyWALDeterminism(ctx, tc.wal, WALVerificationDeps{
+				Config:     cfg.Consensus,
+				StateStore: cs.stateStore,
+				BlockStore: cs.blockStore,
+				BlockExec:  cs.blockExec,
+				EventBus:   cs.eventBus,
+				PubKey:     pubKey,
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, report.Decisions)
+
+			divergences := report.Divergences()
+			require.Len(t, divergences, len(tc.divergences))
+			require.Equal(t, len(tc.divergences) == 0, report.IsDeterministic())
+			for i, d := range divergences {
+				require.Equal(t, tc.divergences[i].Height, d.Height)
+				require.Equal(t, tc.divergences[i].Round, d.Round)
+				require.Equal(t, tc.divergences[i].Type, d.Type)
+				tc.check(t, d)
+			}
+
+			// every committed height has a matching precommit, and the
+			// proposals are rebuilt as they were recorded
+			var proposals int
+			for _, d := range report.Decisions {
+				if d.Partial {
+					continue
+				}
+				switch d.Type {
+				case tmproto.PrecommitType:
+					require.True(t, d.Matches(), "precommit at %d/%d diverged", d.Height, d.Round)
+				case tmproto.ProposalType:
+					require.True(t, d.Matches(), "proposal at %d/%d diverged", d.Height, d.Round)
+					require.False(t, d.Replayed.IsNil())
+					proposals++
+				}
+			}
+			require.NotZero(t, proposals)
+		})
+	}
+}
+
+func TestDecisionComparisonMatches(t *testing.T) {
+	blockID := types.BlockID{Hash: make([]byte, 32), PartSetHeader: types.PartSetHeader{Total: 1, Hash: make([]byte, 32)}}
+	blockID.Hash[0] = 1
+	nilBlockID := types.BlockID{}
+
+	testCases := []struct {
+		name     string
+		d        DecisionComparison
+		expected bool
+	}{
+		{"same block", DecisionComparison{Type: tmproto.PrevoteType, Recorded: &blockID, Replayed: &blockID}, true},
+		{"other block", DecisionComparison{Type: tmproto.PrevoteType, Recorded: &blockID, Replayed: &nilBlockID}, false},
+		{"recorded only", DecisionComparison{Type: tmproto.PrevoteType, Recorded: &blockID}, false},
+		{"proposal replayed nil", DecisionComparison{Type: tmproto.ProposalType, Recorded: &blockID, Replayed: &nilBlockID}, false},
+		{"proposal not replayed", DecisionComparison{Type: tmproto.ProposalType, Recorded: &blockID}, false},
+		{"extension differs", DecisionComparison{
+			Type: tmproto.PrecommitType, Recorded: &blockID, Replayed: &blockID, RecordedExtension: true,
+		}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.d.Matches())
+		})
+	}
+}
@@ -179,18 +179,78 @@ func (b *EventBus) PublishEventCompleteProposal(data types.EventDataCompleteProp
 	return b.Publish(types.EventCompleteProposalValue, data)
 }
 
+func (b *EventBus) PublishEventBlockApplied(data types.EventDataBlockApplied) error {
+	return b.Publish(types.EventBlockAppliedValue, data)
+}
+
+func (b *EventBus) PublishEventBlockGossip(data types.EventDataBlockGossip) error {
+	return b.Publish(types.EventBlockGossipValue, data)
+}
+
+func (b *EventBus) PublishEventConflictingSelfVote(data types.EventDataConflictingSelfVote) error {
+	return b.Publish(types.EventConflictingSelfVoteValue, data)
+}
+
+func (b *EventBus) PublishEventConsensusStalled(data types.EventDataConsensusStalled) error {
+	return b.Publish(types.EventConsensusStalledValue, data)
+}
+
+func (b *EventBus) PublishEventDoubleSignRefusal(data types.EventDataDoubleSignRefusal) error {
+	return b.Publish(types.EventDoubleSignRefusalValue, data)
+}
+
+func (b *EventBus) PublishEventVoteExtensionRejected(data types.EventDataVoteExtensionRejected) error {
+	return b.Publish(types.EventVoteExtensionRejectedValue, data)
+}
+
+func (b *EventBus) PublishEventHeightSummary(data types.EventDataHeightSummary) error {
+	return b.Publish(types.EventHeightSummaryValue, data)
+}
+
+func (b *EventBus) PublishEventStepBudgetExceeded(data types.EventDataStepBudgetExceeded) error {
+	return b.Publish(types.EventStepBudgetExceededValue, data)
+}
+
+func (b *EventBus) PublishEventPartSetMismatch(data types.EventDataPartSetMismatch) error {
+	return b.Publish(types.EventPartSetMismatchValue, data)
+}
+
+func (b *EventBus) PublishEventPrecommitsExcluded(data types.EventDataPrecommitsExcluded) error {
+	return b.Publish(types.EventPrecommitsExcludedValue, data)
+}
+
+func (b *EventBus) PublishEventRebuildDivergence(data types.EventDataRebuildDivergence) error {
+	return b.Publish(types.EventRebuildDivergenceValue, data)
+}
+
 func (b *EventBus) PublishEventPolka(data types.EventDataRoundState) error {
 	return b.Publish(types.EventPolkaValue, data)
 }
 
-func (b *EventBus) PublishEventRelock(data types.EventDataRoundState) error {
+func (b *EventBus) PublishEventRelock(data types.EventDataLock) error {
 	return b.Publish(types.EventRelockValue, data)
 }
 
-func (b *EventBus) PublishEventLock(data types.EventDataRoundState) error {
+func (b *EventBus) PublishEventRoundSkip(data types.EventDataRoundSkip) error {
+	return b.Publish(types.EventRoundSkipValue, data)
+}
+
+func (b *EventBus) PublishEventLock(data types.EventDataLock) error {
 	return b.Publish(types.EventLockValue, data)
 }
 
+func (b *EventBus) PublishEventValidatorSetDiff(data types.EventDataValidatorSetDiff) error {
+	return b.Publish(types.EventValidatorSetDiffValue, data)
+}
+
+func (b *EventBus) PublishEventValidatorAdded(data types.EventDataValidatorMembership) error {
+	return b.Publish(types.EventValidatorAddedValue, data)
+}
+
+func (b *EventBus) PublishEventValidatorRemoved(data types.EventDataValidatorMembership) error {
+	return b.Publish(types.EventValidatorRemovedValue, data)
+}
+
 func (b *EventBus) PublishEventValidatorSetUpdates(data types.EventDataValidatorSetUpdates) error {
 	return b.Publish(types.EventValidatorSetUpdatesValue, data)
 }
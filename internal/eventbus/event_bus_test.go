@@ -418,9 +418,24 @@ func TestEventBusPublish(t *testing.T) {
 	require.NoError(t, eventBus.PublishEventTimeoutWait(types.EventDataRoundState{}))
 	require.NoError(t, eventBus.PublishEventNewRound(types.EventDataNewRound{}))
 	require.NoError(t, eventBus.PublishEventCompleteProposal(types.EventDataCompleteProposal{}))
+	require.NoError(t, eventBus.PublishEventBlockApplied(types.EventDataBlockApplied{}))
+	require.NoError(t, eventBus.PublishEventBlockGossip(types.EventDataBlockGossip{}))
+	require.NoError(t, eventBus.PublishEventConflictingSelfVote(types.EventDataConflictingSelfVote{}))
+	require.NoError(t, eventBus.PublishEventConsensusStalled(types.EventDataConsensusStalled{}))
+	require.NoError(t, eventBus.PublishEventDoubleSignRefusal(types.EventDataDoubleSignRefusal{}))
+	require.NoError(t, eventBus.PublishEventHeightSummary(types.EventDataHeightSummary{}))
+	require.NoError(t, eventBus.PublishEventStepBudgetExceeded(types.EventDataStepBudgetExceeded{}))
+	require.NoError(t, eventBus.PublishEventVoteExtensionRejected(types.EventDataVoteExtensionRejected{}))
+	require.NoError(t, eventBus.PublishEventPartSetMismatch(types.EventDataPartSetMismatch{}))
+	require.NoError(t, eventBus.PublishEventPrecommitsExcluded(types.EventDataPrecommitsExcluded{}))
+	require.NoError(t, eventBus.PublishEventRebuildDivergence(types.EventDataRebuildDivergence{}))
 	require.NoError(t, eventBus.PublishEventPolka(types.EventDataRoundState{}))
-	require.NoError(t, eventBus.PublishEventRelock(types.EventDataRoundState{}))
-	require.NoError(t, eventBus.PublishEventLock(types.EventDataRoundState{}))
+	require.NoError(t, eventBus.PublishEventRelock(types.EventDataLock{}))
+	require.NoError(t, eventBus.PublishEventLock(types.EventDataLock{}))
+	require.NoError(t, eventBus.PublishEventRoundSkip(types.EventDataRoundSkip{}))
+	require.NoError(t, eventBus.PublishEventValidatorSetDiff(types.EventDataValidatorSetDiff{}))
+	require.NoError(t, eventBus.PublishEventValidatorAdded(types.EventDataValidatorMembership{}))
+	require.NoError(t, eventBus.PublishEventValidatorRemoved(types.EventDataValidatorMembership{}))
 	require.NoError(t, eventBus.PublishEventValidatorSetUpdates(types.EventDataValidatorSetUpdates{}))
 	require.NoError(t, eventBus.PublishEventBlockSyncStatus(types.EventDataBlockSyncStatus{}))
 	require.NoError(t, eventBus.PublishEventStateSyncStatus(types.EventDataStateSyncStatus{}))
@@ -354,6 +354,40 @@ func (g *Group) NewReader(index int) (*GroupReader, error) {
 	return r, nil
 }
 
+// NewReaderAt returns a new group reader positioned offset bytes into the
+// file at index.
+// CONTRACT: Caller must close the returned GroupReader.
+func (g *Group) NewReaderAt(index int, offset int64) (*GroupReader, error) {
+	r, err := g.NewReader(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.seek(offset); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Position returns the index of the head and the offset in it at which the
+// next write will be, including the buffered data.
+func (g *Group) Position() (index int, offset int64, err error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	size, err := g.Head.Size()
+	if err != nil {
+		return 0, 0, err
+	}
+	return g.maxIndex, size + int64(g.headBuf.Buffered()), nil
+}
+
+// FilePath returns the path of the file at index.
+func (g *Group) FilePath(index int) string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return filePathForIndex(g.Head.Path, index, g.maxIndex)
+}
+
 // GroupInfo holds information about the group.
 type GroupInfo struct {
 	MinIndex  int   // index of the first file in the group, including head
@@ -541,6 +575,18 @@ func (gr *GroupReader) openFile(index int) error {
 	return nil
 }
 
+// seek moves the cursor offset bytes into the current file.
+func (gr *GroupReader) seek(offset int64) error {
+	gr.mtx.Lock()
+	defer gr.mtx.Unlock()
+	if _, err := gr.curFile.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	gr.curReader.Reset(gr.curFile)
+	gr.curLine = nil
+	return nil
+}
+
 // CurIndex returns cursor's file index.
 func (gr *GroupReader) CurIndex() int {
 	gr.mtx.Lock()
@@ -244,6 +244,44 @@ func TestGroupReaderRead(t *testing.T) {
 	destroyTestGroup(t, g)
 }
 
+// test that a reader created at the position of a write reads from it, across
+// the files of the group.
+func TestGroupReaderAtPosition(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := createTestGroupWithHeadSizeLimit(ctx, t, logger, 0)
+
+	_, err := g.Write([]byte("Professor "))
+	require.NoError(t, err)
+	index, offset, err := g.Position()
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.EqualValues(t, len("Professor "), offset)
+	_, err = g.Write([]byte("Monster"))
+	require.NoError(t, err)
+	require.NoError(t, g.FlushAndSync())
+	g.rotateFile(ctx)
+	_, err = g.Write([]byte("Frankenstein"))
+	require.NoError(t, err)
+	require.NoError(t, g.FlushAndSync())
+	assert.Equal(t, g.Head.Path+".000", g.FilePath(0))
+	assert.Equal(t, g.Head.Path, g.FilePath(1))
+
+	gr, err := g.NewReaderAt(index, offset)
+	require.NoError(t, err, "failed to create reader")
+	read := make([]byte, len("MonsterFrankenstein"))
+	_, err = gr.Read(read)
+	assert.NoError(t, err, "failed to read data")
+	assert.Equal(t, "MonsterFrankenstein", string(read))
+	require.NoError(t, gr.Close())
+
+	// Cleanup
+	destroyTestGroup(t, g)
+}
+
 // test that Read returns an error if number of bytes read < size of
 // the given slice. Subsequent call should return 0, io.EOF.
 func TestGroupReaderRead2(t *testing.T) {
@@ -2,7 +2,9 @@ package core
 
 import (
 	"context"
+	"errors"
 
+	"github.com/tendermint/tendermint/internal/consensus"
 	tmmath "github.com/tendermint/tendermint/libs/math"
 	"github.com/tendermint/tendermint/rpc/coretypes"
 )
@@ -81,9 +83,15 @@ func (env *Environment) DumpConsensusState(ctx context.Context) (*coretypes.Resu
 	if err != nil {
 		return nil, err
 	}
+	// The debug snapshot is omitted while it is rate limited.
+	debug, err := env.ConsensusState.DebugDump()
+	if err != nil && !errors.Is(err, consensus.ErrDebugDumpRateLimited) {
+		return nil, err
+	}
 	return &coretypes.ResultDumpConsensusState{
 		RoundState: roundState,
 		Peers:      peerStates,
+		Debug:      debug,
 	}, nil
 }
 
@@ -55,6 +55,10 @@ type consensusState interface {
 	GetLastHeight() int64
 	GetRoundStateJSON() ([]byte, error)
 	GetRoundStateSimpleJSON() ([]byte, error)
+	DebugDump() ([]byte, error)
+	GetLastActivityAge() time.Duration
+	GetLastProposalLatency() (consensus.ProposalLatency, bool)
+	GetLastIgnoredStateUpdate() (consensus.IgnoredStateUpdate, bool)
 }
 
 type peerManager interface {
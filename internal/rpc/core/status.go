@@ -33,6 +33,7 @@ func (env *Environment) Status(ctx context.Context) (*coretypes.ResultStatus, er
 		latestBlockHash     tmbytes.HexBytes
 		latestAppHash       tmbytes.HexBytes
 		latestBlockTimeNano int64
+		latestProposer      tmbytes.HexBytes
 
 		latestHeight = env.BlockStore.Height()
 	)
@@ -42,6 +43,7 @@ func (env *Environment) Status(ctx context.Context) (*coretypes.ResultStatus, er
 			latestBlockHash = latestBlockMeta.BlockID.Hash
 			latestAppHash = latestBlockMeta.Header.AppHash
 			latestBlockTimeNano = latestBlockMeta.Header.Time.UnixNano()
+			latestProposer = latestBlockMeta.Header.ProposerAddress
 		}
 	}
 
@@ -90,6 +92,33 @@ func (env *Environment) Status(ctx context.Context) (*coretypes.ResultStatus, er
 		result.SyncInfo.CatchingUp = env.ConsensusReactor.WaitSync()
 	}
 
+	if env.ConsensusState != nil {
+		result.SyncInfo.ConsensusLastActivityAge = env.ConsensusState.GetLastActivityAge()
+
+		latency, ok := env.ConsensusState.GetLastProposalLatency()
+		if ok && env.PubKey != nil && latency.Height == latestHeight &&
+			bytes.Equal(latestProposer, env.PubKey.Address()) {
+			result.ProposalLatency = &coretypes.ProposalLatencyInfo{
+				Height:                  latency.Height,
+				Round:                   latency.Round,
+				SignedToProcessed:       latency.SignedToProcessed,
+				ProcessedToFirstPrevote: latency.ProcessedToFirstPrevote,
+				FirstPrevoteToQuorum:    latency.FirstPrevoteToQuorum,
+				SignedToQuorum:          latency.SignedToQuorum,
+			}
+		}
+
+		if ignored, ok := env.ConsensusState.GetLastIgnoredStateUpdate(); ok {
+			result.LastIgnoredStateUpdate = &coretypes.IgnoredStateUpdateInfo{
+				Source:    ignored.Source,
+				Reason:    ignored.Reason,
+				OldHeight: ignored.OldHeight,
+				NewHeight: ignored.NewHeight,
+				Time:      ignored.Time,
+			}
+		}
+	}
+
 	if env.BlockSyncReactor != nil {
 		result.SyncInfo.MaxPeerBlockHeight = env.BlockSyncReactor.GetMaxPeerBlockHeight()
 		result.SyncInfo.TotalSyncedTime = env.BlockSyncReactor.GetTotalSyncedTime()
@@ -92,12 +92,35 @@ func (blockExec *BlockExecutor) CreateProposalBlock(
 	lastExtCommit *types.ExtendedCommit,
 	proposerAddr []byte,
 ) (*types.Block, error) {
+	block, _, err := blockExec.CreateProposalBlockWithEvidenceLimit(ctx, height, state, lastExtCommit, proposerAddr,
+		state.ConsensusParams.Evidence.MaxBytes)
+	return block, err
+}
+
+// CreateProposalBlockWithEvidenceLimit is CreateProposalBlock with the
+// evidence of the block limited to maxEvidenceBytes if it is below the
+// evidence max bytes of the consensus params. It also returns the bytes of the
+// pending evidence the limit left out of the block.
+func (blockExec *BlockExecutor) CreateProposalBlockWithEvidenceLimit(
+	ctx context.Context,
+	height int64,
+	state State,
+	lastExtCommit *types.ExtendedCommit,
+	proposerAddr []byte,
+	maxEvidenceBytes int64,
+) (*types.Block, int64, error) {
 
 	maxBytes := state.ConsensusParams.Block.MaxBytes
 	maxGas := state.ConsensusParams.Block.MaxGas
 	maxGasWanted := state.ConsensusParams.Block.MaxGasWanted
 
 	evidence, evSize := blockExec.evpool.PendingEvidence(state.ConsensusParams.Evidence.MaxBytes)
+	var trimmedEvSize int64
+	if evSize > maxEvidenceBytes {
+		var limitedSize int64
+		evidence, limitedSize = blockExec.evpool.PendingEvidence(maxEvidenceBytes)
+		trimmedEvSize, evSize = evSize-limitedSize, limitedSize
+	}
 
 	// Fetch a limited amount of valid txs
 	maxDataBytes := types.MaxDataBytes(maxBytes, evSize, state.Validators.Size())
@@ -137,12 +160,12 @@ func (blockExec *BlockExecutor) CreateProposalBlock(
 		// Either way, we cannot recover in a meaningful way, unless we skip proposing
 		// this block, repair what caused the error and try again. Hence, we return an
 		// error for now (the production code calling this function is expected to panic).
-		return nil, err
+		return nil, 0, err
 	}
 	txrSet := types.NewTxRecordSet(rpp.TxRecords)
 
 	if err := txrSet.Validate(maxDataBytes, block.Txs); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	for _, rtx := range txrSet.RemovedTxs() {
@@ -151,7 +174,7 @@ func (blockExec *BlockExecutor) CreateProposalBlock(
 		}
 	}
 	itxs := txrSet.IncludedTxs()
-	return state.MakeBlock(height, itxs, commit, evidence, proposerAddr), nil
+	return state.MakeBlock(height, itxs, commit, evidence, proposerAddr), trimmedEvSize, nil
 }
 
 func (blockExec *BlockExecutor) GetTxsForKeys(txKeys []types.TxKey) types.Txs {
@@ -218,6 +241,13 @@ func (blockExec *BlockExecutor) ValidateBlock(ctx context.Context, state State,
 	return nil
 }
 
+// RevalidateBlock validates the given block against the given state like
+// ValidateBlock, even if it was already validated in the height.
+func (blockExec *BlockExecutor) RevalidateBlock(ctx context.Context, state State, block *types.Block) error {
+	delete(blockExec.cache, block.Hash().String())
+	return blockExec.ValidateBlock(ctx, state, block)
+}
+
 // ApplyBlock validates the block against the state, executes it against the app,
 // fires the relevant events, commits the app, and saves the new state and responses.
 // It returns the new state.
@@ -568,6 +598,18 @@ func buildExtendedCommitInfo(ec *types.ExtendedCommit, store Store, initialHeigh
 		))
 	}
 
+	// Check if vote extensions were enabled during the commit's height: ec.Height.
+	// ec is the commit from the previous height, so if extensions were enabled
+	// during that height, we ensure they are present for +2/3 of the voting
+	// power and deliver the data to the proposer. Extensions filtered out of
+	// the stored commit are delivered as absent.
+	extensionsEnabled := ap.VoteExtensionsEnabled(ec.Height)
+	if extensionsEnabled {
+		if err := ec.EnsureQuorumExtensions(valSet); err != nil {
+			panic(fmt.Errorf("commit at height %d received with missing vote extensions data: %w", ec.Height, err))
+		}
+	}
+
 	votes := make([]abci.ExtendedVoteInfo, ecSize)
 	for i, val := range valSet.Validators {
 		ecs := ec.ExtendedSignatures[i]
@@ -581,15 +623,7 @@ func buildExtendedCommitInfo(ec *types.ExtendedCommit, store Store, initialHeigh
 		}
 
 		var ext []byte
-		// Check if vote extensions were enabled during the commit's height: ec.Height.
-		// ec is the commit from the previous height, so if extensions were enabled
-		// during that height, we ensure they are present and deliver the data to
-		// the proposer. If they were not enabled during this previous height, we
-		// will not deliver extension data.
-		if ap.VoteExtensionsEnabled(ec.Height) && ecs.BlockIDFlag == types.BlockIDFlagCommit {
-			if err := ecs.EnsureExtension(); err != nil {
-				panic(fmt.Errorf("commit at height %d received with missing vote extensions data", ec.Height))
-			}
+		if extensionsEnabled && ecs.BlockIDFlag == types.BlockIDFlagCommit {
 			ext = ecs.Extension
 		}
 
@@ -823,13 +857,14 @@ func ExecCommitBlock(
 			return nil, err
 		}
 
-		bps, err := block.MakePartSet(types.BlockPartSizeBytes)
-		if err != nil {
-			return nil, err
+		// the parts of the block may be compressed by a block part codec, so
+		// its BlockID is the one of the block store
+		blockMeta := be.blockStore.LoadBlockMeta(block.Height)
+		if blockMeta == nil {
+			return nil, fmt.Errorf("no block meta at height %d", block.Height)
 		}
 
-		blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: bps.Header()}
-		FireEvents(be.logger, be.eventBus, block, blockID, finalizeBlockResponse, validatorUpdates)
+		FireEvents(be.logger, be.eventBus, block, blockMeta.BlockID, finalizeBlockResponse, validatorUpdates)
 	}
 
 	// Commit block
@@ -804,6 +804,74 @@ func TestPrepareProposalReorderTxs(t *testing.T) {
 
 }
 
+// stuffedPendingEvidence returns the functions of a mock evidence pool whose
+// pending evidence is the longest prefix of evidence within the max bytes.
+func stuffedPendingEvidence(evidence []types.Evidence) (func(int64) []types.Evidence, func(int64) int64) {
+	prefix := func(maxBytes int64) int {
+		n := 0
+		for n < len(evidence) && types.EvidenceList(evidence[:n+1]).ByteSize() <= maxBytes {
+			n++
+		}
+		return n
+	}
+	return func(maxBytes int64) []types.Evidence {
+			return evidence[:prefix(maxBytes)]
+		}, func(maxBytes int64) int64 {
+			return types.EvidenceList(evidence[:prefix(maxBytes)]).ByteSize()
+		}
+}
+
+func TestCreateProposalBlockWithEvidenceLimit(t *testing.T) {
+	const height = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewNopLogger()
+	eventBus := eventbus.NewDefault(logger)
+	require.NoError(t, eventBus.Start(ctx))
+
+	state, stateDB, privVals := makeState(t, 1, height)
+	stateStore := sm.NewStore(stateDB)
+
+	evidence := make([]types.Evidence, 20)
+	for i := range evidence {
+		ev, err := types.NewMockDuplicateVoteEvidence(ctx, height-1, state.LastBlockTime, state.ChainID)
+		require.NoError(t, err)
+		evidence[i] = ev
+	}
+	evpool := &mocks.EvidencePool{}
+	evpool.On("PendingEvidence", mock.AnythingOfType("int64")).Return(stuffedPendingEvidence(evidence))
+
+	mp := &mpmocks.Mempool{}
+	mp.On("ReapMaxBytesMaxGas", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(types.Txs{})
+
+	app := abcimocks.NewApplication(t)
+	app.On("PrepareProposal", mock.Anything, mock.Anything).Return(&abci.ResponsePrepareProposal{}, nil)
+
+	cc := abciclient.NewLocalClient(logger, app)
+	proxyApp := proxy.New(cc, logger, proxy.NopMetrics())
+	require.NoError(t, proxyApp.Start(ctx))
+
+	blockExec := sm.NewBlockExecutor(stateStore, logger, proxyApp, mp, evpool, nil, eventBus, sm.NopMetrics())
+	pa, _ := state.Validators.GetByIndex(0)
+	commit, _ := makeValidCommit(ctx, t, height, types.BlockID{}, state.Validators, privVals)
+
+	// all the pending evidence fits within the consensus params
+	block, trimmed, err := blockExec.CreateProposalBlockWithEvidenceLimit(ctx, height, state, commit, pa,
+		state.ConsensusParams.Evidence.MaxBytes)
+	require.NoError(t, err)
+	require.Len(t, block.Evidence, len(evidence))
+	require.Zero(t, trimmed)
+
+	// the evidence above the limit is left out of the block
+	limit := types.EvidenceList(evidence[:5]).ByteSize()
+	block, trimmed, err = blockExec.CreateProposalBlockWithEvidenceLimit(ctx, height, state, commit, pa, limit)
+	require.NoError(t, err)
+	require.Len(t, block.Evidence, 5)
+	require.LessOrEqual(t, block.Evidence.ByteSize(), limit)
+	require.Equal(t, types.EvidenceList(evidence).ByteSize()-limit, trimmed)
+}
+
 // TestPrepareProposalErrorOnTooManyTxs tests that the block creation logic returns
 // an error if the ResponsePrepareProposal returned from the application is invalid.
 func TestPrepareProposalErrorOnTooManyTxs(t *testing.T) {
@@ -137,6 +137,33 @@ func TestValidateBlockHeader(t *testing.T) {
 	assert.Contains(t, err.Error(), "lower than initial height")
 }
 
+func TestRevalidateBlock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := log.NewNopLogger()
+
+	state, stateDB, _ := makeState(t, 1, 1)
+	blockExec := sm.NewBlockExecutor(
+		sm.NewStore(stateDB),
+		logger,
+		nil,
+		&mpmocks.Mempool{},
+		sm.EmptyEvidencePool{},
+		store.NewBlockStore(dbm.NewMemDB()),
+		eventbus.NewDefault(logger),
+		sm.NopMetrics(),
+	)
+	block := statefactory.MakeBlock(state, state.LastBlockHeight+1, &types.Commit{})
+	require.NoError(t, blockExec.ValidateBlock(ctx, state, block))
+
+	// the block validated earlier in the height is not validated again
+	state.ChainID = "other-chain"
+	require.NoError(t, blockExec.ValidateBlock(ctx, state, block))
+	err := blockExec.RevalidateBlock(ctx, state, block)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ChainID")
+}
+
 func TestValidateBlockCommit(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
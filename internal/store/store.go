@@ -135,7 +135,6 @@ func (bs *BlockStore) LoadBlock(height int64) *types.Block {
 		return nil
 	}
 
-	pbb := new(tmproto.Block)
 	buf := []byte{}
 	for i := 0; i < int(blockMeta.BlockID.PartSetHeader.Total); i++ {
 		part := bs.LoadBlockPart(height, i)
@@ -146,18 +145,14 @@ func (bs *BlockStore) LoadBlock(height int64) *types.Block {
 		}
 		buf = append(buf, part.Bytes...)
 	}
-	err := proto.Unmarshal(buf, pbb)
+	// the parts may be compressed by a block part codec
+	block, err := types.BlockFromPartsBytes(buf, types.MaxBlockSizeBytes)
 	if err != nil {
 		// NOTE: The existence of meta should imply the existence of the
 		// block. So, make sure meta is only saved after blocks are saved.
 		panic(fmt.Errorf("error reading block: %w", err))
 	}
 
-	block, err := types.BlockFromProto(pbb)
-	if err != nil {
-		panic(fmt.Errorf("error from proto block: %w", err))
-	}
-
 	return block
 }
 
@@ -498,8 +493,9 @@ func (bs *BlockStore) SaveBlockWithExtendedCommit(block *types.Block, blockParts
 	if block == nil {
 		panic("BlockStore can only save a non-nil block")
 	}
-	if err := seenExtendedCommit.EnsureExtensions(); err != nil {
-		panic(fmt.Errorf("saving block with extensions: %w", err))
+	// Consensus may filter out some of the vote extensions, but not all.
+	if !seenExtendedCommit.HasExtensions() {
+		panic(errors.New("saving block with extensions: vote extension data is missing"))
 	}
 	batch := bs.db.NewBatch()
 	if err := bs.saveBlockToBatch(batch, block, blockParts, seenExtendedCommit.ToCommit()); err != nil {
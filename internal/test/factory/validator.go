@@ -21,7 +21,7 @@ func Validator(ctx context.Context, votingPower int64) (*types.Validator, types.
 	return val, privVal, nil
 }
 
-func ValidatorSet(ctx context.Context, t *testing.T, numValidators int, votingPower int64) (*types.ValidatorSet, []types.PrivValidator) {
+func ValidatorSet(ctx context.Context, t testing.TB, numValidators int, votingPower int64) (*types.ValidatorSet, []types.PrivValidator) {
 	var (
 		valz           = make([]*types.Validator, numValidators)
 		privValidators = make([]types.PrivValidator, numValidators)
@@ -361,6 +361,7 @@ func makeNode(
 
 	// Create the blockchain reactor. Note, we do not start block sync if we're
 	// doing a state sync first.
+	blockPartCodec, _ := types.BlockPartCodecByName(cfg.Consensus.BlockPartCodec)
 	bcReactor := blocksync.NewReactor(
 		logger.With("module", "blockchain"),
 		stateStore,
@@ -372,6 +373,7 @@ func makeNode(
 		blockSync && !stateSync && !shoulddbsync,
 		nodeMetrics.consensus,
 		eventBus,
+		blockPartCodec,
 		restartCh,
 		cfg.SelfRemediation,
 	)
@@ -3,6 +3,8 @@ package privval
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 )
 
 // EndpointTimeoutError occurs when endpoint times out.
@@ -22,6 +24,10 @@ var (
 	ErrWriteTimeout       = errors.New("endpoint write timed out")
 )
 
+// ErrConflictingData is returned when asked to sign data that conflicts with
+// the data already signed at the same height, round and step.
+var ErrConflictingData = errors.New("conflicting data")
+
 // RemoteSignerError allows (remote) validators to include meaningful error
 // descriptions in their reply.
 type RemoteSignerError struct {
@@ -33,3 +39,25 @@ type RemoteSignerError struct {
 func (e *RemoteSignerError) Error() string {
 	return fmt.Sprintf("signerEndpoint returned error #%d: %s", e.Code, e.Description)
 }
+
+// IsConflictingDataError returns true if err is a refusal to sign data that
+// conflicts with the data already signed at the same height, round and step,
+// whether it comes from a local or a remote signer.
+func IsConflictingDataError(err error) bool {
+	if errors.Is(err, ErrConflictingData) {
+		return true
+	}
+	var remoteErr *RemoteSignerError
+	return errors.As(err, &remoteErr) && strings.HasSuffix(remoteErr.Description, ErrConflictingData.Error())
+}
+
+// IsTransientError returns true if err is a failure to reach the signer, such
+// that a retry may succeed. Errors returned by the signer itself are never
+// transient.
+func IsTransientError(err error) bool {
+	if errors.Is(err, ErrNoConnection) || errors.Is(err, ErrReadTimeout) || errors.Is(err, ErrWriteTimeout) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,16 @@
+package privval
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, IsTransientError(ErrNoConnection))
+	assert.True(t, IsTransientError(fmt.Errorf("exhausted all attempts to sign proposal: %w", ErrReadTimeout)))
+	assert.True(t, IsTransientError(ErrConnectionTimeout))
+	assert.False(t, IsTransientError(&RemoteSignerError{Description: ErrNoConnection.Error()}))
+	assert.False(t, IsTransientError(ErrConflictingData))
+}
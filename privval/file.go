@@ -326,6 +326,17 @@ func (pv *FilePV) SignProposal(ctx context.Context, chainID string, proposal *tm
 	return nil
 }
 
+// SignHeightEvidencePackage signs off the height evidence package of digest.
+// Implements types.HeightEvidencePackageSigner. The package is not a
+// consensus message, so the last sign state is left untouched.
+func (pv *FilePV) SignHeightEvidencePackage(ctx context.Context, chainID string, digest []byte) ([]byte, error) {
+	sig, err := pv.Key.PrivKey.Sign(types.HeightEvidencePackageSignBytes(chainID, digest))
+	if err != nil {
+		return nil, fmt.Errorf("error signing height evidence package: %w", err)
+	}
+	return sig, nil
+}
+
 // Save persists the FilePV to disk.
 func (pv *FilePV) Save() error {
 	if err := pv.Key.Save(); err != nil {
@@ -405,7 +416,7 @@ func (pv *FilePV) signVote(chainID string, vote *tmproto.Vote) error {
 				return err
 			}
 			if !ok {
-				return errors.New("conflicting data")
+				return ErrConflictingData
 			}
 
 			vote.Timestamp = timestamp
@@ -449,7 +460,7 @@ func (pv *FilePV) signProposal(chainID string, proposal *tmproto.Proposal) error
 	// If signbytes are the same, use the last signature.
 	if sameHRS {
 		if !bytes.Equal(signBytes, lss.SignBytes) {
-			return errors.New("conflicting data")
+			return ErrConflictingData
 		}
 		proposal.Signature = lss.Signature
 		return nil
@@ -234,6 +234,29 @@ func TestSignProposal(t *testing.T) {
 		assert.Errorf(t, privVal.SignProposal(ctx, "mychainid", c.ToProto()),
 			"expected error on signing conflicting proposal")
 	}
+
+	// only proposals for the same height, round and step conflict
+	err = privVal.SignProposal(ctx, "mychainid", newProposal(height, round, block2, ts).ToProto())
+	assert.True(t, IsConflictingDataError(err))
+	assert.True(t, IsConflictingDataError(&RemoteSignerError{Description: err.Error()}))
+	err = privVal.SignProposal(ctx, "mychainid", newProposal(height, round-1, block1, ts).ToProto())
+	assert.False(t, IsConflictingDataError(err))
+}
+
+func TestSignHeightEvidencePackage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	privVal, _, _ := newTestFilePV(t)
+	digest := tmrand.Bytes(crypto.HashSize)
+
+	sig, err := privVal.SignHeightEvidencePackage(ctx, "mychainid", digest)
+	require.NoError(t, err)
+	assert.True(t, privVal.Key.PubKey.VerifySignature(types.HeightEvidencePackageSignBytes("mychainid", digest), sig))
+
+	// signing a package leaves the last sign state untouched
+	assert.Zero(t, privVal.LastSignState.Height)
+	assert.Empty(t, privVal.LastSignState.SignBytes)
 }
 
 func TestDifferByTimestamp(t *testing.T) {
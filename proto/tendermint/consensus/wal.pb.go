@@ -31,8 +31,9 @@ const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
 // MsgInfo are msgs from the reactor which may update the state
 type MsgInfo struct {
-	Msg    Message `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg"`
-	PeerID string  `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Msg         Message   `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg"`
+	PeerID      string    `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	ReceiveTime time.Time `protobuf:"bytes,3,opt,name=receive_time,json=receiveTime,proto3,stdtime" json:"receive_time"`
 }
 
 func (m *MsgInfo) Reset()         { *m = MsgInfo{} }
@@ -82,6 +83,13 @@ func (m *MsgInfo) GetPeerID() string {
 	return ""
 }
 
+func (m *MsgInfo) GetReceiveTime() time.Time {
+	if m != nil {
+		return m.ReceiveTime
+	}
+	return time.Time{}
+}
+
 // TimeoutInfo internally generated messages which may update the state
 type TimeoutInfo struct {
 	Duration time.Duration `protobuf:"bytes,1,opt,name=duration,proto3,stdduration" json:"duration"`
@@ -197,12 +205,67 @@ func (m *EndHeight) GetHeight() int64 {
 	return 0
 }
 
+// RestartAcknowledgment records the operator's acknowledgment, at startup, of
+// a signature of the node's key found in a recent block.
+type RestartAcknowledgment struct {
+	Height          int64 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	SignatureHeight int64 `protobuf:"varint,2,opt,name=signature_height,json=signatureHeight,proto3" json:"signature_height,omitempty"`
+}
+
+func (m *RestartAcknowledgment) Reset()         { *m = RestartAcknowledgment{} }
+func (m *RestartAcknowledgment) String() string { return proto.CompactTextString(m) }
+func (*RestartAcknowledgment) ProtoMessage()    {}
+func (*RestartAcknowledgment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ed0b60c2d348ab09, []int{3}
+}
+func (m *RestartAcknowledgment) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RestartAcknowledgment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RestartAcknowledgment.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RestartAcknowledgment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestartAcknowledgment.Merge(m, src)
+}
+func (m *RestartAcknowledgment) XXX_Size() int {
+	return m.Size()
+}
+func (m *RestartAcknowledgment) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestartAcknowledgment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestartAcknowledgment proto.InternalMessageInfo
+
+func (m *RestartAcknowledgment) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *RestartAcknowledgment) GetSignatureHeight() int64 {
+	if m != nil {
+		return m.SignatureHeight
+	}
+	return 0
+}
+
 type WALMessage struct {
 	// Types that are valid to be assigned to Sum:
 	//	*WALMessage_EventDataRoundState
 	//	*WALMessage_MsgInfo
 	//	*WALMessage_TimeoutInfo
 	//	*WALMessage_EndHeight
+	//	*WALMessage_RestartAcknowledgment
 	Sum isWALMessage_Sum `protobuf_oneof:"sum"`
 }
 
@@ -210,7 +273,7 @@ func (m *WALMessage) Reset()         { *m = WALMessage{} }
 func (m *WALMessage) String() string { return proto.CompactTextString(m) }
 func (*WALMessage) ProtoMessage()    {}
 func (*WALMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ed0b60c2d348ab09, []int{3}
+	return fileDescriptor_ed0b60c2d348ab09, []int{4}
 }
 func (m *WALMessage) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -257,11 +320,15 @@ type WALMessage_TimeoutInfo struct {
 type WALMessage_EndHeight struct {
 	EndHeight *EndHeight `protobuf:"bytes,4,opt,name=end_height,json=endHeight,proto3,oneof" json:"end_height,omitempty"`
 }
+type WALMessage_RestartAcknowledgment struct {
+	RestartAcknowledgment *RestartAcknowledgment `protobuf:"bytes,5,opt,name=restart_acknowledgment,json=restartAcknowledgment,proto3,oneof" json:"restart_acknowledgment,omitempty"`
+}
 
-func (*WALMessage_EventDataRoundState) isWALMessage_Sum() {}
-func (*WALMessage_MsgInfo) isWALMessage_Sum()             {}
-func (*WALMessage_TimeoutInfo) isWALMessage_Sum()         {}
-func (*WALMessage_EndHeight) isWALMessage_Sum()           {}
+func (*WALMessage_EventDataRoundState) isWALMessage_Sum()   {}
+func (*WALMessage_MsgInfo) isWALMessage_Sum()               {}
+func (*WALMessage_TimeoutInfo) isWALMessage_Sum()           {}
+func (*WALMessage_EndHeight) isWALMessage_Sum()             {}
+func (*WALMessage_RestartAcknowledgment) isWALMessage_Sum() {}
 
 func (m *WALMessage) GetSum() isWALMessage_Sum {
 	if m != nil {
@@ -298,6 +365,13 @@ func (m *WALMessage) GetEndHeight() *EndHeight {
 	return nil
 }
 
+func (m *WALMessage) GetRestartAcknowledgment() *RestartAcknowledgment {
+	if x, ok := m.GetSum().(*WALMessage_RestartAcknowledgment); ok {
+		return x.RestartAcknowledgment
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*WALMessage) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -305,6 +379,7 @@ func (*WALMessage) XXX_OneofWrappers() []interface{} {
 		(*WALMessage_MsgInfo)(nil),
 		(*WALMessage_TimeoutInfo)(nil),
 		(*WALMessage_EndHeight)(nil),
+		(*WALMessage_RestartAcknowledgment)(nil),
 	}
 }
 
@@ -318,7 +393,7 @@ func (m *TimedWALMessage) Reset()         { *m = TimedWALMessage{} }
 func (m *TimedWALMessage) String() string { return proto.CompactTextString(m) }
 func (*TimedWALMessage) ProtoMessage()    {}
 func (*TimedWALMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ed0b60c2d348ab09, []int{4}
+	return fileDescriptor_ed0b60c2d348ab09, []int{5}
 }
 func (m *TimedWALMessage) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -365,6 +440,7 @@ func init() {
 	proto.RegisterType((*MsgInfo)(nil), "tendermint.consensus.MsgInfo")
 	proto.RegisterType((*TimeoutInfo)(nil), "tendermint.consensus.TimeoutInfo")
 	proto.RegisterType((*EndHeight)(nil), "tendermint.consensus.EndHeight")
+	proto.RegisterType((*RestartAcknowledgment)(nil), "tendermint.consensus.RestartAcknowledgment")
 	proto.RegisterType((*WALMessage)(nil), "tendermint.consensus.WALMessage")
 	proto.RegisterType((*TimedWALMessage)(nil), "tendermint.consensus.TimedWALMessage")
 }
@@ -372,41 +448,46 @@ func init() {
 func init() { proto.RegisterFile("tendermint/consensus/wal.proto", fileDescriptor_ed0b60c2d348ab09) }
 
 var fileDescriptor_ed0b60c2d348ab09 = []byte{
-	// 539 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x53, 0xdd, 0x8a, 0xd3, 0x40,
-	0x14, 0xce, 0x6c, 0xff, 0x4f, 0x15, 0x21, 0x96, 0xa5, 0x16, 0x36, 0x8d, 0x5d, 0x84, 0x5e, 0x25,
-	0xb0, 0x22, 0x88, 0x5e, 0xa8, 0xa5, 0x2b, 0x2d, 0xb8, 0x20, 0xe3, 0x8a, 0x20, 0x42, 0x48, 0x37,
-	0xa7, 0x69, 0x60, 0x33, 0x53, 0x32, 0x13, 0xc5, 0x2b, 0x5f, 0xa1, 0x97, 0xbe, 0x89, 0xaf, 0xb0,
-	0x97, 0x7b, 0xe9, 0xd5, 0x2a, 0xed, 0x8b, 0x48, 0x66, 0xd2, 0x36, 0xb8, 0xd9, 0xbb, 0x39, 0x73,
-	0xbe, 0x73, 0xbe, 0x73, 0xbe, 0x6f, 0x06, 0x2c, 0x89, 0x2c, 0xc0, 0x24, 0x8e, 0x98, 0x74, 0x2f,
-	0x38, 0x13, 0xc8, 0x44, 0x2a, 0xdc, 0x6f, 0xfe, 0xa5, 0xb3, 0x4c, 0xb8, 0xe4, 0x66, 0x67, 0x9f,
-	0x77, 0x76, 0xf9, 0x5e, 0x27, 0xe4, 0x21, 0x57, 0x00, 0x37, 0x3b, 0x69, 0x6c, 0xcf, 0x2e, 0xed,
-	0x25, 0xbf, 0x2f, 0x51, 0xe4, 0x88, 0xa3, 0x02, 0x42, 0xdd, 0xbb, 0xf8, 0x15, 0x99, 0xdc, 0xa6,
-	0xad, 0x90, 0xf3, 0xf0, 0x12, 0x5d, 0x15, 0xcd, 0xd2, 0xb9, 0x1b, 0xa4, 0x89, 0x2f, 0x23, 0xce,
-	0xf2, 0x7c, 0xff, 0xff, 0xbc, 0x8c, 0x62, 0x14, 0xd2, 0x8f, 0x97, 0x1a, 0x30, 0x40, 0x68, 0x9c,
-	0x89, 0x70, 0xca, 0xe6, 0xdc, 0x7c, 0x06, 0x95, 0x58, 0x84, 0x5d, 0x62, 0x93, 0x61, 0xfb, 0xe4,
-	0xc8, 0x29, 0x5b, 0xc3, 0x39, 0x43, 0x21, 0xfc, 0x10, 0x47, 0xd5, 0xab, 0x9b, 0xbe, 0x41, 0x33,
-	0xbc, 0x79, 0x0c, 0x8d, 0x25, 0x62, 0xe2, 0x45, 0x41, 0xf7, 0xc0, 0x26, 0xc3, 0xd6, 0x08, 0xd6,
-	0x37, 0xfd, 0xfa, 0x7b, 0xc4, 0x64, 0x3a, 0xa6, 0xf5, 0x2c, 0x35, 0x0d, 0x06, 0x2b, 0x02, 0xed,
-	0xf3, 0x28, 0x46, 0x9e, 0x4a, 0xc5, 0xf5, 0x0a, 0x9a, 0xdb, 0x49, 0x73, 0xc2, 0x47, 0x8e, 0x1e,
-	0xd5, 0xd9, 0x8e, 0xea, 0x8c, 0x73, 0xc0, 0xa8, 0x99, 0x91, 0xfd, 0xfc, 0xd3, 0x27, 0x74, 0x57,
-	0x64, 0x1e, 0x42, 0x7d, 0x81, 0x51, 0xb8, 0x90, 0x8a, 0xb4, 0x42, 0xf3, 0xc8, 0xec, 0x40, 0x2d,
-	0xe1, 0x29, 0x0b, 0xba, 0x15, 0x9b, 0x0c, 0x6b, 0x54, 0x07, 0xa6, 0x09, 0x55, 0x21, 0x71, 0xd9,
-	0xad, 0xda, 0x64, 0x78, 0x9f, 0xaa, 0xf3, 0xe0, 0x18, 0x5a, 0xa7, 0x2c, 0x98, 0xe8, 0xb2, 0x7d,
-	0x3b, 0x52, 0x6c, 0x37, 0xf8, 0x75, 0x00, 0xf0, 0xe9, 0xcd, 0xbb, 0x7c, 0x6d, 0xf3, 0x0b, 0x1c,
-	0x2a, 0xf9, 0xbd, 0xc0, 0x97, 0xbe, 0xa7, 0x7a, 0x7b, 0x42, 0xfa, 0x12, 0xf3, 0x25, 0x9e, 0x14,
-	0x55, 0xd3, 0x36, 0x9e, 0x66, 0xf8, 0xb1, 0x2f, 0x7d, 0x9a, 0xa1, 0x3f, 0x64, 0xe0, 0x89, 0x41,
-	0x1f, 0xe2, 0xed, 0x6b, 0xf3, 0x05, 0x34, 0x63, 0x11, 0x7a, 0x11, 0x9b, 0x73, 0xb5, 0xd5, 0xdd,
-	0x2e, 0x68, 0xc7, 0x26, 0x06, 0x6d, 0xc4, 0xb9, 0x79, 0x6f, 0xe1, 0x9e, 0xd4, 0xfa, 0xea, 0xfa,
-	0x8a, 0xaa, 0x7f, 0x5c, 0x5e, 0x5f, 0x70, 0x62, 0x62, 0xd0, 0xb6, 0x2c, 0x18, 0xf3, 0x1a, 0x00,
-	0x59, 0xe0, 0xe5, 0x62, 0x54, 0x55, 0x97, 0x7e, 0x79, 0x97, 0x9d, 0x7a, 0x13, 0x83, 0xb6, 0x70,
-	0x1b, 0x8c, 0x6a, 0x50, 0x11, 0x69, 0x3c, 0xf8, 0x01, 0x0f, 0x32, 0x9a, 0xa0, 0xa0, 0xde, 0x73,
-	0xa8, 0x66, 0x54, 0xb9, 0x56, 0xbd, 0x5b, 0x86, 0x9f, 0x6f, 0xdf, 0xa6, 0x76, 0x7c, 0x95, 0x39,
-	0xae, 0x2a, 0xcc, 0x13, 0xfd, 0x34, 0xb5, 0x28, 0x76, 0xf9, 0x38, 0x7b, 0x22, 0xf5, 0x2e, 0x47,
-	0x1f, 0xaf, 0xd6, 0x16, 0xb9, 0x5e, 0x5b, 0xe4, 0xef, 0xda, 0x22, 0xab, 0x8d, 0x65, 0x5c, 0x6f,
-	0x2c, 0xe3, 0xf7, 0xc6, 0x32, 0x3e, 0xbf, 0x0c, 0x23, 0xb9, 0x48, 0x67, 0xce, 0x05, 0x8f, 0xdd,
-	0xe2, 0xf7, 0xda, 0x1f, 0xf5, 0x47, 0x2d, 0xfb, 0x9c, 0xb3, 0xba, 0xca, 0x3d, 0xfd, 0x17, 0x00,
-	0x00, 0xff, 0xff, 0x0b, 0xad, 0x1c, 0x1b, 0x07, 0x04, 0x00, 0x00,
+	// 622 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xdd, 0x6a, 0x14, 0x31,
+	0x18, 0x9d, 0xe9, 0xfe, 0xb4, 0xfd, 0xb6, 0x52, 0x89, 0x6d, 0x59, 0x0b, 0x9d, 0x5d, 0xb7, 0x08,
+	0x15, 0x61, 0x06, 0x2a, 0x82, 0xe8, 0x85, 0x76, 0x69, 0x75, 0x0b, 0x16, 0x24, 0x2a, 0x42, 0x11,
+	0x86, 0xe9, 0xce, 0xd7, 0x74, 0xb0, 0x93, 0x2c, 0x93, 0x4c, 0x8b, 0x57, 0xbe, 0x42, 0x2f, 0xbd,
+	0xf1, 0x15, 0x7c, 0x8e, 0x5e, 0xf6, 0xd2, 0xab, 0x2a, 0xdb, 0x17, 0x91, 0x24, 0xb3, 0x3f, 0xb5,
+	0xa3, 0xe0, 0x5d, 0x92, 0x73, 0xbe, 0x93, 0x93, 0x9c, 0x2f, 0x01, 0x4f, 0x21, 0x8f, 0x31, 0x4b,
+	0x13, 0xae, 0x82, 0xbe, 0xe0, 0x12, 0xb9, 0xcc, 0x65, 0x70, 0x1a, 0x1d, 0xfb, 0x83, 0x4c, 0x28,
+	0x41, 0x96, 0x26, 0xb8, 0x3f, 0xc6, 0x57, 0x97, 0x98, 0x60, 0xc2, 0x10, 0x02, 0x3d, 0xb2, 0xdc,
+	0xd5, 0x76, 0xa9, 0x96, 0xfa, 0x3c, 0x40, 0x59, 0x30, 0xd6, 0xa6, 0x18, 0x66, 0x3d, 0xc0, 0x13,
+	0xe4, 0x6a, 0x04, 0x7b, 0x4c, 0x08, 0x76, 0x8c, 0x81, 0x99, 0x1d, 0xe4, 0x87, 0x41, 0x9c, 0x67,
+	0x91, 0x4a, 0x04, 0x2f, 0xf0, 0xd6, 0x9f, 0xb8, 0x4a, 0x52, 0x94, 0x2a, 0x4a, 0x07, 0x96, 0xd0,
+	0xf9, 0xee, 0xc2, 0xec, 0x9e, 0x64, 0xbb, 0xfc, 0x50, 0x90, 0xc7, 0x50, 0x49, 0x25, 0x6b, 0xba,
+	0x6d, 0x77, 0xa3, 0xb1, 0xb9, 0xe6, 0x97, 0x9d, 0xc3, 0xdf, 0x43, 0x29, 0x23, 0x86, 0xdd, 0xea,
+	0xf9, 0x65, 0xcb, 0xa1, 0x9a, 0x4f, 0xd6, 0x61, 0x76, 0x80, 0x98, 0x85, 0x49, 0xdc, 0x9c, 0x69,
+	0xbb, 0x1b, 0xf3, 0x5d, 0x18, 0x5e, 0xb6, 0xea, 0x6f, 0x10, 0xb3, 0xdd, 0x6d, 0x5a, 0xd7, 0xd0,
+	0x6e, 0x4c, 0x5e, 0xc1, 0x42, 0x86, 0x7d, 0x4c, 0x4e, 0x30, 0xd4, 0x16, 0x9a, 0x15, 0xb3, 0xc9,
+	0xaa, 0x6f, 0xfd, 0xf9, 0x23, 0x7f, 0xfe, 0xbb, 0x91, 0xbf, 0xee, 0x9c, 0xde, 0xe1, 0xec, 0x67,
+	0xcb, 0xa5, 0x8d, 0xa2, 0x52, 0x63, 0x9d, 0x33, 0x17, 0x1a, 0x7a, 0x20, 0x72, 0x65, 0x4c, 0x3f,
+	0x87, 0xb9, 0xd1, 0x99, 0x0b, 0xe7, 0x77, 0x6f, 0x88, 0x6e, 0x17, 0x04, 0xab, 0xf9, 0x55, 0x6b,
+	0x8e, 0x8b, 0xc8, 0x0a, 0xd4, 0x8f, 0x30, 0x61, 0x47, 0xca, 0xb8, 0xaf, 0xd0, 0x62, 0x46, 0x96,
+	0xa0, 0x96, 0x89, 0x9c, 0xc7, 0xc6, 0x6a, 0x8d, 0xda, 0x09, 0x21, 0x50, 0x95, 0x0a, 0x07, 0xcd,
+	0x6a, 0xdb, 0xdd, 0xb8, 0x45, 0xcd, 0xb8, 0xb3, 0x0e, 0xf3, 0x3b, 0x3c, 0xee, 0xd9, 0xb2, 0x89,
+	0x9c, 0x3b, 0x2d, 0xd7, 0xd9, 0x87, 0x65, 0xaa, 0x4f, 0x96, 0xa9, 0xad, 0xfe, 0x27, 0x2e, 0x4e,
+	0x8f, 0x31, 0x66, 0x29, 0xf2, 0xbf, 0x16, 0x90, 0x07, 0x70, 0x5b, 0x26, 0x8c, 0x47, 0x2a, 0xcf,
+	0x30, 0xbc, 0xe6, 0x70, 0x71, 0xbc, 0x6e, 0xf7, 0xec, 0x7c, 0xab, 0x00, 0x7c, 0xd8, 0x7a, 0x5d,
+	0x64, 0x43, 0x3e, 0xc2, 0x8a, 0x69, 0x92, 0x30, 0x8e, 0x54, 0x14, 0x1a, 0xdf, 0xa1, 0x54, 0x91,
+	0xc2, 0xe2, 0x82, 0xee, 0x4f, 0x47, 0x6b, 0x9b, 0x6d, 0x47, 0xf3, 0xb7, 0x23, 0x15, 0x51, 0xcd,
+	0x7e, 0xab, 0xc9, 0x3d, 0x87, 0xde, 0xc1, 0x9b, 0xcb, 0xe4, 0x29, 0xcc, 0xa5, 0x92, 0x85, 0x09,
+	0x3f, 0x14, 0xcd, 0x99, 0x7f, 0xb6, 0x8a, 0x6d, 0xab, 0x9e, 0x43, 0x67, 0x53, 0x3b, 0x24, 0x2f,
+	0x61, 0x41, 0xd9, 0xec, 0x6c, 0xbd, 0xed, 0x82, 0x7b, 0xe5, 0xf5, 0x53, 0x29, 0xf7, 0x1c, 0xda,
+	0x50, 0x93, 0x29, 0x79, 0x01, 0x80, 0x3c, 0x1e, 0xdd, 0x4a, 0xd5, 0xa8, 0xb4, 0xca, 0x55, 0xc6,
+	0xc9, 0xf4, 0x1c, 0x3a, 0x8f, 0xe3, 0x98, 0x62, 0x58, 0xc9, 0x6c, 0x1c, 0x61, 0x74, 0x2d, 0x8f,
+	0x66, 0xcd, 0xa8, 0x3d, 0x2c, 0x57, 0x2b, 0x8d, 0xb0, 0xe7, 0xd0, 0xe5, 0xac, 0x0c, 0xe8, 0xd6,
+	0xa0, 0x22, 0xf3, 0xb4, 0xf3, 0x05, 0x16, 0xf5, 0x61, 0xe2, 0xa9, 0x8c, 0x9e, 0x40, 0xd5, 0xbc,
+	0x03, 0xf7, 0x3f, 0xde, 0x81, 0xa9, 0x20, 0x9b, 0xf6, 0x95, 0xda, 0xab, 0x6f, 0x97, 0xdb, 0x9c,
+	0x6c, 0x64, 0x9e, 0x68, 0xf7, 0xfd, 0xf9, 0xd0, 0x73, 0x2f, 0x86, 0x9e, 0xfb, 0x6b, 0xe8, 0xb9,
+	0x67, 0x57, 0x9e, 0x73, 0x71, 0xe5, 0x39, 0x3f, 0xae, 0x3c, 0x67, 0xff, 0x19, 0x4b, 0xd4, 0x51,
+	0x7e, 0xe0, 0xf7, 0x45, 0x1a, 0x4c, 0x7f, 0x35, 0x93, 0xa1, 0xfd, 0xb4, 0xca, 0x3e, 0xaa, 0x83,
+	0xba, 0xc1, 0x1e, 0xfd, 0x1e, 0x00, 0xbb, 0x35, 0x08, 0xed, 0x13, 0x05, 0x00, 0x00,
 }
 
 func (m *MsgInfo) Marshal() (dAtA []byte, err error) {
@@ -429,6 +510,14 @@ func (m *MsgInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	n1, err1 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ReceiveTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ReceiveTime):])
+	if err1 != nil {
+		return 0, err1
+	}
+	i -= n1
+	i = encodeVarintWal(dAtA, i, uint64(n1))
+	i--
+	dAtA[i] = 0x1a
 	if len(m.PeerID) > 0 {
 		i -= len(m.PeerID)
 		copy(dAtA[i:], m.PeerID)
@@ -484,12 +573,12 @@ func (m *TimeoutInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x10
 	}
-	n2, err2 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.Duration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.Duration):])
-	if err2 != nil {
-		return 0, err2
+	n3, err3 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.Duration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.Duration):])
+	if err3 != nil {
+		return 0, err3
 	}
-	i -= n2
-	i = encodeVarintWal(dAtA, i, uint64(n2))
+	i -= n3
+	i = encodeVarintWal(dAtA, i, uint64(n3))
 	i--
 	dAtA[i] = 0xa
 	return len(dAtA) - i, nil
@@ -523,6 +612,39 @@ func (m *EndHeight) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *RestartAcknowledgment) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RestartAcknowledgment) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RestartAcknowledgment) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.SignatureHeight != 0 {
+		i = encodeVarintWal(dAtA, i, uint64(m.SignatureHeight))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Height != 0 {
+		i = encodeVarintWal(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *WALMessage) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -639,6 +761,27 @@ func (m *WALMessage_EndHeight) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
+func (m *WALMessage_RestartAcknowledgment) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *WALMessage_RestartAcknowledgment) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.RestartAcknowledgment != nil {
+		{
+			size, err := m.RestartAcknowledgment.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintWal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
+	return len(dAtA) - i, nil
+}
 func (m *TimedWALMessage) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -671,12 +814,12 @@ func (m *TimedWALMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x12
 	}
-	n8, err8 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
-	if err8 != nil {
-		return 0, err8
+	n10, err10 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
+	if err10 != nil {
+		return 0, err10
 	}
-	i -= n8
-	i = encodeVarintWal(dAtA, i, uint64(n8))
+	i -= n10
+	i = encodeVarintWal(dAtA, i, uint64(n10))
 	i--
 	dAtA[i] = 0xa
 	return len(dAtA) - i, nil
@@ -705,6 +848,8 @@ func (m *MsgInfo) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovWal(uint64(l))
 	}
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.ReceiveTime)
+	n += 1 + l + sovWal(uint64(l))
 	return n
 }
 
@@ -740,6 +885,21 @@ func (m *EndHeight) Size() (n int) {
 	return n
 }
 
+func (m *RestartAcknowledgment) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Height != 0 {
+		n += 1 + sovWal(uint64(m.Height))
+	}
+	if m.SignatureHeight != 0 {
+		n += 1 + sovWal(uint64(m.SignatureHeight))
+	}
+	return n
+}
+
 func (m *WALMessage) Size() (n int) {
 	if m == nil {
 		return 0
@@ -800,6 +960,18 @@ func (m *WALMessage_EndHeight) Size() (n int) {
 	}
 	return n
 }
+func (m *WALMessage_RestartAcknowledgment) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.RestartAcknowledgment != nil {
+		l = m.RestartAcknowledgment.Size()
+		n += 1 + l + sovWal(uint64(l))
+	}
+	return n
+}
 func (m *TimedWALMessage) Size() (n int) {
 	if m == nil {
 		return 0
@@ -915,6 +1087,39 @@ func (m *MsgInfo) Unmarshal(dAtA []byte) error {
 			}
 			m.PeerID = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReceiveTime", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowWal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthWal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthWal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.ReceiveTime, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipWal(dAtA[iNdEx:])
@@ -1145,6 +1350,94 @@ func (m *EndHeight) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *RestartAcknowledgment) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowWal
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RestartAcknowledgment: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RestartAcknowledgment: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowWal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SignatureHeight", wireType)
+			}
+			m.SignatureHeight = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowWal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SignatureHeight |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipWal(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthWal
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func (m *WALMessage) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
@@ -1314,6 +1607,41 @@ func (m *WALMessage) Unmarshal(dAtA []byte) error {
 			}
 			m.Sum = &WALMessage_EndHeight{v}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RestartAcknowledgment", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowWal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthWal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthWal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &RestartAcknowledgment{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Sum = &WALMessage_RestartAcknowledgment{v}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipWal(dAtA[iNdEx:])
@@ -116,6 +116,10 @@ type SyncInfo struct {
 	SnapshotChunksTotal int64         `json:"snapshot_chunks_total,string"`
 	BackFilledBlocks    int64         `json:"backfilled_blocks,string"`
 	BackFillBlocksTotal int64         `json:"backfill_blocks_total,string"`
+
+	// ConsensusLastActivityAge is the time since the consensus state machine
+	// last processed a message.
+	ConsensusLastActivityAge time.Duration `json:"consensus_last_activity_age,string"`
 }
 
 type ApplicationInfo struct {
@@ -165,6 +169,33 @@ type ResultStatus struct {
 	SyncInfo        SyncInfo              `json:"sync_info"`
 	ValidatorInfo   ValidatorInfo         `json:"validator_info"`
 	LightClientInfo types.LightClientInfo `json:"light_client_info,omitempty"`
+
+	// ProposalLatency is only set if this node proposed the latest block.
+	ProposalLatency *ProposalLatencyInfo `json:"proposal_latency,omitempty"`
+	// LastIgnoredStateUpdate is only set if consensus ignored a state update.
+	LastIgnoredStateUpdate *IgnoredStateUpdateInfo `json:"last_ignored_state_update,omitempty"`
+}
+
+// ProposalLatencyInfo breaks down the time the latest block proposed by this
+// node took to disseminate, from signing the proposal until +2/3 prevotes.
+type ProposalLatencyInfo struct {
+	Height int64 `json:"height,string"`
+	Round  int32 `json:"round"`
+
+	SignedToProcessed       time.Duration `json:"signed_to_processed,string"`
+	ProcessedToFirstPrevote time.Duration `json:"processed_to_first_prevote,string"`
+	FirstPrevoteToQuorum    time.Duration `json:"first_prevote_to_quorum,string"`
+	SignedToQuorum          time.Duration `json:"signed_to_quorum,string"`
+}
+
+// IgnoredStateUpdateInfo describes the latest state update that did not
+// change the consensus state because it was not newer than it.
+type IgnoredStateUpdateInfo struct {
+	Source    string    `json:"source"`
+	Reason    string    `json:"reason"`
+	OldHeight int64     `json:"old_height,string"`
+	NewHeight int64     `json:"new_height,string"`
+	Time      time.Time `json:"time"`
 }
 
 // Node lag status
@@ -234,6 +265,9 @@ type ResultConsensusParams struct {
 type ResultDumpConsensusState struct {
 	RoundState json.RawMessage `json:"round_state"`
 	Peers      []PeerStateInfo `json:"peers"`
+	// Debug has the queue depths, WAL health and last message errors of the
+	// consensus state machine. It is omitted if dumped again within a second.
+	Debug json.RawMessage `json:"debug,omitempty"`
 }
 
 // UNSTABLE
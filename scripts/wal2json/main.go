@@ -10,7 +10,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/tendermint/tendermint/internal/consensus"
@@ -22,24 +21,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	f, err := os.Open(os.Args[1])
-	if err != nil {
-		panic(fmt.Errorf("failed to open WAL file: %w", err))
-	}
-	defer f.Close()
-
-	dec := consensus.NewWALDecoder(f)
-	for {
-		msg, err := dec.Decode()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(fmt.Errorf("failed to decode msg: %w", err))
-		}
-
+	err := consensus.IterateWAL(os.Args[1], func(msg consensus.TimedWALMessage) (bool, error) {
 		json, err := json.Marshal(msg)
 		if err != nil {
-			panic(fmt.Errorf("failed to marshal msg: %w", err))
+			return false, fmt.Errorf("failed to marshal msg: %w", err)
 		}
 
 		_, err = os.Stdout.Write(json)
@@ -48,15 +33,18 @@ func main() {
 		}
 
 		if err == nil {
-			if endMsg, ok := msg.Msg.(consensus.EndHeightMessage); ok {
+			if endMsg, ok := msg.EndHeight(); ok {
 				_, err = os.Stdout.Write([]byte(fmt.Sprintf("ENDHEIGHT %d\n", endMsg.Height)))
 			}
 		}
 
 		if err != nil {
-			fmt.Println("Failed to write message", err)
-			os.Exit(1) //nolint:gocritic
+			return false, fmt.Errorf("failed to write message: %w", err)
 		}
-
+		return false, nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 }
@@ -1078,7 +1078,17 @@ func (ec *ExtendedCommit) Clone() *ExtendedCommit {
 // Inverse of VoteSet.MakeExtendedCommit().
 func (ec *ExtendedCommit) ToExtendedVoteSet(chainID string, vals *ValidatorSet) *VoteSet {
 	voteSet := NewExtendedVoteSet(chainID, ec.Height, ec.Round, tmproto.PrecommitType, vals)
-	ec.addSigsToVoteSet(voteSet)
+	ec.addSigsToVoteSet(voteSet, voteSet.AddVote)
+	return voteSet
+}
+
+// ToFilteredExtendedVoteSet is like ToExtendedVoteSet, but tolerates commit
+// signatures whose vote extension data was filtered out of the ExtendedCommit.
+// The corresponding votes are added to the vote set without extension data.
+// Panics if signatures from the ExtendedCommit can't be added to the voteset.
+func (ec *ExtendedCommit) ToFilteredExtendedVoteSet(chainID string, vals *ValidatorSet) *VoteSet {
+	voteSet := NewExtendedVoteSet(chainID, ec.Height, ec.Round, tmproto.PrecommitType, vals)
+	ec.addSigsToVoteSet(voteSet, voteSet.addVoteWithoutExtension)
 	return voteSet
 }
 
@@ -1087,12 +1097,12 @@ func (ec *ExtendedCommit) ToExtendedVoteSet(chainID string, vals *ValidatorSet)
 // Inverse of VoteSet.MakeExtendedCommit().
 func (ec *ExtendedCommit) ToVoteSet(chainID string, vals *ValidatorSet) *VoteSet {
 	voteSet := NewVoteSet(chainID, ec.Height, ec.Round, tmproto.PrecommitType, vals)
-	ec.addSigsToVoteSet(voteSet)
+	ec.addSigsToVoteSet(voteSet, voteSet.AddVote)
 	return voteSet
 }
 
-// addSigsToVoteSet adds all of the signature to voteSet.
-func (ec *ExtendedCommit) addSigsToVoteSet(voteSet *VoteSet) {
+// addSigsToVoteSet adds all of the signature to voteSet using addVote.
+func (ec *ExtendedCommit) addSigsToVoteSet(voteSet *VoteSet, addVote func(*Vote) (bool, error)) {
 	for idx, ecs := range ec.ExtendedSignatures {
 		if ecs.BlockIDFlag == BlockIDFlagAbsent {
 			continue // OK, some precommits can be missing.
@@ -1101,7 +1111,7 @@ func (ec *ExtendedCommit) addSigsToVoteSet(voteSet *VoteSet) {
 		if err := vote.ValidateBasic(); err != nil {
 			panic(fmt.Errorf("failed to validate vote reconstructed from LastCommit: %w", err))
 		}
-		added, err := voteSet.AddVote(vote)
+		added, err := addVote(vote)
 		if !added || err != nil {
 			panic(fmt.Errorf("failed to reconstruct vote set from extended commit: %w", err))
 		}
@@ -1140,6 +1150,37 @@ func (ec *ExtendedCommit) EnsureExtensions() error {
 	return nil
 }
 
+// EnsureQuorumExtensions validates that vote extension data is present for
+// commit signatures holding more than 2/3 of the voting power of vals, the
+// validator set that signed the commit. Unlike EnsureExtensions, it accepts
+// an ExtendedCommit from which some vote extensions were filtered out.
+func (ec *ExtendedCommit) EnsureQuorumExtensions(vals *ValidatorSet) error {
+	if vals.Size() != ec.Size() {
+		return fmt.Errorf("invalid extended commit -- wrong set size: %v vs %v", vals.Size(), ec.Size())
+	}
+	var power int64
+	for idx, ecs := range ec.ExtendedSignatures {
+		if ecs.BlockIDFlag == BlockIDFlagCommit && ecs.EnsureExtension() == nil {
+			power += vals.Validators[idx].VotingPower
+		}
+	}
+	if needed := vals.TotalVotingPower() * 2 / 3; power <= needed {
+		return fmt.Errorf("vote extension data is present for %d voting power, need more than %d", power, needed)
+	}
+	return nil
+}
+
+// HasExtensions returns true if vote extension data is present for any commit
+// signature in the ExtendedCommit.
+func (ec *ExtendedCommit) HasExtensions() bool {
+	for _, ecs := range ec.ExtendedSignatures {
+		if ecs.BlockIDFlag == BlockIDFlagCommit && ecs.EnsureExtension() == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // StripExtensions removes all VoteExtension data from an ExtendedCommit. This
 // is useful when dealing with an ExendedCommit but vote extension data is
 // expected to be absent.
@@ -0,0 +1,226 @@
+package types
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+// blockPartCodecMagic prefixes the serialized blocks compressed by a
+// BlockPartCodec, followed by the ID of the codec and the compressed block.
+// No serialized block starts with it: its first byte is the start of a field
+// tag of the invalid wire type 7.
+var blockPartCodecMagic = []byte{0xff, 'B', 'P'}
+
+// ErrUnknownBlockPartCodec is returned when decoding block parts compressed
+// by a codec that is not registered.
+var ErrUnknownBlockPartCodec = errors.New("block parts are compressed by an unknown codec")
+
+// BlockPartCodec compresses the serialized blocks split into block parts.
+//
+// The BlockID of a block commits to the header of its part set, and so to the
+// bytes its codec produced: a codec must produce the same bytes for the same
+// block on every node and in every version, or the part sets nodes rebuild
+// from the blocks they store or sync will not match the commits of the blocks.
+type BlockPartCodec interface {
+	// ID identifies the codec in the compressed block parts. It must not be
+	// changed once the codec is used.
+	ID() byte
+	// Name identifies the codec in the configuration.
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	// Decompress returns an error if data decompresses to more than maxSize
+	// bytes.
+	Decompress(data []byte, maxSize int64) ([]byte, error)
+}
+
+var (
+	blockPartCodecsMtx sync.RWMutex
+	blockPartCodecs    = map[byte]BlockPartCodec{}
+)
+
+func init() {
+	RegisterBlockPartCodec(FlateBlockPartCodec{})
+}
+
+// RegisterBlockPartCodec registers codec so that the block parts it
+// compressed can be decoded. It panics if a codec with the same ID or name is
+// already registered.
+func RegisterBlockPartCodec(codec BlockPartCodec) {
+	blockPartCodecsMtx.Lock()
+	defer blockPartCodecsMtx.Unlock()
+	for id, c := range blockPartCodecs {
+		if id == codec.ID() || c.Name() == codec.Name() {
+			panic(fmt.Sprintf("block part codec %q (%d) already registered", c.Name(), id))
+		}
+	}
+	blockPartCodecs[codec.ID()] = codec
+}
+
+// BlockPartCodecByName returns the registered codec named name, and false if
+// there is none.
+func BlockPartCodecByName(name string) (BlockPartCodec, bool) {
+	blockPartCodecsMtx.RLock()
+	defer blockPartCodecsMtx.RUnlock()
+	for _, c := range blockPartCodecs {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func blockPartCodecByID(id byte) (BlockPartCodec, bool) {
+	blockPartCodecsMtx.RLock()
+	defer blockPartCodecsMtx.RUnlock()
+	c, ok := blockPartCodecs[id]
+	return c, ok
+}
+
+// MakePartSetWithCodec returns a PartSet containing parts of a serialized block
+// compressed by codec. A nil codec does not compress the block, like
+// MakePartSet.
+// CONTRACT: partSize is greater than zero.
+func (b *Block) MakePartSetWithCodec(partSize uint32, codec BlockPartCodec) (*PartSet, error) {
+	if codec == nil {
+		return b.MakePartSet(partSize)
+	}
+	if b == nil {
+		return nil, errors.New("nil block")
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	pbb, err := b.ToProto()
+	if err != nil {
+		return nil, err
+	}
+	bz, err := proto.Marshal(pbb)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := codec.Compress(bz)
+	if err != nil {
+		return nil, fmt.Errorf("compressing block with %s: %w", codec.Name(), err)
+	}
+	data := make([]byte, 0, len(blockPartCodecMagic)+1+len(compressed))
+	data = append(data, blockPartCodecMagic...)
+	data = append(data, codec.ID())
+	data = append(data, compressed...)
+	return NewPartSetFromData(data, partSize), nil
+}
+
+// MakePartSetMatching returns the PartSet of the block that header commits
+// to, made without compressing the block or compressed by codec, if not nil.
+// Those are the only block parts accepted by a node whose codec is codec. It
+// returns an error if none matches header.
+func (b *Block) MakePartSetMatching(partSize uint32, header PartSetHeader, codec BlockPartCodec) (*PartSet, error) {
+	partSet, err := b.MakePartSet(partSize)
+	if err != nil || partSet.HasHeader(header) {
+		return partSet, err
+	}
+	if codec != nil {
+		partSet, err := b.MakePartSetWithCodec(partSize, codec)
+		if err != nil || partSet.HasHeader(header) {
+			return partSet, err
+		}
+	}
+	return nil, fmt.Errorf("no accepted part set of block %X matches %v", b.Hash(), header)
+}
+
+// BlockPartsCodec returns the codec the serialized block bz was compressed by,
+// nil if it is not compressed, and ErrUnknownBlockPartCodec if the codec is not
+// registered.
+func BlockPartsCodec(bz []byte) (BlockPartCodec, error) {
+	if !bytes.HasPrefix(bz, blockPartCodecMagic) {
+		return nil, nil
+	}
+	if len(bz) == len(blockPartCodecMagic) {
+		return nil, errors.New("truncated compressed block parts")
+	}
+	id := bz[len(blockPartCodecMagic)]
+	codec, ok := blockPartCodecByID(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBlockPartCodec, id)
+	}
+	return codec, nil
+}
+
+// BlockBytesFromPartsBytes returns the serialized block whose parts have the
+// bytes bz, decompressing it if it was compressed by a registered codec to at
+// most maxSize bytes.
+func BlockBytesFromPartsBytes(bz []byte, maxSize int64) ([]byte, error) {
+	codec, err := BlockPartsCodec(bz)
+	if err != nil || codec == nil {
+		return bz, err
+	}
+	bz, err = codec.Decompress(bz[len(blockPartCodecMagic)+1:], maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing block with %s: %w", codec.Name(), err)
+	}
+	return bz, nil
+}
+
+// BlockFromPartsBytes decodes the block serialized in bz, the bytes of its
+// parts, decompressing it if it was compressed by a registered codec to at
+// most maxSize bytes.
+func BlockFromPartsBytes(bz []byte, maxSize int64) (*Block, error) {
+	bz, err := BlockBytesFromPartsBytes(bz, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pbb := new(tmproto.Block)
+	if err := proto.Unmarshal(bz, pbb); err != nil {
+		return nil, err
+	}
+	return BlockFromProto(pbb)
+}
+
+// FlateBlockPartCodec compresses blocks with DEFLATE at the default
+// compression level of compress/flate.
+//
+// Its output is only stable for the versions of compress/flate producing the
+// output expected by TestFlateBlockPartCodecDeterminism; the nodes of a
+// network must be built with such versions.
+type FlateBlockPartCodec struct{}
+
+var _ BlockPartCodec = FlateBlockPartCodec{}
+
+func (FlateBlockPartCodec) ID() byte     { return 1 }
+func (FlateBlockPartCodec) Name() string { return "flate" }
+
+func (FlateBlockPartCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (FlateBlockPartCodec) Decompress(data []byte, maxSize int64) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	bz, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(bz)) > maxSize {
+		return nil, fmt.Errorf("block decompresses to more than %d bytes", maxSize)
+	}
+	return bz, nil
+}
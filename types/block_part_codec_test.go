@@ -0,0 +1,140 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeCalldataBlock returns a block of txs dominated by repetitive calldata.
+func makeCalldataBlock(numTxs int) *Block {
+	txs := make([]Tx, numTxs)
+	for i := range txs {
+		txs[i] = Tx(fmt.Sprintf("transfer(to=0x%040x,amount=%020d,memo=%0128d)", i%16, i*1000, 0))
+	}
+	block := MakeBlock(3, txs, &Commit{
+		Height:     2,
+		BlockID:    makeBlockID(bytes.Repeat([]byte{1}, 32), 1, bytes.Repeat([]byte{2}, 32)),
+		Signatures: []CommitSig{NewCommitSigAbsent()},
+	}, nil)
+	block.ProposerAddress = make([]byte, 20)
+	return block
+}
+
+func readPartSet(t *testing.T, partSet *PartSet) []byte {
+	t.Helper()
+	bz, err := io.ReadAll(partSet.GetReader())
+	require.NoError(t, err)
+	return bz
+}
+
+func TestBlockPartCodecRoundTrip(t *testing.T) {
+	block := makeCalldataBlock(100)
+
+	for _, codec := range []BlockPartCodec{nil, FlateBlockPartCodec{}} {
+		partSet, err := block.MakePartSetWithCodec(BlockPartSizeBytes, codec)
+		require.NoError(t, err)
+		bz := readPartSet(t, partSet)
+
+		decodedCodec, err := BlockPartsCodec(bz)
+		require.NoError(t, err)
+		assert.Equal(t, codec, decodedCodec)
+
+		decoded, err := BlockFromPartsBytes(bz, MaxBlockSizeBytes)
+		require.NoError(t, err)
+		assert.Equal(t, block.Hash(), decoded.Hash())
+		assert.Equal(t, block.Txs, decoded.Txs)
+
+		// the part set the header commits to is found whether it is
+		// compressed or not
+		matching, err := block.MakePartSetMatching(BlockPartSizeBytes, partSet.Header(), FlateBlockPartCodec{})
+		require.NoError(t, err)
+		assert.True(t, matching.HasHeader(partSet.Header()))
+	}
+
+	uncompressed, err := block.MakePartSet(BlockPartSizeBytes)
+	require.NoError(t, err)
+	compressed, err := block.MakePartSetWithCodec(BlockPartSizeBytes, FlateBlockPartCodec{})
+	require.NoError(t, err)
+	assert.Less(t, compressed.ByteSize()*3, uncompressed.ByteSize())
+
+	_, err = block.MakePartSetMatching(BlockPartSizeBytes, PartSetHeader{Total: 1, Hash: make([]byte, 32)}, FlateBlockPartCodec{})
+	assert.Error(t, err)
+	// compressed block parts are not accepted without their codec
+	_, err = block.MakePartSetMatching(BlockPartSizeBytes, compressed.Header(), nil)
+	assert.Error(t, err)
+}
+
+func TestBlockFromPartsBytesErrors(t *testing.T) {
+	block := makeCalldataBlock(100)
+	partSet, err := block.MakePartSetWithCodec(BlockPartSizeBytes, FlateBlockPartCodec{})
+	require.NoError(t, err)
+	bz := readPartSet(t, partSet)
+
+	// the decompressed block may not exceed the maximum size
+	_, err = BlockFromPartsBytes(bz, 1024)
+	assert.Error(t, err)
+
+	unknown := append([]byte{}, bz...)
+	unknown[len(blockPartCodecMagic)] = 0xee
+	_, err = BlockFromPartsBytes(unknown, MaxBlockSizeBytes)
+	assert.ErrorIs(t, err, ErrUnknownBlockPartCodec)
+
+	_, err = BlockFromPartsBytes(blockPartCodecMagic, MaxBlockSizeBytes)
+	assert.Error(t, err)
+}
+
+func TestRegisterBlockPartCodecDuplicate(t *testing.T) {
+	assert.Panics(t, func() { RegisterBlockPartCodec(FlateBlockPartCodec{}) })
+	codec, ok := BlockPartCodecByName("flate")
+	require.True(t, ok)
+	assert.Equal(t, FlateBlockPartCodec{}, codec)
+}
+
+// TestFlateBlockPartCodecDeterminism checks that the flate codec still
+// produces the part sets of the blocks compressed by earlier versions. If it
+// fails, the encoder changed: the part sets of the blocks the nodes rebuild
+// would no longer match the BlockIDs committed by nodes of earlier versions.
+func TestFlateBlockPartCodecDeterminism(t *testing.T) {
+	block := makeCalldataBlock(1000)
+
+	var headers []PartSetHeader
+	for i := 0; i < 2; i++ {
+		partSet, err := block.MakePartSetWithCodec(BlockPartSizeBytes, FlateBlockPartCodec{})
+		require.NoError(t, err)
+		headers = append(headers, partSet.Header())
+	}
+	require.Equal(t, headers[0], headers[1])
+
+	partSet, err := block.MakePartSetWithCodec(BlockPartSizeBytes, FlateBlockPartCodec{})
+	require.NoError(t, err)
+	sum := sha256.Sum256(readPartSet(t, partSet))
+	assert.Equal(t, "3e8789adaccc3a1bcc336904bdc023ee47a762e03f7201c8c045f71769ca740f", hex.EncodeToString(sum[:]))
+}
+
+func BenchmarkBlockPartCodec(b *testing.B) {
+	block := makeCalldataBlock(10000)
+	uncompressed, err := block.MakePartSet(BlockPartSizeBytes)
+	require.NoError(b, err)
+
+	for _, codec := range []BlockPartCodec{nil, FlateBlockPartCodec{}} {
+		name := "none"
+		if codec != nil {
+			name = codec.Name()
+		}
+		b.Run(name, func(b *testing.B) {
+			var partSet *PartSet
+			for i := 0; i < b.N; i++ {
+				partSet, err = block.MakePartSetWithCodec(BlockPartSizeBytes, codec)
+				require.NoError(b, err)
+			}
+			b.ReportMetric(float64(uncompressed.ByteSize())/float64(partSet.ByteSize()), "reduction")
+		})
+	}
+}
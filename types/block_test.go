@@ -691,6 +691,55 @@ func TestExtendedCommitToVoteSet(t *testing.T) {
 	}
 }
 
+func TestExtendedCommitToFilteredExtendedVoteSet(t *testing.T) {
+	for _, testCase := range []struct {
+		name           string
+		numFiltered    int
+		expectQuorumOK bool
+	}{
+		{"no extensions filtered", 0, true},
+		{"extensions filtered below quorum", 3, true},
+		{"extensions filtered beyond quorum", 4, false},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			lastID := makeBlockIDRandom()
+			h := int64(3)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			voteSet, valSet, vals := randVoteSet(ctx, t, h-1, 1, tmproto.PrecommitType, 10, 1)
+			extCommit, err := makeExtCommit(ctx, lastID, h-1, 1, voteSet, vals, time.Now())
+			require.NoError(t, err)
+
+			for i := 0; i < testCase.numFiltered; i++ {
+				extCommit.ExtendedSignatures[i].Extension = nil
+				extCommit.ExtendedSignatures[i].ExtensionSignature = nil
+			}
+			require.True(t, extCommit.HasExtensions())
+			require.Equal(t, testCase.numFiltered == 0, extCommit.EnsureExtensions() == nil)
+
+			err = extCommit.EnsureQuorumExtensions(valSet)
+			if !testCase.expectQuorumOK {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			chainID := voteSet.ChainID()
+			if testCase.numFiltered > 0 {
+				require.Panics(t, func() { extCommit.ToExtendedVoteSet(chainID, valSet) })
+			}
+			voteSet2 := extCommit.ToFilteredExtendedVoteSet(chainID, valSet)
+			require.True(t, voteSet2.HasAll())
+			for i := int32(0); int(i) < len(vals); i++ {
+				vote := voteSet2.GetByIndex(i)
+				require.Equal(t, int(i) >= testCase.numFiltered, len(vote.ExtensionSignature) > 0)
+			}
+		})
+	}
+}
+
 func TestCommitToVoteSetWithVotesForNilBlock(t *testing.T) {
 	blockID := makeBlockID([]byte("blockhash"), 1000, []byte("partshash"))
 
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/internal/jsontypes"
 	tmquery "github.com/tendermint/tendermint/internal/pubsub/query"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 	"github.com/tendermint/tendermint/proto/tendermint/types"
 )
 
@@ -28,19 +30,77 @@ const (
 	// These are used for testing the consensus state machine.
 	// They can also be used to build real-time consensus visualizers.
 	EventCompleteProposalValue = "CompleteProposal"
+	// The BlockApplied event is emitted once the block of a height is
+	// applied and its results are available.
+	EventBlockAppliedValue = "BlockApplied"
+	// The BlockGossip event is emitted as the parts of a proposal block are
+	// received: when the gossip starts, at progress thresholds and once the
+	// block is complete.
+	EventBlockGossipValue = "BlockGossip"
+	// The BlockNeeded event is emitted on the internal event switch when the
+	// state machine commits a block it does not have.
+	EventBlockNeededValue = "BlockNeeded"
+	// The BlockPartPriority event is emitted on the internal event switch
+	// when the most blocking missing parts of the proposal block change.
+	EventBlockPartPriorityValue = "BlockPartPriority"
 	// The BlockSyncStatus event will be emitted when the node switching
 	// state sync mechanism between the consensus reactor and the blocksync reactor.
 	EventBlockSyncStatusValue = "BlockSyncStatus"
-	EventLockValue            = "Lock"
-	EventNewRoundValue        = "NewRound"
-	EventNewRoundStepValue    = "NewRoundStep"
+	// The ConflictingSelfVote event is emitted when a vote of this node's key
+	// conflicting with one it already signed is received.
+	EventConflictingSelfVoteValue = "ConflictingSelfVote"
+	// The ConsensusStalled event is emitted by the consensus watchdog when
+	// the state machine stops processing pending messages.
+	EventConsensusStalledValue = "ConsensusStalled"
+	// The DoubleSignRefusal event is emitted when the private validator
+	// refuses to sign a proposal conflicting with one it already signed.
+	EventDoubleSignRefusalValue = "DoubleSignRefusal"
+	// The HeightSummary event is emitted once the block of a height is
+	// committed and applied, summarizing how the height was decided.
+	EventHeightSummaryValue = "HeightSummary"
+	EventLockValue          = "Lock"
+	EventNewRoundValue      = "NewRound"
+	EventNewRoundStepValue  = "NewRoundStep"
+	// The POLNeeded event is emitted on the internal event switch when a
+	// proposal references a POL round we have no 2/3 majority of prevotes for.
+	EventPOLNeededValue = "POLNeeded"
+	// The PartSetMismatch event is emitted when the block rebuilt from the tx
+	// keys of a proposal does not match the part set header of the proposal.
+	EventPartSetMismatchValue = "PartSetMismatch"
 	EventPolkaValue           = "Polka"
-	EventRelockValue          = "Relock"
+	// The PrecommitsExcluded event is emitted when the rate of our precommits
+	// for committed blocks that were excluded from their commit exceeds its
+	// threshold.
+	EventPrecommitsExcludedValue = "PrecommitsExcluded"
+	// The ProposalNeeded event is emitted on the internal event switch when
+	// the prevote of the proposer of the round for a block is received before
+	// its proposal.
+	EventProposalNeededValue = "ProposalNeeded"
+	// The RebuildDivergence event is emitted when a committed block rebuilt
+	// from the tx keys of its proposal serializes otherwise than the block
+	// its stored parts encode.
+	EventRebuildDivergenceValue = "RebuildDivergence"
+	EventRelockValue            = "Relock"
+	// The RoundSkip event is emitted when the state machine skips to a
+	// higher round of the height on +2/3 votes of any kind for that round.
+	EventRoundSkipValue       = "RoundSkip"
 	EventStateSyncStatusValue = "StateSyncStatus"
-	EventTimeoutProposeValue  = "TimeoutPropose"
-	EventTimeoutWaitValue     = "TimeoutWait"
-	EventValidBlockValue      = "ValidBlock"
-	EventVoteValue            = "Vote"
+	// The StepBudgetExceeded event is emitted when the state machine keeps
+	// exceeding its budget of steps per second.
+	EventStepBudgetExceededValue = "StepBudgetExceeded"
+	EventTimeoutProposeValue     = "TimeoutPropose"
+	EventTimeoutWaitValue        = "TimeoutWait"
+	EventValidBlockValue         = "ValidBlock"
+	// The ValidatorAdded and ValidatorRemoved events are emitted when the key
+	// of this node enters or leaves the validator set.
+	EventValidatorAddedValue   = "ValidatorAdded"
+	EventValidatorRemovedValue = "ValidatorRemoved"
+	EventValidatorSetDiffValue = "ValidatorSetDiff"
+	EventVoteValue             = "Vote"
+	// The VoteExtensionRejected event is emitted when the extension of a
+	// precommit fails its signature verification or is rejected by the
+	// application.
+	EventVoteExtensionRejectedValue = "VoteExtensionRejected"
 
 	// Events emitted by the evidence reactor when evidence is validated
 	// and before it is committed
@@ -109,17 +169,32 @@ type LegacyEventData interface {
 }
 
 func init() {
+	jsontypes.MustRegister(EventDataBlockApplied{})
+	jsontypes.MustRegister(EventDataBlockGossip{})
 	jsontypes.MustRegister(EventDataBlockSyncStatus{})
 	jsontypes.MustRegister(EventDataCompleteProposal{})
+	jsontypes.MustRegister(EventDataConflictingSelfVote{})
+	jsontypes.MustRegister(EventDataConsensusStalled{})
+	jsontypes.MustRegister(EventDataDoubleSignRefusal{})
+	jsontypes.MustRegister(EventDataHeightSummary{})
+	jsontypes.MustRegister(EventDataLock{})
 	jsontypes.MustRegister(EventDataNewBlock{})
 	jsontypes.MustRegister(EventDataNewBlockHeader{})
 	jsontypes.MustRegister(EventDataNewEvidence{})
 	jsontypes.MustRegister(EventDataNewRound{})
+	jsontypes.MustRegister(EventDataPartSetMismatch{})
+	jsontypes.MustRegister(EventDataPrecommitsExcluded{})
+	jsontypes.MustRegister(EventDataRebuildDivergence{})
+	jsontypes.MustRegister(EventDataRoundSkip{})
 	jsontypes.MustRegister(EventDataRoundState{})
 	jsontypes.MustRegister(EventDataStateSyncStatus{})
+	jsontypes.MustRegister(EventDataStepBudgetExceeded{})
 	jsontypes.MustRegister(EventDataTx{})
+	jsontypes.MustRegister(EventDataValidatorMembership{})
+	jsontypes.MustRegister(EventDataValidatorSetDiff{})
 	jsontypes.MustRegister(EventDataValidatorSetUpdates{})
 	jsontypes.MustRegister(EventDataVote{})
+	jsontypes.MustRegister(EventDataVoteExtensionRejected{})
 	jsontypes.MustRegister(EventDataEvidenceValidated{})
 	jsontypes.MustRegister(LegacyEventDataNewBlock{})
 	jsontypes.MustRegister(LegacyEventDataTx{})
@@ -332,6 +407,10 @@ type EventDataRoundState struct {
 	Height int64  `json:"height,string"`
 	Round  int32  `json:"round"`
 	Step   string `json:"step"`
+
+	// Entry is what caused the transition to Step, for the NewRoundStep
+	// event.
+	Entry string `json:"entry,omitempty"`
 }
 
 // TypeTag implements the required method of jsontypes.Tagged.
@@ -350,6 +429,8 @@ type EventDataNewRound struct {
 	Height int64  `json:"height,string"`
 	Round  int32  `json:"round"`
 	Step   string `json:"step"`
+	// Entry is what caused the new round.
+	Entry string `json:"entry,omitempty"`
 
 	Proposer ValidatorInfo `json:"proposer"`
 }
@@ -376,6 +457,305 @@ func (e EventDataCompleteProposal) ToLegacy() LegacyEventData {
 	return e
 }
 
+// EventDataLock is published when the node locks or relocks on a block.
+type EventDataLock struct {
+	Height int64  `json:"height,string"`
+	Round  int32  `json:"round"`
+	Step   string `json:"step"`
+
+	BlockID BlockID `json:"block_id"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataLock) TypeTag() string { return "tendermint/event/Lock" }
+
+func (e EventDataLock) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataBlockApplied reports that the block of a height was applied, with
+// the app hash resulting from it.
+type EventDataBlockApplied struct {
+	Height    int64            `json:"height,string"`
+	BlockHash tmbytes.HexBytes `json:"block_hash"`
+	AppHash   tmbytes.HexBytes `json:"app_hash"`
+
+	ApplyDuration time.Duration `json:"apply_duration,string"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataBlockApplied) TypeTag() string { return "tendermint/event/BlockApplied" }
+
+func (e EventDataBlockApplied) ToLegacy() LegacyEventData {
+	return e
+}
+
+// BlockGossipStage is the stage of the gossip of a block reported by
+// EventDataBlockGossip.
+type BlockGossipStage string
+
+const (
+	BlockGossipStarted  BlockGossipStage = "started"
+	BlockGossipProgress BlockGossipStage = "progress"
+	BlockGossipComplete BlockGossipStage = "complete"
+)
+
+// EventDataBlockGossip reports the progress of the reception of the parts of
+// the proposal block of a round.
+type EventDataBlockGossip struct {
+	Height          int64            `json:"height,string"`
+	Round           int32            `json:"round"`
+	Stage           BlockGossipStage `json:"stage"`
+	ProposerAddress Address          `json:"proposer_address"`
+
+	PartSetHeader PartSetHeader `json:"part_set_header"`
+	Parts         uint32        `json:"parts"`
+	// Percent is the progress threshold reached, for the progress stage.
+	Percent int `json:"percent"`
+	// Elapsed is the time since the gossip started, for the complete stage.
+	Elapsed time.Duration `json:"elapsed,string"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataBlockGossip) TypeTag() string { return "tendermint/event/BlockGossip" }
+
+func (e EventDataBlockGossip) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataConsensusStalled reports the round state the consensus state
+// machine was stuck in and the number of messages waiting to be processed.
+type EventDataConsensusStalled struct {
+	Height int64  `json:"height,string"`
+	Round  int32  `json:"round"`
+	Step   string `json:"step"`
+
+	LastActivity         time.Time `json:"last_activity"`
+	PeerMsgQueueSize     int       `json:"peer_msg_queue_size"`
+	InternalMsgQueueSize int       `json:"internal_msg_queue_size"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataConsensusStalled) TypeTag() string { return "tendermint/event/ConsensusStalled" }
+
+func (e EventDataConsensusStalled) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataConflictingSelfVote reports the conflicting votes of this node's
+// key, and whether this node stopped signing.
+type EventDataConflictingSelfVote struct {
+	Height int64               `json:"height,string"`
+	Round  int32               `json:"round"`
+	Type   types.SignedMsgType `json:"type"`
+	// BlockHashA and BlockHashB are the blocks of the conflicting votes. They
+	// are empty for nil votes.
+	BlockHashA tmbytes.HexBytes `json:"block_hash_a"`
+	BlockHashB tmbytes.HexBytes `json:"block_hash_b"`
+	// Halted is set if this node stopped signing.
+	Halted bool `json:"halted"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataConflictingSelfVote) TypeTag() string { return "tendermint/event/ConflictingSelfVote" }
+
+func (e EventDataConflictingSelfVote) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataDoubleSignRefusal reports the proposal the private validator
+// refused to sign, and the error it returned.
+type EventDataDoubleSignRefusal struct {
+	Height int64  `json:"height,string"`
+	Round  int32  `json:"round"`
+	Error  string `json:"error"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataDoubleSignRefusal) TypeTag() string { return "tendermint/event/DoubleSignRefusal" }
+
+func (e EventDataDoubleSignRefusal) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataHeightSummary summarizes a committed height: its rounds, its
+// block and commit, the participation of this node and the time spent in
+// each stage. Durations of stages that were not observed are zero, e.g.
+// ProposeToPolka if the block was committed from precommits alone.
+type EventDataHeightSummary struct {
+	Height int64 `json:"height,string"`
+	// Rounds is the number of rounds used, the commit round included.
+	Rounds int32 `json:"rounds"`
+	// Proposer is the proposer of the commit round.
+	Proposer  Address          `json:"proposer"`
+	BlockHash tmbytes.HexBytes `json:"block_hash"`
+	NumTxs    int              `json:"num_txs"`
+	BlockSize int              `json:"block_size"`
+	// CommitSignatures is the number of precommits for the block in the
+	// commit round, out of Validators.
+	CommitSignatures int `json:"commit_signatures"`
+	Validators       int `json:"validators"`
+
+	// Proposed, Prevoted and Precommitted report whether this node proposed
+	// in the height and voted in the commit round.
+	Proposed     bool `json:"proposed"`
+	Prevoted     bool `json:"prevoted"`
+	Precommitted bool `json:"precommitted"`
+
+	// ProposeToPolka is the time from the receipt of the proposal of the
+	// commit round until +2/3 prevotes for its block.
+	ProposeToPolka time.Duration `json:"propose_to_polka,string"`
+	// PolkaToCommit is the time from +2/3 prevotes until +2/3 precommits for
+	// the block.
+	PolkaToCommit time.Duration `json:"polka_to_commit,string"`
+	ApplyDuration time.Duration `json:"apply_duration,string"`
+	// Finality is the time from the timestamp of the block to its
+	// application completing locally, zero if the timestamp is ahead of the
+	// local clock.
+	Finality time.Duration `json:"finality,string"`
+
+	// ProposalPeer is the peer the proposal of the commit round was
+	// received from and BlockPeer the peer whose part completed the block,
+	// PeerOriginSelf for this node. They are empty if unknown, as for a
+	// block rebuilt from its tx keys.
+	ProposalPeer NodeID `json:"proposal_peer"`
+	BlockPeer    NodeID `json:"block_peer"`
+}
+
+// PeerOriginSelf is the peer of EventDataHeightSummary for the proposals and
+// block parts of this node.
+const PeerOriginSelf NodeID = "self"
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataHeightSummary) TypeTag() string { return "tendermint/event/HeightSummary" }
+
+func (e EventDataHeightSummary) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataVoteExtensionRejected reports the precommit of a validator whose
+// extension was rejected, and why: "signature" if the signature of the
+// extension is invalid, "application" if the application rejected it.
+type EventDataVoteExtensionRejected struct {
+	Height           int64   `json:"height,string"`
+	Round            int32   `json:"round"`
+	ValidatorAddress Address `json:"validator_address"`
+	Reason           string  `json:"reason"`
+	Error            string  `json:"error"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataVoteExtensionRejected) TypeTag() string {
+	return "tendermint/event/VoteExtensionRejected"
+}
+
+func (e EventDataVoteExtensionRejected) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataPartSetMismatch reports that the block rebuilt from the tx keys of
+// the proposal of Height and Round does not match the part set header of the
+// proposal. The rebuilt block was discarded for the parts of the proposal.
+type EventDataPartSetMismatch struct {
+	Height int64 `json:"height,string"`
+	Round  int32 `json:"round"`
+	// TxCount is the number of txs of the rebuilt block, ProposalTxCount the
+	// number of tx keys of the proposal.
+	TxCount         int `json:"tx_count"`
+	ProposalTxCount int `json:"proposal_tx_count"`
+
+	RebuiltBlockHash    tmbytes.HexBytes `json:"rebuilt_block_hash"`
+	RebuiltPartSetHash  tmbytes.HexBytes `json:"rebuilt_part_set_hash"`
+	ProposalBlockHash   tmbytes.HexBytes `json:"proposal_block_hash"`
+	ProposalPartSetHash tmbytes.HexBytes `json:"proposal_part_set_hash"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataPartSetMismatch) TypeTag() string { return "tendermint/event/PartSetMismatch" }
+
+func (e EventDataPartSetMismatch) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataPrecommitsExcluded reports that too many of the precommits we
+// signed for committed blocks were excluded from their commit, in the last
+// Window heights we signed one in.
+type EventDataPrecommitsExcluded struct {
+	Height           int64   `json:"height,string"`
+	ValidatorAddress Address `json:"validator_address"`
+
+	Excluded int     `json:"excluded"`
+	Window   int     `json:"window"`
+	Rate     float64 `json:"rate"`
+	// Lateness is the time between +2/3 precommits for the block of Height
+	// and our last precommit for it; negative if we signed it before.
+	Lateness time.Duration `json:"lateness"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataPrecommitsExcluded) TypeTag() string { return "tendermint/event/PrecommitsExcluded" }
+
+func (e EventDataPrecommitsExcluded) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataRebuildDivergence reports that the committed block of Height,
+// rebuilt from the mempool with the tx keys of its proposal, serializes
+// otherwise than the block its stored parts encode. Both serializations were
+// dumped to Dir.
+type EventDataRebuildDivergence struct {
+	Height    int64            `json:"height,string"`
+	BlockHash tmbytes.HexBytes `json:"block_hash"`
+	Dir       string           `json:"dir"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataRebuildDivergence) TypeTag() string { return "tendermint/event/RebuildDivergence" }
+
+func (e EventDataRebuildDivergence) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataRoundSkip reports a skip from Round to TargetRound of Height, and
+// the validators whose votes of Type for TargetRound justified it.
+type EventDataRoundSkip struct {
+	Height      int64               `json:"height,string"`
+	Round       int32               `json:"round"`
+	TargetRound int32               `json:"target_round"`
+	Type        types.SignedMsgType `json:"type"`
+	// Reason is the entry label of the new round, e.g. "prevote-future".
+	Reason string `json:"reason"`
+	// Validators are the addresses of the validators we had a vote of Type
+	// for TargetRound of, in the order of the validator set.
+	Validators  []tmbytes.HexBytes `json:"validators"`
+	VotingPower int64              `json:"voting_power,string"`
+	TotalPower  int64              `json:"total_power,string"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataRoundSkip) TypeTag() string { return "tendermint/event/RoundSkip" }
+
+func (e EventDataRoundSkip) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataStepBudgetExceeded reports that the state machine exceeded its
+// budget of steps per second for a number of consecutive seconds.
+type EventDataStepBudgetExceeded struct {
+	Height int64 `json:"height,string"`
+	Round  int32 `json:"round"`
+
+	MaxStepsPerSecond int64 `json:"max_steps_per_second,string"`
+	Windows           int   `json:"windows"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataStepBudgetExceeded) TypeTag() string { return "tendermint/event/StepBudgetExceeded" }
+
+func (e EventDataStepBudgetExceeded) ToLegacy() LegacyEventData {
+	return e
+}
+
 type EventDataVote struct {
 	Vote *Vote
 }
@@ -407,6 +787,47 @@ func (e EventDataValidatorSetUpdates) ToLegacy() LegacyEventData {
 	return e
 }
 
+// ValidatorPowerChange is the voting power of a validator before and after a
+// validator set change. OldPower is 0 for an added validator and NewPower is
+// 0 for a removed one.
+type ValidatorPowerChange struct {
+	Address  Address `json:"address"`
+	OldPower int64   `json:"old_power,string"`
+	NewPower int64   `json:"new_power,string"`
+}
+
+// EventDataValidatorSetDiff lists the changes between the validator set of
+// Height and the validator set of the previous height.
+type EventDataValidatorSetDiff struct {
+	Height       int64                  `json:"height,string"`
+	Added        []ValidatorPowerChange `json:"added"`
+	Removed      []ValidatorPowerChange `json:"removed"`
+	PowerChanged []ValidatorPowerChange `json:"power_changed"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataValidatorSetDiff) TypeTag() string { return "tendermint/event/ValidatorSetDiff" }
+
+func (e EventDataValidatorSetDiff) ToLegacy() LegacyEventData {
+	return e
+}
+
+// EventDataValidatorMembership is the address of the key of this node,
+// which entered or left the validator set of Height.
+type EventDataValidatorMembership struct {
+	Height  int64   `json:"height,string"`
+	Address Address `json:"address"`
+}
+
+// TypeTag implements the required method of jsontypes.Tagged.
+func (EventDataValidatorMembership) TypeTag() string {
+	return "tendermint/event/ValidatorMembership"
+}
+
+func (e EventDataValidatorMembership) ToLegacy() LegacyEventData {
+	return e
+}
+
 // EventDataBlockSyncStatus shows the fastsync status and the
 // height when the node state sync mechanism changes.
 type EventDataBlockSyncStatus struct {
@@ -467,7 +888,13 @@ const (
 )
 
 var (
+	EventQueryBlockApplied        = QueryForEvent(EventBlockAppliedValue)
+	EventQueryBlockGossip         = QueryForEvent(EventBlockGossipValue)
 	EventQueryCompleteProposal    = QueryForEvent(EventCompleteProposalValue)
+	EventQueryConflictingSelfVote = QueryForEvent(EventConflictingSelfVoteValue)
+	EventQueryConsensusStalled    = QueryForEvent(EventConsensusStalledValue)
+	EventQueryDoubleSignRefusal   = QueryForEvent(EventDoubleSignRefusalValue)
+	EventQueryHeightSummary       = QueryForEvent(EventHeightSummaryValue)
 	EventQueryLock                = QueryForEvent(EventLockValue)
 	EventQueryNewBlock            = QueryForEvent(EventNewBlockValue)
 	EventQueryNewBlockHeader      = QueryForEvent(EventNewBlockHeaderValue)
@@ -475,16 +902,26 @@ var (
 	EventQueryNewRound            = QueryForEvent(EventNewRoundValue)
 	EventQueryNewRoundStep        = QueryForEvent(EventNewRoundStepValue)
 	EventQueryPolka               = QueryForEvent(EventPolkaValue)
+	EventQueryPartSetMismatch     = QueryForEvent(EventPartSetMismatchValue)
+	EventQueryPrecommitsExcluded  = QueryForEvent(EventPrecommitsExcludedValue)
+	EventQueryRebuildDivergence   = QueryForEvent(EventRebuildDivergenceValue)
 	EventQueryRelock              = QueryForEvent(EventRelockValue)
+	EventQueryRoundSkip           = QueryForEvent(EventRoundSkipValue)
+	EventQueryStepBudgetExceeded  = QueryForEvent(EventStepBudgetExceededValue)
 	EventQueryTimeoutPropose      = QueryForEvent(EventTimeoutProposeValue)
 	EventQueryTimeoutWait         = QueryForEvent(EventTimeoutWaitValue)
 	EventQueryTx                  = QueryForEvent(EventTxValue)
 	EventQueryValidatorSetUpdates = QueryForEvent(EventValidatorSetUpdatesValue)
 	EventQueryValidBlock          = QueryForEvent(EventValidBlockValue)
+	EventQueryValidatorSetDiff    = QueryForEvent(EventValidatorSetDiffValue)
+	EventQueryValidatorAdded      = QueryForEvent(EventValidatorAddedValue)
+	EventQueryValidatorRemoved    = QueryForEvent(EventValidatorRemovedValue)
 	EventQueryVote                = QueryForEvent(EventVoteValue)
 	EventQueryBlockSyncStatus     = QueryForEvent(EventBlockSyncStatusValue)
 	EventQueryStateSyncStatus     = QueryForEvent(EventStateSyncStatusValue)
 	EventQueryEvidenceValidated   = QueryForEvent(EventEvidenceValidatedValue)
+
+	EventQueryVoteExtensionRejected = QueryForEvent(EventVoteExtensionRejectedValue)
 )
 
 func EventQueryTxFor(tx Tx) *tmquery.Query {
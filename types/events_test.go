@@ -13,17 +13,30 @@ import (
 
 // Verify that the event data types satisfy their shared interface.
 var (
+	_ EventData = EventDataBlockApplied{}
+	_ EventData = EventDataBlockGossip{}
 	_ EventData = EventDataBlockSyncStatus{}
 	_ EventData = EventDataCompleteProposal{}
+	_ EventData = EventDataConflictingSelfVote{}
+	_ EventData = EventDataConsensusStalled{}
+	_ EventData = EventDataDoubleSignRefusal{}
+	_ EventData = EventDataHeightSummary{}
+	_ EventData = EventDataLock{}
 	_ EventData = EventDataNewBlock{}
 	_ EventData = EventDataNewBlockHeader{}
 	_ EventData = EventDataNewEvidence{}
 	_ EventData = EventDataNewRound{}
+	_ EventData = EventDataPartSetMismatch{}
+	_ EventData = EventDataPrecommitsExcluded{}
+	_ EventData = EventDataRoundSkip{}
 	_ EventData = EventDataRoundState{}
 	_ EventData = EventDataStateSyncStatus{}
+	_ EventData = EventDataStepBudgetExceeded{}
 	_ EventData = EventDataTx{}
+	_ EventData = EventDataValidatorSetDiff{}
 	_ EventData = EventDataValidatorSetUpdates{}
 	_ EventData = EventDataVote{}
+	_ EventData = EventDataVoteExtensionRejected{}
 	_ EventData = EventDataString("")
 )
 
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+)
+
+// heightEvidencePackageSignPrefix starts the sign bytes of the height evidence
+// packages. The sign bytes of the votes, vote extensions and proposals are
+// length delimited protobuf, which starts with a valid uvarint: the
+// binary.MaxVarintLen64 bytes of 0xff of the prefix overflow any uvarint, so
+// that the signature of a package can never be taken for the signature of a
+// consensus message.
+var heightEvidencePackageSignPrefix = append(
+	bytes.Repeat([]byte{0xff}, binary.MaxVarintLen64),
+	"tendermint/HeightEvidencePackage"...,
+)
+
+// HeightEvidencePackageSignBytes returns the bytes a private validator signs
+// to sign off the height evidence package of the given digest, for chainID.
+func HeightEvidencePackageSignBytes(chainID string, digest []byte) []byte {
+	bz := make([]byte, 0, len(heightEvidencePackageSignPrefix)+binary.MaxVarintLen64+len(chainID)+len(digest))
+	bz = append(bz, heightEvidencePackageSignPrefix...)
+	bz = binary.AppendUvarint(bz, uint64(len(chainID)))
+	bz = append(bz, chainID...)
+	return append(bz, digest...)
+}
+
+// HeightEvidencePackageSigner is implemented by the private validators which
+// can sign off the height evidence packages exported to report byzantine
+// faults. The packages are signed outside of the domain of the consensus
+// messages, see HeightEvidencePackageSignBytes.
+type HeightEvidencePackageSigner interface {
+	SignHeightEvidencePackage(ctx context.Context, chainID string, digest []byte) ([]byte, error)
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requireDelimited checks that signBytes are a single length delimited
+// protobuf message, as the sign bytes of all the consensus messages are.
+func requireDelimited(t *testing.T, signBytes []byte) {
+	t.Helper()
+	length, n := binary.Uvarint(signBytes)
+	require.Positive(t, n)
+	require.Equal(t, len(signBytes), n+int(length))
+}
+
+func TestHeightEvidencePackageSignBytesDomain(t *testing.T) {
+	digest := bytes.Repeat([]byte{0x0a}, 32)
+	for _, chainID := range []string{"", "test_chain_id", string(bytes.Repeat([]byte{0x08}, 300))} {
+		signBytes := HeightEvidencePackageSignBytes(chainID, digest)
+
+		// the sign bytes of a package can not be read as length delimited
+		_, n := binary.Uvarint(signBytes)
+		assert.Negative(t, n, "the sign bytes of a package start with a valid uvarint")
+
+		for _, vote := range []*Vote{examplePrevote(t), examplePrecommit(t)} {
+			v := vote.ToProto()
+			v.Extension = []byte("extension")
+			for _, consensusSignBytes := range [][]byte{VoteSignBytes(chainID, v), VoteExtensionSignBytes(chainID, v)} {
+				requireDelimited(t, consensusSignBytes)
+				assert.NotEqual(t, consensusSignBytes[:1], signBytes[:1])
+			}
+		}
+		proposalSignBytes := ProposalSignBytes(chainID, getTestProposal(t).ToProto())
+		requireDelimited(t, proposalSignBytes)
+		assert.NotEqual(t, proposalSignBytes[:1], signBytes[:1])
+	}
+
+	// the chain ID and the digest are both signed
+	assert.NotEqual(t,
+		HeightEvidencePackageSignBytes("a", []byte("bc")),
+		HeightEvidencePackageSignBytes("ab", []byte("c")))
+}
+
+func TestMockPVSignHeightEvidencePackage(t *testing.T) {
+	ctx := context.Background()
+	pv := NewMockPV()
+	pubKey, err := pv.GetPubKey(ctx)
+	require.NoError(t, err)
+	digest := bytes.Repeat([]byte{1}, 32)
+
+	sig, err := pv.SignHeightEvidencePackage(ctx, "test_chain_id", digest)
+	require.NoError(t, err)
+	assert.True(t, pubKey.VerifySignature(HeightEvidencePackageSignBytes("test_chain_id", digest), sig))
+
+	// the signature is not valid for a vote
+	vote := examplePrecommit(t).ToProto()
+	assert.False(t, pubKey.VerifySignature(VoteSignBytes("test_chain_id", vote), sig))
+
+	_, err = NewErroringMockPV().SignHeightEvidencePackage(ctx, "test_chain_id", digest)
+	assert.ErrorIs(t, err, ErroringMockPVErr)
+}
@@ -252,6 +252,8 @@ func (ps *PartSet) Count() uint32 {
 	if ps == nil {
 		return 0
 	}
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
 	return ps.count
 }
 
@@ -259,6 +261,8 @@ func (ps *PartSet) ByteSize() int64 {
 	if ps == nil {
 		return 0
 	}
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
 	return ps.byteSize
 }
 
@@ -360,7 +364,7 @@ func (ps *PartSet) StringShort() string {
 	}
 	ps.mtx.Lock()
 	defer ps.mtx.Unlock()
-	return fmt.Sprintf("(%v of %v)", ps.Count(), ps.Total())
+	return fmt.Sprintf("(%v of %v)", ps.count, ps.total)
 }
 
 func (ps *PartSet) MarshalJSON() ([]byte, error) {
@@ -375,7 +379,7 @@ func (ps *PartSet) MarshalJSON() ([]byte, error) {
 		CountTotal    string         `json:"count/total"`
 		PartsBitArray *bits.BitArray `json:"parts_bit_array"`
 	}{
-		fmt.Sprintf("%d/%d", ps.Count(), ps.Total()),
+		fmt.Sprintf("%d/%d", ps.count, ps.total),
 		ps.partsBitArray,
 	})
 }
@@ -127,6 +127,11 @@ func (pv MockPV) SignProposal(ctx context.Context, chainID string, proposal *tmp
 	return nil
 }
 
+// Implements HeightEvidencePackageSigner.
+func (pv MockPV) SignHeightEvidencePackage(ctx context.Context, chainID string, digest []byte) ([]byte, error) {
+	return pv.PrivKey.Sign(HeightEvidencePackageSignBytes(chainID, digest))
+}
+
 func (pv MockPV) ExtractIntoValidator(ctx context.Context, votingPower int64) *Validator {
 	pubKey, _ := pv.GetPubKey(ctx)
 	return &Validator{
@@ -169,6 +174,11 @@ func (pv *ErroringMockPV) SignProposal(ctx context.Context, chainID string, prop
 	return ErroringMockPVErr
 }
 
+// Implements HeightEvidencePackageSigner.
+func (pv *ErroringMockPV) SignHeightEvidencePackage(ctx context.Context, chainID string, digest []byte) ([]byte, error) {
+	return nil, ErroringMockPVErr
+}
+
 // NewErroringMockPV returns a MockPV that fails on each signing request. Again, for testing only.
 
 func NewErroringMockPV() *ErroringMockPV {
@@ -19,6 +19,35 @@ const (
 	MaxVotesCount = 10000
 )
 
+// VoteVerification is how much of a vote its source has verified before
+// adding it to a VoteSet. The checks already made are skipped by the VoteSet.
+type VoteVerification int
+
+const (
+	// VoteUnverified votes have their signature and extension signature
+	// verified by the VoteSet.
+	VoteUnverified VoteVerification = iota
+	// VoteSignatureVerified votes only have their extension signature
+	// verified by the VoteSet.
+	VoteSignatureVerified
+	// VoteAndExtensionVerified votes have no signature verified by the
+	// VoteSet.
+	VoteAndExtensionVerified
+)
+
+func (v VoteVerification) String() string {
+	switch v {
+	case VoteUnverified:
+		return "unverified"
+	case VoteSignatureVerified:
+		return "signature-verified"
+	case VoteAndExtensionVerified:
+		return "signature-and-extension-verified"
+	default:
+		return "unknown"
+	}
+}
+
 /*
 	VoteSet helps collect signatures from validators at each height+round for a
 	predefined vote type.
@@ -102,6 +131,15 @@ func NewExtendedVoteSet(chainID string, height int64, round int32,
 	return vs
 }
 
+// ExtensionsEnabled returns whether the vote set verifies the vote extension
+// data of its votes.
+func (voteSet *VoteSet) ExtensionsEnabled() bool {
+	if voteSet == nil {
+		return false
+	}
+	return voteSet.extensionsEnabled
+}
+
 func (voteSet *VoteSet) ChainID() string {
 	return voteSet.chainID
 }
@@ -154,11 +192,34 @@ func (voteSet *VoteSet) AddVote(vote *Vote) (added bool, err error) {
 	voteSet.mtx.Lock()
 	defer voteSet.mtx.Unlock()
 
-	return voteSet.addVote(vote)
+	return voteSet.addVote(vote, true, VoteUnverified)
+}
+
+// AddPreVerifiedVote is like AddVote, but skips the signature checks verified
+// says were already made. The vote is validated otherwise, so a malformed vote
+// is still rejected. It must only be used for votes from trusted sources.
+func (voteSet *VoteSet) AddPreVerifiedVote(vote *Vote, verified VoteVerification) (added bool, err error) {
+	if voteSet == nil {
+		panic("AddPreVerifiedVote() on nil VoteSet")
+	}
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+
+	return voteSet.addVote(vote, true, verified)
+}
+
+// addVoteWithoutExtension is like AddVote, but a vote set with extensions
+// enabled also accepts precommits whose vote extension data is absent. It is
+// used to reconstruct vote sets from extended commits with filtered extensions.
+func (voteSet *VoteSet) addVoteWithoutExtension(vote *Vote) (added bool, err error) {
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+
+	return voteSet.addVote(vote, false, VoteUnverified)
 }
 
 // NOTE: Validates as much as possible before attempting to verify the signature.
-func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
+func (voteSet *VoteSet) addVote(vote *Vote, requireExtension bool, verified VoteVerification) (added bool, err error) {
 	if vote == nil {
 		return false, ErrVoteNil
 	}
@@ -207,13 +268,32 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 	}
 
 	// Check signature.
-	if voteSet.extensionsEnabled {
-		if err := vote.VerifyVoteAndExtension(voteSet.chainID, val.PubKey); err != nil {
+	if voteSet.extensionsEnabled && (requireExtension || len(vote.ExtensionSignature) > 0) {
+		var err error
+		switch verified {
+		case VoteUnverified:
+			err = vote.VerifyVoteAndExtension(voteSet.chainID, val.PubKey)
+		case VoteSignatureVerified:
+			err = vote.VerifyExtension(voteSet.chainID, val.PubKey)
+		}
+		if err != nil {
 			return false, fmt.Errorf("failed to verify vote with ChainID %s and PubKey %s: %w", voteSet.chainID, val.PubKey, err)
 		}
+	} else if voteSet.extensionsEnabled {
+		// The extension was filtered out, only the vote itself can be verified.
+		if verified == VoteUnverified {
+			if err := vote.Verify(voteSet.chainID, val.PubKey); err != nil {
+				return false, fmt.Errorf("failed to verify vote with ChainID %s and PubKey %s: %w", voteSet.chainID, val.PubKey, err)
+			}
+		}
+		if len(vote.Extension) > 0 {
+			return false, errors.New("vote extension present without its signature")
+		}
 	} else {
-		if err := vote.Verify(voteSet.chainID, val.PubKey); err != nil {
-			return false, fmt.Errorf("failed to verify vote with ChainID %s and PubKey %s: %w", voteSet.chainID, val.PubKey, err)
+		if verified == VoteUnverified {
+			if err := vote.Verify(voteSet.chainID, val.PubKey); err != nil {
+				return false, fmt.Errorf("failed to verify vote with ChainID %s and PubKey %s: %w", voteSet.chainID, val.PubKey, err)
+			}
 		}
 		if len(vote.ExtensionSignature) > 0 || len(vote.Extension) > 0 {
 			return false, errors.New("unexpected vote extension data present in vote")
@@ -584,6 +584,102 @@ func TestVoteSet_VoteExtensionsEnabled(t *testing.T) {
 	}
 }
 
+func TestVoteSet_AddPreVerifiedVote(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	height, round := int64(1), int32(0)
+	voteSet, _, privValidators := randVoteSet(ctx, t, height, round, tmproto.PrecommitType, 4, 1)
+	blockID := BlockID{crypto.CRandBytes(32), PartSetHeader{123, crypto.CRandBytes(32)}}
+
+	signedVote := func(idx int32) *Vote {
+		pubKey, err := privValidators[idx].GetPubKey(ctx)
+		require.NoError(t, err)
+		vote := &Vote{
+			ValidatorAddress: pubKey.Address(),
+			ValidatorIndex:   idx,
+			Height:           height,
+			Round:            round,
+			Type:             tmproto.PrecommitType,
+			Timestamp:        tmtime.Now(),
+			BlockID:          blockID,
+		}
+		v := vote.ToProto()
+		require.NoError(t, privValidators[idx].SignVote(ctx, voteSet.ChainID(), v))
+		vote.Signature = v.Signature
+		vote.ExtensionSignature = v.ExtensionSignature
+		return vote
+	}
+
+	// a bad signature is only detected if the vote was not verified
+	vote := signedVote(0)
+	vote.Signature = crypto.CRandBytes(64)
+	added, err := voteSet.AddVote(vote)
+	require.ErrorIs(t, err, ErrVoteInvalidSignature)
+	require.False(t, added)
+	added, err = voteSet.AddPreVerifiedVote(vote, VoteSignatureVerified)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	// the extension signature is verified unless flagged as verified
+	vote = signedVote(1)
+	vote.ExtensionSignature = crypto.CRandBytes(64)
+	added, err = voteSet.AddPreVerifiedVote(vote, VoteSignatureVerified)
+	require.ErrorIs(t, err, ErrVoteInvalidSignature)
+	require.False(t, added)
+	added, err = voteSet.AddPreVerifiedVote(vote, VoteAndExtensionVerified)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	// malformed votes are rejected
+	added, err = voteSet.AddPreVerifiedVote(withValidator(signedVote(2), crypto.CRandBytes(20), 2), VoteAndExtensionVerified)
+	require.ErrorIs(t, err, ErrVoteInvalidValidatorAddress)
+	require.False(t, added)
+	added, err = voteSet.AddPreVerifiedVote(withHeight(signedVote(2), height+1), VoteAndExtensionVerified)
+	require.ErrorIs(t, err, ErrVoteUnexpectedStep)
+	require.False(t, added)
+}
+
+func BenchmarkVoteSetAddVote(b *testing.B) {
+	for _, verified := range []VoteVerification{VoteUnverified, VoteSignatureVerified, VoteAndExtensionVerified} {
+		b.Run(verified.String(), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			const numValidators = 100
+			valSet, privValidators := randValidatorPrivValSet(ctx, b, numValidators, 1)
+			blockID := BlockID{crypto.CRandBytes(32), PartSetHeader{123, crypto.CRandBytes(32)}}
+			votes := make([]*Vote, numValidators)
+			for i, privVal := range privValidators {
+				pubKey, err := privVal.GetPubKey(ctx)
+				require.NoError(b, err)
+				votes[i] = &Vote{
+					ValidatorAddress: pubKey.Address(),
+					ValidatorIndex:   int32(i),
+					Height:           1,
+					Type:             tmproto.PrecommitType,
+					Timestamp:        tmtime.Now(),
+					BlockID:          blockID,
+				}
+				v := votes[i].ToProto()
+				require.NoError(b, privVal.SignVote(ctx, "test_chain_id", v))
+				votes[i].Signature = v.Signature
+				votes[i].ExtensionSignature = v.ExtensionSignature
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				voteSet := NewExtendedVoteSet("test_chain_id", 1, 0, tmproto.PrecommitType, valSet)
+				for _, vote := range votes {
+					if _, err := voteSet.AddPreVerifiedVote(vote, verified); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
 // NOTE: privValidators are in order
 func randVoteSet(
 	ctx context.Context,